@@ -0,0 +1,27 @@
+// Package openapi embeds the OpenAPI specification of the HTTP API.
+package openapi
+
+import (
+	_ "embed"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+//go:embed api.yml
+var spec []byte
+
+// Load parses and validates the embedded specification.
+func Load() (*openapi3.T, error) {
+	loader := openapi3.NewLoader()
+
+	doc, err := loader.LoadFromData(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := doc.Validate(loader.Context); err != nil {
+		return nil, err
+	}
+
+	return doc, nil
+}
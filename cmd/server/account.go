@@ -0,0 +1,70 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	"github.com/Sergey-Polishchenko/simple-api/internal/config"
+	"github.com/Sergey-Polishchenko/simple-api/internal/infrastructure/mail"
+	repo "github.com/Sergey-Polishchenko/simple-api/internal/infrastructure/postgres"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/logger"
+)
+
+// newAccounts builds the email verification and password reset flows.
+func newAccounts(
+	env *config.Environment,
+	db *gorm.DB,
+	users app.UserRepository,
+	sessions app.RefreshTokenRepository,
+	audit app.AuditRepository,
+	hasher app.PasswordHasher,
+	logger logger.Logger,
+) (app.AccountService, error) {
+	if len(env.Mail.TokenSecret) < minSecretLength {
+		return nil, fmt.Errorf("ACCOUNT_TOKEN_SECRET must be at least %d bytes long", minSecretLength)
+	}
+
+	mailer, err := newMailer(env, logger)
+	if err != nil {
+		return nil, err
+	}
+	usedRepo, err := repo.NewUsedTokenRepo(db)
+	if err != nil {
+		return nil, err
+	}
+
+	return app.NewAccountApp(users, usedRepo, sessions, mailer, hasher, logger, app.AccountConfig{
+		Secret:           []byte(env.Mail.TokenSecret),
+		VerificationTTL:  env.Mail.VerificationTTL,
+		PasswordResetTTL: env.Mail.PasswordResetTTL,
+		VerifyEmailURL:   env.Mail.VerifyEmailURL,
+		ResetPasswordURL: env.Mail.ResetPasswordURL,
+	}, app.WithAccountAuditLog(audit), app.WithAccountTransactor(repo.NewTransactor(db)))
+}
+
+func newMailer(env *config.Environment, logger logger.Logger) (app.Mailer, error) {
+	// The development drivers expose tokens to whoever reads the files or logs.
+	if env.IsProduction() && env.Mail.Driver != "smtp" {
+		return nil, errors.New("MAIL_DRIVER must be smtp in production")
+	}
+
+	switch env.Mail.Driver {
+	case "smtp":
+		return mail.NewSMTPMailer(mail.SMTPConfig{
+			Host:     env.Mail.SMTPHost,
+			Port:     env.Mail.SMTPPort,
+			Username: env.Mail.SMTPUsername,
+			Password: env.Mail.SMTPPassword,
+			From:     env.Mail.From,
+		}, mail.DefaultTemplates)
+	case "file":
+		return mail.NewFileMailer(env.Mail.Dir, env.Mail.From, mail.DefaultTemplates, logger)
+	case "log":
+		return mail.NewLogMailer(env.Mail.From, mail.DefaultTemplates, logger), nil
+	default:
+		return nil, fmt.Errorf("unknown MAIL_DRIVER %q", env.Mail.Driver)
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Sergey-Polishchenko/simple-api/internal/config"
+	"github.com/Sergey-Polishchenko/simple-api/internal/interfaces/http/admin"
+	httpserver "github.com/Sergey-Polishchenko/simple-api/internal/interfaces/http/server"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/logger"
+)
+
+// version is the version of the build, set with
+// -ldflags "-X main.version=v1.2.3".
+var version = "dev"
+
+// newAdminServer builds the admin server configured by env, nil when it
+// is disabled.
+func newAdminServer(env *config.Environment, log logger.Logger) (*httpserver.Server, error) {
+	if env.Admin.Port == "" {
+		return nil, nil
+	}
+	if len(env.Admin.Token) < minSecretLength {
+		return nil, fmt.Errorf("ADMIN_TOKEN must be at least %d bytes long", minSecretLength)
+	}
+	levels, ok := log.(logger.LevelController)
+	if !ok {
+		return nil, errors.New("the log level can't be changed")
+	}
+
+	handler := admin.NewHandler(admin.Config{
+		Token:    env.Admin.Token,
+		Version:  version,
+		Settings: env,
+		Levels:   levels,
+		Logger:   log.With("component", "admin"),
+	})
+	return httpserver.New(fmt.Sprintf(":%s", env.Admin.Port), handler), nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"os"
+
+	"github.com/Sergey-Polishchenko/simple-api/internal/config"
+	"github.com/Sergey-Polishchenko/simple-api/internal/interfaces/http/middleware"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/jwks"
+)
+
+// minSecretLength is the HS256 key size recommended by RFC 7518.
+const minSecretLength = 32
+
+// newSigner returns the signer of the tokens issued by password logins,
+// or nil when there is no signing key: the private key file if any,
+// otherwise the HS256 secret.
+func newSigner(env *config.Environment) (*jwks.Signer, error) {
+	switch {
+	case env.JWT.SigningKeyFile != "":
+		data, err := os.ReadFile(env.JWT.SigningKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		key, err := jwks.ParsePrivateKeyPEM(data)
+		if err != nil {
+			return nil, err
+		}
+		return jwks.NewSigner(key, env.JWT.SigningKeyID)
+	case env.JWT.Secret != "":
+		if err := checkSecret(env.JWT.Secret); err != nil {
+			return nil, err
+		}
+		return jwks.NewSigner([]byte(env.JWT.Secret), "")
+	}
+	return nil, nil
+}
+
+func checkSecret(secret string) error {
+	if len(secret) < minSecretLength {
+		return errors.New("JWT_SECRET must be at least 32 bytes long")
+	}
+	return nil
+}
+
+// newJWTConfig builds the bearer authentication settings, or returns nil
+// when no verification key is configured. Tokens of signer, if any, are accepted.
+func newJWTConfig(env *config.Environment, signer *jwks.Signer) (*middleware.JWTConfig, error) {
+	if !env.JWT.Enabled() {
+		return nil, nil
+	}
+
+	var static []jwks.Key
+	if env.JWT.Secret != "" {
+		if err := checkSecret(env.JWT.Secret); err != nil {
+			return nil, err
+		}
+		static = append(static, jwks.Key{Algorithm: jwks.HS256, Material: []byte(env.JWT.Secret)})
+	}
+	if env.JWT.SigningKeyFile != "" {
+		static = append(static, signer.VerificationKey())
+	}
+	if env.JWT.PublicKeyFile != "" {
+		data, err := os.ReadFile(env.JWT.PublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		key, err := jwks.ParsePublicKeyPEM(data)
+		if err != nil {
+			return nil, err
+		}
+		static = append(static, jwks.Key{Material: key})
+	}
+
+	keys, err := jwks.NewSet(static, env.JWT.JWKSFile, env.JWT.JWKSRefresh)
+	if err != nil {
+		return nil, err
+	}
+
+	return &middleware.JWTConfig{
+		Keys:      keys,
+		Issuer:    env.JWT.Issuer,
+		Audience:  env.JWT.Audience,
+		ClockSkew: env.JWT.ClockSkew,
+	}, nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	"github.com/Sergey-Polishchenko/simple-api/internal/config"
+	repo "github.com/Sergey-Polishchenko/simple-api/internal/infrastructure/postgres"
+	"github.com/Sergey-Polishchenko/simple-api/internal/interfaces/http"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/jwks"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/logger"
+)
+
+// newOIDC builds the OpenID Connect provider. Its tokens are signed with
+// the private key file, whose public key relying parties fetch from the JWKS.
+func newOIDC(
+	env *config.Environment,
+	db *gorm.DB,
+	users app.UserService,
+	signer *jwks.Signer,
+	logger logger.Logger,
+) (*http.OIDCConfig, error) {
+	if env.JWT.SigningKeyFile == "" {
+		return nil, errors.New("OIDC_ENABLED requires JWT_SIGNING_KEY_FILE")
+	}
+	if env.JWT.Issuer == "" {
+		return nil, errors.New("OIDC_ENABLED requires JWT_ISSUER")
+	}
+	// ID tokens are signed with the same key; only the audience keeps
+	// them from being accepted as access tokens.
+	if env.JWT.Audience == "" {
+		return nil, errors.New("OIDC_ENABLED requires JWT_AUDIENCE")
+	}
+
+	clientRepo, err := repo.NewOAuthClientRepo(db)
+	if err != nil {
+		return nil, err
+	}
+	codeRepo, err := repo.NewAuthorizationCodeRepo(db)
+	if err != nil {
+		return nil, err
+	}
+	revokedRepo, err := repo.NewRevokedTokenRepo(db)
+	if err != nil {
+		return nil, err
+	}
+
+	service := app.NewOIDCApp(clientRepo, codeRepo, revokedRepo, users, signer, logger, app.OIDCConfig{
+		Issuer:         env.JWT.Issuer,
+		Audience:       env.JWT.Audience,
+		AccessTokenTTL: env.Session.AccessTokenTTL,
+		IDTokenTTL:     env.OIDC.IDTokenTTL,
+	})
+
+	return &http.OIDCConfig{
+		Service: service,
+		Clients: app.NewOAuthClientApp(clientRepo, logger),
+		Issuer:  env.JWT.Issuer,
+		Key:     signer.VerificationKey(),
+	}, nil
+}
@@ -2,7 +2,10 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
+	"log/slog"
+	nethttp "net/http"
 	"os"
 	"os/signal"
 	"syscall"
@@ -14,17 +17,25 @@ import (
 	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
 	"github.com/Sergey-Polishchenko/simple-api/internal/config"
 	repo "github.com/Sergey-Polishchenko/simple-api/internal/infrastructure/postgres"
+	"github.com/Sergey-Polishchenko/simple-api/internal/interfaces/graphql"
 	"github.com/Sergey-Polishchenko/simple-api/internal/interfaces/http"
 	httpserver "github.com/Sergey-Polishchenko/simple-api/internal/interfaces/http/server"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/health"
 	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/logger"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/metrics"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/password"
 )
 
 func main() {
-	logger := logger.NewZapLogger()
-
 	env, err := config.Load()
 	if err != nil {
-		logger.Error("can't load .env", "error", err)
+		logger.NewZapLogger().Error("can't load .env", "error", err)
+		return
+	}
+
+	logger, err := newLogger(env)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "can't configure logging:", err)
 		return
 	}
 	port := fmt.Sprintf(":%s", env.Port)
@@ -35,14 +46,215 @@ func main() {
 		return
 	}
 
-	repo, err := repo.NewUserRepo(db)
+	sqlDB, err := db.DB()
+	if err != nil {
+		logger.Error("can't connect to postgres database", "error", err)
+		return
+	}
+	probes := health.NewRegistry(env.Health.CheckTimeout)
+	probes.Register("database", health.PingChecker(sqlDB))
+
+	var registry *metrics.Registry
+	if env.Metrics.Port != "" {
+		if registry, err = newMetrics(db, sqlDB); err != nil {
+			logger.Error("can't initialize metrics", "error", err)
+			return
+		}
+	}
+
+	tracer, stopTracing, err := newTracer(env, logger)
+	if err != nil {
+		logger.Error("can't initialize tracing", "error", err)
+		return
+	}
+	if tracer != nil {
+		if err := db.Use(repo.NewQueryTracing(tracer)); err != nil {
+			logger.Error("can't initialize tracing", "error", err)
+			return
+		}
+	}
+
+	var userRepoOpts []repo.UserRepoOption
+	if env.DB.RowLevelSecurity {
+		userRepoOpts = append(userRepoOpts, repo.WithRowLevelSecurity())
+	}
+	userRepo, err := repo.NewUserRepo(db, userRepoOpts...)
+	if err != nil {
+		logger.Error("can't automigrate postgres database", "error", err)
+		return
+	}
+
+	signer, err := newSigner(env)
+	if err != nil {
+		logger.Error("can't load JWT signing key", "error", err)
+		return
+	}
+
+	jwtConfig, err := newJWTConfig(env, signer)
+	if err != nil {
+		logger.Error("can't load JWT keys", "error", err)
+		return
+	}
+
+	hasher := password.NewHasher(password.Params{
+		Memory:      env.Password.Memory,
+		Iterations:  env.Password.Iterations,
+		Parallelism: env.Password.Parallelism,
+		SaltLength:  password.DefaultParams.SaltLength,
+		KeyLength:   password.DefaultParams.KeyLength,
+	})
+
+	var (
+		auth app.AuthService
+		mfa  app.MFAService
+		// privacy gathers the stores holding data about users as they are set up.
+		privacy app.PrivacySources
+	)
+	if signer != nil {
+		refreshTokenRepo, err := repo.NewRefreshTokenRepo(db)
+		if err != nil {
+			logger.Error("can't automigrate postgres database", "error", err)
+			return
+		}
+		mfaRepo, err := repo.NewMFARepo(db)
+		if err != nil {
+			logger.Error("can't automigrate postgres database", "error", err)
+			return
+		}
+		privacy.Sessions, privacy.MFA = refreshTokenRepo, mfaRepo
+		mfa = app.NewMFAApp(mfaRepo, logger, env.Session.MFAIssuer)
+		auth, err = app.NewAuthApp(userRepo, refreshTokenRepo, hasher, signer, logger, app.AuthConfig{
+			Issuer:          env.JWT.Issuer,
+			Audience:        env.JWT.Audience,
+			AccessTokenTTL:  env.Session.AccessTokenTTL,
+			RefreshTokenTTL: env.Session.RefreshTokenTTL,
+		}, app.WithSecondFactor(mfa))
+		if err != nil {
+			logger.Error("can't initialize password login", "error", err)
+			return
+		}
+	}
+
+	var apiKeys app.APIKeyService
+	if env.APIKeyPepper != "" {
+		if len(env.APIKeyPepper) < minSecretLength {
+			logger.Error("API_KEY_PEPPER must be at least 32 bytes long")
+			return
+		}
+		apiKeyRepo, err := repo.NewAPIKeyRepo(db)
+		if err != nil {
+			logger.Error("can't automigrate postgres database", "error", err)
+			return
+		}
+		apiKeys = app.NewAPIKeyApp(apiKeyRepo, logger, []byte(env.APIKeyPepper))
+	}
+
+	if jwtConfig == nil && apiKeys == nil {
+		if env.IsProduction() {
+			logger.Error("JWT keys or API_KEY_PEPPER must be configured in production")
+			return
+		}
+		logger.Info("Neither JWT keys nor API_KEY_PEPPER are configured, authentication is disabled")
+	}
+
+	attributeRepo, err := repo.NewAttributeSchemaRepo(db)
+	if err != nil {
+		logger.Error("can't automigrate postgres database", "error", err)
+		return
+	}
+
+	auditRepo, err := repo.NewAuditRepo(db)
 	if err != nil {
 		logger.Error("can't automigrate postgres database", "error", err)
 		return
 	}
 
-	app := app.NewUserApp(repo, logger)
-	router := http.NewRouter(app)
+	privacy.Audit = auditRepo
+
+	events := app.NewUserEventBroker()
+	users := app.NewUserApp(userRepo, logger,
+		app.WithEventPublisher(events), app.WithPasswordHasher(hasher), app.WithAttributeSchema(attributeRepo),
+		app.WithAuditLog(auditRepo), app.WithUserHistory(repo.NewUserHistoryRepo(db, userRepoOpts...)),
+		app.WithSessions(privacy.Sessions), app.WithTransactor(repo.NewTransactor(db)))
+	if registry != nil {
+		users = app.InstrumentUserService(users, registry.NewOperations())
+	}
+	if tracer != nil {
+		users = app.InstrumentUserService(users, tracer)
+	}
+
+	groupRepo, err := repo.NewGroupRepo(db)
+	if err != nil {
+		logger.Error("can't automigrate postgres database", "error", err)
+		return
+	}
+	groups := app.NewGroupApp(groupRepo, userRepo, logger)
+	privacy.Groups = groupRepo
+
+	erasureReceiptRepo, err := repo.NewErasureReceiptRepo(db)
+	if err != nil {
+		logger.Error("can't automigrate postgres database", "error", err)
+		return
+	}
+
+	var accounts app.AccountService
+	if env.Mail.TokenSecret != "" {
+		if accounts, err = newAccounts(env, db, userRepo, privacy.Sessions, auditRepo, hasher, logger); err != nil {
+			logger.Error("can't initialize account emails", "error", err)
+			return
+		}
+	}
+
+	var tenants *http.TenantConfig
+	if env.Tenancy.Enabled {
+		tenantRepo, err := repo.NewTenantRepo(db)
+		if err != nil {
+			logger.Error("can't automigrate postgres database", "error", err)
+			return
+		}
+		tenants = &http.TenantConfig{
+			Service: app.NewTenantApp(tenantRepo, userRepo, logger),
+			Header:  env.Tenancy.Header,
+			Domain:  env.Tenancy.Domain,
+		}
+	}
+
+	var oidc *http.OIDCConfig
+	if env.OIDC.Enabled {
+		if oidc, err = newOIDC(env, db, users, signer, logger); err != nil {
+			logger.Error("can't initialize the OpenID Connect provider", "error", err)
+			return
+		}
+		jwtConfig.Revoked = oidc.Service
+	}
+
+	router, err := http.NewRouter(users, logger, http.Config{
+		UserEvents: events,
+		Health:     probes,
+		GraphQL: graphql.Config{
+			MaxDepth:      env.GraphQL.MaxDepth,
+			MaxComplexity: env.GraphQL.MaxComplexity,
+		},
+		Metrics:         registry,
+		Tracer:          tracer,
+		LogBodies:       env.Log.HTTPBodies,
+		EnforceContract: !env.IsProduction(),
+		JWT:             jwtConfig,
+		APIKeys:         apiKeys,
+		Auth:            auth,
+		OIDC:            oidc,
+		MFA:             mfa,
+		Accounts:        accounts,
+		Tenants:         tenants,
+		Groups:          groups,
+		Attributes:      app.NewAttributeSchemaApp(attributeRepo, logger),
+		Audit:           app.NewAuditApp(auditRepo, logger),
+		Privacy:         app.NewPrivacyApp(users, erasureReceiptRepo, privacy, logger),
+	})
+	if err != nil {
+		logger.Error("can't initialize HTTP router", "error", err)
+		return
+	}
 	httpServer := httpserver.New(port, router)
 
 	errChan := make(chan error, 1)
@@ -54,6 +266,34 @@ func main() {
 		}
 	}()
 
+	var metricsServer *httpserver.Server
+	if registry != nil {
+		metricsPort := fmt.Sprintf(":%s", env.Metrics.Port)
+		metricsServer = httpserver.New(metricsPort, newMetricsHandler(registry))
+		go func() {
+			logger.Info("Starting metrics server", "address", metricsPort)
+			if err := metricsServer.Start(); err != nil {
+				errChan <- fmt.Errorf("metrics server error: %w", err)
+			}
+		}()
+	}
+
+	adminServer, err := newAdminServer(env, logger)
+	if err != nil {
+		logger.Error("can't initialize the admin server", "error", err)
+		return
+	}
+	if adminServer != nil {
+		go func() {
+			logger.Info("Starting admin server", "address", ":"+env.Admin.Port)
+			if err := adminServer.Start(); err != nil {
+				errChan <- fmt.Errorf("admin server error: %w", err)
+			}
+		}()
+	}
+
+	probes.MarkStarted()
+
 	interrupt := make(chan os.Signal, 1)
 	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
 
@@ -61,15 +301,65 @@ func main() {
 	case <-interrupt:
 		logger.Info("Received interrupt signal, shutting down...")
 	case err := <-errChan:
-		logger.Error("Server error", err)
+		logger.Error("Server error", "error", err)
+	}
+
+	probes.MarkShuttingDown()
+	if env.Health.ShutdownDelay > 0 {
+		logger.Info("Waiting for traffic to drain", "delay", env.Health.ShutdownDelay)
+		time.Sleep(env.Health.ShutdownDelay)
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
 	if err := httpServer.Stop(ctx); err != nil {
-		logger.Error("HTTP shutdown error", err)
+		logger.Error("HTTP shutdown error", "error", err)
+	}
+	if metricsServer != nil {
+		if err := metricsServer.Stop(ctx); err != nil {
+			logger.Error("Metrics shutdown error", "error", err)
+		}
+	}
+	if adminServer != nil {
+		if err := adminServer.Stop(ctx); err != nil {
+			logger.Error("Admin shutdown error", "error", err)
+		}
+	}
+	if err := stopTracing(ctx); err != nil {
+		logger.Error("Tracing shutdown error", "error", err)
 	}
 
 	logger.Info("Server stopped")
 }
+
+// newMetrics registers the runtime metrics, and the metrics of the
+// connection pool and the queries of db, whose pool is sqlDB.
+func newMetrics(db *gorm.DB, sqlDB *sql.DB) (*metrics.Registry, error) {
+	registry := metrics.NewRegistry()
+	registry.RegisterRuntime()
+	registry.RegisterDBStats(sqlDB)
+
+	if err := db.Use(repo.NewQueryMetrics(registry)); err != nil {
+		return nil, err
+	}
+	return registry, nil
+}
+
+// newMetricsHandler serves the metrics at /metrics.
+func newMetricsHandler(registry *metrics.Registry) *nethttp.ServeMux {
+	mux := nethttp.NewServeMux()
+	mux.Handle("GET /metrics", registry)
+	return mux
+}
+
+// newLogger builds the logger configured by env and makes log/slog write
+// to it too.
+func newLogger(env *config.Environment) (logger.Logger, error) {
+	log, err := logger.New(logger.Config{Level: env.Log.Level, Format: env.Log.Format})
+	if err != nil {
+		return nil, err
+	}
+	slog.SetDefault(slog.New(logger.NewSlogHandler(log)))
+	return log, nil
+}
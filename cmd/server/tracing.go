@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	nethttp "net/http"
+	"os"
+	"time"
+
+	"github.com/Sergey-Polishchenko/simple-api/internal/config"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/logger"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/tracing"
+)
+
+// otlpTimeout bounds the requests exporting spans.
+const otlpTimeout = 10 * time.Second
+
+// newTracer builds the tracer configured by env, nil when tracing is
+// disabled. The returned function exports the remaining spans and
+// releases the exporter.
+func newTracer(env *config.Environment, logger logger.Logger) (*tracing.Tracer, func(context.Context) error, error) {
+	var (
+		exporter tracing.Exporter
+		file     *os.File
+	)
+	switch env.Tracing.Exporter {
+	case "":
+		return nil, func(context.Context) error { return nil }, nil
+	case "otlp":
+		exporter = tracing.NewOTLPExporter(env.Tracing.OTLPEndpoint, &nethttp.Client{Timeout: otlpTimeout})
+	case "stdout":
+		exporter = tracing.NewWriterExporter(os.Stdout)
+	case "file":
+		var err error
+		if file, err = os.OpenFile(env.Tracing.File, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600); err != nil {
+			return nil, nil, err
+		}
+		exporter = tracing.NewWriterExporter(file)
+	default:
+		return nil, nil, fmt.Errorf("unknown TRACING_EXPORTER %q", env.Tracing.Exporter)
+	}
+
+	tracer := tracing.NewTracer(tracing.Config{
+		ServiceName: env.Tracing.ServiceName,
+		Sampler:     tracing.RatioSampler(env.Tracing.SampleRatio),
+		Exporter:    exporter,
+		Logger:      logger,
+	})
+	stop := func(ctx context.Context) error {
+		err := tracer.Shutdown(ctx)
+		if file != nil {
+			err = errors.Join(err, file.Close())
+		}
+		return err
+	}
+	return tracer, stop, nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var apiKeyCommands = map[string]command{
+	"create": apiKeyCreateCmd,
+	"list":   apiKeyListCmd,
+	"revoke": apiKeyRevokeCmd,
+}
+
+func apiKeyCmd(ctx context.Context, c *cli, args []string) error {
+	if len(args) == 0 {
+		return errors.New("apikey takes a subcommand: create, list or revoke")
+	}
+
+	command, ok := apiKeyCommands[args[0]]
+	if !ok {
+		return fmt.Errorf("unknown apikey subcommand %q", args[0])
+	}
+	return command(ctx, c, args[1:])
+}
+
+func apiKeyCreateCmd(ctx context.Context, c *cli, args []string) error {
+	flags := c.flagSet("apikey create")
+	name := flags.String("name", "", "label of the key")
+	scopes := flags.String("scopes", "", "comma-separated scopes: users:read, users:write, users:admin")
+	expiresIn := flags.Duration("expires-in", 0, "lifetime of the key, e.g. 720h; the key never expires if zero")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *name == "" || *scopes == "" {
+		return errors.New("--name and --scopes are required")
+	}
+
+	var expiresAt *time.Time
+	if *expiresIn > 0 {
+		at := time.Now().Add(*expiresIn).UTC()
+		expiresAt = &at
+	}
+
+	key, err := c.backend.MintAPIKey(ctx, *name, strings.Split(*scopes, ","), expiresAt)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(c.stderr, "Store the key now, it can't be shown again.")
+	return write(c.stdout, c.format, key)
+}
+
+func apiKeyListCmd(ctx context.Context, c *cli, args []string) error {
+	flags := c.flagSet("apikey list")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	keys, err := c.backend.ListAPIKeys(ctx)
+	if err != nil {
+		return err
+	}
+	return write(c.stdout, c.format, keys)
+}
+
+func apiKeyRevokeCmd(ctx context.Context, c *cli, args []string) error {
+	flags := c.flagSet("apikey revoke")
+	yes := flags.Bool("yes", false, "don't ask for confirmation")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("apikey revoke takes exactly one key ID")
+	}
+
+	id := flags.Arg(0)
+	if !*yes {
+		if err := c.confirm(fmt.Sprintf("Revoke API key %s? Callers using it will be rejected.", id)); err != nil {
+			return err
+		}
+	}
+
+	if err := c.backend.RevokeAPIKey(ctx, id); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.stderr, "revoked %s\n", id)
+	return nil
+}
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	"github.com/Sergey-Polishchenko/simple-api/internal/config"
+	"github.com/Sergey-Polishchenko/simple-api/internal/domain"
+	repo "github.com/Sergey-Polishchenko/simple-api/internal/infrastructure/postgres"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/logger"
+	"github.com/Sergey-Polishchenko/simple-api/pkg/client"
+)
+
+// user is the record printed, imported and exported by usersctl.
+type user struct {
+	ID   string `json:"id,omitempty" yaml:"id,omitempty"`
+	Name string `json:"name" yaml:"name"`
+}
+
+// apiKey is the API key record printed by usersctl. Key is set only when minted.
+type apiKey struct {
+	ID         string     `json:"id" yaml:"id"`
+	Name       string     `json:"name" yaml:"name"`
+	Prefix     string     `json:"prefix" yaml:"prefix"`
+	Scopes     []string   `json:"scopes" yaml:"scopes"`
+	CreatedAt  time.Time  `json:"createdAt" yaml:"createdAt"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty" yaml:"expiresAt,omitempty"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty" yaml:"lastUsedAt,omitempty"`
+	RevokedAt  *time.Time `json:"revokedAt,omitempty" yaml:"revokedAt,omitempty"`
+	Key        string     `json:"key,omitempty" yaml:"key,omitempty"`
+}
+
+// backend performs user operations either over the API or directly on the repository.
+type backend interface {
+	List(ctx context.Context, name string) iter.Seq2[*user, error]
+	Get(ctx context.Context, id string) (*user, error)
+	Create(ctx context.Context, name string) (*user, error)
+	Update(ctx context.Context, id, name string) error
+	Delete(ctx context.Context, id string) error
+
+	MintAPIKey(ctx context.Context, name string, scopes []string, expiresAt *time.Time) (*apiKey, error)
+	ListAPIKeys(ctx context.Context) ([]*apiKey, error)
+	RevokeAPIKey(ctx context.Context, id string) error
+}
+
+// apiBackend talks to a running server.
+type apiBackend struct {
+	client *client.Client
+}
+
+func newAPIBackend(baseURL string, auth client.Authenticator, tenant string) (*apiBackend, error) {
+	opts := []client.Option{client.WithUserAgent("usersctl")}
+	if auth != nil {
+		opts = append(opts, client.WithAuth(auth))
+	}
+	if tenant != "" {
+		opts = append(opts, client.WithTenant(tenant))
+	}
+
+	c, err := client.New(baseURL, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &apiBackend{client: c}, nil
+}
+
+func (b *apiBackend) List(ctx context.Context, name string) iter.Seq2[*user, error] {
+	return func(yield func(*user, error) bool) {
+		for u, err := range b.client.Users(ctx, client.ListOptions{Name: name}) {
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if !yield(&user{ID: u.ID, Name: u.Name}, nil) {
+				return
+			}
+		}
+	}
+}
+
+func (b *apiBackend) Get(ctx context.Context, id string) (*user, error) {
+	u, err := b.client.GetUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &user{ID: u.ID, Name: u.Name}, nil
+}
+
+func (b *apiBackend) Create(ctx context.Context, name string) (*user, error) {
+	u, err := b.client.CreateUser(ctx, client.CreateUserInput{Name: name})
+	if err != nil {
+		return nil, err
+	}
+	return &user{ID: u.ID, Name: u.Name}, nil
+}
+
+func (b *apiBackend) Update(ctx context.Context, id, name string) error {
+	return b.client.UpdateUser(ctx, id, client.UpdateUserInput{Name: name})
+}
+
+func (b *apiBackend) Delete(ctx context.Context, id string) error {
+	return b.client.DeleteUser(ctx, id)
+}
+
+func (b *apiBackend) MintAPIKey(
+	ctx context.Context,
+	name string,
+	scopes []string,
+	expiresAt *time.Time,
+) (*apiKey, error) {
+	key, err := b.client.MintAPIKey(ctx, client.MintAPIKeyInput{Name: name, Scopes: scopes, ExpiresAt: expiresAt})
+	if err != nil {
+		return nil, err
+	}
+	return (*apiKey)(key), nil
+}
+
+func (b *apiBackend) ListAPIKeys(ctx context.Context) ([]*apiKey, error) {
+	keys, err := b.client.ListAPIKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*apiKey, len(keys))
+	for i, key := range keys {
+		result[i] = (*apiKey)(key)
+	}
+	return result, nil
+}
+
+func (b *apiBackend) RevokeAPIKey(ctx context.Context, id string) error {
+	return b.client.RevokeAPIKey(ctx, id)
+}
+
+// serviceBackend works on the configured repository through the application layer.
+type serviceBackend struct {
+	service app.UserService
+	apiKeys app.APIKeyService
+}
+
+// operator are the claims of a usersctl user working directly on the
+// database, who can do anything anyway.
+var operator = &app.Claims{Subject: "usersctl", Roles: []string{app.RoleAdmin}}
+
+func newRepositoryBackend(env *config.Environment, logger logger.Logger) (*serviceBackend, error) {
+	db, err := gorm.Open(postgres.Open(env.DB.ConnString()), &gorm.Config{})
+	if err != nil {
+		return nil, err
+	}
+
+	var userRepoOpts []repo.UserRepoOption
+	if env.DB.RowLevelSecurity {
+		userRepoOpts = append(userRepoOpts, repo.WithRowLevelSecurity())
+	}
+	userRepo, err := repo.NewUserRepo(db, userRepoOpts...)
+	if err != nil {
+		return nil, err
+	}
+	attributeRepo, err := repo.NewAttributeSchemaRepo(db)
+	if err != nil {
+		return nil, err
+	}
+	auditRepo, err := repo.NewAuditRepo(db)
+	if err != nil {
+		return nil, err
+	}
+	b := &serviceBackend{service: app.NewUserApp(userRepo, logger,
+		app.WithAttributeSchema(attributeRepo), app.WithAuditLog(auditRepo),
+		app.WithUserHistory(repo.NewUserHistoryRepo(db, userRepoOpts...)), app.WithTransactor(repo.NewTransactor(db)))}
+
+	if env.APIKeyPepper != "" {
+		apiKeyRepo, err := repo.NewAPIKeyRepo(db)
+		if err != nil {
+			return nil, err
+		}
+		b.apiKeys = app.NewAPIKeyApp(apiKeyRepo, logger, []byte(env.APIKeyPepper))
+	}
+
+	return b, nil
+}
+
+func (b *serviceBackend) List(ctx context.Context, name string) iter.Seq2[*user, error] {
+	return func(yield func(*user, error) bool) {
+		params := app.ListParams{Filter: app.UserFilter{NameContains: name}, Limit: app.MaxPageSize}
+		for {
+			page, err := b.service.List(ctx, params)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			for _, u := range page.Users {
+				if !yield(toUser(u), nil) {
+					return
+				}
+			}
+			if !page.HasNextPage {
+				return
+			}
+			params.After = page.Users[len(page.Users)-1].ID()
+		}
+	}
+}
+
+func (b *serviceBackend) Get(ctx context.Context, id string) (*user, error) {
+	u, err := b.service.GetUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return toUser(u), nil
+}
+
+func (b *serviceBackend) Create(ctx context.Context, name string) (*user, error) {
+	u, err := b.service.Create(ctx, domain.NewUser("", name))
+	if err != nil {
+		return nil, err
+	}
+	return toUser(u), nil
+}
+
+func (b *serviceBackend) Update(ctx context.Context, id, name string) error {
+	return b.service.Update(ctx, domain.NewUser(id, name))
+}
+
+func (b *serviceBackend) Delete(ctx context.Context, id string) error {
+	return b.service.Remove(ctx, id)
+}
+
+var errAPIKeysDisabled = errors.New("API_KEY_PEPPER is not configured")
+
+func (b *serviceBackend) MintAPIKey(
+	ctx context.Context,
+	name string,
+	scopes []string,
+	expiresAt *time.Time,
+) (*apiKey, error) {
+	if b.apiKeys == nil {
+		return nil, errAPIKeysDisabled
+	}
+
+	ctx = app.ContextWithClaims(ctx, operator)
+	params := app.MintAPIKeyParams{Name: name, Scopes: scopes, ExpiresAt: expiresAt}
+	key, secret, err := b.apiKeys.Mint(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	minted := toAPIKey(key)
+	minted.Key = secret
+	return minted, nil
+}
+
+func (b *serviceBackend) ListAPIKeys(ctx context.Context) ([]*apiKey, error) {
+	if b.apiKeys == nil {
+		return nil, errAPIKeysDisabled
+	}
+
+	keys, err := b.apiKeys.List(app.ContextWithClaims(ctx, operator))
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*apiKey, len(keys))
+	for i, key := range keys {
+		result[i] = toAPIKey(key)
+	}
+	return result, nil
+}
+
+func (b *serviceBackend) RevokeAPIKey(ctx context.Context, id string) error {
+	if b.apiKeys == nil {
+		return errAPIKeysDisabled
+	}
+	return b.apiKeys.Revoke(app.ContextWithClaims(ctx, operator), id)
+}
+
+func toAPIKey(k *app.APIKey) *apiKey {
+	return &apiKey{
+		ID:         k.ID,
+		Name:       k.Name,
+		Prefix:     k.Prefix,
+		Scopes:     k.Scopes,
+		CreatedAt:  k.CreatedAt,
+		ExpiresAt:  k.ExpiresAt,
+		LastUsedAt: k.LastUsedAt,
+		RevokedAt:  k.RevokedAt,
+	}
+}
+
+func toUser(u *domain.User) *user {
+	return &user{ID: u.ID(), Name: u.Name()}
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type command func(ctx context.Context, c *cli, args []string) error
+
+var commands = map[string]command{
+	"list":   listCmd,
+	"get":    getCmd,
+	"create": createCmd,
+	"update": updateCmd,
+	"delete": deleteCmd,
+	"import": importCmd,
+	"export": exportCmd,
+	"apikey": apiKeyCmd,
+}
+
+func (c *cli) flagSet(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet("usersctl "+name, flag.ContinueOnError)
+	flags.SetOutput(c.stderr)
+	return flags
+}
+
+// confirm asks a yes/no question on stderr and reads the answer from stdin.
+// Anything but "y" or "yes", including end of input, declines.
+func (c *cli) confirm(question string) error {
+	fmt.Fprintf(c.stderr, "%s [y/N] ", question)
+
+	answer, err := bufio.NewReader(c.stdin).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return nil
+	}
+	return errAborted
+}
+
+// collect drains the user iterator into a slice.
+func (c *cli) collect(ctx context.Context, name string) ([]*user, error) {
+	users := []*user{}
+	for u, err := range c.backend.List(ctx, name) {
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, nil
+}
+
+func listCmd(ctx context.Context, c *cli, args []string) error {
+	flags := c.flagSet("list")
+	name := flags.String("name", "", "keep users whose name contains the value, case-insensitively")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	users, err := c.collect(ctx, *name)
+	if err != nil {
+		return err
+	}
+	return write(c.stdout, c.format, users)
+}
+
+func getCmd(ctx context.Context, c *cli, args []string) error {
+	flags := c.flagSet("get")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		return errors.New("get takes at least one user ID")
+	}
+
+	users := make([]*user, 0, flags.NArg())
+	for _, id := range flags.Args() {
+		u, err := c.backend.Get(ctx, id)
+		if err != nil {
+			return fmt.Errorf("user %s: %w", id, err)
+		}
+		users = append(users, u)
+	}
+	// A single user is printed on its own, several as one list.
+	if len(users) == 1 {
+		return write(c.stdout, c.format, users[0])
+	}
+	return write(c.stdout, c.format, users)
+}
+
+func createCmd(ctx context.Context, c *cli, args []string) error {
+	flags := c.flagSet("create")
+	name := flags.String("name", "", "name of the new user")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *name == "" {
+		return errors.New("--name is required")
+	}
+
+	u, err := c.backend.Create(ctx, *name)
+	if err != nil {
+		return err
+	}
+	return write(c.stdout, c.format, u)
+}
+
+func updateCmd(ctx context.Context, c *cli, args []string) error {
+	flags := c.flagSet("update")
+	name := flags.String("name", "", "new name of the user")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("update takes exactly one user ID")
+	}
+	if *name == "" {
+		return errors.New("--name is required")
+	}
+
+	u := &user{ID: flags.Arg(0), Name: *name}
+	if err := c.backend.Update(ctx, u.ID, u.Name); err != nil {
+		return err
+	}
+	return write(c.stdout, c.format, u)
+}
+
+func deleteCmd(ctx context.Context, c *cli, args []string) error {
+	flags := c.flagSet("delete")
+	yes := flags.Bool("yes", false, "don't ask for confirmation")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		return errors.New("delete takes at least one user ID")
+	}
+
+	// Resolve every user first, so that nothing is deleted if an ID is wrong.
+	users := make([]*user, 0, flags.NArg())
+	for _, id := range flags.Args() {
+		u, err := c.backend.Get(ctx, id)
+		if err != nil {
+			return fmt.Errorf("user %s: %w", id, err)
+		}
+		users = append(users, u)
+	}
+
+	if !*yes {
+		if err := write(c.stderr, formatTable, users); err != nil {
+			return err
+		}
+		if err := c.confirm(fmt.Sprintf("Delete %d user(s)?", len(users))); err != nil {
+			return err
+		}
+	}
+
+	for _, u := range users {
+		if err := c.backend.Delete(ctx, u.ID); err != nil {
+			return fmt.Errorf("user %s: %w", u.ID, err)
+		}
+		fmt.Fprintf(c.stderr, "deleted %s\n", u.ID)
+	}
+	return nil
+}
+
+// importCmd creates the users of a file. Records that carry an ID update the
+// existing user instead, which is why the import asks for confirmation.
+func importCmd(ctx context.Context, c *cli, args []string) error {
+	flags := c.flagSet("import")
+	yes := flags.Bool("yes", false, "don't ask for confirmation")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 1 {
+		return errors.New("import takes at most one file")
+	}
+
+	input := c.stdin
+	if path := flags.Arg(0); path != "" && path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		input = file
+	} else if !*yes {
+		return errors.New("importing from stdin requires --yes")
+	}
+
+	users, err := read(input)
+	if err != nil {
+		return fmt.Errorf("parsing users: %w", err)
+	}
+
+	var creates, updates int
+	for i, u := range users {
+		if strings.TrimSpace(u.Name) == "" {
+			return fmt.Errorf("record %d: name is required", i+1)
+		}
+		if u.ID == "" {
+			creates++
+		} else {
+			updates++
+		}
+	}
+
+	if !*yes {
+		question := fmt.Sprintf("Create %d and update %d user(s)?", creates, updates)
+		if err := c.confirm(question); err != nil {
+			return err
+		}
+	}
+
+	imported := make([]*user, 0, len(users))
+	for i, u := range users {
+		if u.ID == "" {
+			created, err := c.backend.Create(ctx, u.Name)
+			if err != nil {
+				return fmt.Errorf("record %d: %w", i+1, err)
+			}
+			u = created
+		} else if err := c.backend.Update(ctx, u.ID, u.Name); err != nil {
+			return fmt.Errorf("record %d: %w", i+1, err)
+		}
+		imported = append(imported, u)
+	}
+
+	return write(c.stdout, c.format, imported)
+}
+
+// exportCmd writes users in a format import reads back: YAML when asked for
+// with -o yaml or a .yaml/.yml file, JSON otherwise.
+func exportCmd(ctx context.Context, c *cli, args []string) error {
+	flags := c.flagSet("export")
+	name := flags.String("name", "", "keep users whose name contains the value, case-insensitively")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 1 {
+		return errors.New("export takes at most one file")
+	}
+
+	format := formatJSON
+	path := flags.Arg(0)
+	if ext := filepath.Ext(path); c.format == formatYAML || ext == ".yaml" || ext == ".yml" {
+		format = formatYAML
+	}
+
+	users, err := c.collect(ctx, *name)
+	if err != nil {
+		return err
+	}
+
+	if path == "" || path == "-" {
+		return write(c.stdout, format, users)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(file, format, users); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.stderr, "exported %d user(s) to %s\n", len(users), path)
+	return nil
+}
@@ -0,0 +1,146 @@
+// Command usersctl manages users from the command line, either through the
+// HTTP API or directly on the configured repository.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	"github.com/Sergey-Polishchenko/simple-api/internal/config"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/logger"
+	"github.com/Sergey-Polishchenko/simple-api/pkg/client"
+)
+
+const usage = `usage: usersctl [flags] <command> [args]
+
+Commands:
+  list     [--name SUBSTR]              list users
+  get      ID...                        show users by ID, several as one list
+  create   --name NAME                  create a user
+  update   --name NAME ID               rename a user
+  delete   [--yes] ID...                delete users
+  import   [--yes] [FILE]               create users from a JSON or YAML file (stdin if omitted)
+  export   [--name SUBSTR] [FILE]       write users to a file (stdout if omitted)
+  apikey   create --name NAME --scopes SCOPE,... [--expires-in DURATION]
+  apikey   list
+  apikey   revoke [--yes] ID
+
+Flags:
+`
+
+// errAborted is returned when the user declines a confirmation prompt.
+var errAborted = errors.New("aborted")
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	err := run(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "usersctl:", err)
+		os.Exit(1)
+	}
+}
+
+// cli holds the global flags and streams shared by all commands.
+type cli struct {
+	backend backend
+	format  string
+	stdin   io.Reader
+	stdout  io.Writer
+	stderr  io.Writer
+}
+
+// run parses args and executes a single command.
+func run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("usersctl", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		flags.PrintDefaults()
+	}
+
+	apiURL := flags.String("api-url", os.Getenv("USERSCTL_API_URL"),
+		"base URL of the API; defaults to $USERSCTL_API_URL or localhost on the configured PORT")
+	token := flags.String("token", os.Getenv("USERSCTL_TOKEN"), "bearer token for the API; defaults to $USERSCTL_TOKEN")
+	key := flags.String("api-key", os.Getenv("USERSCTL_API_KEY"), "API key for the API; defaults to $USERSCTL_API_KEY")
+	tenant := flags.String("tenant", os.Getenv("USERSCTL_TENANT"), "tenant to work in; defaults to $USERSCTL_TENANT")
+	direct := flags.Bool("direct", false, "work on the configured database instead of the API")
+	format := flags.String("o", formatTable, "output format: table, json or yaml")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return errors.New("no command given")
+	}
+	if !validFormat(*format) {
+		return fmt.Errorf("unknown output format %q", *format)
+	}
+
+	command, ok := commands[flags.Arg(0)]
+	if !ok {
+		flags.Usage()
+		return fmt.Errorf("unknown command %q", flags.Arg(0))
+	}
+
+	apiURLSet := false
+	flags.Visit(func(f *flag.Flag) { apiURLSet = apiURLSet || f.Name == "api-url" })
+	if *direct && apiURLSet {
+		return errors.New("--direct and --api-url are mutually exclusive")
+	}
+
+	var auth client.Authenticator
+	switch {
+	case *token != "" && *key != "":
+		return errors.New("--token and --api-key are mutually exclusive")
+	case *token != "":
+		auth = client.BearerToken(*token)
+	case *key != "":
+		auth = client.APIKey(*key)
+	}
+
+	b, err := newBackend(*apiURL, auth, *tenant, *direct)
+	if err != nil {
+		return err
+	}
+	if *direct && *tenant != "" {
+		ctx = app.ContextWithTenant(ctx, *tenant)
+	}
+
+	c := &cli{backend: b, format: *format, stdin: stdin, stdout: stdout, stderr: stderr}
+	return command(ctx, c, flags.Args()[1:])
+}
+
+// newBackend picks the API or the repository. The configuration is loaded
+// unless an API URL makes it unnecessary.
+func newBackend(apiURL string, auth client.Authenticator, tenant string, direct bool) (backend, error) {
+	if !direct && apiURL != "" {
+		return newAPIBackend(apiURL, auth, tenant)
+	}
+
+	env, err := config.Load()
+	if err != nil {
+		return nil, fmt.Errorf("loading configuration: %w", err)
+	}
+
+	if direct {
+		log, err := logger.New(logger.Config{Level: env.Log.Level, Format: env.Log.Format})
+		if err != nil {
+			return nil, fmt.Errorf("configuring logging: %w", err)
+		}
+		return newRepositoryBackend(env, log)
+	}
+	return newAPIBackend("http://localhost:"+env.Port, auth, tenant)
+}
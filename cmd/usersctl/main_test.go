@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	"github.com/Sergey-Polishchenko/simple-api/internal/infrastructure/memory"
+	httpapi "github.com/Sergey-Polishchenko/simple-api/internal/interfaces/http"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/logger"
+)
+
+func newTestServer(t *testing.T) string {
+	gin.SetMode(gin.TestMode)
+
+	logger := logger.NewZapLogger()
+	service := app.NewUserApp(memory.NewUserRepo(), logger)
+
+	router, err := httpapi.NewRouter(service, logger, httpapi.Config{EnforceContract: true})
+	require.NoError(t, err)
+
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+// usersctl runs a command against url and returns its stdout.
+func usersctl(t *testing.T, url, stdin string, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	args = append([]string{"--api-url", url}, args...)
+	err := run(context.Background(), args, strings.NewReader(stdin), &stdout, &stderr)
+	return stdout.String(), err
+}
+
+func TestUsersctl_Lifecycle(t *testing.T) {
+	url := newTestServer(t)
+
+	out, err := usersctl(t, url, "", "-o", "json", "create", "--name", "John")
+	require.NoError(t, err)
+
+	var created user
+	require.NoError(t, json.Unmarshal([]byte(out), &created))
+	assert.Equal(t, "John", created.Name)
+
+	_, err = usersctl(t, url, "", "update", "--name", "Jane", created.ID)
+	require.NoError(t, err)
+
+	out, err = usersctl(t, url, "", "-o", "yaml", "get", created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "id: "+created.ID+"\nname: Jane\n", out)
+
+	other, err := usersctl(t, url, "", "-o", "json", "create", "--name", "Jim")
+	require.NoError(t, err)
+	var second user
+	require.NoError(t, json.Unmarshal([]byte(other), &second))
+
+	out, err = usersctl(t, url, "", "get", created.ID, second.ID)
+	require.NoError(t, err)
+	assert.Regexp(t, `^ID\s+NAME\n`+created.ID+`\s+Jane\n`+second.ID+`\s+Jim\n$`, out)
+
+	out, err = usersctl(t, url, "", "-o", "json", "get", created.ID, second.ID)
+	require.NoError(t, err)
+	var got []*user
+	require.NoError(t, json.Unmarshal([]byte(out), &got))
+	assert.Equal(t, []*user{{ID: created.ID, Name: "Jane"}, &second}, got)
+
+	_, err = usersctl(t, url, "", "get", created.ID, "missing")
+	assert.Error(t, err)
+
+	out, err = usersctl(t, url, "", "list", "--name", "jan")
+	require.NoError(t, err)
+	assert.Regexp(t, `^ID\s+NAME\n`+created.ID+`\s+Jane\n$`, out)
+
+	_, err = usersctl(t, url, "n\n", "delete", created.ID)
+	assert.ErrorIs(t, err, errAborted)
+
+	_, err = usersctl(t, url, "y\n", "delete", created.ID)
+	require.NoError(t, err)
+
+	_, err = usersctl(t, url, "", "get", created.ID)
+	assert.Error(t, err)
+}
+
+func TestUsersctl_ImportExport(t *testing.T) {
+	url := newTestServer(t)
+	dir := t.TempDir()
+
+	input := filepath.Join(dir, "users.yaml")
+	require.NoError(t, os.WriteFile(input, []byte("- name: John\n- name: Jane\n"), 0o600))
+
+	_, err := usersctl(t, url, "", "import", input)
+	assert.ErrorIs(t, err, errAborted, "import asks for confirmation")
+
+	_, err = usersctl(t, url, "", "import", "--yes", input)
+	require.NoError(t, err)
+
+	output := filepath.Join(dir, "export.json")
+	_, err = usersctl(t, url, "", "export", output)
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(output)
+	require.NoError(t, err)
+
+	var exported []*user
+	require.NoError(t, json.Unmarshal(data, &exported))
+	require.Len(t, exported, 2)
+
+	names := []string{exported[0].Name, exported[1].Name}
+	assert.ElementsMatch(t, []string{"John", "Jane"}, names)
+
+	// Exported records carry IDs, so importing them again updates in place.
+	exported[0].Name = "Jack"
+	data, err = json.Marshal(exported)
+	require.NoError(t, err)
+
+	_, err = usersctl(t, url, string(data), "import", "--yes")
+	require.NoError(t, err)
+
+	out, err := usersctl(t, url, "", "-o", "json", "list")
+	require.NoError(t, err)
+
+	var listed []*user
+	require.NoError(t, json.Unmarshal([]byte(out), &listed))
+	assert.Len(t, listed, 2)
+	assert.Contains(t, listed, &user{ID: exported[0].ID, Name: "Jack"})
+}
+
+func TestUsersctl_Usage(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{name: "No command", args: nil},
+		{name: "Unknown command", args: []string{"frobnicate"}},
+		{name: "Unknown format", args: []string{"-o", "xml", "list"}},
+		{name: "Direct with API URL", args: []string{"--direct", "list"}},
+		{name: "Create without name", args: []string{"create"}},
+		{name: "Get without ID", args: []string{"get"}},
+		{name: "Stdin import without --yes", args: []string{"import"}},
+	}
+
+	url := newTestServer(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := usersctl(t, url, "", tt.args...)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestUsersctl_APIKeys(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	logger := logger.NewZapLogger()
+	apiKeys := app.NewAPIKeyApp(memory.NewAPIKeyRepo(), logger, []byte("0123456789abcdef0123456789abcdef"))
+	router, err := httpapi.NewRouter(app.NewUserApp(memory.NewUserRepo(), logger), logger, httpapi.Config{APIKeys: apiKeys})
+	require.NoError(t, err)
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	admin := app.ContextWithClaims(context.Background(), &app.Claims{Roles: []string{app.RoleAdmin}})
+	_, adminKey, err := apiKeys.Mint(admin, app.MintAPIKeyParams{Name: "bootstrap", Scopes: []string{app.ScopeUsersAdmin}})
+	require.NoError(t, err)
+
+	out, err := usersctl(t, server.URL, "", "--api-key", adminKey, "-o", "json",
+		"apikey", "create", "--name", "reporting", "--scopes", "users:read", "--expires-in", "24h")
+	require.NoError(t, err)
+
+	var minted apiKey
+	require.NoError(t, json.Unmarshal([]byte(out), &minted))
+	assert.NotEmpty(t, minted.Key)
+	assert.NotNil(t, minted.ExpiresAt)
+
+	_, err = usersctl(t, server.URL, "", "--api-key", minted.Key, "list")
+	require.NoError(t, err)
+	_, err = usersctl(t, server.URL, "", "--api-key", minted.Key, "create", "--name", "John")
+	assert.Error(t, err, "the key is read-only")
+
+	out, err = usersctl(t, server.URL, "", "--api-key", adminKey, "apikey", "list")
+	require.NoError(t, err)
+	assert.Regexp(t, `reporting\s+`+minted.Prefix+`\s+users:read\s+.*\s+active\n`, out)
+
+	_, err = usersctl(t, server.URL, "y\n", "--api-key", adminKey, "apikey", "revoke", minted.ID)
+	require.NoError(t, err)
+
+	_, err = usersctl(t, server.URL, "", "--api-key", minted.Key, "list")
+	assert.Error(t, err, "the key is revoked")
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+	formatYAML  = "yaml"
+)
+
+func validFormat(format string) bool {
+	switch format {
+	case formatTable, formatJSON, formatYAML:
+		return true
+	}
+	return false
+}
+
+// write prints v, a *user, []*user, *apiKey or []*apiKey, in the given format.
+func write(w io.Writer, format string, v interface{}) error {
+	switch format {
+	case formatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(v)
+	case formatYAML:
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(v); err != nil {
+			return err
+		}
+		return encoder.Close()
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	switch v := v.(type) {
+	case *user:
+		writeUsers(tw, []*user{v})
+	case []*user:
+		writeUsers(tw, v)
+	case *apiKey:
+		writeAPIKeys(tw, []*apiKey{v})
+		if v.Key != "" {
+			fmt.Fprintf(tw, "\nKEY\t%s\n", v.Key)
+		}
+	case []*apiKey:
+		writeAPIKeys(tw, v)
+	default:
+		return fmt.Errorf("can't print %T as a table", v)
+	}
+	return tw.Flush()
+}
+
+func writeUsers(w io.Writer, users []*user) {
+	fmt.Fprintln(w, "ID\tNAME")
+	for _, u := range users {
+		fmt.Fprintf(w, "%s\t%s\n", u.ID, u.Name)
+	}
+}
+
+func writeAPIKeys(w io.Writer, keys []*apiKey) {
+	fmt.Fprintln(w, "ID\tNAME\tPREFIX\tSCOPES\tEXPIRES\tLAST USED\tSTATUS")
+	for _, k := range keys {
+		status := "active"
+		switch {
+		case k.RevokedAt != nil:
+			status = "revoked"
+		case k.ExpiresAt != nil && k.ExpiresAt.Before(time.Now()):
+			status = "expired"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			k.ID, k.Name, k.Prefix, strings.Join(k.Scopes, ","),
+			formatTime(k.ExpiresAt, "never"), formatTime(k.LastUsedAt, "never"), status)
+	}
+}
+
+func formatTime(t *time.Time, zero string) string {
+	if t == nil {
+		return zero
+	}
+	return t.Local().Format(time.DateTime)
+}
+
+// read decodes a list of users from JSON or YAML; JSON is valid YAML.
+func read(r io.Reader) ([]*user, error) {
+	var users []*user
+	if err := yaml.NewDecoder(r).Decode(&users); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return users, nil
+}
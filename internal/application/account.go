@@ -0,0 +1,434 @@
+package app
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Sergey-Polishchenko/simple-api/internal/domain"
+	perrors "github.com/Sergey-Polishchenko/simple-api/internal/pkg/errors"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/logger"
+)
+
+// Templates of the emails sent by AccountApp. Their data holds "Name",
+// "Token", "Link", empty unless a page completing the flow is configured,
+// and "ExpiresIn".
+const (
+	TemplateVerifyEmail   = "verify-email"
+	TemplateResetPassword = "reset-password"
+)
+
+const (
+	// maxAccountEmails emails of a flow are sent to a user within
+	// accountEmailWindow, so that the public endpoints can't flood inboxes.
+	maxAccountEmails   = 3
+	accountEmailWindow = time.Hour
+	// accountEmailTimeout bounds the delivery of an email.
+	accountEmailTimeout = 30 * time.Second
+)
+
+// Email is a message rendered from a template.
+type Email struct {
+	To       string
+	Template string
+	Data     map[string]string
+}
+
+// Mailer delivers emails.
+type Mailer interface {
+	Send(ctx context.Context, email Email) error
+}
+
+// UsedTokenRepository keeps the IDs of used single-use tokens until they expire.
+type UsedTokenRepository interface {
+	// Records a token as used, reporting whether it was unused.
+	Use(ctx context.Context, id string, expiresAt time.Time) (bool, error)
+}
+
+// AccountConfig configures AccountApp.
+type AccountConfig struct {
+	// Secret signs the tokens; it must be at least 32 bytes long.
+	Secret           []byte
+	VerificationTTL  time.Duration
+	PasswordResetTTL time.Duration
+	// VerifyEmailURL and ResetPasswordURL are pages completing the flows,
+	// which get the token as the "token" query parameter. Without them
+	// emails carry the bare token.
+	VerifyEmailURL   string
+	ResetPasswordURL string
+}
+
+// AccountService defines the email verification and password reset flows.
+// Requests succeed whether an account exists or not, so that they don't
+// reveal it; the email is sent in the background.
+type AccountService interface {
+	// Sends a verification token to the address, if it belongs to a user
+	// who has not verified it yet.
+	RequestEmailVerification(ctx context.Context, email string) error
+	// Marks the email address a token was sent to verified.
+	VerifyEmail(ctx context.Context, token string) error
+	// Sends a password reset token to the address, if it is the verified
+	// address of a user.
+	RequestPasswordReset(ctx context.Context, email string) error
+	// Sets the password of the user a reset token was sent to.
+	ResetPassword(ctx context.Context, token, password string) error
+}
+
+// AccountApp implements AccountService with signed tokens, which are
+// recorded as used so that each works once. Emails are counted per user in
+// memory, like failed MFA codes. A password reset ends the sessions of the
+// user, unless sessions is nil because password login is disabled.
+type AccountApp struct {
+	users    UserRepository
+	used     UsedTokenRepository
+	sessions RefreshTokenRepository
+	audit    AuditRepository
+	tx       Transactor
+	mailer   Mailer
+	hasher   PasswordHasher
+	logger   logger.Logger
+	config   AccountConfig
+	now      func() time.Time
+	limiter  *attemptLimiter
+}
+
+// AccountOption configures optional AccountApp dependencies.
+type AccountOption func(*AccountApp)
+
+// WithAccountAuditLog makes AccountApp record verified addresses and
+// password resets in the log.
+func WithAccountAuditLog(log AuditRepository) AccountOption {
+	return func(app *AccountApp) {
+		app.audit = log
+	}
+}
+
+// WithAccountTransactor makes AccountApp record the changes in the audit
+// log in the transaction making them, like WithTransactor does for UserApp.
+func WithAccountTransactor(tx Transactor) AccountOption {
+	return func(app *AccountApp) {
+		app.tx = tx
+	}
+}
+
+// NewAccountApp initializes an AccountApp instance.
+func NewAccountApp(
+	users UserRepository,
+	used UsedTokenRepository,
+	sessions RefreshTokenRepository,
+	mailer Mailer,
+	hasher PasswordHasher,
+	logger logger.Logger,
+	config AccountConfig,
+	opts ...AccountOption,
+) (AccountService, error) {
+	if len(config.Secret) < 32 {
+		return nil, errors.New("account token secret must be at least 32 bytes long")
+	}
+	if config.VerificationTTL <= 0 || config.PasswordResetTTL <= 0 {
+		return nil, errors.New("account token lifetimes must be positive")
+	}
+
+	app := &AccountApp{
+		users:    users,
+		used:     used,
+		sessions: sessions,
+		mailer:   mailer,
+		hasher:   hasher,
+		logger:   logger,
+		config:   config,
+		now:      time.Now,
+		limiter:  newAttemptLimiter(maxAccountEmails, accountEmailWindow),
+	}
+	for _, opt := range opts {
+		opt(app)
+	}
+	return app, nil
+}
+
+// accountToken is the payload of a token; Email binds it to the address
+// it was sent to.
+type accountToken struct {
+	ID        string `json:"jti"`
+	Purpose   string `json:"pur"`
+	UserID    string `json:"sub"`
+	Email     string `json:"email"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+func (app *AccountApp) RequestEmailVerification(ctx context.Context, email string) error {
+	user, err := app.findUser(ctx, email)
+	if err != nil || user == nil || user.EmailVerified() {
+		return err
+	}
+
+	app.send(ctx, user, TemplateVerifyEmail, app.config.VerificationTTL, app.config.VerifyEmailURL)
+	return nil
+}
+
+func (app *AccountApp) VerifyEmail(ctx context.Context, token string) error {
+	claims, err := app.useToken(ctx, token, TemplateVerifyEmail)
+	if err != nil {
+		return err
+	}
+
+	err = inTransaction(ctx, app.tx, func(ctx context.Context) error {
+		verified, err := app.users.VerifyEmail(ctx, claims.UserID, claims.Email)
+		if err != nil {
+			app.logger.WithContext(ctx).Error("can't verify email", "error", err)
+			return err
+		}
+		if !verified {
+			return perrors.ErrInvalidAccountToken
+		}
+
+		changes := []AuditChange{{Field: "email_verified", Before: false, After: true}}
+		return app.record(ctx, AuditUserEmailVerified, claims.UserID, changes)
+	})
+	if err != nil {
+		return err
+	}
+
+	app.logger.WithContext(ctx).Info("Email verified", "user_id", claims.UserID)
+
+	return nil
+}
+
+func (app *AccountApp) RequestPasswordReset(ctx context.Context, email string) error {
+	user, err := app.findUser(ctx, email)
+	// Unverified addresses may belong to someone else, who must not get
+	// to reset the password.
+	if err != nil || user == nil || !user.EmailVerified() {
+		return err
+	}
+
+	app.send(ctx, user, TemplateResetPassword, app.config.PasswordResetTTL, app.config.ResetPasswordURL)
+	return nil
+}
+
+func (app *AccountApp) ResetPassword(ctx context.Context, token, password string) error {
+	// Check the password first, so that a rejected one doesn't use up the token.
+	if err := validatePassword(password); err != nil {
+		return err
+	}
+
+	claims, err := app.useToken(ctx, token, TemplateResetPassword)
+	if err != nil {
+		return err
+	}
+
+	hash, err := app.hasher.Hash(password)
+	if err != nil {
+		app.logger.WithContext(ctx).Error("can't hash password", "error", err)
+		return err
+	}
+	err = inTransaction(ctx, app.tx, func(ctx context.Context) error {
+		if err := app.users.SetPasswordHash(ctx, claims.UserID, hash); err != nil {
+			if errors.Is(err, perrors.ErrUserNotFound) {
+				return perrors.ErrInvalidAccountToken
+			}
+			app.logger.WithContext(ctx).Error("can't set password", "error", err)
+			return err
+		}
+
+		// Whoever got hold of the old password may have signed in with it.
+		if app.sessions != nil {
+			if err := app.sessions.RevokeByUser(ctx, claims.UserID, "", app.now().UTC()); err != nil {
+				app.logger.WithContext(ctx).Error("can't revoke refresh tokens", "error", err)
+				return err
+			}
+		}
+
+		return app.record(ctx, AuditUserPasswordReset, claims.UserID, nil)
+	})
+	if err != nil {
+		return err
+	}
+
+	app.logger.WithContext(ctx).Info("Password reset", "user_id", claims.UserID)
+
+	return nil
+}
+
+// record appends an entry for a change of a user to the audit log, like
+// UserApp does. The token proves the user holds the address, so they are
+// recorded as the actor.
+func (app *AccountApp) record(ctx context.Context, action AuditAction, userID string, changes []AuditChange) error {
+	if app.audit == nil {
+		return nil
+	}
+	ctx = ContextWithClaims(ctx, &Claims{Subject: userID, TenantID: TenantFromContext(ctx)})
+	if err := appendAuditEntry(ctx, app.audit, action, userID, changes); err != nil {
+		app.logger.WithContext(ctx).Error("can't record audit entry", "action", action, "user_id", userID, "error", err)
+		return err
+	}
+	return nil
+}
+
+// findUser returns the user with the email address, or nil if there is none.
+func (app *AccountApp) findUser(ctx context.Context, email string) (*domain.User, error) {
+	email, err := normalizeEmail(email)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := app.users.GetByEmail(ctx, email)
+	if errors.Is(err, perrors.ErrUserNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		app.logger.WithContext(ctx).Error("can't retrive user by email", "error", err)
+		return nil, err
+	}
+	return user, nil
+}
+
+// send mails a token for the flow to the user in the background, so that
+// the response takes as long whether the account exists or not.
+func (app *AccountApp) send(ctx context.Context, user *domain.User, purpose string, ttl time.Duration, page string) {
+	now := app.now()
+	key := purpose + ":" + user.ID()
+	if !app.limiter.allow(key, now) {
+		app.logger.WithContext(ctx).Info("Account email throttled", "user_id", user.ID(), "template", purpose)
+		return
+	}
+	// Every email counts like a failed attempt would.
+	app.limiter.fail(key, now)
+
+	token, err := app.sign(accountToken{
+		ID:        uuid.New().String(),
+		Purpose:   purpose,
+		UserID:    user.ID(),
+		Email:     user.Email(),
+		ExpiresAt: now.Add(ttl).Unix(),
+	})
+	if err != nil {
+		app.logger.WithContext(ctx).Error("can't sign account token", "error", err)
+		return
+	}
+
+	email := Email{
+		To:       user.Email(),
+		Template: purpose,
+		Data: map[string]string{
+			"Name":      user.Name(),
+			"Token":     token,
+			"Link":      tokenLink(page, token),
+			"ExpiresIn": formatTTL(ttl),
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), accountEmailTimeout)
+	go func() {
+		defer cancel()
+		if err := app.mailer.Send(ctx, email); err != nil {
+			app.logger.WithContext(ctx).Error("can't send email", "user_id", user.ID(), "template", purpose, "error", err)
+			return
+		}
+		app.logger.WithContext(ctx).Info("Account email sent", "user_id", user.ID(), "template", purpose)
+	}()
+}
+
+// formatTTL spells out a lifetime for the emails, e.g. "24 hours".
+func formatTTL(ttl time.Duration) string {
+	n, unit := int(ttl/time.Minute), "minute"
+	if ttl%time.Hour == 0 {
+		n, unit = int(ttl/time.Hour), "hour"
+	}
+	if n != 1 {
+		unit += "s"
+	}
+	return fmt.Sprintf("%d %s", n, unit)
+}
+
+// tokenLink appends the token to the page, if there is one.
+func tokenLink(page, token string) string {
+	if page == "" {
+		return ""
+	}
+	u, err := url.Parse(page)
+	if err != nil {
+		return ""
+	}
+	query := u.Query()
+	query.Set("token", token)
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// useToken verifies a token of the flow and records it as used. Tokens
+// sent to an address the user has no more are rejected.
+func (app *AccountApp) useToken(ctx context.Context, token, purpose string) (*accountToken, error) {
+	claims, ok := app.verify(token)
+	if !ok || claims.Purpose != purpose || app.now().Unix() >= claims.ExpiresAt {
+		return nil, perrors.ErrInvalidAccountToken
+	}
+
+	user, err := app.users.GetByID(ctx, claims.UserID)
+	if errors.Is(err, perrors.ErrUserNotFound) {
+		return nil, perrors.ErrInvalidAccountToken
+	}
+	if err != nil {
+		app.logger.WithContext(ctx).Error("can't retrive user", "error", err)
+		return nil, err
+	}
+	if user.Email() != claims.Email {
+		return nil, perrors.ErrInvalidAccountToken
+	}
+
+	unused, err := app.used.Use(ctx, claims.ID, time.Unix(claims.ExpiresAt, 0))
+	if err != nil {
+		app.logger.WithContext(ctx).Error("can't record account token use", "error", err)
+		return nil, err
+	}
+	if !unused {
+		return nil, perrors.ErrInvalidAccountToken
+	}
+	return claims, nil
+}
+
+// sign returns a token of the form "<payload>.<signature>", both base64url-encoded.
+func (app *AccountApp) sign(claims accountToken) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(app.mac(encoded)), nil
+}
+
+func (app *AccountApp) verify(token string) (*accountToken, bool) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, false
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, app.mac(encoded)) {
+		return nil, false
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, false
+	}
+	claims := &accountToken{}
+	if err := json.Unmarshal(payload, claims); err != nil {
+		return nil, false
+	}
+	return claims, true
+}
+
+func (app *AccountApp) mac(payload string) []byte {
+	mac := hmac.New(sha256.New, app.config.Secret)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
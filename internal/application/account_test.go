@@ -0,0 +1,250 @@
+package app_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	"github.com/Sergey-Polishchenko/simple-api/internal/domain"
+	"github.com/Sergey-Polishchenko/simple-api/internal/infrastructure/memory"
+	perrors "github.com/Sergey-Polishchenko/simple-api/internal/pkg/errors"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/logger"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/password"
+)
+
+// chanMailer hands the emails, which AccountApp sends in the background,
+// over to the test.
+type chanMailer chan app.Email
+
+func (m chanMailer) Send(_ context.Context, email app.Email) error {
+	m <- email
+	return nil
+}
+
+func (m chanMailer) receive(t *testing.T) app.Email {
+	t.Helper()
+
+	select {
+	case email := <-m:
+		return email
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "no email sent")
+		return app.Email{}
+	}
+}
+
+func (m chanMailer) none(t *testing.T) {
+	t.Helper()
+
+	select {
+	case email := <-m:
+		assert.Failf(t, "unexpected email", "sent to %s", email.To)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+type accountFixture struct {
+	accounts app.AccountService
+	auth     app.AuthService
+	users    app.UserRepository
+	audit    *memory.AuditRepo
+	mailer   chanMailer
+	hasher   app.PasswordHasher
+}
+
+// newAccountFixture serves user "1" with the unverified address
+// john@example.com and user "2" with the verified address jane@example.com.
+func newAccountFixture(t *testing.T) *accountFixture {
+	t.Helper()
+
+	ctx := context.Background()
+	users := memory.NewUserRepo()
+	require.NoError(t, users.Create(ctx, domain.NewUser("1", "John").WithEmail("john@example.com", false)))
+	require.NoError(t, users.Create(ctx, domain.NewUser("2", "Jane").WithEmail("jane@example.com", false)))
+	verified, err := users.VerifyEmail(ctx, "2", "jane@example.com")
+	require.NoError(t, err)
+	require.True(t, verified)
+
+	mailer := make(chanMailer, 10)
+	hasher := password.NewHasher(cheapParams)
+	sessions := memory.NewRefreshTokenRepo()
+	auth, err := app.NewAuthApp(users, sessions, hasher, jsonSigner{}, logger.NewZapLogger(), app.AuthConfig{
+		AccessTokenTTL:  15 * time.Minute,
+		RefreshTokenTTL: time.Hour,
+	})
+	require.NoError(t, err)
+	audit := memory.NewAuditRepo()
+	accounts, err := app.NewAccountApp(users, memory.NewUsedTokenRepo(), sessions, mailer, hasher, logger.NewZapLogger(), app.AccountConfig{
+		Secret:           []byte("0123456789abcdef0123456789abcdef"),
+		VerificationTTL:  24 * time.Hour,
+		PasswordResetTTL: time.Hour,
+		ResetPasswordURL: "https://example.com/reset?lang=en",
+	}, app.WithAccountAuditLog(audit))
+	require.NoError(t, err)
+
+	return &accountFixture{accounts: accounts, auth: auth, users: users, audit: audit, mailer: mailer, hasher: hasher}
+}
+
+func TestNewAccountApp(t *testing.T) {
+	tests := []struct {
+		name   string
+		config app.AccountConfig
+	}{
+		{"short secret", app.AccountConfig{Secret: []byte("secret"), VerificationTTL: time.Hour, PasswordResetTTL: time.Hour}},
+		{"no verification TTL", app.AccountConfig{Secret: make([]byte, 32), PasswordResetTTL: time.Hour}},
+		{"no password reset TTL", app.AccountConfig{Secret: make([]byte, 32), VerificationTTL: time.Hour}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := app.NewAccountApp(memory.NewUserRepo(), memory.NewUsedTokenRepo(), nil, make(chanMailer), nil, logger.NewZapLogger(), tt.config)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestAccountApp_VerifyEmail(t *testing.T) {
+	f := newAccountFixture(t)
+	ctx := context.Background()
+
+	require.NoError(t, f.accounts.RequestEmailVerification(ctx, " John@Example.com "))
+	email := f.mailer.receive(t)
+	assert.Equal(t, "john@example.com", email.To)
+	assert.Equal(t, app.TemplateVerifyEmail, email.Template)
+	assert.Equal(t, "John", email.Data["Name"])
+	assert.Equal(t, "24 hours", email.Data["ExpiresIn"])
+	assert.Empty(t, email.Data["Link"], "no page is configured")
+
+	token := email.Data["Token"]
+	assert.ErrorIs(t, f.accounts.ResetPassword(ctx, token, "battery staple"), perrors.ErrInvalidAccountToken, "wrong purpose")
+	assert.ErrorIs(t, f.accounts.VerifyEmail(ctx, token+"x"), perrors.ErrInvalidAccountToken, "bad signature")
+
+	require.NoError(t, f.accounts.VerifyEmail(ctx, token))
+	user, err := f.users.GetByID(ctx, "1")
+	require.NoError(t, err)
+	assert.True(t, user.EmailVerified())
+
+	assert.ErrorIs(t, f.accounts.VerifyEmail(ctx, token), perrors.ErrInvalidAccountToken, "tokens work once")
+
+	require.NoError(t, f.accounts.RequestEmailVerification(ctx, "john@example.com"))
+	f.mailer.none(t)
+}
+
+func TestAccountApp_NoEmails(t *testing.T) {
+	f := newAccountFixture(t)
+	ctx := context.Background()
+
+	require.NoError(t, f.accounts.RequestEmailVerification(ctx, "nobody@example.com"))
+	require.NoError(t, f.accounts.RequestEmailVerification(ctx, "jane@example.com"), "verified already")
+	require.NoError(t, f.accounts.RequestPasswordReset(ctx, "nobody@example.com"))
+	require.NoError(t, f.accounts.RequestPasswordReset(ctx, "john@example.com"), "unverified")
+	f.mailer.none(t)
+
+	var invalid *app.InvalidEmailError
+	assert.ErrorAs(t, f.accounts.RequestPasswordReset(ctx, "John <john@example.com>"), &invalid)
+}
+
+func TestAccountApp_ResetPassword(t *testing.T) {
+	f := newAccountFixture(t)
+	ctx := context.Background()
+
+	require.NoError(t, f.accounts.RequestPasswordReset(ctx, "jane@example.com"))
+	email := f.mailer.receive(t)
+	assert.Equal(t, app.TemplateResetPassword, email.Template)
+	assert.Equal(t, "1 hour", email.Data["ExpiresIn"])
+	assert.Equal(t, "https://example.com/reset?lang=en&token="+email.Data["Token"], email.Data["Link"])
+
+	token := email.Data["Token"]
+	var invalid *app.InvalidPasswordError
+	assert.ErrorAs(t, f.accounts.ResetPassword(ctx, token, "short"), &invalid)
+
+	require.NoError(t, f.accounts.ResetPassword(ctx, token, "battery staple"), "a rejected password doesn't use the token")
+	user, err := f.users.GetByID(ctx, "2")
+	require.NoError(t, err)
+	match, _, err := f.hasher.Verify("battery staple", user.PasswordHash())
+	require.NoError(t, err)
+	assert.True(t, match)
+
+	assert.ErrorIs(t, f.accounts.ResetPassword(ctx, token, "correct horse"), perrors.ErrInvalidAccountToken)
+}
+
+func TestAccountApp_ResetPasswordEndsSessions(t *testing.T) {
+	f := newAccountFixture(t)
+	ctx := context.Background()
+
+	hash, err := f.hasher.Hash("correct horse")
+	require.NoError(t, err)
+	require.NoError(t, f.users.SetPasswordHash(ctx, "2", hash))
+	first, err := f.auth.Login(ctx, app.Credentials{UserID: "2", Password: "correct horse"})
+	require.NoError(t, err)
+	second, err := f.auth.Login(ctx, app.Credentials{UserID: "2", Password: "correct horse"})
+	require.NoError(t, err)
+
+	require.NoError(t, f.accounts.RequestPasswordReset(ctx, "jane@example.com"))
+	require.NoError(t, f.accounts.ResetPassword(ctx, f.mailer.receive(t).Data["Token"], "battery staple"))
+
+	_, err = f.auth.Refresh(ctx, first.RefreshToken)
+	assert.ErrorIs(t, err, perrors.ErrInvalidRefreshToken)
+	_, err = f.auth.Refresh(ctx, second.RefreshToken)
+	assert.ErrorIs(t, err, perrors.ErrInvalidRefreshToken)
+
+	_, err = f.auth.Login(ctx, app.Credentials{UserID: "2", Password: "battery staple"})
+	assert.NoError(t, err)
+}
+
+func TestAccountApp_AuditLog(t *testing.T) {
+	f := newAccountFixture(t)
+	ctx := context.Background()
+
+	require.NoError(t, f.accounts.RequestEmailVerification(ctx, "john@example.com"))
+	require.NoError(t, f.accounts.VerifyEmail(ctx, f.mailer.receive(t).Data["Token"]))
+	require.NoError(t, f.accounts.RequestPasswordReset(ctx, "jane@example.com"))
+	require.NoError(t, f.accounts.ResetPassword(ctx, f.mailer.receive(t).Data["Token"], "battery staple"))
+
+	entries, err := f.audit.List(ctx, app.AuditListParams{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	reset, verified := entries[0], entries[1]
+	assert.Equal(t, app.AuditUserPasswordReset, reset.Action)
+	assert.Equal(t, "2", reset.TargetID)
+	assert.Equal(t, "user", reset.ActorKind)
+	assert.Equal(t, "2", reset.ActorID)
+	assert.Empty(t, reset.Changes)
+
+	assert.Equal(t, app.AuditUserEmailVerified, verified.Action)
+	assert.Equal(t, "1", verified.TargetID)
+	assert.Equal(t, "1", verified.ActorID)
+	assert.Equal(t, []app.AuditChange{{Field: "email_verified", Before: false, After: true}}, verified.Changes)
+}
+
+func TestAccountApp_EmailChangeInvalidatesTokens(t *testing.T) {
+	f := newAccountFixture(t)
+	ctx := context.Background()
+
+	require.NoError(t, f.accounts.RequestPasswordReset(ctx, "jane@example.com"))
+	token := f.mailer.receive(t).Data["Token"]
+
+	require.NoError(t, f.users.SetEmail(ctx, "2", "jane@example.org"))
+	assert.ErrorIs(t, f.accounts.ResetPassword(ctx, token, "battery staple"), perrors.ErrInvalidAccountToken)
+}
+
+func TestAccountApp_Throttling(t *testing.T) {
+	f := newAccountFixture(t)
+	ctx := context.Background()
+
+	for range 3 {
+		require.NoError(t, f.accounts.RequestEmailVerification(ctx, "john@example.com"))
+		f.mailer.receive(t)
+	}
+
+	require.NoError(t, f.accounts.RequestEmailVerification(ctx, "john@example.com"))
+	f.mailer.none(t)
+
+	require.NoError(t, f.accounts.RequestPasswordReset(ctx, "jane@example.com"))
+	f.mailer.receive(t)
+}
@@ -0,0 +1,268 @@
+package app
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	perrors "github.com/Sergey-Polishchenko/simple-api/internal/pkg/errors"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/logger"
+)
+
+const (
+	// apiKeyPrefix makes keys recognisable, e.g. by secret scanners.
+	apiKeyPrefix = "sak"
+	// lastUsedPrecision limits how often authentication writes the last use of a key.
+	lastUsedPrecision = time.Minute
+)
+
+// APIKey is a credential of a machine-to-machine caller. Only a hash of the
+// secret is kept; the key itself is shown once, when it is minted.
+type APIKey struct {
+	ID string
+	// TenantID is the tenant the key acts in.
+	TenantID string
+	Name     string
+	// Prefix is the public part of the key, used to look it up.
+	Prefix     string
+	Hash       []byte
+	Scopes     []string
+	CreatedAt  time.Time
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+}
+
+// Active reports whether the key can be used at time now.
+func (k *APIKey) Active(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
+
+// APIKeyRepository defines persistence operations for API keys.
+type APIKeyRepository interface {
+	// Stores a new key.
+	Create(ctx context.Context, key *APIKey) error
+	// Fetches a key by its prefix.
+	GetByPrefix(ctx context.Context, prefix string) (*APIKey, error)
+	// Retrieves the keys of the tenant of ctx, revoked ones included,
+	// ordered by creation time.
+	GetAll(ctx context.Context) ([]*APIKey, error)
+	// Marks a key of the tenant of ctx as revoked.
+	Revoke(ctx context.Context, id string, at time.Time) error
+	// Records the last use of a key.
+	Touch(ctx context.Context, id string, at time.Time) error
+}
+
+// MintAPIKeyParams describes a new API key.
+type MintAPIKeyParams struct {
+	Name   string
+	Scopes []string
+	// ExpiresAt is optional; keys without it never expire.
+	ExpiresAt *time.Time
+}
+
+// APIKeyService defines the operations related to API keys.
+type APIKeyService interface {
+	// Creates a key and returns it along with its secret.
+	Mint(ctx context.Context, params MintAPIKeyParams) (*APIKey, string, error)
+	// Retrieves the keys of the tenant.
+	List(ctx context.Context) ([]*APIKey, error)
+	// Revokes a key.
+	Revoke(ctx context.Context, id string) error
+	// Returns the caller's claims for an active key.
+	Authenticate(ctx context.Context, secret string) (*Claims, error)
+}
+
+// APIKeyApp implements APIKeyService. Keys are hashed with HMAC-SHA256 keyed
+// with a pepper kept out of the database, so a leaked table is useless on its own.
+type APIKeyApp struct {
+	db     APIKeyRepository
+	logger logger.Logger
+	pepper []byte
+	now    func() time.Time
+}
+
+// NewAPIKeyApp initializes an APIKeyApp instance.
+func NewAPIKeyApp(db APIKeyRepository, logger logger.Logger, pepper []byte) APIKeyService {
+	return &APIKeyApp{
+		db:     db,
+		logger: logger,
+		pepper: pepper,
+		now:    time.Now,
+	}
+}
+
+// ValidScope reports whether scope can be granted to an API key or an OAuth client.
+func ValidScope(scope string) bool {
+	switch scope {
+	case ScopeUsersRead, ScopeUsersWrite, ScopeUsersAdmin:
+		return true
+	}
+	return false
+}
+
+func (app *APIKeyApp) Mint(ctx context.Context, params MintAPIKeyParams) (*APIKey, string, error) {
+	if err := Authorize(ctx, ActionAPIKeys, ""); err != nil {
+		return nil, "", err
+	}
+	if err := app.validate(params); err != nil {
+		return nil, "", err
+	}
+
+	prefix, secret, err := generateAPIKey()
+	if err != nil {
+		return nil, "", err
+	}
+
+	key := &APIKey{
+		ID:        uuid.New().String(),
+		TenantID:  TenantFromContext(ctx),
+		Name:      strings.TrimSpace(params.Name),
+		Prefix:    prefix,
+		Hash:      app.hash(secret),
+		Scopes:    slices.Compact(slices.Sorted(slices.Values(params.Scopes))),
+		CreatedAt: app.now().UTC(),
+		ExpiresAt: params.ExpiresAt,
+	}
+	if err := app.db.Create(ctx, key); err != nil {
+		app.logger.WithContext(ctx).Error("can't create api key", "error", err)
+		return nil, "", err
+	}
+
+	app.logger.WithContext(ctx).Info("API key minted", "api_key_id", key.ID, "scopes", key.Scopes, "actor", actor(ctx))
+
+	return key, secret, nil
+}
+
+// InvalidAPIKeyParamsError explains why MintAPIKeyParams were rejected.
+type InvalidAPIKeyParamsError struct {
+	Reason string
+}
+
+func (e *InvalidAPIKeyParamsError) Error() string {
+	return e.Reason
+}
+
+func (app *APIKeyApp) validate(params MintAPIKeyParams) error {
+	if strings.TrimSpace(params.Name) == "" {
+		return &InvalidAPIKeyParamsError{Reason: "name is required"}
+	}
+	if len(params.Scopes) == 0 {
+		return &InvalidAPIKeyParamsError{Reason: "at least one scope is required"}
+	}
+	for _, scope := range params.Scopes {
+		if !ValidScope(scope) {
+			return &InvalidAPIKeyParamsError{Reason: fmt.Sprintf("unknown scope %q", scope)}
+		}
+	}
+	if params.ExpiresAt != nil && !params.ExpiresAt.After(app.now()) {
+		return &InvalidAPIKeyParamsError{Reason: "expiry must be in the future"}
+	}
+	return nil
+}
+
+func (app *APIKeyApp) List(ctx context.Context) ([]*APIKey, error) {
+	if err := Authorize(ctx, ActionAPIKeys, ""); err != nil {
+		return nil, err
+	}
+
+	keys, err := app.db.GetAll(ctx)
+	if err != nil {
+		app.logger.WithContext(ctx).Error("can't retrive api keys", "error", err)
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+func (app *APIKeyApp) Revoke(ctx context.Context, id string) error {
+	if err := Authorize(ctx, ActionAPIKeys, ""); err != nil {
+		return err
+	}
+
+	if err := app.db.Revoke(ctx, id, app.now().UTC()); err != nil {
+		app.logger.WithContext(ctx).Error("can't revoke api key", "error", err)
+		return err
+	}
+
+	app.logger.WithContext(ctx).Info("API key revoked", "api_key_id", id, "actor", actor(ctx))
+
+	return nil
+}
+
+func (app *APIKeyApp) Authenticate(ctx context.Context, secret string) (*Claims, error) {
+	prefix, ok := parseAPIKey(secret)
+	if !ok {
+		return nil, perrors.ErrInvalidAPIKey
+	}
+
+	key, err := app.db.GetByPrefix(ctx, prefix)
+	if errors.Is(err, perrors.ErrAPIKeyNotFound) {
+		return nil, perrors.ErrInvalidAPIKey
+	}
+	if err != nil {
+		app.logger.WithContext(ctx).Error("can't retrive api key", "error", err)
+		return nil, err
+	}
+
+	now := app.now()
+	if !hmac.Equal(key.Hash, app.hash(secret)) || !key.Active(now) {
+		return nil, perrors.ErrInvalidAPIKey
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= lastUsedPrecision {
+		// Failing to record the use must not fail the request.
+		if err := app.db.Touch(ctx, key.ID, now.UTC()); err != nil {
+			app.logger.WithContext(ctx).Error("can't record api key use", "error", err)
+		}
+	}
+
+	claims := &Claims{
+		Subject:  "apikey:" + key.ID,
+		APIKeyID: key.ID,
+		TenantID: key.TenantID,
+		Scopes:   key.Scopes,
+	}
+	if key.ExpiresAt != nil {
+		claims.ExpiresAt = *key.ExpiresAt
+	}
+	return claims, nil
+}
+
+func (app *APIKeyApp) hash(secret string) []byte {
+	mac := hmac.New(sha256.New, app.pepper)
+	mac.Write([]byte(secret))
+	return mac.Sum(nil)
+}
+
+// generateAPIKey returns a key of the form "sak_<prefix>_<secret>" and its prefix.
+func generateAPIKey() (string, string, error) {
+	random := make([]byte, 8+32)
+	if _, err := rand.Read(random); err != nil {
+		return "", "", err
+	}
+
+	prefix := hex.EncodeToString(random[:8])
+	secret := base64.RawURLEncoding.EncodeToString(random[8:])
+	return prefix, apiKeyPrefix + "_" + prefix + "_" + secret, nil
+}
+
+// parseAPIKey returns the prefix of a well-formed key.
+func parseAPIKey(key string) (string, bool) {
+	// The secret is base64url, which may itself contain underscores.
+	parts := strings.SplitN(key, "_", 3)
+	if len(parts) != 3 || parts[0] != apiKeyPrefix || len(parts[1]) != 16 || parts[2] == "" {
+		return "", false
+	}
+	return parts[1], true
+}
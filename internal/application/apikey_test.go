@@ -0,0 +1,186 @@
+package app_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	"github.com/Sergey-Polishchenko/simple-api/internal/infrastructure/memory"
+	perrors "github.com/Sergey-Polishchenko/simple-api/internal/pkg/errors"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/logger"
+)
+
+var pepper = []byte("0123456789abcdef0123456789abcdef")
+
+func adminContext() context.Context {
+	return app.ContextWithClaims(context.Background(), &app.Claims{Subject: "admin", Roles: []string{app.RoleAdmin}})
+}
+
+func TestAPIKeyApp_Mint(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name        string
+		ctx         context.Context
+		params      app.MintAPIKeyParams
+		expectedErr string
+	}{
+		{
+			name:   "success",
+			ctx:    adminContext(),
+			params: app.MintAPIKeyParams{Name: "billing", Scopes: []string{app.ScopeUsersRead}},
+		},
+		{
+			name:        "anonymous",
+			ctx:         context.Background(),
+			params:      app.MintAPIKeyParams{Name: "billing", Scopes: []string{app.ScopeUsersRead}},
+			expectedErr: perrors.ErrForbidden.Error(),
+		},
+		{
+			name: "key without admin scope",
+			ctx: app.ContextWithClaims(context.Background(), &app.Claims{
+				APIKeyID: "1", Scopes: []string{app.ScopeUsersWrite},
+			}),
+			params:      app.MintAPIKeyParams{Name: "billing", Scopes: []string{app.ScopeUsersRead}},
+			expectedErr: perrors.ErrForbidden.Error(),
+		},
+		{
+			name:        "missing name",
+			ctx:         adminContext(),
+			params:      app.MintAPIKeyParams{Name: " ", Scopes: []string{app.ScopeUsersRead}},
+			expectedErr: "name is required",
+		},
+		{
+			name:        "missing scopes",
+			ctx:         adminContext(),
+			params:      app.MintAPIKeyParams{Name: "billing"},
+			expectedErr: "at least one scope is required",
+		},
+		{
+			name:        "unknown scope",
+			ctx:         adminContext(),
+			params:      app.MintAPIKeyParams{Name: "billing", Scopes: []string{"users:everything"}},
+			expectedErr: `unknown scope "users:everything"`,
+		},
+		{
+			name:        "expiry in the past",
+			ctx:         adminContext(),
+			params:      app.MintAPIKeyParams{Name: "billing", Scopes: []string{app.ScopeUsersRead}, ExpiresAt: &past},
+			expectedErr: "expiry must be in the future",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := memory.NewAPIKeyRepo()
+			service := app.NewAPIKeyApp(repo, logger.NewZapLogger(), pepper)
+
+			key, secret, err := service.Mint(tt.ctx, tt.params)
+
+			if tt.expectedErr != "" {
+				assert.EqualError(t, err, tt.expectedErr)
+				keys, _ := repo.GetAll(context.Background())
+				assert.Empty(t, keys)
+				return
+			}
+			require.NoError(t, err)
+			assert.Regexp(t, `^sak_[0-9a-f]{16}_[A-Za-z0-9_-]{43}$`, secret)
+			assert.Contains(t, secret, key.Prefix)
+			assert.NotContains(t, string(key.Hash), secret, "only the hash is stored")
+		})
+	}
+}
+
+func TestAPIKeyApp_Authenticate(t *testing.T) {
+	repo := memory.NewAPIKeyRepo()
+	service := app.NewAPIKeyApp(repo, logger.NewZapLogger(), pepper)
+	ctx := adminContext()
+
+	key, secret, err := service.Mint(ctx, app.MintAPIKeyParams{
+		Name:   "billing",
+		Scopes: []string{app.ScopeUsersWrite, app.ScopeUsersRead, app.ScopeUsersRead},
+	})
+	require.NoError(t, err)
+
+	claims, err := service.Authenticate(context.Background(), secret)
+	require.NoError(t, err)
+	assert.Equal(t, key.ID, claims.APIKeyID)
+	assert.Equal(t, []string{app.ScopeUsersRead, app.ScopeUsersWrite}, claims.Scopes)
+
+	keys, err := service.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.NotNil(t, keys[0].LastUsedAt, "use is recorded")
+
+	for _, invalid := range []string{
+		"",
+		"sak_" + key.Prefix,
+		secret + "x",
+		"sak_0000000000000000_" + secret[len("sak_0000000000000000_"):],
+	} {
+		_, err := service.Authenticate(context.Background(), invalid)
+		assert.ErrorIs(t, err, perrors.ErrInvalidAPIKey, invalid)
+	}
+
+	// A different pepper makes the stored hashes useless.
+	other := app.NewAPIKeyApp(repo, logger.NewZapLogger(), []byte("another pepper of thirty-two bytes"))
+	_, err = other.Authenticate(context.Background(), secret)
+	assert.ErrorIs(t, err, perrors.ErrInvalidAPIKey)
+
+	require.NoError(t, service.Revoke(ctx, key.ID))
+	_, err = service.Authenticate(context.Background(), secret)
+	assert.ErrorIs(t, err, perrors.ErrInvalidAPIKey, "revoked keys are rejected")
+
+	assert.ErrorIs(t, service.Revoke(ctx, "missing"), perrors.ErrAPIKeyNotFound)
+}
+
+func TestAPIKeyApp_AuthenticateExpired(t *testing.T) {
+	repo := memory.NewAPIKeyRepo()
+	service := app.NewAPIKeyApp(repo, logger.NewZapLogger(), pepper)
+
+	expiresAt := time.Now().Add(50 * time.Millisecond)
+	_, secret, err := service.Mint(adminContext(), app.MintAPIKeyParams{
+		Name:      "short-lived",
+		Scopes:    []string{app.ScopeUsersRead},
+		ExpiresAt: &expiresAt,
+	})
+	require.NoError(t, err)
+
+	_, err = service.Authenticate(context.Background(), secret)
+	require.NoError(t, err)
+
+	time.Sleep(time.Until(expiresAt))
+	_, err = service.Authenticate(context.Background(), secret)
+	assert.ErrorIs(t, err, perrors.ErrInvalidAPIKey)
+}
+
+func TestAPIKeyApp_Tenants(t *testing.T) {
+	service := app.NewAPIKeyApp(memory.NewAPIKeyRepo(), logger.NewZapLogger(), pepper)
+	operator := adminContext()
+	acme := app.ContextWithClaims(context.Background(), &app.Claims{Subject: "1", TenantID: "acme", Roles: []string{app.RoleAdmin}})
+
+	key, secret, err := service.Mint(app.ContextWithTenant(operator, "acme"), app.MintAPIKeyParams{
+		Name:   "billing",
+		Scopes: []string{app.ScopeUsersRead},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "acme", key.TenantID)
+
+	claims, err := service.Authenticate(context.Background(), secret)
+	require.NoError(t, err)
+	assert.Equal(t, "acme", claims.TenantID, "keys act in their tenant")
+
+	keys, err := service.List(operator)
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+	assert.ErrorIs(t, service.Revoke(operator, key.ID), perrors.ErrAPIKeyNotFound)
+
+	keys, err = service.List(acme)
+	require.NoError(t, err)
+	assert.Len(t, keys, 1)
+	require.NoError(t, service.Revoke(acme, key.ID))
+}
@@ -0,0 +1,342 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	perrors "github.com/Sergey-Polishchenko/simple-api/internal/pkg/errors"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/logger"
+)
+
+// AttributeType is the type of the values of a custom attribute.
+type AttributeType string
+
+const (
+	AttributeString  AttributeType = "string"
+	AttributeNumber  AttributeType = "number"
+	AttributeBoolean AttributeType = "boolean"
+)
+
+const (
+	// MaxAttributeValueLength is the longest string value an attribute can have.
+	MaxAttributeValueLength = 1000
+	// MaxAttributeDefinitions caps the attributes of a tenant.
+	MaxAttributeDefinitions = 50
+)
+
+var attributeNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,62}$`)
+
+// AttributeDefinition describes a custom attribute users of a tenant can
+// have. Users can only have the attributes their tenant defines.
+type AttributeDefinition struct {
+	// Name is a lowercase identifier, like "cost_center".
+	Name     string
+	Type     AttributeType
+	Required bool
+	// Enum lists the values a string attribute is restricted to, if any.
+	Enum []string
+	// Pattern is a regular expression string values must match, if any.
+	// Like in JSON Schema, it is not anchored.
+	Pattern   string
+	UpdatedAt time.Time
+}
+
+// AttributeSchemaRepository defines persistence operations for attribute
+// definitions. Definitions belong to the tenant of the context.
+type AttributeSchemaRepository interface {
+	// Retrieves all definitions ordered by name.
+	GetAll(ctx context.Context) ([]*AttributeDefinition, error)
+	// Stores a definition, replacing the one with the same name if any.
+	Put(ctx context.Context, definition *AttributeDefinition) error
+	// Deletes a definition.
+	Remove(ctx context.Context, name string) error
+}
+
+// AttributeSchemaService defines the management of the custom attributes
+// of users. Changing or removing a definition leaves the values users
+// have alone; they are validated again when next written.
+type AttributeSchemaService interface {
+	// Retrieves all definitions.
+	List(ctx context.Context) ([]*AttributeDefinition, error)
+	// Creates or replaces a definition.
+	Define(ctx context.Context, definition *AttributeDefinition) (*AttributeDefinition, error)
+	// Deletes a definition.
+	Remove(ctx context.Context, name string) error
+}
+
+// AttributeSchemaApp implements AttributeSchemaService.
+type AttributeSchemaApp struct {
+	db     AttributeSchemaRepository
+	logger logger.Logger
+	now    func() time.Time
+}
+
+// NewAttributeSchemaApp initializes an AttributeSchemaApp instance.
+func NewAttributeSchemaApp(db AttributeSchemaRepository, logger logger.Logger) AttributeSchemaService {
+	return &AttributeSchemaApp{db: db, logger: logger, now: time.Now}
+}
+
+// InvalidAttributesError explains why attributes, their definition or a
+// filter on them were rejected.
+type InvalidAttributesError struct {
+	Reason string
+}
+
+func (e *InvalidAttributesError) Error() string {
+	return e.Reason
+}
+
+func (app *AttributeSchemaApp) List(ctx context.Context) ([]*AttributeDefinition, error) {
+	if err := Authorize(ctx, ActionUserList, ""); err != nil {
+		return nil, err
+	}
+
+	definitions, err := app.db.GetAll(ctx)
+	if err != nil {
+		app.logger.WithContext(ctx).Error("can't retrive attribute definitions", "error", err)
+	}
+	return definitions, err
+}
+
+func (app *AttributeSchemaApp) Define(ctx context.Context, definition *AttributeDefinition) (*AttributeDefinition, error) {
+	if err := Authorize(ctx, ActionAttributeSchema, definition.Name); err != nil {
+		return nil, err
+	}
+
+	definition = &AttributeDefinition{
+		Name:      definition.Name,
+		Type:      definition.Type,
+		Required:  definition.Required,
+		Enum:      slices.Clone(definition.Enum),
+		Pattern:   definition.Pattern,
+		UpdatedAt: app.now().UTC(),
+	}
+	if err := validateAttributeDefinition(definition); err != nil {
+		return nil, err
+	}
+
+	definitions, err := app.db.GetAll(ctx)
+	if err != nil {
+		app.logger.WithContext(ctx).Error("can't retrive attribute definitions", "error", err)
+		return nil, err
+	}
+	exists := slices.ContainsFunc(definitions, func(d *AttributeDefinition) bool { return d.Name == definition.Name })
+	if !exists && len(definitions) >= MaxAttributeDefinitions {
+		return nil, &InvalidAttributesError{Reason: fmt.Sprintf("at most %d attributes can be defined", MaxAttributeDefinitions)}
+	}
+
+	if err := app.db.Put(ctx, definition); err != nil {
+		app.logger.WithContext(ctx).Error("can't store attribute definition", "error", err)
+		return nil, err
+	}
+
+	app.logger.WithContext(ctx).Info("Attribute defined", "attribute", definition.Name, "type", definition.Type, "actor", actor(ctx))
+
+	return definition, nil
+}
+
+func (app *AttributeSchemaApp) Remove(ctx context.Context, name string) error {
+	if err := Authorize(ctx, ActionAttributeSchema, name); err != nil {
+		return err
+	}
+
+	if err := app.db.Remove(ctx, name); err != nil {
+		if !errors.Is(err, perrors.ErrAttributeNotFound) {
+			app.logger.WithContext(ctx).Error("can't remove attribute definition", "error", err)
+		}
+		return err
+	}
+
+	app.logger.WithContext(ctx).Info("Attribute removed", "attribute", name, "actor", actor(ctx))
+
+	return nil
+}
+
+func validateAttributeDefinition(definition *AttributeDefinition) error {
+	if !attributeNamePattern.MatchString(definition.Name) {
+		return &InvalidAttributesError{Reason: "attribute names must be 1 to 63 lowercase letters, digits or underscores, starting with a letter"}
+	}
+	switch definition.Type {
+	case AttributeString:
+	case AttributeNumber, AttributeBoolean:
+		if len(definition.Enum) > 0 || definition.Pattern != "" {
+			return &InvalidAttributesError{Reason: "only string attributes can have an enum or a pattern"}
+		}
+	default:
+		return &InvalidAttributesError{Reason: fmt.Sprintf("unknown attribute type %q", definition.Type)}
+	}
+	for _, value := range definition.Enum {
+		if len(value) > MaxAttributeValueLength {
+			return &InvalidAttributesError{Reason: "enum value is too long"}
+		}
+	}
+	if definition.Pattern != "" {
+		if _, err := regexp.Compile(definition.Pattern); err != nil {
+			return &InvalidAttributesError{Reason: fmt.Sprintf("invalid pattern: %v", err)}
+		}
+	}
+	return nil
+}
+
+// attributeSchema is the set of attribute definitions of a tenant.
+type attributeSchema map[string]*AttributeDefinition
+
+// loadAttributeSchema fetches the definitions of the tenant of ctx; there
+// are none without a repository.
+func loadAttributeSchema(ctx context.Context, db AttributeSchemaRepository) (attributeSchema, error) {
+	schema := make(attributeSchema)
+	if db == nil {
+		return schema, nil
+	}
+
+	definitions, err := db.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, definition := range definitions {
+		schema[definition.Name] = definition
+	}
+	return schema, nil
+}
+
+// validate checks the attributes of a user against the schema, returning
+// them with numbers as float64.
+func (s attributeSchema) validate(attributes map[string]interface{}) (map[string]interface{}, error) {
+	valid := make(map[string]interface{}, len(attributes))
+	for _, name := range slices.Sorted(maps.Keys(attributes)) {
+		definition, ok := s[name]
+		if !ok {
+			return nil, &InvalidAttributesError{Reason: fmt.Sprintf("unknown attribute %q", name)}
+		}
+		value, err := definition.check(attributes[name])
+		if err != nil {
+			return nil, err
+		}
+		valid[name] = value
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(s)) {
+		if _, ok := valid[name]; !ok && s[name].Required {
+			return nil, &InvalidAttributesError{Reason: fmt.Sprintf("attribute %q is required", name)}
+		}
+	}
+	return valid, nil
+}
+
+// check validates a value of the attribute, returning numbers as float64.
+func (d *AttributeDefinition) check(value interface{}) (interface{}, error) {
+	invalid := func(reason string) error {
+		return &InvalidAttributesError{Reason: fmt.Sprintf("attribute %q %s", d.Name, reason)}
+	}
+
+	switch d.Type {
+	case AttributeString:
+		s, ok := value.(string)
+		if !ok {
+			return nil, invalid("must be a string")
+		}
+		if len(s) > MaxAttributeValueLength {
+			return nil, invalid("is too long")
+		}
+		if len(d.Enum) > 0 && !slices.Contains(d.Enum, s) {
+			return nil, invalid("must be one of " + strings.Join(d.Enum, ", "))
+		}
+		if d.Pattern != "" {
+			if matched, _ := regexp.MatchString(d.Pattern, s); !matched {
+				return nil, invalid("must match " + d.Pattern)
+			}
+		}
+		return s, nil
+	case AttributeNumber:
+		switch n := value.(type) {
+		case float64:
+			return n, nil
+		case int:
+			return float64(n), nil
+		case int64:
+			return float64(n), nil
+		}
+		return nil, invalid("must be a number")
+	case AttributeBoolean:
+		b, ok := value.(bool)
+		if !ok {
+			return nil, invalid("must be a boolean")
+		}
+		return b, nil
+	}
+	return nil, invalid("has an unknown type")
+}
+
+// parse converts a filter value given as a string into a value of the
+// attribute.
+func (d *AttributeDefinition) parse(value string) (interface{}, error) {
+	switch d.Type {
+	case AttributeNumber:
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, &InvalidAttributesError{Reason: fmt.Sprintf("attribute %q must be a number", d.Name)}
+		}
+		return n, nil
+	case AttributeBoolean:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, &InvalidAttributesError{Reason: fmt.Sprintf("attribute %q must be a boolean", d.Name)}
+		}
+		return b, nil
+	}
+	return value, nil
+}
+
+// validateAttributes checks the attributes of a user against the schema of
+// the tenant of ctx.
+func (app *UserApp) validateAttributes(ctx context.Context, attributes map[string]interface{}) (map[string]interface{}, error) {
+	schema, err := loadAttributeSchema(ctx, app.schema)
+	if err != nil {
+		app.logger.WithContext(ctx).Error("can't retrive attribute definitions", "error", err)
+		return nil, err
+	}
+	return schema.validate(attributes)
+}
+
+// parseAttributeParams checks the attribute filter and sort of a list
+// request against the schema, converting filter values to the attribute
+// types.
+func (app *UserApp) parseAttributeParams(ctx context.Context, params ListParams) (ListParams, error) {
+	schema, err := loadAttributeSchema(ctx, app.schema)
+	if err != nil {
+		app.logger.WithContext(ctx).Error("can't retrive attribute definitions", "error", err)
+		return params, err
+	}
+
+	if params.SortAttribute != "" {
+		if _, ok := schema[params.SortAttribute]; !ok {
+			return params, &InvalidAttributesError{Reason: fmt.Sprintf("unknown attribute %q", params.SortAttribute)}
+		}
+	}
+
+	filter := make(map[string]interface{}, len(params.Filter.Attributes))
+	for name, value := range params.Filter.Attributes {
+		definition, ok := schema[name]
+		if !ok {
+			return params, &InvalidAttributesError{Reason: fmt.Sprintf("unknown attribute %q", name)}
+		}
+		if s, ok := value.(string); ok {
+			if value, err = definition.parse(s); err != nil {
+				return params, err
+			}
+		}
+		if filter[name], err = definition.check(value); err != nil {
+			return params, err
+		}
+	}
+	params.Filter.Attributes = filter
+
+	return params, nil
+}
@@ -0,0 +1,259 @@
+package app_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	"github.com/Sergey-Polishchenko/simple-api/internal/domain"
+	"github.com/Sergey-Polishchenko/simple-api/internal/infrastructure/memory"
+	perrors "github.com/Sergey-Polishchenko/simple-api/internal/pkg/errors"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/logger"
+)
+
+// newAttributeFixture returns a UserService validating attributes against
+// a schema with a required "department" enum, a "cost_center" pattern, a
+// "level" number and a "remote" boolean.
+func newAttributeFixture(t *testing.T) (app.UserService, app.AttributeSchemaService) {
+	logger := logger.NewZapLogger()
+	schemaRepo := memory.NewAttributeSchemaRepo()
+	schema := app.NewAttributeSchemaApp(schemaRepo, logger)
+	for _, definition := range []*app.AttributeDefinition{
+		{Name: "department", Type: app.AttributeString, Required: true, Enum: []string{"eng", "sales"}},
+		{Name: "cost_center", Type: app.AttributeString, Pattern: `^CC-\d+$`},
+		{Name: "level", Type: app.AttributeNumber},
+		{Name: "remote", Type: app.AttributeBoolean},
+	} {
+		_, err := schema.Define(adminContext(), definition)
+		require.NoError(t, err)
+	}
+	return app.NewUserApp(memory.NewUserRepo(), logger, app.WithAttributeSchema(schemaRepo)), schema
+}
+
+func TestAttributeSchemaApp_Define(t *testing.T) {
+	tests := []struct {
+		name        string
+		definition  *app.AttributeDefinition
+		user        bool
+		expectedErr string
+	}{
+		{
+			name:       "success",
+			definition: &app.AttributeDefinition{Name: "locale", Type: app.AttributeString, Pattern: `^[a-z]{2}$`},
+		},
+		{
+			name:        "user",
+			definition:  &app.AttributeDefinition{Name: "locale", Type: app.AttributeString},
+			user:        true,
+			expectedErr: perrors.ErrForbidden.Error(),
+		},
+		{
+			name:        "invalid name",
+			definition:  &app.AttributeDefinition{Name: "Cost Center", Type: app.AttributeString},
+			expectedErr: "attribute names must be",
+		},
+		{
+			name:        "unknown type",
+			definition:  &app.AttributeDefinition{Name: "locale", Type: "date"},
+			expectedErr: `unknown attribute type "date"`,
+		},
+		{
+			name:        "enum on a number",
+			definition:  &app.AttributeDefinition{Name: "level", Type: app.AttributeNumber, Enum: []string{"1"}},
+			expectedErr: "only string attributes can have an enum or a pattern",
+		},
+		{
+			name:        "invalid pattern",
+			definition:  &app.AttributeDefinition{Name: "locale", Type: app.AttributeString, Pattern: "["},
+			expectedErr: "invalid pattern",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, schema := newAttributeFixture(t)
+			ctx := adminContext()
+			if tt.user {
+				ctx = userContext("1")
+			}
+
+			definition, err := schema.Define(ctx, tt.definition)
+			if tt.expectedErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.definition.Name, definition.Name)
+			assert.False(t, definition.UpdatedAt.IsZero())
+		})
+	}
+}
+
+func TestUserApp_CreateWithAttributes(t *testing.T) {
+	tests := []struct {
+		name        string
+		attributes  map[string]interface{}
+		expected    map[string]interface{}
+		expectedErr string
+	}{
+		{
+			name:       "success",
+			attributes: map[string]interface{}{"department": "eng", "cost_center": "CC-42", "level": 3, "remote": true},
+			expected:   map[string]interface{}{"department": "eng", "cost_center": "CC-42", "level": 3.0, "remote": true},
+		},
+		{
+			name:        "missing required",
+			attributes:  map[string]interface{}{"level": 3.0},
+			expectedErr: `attribute "department" is required`,
+		},
+		{
+			name:        "unknown",
+			attributes:  map[string]interface{}{"department": "eng", "shoe_size": 42.0},
+			expectedErr: `unknown attribute "shoe_size"`,
+		},
+		{
+			name:        "wrong type",
+			attributes:  map[string]interface{}{"department": "eng", "level": "3"},
+			expectedErr: `attribute "level" must be a number`,
+		},
+		{
+			name:        "not in enum",
+			attributes:  map[string]interface{}{"department": "hr"},
+			expectedErr: `attribute "department" must be one of eng, sales`,
+		},
+		{
+			name:        "pattern mismatch",
+			attributes:  map[string]interface{}{"department": "eng", "cost_center": "42"},
+			expectedErr: `attribute "cost_center" must match`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, _ := newAttributeFixture(t)
+
+			user, err := service.Create(adminContext(), domain.NewUser("", "John").WithAttributes(tt.attributes))
+			if tt.expectedErr != "" {
+				var invalid *app.InvalidAttributesError
+				require.ErrorAs(t, err, &invalid)
+				assert.Contains(t, err.Error(), tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, user.Attributes())
+		})
+	}
+}
+
+func TestUserApp_UpdateAttributes(t *testing.T) {
+	service, schema := newAttributeFixture(t)
+	ctx := adminContext()
+
+	user, err := service.Create(ctx, domain.NewUser("", "John").WithAttributes(map[string]interface{}{"department": "eng"}))
+	require.NoError(t, err)
+
+	require.NoError(t, service.Update(ctx, domain.NewUser(user.ID(), "Johnny")))
+	updated, err := service.GetUser(ctx, user.ID())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"department": "eng"}, updated.Attributes(), "attributes are kept")
+
+	err = service.Update(ctx, domain.NewUser(user.ID(), "Johnny").WithAttributes(map[string]interface{}{}))
+	assert.ErrorContains(t, err, `attribute "department" is required`)
+
+	require.NoError(t, schema.Remove(ctx, "department"))
+	require.NoError(t, service.Update(ctx, domain.NewUser(user.ID(), "Johnny")), "stored values outlive their definition")
+	err = service.Update(ctx, domain.NewUser(user.ID(), "Johnny").WithAttributes(map[string]interface{}{"department": "eng"}))
+	assert.ErrorContains(t, err, `unknown attribute "department"`)
+
+	assert.ErrorIs(t, schema.Remove(ctx, "department"), perrors.ErrAttributeNotFound)
+}
+
+func TestUserApp_ListByAttributes(t *testing.T) {
+	service, _ := newAttributeFixture(t)
+	ctx := adminContext()
+
+	for name, attributes := range map[string]map[string]interface{}{
+		"Ann":  {"department": "eng", "level": 2.0, "remote": true},
+		"Bob":  {"department": "eng", "level": 1.0},
+		"Cid":  {"department": "sales", "level": 3.0, "remote": true},
+		"Dana": {"department": "eng"},
+	} {
+		_, err := service.Create(ctx, domain.NewUser("", name).WithAttributes(attributes))
+		require.NoError(t, err)
+	}
+
+	names := func(users []*domain.User) []string {
+		result := make([]string, len(users))
+		for i, user := range users {
+			result[i] = user.Name()
+		}
+		return result
+	}
+
+	tests := []struct {
+		name        string
+		params      app.ListParams
+		expected    []string
+		expectedErr string
+	}{
+		{
+			name:     "filter",
+			params:   app.ListParams{Filter: app.UserFilter{Attributes: map[string]interface{}{"department": "eng", "remote": "true"}}},
+			expected: []string{"Ann"},
+		},
+		{
+			name:     "sort",
+			params:   app.ListParams{SortAttribute: "level"},
+			expected: []string{"Dana", "Bob", "Ann", "Cid"},
+		},
+		{
+			name:     "sort descending with filter",
+			params:   app.ListParams{SortAttribute: "level", Descending: true, Filter: app.UserFilter{Attributes: map[string]interface{}{"department": "eng"}}},
+			expected: []string{"Ann", "Bob", "Dana"},
+		},
+		{
+			name:        "unknown filter",
+			params:      app.ListParams{Filter: app.UserFilter{Attributes: map[string]interface{}{"shoe_size": "42"}}},
+			expectedErr: `unknown attribute "shoe_size"`,
+		},
+		{
+			name:        "invalid filter value",
+			params:      app.ListParams{Filter: app.UserFilter{Attributes: map[string]interface{}{"level": "high"}}},
+			expectedErr: `attribute "level" must be a number`,
+		},
+		{
+			name:        "unknown sort",
+			params:      app.ListParams{SortAttribute: "shoe_size"},
+			expectedErr: `unknown attribute "shoe_size"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := service.List(ctx, tt.params)
+			if tt.expectedErr != "" {
+				assert.ErrorContains(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, names(page.Users))
+		})
+	}
+
+	t.Run("paginated sort", func(t *testing.T) {
+		params := app.ListParams{SortAttribute: "level", Descending: true, Limit: 3}
+		page, err := service.List(ctx, params)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Cid", "Ann", "Bob"}, names(page.Users))
+		require.True(t, page.HasNextPage)
+
+		params.After = page.Users[len(page.Users)-1].ID()
+		page, err = service.List(ctx, params)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Dana"}, names(page.Users))
+		assert.False(t, page.HasNextPage)
+	})
+}
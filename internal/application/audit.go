@@ -0,0 +1,432 @@
+package app
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Sergey-Polishchenko/simple-api/internal/domain"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/logger"
+)
+
+// AuditAction is the kind of change an audit entry records.
+type AuditAction string
+
+const (
+	AuditUserCreated         AuditAction = "user.created"
+	AuditUserUpdated         AuditAction = "user.updated"
+	AuditUserRemoved         AuditAction = "user.removed"
+	AuditUserPasswordChanged AuditAction = "user.password_changed"
+	AuditUserEmailChanged    AuditAction = "user.email_changed"
+	AuditUserEmailVerified   AuditAction = "user.email_verified"
+	AuditUserPasswordReset   AuditAction = "user.password_reset"
+	AuditUserReverted        AuditAction = "user.reverted"
+	AuditUserErased          AuditAction = "user.erased"
+)
+
+// AuditChange is the change of a field of the target of an audit entry.
+// Before is nil for fields the target gains, After for the ones it loses.
+type AuditChange struct {
+	Field  string      `json:"field"`
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// AuditEntry records who changed what. The entries of a tenant form a
+// chain: every entry holds the hash of the previous one, so that
+// altering or deleting entries breaks the chain, see AuditService.Verify.
+type AuditEntry struct {
+	// Sequence orders entries across tenants; it is set on append.
+	Sequence int64
+	ID       string
+	TenantID string
+	// ActorKind is "system", "user" or "service", see PrincipalKind.
+	ActorKind string
+	// ActorID is the user ID, API key ID or OAuth client ID of the actor;
+	// empty for the system.
+	ActorID  string
+	Action   AuditAction
+	TargetID string
+	// Changes are ordered by field. They are erased along with the user
+	// they are about, see AuditRepository.Erase.
+	Changes []AuditChange
+	// The chain covers ChangesHash, the hash of the changes salted with
+	// ChangesSalt, in place of the changes, so that they can be erased
+	// along with the salt without breaking it. Without the salt the hash
+	// can't confirm guesses of the erased values. Both are empty for
+	// entries without changes.
+	ChangesSalt string
+	ChangesHash string
+	// RequestID and ClientIP identify the request of the actor. They are
+	// erased along with the user who made it, and covered by the chain
+	// like the changes, through RequestSalt and RequestHash.
+	RequestID   string
+	ClientIP    string
+	RequestSalt string
+	RequestHash string
+	CreatedAt   time.Time
+	// PrevHash is the hash of the previous entry of the tenant, empty for
+	// the first one.
+	PrevHash string
+	Hash     string
+}
+
+// Seal links the entry to the previous one of its chain and computes its
+// hash, salting the changes and the request first if it has any.
+func (e *AuditEntry) Seal(prevHash string) {
+	if len(e.Changes) > 0 {
+		if e.ChangesSalt == "" {
+			e.ChangesSalt = rand.Text()
+		}
+		e.ChangesHash = e.computeChangesHash()
+	}
+	if e.RequestID != "" || e.ClientIP != "" {
+		if e.RequestSalt == "" {
+			e.RequestSalt = rand.Text()
+		}
+		e.RequestHash = e.computeRequestHash()
+	}
+	e.PrevHash = prevHash
+	e.Hash = e.computeHash()
+}
+
+// ChangesErased reports whether the entry had changes that were erased.
+func (e *AuditEntry) ChangesErased() bool {
+	return e.ChangesHash != "" && e.ChangesSalt == ""
+}
+
+// RequestErased reports whether the entry identified a request that was
+// erased.
+func (e *AuditEntry) RequestErased() bool {
+	return e.RequestHash != "" && e.RequestSalt == ""
+}
+
+// computeHash hashes the content of the entry along with PrevHash. The
+// time is hashed at microsecond precision, the one Postgres keeps.
+func (e *AuditEntry) computeHash() string {
+	// Marshalling a struct of marshallable values can't fail.
+	data, _ := json.Marshal(struct {
+		ID          string      `json:"id"`
+		TenantID    string      `json:"tenant_id"`
+		ActorKind   string      `json:"actor_kind"`
+		ActorID     string      `json:"actor_id"`
+		Action      AuditAction `json:"action"`
+		TargetID    string      `json:"target_id"`
+		ChangesHash string      `json:"changes_hash"`
+		RequestHash string      `json:"request_hash"`
+		CreatedAt   string      `json:"created_at"`
+		PrevHash    string      `json:"prev_hash"`
+	}{
+		ID:          e.ID,
+		TenantID:    e.TenantID,
+		ActorKind:   e.ActorKind,
+		ActorID:     e.ActorID,
+		Action:      e.Action,
+		TargetID:    e.TargetID,
+		ChangesHash: e.ChangesHash,
+		RequestHash: e.RequestHash,
+		CreatedAt:   e.CreatedAt.UTC().Truncate(time.Microsecond).Format(time.RFC3339Nano),
+		PrevHash:    e.PrevHash,
+	})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// computeChangesHash hashes the changes along with ChangesSalt.
+func (e *AuditEntry) computeChangesHash() string {
+	data, _ := json.Marshal(e.Changes)
+	sum := sha256.Sum256(append([]byte(e.ChangesSalt), data...))
+	return hex.EncodeToString(sum[:])
+}
+
+// computeRequestHash hashes the request along with RequestSalt.
+func (e *AuditEntry) computeRequestHash() string {
+	data, _ := json.Marshal([]string{e.RequestID, e.ClientIP})
+	sum := sha256.Sum256(append([]byte(e.RequestSalt), data...))
+	return hex.EncodeToString(sum[:])
+}
+
+// AuditFilter narrows the entries returned by AuditService.List.
+type AuditFilter struct {
+	ActorID  string
+	Action   AuditAction
+	TargetID string
+	// Since and Until bound the time of the entries when set; Until is
+	// exclusive.
+	Since, Until time.Time
+}
+
+// AuditListParams describes a keyset-paginated request for audit entries,
+// newest first.
+type AuditListParams struct {
+	Filter AuditFilter
+	// After is the sequence number of the last entry of the previous page.
+	After int64
+	Limit int
+}
+
+// normalize clamps the limit to the allowed range.
+func (p AuditListParams) normalize() AuditListParams {
+	switch {
+	case p.Limit <= 0:
+		p.Limit = DefaultPageSize
+	case p.Limit > MaxPageSize:
+		p.Limit = MaxPageSize
+	}
+	return p
+}
+
+// AuditPage is a single page of audit entries, newest first.
+type AuditPage struct {
+	Entries     []*AuditEntry
+	HasNextPage bool
+}
+
+// AuditVerification is the outcome of the verification of a chain.
+type AuditVerification struct {
+	// Entries is the number of entries verified.
+	Entries int
+	// BrokenAt is the first entry that doesn't match its hash or doesn't
+	// follow the previous one; nil when the chain is intact.
+	BrokenAt *AuditEntry
+	// Reason tells how the chain is broken.
+	Reason string
+}
+
+// Valid reports whether the chain is intact.
+func (v *AuditVerification) Valid() bool {
+	return v.BrokenAt == nil
+}
+
+// AuditRepository stores audit entries in the chains of their tenants.
+// Entries can only be appended.
+type AuditRepository interface {
+	// Appends an entry to the chain of the tenant of the context, sealing
+	// it with the hash of the last entry of the chain. Appends to a chain
+	// are serialized, so that it doesn't fork.
+	Append(ctx context.Context, entry *AuditEntry) error
+	// Retrieves up to params.Limit entries matching the filter, newest first.
+	List(ctx context.Context, params AuditListParams) ([]*AuditEntry, error)
+	// Retrieves up to limit entries following the one with the given
+	// sequence number, oldest first, for walking the chain.
+	Chain(ctx context.Context, after int64, limit int) ([]*AuditEntry, error)
+	// Deletes the personal data of a user, along with its salts: the
+	// changes of the entries about the user and the request of the entries
+	// made by them. It reports how many entries had any. The entries stay,
+	// and so does their chain.
+	Erase(ctx context.Context, userID string) (int, error)
+}
+
+// AuditService reads the audit log of the tenant of the context.
+type AuditService interface {
+	// Retrieves a page of entries matching the filter.
+	List(ctx context.Context, params AuditListParams) (*AuditPage, error)
+	// Retrieves a page of the entries about a user, including removed ones.
+	History(ctx context.Context, userID string, params AuditListParams) (*AuditPage, error)
+	// Walks the chain, checking that no entry was altered or deleted
+	// since. Only the deletion of the latest entries goes unnoticed.
+	Verify(ctx context.Context) (*AuditVerification, error)
+}
+
+// AuditApp implements AuditService.
+type AuditApp struct {
+	db     AuditRepository
+	logger logger.Logger
+}
+
+// NewAuditApp initializes an AuditApp instance.
+func NewAuditApp(db AuditRepository, logger logger.Logger) AuditService {
+	return &AuditApp{db: db, logger: logger}
+}
+
+func (app *AuditApp) List(ctx context.Context, params AuditListParams) (*AuditPage, error) {
+	if err := Authorize(ctx, ActionAuditRead, ""); err != nil {
+		return nil, err
+	}
+	return app.list(ctx, params)
+}
+
+func (app *AuditApp) History(ctx context.Context, userID string, params AuditListParams) (*AuditPage, error) {
+	if err := Authorize(ctx, ActionAuditRead, userID); err != nil {
+		return nil, err
+	}
+	params.Filter.TargetID = userID
+	return app.list(ctx, params)
+}
+
+func (app *AuditApp) list(ctx context.Context, params AuditListParams) (*AuditPage, error) {
+	params = params.normalize()
+	limit := params.Limit
+
+	// Fetch one extra entry to find out whether another page exists.
+	params.Limit++
+	entries, err := app.db.List(ctx, params)
+	if err != nil {
+		app.logger.WithContext(ctx).Error("can't list audit entries", "error", err)
+		return nil, err
+	}
+
+	page := &AuditPage{Entries: entries}
+	if len(entries) > limit {
+		page.Entries = entries[:limit]
+		page.HasNextPage = true
+	}
+	return page, nil
+}
+
+func (app *AuditApp) Verify(ctx context.Context) (*AuditVerification, error) {
+	if err := Authorize(ctx, ActionAuditRead, ""); err != nil {
+		return nil, err
+	}
+
+	verification := &AuditVerification{}
+	var (
+		after    int64
+		prevHash string
+	)
+	for {
+		entries, err := app.db.Chain(ctx, after, MaxPageSize)
+		if err != nil {
+			app.logger.WithContext(ctx).Error("can't read audit entries", "error", err)
+			return nil, err
+		}
+		for _, entry := range entries {
+			switch {
+			case entry.PrevHash != prevHash:
+				verification.BrokenAt, verification.Reason = entry, "the previous entry is missing or was altered"
+			case entry.computeHash() != entry.Hash:
+				verification.BrokenAt, verification.Reason = entry, "the entry was altered"
+			case (entry.ChangesSalt != "" || len(entry.Changes) > 0) && entry.computeChangesHash() != entry.ChangesHash:
+				verification.BrokenAt, verification.Reason = entry, "the changes were altered"
+			case (entry.RequestSalt != "" || entry.RequestID != "" || entry.ClientIP != "") &&
+				entry.computeRequestHash() != entry.RequestHash:
+				verification.BrokenAt, verification.Reason = entry, "the request was altered"
+			}
+			if verification.BrokenAt != nil {
+				app.logger.WithContext(ctx).Error("Audit chain broken",
+					"entry_id", entry.ID, "sequence", entry.Sequence, "reason", verification.Reason)
+				return verification, nil
+			}
+			verification.Entries++
+			after, prevHash = entry.Sequence, entry.Hash
+		}
+		if len(entries) < MaxPageSize {
+			return verification, nil
+		}
+	}
+}
+
+// RequestInfo describes the request an operation is performed for.
+type RequestInfo struct {
+	ID       string
+	ClientIP string
+}
+
+type requestInfoKey struct{}
+
+// ContextWithRequestInfo returns a copy of ctx carrying the request it
+// serves, for the audit log.
+func ContextWithRequestInfo(ctx context.Context, info RequestInfo) context.Context {
+	return context.WithValue(ctx, requestInfoKey{}, info)
+}
+
+// RequestInfoFromContext returns the request ctx serves; it is empty
+// outside of requests, e.g. in usersctl.
+func RequestInfoFromContext(ctx context.Context) RequestInfo {
+	info, _ := ctx.Value(requestInfoKey{}).(RequestInfo)
+	return info
+}
+
+// WithAuditLog makes UserApp record every mutation of users in the log.
+func WithAuditLog(log AuditRepository) Option {
+	return func(app *UserApp) {
+		app.audit = log
+	}
+}
+
+// WithTransactor makes UserApp change users and record the changes in the
+// audit log in one transaction of tx, so that neither is kept without the
+// other. The repositories UserApp is given must take part in it.
+func WithTransactor(tx Transactor) Option {
+	return func(app *UserApp) {
+		app.tx = tx
+	}
+}
+
+// record appends an entry for a change of a user to the audit log. An
+// unrecorded change must not pass for a successful one, so failing to
+// record it fails the operation, which rolls the change back along with
+// the transaction it is recorded in, see WithTransactor.
+func (app *UserApp) record(ctx context.Context, action AuditAction, targetID string, changes []AuditChange) error {
+	if app.audit == nil {
+		return nil
+	}
+	if err := appendAuditEntry(ctx, app.audit, action, targetID, changes); err != nil {
+		app.logger.WithContext(ctx).Error("can't record audit entry",
+			"action", action, "user_id", targetID, "actor", actor(ctx), "error", err)
+		return err
+	}
+	return nil
+}
+
+// appendAuditEntry appends an entry by the caller of ctx to the log.
+func appendAuditEntry(ctx context.Context, log AuditRepository, action AuditAction, targetID string, changes []AuditChange) error {
+	principal := PrincipalFromContext(ctx)
+	request := RequestInfoFromContext(ctx)
+	return log.Append(ctx, &AuditEntry{
+		ID:        uuid.New().String(),
+		TenantID:  TenantFromContext(ctx),
+		ActorKind: principal.Kind.String(),
+		ActorID:   principal.ID,
+		Action:    action,
+		TargetID:  targetID,
+		Changes:   changes,
+		RequestID: request.ID,
+		ClientIP:  request.ClientIP,
+		CreatedAt: time.Now().UTC().Truncate(time.Microsecond),
+	})
+}
+
+// diffUsers returns the changes of the fields of a user; before is nil for
+// new users and after for removed ones. Attributes are diffed one by one.
+func diffUsers(before, after *domain.User) []AuditChange {
+	fields := func(user *domain.User) map[string]interface{} {
+		if user == nil {
+			return nil
+		}
+		values := map[string]interface{}{"name": user.Name()}
+		if user.Email() != "" {
+			values["email"] = user.Email()
+			values["email_verified"] = user.EmailVerified()
+		}
+		for name, value := range user.Attributes() {
+			values[fmt.Sprintf("attributes.%s", name)] = value
+		}
+		return values
+	}
+	old, current := fields(before), fields(after)
+
+	names := slices.Sorted(maps.Keys(old))
+	for name := range current {
+		if _, ok := old[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	var changes []AuditChange
+	for _, name := range names {
+		if old[name] != current[name] {
+			changes = append(changes, AuditChange{Field: name, Before: old[name], After: current[name]})
+		}
+	}
+	return changes
+}
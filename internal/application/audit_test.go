@@ -0,0 +1,447 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	"github.com/Sergey-Polishchenko/simple-api/internal/domain"
+	"github.com/Sergey-Polishchenko/simple-api/internal/infrastructure/memory"
+	perrors "github.com/Sergey-Polishchenko/simple-api/internal/pkg/errors"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/logger"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/password"
+)
+
+type auditFixture struct {
+	users app.UserService
+	audit app.AuditService
+	repo  *memory.AuditRepo
+}
+
+func newAuditFixture() *auditFixture {
+	repo := memory.NewAuditRepo()
+	log := logger.NewZapLogger()
+	return &auditFixture{
+		users: app.NewUserApp(memory.NewUserRepo(), log,
+			app.WithPasswordHasher(password.NewHasher(cheapParams)), app.WithAuditLog(repo)),
+		audit: app.NewAuditApp(repo, log),
+		repo:  repo,
+	}
+}
+
+// createUsers creates users with the given names and returns their IDs.
+func (f *auditFixture) createUsers(t *testing.T, ctx context.Context, names ...string) []string {
+	t.Helper()
+	ids := make([]string, len(names))
+	for i, name := range names {
+		user, err := f.users.Create(ctx, domain.NewUser("", name))
+		require.NoError(t, err)
+		ids[i] = user.ID()
+	}
+	return ids
+}
+
+func auditActions(entries []*app.AuditEntry) []app.AuditAction {
+	actions := make([]app.AuditAction, len(entries))
+	for i, entry := range entries {
+		actions[i] = entry.Action
+	}
+	return actions
+}
+
+func TestUserApp_AuditLog(t *testing.T) {
+	f := newAuditFixture()
+	ctx := app.ContextWithRequestInfo(adminContext(), app.RequestInfo{ID: "r1", ClientIP: "203.0.113.7"})
+
+	user, err := f.users.Create(ctx, domain.NewUser("", "Alice").WithEmail("alice@example.com", false))
+	require.NoError(t, err)
+	require.NoError(t, f.users.Update(ctx, domain.NewUser(user.ID(), "Alicia")))
+	require.NoError(t, f.users.SetEmail(ctx, user.ID(), "", ""))
+	require.NoError(t, f.users.SetPassword(ctx, user.ID(), "", "correct horse"))
+	require.NoError(t, f.users.Remove(ctx, user.ID()))
+
+	page, err := f.audit.History(adminContext(), user.ID(), app.AuditListParams{})
+	require.NoError(t, err)
+	require.Len(t, page.Entries, 5)
+	assert.False(t, page.HasNextPage)
+	assert.Equal(t, []app.AuditAction{
+		app.AuditUserRemoved,
+		app.AuditUserPasswordChanged,
+		app.AuditUserEmailChanged,
+		app.AuditUserUpdated,
+		app.AuditUserCreated,
+	}, auditActions(page.Entries))
+
+	created := page.Entries[4]
+	assert.Equal(t, "user", created.ActorKind)
+	assert.Equal(t, "admin", created.ActorID)
+	assert.Equal(t, user.ID(), created.TargetID)
+	assert.Equal(t, app.DefaultTenantID, created.TenantID)
+	assert.Equal(t, "r1", created.RequestID)
+	assert.Equal(t, "203.0.113.7", created.ClientIP)
+	assert.Empty(t, created.PrevHash)
+	assert.NotEmpty(t, created.Hash)
+	assert.Equal(t, []app.AuditChange{
+		{Field: "email", After: "alice@example.com"},
+		{Field: "email_verified", After: false},
+		{Field: "name", After: "Alice"},
+	}, created.Changes)
+
+	assert.Equal(t, []app.AuditChange{{Field: "name", Before: "Alice", After: "Alicia"}}, page.Entries[3].Changes)
+	assert.Equal(t, []app.AuditChange{
+		{Field: "email", Before: "alice@example.com"},
+		{Field: "email_verified", Before: false},
+	}, page.Entries[2].Changes)
+	assert.Empty(t, page.Entries[1].Changes, "passwords are never recorded")
+	assert.Equal(t, []app.AuditChange{{Field: "name", Before: "Alicia"}}, page.Entries[0].Changes)
+
+	for i := 0; i < 4; i++ {
+		assert.Equal(t, page.Entries[i+1].Hash, page.Entries[i].PrevHash)
+	}
+}
+
+func TestUserApp_AuditLogSkipsFailures(t *testing.T) {
+	f := newAuditFixture()
+
+	assert.ErrorIs(t, f.users.Remove(adminContext(), "missing"), perrors.ErrUserNotFound)
+	assert.ErrorIs(t, f.users.Update(adminContext(), domain.NewUser("missing", "Bob")), perrors.ErrUserNotFound)
+	_, err := f.users.Create(userContext("1"), domain.NewUser("", "Bob"))
+	assert.ErrorIs(t, err, perrors.ErrForbidden)
+
+	page, err := f.audit.List(adminContext(), app.AuditListParams{})
+	require.NoError(t, err)
+	assert.Empty(t, page.Entries)
+}
+
+// brokenAuditRepo fails to append entries.
+type brokenAuditRepo struct {
+	*memory.AuditRepo
+}
+
+func (brokenAuditRepo) Append(context.Context, *app.AuditEntry) error {
+	return errors.New("audit log unavailable")
+}
+
+func TestUserApp_AuditLogFailure(t *testing.T) {
+	users := memory.NewUserRepo()
+	require.NoError(t, users.Create(context.Background(), domain.NewUser("1", "Alice")))
+	service := app.NewUserApp(users, logger.NewZapLogger(),
+		app.WithPasswordHasher(password.NewHasher(cheapParams)), app.WithAuditLog(brokenAuditRepo{memory.NewAuditRepo()}))
+	ctx := adminContext()
+
+	_, err := service.Create(ctx, domain.NewUser("", "Bob"))
+	assert.Error(t, err)
+	assert.Error(t, service.Update(ctx, domain.NewUser("1", "Alicia")))
+	assert.Error(t, service.SetPassword(ctx, "1", "", "correct horse"))
+	assert.Error(t, service.SetEmail(ctx, "1", "", "alice@example.com"))
+	assert.Error(t, service.Remove(ctx, "1"))
+}
+
+func TestUserApp_AuditLogFailureRollsBack(t *testing.T) {
+	users := memory.NewUserRepo()
+	audit := memory.NewAuditRepo()
+	sessions := memory.NewRefreshTokenRepo()
+	opts := []app.Option{
+		app.WithPasswordHasher(password.NewHasher(cheapParams)), app.WithUserHistory(users),
+		app.WithSessions(sessions), app.WithTransactor(memory.NewTransactor()),
+	}
+	service := app.NewUserApp(users, logger.NewZapLogger(), append(opts, app.WithAuditLog(audit))...)
+	ctx := adminContext()
+
+	alice, err := service.Create(ctx, domain.NewUser("", "Alice").WithEmail("alice@example.com", false))
+	require.NoError(t, err)
+	require.NoError(t, service.Update(ctx, domain.NewUser(alice.ID(), "Alicia")))
+	require.NoError(t, sessions.Create(ctx, &app.RefreshToken{ID: "1", FamilyID: "1", UserID: alice.ID()}))
+
+	snapshot := func() (*domain.User, []*app.UserVersion, []*app.AuditEntry, []*app.RefreshToken) {
+		user, err := users.GetByID(ctx, alice.ID())
+		require.NoError(t, err)
+		versions, err := users.ListVersions(ctx, alice.ID(), app.VersionListParams{})
+		require.NoError(t, err)
+		entries, err := audit.List(ctx, app.AuditListParams{Limit: 10})
+		require.NoError(t, err)
+		tokens, err := sessions.ListByUser(ctx, alice.ID())
+		require.NoError(t, err)
+		return user, versions, entries, tokens
+	}
+	user, versions, entries, tokens := snapshot()
+
+	broken := app.NewUserApp(users, logger.NewZapLogger(), append(opts, app.WithAuditLog(brokenAuditRepo{audit}))...)
+	_, err = broken.Create(ctx, domain.NewUser("", "Bob"))
+	assert.Error(t, err)
+	assert.Error(t, broken.Update(ctx, domain.NewUser(alice.ID(), "Ally")))
+	assert.Error(t, broken.SetPassword(ctx, alice.ID(), "", "correct horse"))
+	assert.Error(t, broken.SetEmail(ctx, alice.ID(), "", "ally@example.com"))
+	_, err = broken.Revert(ctx, alice.ID(), 1)
+	assert.Error(t, err)
+	assert.Error(t, broken.Remove(ctx, alice.ID()))
+	_, err = broken.Erase(ctx, alice.ID())
+	assert.Error(t, err)
+
+	all, err := users.GetAll(ctx)
+	require.NoError(t, err)
+	assert.Len(t, all, 1, "no user is created")
+	afterUser, afterVersions, afterEntries, afterTokens := snapshot()
+	assert.Equal(t, user, afterUser)
+	assert.Equal(t, versions, afterVersions)
+	assert.Equal(t, entries, afterEntries)
+	assert.Equal(t, tokens, afterTokens)
+}
+
+func TestAuditApp_List(t *testing.T) {
+	f := newAuditFixture()
+	ids := f.createUsers(t, adminContext(), "Alice", "Bob", "Carol")
+	serviceCtx := app.ContextWithClaims(context.Background(), &app.Claims{
+		Subject: "key-1", APIKeyID: "key-1", Scopes: []string{app.ScopeUsersWrite},
+	})
+	require.NoError(t, f.users.Update(serviceCtx, domain.NewUser(ids[1], "Robert")))
+	require.NoError(t, f.users.Remove(adminContext(), ids[2]))
+
+	tests := []struct {
+		name            string
+		params          app.AuditListParams
+		expectedTargets []string
+		expectedNext    bool
+	}{
+		{
+			name:            "all",
+			expectedTargets: []string{ids[2], ids[1], ids[2], ids[1], ids[0]},
+		},
+		{
+			name:            "first page",
+			params:          app.AuditListParams{Limit: 2},
+			expectedTargets: []string{ids[2], ids[1]},
+			expectedNext:    true,
+		},
+		{
+			name:            "second page",
+			params:          app.AuditListParams{Limit: 2, After: 4},
+			expectedTargets: []string{ids[2], ids[1]},
+			expectedNext:    true,
+		},
+		{
+			name:            "last page",
+			params:          app.AuditListParams{Limit: 2, After: 2},
+			expectedTargets: []string{ids[0]},
+		},
+		{
+			name:            "by action",
+			params:          app.AuditListParams{Filter: app.AuditFilter{Action: app.AuditUserCreated}},
+			expectedTargets: []string{ids[2], ids[1], ids[0]},
+		},
+		{
+			name:            "by actor",
+			params:          app.AuditListParams{Filter: app.AuditFilter{ActorID: "key-1"}},
+			expectedTargets: []string{ids[1]},
+		},
+		{
+			name:            "by target",
+			params:          app.AuditListParams{Filter: app.AuditFilter{TargetID: ids[2]}},
+			expectedTargets: []string{ids[2], ids[2]},
+		},
+		{
+			name:            "until",
+			params:          app.AuditListParams{Filter: app.AuditFilter{Until: time.Now().Add(-time.Hour)}},
+			expectedTargets: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := f.audit.List(adminContext(), tt.params)
+			require.NoError(t, err)
+
+			targets := make([]string, len(page.Entries))
+			for i, entry := range page.Entries {
+				targets[i] = entry.TargetID
+			}
+			assert.Equal(t, tt.expectedTargets, targets)
+			assert.Equal(t, tt.expectedNext, page.HasNextPage)
+		})
+	}
+
+	t.Run("service actor", func(t *testing.T) {
+		page, err := f.audit.List(adminContext(), app.AuditListParams{Filter: app.AuditFilter{ActorID: "key-1"}})
+		require.NoError(t, err)
+		require.Len(t, page.Entries, 1)
+		assert.Equal(t, "service", page.Entries[0].ActorKind)
+	})
+}
+
+func TestAuditApp_Authorization(t *testing.T) {
+	f := newAuditFixture()
+	ids := f.createUsers(t, adminContext(), "Alice", "Bob")
+
+	tests := []struct {
+		name        string
+		read        func(ctx context.Context) error
+		ctx         context.Context
+		expectedErr error
+	}{
+		{
+			name: "admin lists",
+			read: func(ctx context.Context) error {
+				_, err := f.audit.List(ctx, app.AuditListParams{})
+				return err
+			},
+			ctx: adminContext(),
+		},
+		{
+			name: "user lists",
+			read: func(ctx context.Context) error {
+				_, err := f.audit.List(ctx, app.AuditListParams{})
+				return err
+			},
+			ctx:         userContext(ids[0]),
+			expectedErr: perrors.ErrForbidden,
+		},
+		{
+			name: "user reads own history",
+			read: func(ctx context.Context) error {
+				_, err := f.audit.History(ctx, ids[0], app.AuditListParams{})
+				return err
+			},
+			ctx: userContext(ids[0]),
+		},
+		{
+			name: "user reads history of another",
+			read: func(ctx context.Context) error {
+				_, err := f.audit.History(ctx, ids[1], app.AuditListParams{})
+				return err
+			},
+			ctx:         userContext(ids[0]),
+			expectedErr: perrors.ErrForbidden,
+		},
+		{
+			name: "user verifies",
+			read: func(ctx context.Context) error {
+				_, err := f.audit.Verify(ctx)
+				return err
+			},
+			ctx:         userContext(ids[0]),
+			expectedErr: perrors.ErrForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.read(tt.ctx)
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestAuditApp_Verify(t *testing.T) {
+	tests := []struct {
+		name            string
+		tamper          func(repo *memory.AuditRepo)
+		expectedEntries int
+		expectedBroken  int64
+	}{
+		{
+			name:            "intact",
+			expectedEntries: 3,
+		},
+		{
+			name: "altered entry",
+			tamper: func(repo *memory.AuditRepo) {
+				repo.Tamper(2, func(entry *app.AuditEntry) { entry.ActorID = "someone-else" })
+			},
+			expectedEntries: 1,
+			expectedBroken:  2,
+		},
+		{
+			name: "altered changes",
+			tamper: func(repo *memory.AuditRepo) {
+				repo.Tamper(2, func(entry *app.AuditEntry) { entry.Changes[0].After = "Mallory" })
+			},
+			expectedEntries: 1,
+			expectedBroken:  2,
+		},
+		{
+			name: "erased changes",
+			tamper: func(repo *memory.AuditRepo) {
+				repo.Tamper(2, func(entry *app.AuditEntry) { entry.Changes, entry.ChangesSalt = nil, "" })
+			},
+			expectedEntries: 3,
+		},
+		{
+			name: "altered request",
+			tamper: func(repo *memory.AuditRepo) {
+				repo.Tamper(2, func(entry *app.AuditEntry) { entry.ClientIP = "198.51.100.1" })
+			},
+			expectedEntries: 1,
+			expectedBroken:  2,
+		},
+		{
+			name: "erased request",
+			tamper: func(repo *memory.AuditRepo) {
+				repo.Tamper(2, func(entry *app.AuditEntry) { entry.RequestID, entry.ClientIP, entry.RequestSalt = "", "", "" })
+			},
+			expectedEntries: 3,
+		},
+		{
+			name: "rehashed entry",
+			tamper: func(repo *memory.AuditRepo) {
+				repo.Tamper(2, func(entry *app.AuditEntry) {
+					entry.ActorID = "someone-else"
+					entry.Seal(entry.PrevHash)
+				})
+			},
+			expectedEntries: 2,
+			expectedBroken:  4,
+		},
+		{
+			name:            "deleted entry",
+			tamper:          func(repo *memory.AuditRepo) { repo.Delete(2) },
+			expectedEntries: 1,
+			expectedBroken:  4,
+		},
+		{
+			name:            "deleted first entry",
+			tamper:          func(repo *memory.AuditRepo) { repo.Delete(1) },
+			expectedBroken:  2,
+			expectedEntries: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newAuditFixture()
+			admin := app.ContextWithRequestInfo(adminContext(), app.RequestInfo{ID: "r1", ClientIP: "203.0.113.7"})
+			acme := app.ContextWithTenant(tenantAdminContext("acme"), "acme")
+			// Entries 1, 2 and 4 belong to the default tenant, 3 to acme.
+			f.createUsers(t, admin, "Alice", "Bob")
+			f.createUsers(t, acme, "Carol")
+			f.createUsers(t, admin, "Dave")
+			if tt.tamper != nil {
+				tt.tamper(f.repo)
+			}
+
+			verification, err := f.audit.Verify(adminContext())
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedEntries, verification.Entries)
+			if tt.expectedBroken == 0 {
+				assert.True(t, verification.Valid())
+			} else {
+				require.False(t, verification.Valid())
+				assert.Equal(t, tt.expectedBroken, verification.BrokenAt.Sequence)
+				assert.NotEmpty(t, verification.Reason)
+			}
+
+			verification, err = f.audit.Verify(acme)
+			require.NoError(t, err)
+			assert.True(t, verification.Valid(), "the chains of tenants are apart")
+			assert.Equal(t, 1, verification.Entries)
+		})
+	}
+}
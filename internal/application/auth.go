@@ -0,0 +1,384 @@
+package app
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	perrors "github.com/Sergey-Polishchenko/simple-api/internal/pkg/errors"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/logger"
+)
+
+// refreshTokenPrefix makes refresh tokens recognisable, e.g. by secret scanners.
+const refreshTokenPrefix = "srt"
+
+// RefreshToken is a server-side session credential. Every refresh uses up
+// the token and issues a new one of the same family; only a hash of the
+// token itself is kept.
+type RefreshToken struct {
+	ID string
+	// FamilyID is shared by the tokens descending from one login.
+	FamilyID  string
+	UserID    string
+	Hash      []byte
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	RevokedAt *time.Time
+}
+
+// RefreshTokenRepository defines persistence operations for refresh tokens.
+type RefreshTokenRepository interface {
+	// Stores a new token.
+	Create(ctx context.Context, token *RefreshToken) error
+	// Fetches a token by the hash of its value.
+	GetByHash(ctx context.Context, hash []byte) (*RefreshToken, error)
+	// Marks a token as used unless it already is, reporting whether it was marked.
+	MarkUsed(ctx context.Context, id string, at time.Time) (bool, error)
+	// Revokes every token of a family.
+	RevokeFamily(ctx context.Context, familyID string, at time.Time) error
+	// Revokes every token of a user, ending all of their sessions but the
+	// one of the family exceptFamilyID, if it isn't empty.
+	RevokeByUser(ctx context.Context, userID, exceptFamilyID string, at time.Time) error
+	// Retrieves the tokens of a user, oldest first.
+	ListByUser(ctx context.Context, userID string) ([]*RefreshToken, error)
+	// Deletes the tokens of a user, reporting how many there were.
+	DeleteByUser(ctx context.Context, userID string) (int, error)
+}
+
+// TokenSigner signs access tokens.
+type TokenSigner interface {
+	Sign(claims map[string]interface{}) (string, error)
+}
+
+// TokenPair is the result of a login or a refresh.
+type TokenPair struct {
+	AccessToken     string
+	AccessExpiresIn time.Duration
+	RefreshToken    string
+}
+
+// AuthConfig holds the settings of the tokens AuthApp issues.
+type AuthConfig struct {
+	Issuer   string
+	Audience string
+	// AccessTokenTTL should be short: access tokens can't be revoked.
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+}
+
+// Credentials are what users sign in with.
+type Credentials struct {
+	UserID   string
+	Password string
+	// MFACode is a TOTP or recovery code, required once the user enabled MFA.
+	MFACode string
+}
+
+// AuthService defines password logins and the sessions they start.
+type AuthService interface {
+	// Checks the credentials of a user and starts a session.
+	Login(ctx context.Context, credentials Credentials) (*TokenPair, error)
+	// Checks the credentials of a user without starting a session,
+	// for sign-ins that issue credentials of their own.
+	VerifyCredentials(ctx context.Context, credentials Credentials) error
+	// Exchanges a refresh token for a new pair of tokens.
+	Refresh(ctx context.Context, refreshToken string) (*TokenPair, error)
+	// Ends the session a refresh token belongs to.
+	Logout(ctx context.Context, refreshToken string) error
+}
+
+// AuthApp implements AuthService. Refresh tokens rotate on every use, and
+// presenting a used one again revokes its whole family: either the caller
+// or whoever it leaked to holds a stale copy, and there is no telling which.
+type AuthApp struct {
+	users  UserRepository
+	tokens RefreshTokenRepository
+	hasher PasswordHasher
+	signer TokenSigner
+	mfa    SecondFactor
+	logger logger.Logger
+	config AuthConfig
+	now    func() time.Time
+	// dummyHash is verified against when the user has no password,
+	// so that the response time doesn't tell whether the user exists.
+	dummyHash string
+}
+
+// AuthOption configures optional AuthApp dependencies.
+type AuthOption func(*AuthApp)
+
+// WithSecondFactor makes users who enabled MFA sign in with a code too.
+func WithSecondFactor(mfa SecondFactor) AuthOption {
+	return func(app *AuthApp) {
+		app.mfa = mfa
+	}
+}
+
+// NewAuthApp initializes an AuthApp instance.
+func NewAuthApp(
+	users UserRepository,
+	tokens RefreshTokenRepository,
+	hasher PasswordHasher,
+	signer TokenSigner,
+	logger logger.Logger,
+	config AuthConfig,
+	opts ...AuthOption,
+) (AuthService, error) {
+	dummyHash, err := hasher.Hash(uuid.New().String())
+	if err != nil {
+		return nil, err
+	}
+
+	app := &AuthApp{
+		users:     users,
+		tokens:    tokens,
+		hasher:    hasher,
+		signer:    signer,
+		logger:    logger,
+		config:    config,
+		now:       time.Now,
+		dummyHash: dummyHash,
+	}
+	for _, opt := range opts {
+		opt(app)
+	}
+	return app, nil
+}
+
+func (app *AuthApp) Login(ctx context.Context, credentials Credentials) (*TokenPair, error) {
+	if err := app.VerifyCredentials(ctx, credentials); err != nil {
+		return nil, err
+	}
+
+	pair, err := app.issue(ctx, credentials.UserID, uuid.New().String())
+	if err != nil {
+		return nil, err
+	}
+
+	app.logger.WithContext(ctx).Info("User logged in", "user_id", credentials.UserID)
+
+	return pair, nil
+}
+
+// VerifyCredentials checks the password first, so that only those who know
+// it learn whether a code is required.
+func (app *AuthApp) VerifyCredentials(ctx context.Context, credentials Credentials) error {
+	if err := app.verifyPassword(ctx, credentials.UserID, credentials.Password); err != nil {
+		return err
+	}
+	if app.mfa == nil {
+		return nil
+	}
+	return app.mfa.Check(ctx, credentials.UserID, credentials.MFACode)
+}
+
+func (app *AuthApp) verifyPassword(ctx context.Context, userID, password string) error {
+	user, err := app.users.GetByID(ctx, userID)
+	if err != nil && !errors.Is(err, perrors.ErrUserNotFound) {
+		app.logger.WithContext(ctx).Error("can't retrive user", "error", err)
+		return err
+	}
+
+	hasPassword := user != nil && user.PasswordHash() != ""
+	encoded := app.dummyHash
+	if hasPassword {
+		encoded = user.PasswordHash()
+	}
+
+	match, rehash, err := app.hasher.Verify(password, encoded)
+	if err != nil {
+		app.logger.WithContext(ctx).Error("can't verify password", "user_id", userID, "error", err)
+		return perrors.ErrInvalidCredentials
+	}
+	if !match || !hasPassword {
+		app.logger.WithContext(ctx).Info("Login failed", "user_id", userID)
+		return perrors.ErrInvalidCredentials
+	}
+
+	if rehash {
+		app.rehash(ctx, userID, password)
+	}
+	return nil
+}
+
+// rehash replaces an outdated password hash. Failing to do so must not fail
+// the login: the old hash still works and is replaced on the next one.
+func (app *AuthApp) rehash(ctx context.Context, userID, password string) {
+	hash, err := app.hasher.Hash(password)
+	if err == nil {
+		err = app.users.SetPasswordHash(ctx, userID, hash)
+	}
+	if err != nil {
+		app.logger.WithContext(ctx).Error("can't rehash password", "user_id", userID, "error", err)
+		return
+	}
+
+	app.logger.WithContext(ctx).Info("Password rehashed", "user_id", userID)
+}
+
+func (app *AuthApp) Refresh(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	token, err := app.lookup(ctx, refreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	now := app.now()
+	if token.RevokedAt != nil || !now.Before(token.ExpiresAt) {
+		return nil, perrors.ErrInvalidRefreshToken
+	}
+
+	// Deleted users lose their sessions. The user is looked up in the
+	// tenant of the request before the token is used, so that a refresh
+	// sent to the wrong tenant leaves the token for the right one.
+	if _, err := app.users.GetByID(ctx, token.UserID); err != nil {
+		if errors.Is(err, perrors.ErrUserNotFound) {
+			return nil, perrors.ErrInvalidRefreshToken
+		}
+		app.logger.WithContext(ctx).Error("can't retrive user", "error", err)
+		return nil, err
+	}
+
+	marked, err := app.tokens.MarkUsed(ctx, token.ID, now.UTC())
+	if err != nil {
+		app.logger.WithContext(ctx).Error("can't use refresh token", "error", err)
+		return nil, err
+	}
+	if !marked {
+		app.logger.WithContext(ctx).Info("Refresh token reused, revoking its family", "user_id", token.UserID, "family_id", token.FamilyID)
+		if err := app.tokens.RevokeFamily(ctx, token.FamilyID, now.UTC()); err != nil {
+			app.logger.WithContext(ctx).Error("can't revoke refresh tokens", "error", err)
+			return nil, err
+		}
+		return nil, perrors.ErrInvalidRefreshToken
+	}
+
+	pair, err := app.issue(ctx, token.UserID, token.FamilyID)
+	if err != nil {
+		return nil, err
+	}
+
+	app.logger.WithContext(ctx).Info("Tokens refreshed", "user_id", token.UserID)
+
+	return pair, nil
+}
+
+func (app *AuthApp) Logout(ctx context.Context, refreshToken string) error {
+	token, err := app.lookup(ctx, refreshToken)
+	if errors.Is(err, perrors.ErrInvalidRefreshToken) {
+		// There is no session to end.
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := app.tokens.RevokeFamily(ctx, token.FamilyID, app.now().UTC()); err != nil {
+		app.logger.WithContext(ctx).Error("can't revoke refresh tokens", "error", err)
+		return err
+	}
+
+	app.logger.WithContext(ctx).Info("User logged out", "user_id", token.UserID)
+
+	return nil
+}
+
+func (app *AuthApp) lookup(ctx context.Context, refreshToken string) (*RefreshToken, error) {
+	if !strings.HasPrefix(refreshToken, refreshTokenPrefix+"_") {
+		return nil, perrors.ErrInvalidRefreshToken
+	}
+
+	token, err := app.tokens.GetByHash(ctx, hashToken(refreshToken))
+	if errors.Is(err, perrors.ErrRefreshTokenNotFound) {
+		return nil, perrors.ErrInvalidRefreshToken
+	}
+	if err != nil {
+		app.logger.WithContext(ctx).Error("can't retrive refresh token", "error", err)
+		return nil, err
+	}
+	return token, nil
+}
+
+// issue signs an access token for the user and stores a new refresh token of the family.
+func (app *AuthApp) issue(ctx context.Context, userID, familyID string) (*TokenPair, error) {
+	now := app.now()
+
+	claims := map[string]interface{}{
+		"sub": userID,
+		"iat": now.Unix(),
+		"exp": now.Add(app.config.AccessTokenTTL).Unix(),
+		"jti": uuid.New().String(),
+		"sid": familyID,
+	}
+	if app.config.Issuer != "" {
+		claims["iss"] = app.config.Issuer
+	}
+	if app.config.Audience != "" {
+		claims["aud"] = app.config.Audience
+	}
+	// The user was found in the tenant of the request.
+	if tenant := TenantFromContext(ctx); tenant != DefaultTenantID {
+		claims["tenant"] = tenant
+	}
+
+	accessToken, err := app.signer.Sign(claims)
+	if err != nil {
+		app.logger.WithContext(ctx).Error("can't sign access token", "error", err)
+		return nil, err
+	}
+
+	refreshToken, err := generateRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	err = app.tokens.Create(ctx, &RefreshToken{
+		ID:        uuid.New().String(),
+		FamilyID:  familyID,
+		UserID:    userID,
+		Hash:      hashToken(refreshToken),
+		CreatedAt: now.UTC(),
+		ExpiresAt: now.Add(app.config.RefreshTokenTTL).UTC(),
+	})
+	if err != nil {
+		app.logger.WithContext(ctx).Error("can't create refresh token", "error", err)
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:     accessToken,
+		AccessExpiresIn: app.config.AccessTokenTTL,
+		RefreshToken:    refreshToken,
+	}, nil
+}
+
+// hashToken hashes a token for storage. Unlike passwords, tokens are
+// random and long enough for a plain hash.
+func hashToken(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
+
+// randomToken returns 32 random bytes encoded as base64url.
+func randomToken() (string, error) {
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(random), nil
+}
+
+// generateRefreshToken returns a token of the form "srt_<secret>".
+func generateRefreshToken() (string, error) {
+	secret, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+	return refreshTokenPrefix + "_" + secret, nil
+}
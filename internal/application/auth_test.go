@@ -0,0 +1,191 @@
+package app_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	"github.com/Sergey-Polishchenko/simple-api/internal/domain"
+	"github.com/Sergey-Polishchenko/simple-api/internal/infrastructure/memory"
+	perrors "github.com/Sergey-Polishchenko/simple-api/internal/pkg/errors"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/logger"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/password"
+)
+
+// cheapParams keep the tests fast; they are far too weak for real use.
+var cheapParams = password.Params{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 16}
+
+// jsonSigner "signs" tokens by encoding their claims, so tests can read them back.
+type jsonSigner struct{}
+
+func (jsonSigner) Sign(claims map[string]interface{}) (string, error) {
+	data, err := json.Marshal(claims)
+	return string(data), err
+}
+
+type authFixture struct {
+	users *memory.UserRepo
+	auth  app.AuthService
+}
+
+func newAuthFixture(t *testing.T) *authFixture {
+	t.Helper()
+
+	users := memory.NewUserRepo()
+	auth, err := app.NewAuthApp(users, memory.NewRefreshTokenRepo(), password.NewHasher(cheapParams), jsonSigner{}, logger.NewZapLogger(), app.AuthConfig{
+		Issuer:          "simple-api",
+		AccessTokenTTL:  15 * time.Minute,
+		RefreshTokenTTL: time.Hour,
+	})
+	require.NoError(t, err)
+
+	return &authFixture{users: users, auth: auth}
+}
+
+func (f *authFixture) addUser(t *testing.T, id, pw string, params password.Params) {
+	t.Helper()
+
+	hash := ""
+	if pw != "" {
+		var err error
+		hash, err = password.NewHasher(params).Hash(pw)
+		require.NoError(t, err)
+	}
+	require.NoError(t, f.users.Create(context.Background(), domain.NewUser(id, "John").WithPasswordHash(hash)))
+}
+
+func TestAuthApp_Login(t *testing.T) {
+	f := newAuthFixture(t)
+	f.addUser(t, "1", "correct horse", cheapParams)
+	f.addUser(t, "2", "", cheapParams)
+
+	tests := []struct {
+		name        string
+		userID      string
+		password    string
+		expectedErr error
+	}{
+		{name: "success", userID: "1", password: "correct horse"},
+		{name: "wrong password", userID: "1", password: "battery staple", expectedErr: perrors.ErrInvalidCredentials},
+		{name: "unknown user", userID: "3", password: "correct horse", expectedErr: perrors.ErrInvalidCredentials},
+		{name: "user without password", userID: "2", password: "", expectedErr: perrors.ErrInvalidCredentials},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pair, err := f.auth.Login(context.Background(), app.Credentials{UserID: tt.userID, Password: tt.password})
+
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Regexp(t, `^srt_[A-Za-z0-9_-]{43}$`, pair.RefreshToken)
+			assert.Equal(t, 15*time.Minute, pair.AccessExpiresIn)
+
+			var claims map[string]interface{}
+			require.NoError(t, json.Unmarshal([]byte(pair.AccessToken), &claims))
+			assert.Equal(t, tt.userID, claims["sub"])
+			assert.Equal(t, "simple-api", claims["iss"])
+			assert.Contains(t, claims, "exp")
+		})
+	}
+}
+
+func TestAuthApp_LoginRehashesOutdatedPasswords(t *testing.T) {
+	f := newAuthFixture(t)
+	old := cheapParams
+	old.Iterations++
+	f.addUser(t, "1", "correct horse", old)
+
+	_, err := f.auth.Login(context.Background(), app.Credentials{UserID: "1", Password: "correct horse"})
+	require.NoError(t, err)
+
+	user, err := f.users.GetByID(context.Background(), "1")
+	require.NoError(t, err)
+	match, rehash, err := password.NewHasher(cheapParams).Verify("correct horse", user.PasswordHash())
+	require.NoError(t, err)
+	assert.True(t, match)
+	assert.False(t, rehash, "the hash uses the current params")
+}
+
+func TestAuthApp_Refresh(t *testing.T) {
+	f := newAuthFixture(t)
+	f.addUser(t, "1", "correct horse", cheapParams)
+	ctx := context.Background()
+
+	login, err := f.auth.Login(ctx, app.Credentials{UserID: "1", Password: "correct horse"})
+	require.NoError(t, err)
+
+	refreshed, err := f.auth.Refresh(ctx, login.RefreshToken)
+	require.NoError(t, err)
+	assert.NotEqual(t, login.RefreshToken, refreshed.RefreshToken, "refresh tokens rotate")
+
+	// Reusing the first token revokes the family, including its successor.
+	_, err = f.auth.Refresh(ctx, login.RefreshToken)
+	assert.ErrorIs(t, err, perrors.ErrInvalidRefreshToken)
+	_, err = f.auth.Refresh(ctx, refreshed.RefreshToken)
+	assert.ErrorIs(t, err, perrors.ErrInvalidRefreshToken)
+
+	// Other sessions are unaffected.
+	other, err := f.auth.Login(ctx, app.Credentials{UserID: "1", Password: "correct horse"})
+	require.NoError(t, err)
+	_, err = f.auth.Refresh(ctx, other.RefreshToken)
+	assert.NoError(t, err)
+
+	for _, invalid := range []string{"", "srt_", "srt_unknown", login.AccessToken} {
+		_, err := f.auth.Refresh(ctx, invalid)
+		assert.ErrorIs(t, err, perrors.ErrInvalidRefreshToken, invalid)
+	}
+}
+
+func TestAuthApp_RefreshDeletedUser(t *testing.T) {
+	f := newAuthFixture(t)
+	f.addUser(t, "1", "correct horse", cheapParams)
+	ctx := context.Background()
+
+	login, err := f.auth.Login(ctx, app.Credentials{UserID: "1", Password: "correct horse"})
+	require.NoError(t, err)
+	require.NoError(t, f.users.Remove(ctx, "1"))
+
+	_, err = f.auth.Refresh(ctx, login.RefreshToken)
+	assert.ErrorIs(t, err, perrors.ErrInvalidRefreshToken)
+}
+
+func TestAuthApp_RefreshWrongTenant(t *testing.T) {
+	f := newAuthFixture(t)
+	f.addUser(t, "1", "correct horse", cheapParams)
+	ctx := context.Background()
+
+	login, err := f.auth.Login(ctx, app.Credentials{UserID: "1", Password: "correct horse"})
+	require.NoError(t, err)
+
+	_, err = f.auth.Refresh(app.ContextWithTenant(ctx, "acme"), login.RefreshToken)
+	assert.ErrorIs(t, err, perrors.ErrInvalidRefreshToken)
+
+	_, err = f.auth.Refresh(ctx, login.RefreshToken)
+	assert.NoError(t, err, "the token isn't used up by the wrong tenant")
+}
+
+func TestAuthApp_Logout(t *testing.T) {
+	f := newAuthFixture(t)
+	f.addUser(t, "1", "correct horse", cheapParams)
+	ctx := context.Background()
+
+	login, err := f.auth.Login(ctx, app.Credentials{UserID: "1", Password: "correct horse"})
+	require.NoError(t, err)
+	refreshed, err := f.auth.Refresh(ctx, login.RefreshToken)
+	require.NoError(t, err)
+
+	require.NoError(t, f.auth.Logout(ctx, refreshed.RefreshToken))
+	_, err = f.auth.Refresh(ctx, refreshed.RefreshToken)
+	assert.ErrorIs(t, err, perrors.ErrInvalidRefreshToken)
+
+	assert.NoError(t, f.auth.Logout(ctx, refreshed.RefreshToken), "logging out twice is fine")
+	assert.NoError(t, f.auth.Logout(ctx, "srt_unknown"))
+}
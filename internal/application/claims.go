@@ -0,0 +1,74 @@
+package app
+
+import (
+	"context"
+	"slices"
+	"time"
+)
+
+// Scopes granted to API keys.
+const (
+	ScopeUsersRead  = "users:read"
+	ScopeUsersWrite = "users:write"
+	// ScopeUsersAdmin makes the key an admin, see DefaultPolicy.
+	ScopeUsersAdmin = "users:admin"
+)
+
+// RoleAdmin is the role of users allowed to do anything.
+const RoleAdmin = "admin"
+
+// Claims are the verified identity claims of the caller, taken from its credentials.
+type Claims struct {
+	// Subject identifies the caller, e.g. a user ID.
+	Subject string
+	// APIKeyID is set when the caller authenticated with an API key.
+	APIKeyID string
+	// ClientID is the OAuth client the token was issued to, if any.
+	ClientID string
+	// SessionID is the refresh token family the token was issued with,
+	// taken from the "sid" claim; empty for other credentials.
+	SessionID string
+	// TenantID is the tenant the credentials belong to; empty for the
+	// default tenant.
+	TenantID  string
+	Issuer    string
+	Audience  []string
+	ExpiresAt time.Time
+	// Scopes come from the space-separated "scope" claim.
+	Scopes []string
+	// Roles come from the "roles" claim.
+	Roles []string
+	// Raw holds every claim of the token, including the ones above.
+	Raw map[string]interface{}
+}
+
+// HasScope reports whether the caller was granted scope.
+func (c *Claims) HasScope(scope string) bool {
+	return slices.Contains(c.Scopes, scope)
+}
+
+// HasRole reports whether the caller has role.
+func (c *Claims) HasRole(role string) bool {
+	return slices.Contains(c.Roles, role)
+}
+
+type claimsKey struct{}
+
+// ContextWithClaims returns a copy of ctx carrying the caller's claims.
+func ContextWithClaims(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, claims)
+}
+
+// ClaimsFromContext returns the caller's claims, if the request was authenticated.
+func ClaimsFromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(*Claims)
+	return claims, ok && claims != nil
+}
+
+// actor names the caller in logs; "anonymous" when the request was not authenticated.
+func actor(ctx context.Context) string {
+	if claims, ok := ClaimsFromContext(ctx); ok {
+		return claims.Subject
+	}
+	return "anonymous"
+}
@@ -0,0 +1,84 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net/mail"
+	"strings"
+
+	perrors "github.com/Sergey-Polishchenko/simple-api/internal/pkg/errors"
+)
+
+// MaxEmailLength is the longest email address a user can have.
+const MaxEmailLength = 254
+
+// InvalidEmailError explains why an email address was rejected.
+type InvalidEmailError struct {
+	Reason string
+}
+
+func (e *InvalidEmailError) Error() string {
+	return e.Reason
+}
+
+// normalizeEmail validates a bare email address, like "john@example.com",
+// and lowercases it so that addresses are compared case-insensitively.
+func normalizeEmail(email string) (string, error) {
+	email = strings.TrimSpace(email)
+	if len(email) > MaxEmailLength {
+		return "", &InvalidEmailError{Reason: "email is too long"}
+	}
+
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Address != email || address.Name != "" {
+		return "", &InvalidEmailError{Reason: "invalid email address"}
+	}
+	return strings.ToLower(email), nil
+}
+
+func (app *UserApp) SetEmail(ctx context.Context, id, current, email string) error {
+	if err := Authorize(ctx, ActionUserUpdate, id); err != nil {
+		return err
+	}
+	if email != "" {
+		var err error
+		if email, err = normalizeEmail(email); err != nil {
+			return err
+		}
+	}
+
+	changed := false
+	err := inTransaction(ctx, app.tx, func(ctx context.Context) error {
+		user, err := app.db.GetByID(ctx, id)
+		if err != nil {
+			app.logger.WithContext(ctx).Error("can't retrive user", "error", err)
+			return err
+		}
+		if email == user.Email() {
+			return nil
+		}
+
+		// Like passwords, email addresses can take over accounts through
+		// password resets, so users changing their own prove who they are.
+		if err := app.checkCurrentPassword(ctx, user, current); err != nil {
+			return err
+		}
+
+		if err := app.db.SetEmail(ctx, id, email); err != nil {
+			if !errors.Is(err, perrors.ErrEmailTaken) {
+				app.logger.WithContext(ctx).Error("can't set email", "error", err)
+			}
+			return err
+		}
+
+		changed = true
+		return app.record(ctx, AuditUserEmailChanged, id, diffUsers(user, user.WithEmail(email, false)))
+	})
+	if err != nil || !changed {
+		return err
+	}
+
+	app.logger.WithContext(ctx).Info("Email changed", "user_id", id, "actor", actor(ctx))
+
+	return nil
+}
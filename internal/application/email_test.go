@@ -0,0 +1,79 @@
+package app_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	"github.com/Sergey-Polishchenko/simple-api/internal/domain"
+	"github.com/Sergey-Polishchenko/simple-api/internal/infrastructure/memory"
+	perrors "github.com/Sergey-Polishchenko/simple-api/internal/pkg/errors"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/logger"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/password"
+)
+
+func TestUserApp_SetEmail(t *testing.T) {
+	ctx := context.Background()
+	self := app.ContextWithClaims(ctx, &app.Claims{Subject: "1"})
+	admin := app.ContextWithClaims(ctx, &app.Claims{Subject: "3", Roles: []string{app.RoleAdmin}})
+
+	users := memory.NewUserRepo()
+	hasher := password.NewHasher(cheapParams)
+	hash, err := hasher.Hash("correct horse")
+	require.NoError(t, err)
+	service := app.NewUserApp(users, logger.NewZapLogger(), app.WithPasswordHasher(hasher))
+	require.NoError(t, users.Create(ctx, domain.NewUser("1", "John").WithPasswordHash(hash)))
+	require.NoError(t, users.Create(ctx, domain.NewUser("2", "Jane").WithEmail("jane@example.com", false)))
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		id      string
+		current string
+		email   string
+		want    string
+		err     error
+	}{
+		{name: "wrong password", ctx: self, id: "1", current: "battery staple", email: "john@example.com", err: perrors.ErrInvalidCredentials},
+		{name: "own email", ctx: self, id: "1", current: "correct horse", email: " John@Example.com", want: "john@example.com"},
+		{name: "unchanged", ctx: self, id: "1", email: "john@example.com", want: "john@example.com"},
+		{name: "taken", ctx: admin, id: "1", email: "JANE@example.com", want: "john@example.com", err: perrors.ErrEmailTaken},
+		{name: "other user", ctx: self, id: "2", email: "john@example.org", err: perrors.ErrForbidden},
+		{name: "admin", ctx: admin, id: "1", email: "john@example.org", want: "john@example.org"},
+		{name: "removed", ctx: admin, id: "1", email: "", want: ""},
+		{name: "unknown user", ctx: admin, id: "4", email: "john@example.org", err: perrors.ErrUserNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := service.SetEmail(tt.ctx, tt.id, tt.current, tt.email)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+			} else {
+				require.NoError(t, err)
+			}
+			if tt.id != "1" {
+				return
+			}
+
+			user, err := users.GetByID(ctx, "1")
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, user.Email())
+			assert.False(t, user.EmailVerified())
+		})
+	}
+}
+
+func TestUserApp_SetEmailInvalid(t *testing.T) {
+	admin := app.ContextWithClaims(context.Background(), &app.Claims{Subject: "2", Roles: []string{app.RoleAdmin}})
+	service := app.NewUserApp(memory.NewUserRepo(), logger.NewZapLogger())
+
+	for _, email := range []string{"john", "John <john@example.com>", "john@example.com, jane@example.com", strings.Repeat("a", 250) + "@example.com"} {
+		var invalid *app.InvalidEmailError
+		assert.ErrorAs(t, service.SetEmail(admin, "1", "", email), &invalid, email)
+	}
+}
@@ -0,0 +1,89 @@
+package app
+
+import (
+	"context"
+	"sync"
+
+	"github.com/Sergey-Polishchenko/simple-api/internal/domain"
+)
+
+// UserEventType describes the kind of change applied to a user.
+type UserEventType string
+
+const (
+	UserCreated UserEventType = "CREATED"
+	UserUpdated UserEventType = "UPDATED"
+	UserRemoved UserEventType = "DELETED"
+)
+
+// UserEvent is emitted after a user has been changed.
+type UserEvent struct {
+	Type   UserEventType
+	UserID string
+	// TenantID is the tenant of the user.
+	TenantID string
+	// User holds the new state; nil for removals.
+	User *domain.User
+}
+
+// UserEventPublisher receives user change notifications.
+type UserEventPublisher interface {
+	Publish(ctx context.Context, event UserEvent)
+}
+
+// UserEventSubscriber streams user change notifications.
+type UserEventSubscriber interface {
+	// Subscribe returns a channel that is closed once ctx is done.
+	Subscribe(ctx context.Context) <-chan UserEvent
+}
+
+// subscriberBuffer is the number of events buffered per subscriber.
+const subscriberBuffer = 16
+
+// UserEventBroker is an in-process fan-out of user events.
+// Slow subscribers drop events instead of blocking publishers.
+type UserEventBroker struct {
+	mu   sync.RWMutex
+	subs map[chan UserEvent]struct{}
+}
+
+// NewUserEventBroker creates an empty UserEventBroker.
+func NewUserEventBroker() *UserEventBroker {
+	return &UserEventBroker{subs: make(map[chan UserEvent]struct{})}
+}
+
+func (b *UserEventBroker) Publish(_ context.Context, event UserEvent) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for ch := range b.subs {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+func (b *UserEventBroker) Subscribe(ctx context.Context) <-chan UserEvent {
+	ch := make(chan UserEvent, subscriberBuffer)
+
+	b.mu.Lock()
+	b.subs[ch] = struct{}{}
+	b.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		b.mu.Lock()
+		delete(b.subs, ch)
+		close(ch)
+		b.mu.Unlock()
+	}()
+
+	return ch
+}
+
+var (
+	_ UserEventPublisher  = (*UserEventBroker)(nil)
+	_ UserEventSubscriber = (*UserEventBroker)(nil)
+)
@@ -0,0 +1,297 @@
+package app
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+
+	"github.com/Sergey-Polishchenko/simple-api/internal/domain"
+	perrors "github.com/Sergey-Polishchenko/simple-api/internal/pkg/errors"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/logger"
+)
+
+const (
+	MaxGroupNameLength        = 100
+	MaxGroupDescriptionLength = 1000
+)
+
+// GroupRepository defines persistence operations for groups and their
+// memberships. Groups belong to the tenant of the context, like users.
+type GroupRepository interface {
+	// Stores a new group.
+	Create(ctx context.Context, group *domain.Group) error
+	// Retrieves a group by ID.
+	GetByID(ctx context.Context, id string) (*domain.Group, error)
+	// Retrieves all groups ordered by name.
+	GetAll(ctx context.Context) ([]*domain.Group, error)
+	// Updates the name and description of a group.
+	Update(ctx context.Context, group *domain.Group) error
+	// Deletes a group along with its memberships, in either direction.
+	Remove(ctx context.Context, id string) error
+	// Adds a user to a group; adding a member again is not an error.
+	AddUser(ctx context.Context, groupID, userID string) error
+	// Nests a group in another, returning perrors.ErrGroupCycle if the
+	// parent is the child or nested in it. The check and the insertion are
+	// atomic, so that concurrent nestings can't create a cycle either.
+	AddGroup(ctx context.Context, parentID, childID string) error
+	// Removes a direct member, user or group, from a group.
+	RemoveMember(ctx context.Context, groupID, memberID string) error
+	// Retrieves the IDs of the users and the groups directly in a group,
+	// the latter ordered by name.
+	Members(ctx context.Context, groupID string) ([]string, []*domain.Group, error)
+	// Retrieves the groups a user belongs to ordered by name, including
+	// the ones containing them through nested groups unless direct.
+	GetByUser(ctx context.Context, userID string, direct bool) ([]*domain.Group, error)
+}
+
+// GroupMember is a user or a group; exactly one of the IDs is set.
+type GroupMember struct {
+	UserID  string
+	GroupID string
+}
+
+// GroupMembers are the direct members of a group.
+type GroupMembers struct {
+	Users  []*domain.User
+	Groups []*domain.Group
+}
+
+// GroupService defines the management of groups of users.
+type GroupService interface {
+	// Creates a group.
+	Create(ctx context.Context, group *domain.Group) (*domain.Group, error)
+	// Fetches a group by ID.
+	Get(ctx context.Context, id string) (*domain.Group, error)
+	// Retrieves all groups.
+	List(ctx context.Context) ([]*domain.Group, error)
+	// Updates the name and description of a group.
+	Update(ctx context.Context, group *domain.Group) error
+	// Deletes a group; its members stay, in the other groups they belong to.
+	Remove(ctx context.Context, id string) error
+	// Adds a user or a group to a group.
+	AddMember(ctx context.Context, groupID string, member GroupMember) error
+	// Removes a direct member, user or group, from a group.
+	RemoveMember(ctx context.Context, groupID, memberID string) error
+	// Retrieves the direct members of a group.
+	Members(ctx context.Context, groupID string) (*GroupMembers, error)
+	// Retrieves the groups a user belongs to, through nested groups too unless direct.
+	UserGroups(ctx context.Context, userID string, direct bool) ([]*domain.Group, error)
+}
+
+// GroupApp implements GroupService.
+type GroupApp struct {
+	db     GroupRepository
+	users  UserRepository
+	logger logger.Logger
+}
+
+// NewGroupApp initializes a GroupApp instance. users resolves the users
+// added to groups and listed as members.
+func NewGroupApp(db GroupRepository, users UserRepository, logger logger.Logger) GroupService {
+	return &GroupApp{db: db, users: users, logger: logger}
+}
+
+// InvalidGroupError explains why a group or a membership was rejected.
+type InvalidGroupError struct {
+	Reason string
+}
+
+func (e *InvalidGroupError) Error() string {
+	return e.Reason
+}
+
+func (app *GroupApp) Create(ctx context.Context, group *domain.Group) (*domain.Group, error) {
+	if err := Authorize(ctx, ActionGroupWrite, ""); err != nil {
+		return nil, err
+	}
+
+	group = domain.NewGroup(uuid.New().String(), group.Name(), group.Description())
+	if err := validateGroup(group); err != nil {
+		return nil, err
+	}
+
+	if err := app.db.Create(ctx, group); err != nil {
+		if !errors.Is(err, perrors.ErrGroupNameTaken) {
+			app.logger.WithContext(ctx).Error("can't create group", "error", err)
+		}
+		return nil, err
+	}
+
+	app.logger.WithContext(ctx).Info("Group created", "group_id", group.ID(), "actor", actor(ctx))
+
+	return group, nil
+}
+
+func (app *GroupApp) Get(ctx context.Context, id string) (*domain.Group, error) {
+	if err := Authorize(ctx, ActionGroupRead, id); err != nil {
+		return nil, err
+	}
+
+	group, err := app.db.GetByID(ctx, id)
+	if err != nil && !errors.Is(err, perrors.ErrGroupNotFound) {
+		app.logger.WithContext(ctx).Error("can't retrive group", "error", err)
+	}
+	return group, err
+}
+
+func (app *GroupApp) List(ctx context.Context) ([]*domain.Group, error) {
+	if err := Authorize(ctx, ActionGroupRead, ""); err != nil {
+		return nil, err
+	}
+
+	groups, err := app.db.GetAll(ctx)
+	if err != nil {
+		app.logger.WithContext(ctx).Error("can't retrive groups", "error", err)
+	}
+	return groups, err
+}
+
+func (app *GroupApp) Update(ctx context.Context, group *domain.Group) error {
+	if err := Authorize(ctx, ActionGroupWrite, group.ID()); err != nil {
+		return err
+	}
+	if err := validateGroup(group); err != nil {
+		return err
+	}
+
+	if err := app.db.Update(ctx, group); err != nil {
+		if !errors.Is(err, perrors.ErrGroupNotFound) && !errors.Is(err, perrors.ErrGroupNameTaken) {
+			app.logger.WithContext(ctx).Error("can't update group", "error", err)
+		}
+		return err
+	}
+
+	app.logger.WithContext(ctx).Info("Group updated", "group_id", group.ID(), "actor", actor(ctx))
+
+	return nil
+}
+
+func (app *GroupApp) Remove(ctx context.Context, id string) error {
+	if err := Authorize(ctx, ActionGroupWrite, id); err != nil {
+		return err
+	}
+
+	if err := app.db.Remove(ctx, id); err != nil {
+		if !errors.Is(err, perrors.ErrGroupNotFound) {
+			app.logger.WithContext(ctx).Error("can't remove group", "error", err)
+		}
+		return err
+	}
+
+	app.logger.WithContext(ctx).Info("Group removed", "group_id", id, "actor", actor(ctx))
+
+	return nil
+}
+
+func (app *GroupApp) AddMember(ctx context.Context, groupID string, member GroupMember) error {
+	if err := Authorize(ctx, ActionGroupWrite, groupID); err != nil {
+		return err
+	}
+	if (member.UserID == "") == (member.GroupID == "") {
+		return &InvalidGroupError{Reason: "either a user or a group is required"}
+	}
+
+	// Look the group and the member up in the tenant of ctx, so that
+	// memberships never cross tenants.
+	if _, err := app.db.GetByID(ctx, groupID); err != nil {
+		return err
+	}
+
+	var err error
+	if member.UserID != "" {
+		if _, err = app.users.GetByID(ctx, member.UserID); err != nil {
+			return err
+		}
+		err = app.db.AddUser(ctx, groupID, member.UserID)
+	} else {
+		if _, err = app.db.GetByID(ctx, member.GroupID); err != nil {
+			return err
+		}
+		err = app.db.AddGroup(ctx, groupID, member.GroupID)
+	}
+	if err != nil {
+		if !errors.Is(err, perrors.ErrGroupCycle) {
+			app.logger.WithContext(ctx).Error("can't add group member", "error", err)
+		}
+		return err
+	}
+
+	app.logger.WithContext(ctx).Info("Group member added", "group_id", groupID,
+		"user_id", member.UserID, "member_group_id", member.GroupID, "actor", actor(ctx))
+
+	return nil
+}
+
+func (app *GroupApp) RemoveMember(ctx context.Context, groupID, memberID string) error {
+	if err := Authorize(ctx, ActionGroupWrite, groupID); err != nil {
+		return err
+	}
+	if _, err := app.db.GetByID(ctx, groupID); err != nil {
+		return err
+	}
+
+	if err := app.db.RemoveMember(ctx, groupID, memberID); err != nil {
+		if !errors.Is(err, perrors.ErrGroupMemberNotFound) {
+			app.logger.WithContext(ctx).Error("can't remove group member", "error", err)
+		}
+		return err
+	}
+
+	app.logger.WithContext(ctx).Info("Group member removed", "group_id", groupID, "member_id", memberID, "actor", actor(ctx))
+
+	return nil
+}
+
+func (app *GroupApp) Members(ctx context.Context, groupID string) (*GroupMembers, error) {
+	if err := Authorize(ctx, ActionGroupRead, groupID); err != nil {
+		return nil, err
+	}
+	if _, err := app.db.GetByID(ctx, groupID); err != nil {
+		return nil, err
+	}
+
+	userIDs, groups, err := app.db.Members(ctx, groupID)
+	if err != nil {
+		app.logger.WithContext(ctx).Error("can't retrive group members", "error", err)
+		return nil, err
+	}
+
+	users := []*domain.User{}
+	if len(userIDs) > 0 {
+		if users, err = app.users.GetByIDs(ctx, userIDs); err != nil {
+			app.logger.WithContext(ctx).Error("can't retrive group members", "error", err)
+			return nil, err
+		}
+	}
+
+	return &GroupMembers{Users: users, Groups: groups}, nil
+}
+
+func (app *GroupApp) UserGroups(ctx context.Context, userID string, direct bool) ([]*domain.Group, error) {
+	if err := Authorize(ctx, ActionUserRead, userID); err != nil {
+		return nil, err
+	}
+	if _, err := app.users.GetByID(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	groups, err := app.db.GetByUser(ctx, userID, direct)
+	if err != nil {
+		app.logger.WithContext(ctx).Error("can't retrive groups of user", "error", err)
+	}
+	return groups, err
+}
+
+func validateGroup(group *domain.Group) error {
+	if group.Name() == "" {
+		return &InvalidGroupError{Reason: "name is required"}
+	}
+	if len([]rune(group.Name())) > MaxGroupNameLength {
+		return &InvalidGroupError{Reason: "name is too long"}
+	}
+	if len([]rune(group.Description())) > MaxGroupDescriptionLength {
+		return &InvalidGroupError{Reason: "description is too long"}
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package app_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	"github.com/Sergey-Polishchenko/simple-api/internal/domain"
+	"github.com/Sergey-Polishchenko/simple-api/internal/infrastructure/memory"
+	perrors "github.com/Sergey-Polishchenko/simple-api/internal/pkg/errors"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/logger"
+)
+
+// newGroupFixture returns a GroupService with the users "1" and "2".
+func newGroupFixture(t *testing.T) app.GroupService {
+	users := memory.NewUserRepo()
+	for _, id := range []string{"1", "2"} {
+		require.NoError(t, users.Create(context.Background(), domain.NewUser(id, "User "+id)))
+	}
+	return app.NewGroupApp(memory.NewGroupRepo(), users, logger.NewZapLogger())
+}
+
+// createGroups creates groups with the given names and returns their IDs.
+func createGroups(t *testing.T, service app.GroupService, names ...string) []string {
+	ids := make([]string, len(names))
+	for i, name := range names {
+		group, err := service.Create(adminContext(), domain.NewGroup("", name, ""))
+		require.NoError(t, err)
+		ids[i] = group.ID()
+	}
+	return ids
+}
+
+func groupNames(groups []*domain.Group) []string {
+	names := make([]string, len(groups))
+	for i, group := range groups {
+		names[i] = group.Name()
+	}
+	return names
+}
+
+func TestGroupApp_Create(t *testing.T) {
+	tests := []struct {
+		name        string
+		ctx         context.Context
+		group       *domain.Group
+		expectedErr string
+	}{
+		{
+			name:  "success",
+			ctx:   adminContext(),
+			group: domain.NewGroup("", "Engineering", "Builds things"),
+		},
+		{
+			name:        "user",
+			ctx:         userContext("1"),
+			group:       domain.NewGroup("", "Engineering", ""),
+			expectedErr: perrors.ErrForbidden.Error(),
+		},
+		{
+			name:        "missing name",
+			ctx:         adminContext(),
+			group:       domain.NewGroup("", "  ", ""),
+			expectedErr: "name is required",
+		},
+		{
+			name:        "taken name",
+			ctx:         adminContext(),
+			group:       domain.NewGroup("", "Existing", ""),
+			expectedErr: perrors.ErrGroupNameTaken.Error(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := newGroupFixture(t)
+			createGroups(t, service, "Existing")
+
+			group, err := service.Create(tt.ctx, tt.group)
+			if tt.expectedErr != "" {
+				assert.EqualError(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.NotEmpty(t, group.ID())
+
+			found, err := service.Get(tt.ctx, group.ID())
+			require.NoError(t, err)
+			assert.Equal(t, group, found)
+		})
+	}
+}
+
+func TestGroupApp_Nesting(t *testing.T) {
+	service := newGroupFixture(t)
+	ctx := adminContext()
+	ids := createGroups(t, service, "Company", "Engineering", "Backend")
+	company, engineering, backend := ids[0], ids[1], ids[2]
+
+	require.NoError(t, service.AddMember(ctx, company, app.GroupMember{GroupID: engineering}))
+	require.NoError(t, service.AddMember(ctx, engineering, app.GroupMember{GroupID: backend}))
+	require.NoError(t, service.AddMember(ctx, backend, app.GroupMember{UserID: "1"}))
+	require.NoError(t, service.AddMember(ctx, backend, app.GroupMember{UserID: "1"}), "adding again is a no-op")
+	require.NoError(t, service.AddMember(ctx, company, app.GroupMember{UserID: "2"}))
+
+	tests := []struct {
+		name   string
+		parent string
+		child  string
+	}{
+		{name: "itself", parent: backend, child: backend},
+		{name: "its parent", parent: backend, child: engineering},
+		{name: "a transitive parent", parent: backend, child: company},
+	}
+	for _, tt := range tests {
+		t.Run("cycle through "+tt.name, func(t *testing.T) {
+			err := service.AddMember(ctx, tt.parent, app.GroupMember{GroupID: tt.child})
+			assert.ErrorIs(t, err, perrors.ErrGroupCycle)
+		})
+	}
+
+	groups, err := service.UserGroups(ctx, "1", false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Backend", "Company", "Engineering"}, groupNames(groups))
+
+	groups, err = service.UserGroups(userContext("1"), "1", true)
+	require.NoError(t, err, "users read their own groups")
+	assert.Equal(t, []string{"Backend"}, groupNames(groups))
+
+	_, err = service.UserGroups(userContext("1"), "2", false)
+	assert.ErrorIs(t, err, perrors.ErrForbidden)
+
+	members, err := service.Members(ctx, company)
+	require.NoError(t, err)
+	require.Len(t, members.Users, 1)
+	assert.Equal(t, "2", members.Users[0].ID())
+	assert.Equal(t, []string{"Engineering"}, groupNames(members.Groups))
+
+	// Removing the middle group cuts the path from the user to the top.
+	require.NoError(t, service.Remove(ctx, engineering))
+	groups, err = service.UserGroups(ctx, "1", false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Backend"}, groupNames(groups))
+
+	require.NoError(t, service.RemoveMember(ctx, backend, "1"))
+	assert.ErrorIs(t, service.RemoveMember(ctx, backend, "1"), perrors.ErrGroupMemberNotFound)
+}
+
+func TestGroupApp_AddMemberErrors(t *testing.T) {
+	service := newGroupFixture(t)
+	ctx := adminContext()
+	group := createGroups(t, service, "Engineering")[0]
+
+	var invalid *app.InvalidGroupError
+	assert.ErrorAs(t, service.AddMember(ctx, group, app.GroupMember{}), &invalid)
+	assert.ErrorAs(t, service.AddMember(ctx, group, app.GroupMember{UserID: "1", GroupID: group}), &invalid)
+	assert.ErrorIs(t, service.AddMember(ctx, "missing", app.GroupMember{UserID: "1"}), perrors.ErrGroupNotFound)
+	assert.ErrorIs(t, service.AddMember(ctx, group, app.GroupMember{UserID: "missing"}), perrors.ErrUserNotFound)
+	assert.ErrorIs(t, service.AddMember(ctx, group, app.GroupMember{GroupID: "missing"}), perrors.ErrGroupNotFound)
+	assert.ErrorIs(t, service.AddMember(userContext("1"), group, app.GroupMember{UserID: "1"}), perrors.ErrForbidden)
+
+	// Users and groups of other tenants are not found.
+	acme := app.ContextWithTenant(ctx, "acme")
+	other, err := service.Create(acme, domain.NewGroup("", "Engineering", ""))
+	require.NoError(t, err, "names are unique per tenant")
+	assert.ErrorIs(t, service.AddMember(acme, other.ID(), app.GroupMember{UserID: "1"}), perrors.ErrUserNotFound)
+	assert.ErrorIs(t, service.AddMember(ctx, group, app.GroupMember{GroupID: other.ID()}), perrors.ErrGroupNotFound)
+}
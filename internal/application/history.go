@@ -0,0 +1,186 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Sergey-Polishchenko/simple-api/internal/domain"
+	perrors "github.com/Sergey-Polishchenko/simple-api/internal/pkg/errors"
+)
+
+// UserVersion is a user as it was from ValidFrom until ValidTo. Versions
+// don't keep password hashes, so reverting never restores a password.
+type UserVersion struct {
+	// Version numbers the versions of a user from 1.
+	Version   int
+	User      *domain.User
+	ValidFrom time.Time
+	// ValidTo is zero for the current version of a user.
+	ValidTo time.Time
+}
+
+// Current reports whether the version is the current one of the user.
+func (v *UserVersion) Current() bool {
+	return v.ValidTo.IsZero()
+}
+
+// VersionListParams describes a keyset-paginated request for the versions
+// of a user, newest first.
+type VersionListParams struct {
+	// After is the last version of the previous page.
+	After int
+	Limit int
+}
+
+// normalize clamps the limit to the allowed range.
+func (p VersionListParams) normalize() VersionListParams {
+	switch {
+	case p.Limit <= 0:
+		p.Limit = DefaultPageSize
+	case p.Limit > MaxPageSize:
+		p.Limit = MaxPageSize
+	}
+	return p
+}
+
+// UserVersionPage is a single page of the versions of a user, newest first.
+type UserVersionPage struct {
+	Versions    []*UserVersion
+	HasNextPage bool
+}
+
+// UserHistoryRepository reads the versions of users, which the user
+// repository keeps on every change of a name, email address or attributes,
+// in the tenant of the context. Versions outlive their users.
+type UserHistoryRepository interface {
+	// Retrieves the version of a user valid at the given time, failing with
+	// perrors.ErrUserNotFound if the user didn't exist then.
+	GetAsOf(ctx context.Context, id string, at time.Time) (*UserVersion, error)
+	// Retrieves a version of a user.
+	GetVersion(ctx context.Context, id string, version int) (*UserVersion, error)
+	// Retrieves up to params.Limit versions of a user preceding
+	// params.After, newest first.
+	ListVersions(ctx context.Context, id string, params VersionListParams) ([]*UserVersion, error)
+	// Deletes the versions of a user, reporting how many there were.
+	Erase(ctx context.Context, id string) (int, error)
+}
+
+// WithUserHistory lets UserApp read the past versions of users and revert
+// them. Without it, those operations fail with perrors.ErrHistoryDisabled.
+func WithUserHistory(history UserHistoryRepository) Option {
+	return func(app *UserApp) {
+		app.history = history
+	}
+}
+
+func (app *UserApp) GetUserAsOf(ctx context.Context, id string, at time.Time) (*domain.User, error) {
+	if err := Authorize(ctx, ActionUserRead, id); err != nil {
+		return nil, err
+	}
+	if app.history == nil {
+		return nil, perrors.ErrHistoryDisabled
+	}
+
+	version, err := app.history.GetAsOf(ctx, id, at)
+	if err != nil {
+		if !errors.Is(err, perrors.ErrUserNotFound) {
+			app.logger.WithContext(ctx).Error("can't retrive user version", "error", err)
+		}
+		return nil, err
+	}
+
+	app.logger.WithContext(ctx).Info("User retrived as of", "user_id", id, "version", version.Version)
+
+	return version.User, nil
+}
+
+func (app *UserApp) Versions(ctx context.Context, id string, params VersionListParams) (*UserVersionPage, error) {
+	if err := Authorize(ctx, ActionUserRead, id); err != nil {
+		return nil, err
+	}
+	if app.history == nil {
+		return nil, perrors.ErrHistoryDisabled
+	}
+
+	params = params.normalize()
+	limit := params.Limit
+
+	// Fetch one extra version to find out whether another page exists.
+	params.Limit++
+	versions, err := app.history.ListVersions(ctx, id, params)
+	if err != nil {
+		app.logger.WithContext(ctx).Error("can't list user versions", "error", err)
+		return nil, err
+	}
+	if len(versions) == 0 && params.After == 0 {
+		return nil, perrors.ErrUserNotFound
+	}
+
+	page := &UserVersionPage{Versions: versions}
+	if len(versions) > limit {
+		page.Versions = versions[:limit]
+		page.HasNextPage = true
+	}
+	return page, nil
+}
+
+func (app *UserApp) Revert(ctx context.Context, id string, version int) (*domain.User, error) {
+	if err := Authorize(ctx, ActionUserRevert, id); err != nil {
+		return nil, err
+	}
+	if app.history == nil {
+		return nil, perrors.ErrHistoryDisabled
+	}
+
+	var reverted *domain.User
+	err := inTransaction(ctx, app.tx, func(ctx context.Context) error {
+		target, err := app.history.GetVersion(ctx, id, version)
+		if err != nil {
+			if !errors.Is(err, perrors.ErrUserVersionNotFound) {
+				app.logger.WithContext(ctx).Error("can't retrive user version", "error", err)
+			}
+			return err
+		}
+		current, err := app.db.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		// The schema may have changed since, so old attributes are checked
+		// like new ones.
+		attributes, err := app.validateAttributes(ctx, target.User.Attributes())
+		if err != nil {
+			return err
+		}
+
+		// The email address goes first, as it is the change that can fail.
+		if email := target.User.Email(); email != current.Email() {
+			if err := app.db.SetEmail(ctx, id, email); err != nil {
+				if !errors.Is(err, perrors.ErrEmailTaken) {
+					app.logger.WithContext(ctx).Error("can't set email", "error", err)
+				}
+				return err
+			}
+		}
+		if err := app.db.Update(ctx, domain.NewUser(id, target.User.Name()).WithAttributes(attributes)); err != nil {
+			app.logger.WithContext(ctx).Error("can't revert user", "error", err)
+			return err
+		}
+
+		if reverted, err = app.db.GetByID(ctx, id); err != nil {
+			app.logger.WithContext(ctx).Error("can't retrive user", "error", err)
+			return err
+		}
+
+		return app.record(ctx, AuditUserReverted, id, diffUsers(current, reverted))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	app.logger.WithContext(ctx).Info("User reverted", "user_id", id, "version", version, "actor", actor(ctx))
+	app.publish(ctx, UserEvent{Type: UserUpdated, UserID: id, User: reverted})
+
+	return reverted, nil
+}
@@ -0,0 +1,291 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	"github.com/Sergey-Polishchenko/simple-api/internal/domain"
+	"github.com/Sergey-Polishchenko/simple-api/internal/infrastructure/memory"
+	perrors "github.com/Sergey-Polishchenko/simple-api/internal/pkg/errors"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/logger"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/password"
+)
+
+var historyStart = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// newHistoryFixture returns a service keeping the history of users in a
+// repository whose clock moves by an hour with every call of tick.
+func newHistoryFixture() (app.UserService, *memory.UserRepo, func()) {
+	repo := memory.NewUserRepo()
+	now := historyStart
+	repo.SetClock(func() time.Time { return now })
+
+	service := app.NewUserApp(repo, logger.NewZapLogger(),
+		app.WithPasswordHasher(password.NewHasher(cheapParams)), app.WithUserHistory(repo))
+	return service, repo, func() { now = now.Add(time.Hour) }
+}
+
+func TestUserApp_GetUserAsOf(t *testing.T) {
+	service, _, tick := newHistoryFixture()
+	ctx := adminContext()
+
+	user, err := service.Create(ctx, domain.NewUser("", "Alice"))
+	require.NoError(t, err)
+	tick()
+	require.NoError(t, service.Update(ctx, domain.NewUser(user.ID(), "Alicia")))
+	tick()
+	require.NoError(t, service.SetEmail(ctx, user.ID(), "", "alicia@example.com"))
+	tick()
+	require.NoError(t, service.SetPassword(ctx, user.ID(), "", "correct horse"))
+	tick()
+	require.NoError(t, service.Remove(ctx, user.ID()))
+
+	tests := []struct {
+		name          string
+		at            time.Time
+		expectedName  string
+		expectedEmail string
+		expectedErr   error
+	}{
+		{name: "before creation", at: historyStart.Add(-time.Second), expectedErr: perrors.ErrUserNotFound},
+		{name: "at creation", at: historyStart, expectedName: "Alice"},
+		{name: "before update", at: historyStart.Add(time.Hour - time.Second), expectedName: "Alice"},
+		{name: "after update", at: historyStart.Add(time.Hour), expectedName: "Alicia"},
+		{
+			name:          "after email change",
+			at:            historyStart.Add(2 * time.Hour),
+			expectedName:  "Alicia",
+			expectedEmail: "alicia@example.com",
+		},
+		{
+			name:          "password changes are not versions",
+			at:            historyStart.Add(3*time.Hour + time.Minute),
+			expectedName:  "Alicia",
+			expectedEmail: "alicia@example.com",
+		},
+		{name: "after removal", at: historyStart.Add(4 * time.Hour), expectedErr: perrors.ErrUserNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, err := service.GetUserAsOf(ctx, user.ID(), tt.at)
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedName, user.Name())
+			assert.Equal(t, tt.expectedEmail, user.Email())
+			assert.Empty(t, user.PasswordHash())
+		})
+	}
+
+	t.Run("other tenant", func(t *testing.T) {
+		acme := app.ContextWithTenant(tenantAdminContext("acme"), "acme")
+		_, err := service.GetUserAsOf(acme, user.ID(), historyStart)
+		assert.ErrorIs(t, err, perrors.ErrUserNotFound)
+	})
+}
+
+func TestUserApp_Versions(t *testing.T) {
+	service, _, tick := newHistoryFixture()
+	ctx := adminContext()
+
+	user, err := service.Create(ctx, domain.NewUser("", "v1"))
+	require.NoError(t, err)
+	for _, name := range []string{"v2", "v3"} {
+		tick()
+		require.NoError(t, service.Update(ctx, domain.NewUser(user.ID(), name)))
+	}
+	// Updates changing nothing make no version.
+	require.NoError(t, service.Update(ctx, domain.NewUser(user.ID(), "v3")))
+
+	page, err := service.Versions(ctx, user.ID(), app.VersionListParams{Limit: 2})
+	require.NoError(t, err)
+	assert.True(t, page.HasNextPage)
+	require.Len(t, page.Versions, 2)
+	assert.Equal(t, 3, page.Versions[0].Version)
+	assert.Equal(t, "v3", page.Versions[0].User.Name())
+	assert.True(t, page.Versions[0].Current())
+	assert.Equal(t, historyStart.Add(2*time.Hour), page.Versions[1].ValidTo)
+
+	page, err = service.Versions(ctx, user.ID(), app.VersionListParams{Limit: 2, After: 2})
+	require.NoError(t, err)
+	assert.False(t, page.HasNextPage)
+	require.Len(t, page.Versions, 1)
+	assert.Equal(t, "v1", page.Versions[0].User.Name())
+	assert.Equal(t, historyStart, page.Versions[0].ValidFrom)
+
+	tick()
+	require.NoError(t, service.Remove(ctx, user.ID()))
+	page, err = service.Versions(ctx, user.ID(), app.VersionListParams{})
+	require.NoError(t, err)
+	require.Len(t, page.Versions, 3, "versions outlive their users")
+	assert.Equal(t, historyStart.Add(3*time.Hour), page.Versions[0].ValidTo)
+
+	_, err = service.Versions(ctx, "missing", app.VersionListParams{})
+	assert.ErrorIs(t, err, perrors.ErrUserNotFound)
+}
+
+func TestUserApp_Revert(t *testing.T) {
+	tests := []struct {
+		name          string
+		ctx           func(userID string) context.Context
+		version       int
+		setup         func(t *testing.T, service app.UserService)
+		expectedName  string
+		expectedEmail string
+		expectedErr   error
+	}{
+		{
+			name:          "first version",
+			version:       1,
+			expectedName:  "Alice",
+			expectedEmail: "alice@example.com",
+		},
+		{
+			name:          "latest version",
+			version:       3,
+			expectedName:  "Alicia",
+			expectedEmail: "alicia@example.com",
+		},
+		{
+			name:        "unknown version",
+			version:     9,
+			expectedErr: perrors.ErrUserVersionNotFound,
+		},
+		{
+			name:    "email taken since",
+			version: 1,
+			setup: func(t *testing.T, service app.UserService) {
+				_, err := service.Create(adminContext(), domain.NewUser("", "Eve").WithEmail("alice@example.com", false))
+				require.NoError(t, err)
+			},
+			expectedErr: perrors.ErrEmailTaken,
+		},
+		{
+			name:        "by the user",
+			ctx:         func(userID string) context.Context { return userContext(userID) },
+			version:     1,
+			expectedErr: perrors.ErrForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, _, tick := newHistoryFixture()
+			user, err := service.Create(adminContext(), domain.NewUser("", "Alice").WithEmail("alice@example.com", false))
+			require.NoError(t, err)
+			tick()
+			require.NoError(t, service.Update(adminContext(), domain.NewUser(user.ID(), "Alicia")))
+			tick()
+			require.NoError(t, service.SetEmail(adminContext(), user.ID(), "", "alicia@example.com"))
+			if tt.setup != nil {
+				tt.setup(t, service)
+			}
+
+			ctx := adminContext()
+			if tt.ctx != nil {
+				ctx = tt.ctx(user.ID())
+			}
+			tick()
+			reverted, err := service.Revert(ctx, user.ID(), tt.version)
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedName, reverted.Name())
+			assert.Equal(t, tt.expectedEmail, reverted.Email())
+			assert.False(t, reverted.EmailVerified())
+
+			fetched, err := service.GetUser(adminContext(), user.ID())
+			require.NoError(t, err)
+			assert.Equal(t, reverted, fetched)
+		})
+	}
+}
+
+func TestUserApp_RevertKeepsPassword(t *testing.T) {
+	service, repo, tick := newHistoryFixture()
+	ctx := adminContext()
+
+	user, err := service.Create(ctx, domain.NewUser("", "Alice"))
+	require.NoError(t, err)
+	tick()
+	require.NoError(t, service.SetPassword(ctx, user.ID(), "", "correct horse"))
+	require.NoError(t, service.Update(ctx, domain.NewUser(user.ID(), "Alicia")))
+	stored, err := repo.GetByID(ctx, user.ID())
+	require.NoError(t, err)
+
+	tick()
+	reverted, err := service.Revert(ctx, user.ID(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, "Alice", reverted.Name())
+	assert.Equal(t, stored.PasswordHash(), reverted.PasswordHash())
+
+	page, err := service.Versions(ctx, user.ID(), app.VersionListParams{})
+	require.NoError(t, err)
+	require.Len(t, page.Versions, 3, "a revert is a version of its own")
+	assert.Equal(t, "Alice", page.Versions[0].User.Name())
+}
+
+func TestUserApp_HistoryDisabled(t *testing.T) {
+	service := app.NewUserApp(memory.NewUserRepo(), logger.NewZapLogger())
+	user, err := service.Create(adminContext(), domain.NewUser("", "Alice"))
+	require.NoError(t, err)
+
+	_, err = service.GetUserAsOf(adminContext(), user.ID(), time.Now())
+	assert.ErrorIs(t, err, perrors.ErrHistoryDisabled)
+	_, err = service.Versions(adminContext(), user.ID(), app.VersionListParams{})
+	assert.ErrorIs(t, err, perrors.ErrHistoryDisabled)
+	_, err = service.Revert(adminContext(), user.ID(), 1)
+	assert.ErrorIs(t, err, perrors.ErrHistoryDisabled)
+}
+
+// flakyHistory fails to erase versions until fixed.
+type flakyHistory struct {
+	*memory.UserRepo
+	broken bool
+}
+
+func (h *flakyHistory) Erase(ctx context.Context, id string) (int, error) {
+	if h.broken {
+		return 0, errors.New("history unavailable")
+	}
+	return h.UserRepo.Erase(ctx, id)
+}
+
+func TestUserApp_Erase(t *testing.T) {
+	repo := memory.NewUserRepo()
+	history := &flakyHistory{UserRepo: repo, broken: true}
+	service := app.NewUserApp(repo, logger.NewZapLogger(),
+		app.WithPasswordHasher(password.NewHasher(cheapParams)), app.WithUserHistory(history))
+	ctx := adminContext()
+
+	user, err := service.Create(ctx, domain.NewUser("", "Alice"))
+	require.NoError(t, err)
+	require.NoError(t, service.Update(ctx, domain.NewUser(user.ID(), "Alicia")))
+
+	// A failure leaves the user removed, not a user without history.
+	_, err = service.Erase(ctx, user.ID())
+	assert.Error(t, err)
+	_, err = service.GetUser(ctx, user.ID())
+	assert.ErrorIs(t, err, perrors.ErrUserNotFound)
+
+	history.broken = false
+	versions, err := service.Erase(ctx, user.ID())
+	require.NoError(t, err)
+	assert.Equal(t, 2, versions)
+
+	_, err = service.Erase(ctx, user.ID())
+	assert.ErrorIs(t, err, perrors.ErrUserNotFound)
+	_, err = service.Versions(ctx, user.ID(), app.VersionListParams{})
+	assert.ErrorIs(t, err, perrors.ErrUserNotFound)
+}
@@ -0,0 +1,127 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"github.com/Sergey-Polishchenko/simple-api/internal/domain"
+)
+
+// OperationObserver watches the operations of services, e.g. to count
+// them and measure their latency.
+type OperationObserver interface {
+	// StartOperation is called as an operation starts, with the names of
+	// the service and of the operation. The returned function is called
+	// with the error of the operation once it ends.
+	StartOperation(ctx context.Context, service, operation string) (context.Context, func(err error))
+}
+
+// instrumentedUserService reports the operations of a UserService to an
+// OperationObserver.
+type instrumentedUserService struct {
+	next     UserService
+	observer OperationObserver
+}
+
+// InstrumentUserService makes every operation of a UserService known to
+// an observer.
+func InstrumentUserService(next UserService, observer OperationObserver) UserService {
+	return &instrumentedUserService{next: next, observer: observer}
+}
+
+func (s *instrumentedUserService) start(ctx context.Context, operation string) (context.Context, func(error)) {
+	return s.observer.StartOperation(ctx, "user", operation)
+}
+
+func (s *instrumentedUserService) Create(ctx context.Context, user *domain.User) (*domain.User, error) {
+	ctx, end := s.start(ctx, "create")
+	created, err := s.next.Create(ctx, user)
+	end(err)
+	return created, err
+}
+
+func (s *instrumentedUserService) GetAll(ctx context.Context) ([]*domain.User, error) {
+	ctx, end := s.start(ctx, "get_all")
+	users, err := s.next.GetAll(ctx)
+	end(err)
+	return users, err
+}
+
+func (s *instrumentedUserService) GetUser(ctx context.Context, id string) (*domain.User, error) {
+	ctx, end := s.start(ctx, "get")
+	user, err := s.next.GetUser(ctx, id)
+	end(err)
+	return user, err
+}
+
+func (s *instrumentedUserService) GetByIDs(ctx context.Context, ids []string) ([]*domain.User, error) {
+	ctx, end := s.start(ctx, "get_by_ids")
+	users, err := s.next.GetByIDs(ctx, ids)
+	end(err)
+	return users, err
+}
+
+func (s *instrumentedUserService) List(ctx context.Context, params ListParams) (*UserPage, error) {
+	ctx, end := s.start(ctx, "list")
+	page, err := s.next.List(ctx, params)
+	end(err)
+	return page, err
+}
+
+func (s *instrumentedUserService) Update(ctx context.Context, user *domain.User) error {
+	ctx, end := s.start(ctx, "update")
+	err := s.next.Update(ctx, user)
+	end(err)
+	return err
+}
+
+func (s *instrumentedUserService) Remove(ctx context.Context, id string) error {
+	ctx, end := s.start(ctx, "remove")
+	err := s.next.Remove(ctx, id)
+	end(err)
+	return err
+}
+
+func (s *instrumentedUserService) SetPassword(ctx context.Context, id, current, password string) error {
+	ctx, end := s.start(ctx, "set_password")
+	err := s.next.SetPassword(ctx, id, current, password)
+	end(err)
+	return err
+}
+
+func (s *instrumentedUserService) SetEmail(ctx context.Context, id, current, email string) error {
+	ctx, end := s.start(ctx, "set_email")
+	err := s.next.SetEmail(ctx, id, current, email)
+	end(err)
+	return err
+}
+
+func (s *instrumentedUserService) GetUserAsOf(ctx context.Context, id string, at time.Time) (*domain.User, error) {
+	ctx, end := s.start(ctx, "get_as_of")
+	user, err := s.next.GetUserAsOf(ctx, id, at)
+	end(err)
+	return user, err
+}
+
+func (s *instrumentedUserService) Versions(ctx context.Context, id string, params VersionListParams) (*UserVersionPage, error) {
+	ctx, end := s.start(ctx, "versions")
+	page, err := s.next.Versions(ctx, id, params)
+	end(err)
+	return page, err
+}
+
+func (s *instrumentedUserService) Revert(ctx context.Context, id string, version int) (*domain.User, error) {
+	ctx, end := s.start(ctx, "revert")
+	user, err := s.next.Revert(ctx, id, version)
+	end(err)
+	return user, err
+}
+
+func (s *instrumentedUserService) Erase(ctx context.Context, id string) (int, error) {
+	ctx, end := s.start(ctx, "erase")
+	versions, err := s.next.Erase(ctx, id)
+	end(err)
+	return versions, err
+}
+
+var _ UserService = (*instrumentedUserService)(nil)
@@ -0,0 +1,52 @@
+package app_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	"github.com/Sergey-Polishchenko/simple-api/internal/domain"
+	"github.com/Sergey-Polishchenko/simple-api/internal/infrastructure/memory"
+	perrors "github.com/Sergey-Polishchenko/simple-api/internal/pkg/errors"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/logger"
+)
+
+type operation struct {
+	service, name string
+	err           error
+}
+
+// recordingObserver records the operations that ended.
+type recordingObserver struct {
+	operations []operation
+}
+
+func (o *recordingObserver) StartOperation(ctx context.Context, service, name string) (context.Context, func(error)) {
+	return ctx, func(err error) {
+		o.operations = append(o.operations, operation{service: service, name: name, err: err})
+	}
+}
+
+func TestInstrumentUserService(t *testing.T) {
+	observer := &recordingObserver{}
+	users := app.InstrumentUserService(app.NewUserApp(memory.NewUserRepo(), logger.NewZapLogger()), observer)
+	ctx := context.Background()
+
+	created, err := users.Create(ctx, domain.NewUser("", "John"))
+	require.NoError(t, err)
+	_, err = users.GetUser(ctx, created.ID())
+	require.NoError(t, err)
+	require.NoError(t, users.Remove(ctx, created.ID()))
+	_, err = users.GetUser(ctx, created.ID())
+	require.ErrorIs(t, err, perrors.ErrUserNotFound)
+
+	assert.Equal(t, []operation{
+		{service: "user", name: "create"},
+		{service: "user", name: "get"},
+		{service: "user", name: "remove"},
+		{service: "user", name: "get", err: err},
+	}, observer.operations)
+}
@@ -0,0 +1,53 @@
+package app
+
+import "github.com/Sergey-Polishchenko/simple-api/internal/domain"
+
+const (
+	// DefaultPageSize is used when ListParams.Limit is not set.
+	DefaultPageSize = 20
+	// MaxPageSize caps ListParams.Limit.
+	MaxPageSize = 100
+)
+
+// UserFilter narrows the set of users returned by List.
+type UserFilter struct {
+	// NameContains matches users whose name contains the value, case-insensitively.
+	NameContains string
+	// IDs restricts the result to the given user IDs.
+	IDs []string
+	// Attributes matches users whose custom attributes have the given
+	// values. UserService.List parses string values according to the
+	// attribute schema, repositories get values of the attribute type.
+	Attributes map[string]interface{}
+}
+
+// ListParams describes a keyset-paginated request for users.
+type ListParams struct {
+	Filter UserFilter
+	// After is the ID of the last user of the previous page.
+	After string
+	Limit int
+	// SortAttribute orders users by a custom attribute, then by ID, instead
+	// of by ID alone. Users without the attribute come first. After must
+	// then be a user of the previous page, as the position is taken from it.
+	SortAttribute string
+	// Descending reverses the order by SortAttribute and ID.
+	Descending bool
+}
+
+// UserPage is a single page of users in the requested order.
+type UserPage struct {
+	Users       []*domain.User
+	HasNextPage bool
+}
+
+// normalize clamps the limit to the allowed range.
+func (p ListParams) normalize() ListParams {
+	switch {
+	case p.Limit <= 0:
+		p.Limit = DefaultPageSize
+	case p.Limit > MaxPageSize:
+		p.Limit = MaxPageSize
+	}
+	return p
+}
@@ -0,0 +1,394 @@
+package app
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	perrors "github.com/Sergey-Polishchenko/simple-api/internal/pkg/errors"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/logger"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/totp"
+)
+
+const (
+	// recoveryCodeCount codes are issued when MFA is enabled.
+	recoveryCodeCount = 10
+	// maxMFAFailures wrong codes within mfaFailureWindow lock out the
+	// verification of a user's codes until the window ends.
+	maxMFAFailures   = 5
+	mfaFailureWindow = 15 * time.Minute
+)
+
+// MFAEnrollment is the TOTP second factor of a user.
+type MFAEnrollment struct {
+	UserID string
+	// TenantID is the tenant of the user.
+	TenantID string
+	// Secret is the base32-encoded TOTP secret.
+	Secret string
+	// ConfirmedAt is set once the user proved to have the secret;
+	// MFA is enforced from then on.
+	ConfirmedAt *time.Time
+	// LastUsedStep is the time step of the last accepted code, so that codes can't be replayed.
+	LastUsedStep int64
+	CreatedAt    time.Time
+}
+
+// MFARepository defines persistence operations for MFA enrollments and
+// their recovery codes, of which only hashes are kept.
+type MFARepository interface {
+	// Fetches the enrollment of a user.
+	Get(ctx context.Context, userID string) (*MFAEnrollment, error)
+	// Stores an enrollment, replacing the one of the user and its recovery codes.
+	Save(ctx context.Context, enrollment *MFAEnrollment) error
+	// Marks the enrollment of a user confirmed and stores its recovery codes.
+	Confirm(ctx context.Context, userID string, at time.Time, recoveryCodes [][]byte) error
+	// Records the time step of an accepted code, unless that or a later
+	// step was recorded already, reporting whether it was recorded.
+	UseStep(ctx context.Context, userID string, step int64) (bool, error)
+	// Deletes a recovery code, reporting whether the user had it.
+	UseRecoveryCode(ctx context.Context, userID string, hash []byte) (bool, error)
+	// Counts the recovery codes a user has left.
+	CountRecoveryCodes(ctx context.Context, userID string) (int, error)
+	// Deletes the enrollment and the recovery codes of a user.
+	Delete(ctx context.Context, userID string) error
+}
+
+// SecondFactor checks the second factor of a sign-in.
+type SecondFactor interface {
+	// Checks code, a TOTP or recovery code, if the user has MFA enabled.
+	Check(ctx context.Context, userID, code string) error
+}
+
+// MFASetup is the secret of a pending enrollment, to be added to an authenticator app.
+type MFASetup struct {
+	Secret string
+	// URI is the otpauth:// URI of the secret, usually shown as a QR code.
+	URI string
+}
+
+// MFAStatus describes the second factor of a user.
+type MFAStatus struct {
+	Enabled           bool
+	RecoveryCodesLeft int
+}
+
+// MFAService defines the enrollment and verification of TOTP second factors.
+type MFAService interface {
+	SecondFactor
+	// Starts the enrollment of a user, replacing a pending one.
+	Enroll(ctx context.Context, userID string) (*MFASetup, error)
+	// Enables MFA once the user entered a code of the new secret, and
+	// returns the recovery codes, which are not shown again.
+	Confirm(ctx context.Context, userID, code string) ([]string, error)
+	// Describes the second factor of a user.
+	Status(ctx context.Context, userID string) (*MFAStatus, error)
+	// Disables MFA of a user, e.g. one who lost their authenticator.
+	Reset(ctx context.Context, userID string) error
+}
+
+// MFAApp implements MFAService. Failed codes are counted per user in
+// memory, so with several instances an attacker gets the attempts of each.
+type MFAApp struct {
+	db      MFARepository
+	logger  logger.Logger
+	issuer  string
+	now     func() time.Time
+	limiter *attemptLimiter
+}
+
+// NewMFAApp initializes an MFAApp instance. issuer names the service in
+// authenticator apps.
+func NewMFAApp(db MFARepository, logger logger.Logger, issuer string) MFAService {
+	return &MFAApp{
+		db:      db,
+		logger:  logger,
+		issuer:  issuer,
+		now:     time.Now,
+		limiter: newAttemptLimiter(maxMFAFailures, mfaFailureWindow),
+	}
+}
+
+func (app *MFAApp) Enroll(ctx context.Context, userID string) (*MFASetup, error) {
+	if err := Authorize(ctx, ActionMFAEnroll, userID); err != nil {
+		return nil, err
+	}
+
+	enrollment, err := app.get(ctx, userID)
+	if err != nil && !errors.Is(err, perrors.ErrMFANotEnrolled) {
+		app.logger.WithContext(ctx).Error("can't retrive mfa enrollment", "error", err)
+		return nil, err
+	}
+	if enrollment != nil && enrollment.ConfirmedAt != nil {
+		return nil, perrors.ErrMFAEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	err = app.db.Save(ctx, &MFAEnrollment{
+		UserID:    userID,
+		TenantID:  TenantFromContext(ctx),
+		Secret:    secret,
+		CreatedAt: app.now().UTC(),
+	})
+	if err != nil {
+		app.logger.WithContext(ctx).Error("can't save mfa enrollment", "error", err)
+		return nil, err
+	}
+
+	app.logger.WithContext(ctx).Info("MFA enrollment started", "user_id", userID)
+
+	return &MFASetup{Secret: secret, URI: totp.URI(app.issuer, userID, secret)}, nil
+}
+
+func (app *MFAApp) Confirm(ctx context.Context, userID, code string) ([]string, error) {
+	if err := Authorize(ctx, ActionMFAEnroll, userID); err != nil {
+		return nil, err
+	}
+
+	enrollment, err := app.get(ctx, userID)
+	if err != nil {
+		if !errors.Is(err, perrors.ErrMFANotEnrolled) {
+			app.logger.WithContext(ctx).Error("can't retrive mfa enrollment", "error", err)
+		}
+		return nil, err
+	}
+	if enrollment.ConfirmedAt != nil {
+		return nil, perrors.ErrMFAEnabled
+	}
+
+	if err := app.verifyTOTP(ctx, enrollment, code); err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([][]byte, recoveryCodeCount)
+	for i := range codes {
+		if codes[i], err = generateRecoveryCode(); err != nil {
+			return nil, err
+		}
+		hashes[i] = hashToken(normalizeRecoveryCode(codes[i]))
+	}
+
+	if err := app.db.Confirm(ctx, userID, app.now().UTC(), hashes); err != nil {
+		app.logger.WithContext(ctx).Error("can't confirm mfa enrollment", "error", err)
+		return nil, err
+	}
+
+	app.logger.WithContext(ctx).Info("MFA enabled", "user_id", userID)
+
+	return codes, nil
+}
+
+func (app *MFAApp) Status(ctx context.Context, userID string) (*MFAStatus, error) {
+	if err := Authorize(ctx, ActionMFARead, userID); err != nil {
+		return nil, err
+	}
+
+	enrollment, err := app.get(ctx, userID)
+	if errors.Is(err, perrors.ErrMFANotEnrolled) {
+		return &MFAStatus{}, nil
+	}
+	if err != nil {
+		app.logger.WithContext(ctx).Error("can't retrive mfa enrollment", "error", err)
+		return nil, err
+	}
+	if enrollment.ConfirmedAt == nil {
+		return &MFAStatus{}, nil
+	}
+
+	left, err := app.db.CountRecoveryCodes(ctx, userID)
+	if err != nil {
+		app.logger.WithContext(ctx).Error("can't count recovery codes", "error", err)
+		return nil, err
+	}
+
+	return &MFAStatus{Enabled: true, RecoveryCodesLeft: left}, nil
+}
+
+func (app *MFAApp) Reset(ctx context.Context, userID string) error {
+	if err := Authorize(ctx, ActionMFAReset, userID); err != nil {
+		return err
+	}
+
+	_, err := app.get(ctx, userID)
+	if err == nil {
+		err = app.db.Delete(ctx, userID)
+	}
+	if err != nil {
+		if !errors.Is(err, perrors.ErrMFANotEnrolled) {
+			app.logger.WithContext(ctx).Error("can't delete mfa enrollment", "error", err)
+		}
+		return err
+	}
+	app.limiter.reset(userID)
+
+	app.logger.WithContext(ctx).Info("MFA reset", "user_id", userID, "actor", actor(ctx))
+
+	return nil
+}
+
+func (app *MFAApp) Check(ctx context.Context, userID, code string) error {
+	enrollment, err := app.get(ctx, userID)
+	if errors.Is(err, perrors.ErrMFANotEnrolled) {
+		return nil
+	}
+	if err != nil {
+		app.logger.WithContext(ctx).Error("can't retrive mfa enrollment", "error", err)
+		return err
+	}
+	if enrollment.ConfirmedAt == nil {
+		return nil
+	}
+
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return perrors.ErrMFARequired
+	}
+	if len(code) == totp.Digits {
+		return app.verifyTOTP(ctx, enrollment, code)
+	}
+	return app.verifyRecoveryCode(ctx, userID, code)
+}
+
+// get fetches the enrollment of a user of the tenant of ctx. Admins of
+// other tenants get ErrMFANotEnrolled, as if the user had none.
+func (app *MFAApp) get(ctx context.Context, userID string) (*MFAEnrollment, error) {
+	enrollment, err := app.db.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if enrollment.TenantID != TenantFromContext(ctx) {
+		return nil, perrors.ErrMFANotEnrolled
+	}
+	return enrollment, nil
+}
+
+// verifyTOTP accepts a code of the enrollment's secret that is newer than
+// the last one accepted.
+func (app *MFAApp) verifyTOTP(ctx context.Context, enrollment *MFAEnrollment, code string) error {
+	now := app.now()
+	if !app.limiter.allow(enrollment.UserID, now) {
+		return perrors.ErrTooManyAttempts
+	}
+
+	step, ok := totp.Validate(enrollment.Secret, code, now)
+	if ok {
+		used, err := app.db.UseStep(ctx, enrollment.UserID, step)
+		if err != nil {
+			app.logger.WithContext(ctx).Error("can't record mfa code", "error", err)
+			return err
+		}
+		ok = used
+	}
+	if !ok {
+		app.limiter.fail(enrollment.UserID, now)
+		app.logger.WithContext(ctx).Info("MFA code rejected", "user_id", enrollment.UserID)
+		return perrors.ErrInvalidMFACode
+	}
+
+	app.limiter.reset(enrollment.UserID)
+	return nil
+}
+
+func (app *MFAApp) verifyRecoveryCode(ctx context.Context, userID, code string) error {
+	now := app.now()
+	if !app.limiter.allow(userID, now) {
+		return perrors.ErrTooManyAttempts
+	}
+
+	used, err := app.db.UseRecoveryCode(ctx, userID, hashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		app.logger.WithContext(ctx).Error("can't use recovery code", "error", err)
+		return err
+	}
+	if !used {
+		app.limiter.fail(userID, now)
+		app.logger.WithContext(ctx).Info("Recovery code rejected", "user_id", userID)
+		return perrors.ErrInvalidMFACode
+	}
+
+	app.limiter.reset(userID)
+	app.logger.WithContext(ctx).Info("Recovery code used", "user_id", userID)
+	return nil
+}
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// generateRecoveryCode returns a code of the form "xxxxx-xxxxx", carrying 50 random bits.
+func generateRecoveryCode() (string, error) {
+	random := make([]byte, 7)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	code := strings.ToLower(recoveryCodeEncoding.EncodeToString(random))[:10]
+	return code[:5] + "-" + code[5:], nil
+}
+
+// normalizeRecoveryCode forgives case and separators typed by users.
+func normalizeRecoveryCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(code))
+}
+
+// attemptLimiter locks out a key after max failures within a window.
+type attemptLimiter struct {
+	mu       sync.Mutex
+	max      int
+	window   time.Duration
+	failures map[string]*failureWindow
+}
+
+type failureWindow struct {
+	start time.Time
+	count int
+}
+
+func newAttemptLimiter(max int, window time.Duration) *attemptLimiter {
+	return &attemptLimiter{max: max, window: window, failures: make(map[string]*failureWindow)}
+}
+
+func (l *attemptLimiter) allow(key string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	w, ok := l.failures[key]
+	if !ok {
+		return true
+	}
+	if now.Sub(w.start) >= l.window {
+		delete(l.failures, key)
+		return true
+	}
+	return w.count < l.max
+}
+
+func (l *attemptLimiter) fail(key string, now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	w, ok := l.failures[key]
+	if !ok || now.Sub(w.start) >= l.window {
+		w = &failureWindow{start: now}
+		l.failures[key] = w
+	}
+	w.count++
+}
+
+func (l *attemptLimiter) reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.failures, key)
+}
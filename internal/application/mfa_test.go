@@ -0,0 +1,206 @@
+package app_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	"github.com/Sergey-Polishchenko/simple-api/internal/domain"
+	"github.com/Sergey-Polishchenko/simple-api/internal/infrastructure/memory"
+	perrors "github.com/Sergey-Polishchenko/simple-api/internal/pkg/errors"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/logger"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/password"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/totp"
+)
+
+type mfaFixture struct {
+	mfa  app.MFAService
+	auth app.AuthService
+}
+
+// newMFAFixture serves password logins for user "1", whose password is
+// "correct horse", checking the second factor with MFAApp.
+func newMFAFixture(t *testing.T) *mfaFixture {
+	t.Helper()
+
+	hasher := password.NewHasher(cheapParams)
+	hash, err := hasher.Hash("correct horse")
+	require.NoError(t, err)
+	users := memory.NewUserRepo()
+	require.NoError(t, users.Create(context.Background(), domain.NewUser("1", "John").WithPasswordHash(hash)))
+
+	mfa := app.NewMFAApp(memory.NewMFARepo(), logger.NewZapLogger(), "simple-api")
+	auth, err := app.NewAuthApp(users, memory.NewRefreshTokenRepo(), hasher, jsonSigner{}, logger.NewZapLogger(), app.AuthConfig{
+		AccessTokenTTL:  time.Minute,
+		RefreshTokenTTL: time.Hour,
+	}, app.WithSecondFactor(mfa))
+	require.NoError(t, err)
+
+	return &mfaFixture{mfa: mfa, auth: auth}
+}
+
+func userContext(id string) context.Context {
+	return app.ContextWithClaims(context.Background(), &app.Claims{Subject: id})
+}
+
+func totpCode(t *testing.T, secret string, step int64) string {
+	t.Helper()
+
+	code, err := totp.Code(secret, step)
+	require.NoError(t, err)
+	return code
+}
+
+// enable enables MFA of a user and returns its secret, the time step of the
+// code that confirmed it and the recovery codes.
+func (f *mfaFixture) enable(t *testing.T, userID string) (string, int64, []string) {
+	t.Helper()
+
+	setup, err := f.mfa.Enroll(userContext(userID), userID)
+	require.NoError(t, err)
+
+	step := totp.Step(time.Now())
+	codes, err := f.mfa.Confirm(userContext(userID), userID, totpCode(t, setup.Secret, step))
+	require.NoError(t, err)
+
+	return setup.Secret, step, codes
+}
+
+func TestMFAApp_Enrollment(t *testing.T) {
+	f := newMFAFixture(t)
+	ctx := userContext("1")
+
+	status, err := f.mfa.Status(ctx, "1")
+	require.NoError(t, err)
+	assert.Equal(t, &app.MFAStatus{}, status)
+
+	_, err = f.mfa.Enroll(userContext("2"), "1")
+	assert.ErrorIs(t, err, perrors.ErrForbidden)
+	_, err = f.mfa.Enroll(adminContext(), "1")
+	assert.ErrorIs(t, err, perrors.ErrForbidden, "only users enroll themselves")
+	_, err = f.mfa.Confirm(ctx, "1", "123456")
+	assert.ErrorIs(t, err, perrors.ErrMFANotEnrolled)
+
+	setup, err := f.mfa.Enroll(ctx, "1")
+	require.NoError(t, err)
+	assert.Equal(t, totp.URI("simple-api", "1", setup.Secret), setup.URI)
+	assert.NoError(t, f.mfa.Check(context.Background(), "1", ""), "pending enrollments are not enforced")
+
+	code := totpCode(t, setup.Secret, totp.Step(time.Now()))
+	wrong := totpCode(t, setup.Secret, totp.Step(time.Now())+5)
+	_, err = f.mfa.Confirm(ctx, "1", wrong)
+	assert.ErrorIs(t, err, perrors.ErrInvalidMFACode)
+
+	codes, err := f.mfa.Confirm(ctx, "1", code)
+	require.NoError(t, err)
+	assert.Len(t, codes, 10)
+	for _, c := range codes {
+		assert.Regexp(t, `^[a-z2-7]{5}-[a-z2-7]{5}$`, c)
+	}
+
+	status, err = f.mfa.Status(adminContext(), "1")
+	require.NoError(t, err)
+	assert.Equal(t, &app.MFAStatus{Enabled: true, RecoveryCodesLeft: 10}, status)
+
+	_, err = f.mfa.Enroll(ctx, "1")
+	assert.ErrorIs(t, err, perrors.ErrMFAEnabled)
+	_, err = f.mfa.Confirm(ctx, "1", code)
+	assert.ErrorIs(t, err, perrors.ErrMFAEnabled)
+}
+
+func TestMFAApp_Check(t *testing.T) {
+	f := newMFAFixture(t)
+	secret, step, codes := f.enable(t, "1")
+	ctx := context.Background()
+
+	assert.NoError(t, f.mfa.Check(ctx, "2", ""), "users without MFA need no code")
+	assert.ErrorIs(t, f.mfa.Check(ctx, "1", ""), perrors.ErrMFARequired)
+
+	assert.ErrorIs(t, f.mfa.Check(ctx, "1", totpCode(t, secret, step)), perrors.ErrInvalidMFACode, "codes can't be replayed")
+	assert.NoError(t, f.mfa.Check(ctx, "1", totpCode(t, secret, step+1)))
+	assert.ErrorIs(t, f.mfa.Check(ctx, "1", totpCode(t, secret, step+1)), perrors.ErrInvalidMFACode)
+
+	// Recovery codes forgive case and separators, and work once.
+	typed := strings.ToUpper(strings.ReplaceAll(codes[0], "-", " "))
+	assert.NoError(t, f.mfa.Check(ctx, "1", typed))
+	assert.ErrorIs(t, f.mfa.Check(ctx, "1", codes[0]), perrors.ErrInvalidMFACode)
+	assert.ErrorIs(t, f.mfa.Check(ctx, "1", "aaaaa-aaaaa"), perrors.ErrInvalidMFACode)
+
+	status, err := f.mfa.Status(userContext("1"), "1")
+	require.NoError(t, err)
+	assert.Equal(t, 9, status.RecoveryCodesLeft)
+}
+
+func TestMFAApp_Lockout(t *testing.T) {
+	f := newMFAFixture(t)
+	secret, step, codes := f.enable(t, "1")
+	ctx := context.Background()
+
+	for range 5 {
+		assert.ErrorIs(t, f.mfa.Check(ctx, "1", "aaaaa-aaaaa"), perrors.ErrInvalidMFACode)
+	}
+	assert.ErrorIs(t, f.mfa.Check(ctx, "1", totpCode(t, secret, step+1)), perrors.ErrTooManyAttempts)
+	assert.ErrorIs(t, f.mfa.Check(ctx, "1", codes[0]), perrors.ErrTooManyAttempts)
+
+	assert.ErrorIs(t, f.mfa.Reset(userContext("1"), "1"), perrors.ErrForbidden, "users can't drop their second factor")
+	require.NoError(t, f.mfa.Reset(adminContext(), "1"))
+	assert.ErrorIs(t, f.mfa.Reset(adminContext(), "1"), perrors.ErrMFANotEnrolled)
+	assert.NoError(t, f.mfa.Check(ctx, "1", ""))
+
+	// Enrolling again starts over with a clean slate.
+	secret, step, _ = f.enable(t, "1")
+	assert.NoError(t, f.mfa.Check(ctx, "1", totpCode(t, secret, step+1)))
+}
+
+func TestAuthApp_LoginWithMFA(t *testing.T) {
+	f := newMFAFixture(t)
+	secret, step, codes := f.enable(t, "1")
+
+	tests := []struct {
+		name        string
+		credentials app.Credentials
+		expectedErr error
+	}{
+		{
+			name:        "password alone",
+			credentials: app.Credentials{UserID: "1", Password: "correct horse"},
+			expectedErr: perrors.ErrMFARequired,
+		},
+		{
+			name:        "wrong password",
+			credentials: app.Credentials{UserID: "1", Password: "battery staple", MFACode: totpCode(t, secret, step+1)},
+			expectedErr: perrors.ErrInvalidCredentials,
+		},
+		{
+			name:        "wrong code",
+			credentials: app.Credentials{UserID: "1", Password: "correct horse", MFACode: "aaaaa-aaaaa"},
+			expectedErr: perrors.ErrInvalidMFACode,
+		},
+		{
+			name:        "totp code",
+			credentials: app.Credentials{UserID: "1", Password: "correct horse", MFACode: totpCode(t, secret, step+1)},
+		},
+		{
+			name:        "recovery code",
+			credentials: app.Credentials{UserID: "1", Password: "correct horse", MFACode: codes[0]},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pair, err := f.auth.Login(context.Background(), tt.credentials)
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				assert.Nil(t, pair)
+				return
+			}
+			require.NoError(t, err)
+			assert.NotEmpty(t, pair.AccessToken)
+		})
+	}
+}
@@ -25,6 +25,22 @@ func (m *MockUserRepository) GetByID(ctx context.Context, id string) (*domain.Us
 	return args.Get(0).(*domain.User), args.Error(1)
 }
 
+func (m *MockUserRepository) GetByIDs(ctx context.Context, ids []string) ([]*domain.User, error) {
+	args := m.Called(ctx, ids)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.User), args.Error(1)
+}
+
+func (m *MockUserRepository) List(ctx context.Context, params app.ListParams) ([]*domain.User, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.User), args.Error(1)
+}
+
 func (m *MockUserRepository) GetAll(ctx context.Context) ([]*domain.User, error) {
 	args := m.Called(ctx)
 	return args.Get(0).([]*domain.User), args.Error(1)
@@ -34,6 +50,27 @@ func (m *MockUserRepository) Update(ctx context.Context, user *domain.User) erro
 	return m.Called(ctx, user).Error(0)
 }
 
+func (m *MockUserRepository) SetPasswordHash(ctx context.Context, id, hash string) error {
+	return m.Called(ctx, id, hash).Error(0)
+}
+
+func (m *MockUserRepository) GetByEmail(ctx context.Context, email string) (*domain.User, error) {
+	args := m.Called(ctx, email)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.User), args.Error(1)
+}
+
+func (m *MockUserRepository) SetEmail(ctx context.Context, id, email string) error {
+	return m.Called(ctx, id, email).Error(0)
+}
+
+func (m *MockUserRepository) VerifyEmail(ctx context.Context, id, email string) (bool, error) {
+	args := m.Called(ctx, id, email)
+	return args.Bool(0), args.Error(1)
+}
+
 func (m *MockUserRepository) Remove(ctx context.Context, id string) error {
 	return m.Called(ctx, id).Error(0)
 }
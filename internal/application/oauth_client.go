@@ -0,0 +1,204 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/logger"
+)
+
+// clientSecretPrefix makes client secrets recognisable, e.g. by secret scanners.
+const clientSecretPrefix = "scs"
+
+// OAuthClient is an application registered to sign users in through the
+// OpenID Connect provider. Confidential clients authenticate with a secret,
+// of which only a hash is kept; public clients, like SPAs, have none.
+type OAuthClient struct {
+	ID         string
+	Name       string
+	SecretHash []byte
+	// RedirectURIs are compared exactly with the ones of authorization requests.
+	RedirectURIs []string
+	// Scopes can be requested with the client credentials grant.
+	Scopes    []string
+	CreatedAt time.Time
+}
+
+// Confidential reports whether the client authenticates with a secret.
+func (c *OAuthClient) Confidential() bool {
+	return len(c.SecretHash) > 0
+}
+
+// OAuthClientRepository defines persistence operations for OAuth clients.
+type OAuthClientRepository interface {
+	// Stores a new client.
+	Create(ctx context.Context, client *OAuthClient) error
+	// Fetches a client by ID.
+	GetByID(ctx context.Context, id string) (*OAuthClient, error)
+	// Retrieves all clients ordered by creation time.
+	GetAll(ctx context.Context) ([]*OAuthClient, error)
+	// Deletes a client.
+	Remove(ctx context.Context, id string) error
+}
+
+// RegisterOAuthClientParams describes a new OAuth client.
+type RegisterOAuthClientParams struct {
+	Name         string
+	RedirectURIs []string
+	Scopes       []string
+	// Confidential clients get a secret.
+	Confidential bool
+}
+
+// OAuthClientService defines the registration of OAuth clients.
+type OAuthClientService interface {
+	// Registers a client and returns it along with its secret, if it is confidential.
+	Register(ctx context.Context, params RegisterOAuthClientParams) (*OAuthClient, string, error)
+	// Retrieves all clients.
+	List(ctx context.Context) ([]*OAuthClient, error)
+	// Deletes a client; the tokens issued to it stay valid until they expire.
+	Remove(ctx context.Context, id string) error
+}
+
+// OAuthClientApp implements OAuthClientService.
+type OAuthClientApp struct {
+	db     OAuthClientRepository
+	logger logger.Logger
+	now    func() time.Time
+}
+
+// NewOAuthClientApp initializes an OAuthClientApp instance.
+func NewOAuthClientApp(db OAuthClientRepository, logger logger.Logger) OAuthClientService {
+	return &OAuthClientApp{db: db, logger: logger, now: time.Now}
+}
+
+// InvalidOAuthClientParamsError explains why RegisterOAuthClientParams were rejected.
+type InvalidOAuthClientParamsError struct {
+	Reason string
+}
+
+func (e *InvalidOAuthClientParamsError) Error() string {
+	return e.Reason
+}
+
+func (app *OAuthClientApp) Register(ctx context.Context, params RegisterOAuthClientParams) (*OAuthClient, string, error) {
+	if err := Authorize(ctx, ActionOAuthClients, ""); err != nil {
+		return nil, "", err
+	}
+	if err := validateOAuthClient(params); err != nil {
+		return nil, "", err
+	}
+
+	client := &OAuthClient{
+		ID:           uuid.New().String(),
+		Name:         strings.TrimSpace(params.Name),
+		RedirectURIs: params.RedirectURIs,
+		Scopes:       slices.Compact(slices.Sorted(slices.Values(params.Scopes))),
+		CreatedAt:    app.now().UTC(),
+	}
+
+	var secret string
+	if params.Confidential {
+		var err error
+		if secret, err = generateClientSecret(); err != nil {
+			return nil, "", err
+		}
+		client.SecretHash = hashToken(secret)
+	}
+
+	if err := app.db.Create(ctx, client); err != nil {
+		app.logger.WithContext(ctx).Error("can't create oauth client", "error", err)
+		return nil, "", err
+	}
+
+	app.logger.WithContext(ctx).Info("OAuth client registered", "client_id", client.ID, "scopes", client.Scopes, "actor", actor(ctx))
+
+	return client, secret, nil
+}
+
+func validateOAuthClient(params RegisterOAuthClientParams) error {
+	if strings.TrimSpace(params.Name) == "" {
+		return &InvalidOAuthClientParamsError{Reason: "name is required"}
+	}
+	if len(params.RedirectURIs) == 0 && len(params.Scopes) == 0 {
+		return &InvalidOAuthClientParamsError{Reason: "a redirect URI or a scope is required"}
+	}
+	for _, uri := range params.RedirectURIs {
+		if !validRedirectURI(uri) {
+			return &InvalidOAuthClientParamsError{Reason: fmt.Sprintf("invalid redirect URI %q", uri)}
+		}
+	}
+	for _, scope := range params.Scopes {
+		if !ValidScope(scope) {
+			return &InvalidOAuthClientParamsError{Reason: fmt.Sprintf("unknown scope %q", scope)}
+		}
+	}
+	if len(params.Scopes) > 0 && !params.Confidential {
+		return &InvalidOAuthClientParamsError{Reason: "only confidential clients can have scopes"}
+	}
+	return nil
+}
+
+// validRedirectURI accepts absolute https URIs without a fragment, and
+// plain http for the loopback interface, as native apps use it.
+func validRedirectURI(uri string) bool {
+	u, err := url.Parse(uri)
+	if err != nil || u.Host == "" || u.Fragment != "" {
+		return false
+	}
+
+	switch u.Scheme {
+	case "https":
+		return true
+	case "http":
+		host := u.Hostname()
+		ip := net.ParseIP(host)
+		return host == "localhost" || (ip != nil && ip.IsLoopback())
+	}
+	return false
+}
+
+func (app *OAuthClientApp) List(ctx context.Context) ([]*OAuthClient, error) {
+	if err := Authorize(ctx, ActionOAuthClients, ""); err != nil {
+		return nil, err
+	}
+
+	clients, err := app.db.GetAll(ctx)
+	if err != nil {
+		app.logger.WithContext(ctx).Error("can't retrive oauth clients", "error", err)
+		return nil, err
+	}
+
+	return clients, nil
+}
+
+func (app *OAuthClientApp) Remove(ctx context.Context, id string) error {
+	if err := Authorize(ctx, ActionOAuthClients, ""); err != nil {
+		return err
+	}
+
+	if err := app.db.Remove(ctx, id); err != nil {
+		app.logger.WithContext(ctx).Error("can't remove oauth client", "error", err)
+		return err
+	}
+
+	app.logger.WithContext(ctx).Info("OAuth client removed", "client_id", id, "actor", actor(ctx))
+
+	return nil
+}
+
+// generateClientSecret returns a secret of the form "scs_<secret>".
+func generateClientSecret() (string, error) {
+	secret, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+	return clientSecretPrefix + "_" + secret, nil
+}
@@ -0,0 +1,135 @@
+package app_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	"github.com/Sergey-Polishchenko/simple-api/internal/infrastructure/memory"
+	perrors "github.com/Sergey-Polishchenko/simple-api/internal/pkg/errors"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/logger"
+)
+
+func TestOAuthClientApp_Register(t *testing.T) {
+	tests := []struct {
+		name          string
+		ctx           context.Context
+		params        app.RegisterOAuthClientParams
+		expectedErr   error
+		invalidReason string
+	}{
+		{
+			name:   "public client",
+			ctx:    adminContext(),
+			params: app.RegisterOAuthClientParams{Name: "SPA", RedirectURIs: []string{"https://app.example/callback"}},
+		},
+		{
+			name:   "native client on loopback",
+			ctx:    adminContext(),
+			params: app.RegisterOAuthClientParams{Name: "CLI", RedirectURIs: []string{"http://127.0.0.1:8400/callback"}},
+		},
+		{
+			name: "confidential service",
+			ctx:  adminContext(),
+			params: app.RegisterOAuthClientParams{
+				Name:         "Billing",
+				Scopes:       []string{app.ScopeUsersRead},
+				Confidential: true,
+			},
+		},
+		{
+			name:        "non-admin",
+			ctx:         app.ContextWithClaims(context.Background(), &app.Claims{Subject: "1"}),
+			params:      app.RegisterOAuthClientParams{Name: "SPA", RedirectURIs: []string{"https://app.example/callback"}},
+			expectedErr: perrors.ErrForbidden,
+		},
+		{
+			name:          "without name",
+			ctx:           adminContext(),
+			params:        app.RegisterOAuthClientParams{RedirectURIs: []string{"https://app.example/callback"}},
+			invalidReason: "name is required",
+		},
+		{
+			name:          "without redirect URIs and scopes",
+			ctx:           adminContext(),
+			params:        app.RegisterOAuthClientParams{Name: "Nothing"},
+			invalidReason: "a redirect URI or a scope is required",
+		},
+		{
+			name:          "plain http redirect URI",
+			ctx:           adminContext(),
+			params:        app.RegisterOAuthClientParams{Name: "SPA", RedirectURIs: []string{"http://app.example/callback"}},
+			invalidReason: `invalid redirect URI "http://app.example/callback"`,
+		},
+		{
+			name:          "redirect URI with fragment",
+			ctx:           adminContext(),
+			params:        app.RegisterOAuthClientParams{Name: "SPA", RedirectURIs: []string{"https://app.example/#callback"}},
+			invalidReason: `invalid redirect URI "https://app.example/#callback"`,
+		},
+		{
+			name:          "unknown scope",
+			ctx:           adminContext(),
+			params:        app.RegisterOAuthClientParams{Name: "Billing", Scopes: []string{"users:all"}, Confidential: true},
+			invalidReason: `unknown scope "users:all"`,
+		},
+		{
+			name:          "public client with scopes",
+			ctx:           adminContext(),
+			params:        app.RegisterOAuthClientParams{Name: "Billing", Scopes: []string{app.ScopeUsersRead}},
+			invalidReason: "only confidential clients can have scopes",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := memory.NewOAuthClientRepo()
+			service := app.NewOAuthClientApp(repo, logger.NewZapLogger())
+
+			client, secret, err := service.Register(tt.ctx, tt.params)
+
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+			if tt.invalidReason != "" {
+				var invalid *app.InvalidOAuthClientParamsError
+				require.ErrorAs(t, err, &invalid)
+				assert.Equal(t, tt.invalidReason, invalid.Reason)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.params.Confidential, client.Confidential())
+			if tt.params.Confidential {
+				assert.Regexp(t, `^scs_[A-Za-z0-9_-]{43}$`, secret)
+			} else {
+				assert.Empty(t, secret)
+			}
+
+			stored, err := repo.GetByID(context.Background(), client.ID)
+			require.NoError(t, err)
+			assert.Equal(t, client, stored)
+		})
+	}
+}
+
+func TestOAuthClientApp_Remove(t *testing.T) {
+	service := app.NewOAuthClientApp(memory.NewOAuthClientRepo(), logger.NewZapLogger())
+	ctx := adminContext()
+
+	client, _, err := service.Register(ctx, app.RegisterOAuthClientParams{
+		Name:         "SPA",
+		RedirectURIs: []string{"https://app.example/callback"},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, service.Remove(ctx, client.ID))
+	assert.ErrorIs(t, service.Remove(ctx, client.ID), perrors.ErrOAuthClientNotFound)
+
+	clients, err := service.List(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, clients)
+}
@@ -0,0 +1,526 @@
+package app
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	perrors "github.com/Sergey-Polishchenko/simple-api/internal/pkg/errors"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/logger"
+)
+
+// Scopes of the authorization code grant.
+const (
+	ScopeOpenID  = "openid"
+	ScopeProfile = "profile"
+)
+
+// Grant types of the token endpoint.
+const (
+	GrantAuthorizationCode = "authorization_code"
+	GrantClientCredentials = "client_credentials"
+)
+
+// authorizationCodeTTL is the lifetime RFC 6749 recommends at most.
+const authorizationCodeTTL = time.Minute
+
+// AuthorizationCode is issued to a client once the user signed in. Only a
+// hash of the code is kept.
+type AuthorizationCode struct {
+	Hash     []byte
+	ClientID string
+	UserID   string
+	// TenantID is the tenant the user signed in to.
+	TenantID      string
+	RedirectURI   string
+	Scope         string
+	Nonce         string
+	CodeChallenge string
+	AuthTime      time.Time
+	ExpiresAt     time.Time
+}
+
+// AuthorizationCodeRepository defines persistence operations for authorization codes.
+type AuthorizationCodeRepository interface {
+	// Stores a new code.
+	Create(ctx context.Context, code *AuthorizationCode) error
+	// Fetches and deletes a code by its hash, so that it is used at most once.
+	Consume(ctx context.Context, hash []byte) (*AuthorizationCode, error)
+}
+
+// RevokedTokenRepository keeps the IDs of revoked access tokens.
+type RevokedTokenRepository interface {
+	// Records a revoked token until it expires.
+	Revoke(ctx context.Context, id string, expiresAt time.Time) error
+	// Reports whether a token was revoked.
+	IsRevoked(ctx context.Context, id string) (bool, error)
+}
+
+// TokenCodec signs tokens and verifies the ones it signed.
+type TokenCodec interface {
+	TokenSigner
+	// Verify returns the claims of a valid, unexpired token.
+	Verify(token string) (map[string]interface{}, error)
+}
+
+// OAuth error codes, see RFC 6749.
+const (
+	OAuthInvalidRequest          = "invalid_request"
+	OAuthInvalidClient           = "invalid_client"
+	OAuthInvalidGrant            = "invalid_grant"
+	OAuthInvalidScope            = "invalid_scope"
+	OAuthUnauthorizedClient      = "unauthorized_client"
+	OAuthUnsupportedGrantType    = "unsupported_grant_type"
+	OAuthUnsupportedResponseType = "unsupported_response_type"
+	OAuthAccessDenied            = "access_denied"
+)
+
+// OAuthError is an error reported to OAuth clients.
+type OAuthError struct {
+	Code        string
+	Description string
+}
+
+func (e *OAuthError) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+// AuthorizationRequest holds the parameters of an authorization request.
+type AuthorizationRequest struct {
+	ClientID            string
+	RedirectURI         string
+	ResponseType        string
+	Scope               string
+	State               string
+	Nonce               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+}
+
+// TokenRequest holds the parameters of a token request.
+type TokenRequest struct {
+	GrantType    string
+	ClientID     string
+	ClientSecret string
+	// Code, RedirectURI and CodeVerifier are used by the authorization code grant.
+	Code         string
+	RedirectURI  string
+	CodeVerifier string
+	// Scope is used by the client credentials grant.
+	Scope string
+}
+
+// OAuthTokens is the response of the token endpoint.
+type OAuthTokens struct {
+	AccessToken string
+	ExpiresIn   time.Duration
+	Scope       string
+	// IDToken is issued when the openid scope was granted.
+	IDToken string
+}
+
+// Introspection describes a token, see RFC 7662.
+type Introspection struct {
+	Active    bool
+	Subject   string
+	ClientID  string
+	Scope     string
+	Issuer    string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+// OIDCConfig holds the settings of the OpenID Connect provider.
+type OIDCConfig struct {
+	// Issuer is the URL the provider is served at.
+	Issuer string
+	// Audience of the access tokens, if the API requires one.
+	Audience       string
+	AccessTokenTTL time.Duration
+	IDTokenTTL     time.Duration
+}
+
+// OIDCService defines the flows of the OpenID Connect provider.
+type OIDCService interface {
+	// Checks the client and redirect URI of an authorization request.
+	// Errors can't be reported to the client, as its redirect URI is not trusted.
+	ValidateClient(ctx context.Context, clientID, redirectURI string) error
+	// Checks the rest of an authorization request; errors are reported to the client.
+	ValidateAuthorization(ctx context.Context, req AuthorizationRequest) error
+	// Issues an authorization code to the signed-in user in ctx.
+	Authorize(ctx context.Context, req AuthorizationRequest) (string, error)
+	// Serves the token endpoint.
+	Token(ctx context.Context, req TokenRequest) (*OAuthTokens, error)
+	// Describes a token to a confidential client.
+	Introspect(ctx context.Context, clientID, secret, token string) (*Introspection, error)
+	// Revokes an access token issued to the client.
+	Revoke(ctx context.Context, clientID, secret, token string) error
+	// Reports whether an access token, identified by its "jti" claim, was revoked.
+	IsRevoked(ctx context.Context, tokenID string) (bool, error)
+}
+
+// OIDCApp implements OIDCService on top of UserService, which supplies
+// the claims of ID tokens. Only the authorization code grant with PKCE
+// (S256) and the client credentials grant are supported. Clients are
+// registered by admins and trusted, so users are not asked for consent.
+type OIDCApp struct {
+	clients OAuthClientRepository
+	codes   AuthorizationCodeRepository
+	revoked RevokedTokenRepository
+	users   UserService
+	tokens  TokenCodec
+	logger  logger.Logger
+	config  OIDCConfig
+	now     func() time.Time
+}
+
+// NewOIDCApp initializes an OIDCApp instance.
+func NewOIDCApp(
+	clients OAuthClientRepository,
+	codes AuthorizationCodeRepository,
+	revoked RevokedTokenRepository,
+	users UserService,
+	tokens TokenCodec,
+	logger logger.Logger,
+	config OIDCConfig,
+) OIDCService {
+	return &OIDCApp{
+		clients: clients,
+		codes:   codes,
+		revoked: revoked,
+		users:   users,
+		tokens:  tokens,
+		logger:  logger,
+		config:  config,
+		now:     time.Now,
+	}
+}
+
+func (app *OIDCApp) ValidateClient(ctx context.Context, clientID, redirectURI string) error {
+	client, err := app.client(ctx, clientID)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(client.RedirectURIs, redirectURI) {
+		return &OAuthError{Code: OAuthInvalidRequest, Description: "redirect_uri is not registered"}
+	}
+	return nil
+}
+
+func (app *OIDCApp) ValidateAuthorization(_ context.Context, req AuthorizationRequest) error {
+	if req.ResponseType != "code" {
+		return &OAuthError{Code: OAuthUnsupportedResponseType, Description: "only the code response type is supported"}
+	}
+	if req.CodeChallengeMethod != "S256" || len(req.CodeChallenge) != 43 {
+		return &OAuthError{Code: OAuthInvalidRequest, Description: "a S256 code_challenge is required"}
+	}
+	for _, scope := range strings.Fields(req.Scope) {
+		if scope != ScopeOpenID && scope != ScopeProfile {
+			return &OAuthError{Code: OAuthInvalidScope, Description: "unknown scope " + scope}
+		}
+	}
+	return nil
+}
+
+func (app *OIDCApp) Authorize(ctx context.Context, req AuthorizationRequest) (string, error) {
+	if err := app.ValidateClient(ctx, req.ClientID, req.RedirectURI); err != nil {
+		return "", err
+	}
+	if err := app.ValidateAuthorization(ctx, req); err != nil {
+		return "", err
+	}
+
+	principal := PrincipalFromContext(ctx)
+	if principal.Kind != PrincipalUser {
+		return "", &OAuthError{Code: OAuthAccessDenied, Description: "only users can authorize clients"}
+	}
+
+	code, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+
+	now := app.now()
+	err = app.codes.Create(ctx, &AuthorizationCode{
+		Hash:          hashToken(code),
+		ClientID:      req.ClientID,
+		UserID:        principal.ID,
+		TenantID:      TenantFromContext(ctx),
+		RedirectURI:   req.RedirectURI,
+		Scope:         strings.Join(strings.Fields(req.Scope), " "),
+		Nonce:         req.Nonce,
+		CodeChallenge: req.CodeChallenge,
+		AuthTime:      now.UTC(),
+		ExpiresAt:     now.Add(authorizationCodeTTL).UTC(),
+	})
+	if err != nil {
+		app.logger.WithContext(ctx).Error("can't create authorization code", "error", err)
+		return "", err
+	}
+
+	app.logger.WithContext(ctx).Info("Client authorized", "client_id", req.ClientID, "user_id", principal.ID)
+
+	return code, nil
+}
+
+func (app *OIDCApp) Token(ctx context.Context, req TokenRequest) (*OAuthTokens, error) {
+	client, err := app.authenticate(ctx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	switch req.GrantType {
+	case GrantAuthorizationCode:
+		return app.exchangeCode(ctx, client, req)
+	case GrantClientCredentials:
+		return app.clientCredentials(ctx, client, req.Scope)
+	}
+	return nil, &OAuthError{Code: OAuthUnsupportedGrantType, Description: "unsupported grant_type"}
+}
+
+func (app *OIDCApp) exchangeCode(ctx context.Context, client *OAuthClient, req TokenRequest) (*OAuthTokens, error) {
+	code, err := app.codes.Consume(ctx, hashToken(req.Code))
+	if errors.Is(err, perrors.ErrAuthorizationCodeNotFound) {
+		return nil, &OAuthError{Code: OAuthInvalidGrant, Description: "invalid authorization code"}
+	}
+	if err != nil {
+		app.logger.WithContext(ctx).Error("can't retrive authorization code", "error", err)
+		return nil, err
+	}
+
+	if code.ClientID != client.ID || code.RedirectURI != req.RedirectURI || !app.now().Before(code.ExpiresAt) {
+		return nil, &OAuthError{Code: OAuthInvalidGrant, Description: "invalid authorization code"}
+	}
+	if !verifyCodeChallenge(req.CodeVerifier, code.CodeChallenge) {
+		return nil, &OAuthError{Code: OAuthInvalidGrant, Description: "code_verifier doesn't match the code_challenge"}
+	}
+
+	// Read the user as the user, so the policy applies as to any self-service.
+	userCtx := ContextWithClaims(ctx, &Claims{Subject: code.UserID, TenantID: code.TenantID})
+	userCtx = ContextWithTenant(userCtx, code.TenantID)
+	user, err := app.users.GetUser(userCtx, code.UserID)
+	if errors.Is(err, perrors.ErrUserNotFound) {
+		return nil, &OAuthError{Code: OAuthInvalidGrant, Description: "the user no longer exists"}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := app.now()
+	accessToken, err := app.accessToken(now, user.ID(), code.TenantID, client.ID, code.Scope)
+	if err != nil {
+		return nil, err
+	}
+	tokens := &OAuthTokens{AccessToken: accessToken, ExpiresIn: app.config.AccessTokenTTL, Scope: code.Scope}
+
+	scopes := strings.Fields(code.Scope)
+	if slices.Contains(scopes, ScopeOpenID) {
+		claims := map[string]interface{}{
+			"iss":       app.config.Issuer,
+			"sub":       user.ID(),
+			"aud":       client.ID,
+			"iat":       now.Unix(),
+			"exp":       now.Add(app.config.IDTokenTTL).Unix(),
+			"auth_time": code.AuthTime.Unix(),
+		}
+		if code.Nonce != "" {
+			claims["nonce"] = code.Nonce
+		}
+		if slices.Contains(scopes, ScopeProfile) {
+			claims["name"] = user.Name()
+		}
+		if tokens.IDToken, err = app.sign(claims); err != nil {
+			return nil, err
+		}
+	}
+
+	app.logger.WithContext(ctx).Info("Tokens issued", "grant_type", GrantAuthorizationCode, "client_id", client.ID, "user_id", user.ID())
+
+	return tokens, nil
+}
+
+func (app *OIDCApp) clientCredentials(ctx context.Context, client *OAuthClient, scope string) (*OAuthTokens, error) {
+	if !client.Confidential() || len(client.Scopes) == 0 {
+		return nil, &OAuthError{Code: OAuthUnauthorizedClient, Description: "the client can't use the client credentials grant"}
+	}
+
+	scopes := strings.Fields(scope)
+	if len(scopes) == 0 {
+		scopes = client.Scopes
+	}
+	for _, s := range scopes {
+		if !slices.Contains(client.Scopes, s) {
+			return nil, &OAuthError{Code: OAuthInvalidScope, Description: "scope " + s + " is not granted to the client"}
+		}
+	}
+	scope = strings.Join(scopes, " ")
+
+	accessToken, err := app.accessToken(app.now(), client.ID, DefaultTenantID, client.ID, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	app.logger.WithContext(ctx).Info("Tokens issued", "grant_type", GrantClientCredentials, "client_id", client.ID)
+
+	return &OAuthTokens{AccessToken: accessToken, ExpiresIn: app.config.AccessTokenTTL, Scope: scope}, nil
+}
+
+// accessToken signs an access token for the API. Clients acting on their own
+// behalf are their own subject, see PrincipalFromContext, and belong to the
+// default tenant.
+func (app *OIDCApp) accessToken(now time.Time, subject, tenant, clientID, scope string) (string, error) {
+	claims := map[string]interface{}{
+		"iss":       app.config.Issuer,
+		"sub":       subject,
+		"client_id": clientID,
+		"iat":       now.Unix(),
+		"exp":       now.Add(app.config.AccessTokenTTL).Unix(),
+		"jti":       uuid.New().String(),
+	}
+	if app.config.Audience != "" {
+		claims["aud"] = app.config.Audience
+	}
+	if scope != "" {
+		claims["scope"] = scope
+	}
+	if tenant != DefaultTenantID {
+		claims["tenant"] = tenant
+	}
+	return app.sign(claims)
+}
+
+func (app *OIDCApp) sign(claims map[string]interface{}) (string, error) {
+	token, err := app.tokens.Sign(claims)
+	if err != nil {
+		app.logger.Error("can't sign token", "error", err)
+	}
+	return token, err
+}
+
+func (app *OIDCApp) Introspect(ctx context.Context, clientID, secret, token string) (*Introspection, error) {
+	client, err := app.authenticate(ctx, clientID, secret)
+	if err != nil {
+		return nil, err
+	}
+	if !client.Confidential() {
+		return nil, &OAuthError{Code: OAuthUnauthorizedClient, Description: "only confidential clients can introspect tokens"}
+	}
+
+	claims, active, err := app.verify(ctx, token)
+	if err != nil || !active {
+		return &Introspection{}, err
+	}
+
+	introspection := &Introspection{Active: true}
+	introspection.Subject, _ = claims["sub"].(string)
+	introspection.ClientID, _ = claims["client_id"].(string)
+	introspection.Scope, _ = claims["scope"].(string)
+	introspection.Issuer, _ = claims["iss"].(string)
+	introspection.IssuedAt = numericDate(claims["iat"])
+	introspection.ExpiresAt = numericDate(claims["exp"])
+	return introspection, nil
+}
+
+func (app *OIDCApp) Revoke(ctx context.Context, clientID, secret, token string) error {
+	client, err := app.authenticate(ctx, clientID, secret)
+	if err != nil {
+		return err
+	}
+
+	claims, active, err := app.verify(ctx, token)
+	if err != nil || !active {
+		// Invalid tokens need no revocation, see RFC 7009.
+		return err
+	}
+	if issuedTo, _ := claims["client_id"].(string); issuedTo != client.ID {
+		return &OAuthError{Code: OAuthUnauthorizedClient, Description: "the token was issued to another client"}
+	}
+
+	id, _ := claims["jti"].(string)
+	if err := app.revoked.Revoke(ctx, id, numericDate(claims["exp"])); err != nil {
+		app.logger.WithContext(ctx).Error("can't revoke token", "error", err)
+		return err
+	}
+
+	app.logger.WithContext(ctx).Info("Token revoked", "client_id", client.ID, "token_id", id)
+
+	return nil
+}
+
+func (app *OIDCApp) IsRevoked(ctx context.Context, tokenID string) (bool, error) {
+	return app.revoked.IsRevoked(ctx, tokenID)
+}
+
+// verify returns the claims of a token the provider issued, and whether it is active.
+func (app *OIDCApp) verify(ctx context.Context, token string) (map[string]interface{}, bool, error) {
+	claims, err := app.tokens.Verify(token)
+	if err != nil {
+		return nil, false, nil
+	}
+
+	id, _ := claims["jti"].(string)
+	if issuer, _ := claims["iss"].(string); id == "" || issuer != app.config.Issuer {
+		// ID tokens have no ID and can't be used as access tokens.
+		return nil, false, nil
+	}
+
+	revoked, err := app.revoked.IsRevoked(ctx, id)
+	if err != nil {
+		app.logger.WithContext(ctx).Error("can't check token revocation", "error", err)
+		return nil, false, err
+	}
+	return claims, !revoked, nil
+}
+
+func (app *OIDCApp) client(ctx context.Context, clientID string) (*OAuthClient, error) {
+	client, err := app.clients.GetByID(ctx, clientID)
+	if errors.Is(err, perrors.ErrOAuthClientNotFound) {
+		return nil, &OAuthError{Code: OAuthInvalidClient, Description: "unknown client"}
+	}
+	if err != nil {
+		app.logger.WithContext(ctx).Error("can't retrive oauth client", "error", err)
+		return nil, err
+	}
+	return client, nil
+}
+
+// authenticate checks the secret of a confidential client; public clients
+// are identified by their ID alone.
+func (app *OIDCApp) authenticate(ctx context.Context, clientID, secret string) (*OAuthClient, error) {
+	client, err := app.client(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	if client.Confidential() {
+		if subtle.ConstantTimeCompare(client.SecretHash, hashToken(secret)) != 1 {
+			return nil, &OAuthError{Code: OAuthInvalidClient, Description: "invalid client credentials"}
+		}
+	} else if secret != "" {
+		return nil, &OAuthError{Code: OAuthInvalidClient, Description: "public clients have no secret"}
+	}
+	return client, nil
+}
+
+// verifyCodeChallenge checks a PKCE code verifier against its S256 challenge.
+func verifyCodeChallenge(verifier, challenge string) bool {
+	if len(verifier) < 43 || len(verifier) > 128 {
+		return false
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	expected := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(challenge)) == 1
+}
+
+// numericDate converts a JWT NumericDate claim, decoded from JSON as a float64.
+func numericDate(value interface{}) time.Time {
+	seconds, _ := value.(float64)
+	return time.Unix(int64(seconds), 0).UTC()
+}
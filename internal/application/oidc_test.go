@@ -0,0 +1,377 @@
+package app_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	"github.com/Sergey-Polishchenko/simple-api/internal/domain"
+	"github.com/Sergey-Polishchenko/simple-api/internal/infrastructure/memory"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/jwks"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/logger"
+)
+
+const (
+	redirectURI  = "https://app.example/callback"
+	codeVerifier = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+)
+
+func codeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+type oidcFixture struct {
+	oidc   app.OIDCService
+	signer *jwks.Signer
+	// spa is a public client, billing a confidential one with billingSecret.
+	spa           *app.OAuthClient
+	billing       *app.OAuthClient
+	billingSecret string
+}
+
+func newOIDCFixture(t *testing.T) *oidcFixture {
+	t.Helper()
+
+	logger := logger.NewZapLogger()
+	users := memory.NewUserRepo()
+	require.NoError(t, users.Create(context.Background(), domain.NewUser("1", "John")))
+
+	signer, err := jwks.NewSigner([]byte("0123456789abcdef0123456789abcdef"), "")
+	require.NoError(t, err)
+
+	clientRepo := memory.NewOAuthClientRepo()
+	clients := app.NewOAuthClientApp(clientRepo, logger)
+	spa, _, err := clients.Register(adminContext(), app.RegisterOAuthClientParams{
+		Name:         "SPA",
+		RedirectURIs: []string{redirectURI},
+	})
+	require.NoError(t, err)
+	billing, secret, err := clients.Register(adminContext(), app.RegisterOAuthClientParams{
+		Name:         "Billing",
+		RedirectURIs: []string{redirectURI},
+		Scopes:       []string{app.ScopeUsersRead, app.ScopeUsersWrite},
+		Confidential: true,
+	})
+	require.NoError(t, err)
+
+	oidc := app.NewOIDCApp(
+		clientRepo,
+		memory.NewAuthorizationCodeRepo(),
+		memory.NewRevokedTokenRepo(),
+		app.NewUserApp(users, logger),
+		signer,
+		logger,
+		app.OIDCConfig{
+			Issuer:         "https://id.example",
+			Audience:       "simple-api",
+			AccessTokenTTL: 15 * time.Minute,
+			IDTokenTTL:     time.Hour,
+		},
+	)
+
+	return &oidcFixture{oidc: oidc, signer: signer, spa: spa, billing: billing, billingSecret: secret}
+}
+
+func (f *oidcFixture) authorizationRequest(clientID string) app.AuthorizationRequest {
+	return app.AuthorizationRequest{
+		ClientID:            clientID,
+		RedirectURI:         redirectURI,
+		ResponseType:        "code",
+		Scope:               "openid profile",
+		State:               "xyz",
+		Nonce:               "n-0S6_WzA2Mj",
+		CodeChallenge:       codeChallenge(codeVerifier),
+		CodeChallengeMethod: "S256",
+	}
+}
+
+func (f *oidcFixture) authorize(t *testing.T, clientID string) string {
+	t.Helper()
+
+	ctx := app.ContextWithClaims(context.Background(), &app.Claims{Subject: "1"})
+	code, err := f.oidc.Authorize(ctx, f.authorizationRequest(clientID))
+	require.NoError(t, err)
+	return code
+}
+
+func assertOAuthError(t *testing.T, err error, code string) {
+	t.Helper()
+
+	var oauthErr *app.OAuthError
+	require.ErrorAs(t, err, &oauthErr)
+	assert.Equal(t, code, oauthErr.Code)
+}
+
+func TestOIDCApp_AuthorizationCode(t *testing.T) {
+	f := newOIDCFixture(t)
+	ctx := context.Background()
+	code := f.authorize(t, f.spa.ID)
+
+	tokens, err := f.oidc.Token(ctx, app.TokenRequest{
+		GrantType:    app.GrantAuthorizationCode,
+		ClientID:     f.spa.ID,
+		Code:         code,
+		RedirectURI:  redirectURI,
+		CodeVerifier: codeVerifier,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "openid profile", tokens.Scope)
+	assert.Equal(t, 15*time.Minute, tokens.ExpiresIn)
+
+	access, err := f.signer.Verify(tokens.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, "1", access["sub"])
+	assert.Equal(t, f.spa.ID, access["client_id"])
+	assert.Equal(t, "https://id.example", access["iss"])
+	assert.Equal(t, "simple-api", access["aud"])
+	assert.NotEmpty(t, access["jti"])
+
+	id, err := f.signer.Verify(tokens.IDToken)
+	require.NoError(t, err)
+	assert.Equal(t, "1", id["sub"])
+	assert.Equal(t, f.spa.ID, id["aud"])
+	assert.Equal(t, "n-0S6_WzA2Mj", id["nonce"])
+	assert.Equal(t, "John", id["name"])
+	assert.Contains(t, id, "auth_time")
+
+	// Codes are single-use.
+	_, err = f.oidc.Token(ctx, app.TokenRequest{
+		GrantType:    app.GrantAuthorizationCode,
+		ClientID:     f.spa.ID,
+		Code:         code,
+		RedirectURI:  redirectURI,
+		CodeVerifier: codeVerifier,
+	})
+	assertOAuthError(t, err, app.OAuthInvalidGrant)
+}
+
+func TestOIDCApp_AuthorizationCodeRejected(t *testing.T) {
+	tests := []struct {
+		name         string
+		clientID     func(f *oidcFixture) string
+		secret       func(f *oidcFixture) string
+		redirectURI  string
+		codeVerifier string
+		expectedCode string
+	}{
+		{
+			name:         "wrong code verifier",
+			redirectURI:  redirectURI,
+			codeVerifier: strings.Repeat("a", 43),
+			expectedCode: app.OAuthInvalidGrant,
+		},
+		{
+			name:         "without code verifier",
+			redirectURI:  redirectURI,
+			expectedCode: app.OAuthInvalidGrant,
+		},
+		{
+			name:         "other redirect URI",
+			redirectURI:  "https://app.example/other",
+			codeVerifier: codeVerifier,
+			expectedCode: app.OAuthInvalidGrant,
+		},
+		{
+			name:         "other client",
+			clientID:     func(f *oidcFixture) string { return f.billing.ID },
+			secret:       func(f *oidcFixture) string { return f.billingSecret },
+			redirectURI:  redirectURI,
+			codeVerifier: codeVerifier,
+			expectedCode: app.OAuthInvalidGrant,
+		},
+		{
+			name:         "secret of a public client",
+			secret:       func(*oidcFixture) string { return "scs_secret" },
+			redirectURI:  redirectURI,
+			codeVerifier: codeVerifier,
+			expectedCode: app.OAuthInvalidClient,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newOIDCFixture(t)
+			code := f.authorize(t, f.spa.ID)
+
+			req := app.TokenRequest{
+				GrantType:    app.GrantAuthorizationCode,
+				ClientID:     f.spa.ID,
+				Code:         code,
+				RedirectURI:  tt.redirectURI,
+				CodeVerifier: tt.codeVerifier,
+			}
+			if tt.clientID != nil {
+				req.ClientID = tt.clientID(f)
+			}
+			if tt.secret != nil {
+				req.ClientSecret = tt.secret(f)
+			}
+
+			_, err := f.oidc.Token(context.Background(), req)
+			assertOAuthError(t, err, tt.expectedCode)
+		})
+	}
+}
+
+func TestOIDCApp_ValidateAuthorization(t *testing.T) {
+	tests := []struct {
+		name         string
+		modify       func(req *app.AuthorizationRequest)
+		expectedCode string
+	}{
+		{name: "valid", modify: func(*app.AuthorizationRequest) {}},
+		{
+			name:         "implicit flow",
+			modify:       func(req *app.AuthorizationRequest) { req.ResponseType = "token" },
+			expectedCode: app.OAuthUnsupportedResponseType,
+		},
+		{
+			name:         "without PKCE",
+			modify:       func(req *app.AuthorizationRequest) { req.CodeChallenge, req.CodeChallengeMethod = "", "" },
+			expectedCode: app.OAuthInvalidRequest,
+		},
+		{
+			name:         "plain PKCE",
+			modify:       func(req *app.AuthorizationRequest) { req.CodeChallengeMethod = "plain" },
+			expectedCode: app.OAuthInvalidRequest,
+		},
+		{
+			name:         "unknown scope",
+			modify:       func(req *app.AuthorizationRequest) { req.Scope = "openid email" },
+			expectedCode: app.OAuthInvalidScope,
+		},
+	}
+
+	f := newOIDCFixture(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := f.authorizationRequest(f.spa.ID)
+			tt.modify(&req)
+
+			err := f.oidc.ValidateAuthorization(context.Background(), req)
+
+			if tt.expectedCode == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assertOAuthError(t, err, tt.expectedCode)
+		})
+	}
+}
+
+func TestOIDCApp_ValidateClient(t *testing.T) {
+	f := newOIDCFixture(t)
+	ctx := context.Background()
+
+	assert.NoError(t, f.oidc.ValidateClient(ctx, f.spa.ID, redirectURI))
+	assertOAuthError(t, f.oidc.ValidateClient(ctx, f.spa.ID, "https://evil.example/callback"), app.OAuthInvalidRequest)
+	assertOAuthError(t, f.oidc.ValidateClient(ctx, "unknown", redirectURI), app.OAuthInvalidClient)
+}
+
+func TestOIDCApp_AuthorizeRequiresUser(t *testing.T) {
+	f := newOIDCFixture(t)
+
+	_, err := f.oidc.Authorize(context.Background(), f.authorizationRequest(f.spa.ID))
+
+	assertOAuthError(t, err, app.OAuthAccessDenied)
+}
+
+func TestOIDCApp_ClientCredentials(t *testing.T) {
+	tests := []struct {
+		name          string
+		public        bool
+		secret        string
+		scope         string
+		expectedScope string
+		expectedCode  string
+	}{
+		{name: "registered scopes", expectedScope: "users:read users:write"},
+		{name: "narrowed scope", scope: "users:read", expectedScope: "users:read"},
+		{name: "scope not granted", scope: "users:admin", expectedCode: app.OAuthInvalidScope},
+		{name: "wrong secret", secret: "scs_wrong", expectedCode: app.OAuthInvalidClient},
+		{name: "public client", public: true, expectedCode: app.OAuthUnauthorizedClient},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newOIDCFixture(t)
+			req := app.TokenRequest{
+				GrantType:    app.GrantClientCredentials,
+				ClientID:     f.billing.ID,
+				ClientSecret: f.billingSecret,
+				Scope:        tt.scope,
+			}
+			if tt.secret != "" {
+				req.ClientSecret = tt.secret
+			}
+			if tt.public {
+				req.ClientID, req.ClientSecret = f.spa.ID, ""
+			}
+
+			tokens, err := f.oidc.Token(context.Background(), req)
+
+			if tt.expectedCode != "" {
+				assertOAuthError(t, err, tt.expectedCode)
+				return
+			}
+			require.NoError(t, err)
+			assert.Empty(t, tokens.IDToken)
+			assert.Equal(t, tt.expectedScope, tokens.Scope)
+
+			claims, err := f.signer.Verify(tokens.AccessToken)
+			require.NoError(t, err)
+			assert.Equal(t, f.billing.ID, claims["sub"])
+			assert.Equal(t, f.billing.ID, claims["client_id"])
+			assert.Equal(t, tt.expectedScope, claims["scope"])
+		})
+	}
+}
+
+func TestOIDCApp_IntrospectAndRevoke(t *testing.T) {
+	f := newOIDCFixture(t)
+	ctx := context.Background()
+
+	tokens, err := f.oidc.Token(ctx, app.TokenRequest{
+		GrantType:    app.GrantClientCredentials,
+		ClientID:     f.billing.ID,
+		ClientSecret: f.billingSecret,
+	})
+	require.NoError(t, err)
+
+	introspection, err := f.oidc.Introspect(ctx, f.billing.ID, f.billingSecret, tokens.AccessToken)
+	require.NoError(t, err)
+	assert.True(t, introspection.Active)
+	assert.Equal(t, f.billing.ID, introspection.ClientID)
+	assert.Equal(t, "users:read users:write", introspection.Scope)
+	assert.Equal(t, "https://id.example", introspection.Issuer)
+
+	_, err = f.oidc.Introspect(ctx, f.spa.ID, "", tokens.AccessToken)
+	assertOAuthError(t, err, app.OAuthUnauthorizedClient)
+
+	introspection, err = f.oidc.Introspect(ctx, f.billing.ID, f.billingSecret, "not-a-token")
+	require.NoError(t, err)
+	assert.False(t, introspection.Active)
+
+	assertOAuthError(t, f.oidc.Revoke(ctx, f.spa.ID, "", tokens.AccessToken), app.OAuthUnauthorizedClient)
+	require.NoError(t, f.oidc.Revoke(ctx, f.billing.ID, f.billingSecret, tokens.AccessToken))
+	// Revoking an unknown or revoked token succeeds, see RFC 7009.
+	require.NoError(t, f.oidc.Revoke(ctx, f.billing.ID, f.billingSecret, tokens.AccessToken))
+
+	introspection, err = f.oidc.Introspect(ctx, f.billing.ID, f.billingSecret, tokens.AccessToken)
+	require.NoError(t, err)
+	assert.False(t, introspection.Active)
+
+	claims, err := f.signer.Verify(tokens.AccessToken)
+	require.NoError(t, err)
+	revoked, err := f.oidc.IsRevoked(ctx, claims["jti"].(string))
+	require.NoError(t, err)
+	assert.True(t, revoked)
+}
@@ -0,0 +1,135 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Sergey-Polishchenko/simple-api/internal/domain"
+	perrors "github.com/Sergey-Polishchenko/simple-api/internal/pkg/errors"
+)
+
+// Password length limits, in characters. The upper one keeps hashing cheap.
+const (
+	MinPasswordLength = 8
+	MaxPasswordLength = 128
+)
+
+// PasswordHasher hashes passwords and verifies them against stored hashes.
+type PasswordHasher interface {
+	// Hash returns the encoded hash of password.
+	Hash(password string) (string, error)
+	// Verify reports whether password matches encoded and, if it does,
+	// whether encoded is outdated and should be replaced by a fresh hash.
+	Verify(password, encoded string) (match, rehash bool, err error)
+}
+
+// WithPasswordHasher replaces the default argon2id hasher of UserApp.
+func WithPasswordHasher(hasher PasswordHasher) Option {
+	return func(app *UserApp) {
+		app.hasher = hasher
+	}
+}
+
+// WithSessions makes UserApp end the other sessions of a user whose
+// password changes.
+func WithSessions(sessions RefreshTokenRepository) Option {
+	return func(app *UserApp) {
+		app.sessions = sessions
+	}
+}
+
+// InvalidPasswordError explains why a new password was rejected.
+type InvalidPasswordError struct {
+	Reason string
+}
+
+func (e *InvalidPasswordError) Error() string {
+	return e.Reason
+}
+
+func validatePassword(password string) error {
+	switch n := utf8.RuneCountInString(password); {
+	case n < MinPasswordLength:
+		return &InvalidPasswordError{Reason: fmt.Sprintf("password must be at least %d characters long", MinPasswordLength)}
+	case n > MaxPasswordLength:
+		return &InvalidPasswordError{Reason: fmt.Sprintf("password must be at most %d characters long", MaxPasswordLength)}
+	}
+	return nil
+}
+
+func (app *UserApp) SetPassword(ctx context.Context, id, current, password string) error {
+	if err := Authorize(ctx, ActionUserUpdate, id); err != nil {
+		return err
+	}
+	if err := validatePassword(password); err != nil {
+		return err
+	}
+
+	user, err := app.db.GetByID(ctx, id)
+	if err != nil {
+		app.logger.WithContext(ctx).Error("can't retrive user", "error", err)
+		return err
+	}
+
+	if err := app.checkCurrentPassword(ctx, user, current); err != nil {
+		return err
+	}
+
+	hash, err := app.hasher.Hash(password)
+	if err != nil {
+		app.logger.WithContext(ctx).Error("can't hash password", "error", err)
+		return err
+	}
+	err = inTransaction(ctx, app.tx, func(ctx context.Context) error {
+		if err := app.db.SetPasswordHash(ctx, id, hash); err != nil {
+			app.logger.WithContext(ctx).Error("can't set password", "error", err)
+			return err
+		}
+
+		// Whoever got hold of the old password may have signed in with it;
+		// only the session of the user changing it stays.
+		if app.sessions != nil {
+			var current string
+			if principal := PrincipalFromContext(ctx); principal.Kind == PrincipalUser && principal.ID == id {
+				if claims, ok := ClaimsFromContext(ctx); ok {
+					current = claims.SessionID
+				}
+			}
+			if err := app.sessions.RevokeByUser(ctx, id, current, time.Now().UTC()); err != nil {
+				app.logger.WithContext(ctx).Error("can't revoke refresh tokens", "error", err)
+				return err
+			}
+		}
+
+		return app.record(ctx, AuditUserPasswordChanged, id, nil)
+	})
+	if err != nil {
+		return err
+	}
+
+	app.logger.WithContext(ctx).Info("Password changed", "user_id", id, "actor", actor(ctx))
+
+	return nil
+}
+
+// checkCurrentPassword makes users changing their own credentials prove
+// they know the current password, if they have any, so that a stolen
+// access token can't be turned into a takeover.
+func (app *UserApp) checkCurrentPassword(ctx context.Context, user *domain.User, current string) error {
+	principal := PrincipalFromContext(ctx)
+	if principal.Kind != PrincipalUser || principal.ID != user.ID() || user.PasswordHash() == "" {
+		return nil
+	}
+
+	match, _, err := app.hasher.Verify(current, user.PasswordHash())
+	if err != nil {
+		app.logger.WithContext(ctx).Error("can't verify password", "user_id", user.ID(), "error", err)
+		return err
+	}
+	if !match {
+		return perrors.ErrInvalidCredentials
+	}
+	return nil
+}
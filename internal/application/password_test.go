@@ -0,0 +1,88 @@
+package app_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	"github.com/Sergey-Polishchenko/simple-api/internal/domain"
+	"github.com/Sergey-Polishchenko/simple-api/internal/infrastructure/memory"
+	perrors "github.com/Sergey-Polishchenko/simple-api/internal/pkg/errors"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/logger"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/password"
+)
+
+func TestUserApp_SetPassword(t *testing.T) {
+	ctx := context.Background()
+	self := app.ContextWithClaims(ctx, &app.Claims{Subject: "1"})
+	admin := app.ContextWithClaims(ctx, &app.Claims{Subject: "2", Roles: []string{app.RoleAdmin}})
+
+	users := memory.NewUserRepo()
+	hasher := password.NewHasher(cheapParams)
+	service := app.NewUserApp(users, logger.NewZapLogger(), app.WithPasswordHasher(hasher))
+	require.NoError(t, users.Create(ctx, domain.NewUser("1", "John")))
+
+	var invalid *app.InvalidPasswordError
+	assert.ErrorAs(t, service.SetPassword(self, "1", "", "short"), &invalid)
+
+	require.NoError(t, service.SetPassword(self, "1", "", "first password"), "no current password yet")
+	assert.ErrorIs(t, service.SetPassword(self, "1", "wrong password", "second password"), perrors.ErrInvalidCredentials)
+	require.NoError(t, service.SetPassword(self, "1", "first password", "second password"))
+	require.NoError(t, service.SetPassword(admin, "1", "", "third password"), "admins reset passwords")
+
+	assert.ErrorIs(t, service.SetPassword(self, "2", "", "fourth password"), perrors.ErrForbidden)
+	assert.ErrorIs(t, service.SetPassword(admin, "3", "", "fourth password"), perrors.ErrUserNotFound)
+
+	user, err := users.GetByID(ctx, "1")
+	require.NoError(t, err)
+	match, _, err := hasher.Verify("third password", user.PasswordHash())
+	require.NoError(t, err)
+	assert.True(t, match)
+
+	// Updating the user keeps the password.
+	require.NoError(t, service.Update(admin, domain.NewUser("1", "Johnny")))
+	user, err = users.GetByID(ctx, "1")
+	require.NoError(t, err)
+	assert.NotEmpty(t, user.PasswordHash())
+}
+
+func TestUserApp_SetPasswordEndsOtherSessions(t *testing.T) {
+	ctx := context.Background()
+	users := memory.NewUserRepo()
+	tokens := memory.NewRefreshTokenRepo()
+	hasher := password.NewHasher(cheapParams)
+	service := app.NewUserApp(users, logger.NewZapLogger(), app.WithPasswordHasher(hasher), app.WithSessions(tokens))
+	auth, err := app.NewAuthApp(users, tokens, hasher, jsonSigner{}, logger.NewZapLogger(), app.AuthConfig{
+		AccessTokenTTL:  time.Minute,
+		RefreshTokenTTL: time.Hour,
+	})
+	require.NoError(t, err)
+
+	hash, err := hasher.Hash("correct horse")
+	require.NoError(t, err)
+	require.NoError(t, users.Create(ctx, domain.NewUser("1", "John").WithPasswordHash(hash)))
+	current, err := auth.Login(ctx, app.Credentials{UserID: "1", Password: "correct horse"})
+	require.NoError(t, err)
+	other, err := auth.Login(ctx, app.Credentials{UserID: "1", Password: "correct horse"})
+	require.NoError(t, err)
+
+	var claims map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(current.AccessToken), &claims))
+	self := app.ContextWithClaims(ctx, &app.Claims{Subject: "1", SessionID: claims["sid"].(string)})
+	require.NoError(t, service.SetPassword(self, "1", "correct horse", "battery staple"))
+
+	_, err = auth.Refresh(ctx, other.RefreshToken)
+	assert.ErrorIs(t, err, perrors.ErrInvalidRefreshToken)
+	current, err = auth.Refresh(ctx, current.RefreshToken)
+	require.NoError(t, err, "the session changing the password stays")
+
+	admin := app.ContextWithClaims(ctx, &app.Claims{Subject: "2", SessionID: claims["sid"].(string), Roles: []string{app.RoleAdmin}})
+	require.NoError(t, service.SetPassword(admin, "1", "", "staple battery"))
+	_, err = auth.Refresh(ctx, current.RefreshToken)
+	assert.ErrorIs(t, err, perrors.ErrInvalidRefreshToken, "admins end every session")
+}
@@ -0,0 +1,189 @@
+package app
+
+import (
+	"context"
+	"slices"
+
+	perrors "github.com/Sergey-Polishchenko/simple-api/internal/pkg/errors"
+)
+
+// PrincipalKind tells what kind of caller a Principal is.
+type PrincipalKind int
+
+const (
+	// PrincipalSystem is a trusted caller without credentials: requests reach
+	// the application unauthenticated only when authentication is disabled,
+	// or from tools working directly on the database.
+	PrincipalSystem PrincipalKind = iota
+	// PrincipalUser is a person, identified by their user ID.
+	PrincipalUser
+	// PrincipalService is a machine caller using an API key
+	// or a token of the client credentials grant.
+	PrincipalService
+)
+
+func (k PrincipalKind) String() string {
+	switch k {
+	case PrincipalUser:
+		return "user"
+	case PrincipalService:
+		return "service"
+	}
+	return "system"
+}
+
+// Principal is the caller an operation is authorized for.
+type Principal struct {
+	Kind PrincipalKind
+	// ID is the user ID of a user, or the API key ID or OAuth client ID of a service.
+	ID string
+	// Tenant is the tenant the caller belongs to.
+	Tenant string
+	Roles  []string
+	Scopes []string
+}
+
+// PrincipalFromContext derives the caller from the claims in ctx.
+func PrincipalFromContext(ctx context.Context) Principal {
+	claims, ok := ClaimsFromContext(ctx)
+	if !ok {
+		return Principal{Kind: PrincipalSystem, Tenant: TenantFromContext(ctx)}
+	}
+
+	tenant := claims.TenantID
+	if tenant == "" {
+		tenant = DefaultTenantID
+	}
+	switch {
+	case claims.APIKeyID != "":
+		return Principal{Kind: PrincipalService, ID: claims.APIKeyID, Tenant: tenant, Scopes: claims.Scopes}
+	case claims.ClientID != "" && claims.Subject == claims.ClientID:
+		// Clients act on their own behalf only with client credentials.
+		return Principal{Kind: PrincipalService, ID: claims.ClientID, Tenant: tenant, Scopes: claims.Scopes}
+	}
+	return Principal{Kind: PrincipalUser, ID: claims.Subject, Tenant: tenant, Roles: claims.Roles, Scopes: claims.Scopes}
+}
+
+// Action is an operation subject to authorization.
+type Action string
+
+const (
+	ActionUserRead     Action = "user:read"
+	ActionUserList     Action = "user:list"
+	ActionUserCreate   Action = "user:create"
+	ActionUserUpdate   Action = "user:update"
+	ActionUserDelete   Action = "user:delete"
+	ActionUserRevert   Action = "user:revert"
+	ActionUserExport   Action = "user:export"
+	ActionUserErase    Action = "user:erase"
+	ActionAPIKeys      Action = "apikeys:manage"
+	ActionOAuthClients Action = "oauthclients:manage"
+	ActionMFAEnroll    Action = "mfa:enroll"
+	ActionMFARead      Action = "mfa:read"
+	ActionMFAReset     Action = "mfa:reset"
+	ActionTenants      Action = "tenants:manage"
+	ActionGroupRead    Action = "group:read"
+	ActionGroupWrite   Action = "group:write"
+	// ActionAttributeSchema is the management of the custom attributes of users.
+	ActionAttributeSchema Action = "attributes:manage"
+	ActionAuditRead       Action = "audit:read"
+)
+
+// Rule allows an action to a principal, on the resource with the given ID if any.
+type Rule func(p Principal, resourceID string) bool
+
+// AllowSystem allows trusted callers without credentials.
+func AllowSystem(p Principal, _ string) bool {
+	return p.Kind == PrincipalSystem
+}
+
+// AllowAdmin allows users with RoleAdmin and services with ScopeUsersAdmin.
+func AllowAdmin(p Principal, _ string) bool {
+	switch p.Kind {
+	case PrincipalUser:
+		return slices.Contains(p.Roles, RoleAdmin)
+	case PrincipalService:
+		return slices.Contains(p.Scopes, ScopeUsersAdmin)
+	}
+	return false
+}
+
+// AllowOperator allows the admins of the default tenant, who operate the
+// deployment.
+func AllowOperator(p Principal, resourceID string) bool {
+	return p.Tenant == DefaultTenantID && AllowAdmin(p, resourceID)
+}
+
+// AllowScope allows services granted scope.
+func AllowScope(scope string) Rule {
+	return func(p Principal, _ string) bool {
+		return p.Kind == PrincipalService && slices.Contains(p.Scopes, scope)
+	}
+}
+
+// AllowSelf allows users acting on their own account.
+func AllowSelf(p Principal, resourceID string) bool {
+	return p.Kind == PrincipalUser && p.ID != "" && p.ID == resourceID
+}
+
+// Policy lists, for every action, the rules any of which allows it.
+// Actions missing from the policy are denied.
+type Policy map[Action][]Rule
+
+// Authorize returns perrors.ErrForbidden unless a rule allows the action.
+func (p Policy) Authorize(principal Principal, action Action, resourceID string) error {
+	for _, allow := range p[action] {
+		if allow(principal, resourceID) {
+			return nil
+		}
+	}
+	return perrors.ErrForbidden
+}
+
+// DefaultPolicy is the authorization policy of the application: admins
+// manage anyone, users may read and update only themselves, and services
+// act within the scopes of their API key.
+var DefaultPolicy = Policy{
+	ActionUserRead:   {AllowSystem, AllowAdmin, AllowScope(ScopeUsersRead), AllowSelf},
+	ActionUserList:   {AllowSystem, AllowAdmin, AllowScope(ScopeUsersRead)},
+	ActionUserCreate: {AllowSystem, AllowAdmin, AllowScope(ScopeUsersWrite)},
+	ActionUserUpdate: {AllowSystem, AllowAdmin, AllowScope(ScopeUsersWrite), AllowSelf},
+	ActionUserDelete: {AllowSystem, AllowAdmin, AllowScope(ScopeUsersWrite)},
+	// Reverting can restore an email address, which users change only with
+	// their password, so users don't revert themselves.
+	ActionUserRevert: {AllowSystem, AllowAdmin, AllowScope(ScopeUsersWrite)},
+	// Users obtain the data stored about them, but erasing them for good
+	// is up to admins, who handle the requests of the data subjects.
+	ActionUserExport: {AllowSystem, AllowAdmin, AllowSelf},
+	ActionUserErase:  {AllowSystem, AllowAdmin},
+	// API keys grant access, so only admins handle them, even without authentication.
+	ActionAPIKeys: {AllowAdmin},
+	// OAuth clients too: they get tokens with the scopes they are registered
+	// with, in the default tenant, so only operators register them.
+	ActionOAuthClients: {AllowOperator},
+	// Only users can enroll themselves: whoever enrolls learns the secret.
+	ActionMFAEnroll: {AllowSelf},
+	ActionMFARead:   {AllowSystem, AllowAdmin, AllowSelf},
+	ActionMFAReset:  {AllowSystem, AllowAdmin},
+	ActionTenants:   {AllowSystem, AllowOperator},
+	// Groups are managed like users; users read the groups they belong to
+	// through ActionUserRead.
+	ActionGroupRead:  {AllowSystem, AllowAdmin, AllowScope(ScopeUsersRead)},
+	ActionGroupWrite: {AllowSystem, AllowAdmin, AllowScope(ScopeUsersWrite)},
+	// The attribute schema constrains every user, so only admins change it.
+	ActionAttributeSchema: {AllowSystem, AllowAdmin},
+	// The audit log names who did what to whom, so only admins read it;
+	// users read the history of their own account.
+	ActionAuditRead: {AllowSystem, AllowAdmin, AllowSelf},
+}
+
+// Authorize checks the caller in ctx against DefaultPolicy. Callers act
+// within their own tenant only, whatever the policy says, except operators,
+// who set up the others.
+func Authorize(ctx context.Context, action Action, resourceID string) error {
+	principal := PrincipalFromContext(ctx)
+	if principal.Tenant != TenantFromContext(ctx) && !AllowOperator(principal, resourceID) {
+		return perrors.ErrForbidden
+	}
+	return DefaultPolicy.Authorize(principal, action, resourceID)
+}
@@ -0,0 +1,231 @@
+package app_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	perrors "github.com/Sergey-Polishchenko/simple-api/internal/pkg/errors"
+)
+
+func TestPrincipalFromContext(t *testing.T) {
+	tests := []struct {
+		name     string
+		claims   *app.Claims
+		expected app.Principal
+	}{
+		{
+			name:     "no claims",
+			expected: app.Principal{Kind: app.PrincipalSystem, Tenant: app.DefaultTenantID},
+		},
+		{
+			name:   "user",
+			claims: &app.Claims{Subject: "1", Roles: []string{"admin"}},
+			expected: app.Principal{
+				Kind: app.PrincipalUser, ID: "1", Tenant: app.DefaultTenantID, Roles: []string{"admin"},
+			},
+		},
+		{
+			name:   "api key",
+			claims: &app.Claims{Subject: "apikey:2", APIKeyID: "2", TenantID: "acme", Scopes: []string{"users:read"}},
+			expected: app.Principal{
+				Kind: app.PrincipalService, ID: "2", Tenant: "acme", Scopes: []string{"users:read"},
+			},
+		},
+		{
+			name:   "oauth client",
+			claims: &app.Claims{Subject: "c", ClientID: "c", Scopes: []string{"users:read"}},
+			expected: app.Principal{
+				Kind: app.PrincipalService, ID: "c", Tenant: app.DefaultTenantID, Scopes: []string{"users:read"},
+			},
+		},
+		{
+			name:   "user signed in through an oauth client",
+			claims: &app.Claims{Subject: "1", ClientID: "c", TenantID: "acme", Scopes: []string{"openid"}},
+			expected: app.Principal{
+				Kind: app.PrincipalUser, ID: "1", Tenant: "acme", Scopes: []string{"openid"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.claims != nil {
+				ctx = app.ContextWithClaims(ctx, tt.claims)
+			}
+			assert.Equal(t, tt.expected, app.PrincipalFromContext(ctx))
+		})
+	}
+}
+
+func TestDefaultPolicy(t *testing.T) {
+	var (
+		system  = app.Principal{Kind: app.PrincipalSystem}
+		admin   = app.Principal{Kind: app.PrincipalUser, ID: "admin", Tenant: app.DefaultTenantID, Roles: []string{app.RoleAdmin}}
+		tenAdm  = app.Principal{Kind: app.PrincipalUser, ID: "admin", Tenant: "acme", Roles: []string{app.RoleAdmin}}
+		user    = app.Principal{Kind: app.PrincipalUser, ID: "1"}
+		nobody  = app.Principal{Kind: app.PrincipalUser}
+		scoped  = app.Principal{Kind: app.PrincipalUser, ID: "1", Scopes: []string{app.ScopeUsersAdmin}}
+		reader  = app.Principal{Kind: app.PrincipalService, ID: "k1", Scopes: []string{app.ScopeUsersRead}}
+		writer  = app.Principal{Kind: app.PrincipalService, ID: "k2", Scopes: []string{app.ScopeUsersWrite}}
+		keyAdm  = app.Principal{Kind: app.PrincipalService, ID: "k3", Tenant: app.DefaultTenantID, Scopes: []string{app.ScopeUsersAdmin}}
+		roleKey = app.Principal{Kind: app.PrincipalService, ID: "1", Roles: []string{app.RoleAdmin}}
+	)
+
+	// allowed lists, per principal, the actions it may perform on user "1".
+	tests := []struct {
+		name      string
+		principal app.Principal
+		allowed   []app.Action
+	}{
+		{
+			name:      "system",
+			principal: system,
+			allowed: []app.Action{
+				app.ActionUserRead, app.ActionUserList, app.ActionUserCreate,
+				app.ActionUserUpdate, app.ActionUserDelete, app.ActionMFARead, app.ActionMFAReset,
+				app.ActionTenants,
+			},
+		},
+		{
+			name:      "admin user",
+			principal: admin,
+			allowed: []app.Action{
+				app.ActionUserRead, app.ActionUserList, app.ActionUserCreate,
+				app.ActionUserUpdate, app.ActionUserDelete, app.ActionAPIKeys,
+				app.ActionOAuthClients, app.ActionMFARead, app.ActionMFAReset, app.ActionTenants,
+			},
+		},
+		{
+			name:      "admin user of a tenant",
+			principal: tenAdm,
+			allowed: []app.Action{
+				app.ActionUserRead, app.ActionUserList, app.ActionUserCreate,
+				app.ActionUserUpdate, app.ActionUserDelete, app.ActionAPIKeys,
+				app.ActionMFARead, app.ActionMFAReset,
+			},
+		},
+		{
+			name:      "user acting on themselves",
+			principal: user,
+			allowed: []app.Action{
+				app.ActionUserRead, app.ActionUserUpdate, app.ActionMFAEnroll, app.ActionMFARead,
+			},
+		},
+		{
+			name:      "user without subject",
+			principal: nobody,
+		},
+		{
+			name:      "user scopes are ignored",
+			principal: scoped,
+			allowed: []app.Action{
+				app.ActionUserRead, app.ActionUserUpdate, app.ActionMFAEnroll, app.ActionMFARead,
+			},
+		},
+		{
+			name:      "read-only service",
+			principal: reader,
+			allowed:   []app.Action{app.ActionUserRead, app.ActionUserList},
+		},
+		{
+			name:      "write-only service",
+			principal: writer,
+			allowed:   []app.Action{app.ActionUserCreate, app.ActionUserUpdate, app.ActionUserDelete},
+		},
+		{
+			name:      "admin service",
+			principal: keyAdm,
+			allowed: []app.Action{
+				app.ActionUserRead, app.ActionUserList, app.ActionUserCreate,
+				app.ActionUserUpdate, app.ActionUserDelete, app.ActionAPIKeys,
+				app.ActionOAuthClients, app.ActionMFARead, app.ActionMFAReset, app.ActionTenants,
+			},
+		},
+		{
+			name:      "service roles and IDs are ignored",
+			principal: roleKey,
+		},
+	}
+
+	actions := []app.Action{
+		app.ActionUserRead, app.ActionUserList, app.ActionUserCreate,
+		app.ActionUserUpdate, app.ActionUserDelete, app.ActionAPIKeys,
+		app.ActionOAuthClients, app.ActionMFAEnroll, app.ActionMFARead, app.ActionMFAReset,
+		app.ActionTenants,
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, action := range actions {
+				err := app.DefaultPolicy.Authorize(tt.principal, action, "1")
+				if slices.Contains(tt.allowed, action) {
+					assert.NoError(t, err, action)
+				} else {
+					assert.ErrorIs(t, err, perrors.ErrForbidden, action)
+				}
+			}
+		})
+	}
+}
+
+func TestDefaultPolicy_OtherUsers(t *testing.T) {
+	user := app.Principal{Kind: app.PrincipalUser, ID: "1"}
+
+	for _, action := range []app.Action{
+		app.ActionUserRead, app.ActionUserUpdate, app.ActionUserDelete, app.ActionMFAEnroll, app.ActionMFARead,
+	} {
+		assert.ErrorIs(t, app.DefaultPolicy.Authorize(user, action, "2"), perrors.ErrForbidden, action)
+	}
+}
+
+func TestPolicy_DeniesUnknownActions(t *testing.T) {
+	policy := app.Policy{app.ActionUserRead: {app.AllowSystem}}
+	system := app.Principal{Kind: app.PrincipalSystem}
+
+	assert.NoError(t, policy.Authorize(system, app.ActionUserRead, ""))
+	assert.ErrorIs(t, policy.Authorize(system, app.ActionUserDelete, ""), perrors.ErrForbidden)
+}
+
+func TestAuthorize_Tenants(t *testing.T) {
+	admin := &app.Claims{Subject: "1", TenantID: "acme", Roles: []string{app.RoleAdmin}}
+	operator := &app.Claims{Subject: "2", Roles: []string{app.RoleAdmin}}
+
+	tests := []struct {
+		name    string
+		claims  *app.Claims
+		tenant  string
+		allowed bool
+	}{
+		{name: "own tenant", claims: admin, allowed: true},
+		{name: "own tenant named by the request", claims: admin, tenant: "acme", allowed: true},
+		{name: "other tenant", claims: admin, tenant: "globex"},
+		{name: "default tenant", claims: admin, tenant: app.DefaultTenantID},
+		{name: "operator in the default tenant", claims: operator, allowed: true},
+		{name: "operator in another tenant", claims: operator, tenant: "acme", allowed: true},
+		{name: "system", tenant: "acme", allowed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.claims != nil {
+				ctx = app.ContextWithClaims(ctx, tt.claims)
+			}
+			if tt.tenant != "" {
+				ctx = app.ContextWithTenant(ctx, tt.tenant)
+			}
+
+			err := app.Authorize(ctx, app.ActionUserList, "")
+			if tt.allowed {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, perrors.ErrForbidden)
+			}
+		})
+	}
+}
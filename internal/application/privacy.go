@@ -0,0 +1,421 @@
+package app
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Sergey-Polishchenko/simple-api/internal/domain"
+	perrors "github.com/Sergey-Polishchenko/simple-api/internal/pkg/errors"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/logger"
+)
+
+// UserExport is everything stored about a user, in answer to a subject
+// access request.
+type UserExport struct {
+	ExportedAt time.Time
+	User       *domain.User
+	// Versions are the versions of the user, newest first; none without
+	// history.
+	Versions []*UserVersion
+	// Groups are ordered by name.
+	Groups []GroupMembership
+	// Sessions are ordered by start, oldest first.
+	Sessions []*Session
+	MFA      MFAStatus
+	// AuditEntries are the entries about the user or made by them, newest
+	// first. The entries about other users carry no changes, which hold
+	// the personal data of those.
+	AuditEntries []*AuditEntry
+}
+
+// GroupMembership is a group a user belongs to.
+type GroupMembership struct {
+	Group *domain.Group
+	// Direct is false for groups the user belongs to through nested groups.
+	Direct bool
+}
+
+// Session is a sign-in of a user along with the refresh tokens that
+// descend from it.
+type Session struct {
+	// ID is the family ID of the tokens.
+	ID        string
+	StartedAt time.Time
+	// RefreshedAt is when the latest token was issued.
+	RefreshedAt time.Time
+	// ExpiresAt is when the latest token expires.
+	ExpiresAt time.Time
+	// RevokedAt is set for sessions ended by logout or token reuse.
+	RevokedAt *time.Time
+}
+
+// ErasureReceipt records the erasure of a user: who erased it, when, and
+// how much of it. It holds no personal data besides the ID of the user.
+type ErasureReceipt struct {
+	ID       string
+	TenantID string
+	UserID   string
+	// ActorKind and ActorID tell who erased the user, like in AuditEntry.
+	ActorKind string
+	ActorID   string
+	ErasedAt  time.Time
+	// Versions, Sessions and GroupMemberships are the numbers of each
+	// deleted; MFA tells whether an MFA enrollment was.
+	Versions         int
+	Sessions         int
+	GroupMemberships int
+	MFA              bool
+	// AuditEntriesRetained is the number of audit entries about the user
+	// or made by them. The log is append-only, so that it can prove who
+	// did what: the entries are kept, but the ones about the user lose
+	// the values of their changes and the ones made by them their request.
+	AuditEntriesRetained int
+}
+
+// ErasureReceiptRepository stores erasure receipts in the tenant of the
+// context.
+type ErasureReceiptRepository interface {
+	// Stores a new receipt.
+	Create(ctx context.Context, receipt *ErasureReceipt) error
+	// Retrieves a receipt by ID.
+	GetByID(ctx context.Context, id string) (*ErasureReceipt, error)
+}
+
+// PrivacySources are the stores holding data about users besides the
+// users and their versions; nil ones are skipped.
+type PrivacySources struct {
+	Groups   GroupRepository
+	Sessions RefreshTokenRepository
+	MFA      MFARepository
+	Audit    AuditRepository
+}
+
+// PrivacyService handles the requests of data subjects: the export and the
+// erasure of everything stored about a user.
+type PrivacyService interface {
+	// Collects everything stored about a user.
+	Export(ctx context.Context, userID string) (*UserExport, error)
+	// Deletes the user, its versions, sessions, MFA enrollment, group
+	// memberships, the changes recorded in the audit entries about it and
+	// the requests of the ones made by it, returning the receipt of the
+	// erasure. Users removed before are erased if they left versions.
+	Erase(ctx context.Context, userID string) (*ErasureReceipt, error)
+	// Fetches the receipt of an erasure.
+	Receipt(ctx context.Context, id string) (*ErasureReceipt, error)
+}
+
+// PrivacyApp implements PrivacyService.
+type PrivacyApp struct {
+	users    UserService
+	receipts ErasureReceiptRepository
+	sources  PrivacySources
+	logger   logger.Logger
+}
+
+// NewPrivacyApp initializes a PrivacyApp instance. users reads and erases
+// the users and their versions, sources the rest.
+func NewPrivacyApp(
+	users UserService,
+	receipts ErasureReceiptRepository,
+	sources PrivacySources,
+	logger logger.Logger,
+) PrivacyService {
+	return &PrivacyApp{users: users, receipts: receipts, sources: sources, logger: logger}
+}
+
+func (app *PrivacyApp) Export(ctx context.Context, userID string) (*UserExport, error) {
+	if err := Authorize(ctx, ActionUserExport, userID); err != nil {
+		return nil, err
+	}
+
+	user, err := app.users.GetUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	export := &UserExport{ExportedAt: time.Now().UTC(), User: user}
+
+	if export.Versions, err = app.versions(ctx, userID); err != nil {
+		return nil, err
+	}
+	if export.Groups, err = app.groups(ctx, userID); err != nil {
+		return nil, err
+	}
+	if export.Sessions, err = app.sessions(ctx, userID); err != nil {
+		return nil, err
+	}
+	if export.MFA, err = app.mfa(ctx, userID); err != nil {
+		return nil, err
+	}
+	if export.AuditEntries, err = app.auditEntries(ctx, userID); err != nil {
+		return nil, err
+	}
+	for i, entry := range export.AuditEntries {
+		if entry.TargetID != userID {
+			redacted := *entry
+			redacted.Changes = nil
+			export.AuditEntries[i] = &redacted
+		}
+	}
+
+	app.logger.WithContext(ctx).Info("User exported", "user_id", userID, "actor", actor(ctx))
+
+	return export, nil
+}
+
+func (app *PrivacyApp) Erase(ctx context.Context, userID string) (*ErasureReceipt, error) {
+	if err := Authorize(ctx, ActionUserErase, userID); err != nil {
+		return nil, err
+	}
+	if err := app.checkSubject(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	principal := PrincipalFromContext(ctx)
+	receipt := &ErasureReceipt{
+		ID:        uuid.New().String(),
+		TenantID:  TenantFromContext(ctx),
+		UserID:    userID,
+		ActorKind: principal.Kind.String(),
+		ActorID:   principal.ID,
+	}
+
+	// The user goes last, so that an erasure failing halfway can be
+	// retried.
+	if tokens := app.sources.Sessions; tokens != nil {
+		sessions, err := app.sessions(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := tokens.DeleteByUser(ctx, userID); err != nil {
+			app.logger.WithContext(ctx).Error("can't delete refresh tokens", "error", err)
+			return nil, err
+		}
+		receipt.Sessions = len(sessions)
+	}
+
+	if mfa := app.sources.MFA; mfa != nil {
+		err := mfa.Delete(ctx, userID)
+		if err != nil && !errors.Is(err, perrors.ErrMFANotEnrolled) {
+			app.logger.WithContext(ctx).Error("can't delete mfa enrollment", "error", err)
+			return nil, err
+		}
+		receipt.MFA = err == nil
+	}
+
+	if groups := app.sources.Groups; groups != nil {
+		direct, err := groups.GetByUser(ctx, userID, true)
+		if err != nil {
+			app.logger.WithContext(ctx).Error("can't retrive user groups", "error", err)
+			return nil, err
+		}
+		for _, group := range direct {
+			err := groups.RemoveMember(ctx, group.ID(), userID)
+			if err != nil && !errors.Is(err, perrors.ErrGroupMemberNotFound) {
+				app.logger.WithContext(ctx).Error("can't remove group member", "error", err)
+				return nil, err
+			}
+		}
+		receipt.GroupMemberships = len(direct)
+	}
+
+	entries, err := app.auditEntries(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	receipt.AuditEntriesRetained = len(entries)
+
+	if receipt.Versions, err = app.users.Erase(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	// The audit log records the erasure even if the receipt is lost.
+	receipt.ErasedAt = time.Now().UTC().Truncate(time.Microsecond)
+	if err := app.receipts.Create(ctx, receipt); err != nil {
+		app.logger.WithContext(ctx).Error("can't store erasure receipt", "user_id", userID, "error", err)
+		return nil, err
+	}
+
+	app.logger.WithContext(ctx).Info("User erased", "user_id", userID, "receipt_id", receipt.ID, "actor", actor(ctx))
+
+	return receipt, nil
+}
+
+func (app *PrivacyApp) Receipt(ctx context.Context, id string) (*ErasureReceipt, error) {
+	if err := Authorize(ctx, ActionUserErase, ""); err != nil {
+		return nil, err
+	}
+
+	receipt, err := app.receipts.GetByID(ctx, id)
+	if err != nil {
+		if !errors.Is(err, perrors.ErrErasureReceiptNotFound) {
+			app.logger.WithContext(ctx).Error("can't retrive erasure receipt", "error", err)
+		}
+		return nil, err
+	}
+	return receipt, nil
+}
+
+// checkSubject fails with perrors.ErrUserNotFound unless the user is, or
+// was, in the tenant of ctx: the sessions and MFA enrollments are not
+// scoped to tenants, so they are erased only for users known to be.
+func (app *PrivacyApp) checkSubject(ctx context.Context, userID string) error {
+	_, err := app.users.GetUser(ctx, userID)
+	if !errors.Is(err, perrors.ErrUserNotFound) {
+		return err
+	}
+
+	_, err = app.users.Versions(ctx, userID, VersionListParams{Limit: 1})
+	if errors.Is(err, perrors.ErrHistoryDisabled) {
+		return perrors.ErrUserNotFound
+	}
+	return err
+}
+
+// versions collects the versions of a user, newest first.
+func (app *PrivacyApp) versions(ctx context.Context, userID string) ([]*UserVersion, error) {
+	var versions []*UserVersion
+	params := VersionListParams{Limit: MaxPageSize}
+	for {
+		page, err := app.users.Versions(ctx, userID, params)
+		if errors.Is(err, perrors.ErrHistoryDisabled) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		versions = append(versions, page.Versions...)
+		if !page.HasNextPage {
+			return versions, nil
+		}
+		params.After = page.Versions[len(page.Versions)-1].Version
+	}
+}
+
+// groups collects the groups a user belongs to, directly or not.
+func (app *PrivacyApp) groups(ctx context.Context, userID string) ([]GroupMembership, error) {
+	if app.sources.Groups == nil {
+		return nil, nil
+	}
+
+	all, err := app.sources.Groups.GetByUser(ctx, userID, false)
+	if err != nil {
+		app.logger.WithContext(ctx).Error("can't retrive user groups", "error", err)
+		return nil, err
+	}
+	direct, err := app.sources.Groups.GetByUser(ctx, userID, true)
+	if err != nil {
+		app.logger.WithContext(ctx).Error("can't retrive user groups", "error", err)
+		return nil, err
+	}
+
+	memberships := make([]GroupMembership, len(all))
+	for i, group := range all {
+		memberships[i] = GroupMembership{
+			Group: group,
+			Direct: slices.ContainsFunc(direct, func(d *domain.Group) bool {
+				return d.ID() == group.ID()
+			}),
+		}
+	}
+	return memberships, nil
+}
+
+// sessions collects the sessions of a user from its refresh tokens.
+func (app *PrivacyApp) sessions(ctx context.Context, userID string) ([]*Session, error) {
+	if app.sources.Sessions == nil {
+		return nil, nil
+	}
+
+	tokens, err := app.sources.Sessions.ListByUser(ctx, userID)
+	if err != nil {
+		app.logger.WithContext(ctx).Error("can't retrive refresh tokens", "error", err)
+		return nil, err
+	}
+
+	var sessions []*Session
+	byFamily := make(map[string]*Session)
+	for _, token := range tokens {
+		session, ok := byFamily[token.FamilyID]
+		if !ok {
+			session = &Session{ID: token.FamilyID, StartedAt: token.CreatedAt}
+			byFamily[token.FamilyID] = session
+			sessions = append(sessions, session)
+		}
+		if token.CreatedAt.After(session.RefreshedAt) {
+			session.RefreshedAt = token.CreatedAt
+			session.ExpiresAt = token.ExpiresAt
+		}
+		if token.RevokedAt != nil {
+			session.RevokedAt = token.RevokedAt
+		}
+	}
+	return sessions, nil
+}
+
+// mfa describes the second factor of a user.
+func (app *PrivacyApp) mfa(ctx context.Context, userID string) (MFAStatus, error) {
+	if app.sources.MFA == nil {
+		return MFAStatus{}, nil
+	}
+
+	enrollment, err := app.sources.MFA.Get(ctx, userID)
+	if errors.Is(err, perrors.ErrMFANotEnrolled) {
+		return MFAStatus{}, nil
+	}
+	if err != nil {
+		app.logger.WithContext(ctx).Error("can't retrive mfa enrollment", "error", err)
+		return MFAStatus{}, err
+	}
+	if enrollment.ConfirmedAt == nil {
+		return MFAStatus{}, nil
+	}
+
+	left, err := app.sources.MFA.CountRecoveryCodes(ctx, userID)
+	if err != nil {
+		app.logger.WithContext(ctx).Error("can't count recovery codes", "error", err)
+		return MFAStatus{}, err
+	}
+	return MFAStatus{Enabled: true, RecoveryCodesLeft: left}, nil
+}
+
+// auditEntries collects the audit entries about a user or made by them,
+// newest first.
+func (app *PrivacyApp) auditEntries(ctx context.Context, userID string) ([]*AuditEntry, error) {
+	if app.sources.Audit == nil {
+		return nil, nil
+	}
+
+	var entries []*AuditEntry
+	seen := make(map[string]bool)
+	for _, filter := range []AuditFilter{{TargetID: userID}, {ActorID: userID}} {
+		params := AuditListParams{Filter: filter, Limit: MaxPageSize}
+		for {
+			page, err := app.sources.Audit.List(ctx, params)
+			if err != nil {
+				app.logger.WithContext(ctx).Error("can't list audit entries", "error", err)
+				return nil, err
+			}
+			for _, entry := range page {
+				if !seen[entry.ID] {
+					seen[entry.ID] = true
+					entries = append(entries, entry)
+				}
+			}
+			if len(page) < params.Limit {
+				break
+			}
+			params.After = page[len(page)-1].Sequence
+		}
+	}
+
+	slices.SortFunc(entries, func(a, b *AuditEntry) int {
+		return cmp.Compare(b.Sequence, a.Sequence)
+	})
+	return entries, nil
+}
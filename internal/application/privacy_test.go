@@ -0,0 +1,308 @@
+package app_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	"github.com/Sergey-Polishchenko/simple-api/internal/domain"
+	"github.com/Sergey-Polishchenko/simple-api/internal/infrastructure/memory"
+	perrors "github.com/Sergey-Polishchenko/simple-api/internal/pkg/errors"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/logger"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/password"
+)
+
+type privacyFixture struct {
+	users     app.UserService
+	groups    app.GroupService
+	audit     app.AuditService
+	privacy   app.PrivacyService
+	userRepo  *memory.UserRepo
+	groupRepo *memory.GroupRepo
+	tokens    *memory.RefreshTokenRepo
+	mfa       *memory.MFARepo
+}
+
+func newPrivacyFixture() *privacyFixture {
+	log := logger.NewZapLogger()
+	userRepo := memory.NewUserRepo()
+	auditRepo := memory.NewAuditRepo()
+	groupRepo := memory.NewGroupRepo()
+	f := &privacyFixture{
+		users: app.NewUserApp(userRepo, log, app.WithPasswordHasher(password.NewHasher(cheapParams)),
+			app.WithAuditLog(auditRepo), app.WithUserHistory(userRepo)),
+		groups:    app.NewGroupApp(groupRepo, userRepo, log),
+		audit:     app.NewAuditApp(auditRepo, log),
+		userRepo:  userRepo,
+		groupRepo: groupRepo,
+		tokens:    memory.NewRefreshTokenRepo(),
+		mfa:       memory.NewMFARepo(),
+	}
+	f.privacy = app.NewPrivacyApp(f.users, memory.NewErasureReceiptRepo(), app.PrivacySources{
+		Groups:   groupRepo,
+		Sessions: f.tokens,
+		MFA:      f.mfa,
+		Audit:    auditRepo,
+	}, log)
+	return f
+}
+
+// seed creates a user with two versions, a group membership inherited by
+// another group, two sessions, one of them refreshed and the other
+// revoked, and MFA enabled with two recovery codes.
+func (f *privacyFixture) seed(t *testing.T) *domain.User {
+	t.Helper()
+	ctx := adminContext()
+
+	user, err := f.users.Create(ctx, domain.NewUser("", "Alice").WithEmail("alice@example.com", false))
+	require.NoError(t, err)
+	self := app.ContextWithRequestInfo(userContext(user.ID()), app.RequestInfo{ID: "req-alicia", ClientIP: "198.51.100.23"})
+	require.NoError(t, f.users.Update(self, domain.NewUser(user.ID(), "Alicia")))
+
+	staff, err := f.groups.Create(ctx, domain.NewGroup("", "staff", ""))
+	require.NoError(t, err)
+	eng, err := f.groups.Create(ctx, domain.NewGroup("", "eng", ""))
+	require.NoError(t, err)
+	require.NoError(t, f.groups.AddMember(ctx, staff.ID(), app.GroupMember{GroupID: eng.ID()}))
+	require.NoError(t, f.groups.AddMember(ctx, eng.ID(), app.GroupMember{UserID: user.ID()}))
+
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	revoked := start.Add(time.Minute)
+	for _, token := range []*app.RefreshToken{
+		{ID: "t1", FamilyID: "f1", CreatedAt: start, ExpiresAt: start.Add(time.Hour)},
+		{ID: "t2", FamilyID: "f1", CreatedAt: start.Add(time.Minute), ExpiresAt: start.Add(time.Hour + time.Minute)},
+		{ID: "t3", FamilyID: "f2", CreatedAt: start.Add(time.Second), ExpiresAt: start.Add(time.Hour), RevokedAt: &revoked},
+		{ID: "t4", FamilyID: "f3", UserID: "someone else", CreatedAt: start, ExpiresAt: start.Add(time.Hour)},
+	} {
+		if token.UserID == "" {
+			token.UserID = user.ID()
+		}
+		require.NoError(t, f.tokens.Create(ctx, token))
+	}
+
+	require.NoError(t, f.mfa.Save(ctx, &app.MFAEnrollment{UserID: user.ID(), Secret: "secret", CreatedAt: start}))
+	require.NoError(t, f.mfa.Confirm(ctx, user.ID(), start, [][]byte{[]byte("code1"), []byte("code2")}))
+
+	return user
+}
+
+func TestPrivacyApp_Export(t *testing.T) {
+	f := newPrivacyFixture()
+	user := f.seed(t)
+
+	export, err := f.privacy.Export(userContext(user.ID()), user.ID())
+	require.NoError(t, err)
+
+	assert.Equal(t, "Alicia", export.User.Name())
+	assert.Equal(t, "alice@example.com", export.User.Email())
+
+	require.Len(t, export.Versions, 2)
+	assert.Equal(t, "Alicia", export.Versions[0].User.Name())
+	assert.Equal(t, "Alice", export.Versions[1].User.Name())
+
+	require.Len(t, export.Groups, 2)
+	assert.Equal(t, "eng", export.Groups[0].Group.Name())
+	assert.True(t, export.Groups[0].Direct)
+	assert.Equal(t, "staff", export.Groups[1].Group.Name())
+	assert.False(t, export.Groups[1].Direct)
+
+	require.Len(t, export.Sessions, 2)
+	first, second := export.Sessions[0], export.Sessions[1]
+	assert.Equal(t, "f1", first.ID)
+	assert.Equal(t, first.StartedAt.Add(time.Minute), first.RefreshedAt)
+	assert.Equal(t, first.RefreshedAt.Add(time.Hour), first.ExpiresAt)
+	assert.Nil(t, first.RevokedAt)
+	assert.Equal(t, "f2", second.ID)
+	assert.NotNil(t, second.RevokedAt)
+
+	assert.Equal(t, app.MFAStatus{Enabled: true, RecoveryCodesLeft: 2}, export.MFA)
+
+	assert.Equal(t, []app.AuditAction{app.AuditUserUpdated, app.AuditUserCreated}, auditActions(export.AuditEntries))
+}
+
+func TestPrivacyApp_ExportActions(t *testing.T) {
+	f := newPrivacyFixture()
+	ctx := adminContext()
+
+	bob, err := f.users.Create(ctx, domain.NewUser("", "Bob"))
+	require.NoError(t, err)
+	bobAsAdmin := app.ContextWithClaims(context.Background(), &app.Claims{Subject: bob.ID(), Roles: []string{app.RoleAdmin}})
+	carol, err := f.users.Create(bobAsAdmin, domain.NewUser("", "Carol"))
+	require.NoError(t, err)
+
+	export, err := f.privacy.Export(ctx, bob.ID())
+	require.NoError(t, err)
+	require.Len(t, export.AuditEntries, 2, "the entries made by the user are exported too")
+	assert.Equal(t, carol.ID(), export.AuditEntries[0].TargetID)
+	assert.Empty(t, export.AuditEntries[0].Changes, "the data of other users is left out")
+	assert.NotEmpty(t, export.AuditEntries[1].Changes)
+	history, err := f.audit.History(ctx, carol.ID(), app.AuditListParams{})
+	require.NoError(t, err)
+	assert.NotEmpty(t, history.Entries[0].Changes, "the audit log keeps them")
+	assert.Equal(t, bob.ID(), export.AuditEntries[1].TargetID)
+	assert.Empty(t, export.Sessions)
+	assert.Equal(t, app.MFAStatus{}, export.MFA)
+}
+
+func TestPrivacyApp_Erase(t *testing.T) {
+	f := newPrivacyFixture()
+	user := f.seed(t)
+	ctx := app.ContextWithRequestInfo(adminContext(), app.RequestInfo{ID: "r1", ClientIP: "203.0.113.7"})
+
+	receipt, err := f.privacy.Erase(ctx, user.ID())
+	require.NoError(t, err)
+	assert.NotEmpty(t, receipt.ID)
+	assert.Equal(t, user.ID(), receipt.UserID)
+	assert.Equal(t, "user", receipt.ActorKind)
+	assert.Equal(t, "admin", receipt.ActorID)
+	assert.False(t, receipt.ErasedAt.IsZero())
+	assert.Equal(t, 2, receipt.Versions)
+	assert.Equal(t, 2, receipt.Sessions)
+	assert.Equal(t, 1, receipt.GroupMemberships)
+	assert.True(t, receipt.MFA)
+	assert.Equal(t, 2, receipt.AuditEntriesRetained)
+
+	fetched, err := f.privacy.Receipt(adminContext(), receipt.ID)
+	require.NoError(t, err)
+	assert.Equal(t, receipt, fetched)
+
+	_, err = f.users.GetUser(ctx, user.ID())
+	assert.ErrorIs(t, err, perrors.ErrUserNotFound)
+	_, err = f.users.Versions(ctx, user.ID(), app.VersionListParams{})
+	assert.ErrorIs(t, err, perrors.ErrUserNotFound)
+	groups, err := f.groupRepo.GetByUser(ctx, user.ID(), false)
+	require.NoError(t, err)
+	assert.Empty(t, groups)
+	_, err = f.mfa.Get(ctx, user.ID())
+	assert.ErrorIs(t, err, perrors.ErrMFANotEnrolled)
+	tokens, err := f.tokens.ListByUser(ctx, user.ID())
+	require.NoError(t, err)
+	assert.Empty(t, tokens)
+	tokens, err = f.tokens.ListByUser(ctx, "someone else")
+	require.NoError(t, err)
+	assert.Len(t, tokens, 1, "the sessions of other users are left alone")
+
+	history, err := f.audit.History(ctx, user.ID(), app.AuditListParams{})
+	require.NoError(t, err)
+	require.Len(t, history.Entries, 3)
+	erased := history.Entries[0]
+	assert.Equal(t, app.AuditUserErased, erased.Action)
+	assert.Empty(t, erased.Changes, "the erasure records no personal data")
+	for _, entry := range history.Entries[1:] {
+		assert.True(t, entry.ChangesErased(), entry.Action)
+		assert.Empty(t, entry.Changes, entry.Action)
+	}
+	updated := history.Entries[1]
+	assert.True(t, updated.RequestErased(), "the request of the user is erased")
+	assert.Empty(t, updated.ClientIP)
+	assert.Equal(t, "203.0.113.7", erased.ClientIP, "the request of the admin stays")
+
+	all, err := f.audit.List(ctx, app.AuditListParams{})
+	require.NoError(t, err)
+	raw, err := json.Marshal(all.Entries)
+	require.NoError(t, err)
+	for _, value := range []string{"Alice", "Alicia", "alice@example.com", "req-alicia", "198.51.100.23"} {
+		assert.NotContains(t, string(raw), value, "the audit log still holds personal data")
+	}
+
+	verification, err := f.audit.Verify(ctx)
+	require.NoError(t, err)
+	assert.True(t, verification.Valid(), "the chain survives the erasure")
+
+	_, err = f.privacy.Export(ctx, user.ID())
+	assert.ErrorIs(t, err, perrors.ErrUserNotFound)
+	_, err = f.privacy.Erase(ctx, user.ID())
+	assert.ErrorIs(t, err, perrors.ErrUserNotFound)
+}
+
+func TestPrivacyApp_EraseRemovedUser(t *testing.T) {
+	f := newPrivacyFixture()
+	ctx := adminContext()
+
+	user, err := f.users.Create(ctx, domain.NewUser("", "Alice"))
+	require.NoError(t, err)
+	require.NoError(t, f.users.Remove(ctx, user.ID()))
+
+	receipt, err := f.privacy.Erase(ctx, user.ID())
+	require.NoError(t, err)
+	assert.Equal(t, 1, receipt.Versions)
+
+	_, err = f.userRepo.GetVersion(ctx, user.ID(), 1)
+	assert.ErrorIs(t, err, perrors.ErrUserVersionNotFound)
+}
+
+func TestPrivacyApp_Authorization(t *testing.T) {
+	tests := []struct {
+		name        string
+		ctx         func(userID string) context.Context
+		erase       bool
+		expectedErr error
+	}{
+		{
+			name: "user exports themselves",
+			ctx:  func(userID string) context.Context { return userContext(userID) },
+		},
+		{
+			name:        "user exports another",
+			ctx:         func(string) context.Context { return userContext("someone else") },
+			expectedErr: perrors.ErrForbidden,
+		},
+		{
+			name:        "user erases themselves",
+			ctx:         func(userID string) context.Context { return userContext(userID) },
+			erase:       true,
+			expectedErr: perrors.ErrForbidden,
+		},
+		{
+			name:        "admin of another tenant",
+			ctx:         func(string) context.Context { return app.ContextWithTenant(tenantAdminContext("acme"), "acme") },
+			erase:       true,
+			expectedErr: perrors.ErrUserNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newPrivacyFixture()
+			user := f.seed(t)
+
+			var err error
+			if tt.erase {
+				_, err = f.privacy.Erase(tt.ctx(user.ID()), user.ID())
+			} else {
+				_, err = f.privacy.Export(tt.ctx(user.ID()), user.ID())
+			}
+			if tt.expectedErr == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tt.expectedErr)
+
+			// Nothing was erased.
+			tokens, err := f.tokens.ListByUser(adminContext(), user.ID())
+			require.NoError(t, err)
+			assert.Len(t, tokens, 3)
+			_, err = f.mfa.Get(adminContext(), user.ID())
+			assert.NoError(t, err)
+		})
+	}
+
+	t.Run("receipts", func(t *testing.T) {
+		f := newPrivacyFixture()
+		user := f.seed(t)
+		receipt, err := f.privacy.Erase(adminContext(), user.ID())
+		require.NoError(t, err)
+
+		_, err = f.privacy.Receipt(userContext(user.ID()), receipt.ID)
+		assert.ErrorIs(t, err, perrors.ErrForbidden)
+		_, err = f.privacy.Receipt(app.ContextWithTenant(tenantAdminContext("acme"), "acme"), receipt.ID)
+		assert.ErrorIs(t, err, perrors.ErrErasureReceiptNotFound)
+		_, err = f.privacy.Receipt(adminContext(), "missing")
+		assert.ErrorIs(t, err, perrors.ErrErasureReceiptNotFound)
+	})
+}
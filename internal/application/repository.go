@@ -12,10 +12,39 @@ type UserRepository interface {
 	Create(ctx context.Context, user *domain.User) error
 	// Retrieves a user by ID.
 	GetByID(ctx context.Context, id string) (*domain.User, error)
+	// Retrieves a user by their normalized email address.
+	GetByEmail(ctx context.Context, email string) (*domain.User, error)
+	// Retrieves the users with the given IDs, skipping unknown ones.
+	GetByIDs(ctx context.Context, ids []string) ([]*domain.User, error)
 	// Retrieves all users.
 	GetAll(ctx context.Context) ([]*domain.User, error)
-	// Updates user details.
+	// Retrieves up to params.Limit users ordered by ID.
+	List(ctx context.Context, params ListParams) ([]*domain.User, error)
+	// Updates user details, leaving the password hash and email untouched.
 	Update(ctx context.Context, user *domain.User) error
+	// Replaces the user's password hash.
+	SetPasswordHash(ctx context.Context, id, hash string) error
+	// Replaces the user's email address, which is unverified then.
+	SetEmail(ctx context.Context, id, email string) error
+	// Marks the email address of a user verified, unless it changed in
+	// the meantime, reporting whether it was marked.
+	VerifyEmail(ctx context.Context, id, email string) (bool, error)
 	// Deletes a user.
 	Remove(ctx context.Context, id string) error
 }
+
+// Transactor makes the changes of several repositories atomic.
+type Transactor interface {
+	// Runs fn in a transaction, which the repositories called with the
+	// context fn gets take part in. It is committed if fn returns nil and
+	// rolled back otherwise; within a transaction, fn joins it.
+	Transaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// inTransaction runs fn in a transaction of tx, or as is without one.
+func inTransaction(ctx context.Context, tx Transactor, fn func(ctx context.Context) error) error {
+	if tx == nil {
+		return fn(ctx)
+	}
+	return tx.Transaction(ctx, fn)
+}
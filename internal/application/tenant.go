@@ -0,0 +1,212 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	perrors "github.com/Sergey-Polishchenko/simple-api/internal/pkg/errors"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/logger"
+)
+
+// DefaultTenantID is the tenant of requests that name none, and of every
+// user while multi-tenancy is disabled. Its admins operate the deployment,
+// see AllowOperator.
+const DefaultTenantID = "default"
+
+// MaxTenantNameLength is the longest name a tenant can have.
+const MaxTenantNameLength = 100
+
+// tenantIDPattern keeps tenant IDs usable as DNS labels, so that they can
+// be subdomains.
+var tenantIDPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// Tenant is a customer of the deployment. Users, API keys and second
+// factors belong to one tenant and are invisible to the others.
+type Tenant struct {
+	// ID is chosen on creation, e.g. "acme", and can't change.
+	ID        string
+	Name      string
+	CreatedAt time.Time
+}
+
+// TenantRepository defines persistence operations for tenants.
+type TenantRepository interface {
+	// Stores a new tenant.
+	Create(ctx context.Context, tenant *Tenant) error
+	// Fetches a tenant by ID.
+	GetByID(ctx context.Context, id string) (*Tenant, error)
+	// Retrieves all tenants ordered by ID.
+	GetAll(ctx context.Context) ([]*Tenant, error)
+	// Deletes a tenant.
+	Remove(ctx context.Context, id string) error
+}
+
+// TenantService defines the management of tenants.
+type TenantService interface {
+	// Creates a tenant.
+	Create(ctx context.Context, tenant *Tenant) (*Tenant, error)
+	// Retrieves a tenant by ID.
+	Get(ctx context.Context, id string) (*Tenant, error)
+	// Retrieves all tenants.
+	List(ctx context.Context) ([]*Tenant, error)
+	// Deletes a tenant without users.
+	Remove(ctx context.Context, id string) error
+	// Reports whether a tenant exists, for resolving the tenant of a
+	// request; it needs no authorization.
+	Exists(ctx context.Context, id string) (bool, error)
+}
+
+// TenantApp implements TenantService.
+type TenantApp struct {
+	db     TenantRepository
+	users  UserRepository
+	logger logger.Logger
+	now    func() time.Time
+}
+
+// NewTenantApp initializes a TenantApp instance. users tells whether a
+// tenant still has users.
+func NewTenantApp(db TenantRepository, users UserRepository, logger logger.Logger) TenantService {
+	return &TenantApp{db: db, users: users, logger: logger, now: time.Now}
+}
+
+// InvalidTenantError explains why a tenant was rejected.
+type InvalidTenantError struct {
+	Reason string
+}
+
+func (e *InvalidTenantError) Error() string {
+	return e.Reason
+}
+
+func (app *TenantApp) Create(ctx context.Context, tenant *Tenant) (*Tenant, error) {
+	if err := Authorize(ctx, ActionTenants, ""); err != nil {
+		return nil, err
+	}
+
+	tenant = &Tenant{
+		ID:        strings.TrimSpace(tenant.ID),
+		Name:      strings.TrimSpace(tenant.Name),
+		CreatedAt: app.now().UTC(),
+	}
+	if err := validateTenant(tenant); err != nil {
+		return nil, err
+	}
+
+	if err := app.db.Create(ctx, tenant); err != nil {
+		if !errors.Is(err, perrors.ErrTenantExists) {
+			app.logger.WithContext(ctx).Error("can't create tenant", "error", err)
+		}
+		return nil, err
+	}
+
+	app.logger.WithContext(ctx).Info("Tenant created", "tenant_id", tenant.ID, "actor", actor(ctx))
+
+	return tenant, nil
+}
+
+func (app *TenantApp) Get(ctx context.Context, id string) (*Tenant, error) {
+	if err := Authorize(ctx, ActionTenants, id); err != nil {
+		return nil, err
+	}
+
+	tenant, err := app.db.GetByID(ctx, id)
+	if err != nil && !errors.Is(err, perrors.ErrTenantNotFound) {
+		app.logger.WithContext(ctx).Error("can't retrive tenant", "error", err)
+	}
+	return tenant, err
+}
+
+func (app *TenantApp) List(ctx context.Context) ([]*Tenant, error) {
+	if err := Authorize(ctx, ActionTenants, ""); err != nil {
+		return nil, err
+	}
+
+	tenants, err := app.db.GetAll(ctx)
+	if err != nil {
+		app.logger.WithContext(ctx).Error("can't retrive tenants", "error", err)
+	}
+	return tenants, err
+}
+
+func (app *TenantApp) Remove(ctx context.Context, id string) error {
+	if err := Authorize(ctx, ActionTenants, id); err != nil {
+		return err
+	}
+	if id == DefaultTenantID {
+		return &InvalidTenantError{Reason: "the default tenant can't be removed"}
+	}
+
+	// The caller belongs to another tenant, so look in the removed one.
+	users, err := app.users.List(ContextWithTenant(ctx, id), ListParams{Limit: 1})
+	if err != nil {
+		app.logger.WithContext(ctx).Error("can't retrive users of tenant", "error", err)
+		return err
+	}
+	if len(users) > 0 {
+		return perrors.ErrTenantNotEmpty
+	}
+
+	if err := app.db.Remove(ctx, id); err != nil {
+		if !errors.Is(err, perrors.ErrTenantNotFound) {
+			app.logger.WithContext(ctx).Error("can't delete tenant", "error", err)
+		}
+		return err
+	}
+
+	app.logger.WithContext(ctx).Info("Tenant deleted", "tenant_id", id, "actor", actor(ctx))
+
+	return nil
+}
+
+func (app *TenantApp) Exists(ctx context.Context, id string) (bool, error) {
+	if id == DefaultTenantID {
+		return true, nil
+	}
+
+	_, err := app.db.GetByID(ctx, id)
+	if errors.Is(err, perrors.ErrTenantNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		app.logger.WithContext(ctx).Error("can't retrive tenant", "error", err)
+		return false, err
+	}
+	return true, nil
+}
+
+func validateTenant(tenant *Tenant) error {
+	if !tenantIDPattern.MatchString(tenant.ID) {
+		return &InvalidTenantError{Reason: "tenant id must be 1 to 63 lowercase letters, digits or dashes, not starting or ending with a dash"}
+	}
+	if tenant.Name == "" {
+		return &InvalidTenantError{Reason: "name is required"}
+	}
+	if len([]rune(tenant.Name)) > MaxTenantNameLength {
+		return &InvalidTenantError{Reason: "name is too long"}
+	}
+	return nil
+}
+
+type tenantKey struct{}
+
+// ContextWithTenant returns a copy of ctx scoped to a tenant.
+func ContextWithTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenantID)
+}
+
+// TenantFromContext returns the tenant operations on ctx are scoped to:
+// the one the request named, else the one of the caller's credentials,
+// else DefaultTenantID. Repositories scope every query with it.
+func TenantFromContext(ctx context.Context) string {
+	if id, ok := ctx.Value(tenantKey{}).(string); ok && id != "" {
+		return id
+	}
+	if claims, ok := ClaimsFromContext(ctx); ok && claims.TenantID != "" {
+		return claims.TenantID
+	}
+	return DefaultTenantID
+}
@@ -0,0 +1,136 @@
+package app_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	"github.com/Sergey-Polishchenko/simple-api/internal/domain"
+	"github.com/Sergey-Polishchenko/simple-api/internal/infrastructure/memory"
+	perrors "github.com/Sergey-Polishchenko/simple-api/internal/pkg/errors"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/logger"
+)
+
+// tenantAdminContext is the context of an admin of a tenant.
+func tenantAdminContext(tenant string) context.Context {
+	return app.ContextWithClaims(context.Background(), &app.Claims{
+		Subject: "admin", Roles: []string{app.RoleAdmin}, TenantID: tenant,
+	})
+}
+
+func TestTenantApp_Create(t *testing.T) {
+	tests := []struct {
+		name        string
+		ctx         context.Context
+		tenant      app.Tenant
+		expectedErr string
+	}{
+		{
+			name:   "success",
+			ctx:    adminContext(),
+			tenant: app.Tenant{ID: "acme", Name: " Acme Corp "},
+		},
+		{
+			name:        "tenant admin",
+			ctx:         tenantAdminContext("acme"),
+			tenant:      app.Tenant{ID: "globex", Name: "Globex"},
+			expectedErr: perrors.ErrForbidden.Error(),
+		},
+		{
+			name:        "invalid id",
+			ctx:         adminContext(),
+			tenant:      app.Tenant{ID: "Acme_Corp", Name: "Acme Corp"},
+			expectedErr: "tenant id must be 1 to 63 lowercase letters, digits or dashes, not starting or ending with a dash",
+		},
+		{
+			name:        "missing name",
+			ctx:         adminContext(),
+			tenant:      app.Tenant{ID: "acme"},
+			expectedErr: "name is required",
+		},
+		{
+			name:        "existing tenant",
+			ctx:         adminContext(),
+			tenant:      app.Tenant{ID: app.DefaultTenantID, Name: "Default"},
+			expectedErr: perrors.ErrTenantExists.Error(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := app.NewTenantApp(memory.NewTenantRepo(), memory.NewUserRepo(), logger.NewZapLogger())
+
+			tenant, err := service.Create(tt.ctx, &tt.tenant)
+			if tt.expectedErr != "" {
+				assert.EqualError(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "Acme Corp", tenant.Name)
+			assert.False(t, tenant.CreatedAt.IsZero())
+
+			found, err := service.Get(tt.ctx, tenant.ID)
+			require.NoError(t, err)
+			assert.Equal(t, tenant, found)
+		})
+	}
+}
+
+func TestTenantApp_Remove(t *testing.T) {
+	ctx := adminContext()
+	users := memory.NewUserRepo()
+	service := app.NewTenantApp(memory.NewTenantRepo(), users, logger.NewZapLogger())
+
+	_, err := service.Create(ctx, &app.Tenant{ID: "acme", Name: "Acme Corp"})
+	require.NoError(t, err)
+	require.NoError(t, users.Create(app.ContextWithTenant(ctx, "acme"), domain.NewUser("1", "Wile")))
+
+	var invalid *app.InvalidTenantError
+	assert.ErrorAs(t, service.Remove(ctx, app.DefaultTenantID), &invalid)
+	assert.ErrorIs(t, service.Remove(tenantAdminContext("acme"), "acme"), perrors.ErrForbidden)
+	assert.ErrorIs(t, service.Remove(ctx, "acme"), perrors.ErrTenantNotEmpty)
+
+	require.NoError(t, users.Remove(app.ContextWithTenant(ctx, "acme"), "1"))
+	require.NoError(t, service.Remove(ctx, "acme"))
+	assert.ErrorIs(t, service.Remove(ctx, "acme"), perrors.ErrTenantNotFound)
+
+	exists, err := service.Exists(context.Background(), "acme")
+	require.NoError(t, err)
+	assert.False(t, exists)
+	exists, err = service.Exists(context.Background(), app.DefaultTenantID)
+	require.NoError(t, err)
+	assert.True(t, exists)
+}
+
+func TestUserApp_Tenants(t *testing.T) {
+	service := app.NewUserApp(memory.NewUserRepo(), logger.NewZapLogger())
+	acme := tenantAdminContext("acme")
+	globex := tenantAdminContext("globex")
+
+	user, err := service.Create(acme, domain.NewUser("", "Wile").WithEmail("wile@example.com", false))
+	require.NoError(t, err)
+
+	_, err = service.Create(acme, domain.NewUser("", "Road Runner").WithEmail("wile@example.com", false))
+	assert.ErrorIs(t, err, perrors.ErrEmailTaken)
+	_, err = service.Create(globex, domain.NewUser("", "Wile").WithEmail("wile@example.com", false))
+	assert.NoError(t, err, "emails are unique per tenant")
+
+	_, err = service.GetUser(globex, user.ID())
+	assert.ErrorIs(t, err, perrors.ErrUserNotFound)
+	assert.ErrorIs(t, service.Remove(globex, user.ID()), perrors.ErrUserNotFound)
+
+	page, err := service.List(acme, app.ListParams{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, page.Users, 1)
+	assert.Equal(t, user.ID(), page.Users[0].ID())
+
+	_, err = service.GetUser(app.ContextWithTenant(acme, "globex"), user.ID())
+	assert.ErrorIs(t, err, perrors.ErrForbidden, "tenant admins can't name another tenant")
+
+	found, err := service.GetUser(app.ContextWithTenant(adminContext(), "acme"), user.ID())
+	require.NoError(t, err, "operators can")
+	assert.Equal(t, user.ID(), found.ID())
+}
@@ -3,11 +3,15 @@ package app
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/google/uuid"
 
 	"github.com/Sergey-Polishchenko/simple-api/internal/domain"
+	perrors "github.com/Sergey-Polishchenko/simple-api/internal/pkg/errors"
 	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/logger"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/password"
 )
 
 // UserService defines the operations related to user management.
@@ -18,80 +22,353 @@ type UserService interface {
 	GetAll(ctx context.Context) ([]*domain.User, error)
 	// Fetches a user by ID.
 	GetUser(ctx context.Context, id string) (*domain.User, error)
+	// Fetches the users with the given IDs, skipping unknown ones
+	// and the ones the caller may not read.
+	GetByIDs(ctx context.Context, ids []string) ([]*domain.User, error)
+	// Retrieves a page of users matching the filter.
+	List(ctx context.Context, params ListParams) (*UserPage, error)
 	// Updates user details.
 	Update(ctx context.Context, user *domain.User) error
 	// Deletes a user.
 	Remove(ctx context.Context, id string) error
+	// Sets the password the user signs in with. Users changing their own
+	// password must give the current one, if they have any.
+	SetPassword(ctx context.Context, id, current, password string) error
+	// Sets the email address of a user, which then has to be verified
+	// again; an empty one removes it. Users changing their own address
+	// must give their password, if they have any.
+	SetEmail(ctx context.Context, id, current, email string) error
+	// Fetches a user as it was at the given time, even if removed since.
+	GetUserAsOf(ctx context.Context, id string, at time.Time) (*domain.User, error)
+	// Retrieves a page of the versions of a user, newest first.
+	Versions(ctx context.Context, id string, params VersionListParams) (*UserVersionPage, error)
+	// Restores the name, email address and attributes a user had in a
+	// version; a restored email address has to be verified again.
+	Revert(ctx context.Context, id string, version int) (*domain.User, error)
+	// Deletes a user along with its versions, reporting how many there
+	// were, and erases the changes recorded in the audit entries about it
+	// and the requests of the ones made by it; users removed before are
+	// erased if they left any. Unlike Remove, it leaves no personal data
+	// of the user in the audit log.
+	Erase(ctx context.Context, id string) (int, error)
 }
 
 // UserApp implements UserService using a repository and a logger.
+// Passwords are hashed with argon2id unless WithPasswordHasher says otherwise.
 type UserApp struct {
-	db     UserRepository
-	logger logger.Logger
+	db       UserRepository
+	logger   logger.Logger
+	events   UserEventPublisher
+	hasher   PasswordHasher
+	schema   AttributeSchemaRepository
+	audit    AuditRepository
+	history  UserHistoryRepository
+	sessions RefreshTokenRepository
+	tx       Transactor
+}
+
+// Option configures optional UserApp dependencies.
+type Option func(*UserApp)
+
+// WithEventPublisher makes UserApp publish an event after every mutation.
+func WithEventPublisher(publisher UserEventPublisher) Option {
+	return func(app *UserApp) {
+		app.events = publisher
+	}
+}
+
+// WithAttributeSchema validates the custom attributes of users against the
+// definitions in the repository. Without it, users can have no attributes.
+func WithAttributeSchema(schema AttributeSchemaRepository) Option {
+	return func(app *UserApp) {
+		app.schema = schema
+	}
 }
 
 // NewUserApp initializes a UserApp instance.
-func NewUserApp(db UserRepository, logger logger.Logger) UserService {
-	return &UserApp{
+func NewUserApp(db UserRepository, logger logger.Logger, opts ...Option) UserService {
+	app := &UserApp{
 		db:     db,
 		logger: logger,
+		hasher: password.NewHasher(password.DefaultParams),
+	}
+	for _, opt := range opts {
+		opt(app)
+	}
+	return app
+}
+
+func (app *UserApp) publish(ctx context.Context, event UserEvent) {
+	event.TenantID = TenantFromContext(ctx)
+	if app.events != nil {
+		app.events.Publish(ctx, event)
 	}
 }
 
 func (app *UserApp) Create(ctx context.Context, user *domain.User) (*domain.User, error) {
-	user = domain.NewUser(uuid.New().String(), user.Name())
-	if err := app.db.Create(ctx, user); err != nil {
-		app.logger.Error("can't create user", "error", err)
+	if err := Authorize(ctx, ActionUserCreate, ""); err != nil {
+		return nil, err
+	}
+
+	email := user.Email()
+	if email != "" {
+		var err error
+		if email, err = normalizeEmail(email); err != nil {
+			return nil, err
+		}
+	}
+
+	attributes, err := app.validateAttributes(ctx, user.Attributes())
+	if err != nil {
+		return nil, err
+	}
+
+	user = domain.NewUser(uuid.New().String(), user.Name()).WithEmail(email, false).WithAttributes(attributes)
+	err = inTransaction(ctx, app.tx, func(ctx context.Context) error {
+		if err := app.db.Create(ctx, user); err != nil {
+			if !errors.Is(err, perrors.ErrEmailTaken) {
+				app.logger.WithContext(ctx).Error("can't create user", "error", err)
+			}
+			return err
+		}
+		return app.record(ctx, AuditUserCreated, user.ID(), diffUsers(nil, user))
+	})
+	if err != nil {
 		return nil, err
 	}
 
-	app.logger.Info("User creaeted", "user_id", user.ID())
+	app.logger.WithContext(ctx).Info("User creaeted", "user_id", user.ID(), "actor", actor(ctx))
+	app.publish(ctx, UserEvent{Type: UserCreated, UserID: user.ID(), User: user})
 
 	return user, nil
 }
 
 func (app *UserApp) GetAll(ctx context.Context) ([]*domain.User, error) {
+	if err := Authorize(ctx, ActionUserList, ""); err != nil {
+		return nil, err
+	}
+
 	users, err := app.db.GetAll(ctx)
 	if err != nil {
-		app.logger.Error("can't retrive all users", "error", err)
+		app.logger.WithContext(ctx).Error("can't retrive all users", "error", err)
 		return []*domain.User{}, err
 	}
 
-	app.logger.Info("All Users retrived")
+	app.logger.WithContext(ctx).Info("All Users retrived")
 
 	return users, nil
 }
 
 func (app *UserApp) GetUser(ctx context.Context, id string) (*domain.User, error) {
+	if err := Authorize(ctx, ActionUserRead, id); err != nil {
+		return nil, err
+	}
+
 	user, err := app.db.GetByID(ctx, id)
 	if err != nil {
-		app.logger.Error("can't retrive user", "error", err)
+		app.logger.WithContext(ctx).Error("can't retrive user", "error", err)
 		return nil, err
 	}
 
-	app.logger.Info("User retrived", "user_id", user.ID())
+	app.logger.WithContext(ctx).Info("User retrived", "user_id", user.ID())
 
 	return user, nil
 }
 
+func (app *UserApp) GetByIDs(ctx context.Context, ids []string) ([]*domain.User, error) {
+	ids = readable(ctx, ids)
+	if len(ids) == 0 {
+		return []*domain.User{}, nil
+	}
+
+	users, err := app.db.GetByIDs(ctx, ids)
+	if err != nil {
+		app.logger.WithContext(ctx).Error("can't retrive users by ids", "error", err)
+		return nil, err
+	}
+
+	app.logger.WithContext(ctx).Info("Users retrived by ids", "requested", len(ids), "found", len(users))
+
+	return users, nil
+}
+
+func (app *UserApp) List(ctx context.Context, params ListParams) (*UserPage, error) {
+	if err := Authorize(ctx, ActionUserList, ""); err != nil {
+		return nil, err
+	}
+
+	params = params.normalize()
+	limit := params.Limit
+
+	if len(params.Filter.Attributes) > 0 || params.SortAttribute != "" {
+		var err error
+		if params, err = app.parseAttributeParams(ctx, params); err != nil {
+			return nil, err
+		}
+	}
+
+	// Fetch one extra row to find out whether another page exists.
+	params.Limit++
+	users, err := app.db.List(ctx, params)
+	if err != nil {
+		app.logger.WithContext(ctx).Error("can't list users", "error", err)
+		return nil, err
+	}
+
+	page := &UserPage{Users: users}
+	if len(users) > limit {
+		page.Users = users[:limit]
+		page.HasNextPage = true
+	}
+
+	app.logger.WithContext(ctx).Info("Users listed", "count", len(page.Users))
+
+	return page, nil
+}
+
 func (app *UserApp) Update(ctx context.Context, user *domain.User) error {
-	if err := app.db.Update(ctx, user); err != nil {
-		app.logger.Error("can't retrive user", "error", err)
+	if err := Authorize(ctx, ActionUserUpdate, user.ID()); err != nil {
+		return err
+	}
+
+	err := inTransaction(ctx, app.tx, func(ctx context.Context) error {
+		// Users without attributes keep the ones they have, and the audit
+		// log records the changes from the current state.
+		var current *domain.User
+		if user.Attributes() == nil || app.audit != nil {
+			var err error
+			if current, err = app.db.GetByID(ctx, user.ID()); err != nil {
+				return err
+			}
+		}
+		if user.Attributes() == nil {
+			user = user.WithAttributes(current.Attributes())
+		} else {
+			attributes, err := app.validateAttributes(ctx, user.Attributes())
+			if err != nil {
+				return err
+			}
+			user = user.WithAttributes(attributes)
+		}
+
+		if err := app.db.Update(ctx, user); err != nil {
+			app.logger.WithContext(ctx).Error("can't retrive user", "error", err)
+			return err
+		}
+
+		if app.audit == nil {
+			return nil
+		}
+		// Updates leave the email address untouched.
+		updated := user.WithEmail(current.Email(), current.EmailVerified())
+		return app.record(ctx, AuditUserUpdated, user.ID(), diffUsers(current, updated))
+	})
+	if err != nil {
 		return err
 	}
 
-	app.logger.Info("User updated successfully", "user_id", user.ID())
+	app.logger.WithContext(ctx).Info("User updated successfully", "user_id", user.ID(), "actor", actor(ctx))
+	app.publish(ctx, UserEvent{Type: UserUpdated, UserID: user.ID(), User: user})
 
 	return nil
 }
 
 func (app *UserApp) Remove(ctx context.Context, id string) error {
-	if err := app.db.Remove(ctx, id); err != nil {
-		app.logger.Error("can't remove user", "error", err)
+	if err := Authorize(ctx, ActionUserDelete, id); err != nil {
+		return err
+	}
+
+	err := inTransaction(ctx, app.tx, func(ctx context.Context) error {
+		// The audit log records what the user was.
+		var removed *domain.User
+		if app.audit != nil {
+			var err error
+			if removed, err = app.db.GetByID(ctx, id); err != nil {
+				return err
+			}
+		}
+
+		if err := app.db.Remove(ctx, id); err != nil {
+			app.logger.WithContext(ctx).Error("can't remove user", "error", err)
+			return err
+		}
+
+		return app.record(ctx, AuditUserRemoved, id, diffUsers(removed, nil))
+	})
+	if err != nil {
 		return err
 	}
 
-	app.logger.Info("User removed", "user_id", id)
+	app.logger.WithContext(ctx).Info("User removed", "user_id", id, "actor", actor(ctx))
+	app.publish(ctx, UserEvent{Type: UserRemoved, UserID: id})
 
 	return nil
 }
+
+func (app *UserApp) Erase(ctx context.Context, id string) (int, error) {
+	if err := Authorize(ctx, ActionUserErase, id); err != nil {
+		return 0, err
+	}
+
+	var (
+		removed  bool
+		versions int
+	)
+	err := inTransaction(ctx, app.tx, func(ctx context.Context) error {
+		// The user goes first, so that a failure outside of transactions
+		// never leaves a user whose history is gone; erasing again then
+		// finishes the job, as users removed before are erased by what
+		// they left.
+		err := app.db.Remove(ctx, id)
+		removed = err == nil
+		if err != nil && !errors.Is(err, perrors.ErrUserNotFound) {
+			app.logger.WithContext(ctx).Error("can't remove user", "error", err)
+			return err
+		}
+
+		if app.history != nil {
+			if versions, err = app.history.Erase(ctx, id); err != nil {
+				app.logger.WithContext(ctx).Error("can't erase user versions", "error", err)
+				return err
+			}
+		}
+		var audited int
+		if app.audit != nil {
+			if audited, err = app.audit.Erase(ctx, id); err != nil {
+				app.logger.WithContext(ctx).Error("can't erase audit entries", "error", err)
+				return err
+			}
+		}
+		if !removed && versions == 0 && audited == 0 {
+			return perrors.ErrUserNotFound
+		}
+
+		return app.record(ctx, AuditUserErased, id, nil)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	app.logger.WithContext(ctx).Info("User erased", "user_id", id, "versions", versions, "actor", actor(ctx))
+	if removed {
+		app.publish(ctx, UserEvent{Type: UserRemoved, UserID: id})
+	}
+
+	return versions, nil
+}
+
+// readable keeps the IDs of the users the caller may read, so that the
+// others are skipped like unknown ones.
+func readable(ctx context.Context, ids []string) []string {
+	if Authorize(ctx, ActionUserList, "") == nil {
+		return ids
+	}
+
+	allowed := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if Authorize(ctx, ActionUserRead, id) == nil {
+			allowed = append(allowed, id)
+		}
+	}
+	return allowed
+}
@@ -126,3 +126,133 @@ func TestUserApp_GetUser(t *testing.T) {
 		})
 	}
 }
+
+func TestUserApp_List(t *testing.T) {
+	tests := []struct {
+		name         string
+		params       app.ListParams
+		repoParams   app.ListParams
+		repoUsers    []*domain.User
+		expectedPage *app.UserPage
+	}{
+		{
+			name:       "last page",
+			params:     app.ListParams{Limit: 2},
+			repoParams: app.ListParams{Limit: 3},
+			repoUsers:  []*domain.User{domain.NewUser("1", "John")},
+			expectedPage: &app.UserPage{
+				Users: []*domain.User{domain.NewUser("1", "John")},
+			},
+		},
+		{
+			name:       "has next page",
+			params:     app.ListParams{After: "1", Limit: 1},
+			repoParams: app.ListParams{After: "1", Limit: 2},
+			repoUsers:  []*domain.User{domain.NewUser("2", "Jane"), domain.NewUser("3", "Joe")},
+			expectedPage: &app.UserPage{
+				Users:       []*domain.User{domain.NewUser("2", "Jane")},
+				HasNextPage: true,
+			},
+		},
+		{
+			name:         "default and max limits",
+			params:       app.ListParams{Limit: 1000},
+			repoParams:   app.ListParams{Limit: app.MaxPageSize + 1},
+			repoUsers:    []*domain.User{},
+			expectedPage: &app.UserPage{Users: []*domain.User{}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repoMock := new(mocks.MockUserRepository)
+			app := app.NewUserApp(repoMock, logger.NewZapLogger())
+
+			repoMock.On("List", mock.Anything, tt.repoParams).Return(tt.repoUsers, nil)
+
+			page, err := app.List(context.Background(), tt.params)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedPage, page)
+			repoMock.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUserApp_PublishesEvents(t *testing.T) {
+	repoMock := new(mocks.MockUserRepository)
+	broker := app.NewUserEventBroker()
+	app := app.NewUserApp(repoMock, logger.NewZapLogger(), app.WithEventPublisher(broker))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := broker.Subscribe(ctx)
+
+	repoMock.On("Remove", mock.Anything, "1").Return(nil)
+	repoMock.On("Remove", mock.Anything, "2").Return(perrors.ErrUserNotFound)
+
+	assert.NoError(t, app.Remove(ctx, "1"))
+	assert.ErrorIs(t, app.Remove(ctx, "2"), perrors.ErrUserNotFound)
+
+	event := <-events
+	assert.Equal(t, "1", event.UserID)
+	assert.Len(t, events, 0)
+}
+
+func TestUserApp_ChecksAPIKeyScopes(t *testing.T) {
+	repoMock := new(mocks.MockUserRepository)
+	app := app.NewUserApp(repoMock, logger.NewZapLogger())
+
+	readOnly := appContextWithScopes("users:read")
+	repoMock.On("GetByID", mock.Anything, "1").Return(domain.NewUser("1", "John"), nil)
+
+	_, err := app.GetUser(readOnly, "1")
+	assert.NoError(t, err)
+
+	_, err = app.Create(readOnly, domain.NewUser("", "John"))
+	assert.ErrorIs(t, err, perrors.ErrForbidden)
+	assert.ErrorIs(t, app.Update(readOnly, domain.NewUser("1", "Jane")), perrors.ErrForbidden)
+	assert.ErrorIs(t, app.Remove(readOnly, "1"), perrors.ErrForbidden)
+
+	_, err = app.GetUser(appContextWithScopes("users:write"), "1")
+	assert.ErrorIs(t, err, perrors.ErrForbidden, "write does not imply read")
+
+	_, err = app.GetUser(appContextWithScopes("users:admin"), "1")
+	assert.NoError(t, err, "admin implies read")
+
+	repoMock.AssertExpectations(t)
+}
+
+func appContextWithScopes(scopes ...string) context.Context {
+	return app.ContextWithClaims(context.Background(), &app.Claims{Subject: "apikey:1", APIKeyID: "1", Scopes: scopes})
+}
+
+func TestUserApp_ChecksOwnership(t *testing.T) {
+	self := app.ContextWithClaims(context.Background(), &app.Claims{Subject: "1"})
+
+	repoMock := new(mocks.MockUserRepository)
+	app := app.NewUserApp(repoMock, logger.NewZapLogger())
+
+	repoMock.On("GetByID", mock.Anything, "1").Return(domain.NewUser("1", "John"), nil)
+	repoMock.On("Update", mock.Anything, domain.NewUser("1", "Johnny")).Return(nil)
+	repoMock.On("GetByIDs", mock.Anything, []string{"1"}).Return([]*domain.User{domain.NewUser("1", "John")}, nil)
+
+	_, err := app.GetUser(self, "1")
+	assert.NoError(t, err)
+	assert.NoError(t, app.Update(self, domain.NewUser("1", "Johnny")))
+
+	users, err := app.GetByIDs(self, []string{"1", "2"})
+	assert.NoError(t, err)
+	assert.Len(t, users, 1, "other users are skipped")
+
+	_, err = app.GetUser(self, "2")
+	assert.ErrorIs(t, err, perrors.ErrForbidden)
+	assert.ErrorIs(t, app.Update(self, domain.NewUser("2", "Jane")), perrors.ErrForbidden)
+	assert.ErrorIs(t, app.Remove(self, "1"), perrors.ErrForbidden, "users can't delete themselves")
+	_, err = app.Create(self, domain.NewUser("", "Jane"))
+	assert.ErrorIs(t, err, perrors.ErrForbidden)
+	_, err = app.GetAll(self)
+	assert.ErrorIs(t, err, perrors.ErrForbidden)
+
+	repoMock.AssertExpectations(t)
+}
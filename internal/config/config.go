@@ -4,6 +4,7 @@ package config
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/caarlos0/env/v10"
 	"github.com/joho/godotenv"
@@ -11,8 +12,24 @@ import (
 
 // Environment stores application configuration loaded from environment variables.
 type Environment struct {
-	Port string `env:"PORT" envDefault:"8080"`
-	DB   *dbEnvironment
+	// Env is the deployment environment, e.g. "development" or "production".
+	Env      string `env:"APP_ENV" envDefault:"development"`
+	Port     string `env:"PORT" envDefault:"8080"`
+	DB       *dbEnvironment
+	GraphQL  *graphQLEnvironment
+	JWT      *jwtEnvironment
+	Session  *sessionEnvironment
+	Password *passwordEnvironment
+	OIDC     *oidcEnvironment
+	Mail     *mailEnvironment
+	Tenancy  *tenancyEnvironment
+	Log      *logEnvironment
+	Metrics  *metricsEnvironment
+	Tracing  *tracingEnvironment
+	Health   *healthEnvironment
+	Admin    *adminEnvironment
+	// APIKeyPepper keys the hashes of API keys; API keys are disabled without it.
+	APIKeyPepper string `env:"API_KEY_PEPPER"`
 }
 
 // dbEnvironment holds database connection parameters.
@@ -22,6 +39,154 @@ type dbEnvironment struct {
 	Name     string `env:"DB_NAME,required"`
 	Port     string `env:"DB_PORT,required"`
 	Host     string `env:"DB_HOST,required"`
+	// RowLevelSecurity makes Postgres enforce the tenant scope of users too;
+	// the database user must then not be a superuser.
+	RowLevelSecurity bool `env:"DB_ROW_LEVEL_SECURITY"`
+}
+
+// IsProduction reports whether the application runs in production.
+func (e *Environment) IsProduction() bool {
+	return e.Env == "production"
+}
+
+// graphQLEnvironment holds limits applied to GraphQL operations.
+type graphQLEnvironment struct {
+	MaxDepth      int `env:"GRAPHQL_MAX_DEPTH" envDefault:"8"`
+	MaxComplexity int `env:"GRAPHQL_MAX_COMPLEXITY" envDefault:"1000"`
+}
+
+// jwtEnvironment configures verification of bearer tokens. Keys can be
+// given as an HS256 secret, a PEM public key and a JWKS file, in any combination.
+// The server issues tokens of its own only when it has a signing key.
+type jwtEnvironment struct {
+	Secret        string        `env:"JWT_SECRET"`
+	PublicKeyFile string        `env:"JWT_PUBLIC_KEY_FILE"`
+	JWKSFile      string        `env:"JWT_JWKS_FILE"`
+	JWKSRefresh   time.Duration `env:"JWT_JWKS_REFRESH" envDefault:"1m"`
+	Issuer        string        `env:"JWT_ISSUER"`
+	Audience      string        `env:"JWT_AUDIENCE"`
+	ClockSkew     time.Duration `env:"JWT_CLOCK_SKEW" envDefault:"30s"`
+	// SigningKeyFile holds the PEM private key signing the tokens issued by
+	// password logins; they are signed with Secret without it.
+	SigningKeyFile string `env:"JWT_SIGNING_KEY_FILE"`
+	// SigningKeyID is the "kid" of the tokens signed with SigningKeyFile.
+	SigningKeyID string `env:"JWT_SIGNING_KEY_ID"`
+}
+
+// Enabled reports whether any verification key is configured.
+func (j *jwtEnvironment) Enabled() bool {
+	return j.Secret != "" || j.PublicKeyFile != "" || j.JWKSFile != "" || j.SigningKeyFile != ""
+}
+
+// sessionEnvironment configures the tokens issued by password logins.
+type sessionEnvironment struct {
+	AccessTokenTTL  time.Duration `env:"ACCESS_TOKEN_TTL" envDefault:"15m"`
+	RefreshTokenTTL time.Duration `env:"REFRESH_TOKEN_TTL" envDefault:"720h"`
+	// MFAIssuer names the service in the authenticator apps of users.
+	MFAIssuer string `env:"MFA_ISSUER" envDefault:"simple-api"`
+}
+
+// passwordEnvironment holds the argon2id cost parameters. Raising them
+// rehashes passwords as their users log in.
+type passwordEnvironment struct {
+	// Memory is in KiB.
+	Memory      uint32 `env:"PASSWORD_ARGON2_MEMORY" envDefault:"65536"`
+	Iterations  uint32 `env:"PASSWORD_ARGON2_ITERATIONS" envDefault:"3"`
+	Parallelism uint8  `env:"PASSWORD_ARGON2_PARALLELISM" envDefault:"2"`
+}
+
+// oidcEnvironment configures the built-in OpenID Connect provider. It
+// requires JWT_SIGNING_KEY_FILE, as the signing key is published,
+// JWT_ISSUER, the URL the server is reached at, and JWT_AUDIENCE.
+type oidcEnvironment struct {
+	Enabled    bool          `env:"OIDC_ENABLED"`
+	IDTokenTTL time.Duration `env:"ID_TOKEN_TTL" envDefault:"1h"`
+}
+
+// mailEnvironment configures outgoing email and the email verification
+// and password reset flows, which are disabled without a token secret.
+type mailEnvironment struct {
+	// Driver is "smtp", or "file" and "log" for local development.
+	Driver       string `env:"MAIL_DRIVER" envDefault:"log"`
+	From         string `env:"MAIL_FROM" envDefault:"simple-api <no-reply@localhost>"`
+	SMTPHost     string `env:"SMTP_HOST"`
+	SMTPPort     string `env:"SMTP_PORT" envDefault:"587"`
+	SMTPUsername string `env:"SMTP_USERNAME"`
+	SMTPPassword string `env:"SMTP_PASSWORD"`
+	// Dir receives the emails of the "file" driver.
+	Dir string `env:"MAIL_DIR" envDefault:"mail"`
+	// TokenSecret signs verification and reset tokens.
+	TokenSecret      string        `env:"ACCOUNT_TOKEN_SECRET"`
+	VerificationTTL  time.Duration `env:"EMAIL_VERIFICATION_TTL" envDefault:"24h"`
+	PasswordResetTTL time.Duration `env:"PASSWORD_RESET_TTL" envDefault:"1h"`
+	// VerifyEmailURL and ResetPasswordURL are the pages completing the
+	// flows; emails link to them with the token appended.
+	VerifyEmailURL   string `env:"VERIFY_EMAIL_URL"`
+	ResetPasswordURL string `env:"RESET_PASSWORD_URL"`
+}
+
+// tenancyEnvironment configures multi-tenancy. Without it every request
+// is scoped to the default tenant.
+type tenancyEnvironment struct {
+	Enabled bool   `env:"TENANCY_ENABLED"`
+	Header  string `env:"TENANT_HEADER" envDefault:"X-Tenant-ID"`
+	// Domain makes subdomains of it name tenants, e.g. "acme.example.com"
+	// for "example.com".
+	Domain string `env:"TENANT_DOMAIN"`
+}
+
+// logEnvironment configures the application logs.
+type logEnvironment struct {
+	// Level is "debug", "info", "warn" or "error".
+	Level string `env:"LOG_LEVEL" envDefault:"info"`
+	// Format is "json", or "console" for human-readable lines.
+	Format string `env:"LOG_FORMAT" envDefault:"json"`
+	// HTTPBodies logs the bodies of HTTP requests and responses at debug
+	// level, with sensitive values masked.
+	HTTPBodies bool `env:"LOG_HTTP_BODIES"`
+}
+
+// metricsEnvironment configures the Prometheus metrics.
+type metricsEnvironment struct {
+	// Port serves the metrics apart from the API, so that they aren't
+	// public; metrics are disabled when it is empty.
+	Port string `env:"METRICS_PORT" envDefault:"9090"`
+}
+
+// tracingEnvironment configures the tracing of requests.
+type tracingEnvironment struct {
+	// Exporter is "otlp", "stdout" or "file"; requests are not traced
+	// when it is empty.
+	Exporter string `env:"TRACING_EXPORTER"`
+	// OTLPEndpoint is the base URL of the OTLP/HTTP endpoint of the
+	// "otlp" exporter.
+	OTLPEndpoint string `env:"OTEL_EXPORTER_OTLP_ENDPOINT" envDefault:"http://localhost:4318"`
+	// File is where the "file" exporter appends spans.
+	File string `env:"TRACING_FILE" envDefault:"traces.jsonl"`
+	// SampleRatio is the fraction of the traces started by the service
+	// that are recorded; traces of callers are recorded if they are.
+	SampleRatio float64 `env:"TRACING_SAMPLE_RATIO" envDefault:"1"`
+	ServiceName string  `env:"OTEL_SERVICE_NAME" envDefault:"simple-api"`
+}
+
+// healthEnvironment configures the health probes.
+type healthEnvironment struct {
+	// CheckTimeout bounds every dependency check of the readiness probe.
+	CheckTimeout time.Duration `env:"HEALTH_CHECK_TIMEOUT" envDefault:"2s"`
+	// ShutdownDelay keeps serving requests for a while once readiness
+	// fails on shutdown, so that load balancers can notice first.
+	ShutdownDelay time.Duration `env:"SHUTDOWN_DELAY" envDefault:"0s"`
+}
+
+// adminEnvironment configures the admin server, which serves profiles,
+// build information, the effective configuration and the log level.
+type adminEnvironment struct {
+	// Port serves the admin endpoints apart from the API; they are
+	// disabled when it is empty.
+	Port string `env:"ADMIN_PORT"`
+	// Token authenticates the requests to the admin endpoints as a bearer
+	// token; it is required with Port.
+	Token string `env:"ADMIN_TOKEN"`
 }
 
 // ConnString returns the formatted PostgreSQL connection string.
@@ -44,6 +209,18 @@ func Load() (*Environment, error) {
 
 	environment := &Environment{}
 	environment.DB = &dbEnvironment{}
+	environment.GraphQL = &graphQLEnvironment{}
+	environment.JWT = &jwtEnvironment{}
+	environment.Session = &sessionEnvironment{}
+	environment.Password = &passwordEnvironment{}
+	environment.OIDC = &oidcEnvironment{}
+	environment.Mail = &mailEnvironment{}
+	environment.Tenancy = &tenancyEnvironment{}
+	environment.Log = &logEnvironment{}
+	environment.Metrics = &metricsEnvironment{}
+	environment.Tracing = &tracingEnvironment{}
+	environment.Health = &healthEnvironment{}
+	environment.Admin = &adminEnvironment{}
 
 	err := env.Parse(environment)
 
@@ -0,0 +1,31 @@
+package domain
+
+import "strings"
+
+// Group is a named set of users and other groups. A user belongs to a
+// group directly, or through a nested group.
+type Group struct {
+	id          string
+	name        string
+	description string
+}
+
+// NewGroup creates a new Group instance.
+func NewGroup(id, name, description string) *Group {
+	return &Group{id: id, name: strings.Trim(name, " "), description: strings.TrimSpace(description)}
+}
+
+// ID returns the group ID.
+func (g *Group) ID() string {
+	return g.id
+}
+
+// Name returns the group name.
+func (g *Group) Name() string {
+	return g.name
+}
+
+// Description returns what the group is for, or an empty string.
+func (g *Group) Description() string {
+	return g.description
+}
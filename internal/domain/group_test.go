@@ -0,0 +1,10 @@
+package domain
+
+import "testing"
+
+func TestNewGroup(t *testing.T) {
+	got := NewGroup("1", "  Engineering  ", "\tBuilds things\n")
+	if got.name != "Engineering" || got.description != "Builds things" {
+		t.Errorf("NewGroup() = %+v, want trimmed name and description", got)
+	}
+}
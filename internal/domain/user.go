@@ -1,12 +1,21 @@
 // Package domain contains core business entities.
 package domain
 
-import "strings"
+import (
+	"maps"
+	"strings"
+)
 
 // User represents a system user.
 type User struct {
-	id   string
-	name string
+	id           string
+	name         string
+	passwordHash string
+	// email is empty if the user has none.
+	email         string
+	emailVerified bool
+	// attributes is nil if the user has none.
+	attributes map[string]interface{}
 }
 
 // NewUser creates a new User instance.
@@ -24,3 +33,48 @@ func (u *User) ID() string {
 func (u *User) Name() string {
 	return u.name
 }
+
+// PasswordHash returns the encoded hash of the user's password,
+// or an empty string if the user can't sign in with a password.
+func (u *User) PasswordHash() string {
+	return u.passwordHash
+}
+
+// WithPasswordHash returns a copy of the user with the given password hash.
+func (u *User) WithPasswordHash(hash string) *User {
+	user := *u
+	user.passwordHash = hash
+	return &user
+}
+
+// Email returns the user's email address, or an empty string if the user has none.
+func (u *User) Email() string {
+	return u.email
+}
+
+// EmailVerified reports whether the user proved to own their email address.
+func (u *User) EmailVerified() bool {
+	return u.emailVerified
+}
+
+// WithEmail returns a copy of the user with the given email address.
+func (u *User) WithEmail(email string, verified bool) *User {
+	user := *u
+	user.email = email
+	user.emailVerified = verified && email != ""
+	return &user
+}
+
+// Attributes returns a copy of the custom attributes of the user, whose
+// values are strings, float64 numbers or booleans. It is nil if the user
+// has none.
+func (u *User) Attributes() map[string]interface{} {
+	return maps.Clone(u.attributes)
+}
+
+// WithAttributes returns a copy of the user with the given custom attributes.
+func (u *User) WithAttributes(attributes map[string]interface{}) *User {
+	user := *u
+	user.attributes = maps.Clone(attributes)
+	return &user
+}
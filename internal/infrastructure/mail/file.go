@@ -0,0 +1,77 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/logger"
+)
+
+// FileMailer writes every email to a .eml file in a directory, for local
+// development.
+type FileMailer struct {
+	dir       string
+	from      string
+	templates *Templates
+	logger    logger.Logger
+}
+
+// NewFileMailer initializes a FileMailer instance, creating dir if needed.
+func NewFileMailer(dir, from string, templates *Templates, logger logger.Logger) (*FileMailer, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &FileMailer{dir: dir, from: from, templates: templates, logger: logger}, nil
+}
+
+func (m *FileMailer) Send(ctx context.Context, email app.Email) error {
+	msg, err := m.templates.Render(m.from, email)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	data, err := msg.Bytes(now)
+	if err != nil {
+		return err
+	}
+
+	name := filepath.Join(m.dir, fmt.Sprintf("%s-%s.eml", now.UTC().Format("20060102T150405.000000000"), email.Template))
+	if err := os.WriteFile(name, data, 0o600); err != nil {
+		return err
+	}
+
+	m.logger.WithContext(ctx).Info("Email written", "email", email.To, "subject", msg.Subject, "file", name)
+	return nil
+}
+
+// LogMailer logs every email for local development. The logger redacts
+// the tokens of the links in them; FileMailer keeps them.
+type LogMailer struct {
+	from      string
+	templates *Templates
+	logger    logger.Logger
+}
+
+// NewLogMailer initializes a LogMailer instance.
+func NewLogMailer(from string, templates *Templates, logger logger.Logger) *LogMailer {
+	return &LogMailer{from: from, templates: templates, logger: logger}
+}
+
+func (m *LogMailer) Send(ctx context.Context, email app.Email) error {
+	msg, err := m.templates.Render(m.from, email)
+	if err != nil {
+		return err
+	}
+
+	m.logger.WithContext(ctx).Info("Email", "email", email.To, "subject", msg.Subject, "body", msg.Body)
+	return nil
+}
+
+var (
+	_ app.Mailer = (*FileMailer)(nil)
+	_ app.Mailer = (*LogMailer)(nil)
+)
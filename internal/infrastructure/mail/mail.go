@@ -0,0 +1,115 @@
+// Package mail renders templated emails and delivers them over SMTP, or
+// writes them to files or the log for local development.
+package mail
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net/mail"
+	"path"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/google/uuid"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+)
+
+//go:embed templates/*.tmpl
+var templateFiles embed.FS
+
+// Templates renders emails. Every template defines a "subject" and a
+// plain text "body".
+type Templates struct {
+	templates map[string]*template.Template
+}
+
+// DefaultTemplates holds the templates of the emails sent by the application.
+var DefaultTemplates = mustParseTemplates()
+
+func mustParseTemplates() *Templates {
+	files, err := templateFiles.ReadDir("templates")
+	if err != nil {
+		panic(err)
+	}
+
+	templates := &Templates{templates: make(map[string]*template.Template, len(files))}
+	for _, file := range files {
+		name := strings.TrimSuffix(file.Name(), path.Ext(file.Name()))
+		templates.templates[name] = template.Must(
+			template.New(name).Option("missingkey=error").ParseFS(templateFiles, "templates/"+file.Name()),
+		)
+	}
+	return templates
+}
+
+// Message is a rendered email.
+type Message struct {
+	From    string
+	To      string
+	Subject string
+	Body    string
+}
+
+// Render renders an email sent from the given address.
+func (t *Templates) Render(from string, email app.Email) (*Message, error) {
+	tmpl, ok := t.templates[email.Template]
+	if !ok {
+		return nil, fmt.Errorf("unknown email template %q", email.Template)
+	}
+
+	var subject, body strings.Builder
+	if err := tmpl.ExecuteTemplate(&subject, "subject", email.Data); err != nil {
+		return nil, err
+	}
+	if err := tmpl.ExecuteTemplate(&body, "body", email.Data); err != nil {
+		return nil, err
+	}
+
+	return &Message{
+		From:    from,
+		To:      email.To,
+		Subject: strings.TrimSpace(subject.String()),
+		Body:    body.String(),
+	}, nil
+}
+
+// Bytes formats the message as an RFC 5322 email.
+func (m *Message) Bytes(now time.Time) ([]byte, error) {
+	from, err := mail.ParseAddress(m.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender: %w", err)
+	}
+
+	var buf bytes.Buffer
+	header := func(name, value string) {
+		fmt.Fprintf(&buf, "%s: %s\r\n", name, value)
+	}
+	header("From", from.String())
+	header("To", m.To)
+	header("Subject", mime.QEncoding.Encode("utf-8", m.Subject))
+	header("Date", now.Format(time.RFC1123Z))
+	header("Message-ID", "<"+uuid.New().String()+"@"+domainOf(from.Address)+">")
+	header("MIME-Version", "1.0")
+	header("Content-Type", `text/plain; charset="utf-8"`)
+	header("Content-Transfer-Encoding", "quoted-printable")
+	buf.WriteString("\r\n")
+
+	w := quotedprintable.NewWriter(&buf)
+	if _, err := w.Write([]byte(strings.ReplaceAll(m.Body, "\n", "\r\n"))); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func domainOf(address string) string {
+	_, domain, _ := strings.Cut(address, "@")
+	return domain
+}
@@ -0,0 +1,101 @@
+package mail_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime/quotedprintable"
+	netmail "net/mail"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	"github.com/Sergey-Polishchenko/simple-api/internal/infrastructure/mail"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/logger"
+)
+
+func TestTemplates_Render(t *testing.T) {
+	tests := []struct {
+		name     string
+		email    app.Email
+		subject  string
+		contains []string
+		excludes []string
+	}{
+		{
+			name: "verification with a link",
+			email: app.Email{To: "john@example.com", Template: app.TemplateVerifyEmail, Data: map[string]string{
+				"Name": "John", "Token": "t0k3n", "Link": "https://example.com/verify?token=t0k3n", "ExpiresIn": "24 hours",
+			}},
+			subject:  "Verify your email address",
+			contains: []string{"Hello John", "https://example.com/verify?token=t0k3n", "The link expires in 24 hours"},
+		},
+		{
+			name: "reset without a link",
+			email: app.Email{To: "john@example.com", Template: app.TemplateResetPassword, Data: map[string]string{
+				"Name": "John", "Token": "t0k3n", "Link": "", "ExpiresIn": "1 hour",
+			}},
+			subject:  "Reset your password",
+			contains: []string{"t0k3n", "The token expires in 1 hour"},
+			excludes: []string{"Open this link"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := mail.DefaultTemplates.Render("simple-api <no-reply@example.com>", tt.email)
+			require.NoError(t, err)
+			assert.Equal(t, tt.subject, msg.Subject)
+			assert.Equal(t, "john@example.com", msg.To)
+			for _, s := range tt.contains {
+				assert.Contains(t, msg.Body, s)
+			}
+			for _, s := range tt.excludes {
+				assert.NotContains(t, msg.Body, s)
+			}
+		})
+	}
+}
+
+func TestTemplates_RenderErrors(t *testing.T) {
+	_, err := mail.DefaultTemplates.Render("no-reply@example.com", app.Email{Template: "unknown"})
+	assert.Error(t, err)
+
+	_, err = mail.DefaultTemplates.Render("no-reply@example.com", app.Email{Template: app.TemplateVerifyEmail})
+	assert.Error(t, err, "missing data is an error")
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	mailer, err := mail.NewFileMailer(dir, "simple-api <no-reply@example.com>", mail.DefaultTemplates, logger.NewZapLogger())
+	require.NoError(t, err)
+
+	err = mailer.Send(context.Background(), app.Email{To: "john@example.com", Template: app.TemplateResetPassword, Data: map[string]string{
+		"Name": "Jöhn", "Token": "t0k3n", "Link": "", "ExpiresIn": "1 hour",
+	}})
+	require.NoError(t, err)
+
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	data, err := os.ReadFile(filepath.Join(dir, files[0].Name()))
+	require.NoError(t, err)
+
+	msg, err := netmail.ReadMessage(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, `"simple-api" <no-reply@example.com>`, msg.Header.Get("From"))
+	assert.Equal(t, "john@example.com", msg.Header.Get("To"))
+	assert.Equal(t, "Reset your password", msg.Header.Get("Subject"))
+	date, err := msg.Header.Date()
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), date, time.Minute)
+
+	body, err := io.ReadAll(quotedprintable.NewReader(msg.Body))
+	require.NoError(t, err)
+	assert.Contains(t, string(body), "Hello Jöhn")
+}
@@ -0,0 +1,68 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"time"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+)
+
+// SMTPConfig configures SMTPMailer.
+type SMTPConfig struct {
+	Host string
+	Port string
+	// Username and Password authenticate with PLAIN, which net/smtp only
+	// does over TLS or to localhost; no authentication happens without them.
+	Username string
+	Password string
+	// From is the sender, e.g. "simple-api <no-reply@example.com>".
+	From string
+}
+
+// SMTPMailer delivers emails to an SMTP server, upgrading the connection
+// with STARTTLS when the server supports it.
+type SMTPMailer struct {
+	config    SMTPConfig
+	templates *Templates
+}
+
+// NewSMTPMailer initializes an SMTPMailer instance.
+func NewSMTPMailer(config SMTPConfig, templates *Templates) (*SMTPMailer, error) {
+	if config.Host == "" {
+		return nil, errors.New("SMTP host is required")
+	}
+	if _, err := mail.ParseAddress(config.From); err != nil {
+		return nil, err
+	}
+	return &SMTPMailer{config: config, templates: templates}, nil
+}
+
+// Send delivers an email. The context is only checked before connecting,
+// as net/smtp doesn't take one.
+func (m *SMTPMailer) Send(ctx context.Context, email app.Email) error {
+	msg, err := m.templates.Render(m.config.From, email)
+	if err != nil {
+		return err
+	}
+	data, err := msg.Bytes(time.Now())
+	if err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if m.config.Username != "" {
+		auth = smtp.PlainAuth("", m.config.Username, m.config.Password, m.config.Host)
+	}
+	from, _ := mail.ParseAddress(m.config.From)
+
+	return smtp.SendMail(net.JoinHostPort(m.config.Host, m.config.Port), auth, from.Address, []string{email.To}, data)
+}
+
+var _ app.Mailer = (*SMTPMailer)(nil)
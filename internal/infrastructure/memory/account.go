@@ -0,0 +1,31 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+)
+
+type UsedTokenRepo struct {
+	mu     sync.Mutex
+	tokens map[string]time.Time
+}
+
+func NewUsedTokenRepo() *UsedTokenRepo {
+	return &UsedTokenRepo{tokens: make(map[string]time.Time)}
+}
+
+func (tr *UsedTokenRepo) Use(_ context.Context, id string, expiresAt time.Time) (bool, error) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	if _, ok := tr.tokens[id]; ok {
+		return false, nil
+	}
+	tr.tokens[id] = expiresAt
+	return true, nil
+}
+
+var _ app.UsedTokenRepository = (*UsedTokenRepo)(nil)
@@ -0,0 +1,90 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	perrors "github.com/Sergey-Polishchenko/simple-api/internal/pkg/errors"
+)
+
+type APIKeyRepo struct {
+	mu   sync.RWMutex
+	keys map[string]*app.APIKey
+}
+
+func NewAPIKeyRepo() *APIKeyRepo {
+	return &APIKeyRepo{keys: make(map[string]*app.APIKey)}
+}
+
+func (kr *APIKeyRepo) Create(_ context.Context, key *app.APIKey) error {
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+
+	stored := *key
+	kr.keys[key.ID] = &stored
+	return nil
+}
+
+func (kr *APIKeyRepo) GetByPrefix(_ context.Context, prefix string) (*app.APIKey, error) {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+
+	for _, key := range kr.keys {
+		if key.Prefix == prefix {
+			found := *key
+			return &found, nil
+		}
+	}
+	return nil, perrors.ErrAPIKeyNotFound
+}
+
+func (kr *APIKeyRepo) GetAll(ctx context.Context) ([]*app.APIKey, error) {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+
+	tenant := app.TenantFromContext(ctx)
+	keys := make([]*app.APIKey, 0, len(kr.keys))
+	for _, key := range kr.keys {
+		if key.TenantID != tenant {
+			continue
+		}
+		found := *key
+		keys = append(keys, &found)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if !keys[i].CreatedAt.Equal(keys[j].CreatedAt) {
+			return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+		}
+		return keys[i].ID < keys[j].ID
+	})
+	return keys, nil
+}
+
+func (kr *APIKeyRepo) Revoke(ctx context.Context, id string, at time.Time) error {
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+
+	key, ok := kr.keys[id]
+	if !ok || key.TenantID != app.TenantFromContext(ctx) {
+		return perrors.ErrAPIKeyNotFound
+	}
+	if key.RevokedAt == nil {
+		key.RevokedAt = &at
+	}
+	return nil
+}
+
+func (kr *APIKeyRepo) Touch(_ context.Context, id string, at time.Time) error {
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+
+	if key, ok := kr.keys[id]; ok {
+		key.LastUsedAt = &at
+	}
+	return nil
+}
+
+var _ app.APIKeyRepository = (*APIKeyRepo)(nil)
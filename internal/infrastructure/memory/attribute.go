@@ -0,0 +1,67 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"sync"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	perrors "github.com/Sergey-Polishchenko/simple-api/internal/pkg/errors"
+)
+
+// AttributeSchemaRepo keeps a set of definitions per tenant, like the SQL
+// repository.
+type AttributeSchemaRepo struct {
+	mu          sync.RWMutex
+	definitions map[string]map[string]*app.AttributeDefinition
+}
+
+func NewAttributeSchemaRepo() *AttributeSchemaRepo {
+	return &AttributeSchemaRepo{definitions: make(map[string]map[string]*app.AttributeDefinition)}
+}
+
+func (ar *AttributeSchemaRepo) GetAll(ctx context.Context) ([]*app.AttributeDefinition, error) {
+	ar.mu.RLock()
+	defer ar.mu.RUnlock()
+
+	tenant := ar.definitions[app.TenantFromContext(ctx)]
+	definitions := make([]*app.AttributeDefinition, 0, len(tenant))
+	for _, definition := range tenant {
+		definitions = append(definitions, copyDefinition(definition))
+	}
+	sort.Slice(definitions, func(i, j int) bool { return definitions[i].Name < definitions[j].Name })
+	return definitions, nil
+}
+
+func (ar *AttributeSchemaRepo) Put(ctx context.Context, definition *app.AttributeDefinition) error {
+	ar.mu.Lock()
+	defer ar.mu.Unlock()
+
+	tenant := app.TenantFromContext(ctx)
+	if ar.definitions[tenant] == nil {
+		ar.definitions[tenant] = make(map[string]*app.AttributeDefinition)
+	}
+	ar.definitions[tenant][definition.Name] = copyDefinition(definition)
+	return nil
+}
+
+func (ar *AttributeSchemaRepo) Remove(ctx context.Context, name string) error {
+	ar.mu.Lock()
+	defer ar.mu.Unlock()
+
+	tenant := ar.definitions[app.TenantFromContext(ctx)]
+	if _, ok := tenant[name]; !ok {
+		return perrors.ErrAttributeNotFound
+	}
+	delete(tenant, name)
+	return nil
+}
+
+func copyDefinition(definition *app.AttributeDefinition) *app.AttributeDefinition {
+	copied := *definition
+	copied.Enum = slices.Clone(definition.Enum)
+	return &copied
+}
+
+var _ app.AttributeSchemaRepository = (*AttributeSchemaRepo)(nil)
@@ -0,0 +1,171 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+)
+
+// AuditRepo keeps a chain of entries per tenant, like the SQL repository.
+type AuditRepo struct {
+	mu sync.RWMutex
+	// entries are ordered by sequence number, across tenants.
+	entries  []*app.AuditEntry
+	sequence int64
+}
+
+func NewAuditRepo() *AuditRepo {
+	return &AuditRepo{}
+}
+
+func (ar *AuditRepo) Append(ctx context.Context, entry *app.AuditEntry) error {
+	ar.mu.Lock()
+	defer ar.mu.Unlock()
+
+	tenant := app.TenantFromContext(ctx)
+	var prevHash string
+	for i := len(ar.entries) - 1; i >= 0; i-- {
+		if ar.entries[i].TenantID == tenant {
+			prevHash = ar.entries[i].Hash
+			break
+		}
+	}
+
+	entry.TenantID = tenant
+	ar.sequence++
+	entry.Sequence = ar.sequence
+	entry.Seal(prevHash)
+	ar.entries = append(ar.entries, copyAuditEntry(entry))
+
+	sequence := entry.Sequence
+	onRollback(ctx, func() {
+		ar.mu.Lock()
+		defer ar.mu.Unlock()
+
+		ar.entries = slices.DeleteFunc(ar.entries, func(entry *app.AuditEntry) bool {
+			return entry.Sequence == sequence
+		})
+	})
+	return nil
+}
+
+func (ar *AuditRepo) List(ctx context.Context, params app.AuditListParams) ([]*app.AuditEntry, error) {
+	ar.mu.RLock()
+	defer ar.mu.RUnlock()
+
+	tenant := app.TenantFromContext(ctx)
+	filter := params.Filter
+	var entries []*app.AuditEntry
+	for i := len(ar.entries) - 1; i >= 0 && len(entries) < params.Limit; i-- {
+		entry := ar.entries[i]
+		switch {
+		case entry.TenantID != tenant,
+			params.After != 0 && entry.Sequence >= params.After,
+			filter.ActorID != "" && entry.ActorID != filter.ActorID,
+			filter.Action != "" && entry.Action != filter.Action,
+			filter.TargetID != "" && entry.TargetID != filter.TargetID,
+			!filter.Since.IsZero() && entry.CreatedAt.Before(filter.Since),
+			!filter.Until.IsZero() && !entry.CreatedAt.Before(filter.Until):
+			continue
+		}
+		entries = append(entries, copyAuditEntry(entry))
+	}
+	return entries, nil
+}
+
+func (ar *AuditRepo) Chain(ctx context.Context, after int64, limit int) ([]*app.AuditEntry, error) {
+	ar.mu.RLock()
+	defer ar.mu.RUnlock()
+
+	tenant := app.TenantFromContext(ctx)
+	var entries []*app.AuditEntry
+	for _, entry := range ar.entries {
+		if len(entries) == limit {
+			break
+		}
+		if entry.TenantID == tenant && entry.Sequence > after {
+			entries = append(entries, copyAuditEntry(entry))
+		}
+	}
+	return entries, nil
+}
+
+func (ar *AuditRepo) Erase(ctx context.Context, userID string) (int, error) {
+	ar.mu.Lock()
+	defer ar.mu.Unlock()
+
+	tenant := app.TenantFromContext(ctx)
+	var erased int
+	for i, entry := range ar.entries {
+		if entry.TenantID != tenant {
+			continue
+		}
+		about := entry.TargetID == userID && entry.ChangesSalt != ""
+		madeBy := entry.ActorKind == app.PrincipalUser.String() && entry.ActorID == userID && entry.RequestSalt != ""
+		if !about && !madeBy {
+			continue
+		}
+		stripped := copyAuditEntry(entry)
+		if about {
+			stripped.Changes, stripped.ChangesSalt = nil, ""
+		}
+		if madeBy {
+			stripped.RequestID, stripped.ClientIP, stripped.RequestSalt = "", "", ""
+		}
+		ar.entries[i] = stripped
+		ar.keep(ctx, entry)
+		erased++
+	}
+	return erased, nil
+}
+
+// keep puts a stored entry back if the transaction of ctx is rolled back;
+// stored entries are replaced, never changed, so it still holds the state
+// to restore.
+func (ar *AuditRepo) keep(ctx context.Context, kept *app.AuditEntry) {
+	onRollback(ctx, func() {
+		ar.mu.Lock()
+		defer ar.mu.Unlock()
+
+		for i, entry := range ar.entries {
+			if entry.Sequence == kept.Sequence {
+				ar.entries[i] = kept
+			}
+		}
+	})
+}
+
+// Tamper replaces a stored entry as someone with access to the storage
+// could, for testing the verification of chains.
+func (ar *AuditRepo) Tamper(sequence int64, tamper func(entry *app.AuditEntry)) {
+	ar.mu.Lock()
+	defer ar.mu.Unlock()
+
+	for i, entry := range ar.entries {
+		if entry.Sequence == sequence {
+			tampered := copyAuditEntry(entry)
+			tamper(tampered)
+			ar.entries[i] = tampered
+		}
+	}
+}
+
+// Delete removes a stored entry, see Tamper.
+func (ar *AuditRepo) Delete(sequence int64) {
+	ar.mu.Lock()
+	defer ar.mu.Unlock()
+
+	ar.entries = slices.DeleteFunc(ar.entries, func(entry *app.AuditEntry) bool {
+		return entry.Sequence == sequence
+	})
+}
+
+func copyAuditEntry(entry *app.AuditEntry) *app.AuditEntry {
+	copied := *entry
+	copied.Changes = slices.Clone(entry.Changes)
+	return &copied
+}
+
+var _ app.AuditRepository = (*AuditRepo)(nil)
@@ -0,0 +1,255 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	"github.com/Sergey-Polishchenko/simple-api/internal/domain"
+	perrors "github.com/Sergey-Polishchenko/simple-api/internal/pkg/errors"
+)
+
+// GroupRepo scopes groups to the tenant of the context, like the SQL
+// repository.
+type GroupRepo struct {
+	mu     sync.RWMutex
+	groups map[string]*domain.Group
+	// tenants maps group IDs to the tenant of the group.
+	tenants map[string]string
+	// users and children map group IDs to the sets of their direct members.
+	users    map[string]map[string]bool
+	children map[string]map[string]bool
+}
+
+func NewGroupRepo() *GroupRepo {
+	return &GroupRepo{
+		groups:   make(map[string]*domain.Group),
+		tenants:  make(map[string]string),
+		users:    make(map[string]map[string]bool),
+		children: make(map[string]map[string]bool),
+	}
+}
+
+func (gr *GroupRepo) Create(ctx context.Context, group *domain.Group) error {
+	gr.mu.Lock()
+	defer gr.mu.Unlock()
+
+	tenant := app.TenantFromContext(ctx)
+	if gr.nameTaken(tenant, group.Name(), group.ID()) {
+		return perrors.ErrGroupNameTaken
+	}
+	gr.groups[group.ID()] = group
+	gr.tenants[group.ID()] = tenant
+	gr.users[group.ID()] = make(map[string]bool)
+	gr.children[group.ID()] = make(map[string]bool)
+	return nil
+}
+
+func (gr *GroupRepo) GetByID(ctx context.Context, id string) (*domain.Group, error) {
+	gr.mu.RLock()
+	defer gr.mu.RUnlock()
+
+	group, ok := gr.get(ctx, id)
+	if !ok {
+		return nil, perrors.ErrGroupNotFound
+	}
+	return group, nil
+}
+
+func (gr *GroupRepo) GetAll(ctx context.Context) ([]*domain.Group, error) {
+	gr.mu.RLock()
+	defer gr.mu.RUnlock()
+
+	tenant := app.TenantFromContext(ctx)
+	groups := make([]*domain.Group, 0, len(gr.groups))
+	for id, group := range gr.groups {
+		if gr.tenants[id] == tenant {
+			groups = append(groups, group)
+		}
+	}
+	return sortedGroups(groups), nil
+}
+
+func (gr *GroupRepo) Update(ctx context.Context, group *domain.Group) error {
+	gr.mu.Lock()
+	defer gr.mu.Unlock()
+
+	if _, ok := gr.get(ctx, group.ID()); !ok {
+		return perrors.ErrGroupNotFound
+	}
+	if gr.nameTaken(app.TenantFromContext(ctx), group.Name(), group.ID()) {
+		return perrors.ErrGroupNameTaken
+	}
+	gr.groups[group.ID()] = group
+	return nil
+}
+
+func (gr *GroupRepo) Remove(ctx context.Context, id string) error {
+	gr.mu.Lock()
+	defer gr.mu.Unlock()
+
+	if _, ok := gr.get(ctx, id); !ok {
+		return perrors.ErrGroupNotFound
+	}
+	delete(gr.groups, id)
+	delete(gr.tenants, id)
+	delete(gr.users, id)
+	delete(gr.children, id)
+	for _, children := range gr.children {
+		delete(children, id)
+	}
+	return nil
+}
+
+func (gr *GroupRepo) AddUser(ctx context.Context, groupID, userID string) error {
+	gr.mu.Lock()
+	defer gr.mu.Unlock()
+
+	if _, ok := gr.get(ctx, groupID); !ok {
+		return perrors.ErrGroupNotFound
+	}
+	gr.users[groupID][userID] = true
+	return nil
+}
+
+func (gr *GroupRepo) AddGroup(ctx context.Context, parentID, childID string) error {
+	gr.mu.Lock()
+	defer gr.mu.Unlock()
+
+	_, parentOK := gr.get(ctx, parentID)
+	_, childOK := gr.get(ctx, childID)
+	if !parentOK || !childOK {
+		return perrors.ErrGroupNotFound
+	}
+	if gr.descendants(childID)[parentID] {
+		return perrors.ErrGroupCycle
+	}
+	gr.children[parentID][childID] = true
+	return nil
+}
+
+func (gr *GroupRepo) RemoveMember(ctx context.Context, groupID, memberID string) error {
+	gr.mu.Lock()
+	defer gr.mu.Unlock()
+
+	if _, ok := gr.get(ctx, groupID); !ok {
+		return perrors.ErrGroupNotFound
+	}
+	switch {
+	case gr.users[groupID][memberID]:
+		delete(gr.users[groupID], memberID)
+	case gr.children[groupID][memberID]:
+		delete(gr.children[groupID], memberID)
+	default:
+		return perrors.ErrGroupMemberNotFound
+	}
+	return nil
+}
+
+func (gr *GroupRepo) Members(ctx context.Context, groupID string) ([]string, []*domain.Group, error) {
+	gr.mu.RLock()
+	defer gr.mu.RUnlock()
+
+	if _, ok := gr.get(ctx, groupID); !ok {
+		return nil, nil, perrors.ErrGroupNotFound
+	}
+
+	userIDs := make([]string, 0, len(gr.users[groupID]))
+	for id := range gr.users[groupID] {
+		userIDs = append(userIDs, id)
+	}
+	sort.Strings(userIDs)
+
+	groups := make([]*domain.Group, 0, len(gr.children[groupID]))
+	for id := range gr.children[groupID] {
+		groups = append(groups, gr.groups[id])
+	}
+	return userIDs, sortedGroups(groups), nil
+}
+
+func (gr *GroupRepo) GetByUser(ctx context.Context, userID string, direct bool) ([]*domain.Group, error) {
+	gr.mu.RLock()
+	defer gr.mu.RUnlock()
+
+	tenant := app.TenantFromContext(ctx)
+	found := make(map[string]bool)
+	for id, users := range gr.users {
+		if users[userID] && gr.tenants[id] == tenant {
+			found[id] = true
+		}
+	}
+	if !direct {
+		// Walk up from the direct groups to every group containing them.
+		queue := make([]string, 0, len(found))
+		for id := range found {
+			queue = append(queue, id)
+		}
+		for len(queue) > 0 {
+			child := queue[0]
+			queue = queue[1:]
+			for parent, children := range gr.children {
+				if children[child] && !found[parent] {
+					found[parent] = true
+					queue = append(queue, parent)
+				}
+			}
+		}
+	}
+
+	groups := make([]*domain.Group, 0, len(found))
+	for id := range found {
+		groups = append(groups, gr.groups[id])
+	}
+	return sortedGroups(groups), nil
+}
+
+// get returns a group of the tenant of ctx; the caller holds the lock.
+func (gr *GroupRepo) get(ctx context.Context, id string) (*domain.Group, bool) {
+	group, ok := gr.groups[id]
+	if !ok || gr.tenants[id] != app.TenantFromContext(ctx) {
+		return nil, false
+	}
+	return group, true
+}
+
+// descendants returns the IDs of a group and of the groups nested in it;
+// the caller holds the lock.
+func (gr *GroupRepo) descendants(id string) map[string]bool {
+	found := map[string]bool{id: true}
+	queue := []string{id}
+	for len(queue) > 0 {
+		parent := queue[0]
+		queue = queue[1:]
+		for child := range gr.children[parent] {
+			if !found[child] {
+				found[child] = true
+				queue = append(queue, child)
+			}
+		}
+	}
+	return found
+}
+
+// nameTaken reports whether another group of the tenant has the name;
+// the caller holds the lock.
+func (gr *GroupRepo) nameTaken(tenant, name, id string) bool {
+	for otherID, group := range gr.groups {
+		if otherID != id && gr.tenants[otherID] == tenant && group.Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedGroups(groups []*domain.Group) []*domain.Group {
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Name() != groups[j].Name() {
+			return groups[i].Name() < groups[j].Name()
+		}
+		return groups[i].ID() < groups[j].ID()
+	})
+	return groups
+}
+
+var _ app.GroupRepository = (*GroupRepo)(nil)
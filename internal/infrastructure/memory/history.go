@@ -0,0 +1,120 @@
+package memory
+
+import (
+	"context"
+	"maps"
+	"time"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	"github.com/Sergey-Polishchenko/simple-api/internal/domain"
+	perrors "github.com/Sergey-Polishchenko/simple-api/internal/pkg/errors"
+)
+
+// SetClock makes the repository time the versions of users with now, for
+// tests.
+func (ur *UserRepo) SetClock(now func() time.Time) {
+	ur.mu.Lock()
+	defer ur.mu.Unlock()
+
+	ur.now = now
+}
+
+func (ur *UserRepo) GetAsOf(ctx context.Context, id string, at time.Time) (*app.UserVersion, error) {
+	ur.mu.RLock()
+	defer ur.mu.RUnlock()
+
+	for _, version := range ur.versionsOf(ctx, id) {
+		if !version.ValidFrom.After(at) && (version.Current() || at.Before(version.ValidTo)) {
+			v := *version
+			return &v, nil
+		}
+	}
+	return nil, perrors.ErrUserNotFound
+}
+
+func (ur *UserRepo) GetVersion(ctx context.Context, id string, version int) (*app.UserVersion, error) {
+	ur.mu.RLock()
+	defer ur.mu.RUnlock()
+
+	versions := ur.versionsOf(ctx, id)
+	if version <= 0 || version > len(versions) {
+		return nil, perrors.ErrUserVersionNotFound
+	}
+	v := *versions[version-1]
+	return &v, nil
+}
+
+func (ur *UserRepo) ListVersions(ctx context.Context, id string, params app.VersionListParams) ([]*app.UserVersion, error) {
+	ur.mu.RLock()
+	defer ur.mu.RUnlock()
+
+	result := []*app.UserVersion{}
+	versions := ur.versionsOf(ctx, id)
+	for i := len(versions) - 1; i >= 0; i-- {
+		if params.After > 0 && versions[i].Version >= params.After {
+			continue
+		}
+		if params.Limit > 0 && len(result) == params.Limit {
+			break
+		}
+		v := *versions[i]
+		result = append(result, &v)
+	}
+	return result, nil
+}
+
+func (ur *UserRepo) Erase(ctx context.Context, id string) (int, error) {
+	ur.mu.Lock()
+	defer ur.mu.Unlock()
+
+	erased := len(ur.versionsOf(ctx, id))
+	if erased > 0 {
+		ur.keep(ctx, id)
+		delete(ur.versions, id)
+		delete(ur.versionTenants, id)
+	}
+	return erased, nil
+}
+
+// versionsOf returns the versions of a user of the tenant of ctx. It must
+// be called with ur.mu held.
+func (ur *UserRepo) versionsOf(ctx context.Context, id string) []*app.UserVersion {
+	if ur.versionTenants[id] != app.TenantFromContext(ctx) {
+		return nil
+	}
+	return ur.versions[id]
+}
+
+// version ends the current version of a user and starts one with the
+// state of user, nil once removed. Like the SQL repository, it skips
+// changes to the password alone. It must be called with ur.mu held.
+func (ur *UserRepo) version(id, tenant string, user *domain.User) {
+	now := ur.now()
+	versions := ur.versions[id]
+	if n := len(versions); n > 0 && versions[n-1].Current() {
+		if user != nil && sameVersion(versions[n-1].User, user) {
+			return
+		}
+		versions[n-1].ValidTo = now
+	}
+	if user == nil {
+		return
+	}
+
+	// Versions don't keep password hashes.
+	kept := domain.NewUser(id, user.Name()).
+		WithEmail(user.Email(), user.EmailVerified()).
+		WithAttributes(user.Attributes())
+	ur.versions[id] = append(versions, &app.UserVersion{Version: len(versions) + 1, User: kept, ValidFrom: now})
+	ur.versionTenants[id] = tenant
+}
+
+// sameVersion reports whether two states of a user are the same version.
+func sameVersion(a, b *domain.User) bool {
+	return a.Name() == b.Name() &&
+		a.Email() == b.Email() &&
+		a.EmailVerified() == b.EmailVerified() &&
+		maps.Equal(a.Attributes(), b.Attributes())
+}
+
+var _ app.UserHistoryRepository = (*UserRepo)(nil)
@@ -0,0 +1,342 @@
+// Package memory provides an in-memory user repository for tests and local tooling.
+package memory
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	"github.com/Sergey-Polishchenko/simple-api/internal/domain"
+	perrors "github.com/Sergey-Polishchenko/simple-api/internal/pkg/errors"
+)
+
+// UserRepo scopes every operation to the tenant of the context, like the
+// SQL repository. It keeps the versions of users too, see history.go.
+type UserRepo struct {
+	mu    sync.RWMutex
+	users map[string]*domain.User
+	// tenants maps user IDs to the tenant of the user.
+	tenants map[string]string
+	// versions maps user IDs to the versions of the user, oldest first,
+	// and versionTenants to its tenant; both outlive the user.
+	versions       map[string][]*app.UserVersion
+	versionTenants map[string]string
+	now            func() time.Time
+}
+
+func NewUserRepo() *UserRepo {
+	return &UserRepo{
+		users:          make(map[string]*domain.User),
+		tenants:        make(map[string]string),
+		versions:       make(map[string][]*app.UserVersion),
+		versionTenants: make(map[string]string),
+		now:            time.Now,
+	}
+}
+
+func (ur *UserRepo) Create(ctx context.Context, user *domain.User) error {
+	ur.mu.Lock()
+	defer ur.mu.Unlock()
+
+	tenant := app.TenantFromContext(ctx)
+	if ur.emailTaken(tenant, user.Email(), user.ID()) {
+		return perrors.ErrEmailTaken
+	}
+	ur.keep(ctx, user.ID())
+	ur.users[user.ID()] = user
+	ur.tenants[user.ID()] = tenant
+	ur.version(user.ID(), tenant, user)
+	return nil
+}
+
+func (ur *UserRepo) GetAll(ctx context.Context) ([]*domain.User, error) {
+	ur.mu.RLock()
+	defer ur.mu.RUnlock()
+
+	return ur.sorted(app.TenantFromContext(ctx), func(*domain.User) bool { return true }), nil
+}
+
+func (ur *UserRepo) GetByIDs(ctx context.Context, ids []string) ([]*domain.User, error) {
+	ur.mu.RLock()
+	defer ur.mu.RUnlock()
+
+	users := make([]*domain.User, 0, len(ids))
+	for _, id := range ids {
+		if user, ok := ur.get(ctx, id); ok {
+			users = append(users, user)
+		}
+	}
+	return users, nil
+}
+
+func (ur *UserRepo) List(ctx context.Context, params app.ListParams) ([]*domain.User, error) {
+	ur.mu.RLock()
+	defer ur.mu.RUnlock()
+
+	name := strings.ToLower(params.Filter.NameContains)
+	ids := make(map[string]bool, len(params.Filter.IDs))
+	for _, id := range params.Filter.IDs {
+		ids[id] = true
+	}
+
+	tenant := app.TenantFromContext(ctx)
+	users := ur.sorted(tenant, func(u *domain.User) bool {
+		return (len(ids) == 0 || ids[u.ID()]) &&
+			strings.Contains(strings.ToLower(u.Name()), name) &&
+			hasAttributes(u, params.Filter.Attributes)
+	})
+
+	// Users are in the order of the SQL repository: by the sort attribute,
+	// missing values first, then by ID.
+	compare := func(a, b *domain.User) int {
+		if params.SortAttribute != "" {
+			if c := compareAttributes(a.Attributes()[params.SortAttribute], b.Attributes()[params.SortAttribute]); c != 0 {
+				return c
+			}
+		}
+		return strings.Compare(a.ID(), b.ID())
+	}
+	if params.Descending {
+		ascending := compare
+		compare = func(a, b *domain.User) int { return ascending(b, a) }
+	}
+	slices.SortStableFunc(users, compare)
+
+	if params.After != "" {
+		after, ok := ur.users[params.After]
+		if !ok || ur.tenants[params.After] != tenant {
+			// Without the user, the position is unknown, like in SQL.
+			return []*domain.User{}, nil
+		}
+		users = slices.DeleteFunc(users, func(u *domain.User) bool { return compare(u, after) <= 0 })
+	}
+
+	if params.Limit > 0 && len(users) > params.Limit {
+		users = users[:params.Limit]
+	}
+	return users, nil
+}
+
+func (ur *UserRepo) GetByID(ctx context.Context, id string) (*domain.User, error) {
+	ur.mu.RLock()
+	defer ur.mu.RUnlock()
+
+	user, ok := ur.get(ctx, id)
+	if !ok {
+		return nil, perrors.ErrUserNotFound
+	}
+	return user, nil
+}
+
+func (ur *UserRepo) Update(ctx context.Context, user *domain.User) error {
+	ur.mu.Lock()
+	defer ur.mu.Unlock()
+
+	existing, ok := ur.get(ctx, user.ID())
+	if !ok {
+		return perrors.ErrUserNotFound
+	}
+	// Like the SQL repository, Update leaves the password and email alone.
+	ur.keep(ctx, user.ID())
+	ur.users[user.ID()] = user.
+		WithPasswordHash(existing.PasswordHash()).
+		WithEmail(existing.Email(), existing.EmailVerified())
+	ur.version(user.ID(), ur.tenants[user.ID()], ur.users[user.ID()])
+	return nil
+}
+
+func (ur *UserRepo) SetPasswordHash(ctx context.Context, id, hash string) error {
+	ur.mu.Lock()
+	defer ur.mu.Unlock()
+
+	user, ok := ur.get(ctx, id)
+	if !ok {
+		return perrors.ErrUserNotFound
+	}
+	ur.keep(ctx, id)
+	ur.users[id] = user.WithPasswordHash(hash)
+	return nil
+}
+
+func (ur *UserRepo) GetByEmail(ctx context.Context, email string) (*domain.User, error) {
+	ur.mu.RLock()
+	defer ur.mu.RUnlock()
+
+	tenant := app.TenantFromContext(ctx)
+	for id, user := range ur.users {
+		if email != "" && user.Email() == email && ur.tenants[id] == tenant {
+			return user, nil
+		}
+	}
+	return nil, perrors.ErrUserNotFound
+}
+
+func (ur *UserRepo) SetEmail(ctx context.Context, id, email string) error {
+	ur.mu.Lock()
+	defer ur.mu.Unlock()
+
+	user, ok := ur.get(ctx, id)
+	if !ok {
+		return perrors.ErrUserNotFound
+	}
+	if ur.emailTaken(app.TenantFromContext(ctx), email, id) {
+		return perrors.ErrEmailTaken
+	}
+	ur.keep(ctx, id)
+	ur.users[id] = user.WithEmail(email, false)
+	ur.version(id, ur.tenants[id], ur.users[id])
+	return nil
+}
+
+func (ur *UserRepo) VerifyEmail(ctx context.Context, id, email string) (bool, error) {
+	ur.mu.Lock()
+	defer ur.mu.Unlock()
+
+	user, ok := ur.get(ctx, id)
+	if !ok || email == "" || user.Email() != email {
+		return false, nil
+	}
+	ur.keep(ctx, id)
+	ur.users[id] = user.WithEmail(email, true)
+	ur.version(id, ur.tenants[id], ur.users[id])
+	return true, nil
+}
+
+func (ur *UserRepo) Remove(ctx context.Context, id string) error {
+	ur.mu.Lock()
+	defer ur.mu.Unlock()
+
+	if _, ok := ur.get(ctx, id); !ok {
+		return perrors.ErrUserNotFound
+	}
+	ur.keep(ctx, id)
+	ur.version(id, ur.tenants[id], nil)
+	delete(ur.users, id)
+	delete(ur.tenants, id)
+	return nil
+}
+
+// get returns a user of the tenant of ctx. It must be called with ur.mu held.
+func (ur *UserRepo) get(ctx context.Context, id string) (*domain.User, bool) {
+	user, ok := ur.users[id]
+	if !ok || ur.tenants[id] != app.TenantFromContext(ctx) {
+		return nil, false
+	}
+	return user, true
+}
+
+// keep restores a user and its versions as they are if the transaction of
+// ctx is rolled back. It must be called with ur.mu held.
+func (ur *UserRepo) keep(ctx context.Context, id string) {
+	if transactionFrom(ctx) == nil {
+		return
+	}
+
+	user, exists := ur.users[id]
+	tenant := ur.tenants[id]
+	// Versions are changed in place when they end.
+	var versions []*app.UserVersion
+	for _, version := range ur.versions[id] {
+		v := *version
+		versions = append(versions, &v)
+	}
+	versionTenant, versioned := ur.versionTenants[id]
+
+	onRollback(ctx, func() {
+		ur.mu.Lock()
+		defer ur.mu.Unlock()
+
+		if exists {
+			ur.users[id], ur.tenants[id] = user, tenant
+		} else {
+			delete(ur.users, id)
+			delete(ur.tenants, id)
+		}
+		if versioned {
+			ur.versions[id], ur.versionTenants[id] = versions, versionTenant
+		} else {
+			delete(ur.versions, id)
+			delete(ur.versionTenants, id)
+		}
+	})
+}
+
+// emailTaken reports whether another user of the tenant has the email
+// address. It must be called with ur.mu held.
+func (ur *UserRepo) emailTaken(tenant, email, id string) bool {
+	if email == "" {
+		return false
+	}
+	for userID, user := range ur.users {
+		if userID != id && ur.tenants[userID] == tenant && user.Email() == email {
+			return true
+		}
+	}
+	return false
+}
+
+// sorted returns the users of the tenant matching keep ordered by ID. It
+// must be called with ur.mu held.
+func (ur *UserRepo) sorted(tenant string, keep func(*domain.User) bool) []*domain.User {
+	users := make([]*domain.User, 0, len(ur.users))
+	for id, user := range ur.users {
+		if ur.tenants[id] == tenant && keep(user) {
+			users = append(users, user)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID() < users[j].ID() })
+	return users
+}
+
+// hasAttributes reports whether the user has all the attribute values.
+func hasAttributes(user *domain.User, values map[string]interface{}) bool {
+	attributes := user.Attributes()
+	for name, value := range values {
+		if v, ok := attributes[name]; !ok || compareAttributes(v, value) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// compareAttributes orders attribute values like Postgres orders jsonb:
+// missing values first, then strings, numbers and booleans.
+func compareAttributes(a, b interface{}) int {
+	rank := func(v interface{}) int {
+		switch v.(type) {
+		case string:
+			return 1
+		case float64:
+			return 2
+		case bool:
+			return 3
+		}
+		return 0
+	}
+	if c := cmp.Compare(rank(a), rank(b)); c != 0 {
+		return c
+	}
+
+	switch a := a.(type) {
+	case string:
+		return strings.Compare(a, b.(string))
+	case float64:
+		return cmp.Compare(a, b.(float64))
+	case bool:
+		switch {
+		case a == b.(bool):
+			return 0
+		case a:
+			return 1
+		}
+		return -1
+	}
+	return 0
+}
+
+var _ app.UserRepository = (*UserRepo)(nil)
@@ -0,0 +1,108 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	perrors "github.com/Sergey-Polishchenko/simple-api/internal/pkg/errors"
+)
+
+type MFARepo struct {
+	mu            sync.Mutex
+	enrollments   map[string]*app.MFAEnrollment
+	recoveryCodes map[string]map[string]struct{}
+}
+
+func NewMFARepo() *MFARepo {
+	return &MFARepo{
+		enrollments:   make(map[string]*app.MFAEnrollment),
+		recoveryCodes: make(map[string]map[string]struct{}),
+	}
+}
+
+func (mr *MFARepo) Get(_ context.Context, userID string) (*app.MFAEnrollment, error) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	enrollment, ok := mr.enrollments[userID]
+	if !ok {
+		return nil, perrors.ErrMFANotEnrolled
+	}
+	found := *enrollment
+	return &found, nil
+}
+
+func (mr *MFARepo) Save(_ context.Context, enrollment *app.MFAEnrollment) error {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	stored := *enrollment
+	mr.enrollments[enrollment.UserID] = &stored
+	delete(mr.recoveryCodes, enrollment.UserID)
+	return nil
+}
+
+func (mr *MFARepo) Confirm(_ context.Context, userID string, at time.Time, recoveryCodes [][]byte) error {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	enrollment, ok := mr.enrollments[userID]
+	if !ok {
+		return perrors.ErrMFANotEnrolled
+	}
+	enrollment.ConfirmedAt = &at
+
+	codes := make(map[string]struct{}, len(recoveryCodes))
+	for _, hash := range recoveryCodes {
+		codes[string(hash)] = struct{}{}
+	}
+	mr.recoveryCodes[userID] = codes
+	return nil
+}
+
+func (mr *MFARepo) UseStep(_ context.Context, userID string, step int64) (bool, error) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	enrollment, ok := mr.enrollments[userID]
+	if !ok || enrollment.LastUsedStep >= step {
+		return false, nil
+	}
+	enrollment.LastUsedStep = step
+	return true, nil
+}
+
+func (mr *MFARepo) UseRecoveryCode(_ context.Context, userID string, hash []byte) (bool, error) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	codes := mr.recoveryCodes[userID]
+	if _, ok := codes[string(hash)]; !ok {
+		return false, nil
+	}
+	delete(codes, string(hash))
+	return true, nil
+}
+
+func (mr *MFARepo) CountRecoveryCodes(_ context.Context, userID string) (int, error) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	return len(mr.recoveryCodes[userID]), nil
+}
+
+func (mr *MFARepo) Delete(_ context.Context, userID string) error {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+
+	if _, ok := mr.enrollments[userID]; !ok {
+		return perrors.ErrMFANotEnrolled
+	}
+	delete(mr.enrollments, userID)
+	delete(mr.recoveryCodes, userID)
+	return nil
+}
+
+var _ app.MFARepository = (*MFARepo)(nil)
@@ -0,0 +1,131 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	perrors "github.com/Sergey-Polishchenko/simple-api/internal/pkg/errors"
+)
+
+type OAuthClientRepo struct {
+	mu      sync.RWMutex
+	clients map[string]*app.OAuthClient
+}
+
+func NewOAuthClientRepo() *OAuthClientRepo {
+	return &OAuthClientRepo{clients: make(map[string]*app.OAuthClient)}
+}
+
+func (cr *OAuthClientRepo) Create(_ context.Context, client *app.OAuthClient) error {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+
+	stored := *client
+	cr.clients[client.ID] = &stored
+	return nil
+}
+
+func (cr *OAuthClientRepo) GetByID(_ context.Context, id string) (*app.OAuthClient, error) {
+	cr.mu.RLock()
+	defer cr.mu.RUnlock()
+
+	client, ok := cr.clients[id]
+	if !ok {
+		return nil, perrors.ErrOAuthClientNotFound
+	}
+	found := *client
+	return &found, nil
+}
+
+func (cr *OAuthClientRepo) GetAll(_ context.Context) ([]*app.OAuthClient, error) {
+	cr.mu.RLock()
+	defer cr.mu.RUnlock()
+
+	clients := make([]*app.OAuthClient, 0, len(cr.clients))
+	for _, client := range cr.clients {
+		found := *client
+		clients = append(clients, &found)
+	}
+	sort.Slice(clients, func(i, j int) bool {
+		if !clients[i].CreatedAt.Equal(clients[j].CreatedAt) {
+			return clients[i].CreatedAt.Before(clients[j].CreatedAt)
+		}
+		return clients[i].ID < clients[j].ID
+	})
+	return clients, nil
+}
+
+func (cr *OAuthClientRepo) Remove(_ context.Context, id string) error {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+
+	if _, ok := cr.clients[id]; !ok {
+		return perrors.ErrOAuthClientNotFound
+	}
+	delete(cr.clients, id)
+	return nil
+}
+
+type AuthorizationCodeRepo struct {
+	mu    sync.Mutex
+	codes map[string]*app.AuthorizationCode
+}
+
+func NewAuthorizationCodeRepo() *AuthorizationCodeRepo {
+	return &AuthorizationCodeRepo{codes: make(map[string]*app.AuthorizationCode)}
+}
+
+func (cr *AuthorizationCodeRepo) Create(_ context.Context, code *app.AuthorizationCode) error {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+
+	stored := *code
+	cr.codes[string(code.Hash)] = &stored
+	return nil
+}
+
+func (cr *AuthorizationCodeRepo) Consume(_ context.Context, hash []byte) (*app.AuthorizationCode, error) {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+
+	code, ok := cr.codes[string(hash)]
+	if !ok {
+		return nil, perrors.ErrAuthorizationCodeNotFound
+	}
+	delete(cr.codes, string(hash))
+	return code, nil
+}
+
+type RevokedTokenRepo struct {
+	mu     sync.RWMutex
+	tokens map[string]time.Time
+}
+
+func NewRevokedTokenRepo() *RevokedTokenRepo {
+	return &RevokedTokenRepo{tokens: make(map[string]time.Time)}
+}
+
+func (tr *RevokedTokenRepo) Revoke(_ context.Context, id string, expiresAt time.Time) error {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	tr.tokens[id] = expiresAt
+	return nil
+}
+
+func (tr *RevokedTokenRepo) IsRevoked(_ context.Context, id string) (bool, error) {
+	tr.mu.RLock()
+	defer tr.mu.RUnlock()
+
+	_, ok := tr.tokens[id]
+	return ok, nil
+}
+
+var (
+	_ app.OAuthClientRepository       = (*OAuthClientRepo)(nil)
+	_ app.AuthorizationCodeRepository = (*AuthorizationCodeRepo)(nil)
+	_ app.RevokedTokenRepository      = (*RevokedTokenRepo)(nil)
+)
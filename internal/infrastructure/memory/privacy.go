@@ -0,0 +1,41 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	perrors "github.com/Sergey-Polishchenko/simple-api/internal/pkg/errors"
+)
+
+type ErasureReceiptRepo struct {
+	mu       sync.RWMutex
+	receipts map[string]*app.ErasureReceipt
+}
+
+func NewErasureReceiptRepo() *ErasureReceiptRepo {
+	return &ErasureReceiptRepo{receipts: make(map[string]*app.ErasureReceipt)}
+}
+
+func (rr *ErasureReceiptRepo) Create(_ context.Context, receipt *app.ErasureReceipt) error {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+
+	stored := *receipt
+	rr.receipts[receipt.ID] = &stored
+	return nil
+}
+
+func (rr *ErasureReceiptRepo) GetByID(ctx context.Context, id string) (*app.ErasureReceipt, error) {
+	rr.mu.RLock()
+	defer rr.mu.RUnlock()
+
+	receipt, ok := rr.receipts[id]
+	if !ok || receipt.TenantID != app.TenantFromContext(ctx) {
+		return nil, perrors.ErrErasureReceiptNotFound
+	}
+	found := *receipt
+	return &found, nil
+}
+
+var _ app.ErasureReceiptRepository = (*ErasureReceiptRepo)(nil)
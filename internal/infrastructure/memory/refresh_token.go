@@ -0,0 +1,126 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	perrors "github.com/Sergey-Polishchenko/simple-api/internal/pkg/errors"
+)
+
+type RefreshTokenRepo struct {
+	mu     sync.Mutex
+	tokens map[string]*app.RefreshToken
+}
+
+func NewRefreshTokenRepo() *RefreshTokenRepo {
+	return &RefreshTokenRepo{tokens: make(map[string]*app.RefreshToken)}
+}
+
+func (tr *RefreshTokenRepo) Create(_ context.Context, token *app.RefreshToken) error {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	stored := *token
+	tr.tokens[token.ID] = &stored
+	return nil
+}
+
+func (tr *RefreshTokenRepo) GetByHash(_ context.Context, hash []byte) (*app.RefreshToken, error) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	for _, token := range tr.tokens {
+		if bytes.Equal(token.Hash, hash) {
+			found := *token
+			return &found, nil
+		}
+	}
+	return nil, perrors.ErrRefreshTokenNotFound
+}
+
+func (tr *RefreshTokenRepo) MarkUsed(_ context.Context, id string, at time.Time) (bool, error) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	token, ok := tr.tokens[id]
+	if !ok {
+		return false, perrors.ErrRefreshTokenNotFound
+	}
+	if token.UsedAt != nil {
+		return false, nil
+	}
+	token.UsedAt = &at
+	return true, nil
+}
+
+func (tr *RefreshTokenRepo) RevokeFamily(_ context.Context, familyID string, at time.Time) error {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	for _, token := range tr.tokens {
+		if token.FamilyID == familyID && token.RevokedAt == nil {
+			token.RevokedAt = &at
+		}
+	}
+	return nil
+}
+
+func (tr *RefreshTokenRepo) RevokeByUser(ctx context.Context, userID, exceptFamilyID string, at time.Time) error {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	var revoked []*app.RefreshToken
+	for _, token := range tr.tokens {
+		if token.UserID == userID && token.RevokedAt == nil && (exceptFamilyID == "" || token.FamilyID != exceptFamilyID) {
+			token.RevokedAt = &at
+			revoked = append(revoked, token)
+		}
+	}
+
+	onRollback(ctx, func() {
+		tr.mu.Lock()
+		defer tr.mu.Unlock()
+
+		for _, token := range revoked {
+			token.RevokedAt = nil
+		}
+	})
+	return nil
+}
+
+func (tr *RefreshTokenRepo) ListByUser(_ context.Context, userID string) ([]*app.RefreshToken, error) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	tokens := []*app.RefreshToken{}
+	for _, token := range tr.tokens {
+		if token.UserID == userID {
+			found := *token
+			tokens = append(tokens, &found)
+		}
+	}
+	slices.SortFunc(tokens, func(a, b *app.RefreshToken) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return tokens, nil
+}
+
+func (tr *RefreshTokenRepo) DeleteByUser(_ context.Context, userID string) (int, error) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	var deleted int
+	for id, token := range tr.tokens {
+		if token.UserID == userID {
+			delete(tr.tokens, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+var _ app.RefreshTokenRepository = (*RefreshTokenRepo)(nil)
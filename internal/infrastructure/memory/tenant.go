@@ -0,0 +1,73 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	perrors "github.com/Sergey-Polishchenko/simple-api/internal/pkg/errors"
+)
+
+// TenantRepo starts with the default tenant, like the SQL repository.
+type TenantRepo struct {
+	mu      sync.RWMutex
+	tenants map[string]*app.Tenant
+}
+
+func NewTenantRepo() *TenantRepo {
+	return &TenantRepo{tenants: map[string]*app.Tenant{
+		app.DefaultTenantID: {ID: app.DefaultTenantID, Name: "Default", CreatedAt: time.Now().UTC()},
+	}}
+}
+
+func (tr *TenantRepo) Create(_ context.Context, tenant *app.Tenant) error {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	if _, ok := tr.tenants[tenant.ID]; ok {
+		return perrors.ErrTenantExists
+	}
+	stored := *tenant
+	tr.tenants[tenant.ID] = &stored
+	return nil
+}
+
+func (tr *TenantRepo) GetByID(_ context.Context, id string) (*app.Tenant, error) {
+	tr.mu.RLock()
+	defer tr.mu.RUnlock()
+
+	tenant, ok := tr.tenants[id]
+	if !ok {
+		return nil, perrors.ErrTenantNotFound
+	}
+	found := *tenant
+	return &found, nil
+}
+
+func (tr *TenantRepo) GetAll(_ context.Context) ([]*app.Tenant, error) {
+	tr.mu.RLock()
+	defer tr.mu.RUnlock()
+
+	tenants := make([]*app.Tenant, 0, len(tr.tenants))
+	for _, tenant := range tr.tenants {
+		found := *tenant
+		tenants = append(tenants, &found)
+	}
+	sort.Slice(tenants, func(i, j int) bool { return tenants[i].ID < tenants[j].ID })
+	return tenants, nil
+}
+
+func (tr *TenantRepo) Remove(_ context.Context, id string) error {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+
+	if _, ok := tr.tenants[id]; !ok {
+		return perrors.ErrTenantNotFound
+	}
+	delete(tr.tenants, id)
+	return nil
+}
+
+var _ app.TenantRepository = (*TenantRepo)(nil)
@@ -0,0 +1,62 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+)
+
+// Transactor runs the transactions of the repositories of this package,
+// which note how to undo their changes in the transaction of the context.
+// Transactions run one at a time, but operations outside of them see
+// their changes before they are committed.
+type Transactor struct {
+	mu sync.Mutex
+}
+
+func NewTransactor() *Transactor {
+	return &Transactor{}
+}
+
+type transactionKey struct{}
+
+// transaction holds how to undo the changes made in it, in order.
+type transaction struct {
+	undo []func()
+}
+
+func (t *Transactor) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if transactionFrom(ctx) != nil {
+		return fn(ctx)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	tx := &transaction{}
+	if err := fn(context.WithValue(ctx, transactionKey{}, tx)); err != nil {
+		for i := len(tx.undo) - 1; i >= 0; i-- {
+			tx.undo[i]()
+		}
+		return err
+	}
+	return nil
+}
+
+// transactionFrom returns the transaction of ctx, or nil outside of one.
+func transactionFrom(ctx context.Context) *transaction {
+	tx, _ := ctx.Value(transactionKey{}).(*transaction)
+	return tx
+}
+
+// onRollback makes the transaction of ctx call undo if it is rolled back;
+// outside of transactions, changes are final. undo is called with no lock
+// of the repositories held, so it takes the ones it needs.
+func onRollback(ctx context.Context, undo func()) {
+	if tx := transactionFrom(ctx); tx != nil {
+		tx.undo = append(tx.undo, undo)
+	}
+}
+
+var _ app.Transactor = (*Transactor)(nil)
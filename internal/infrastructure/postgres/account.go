@@ -0,0 +1,43 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+)
+
+type UsedTokenPG struct {
+	ID        string    `gorm:"primaryKey"`
+	ExpiresAt time.Time `gorm:"index;not null"`
+}
+
+func (UsedTokenPG) TableName() string {
+	return "used_account_tokens"
+}
+
+type UsedTokenRepo struct {
+	db *gorm.DB
+}
+
+func NewUsedTokenRepo(db *gorm.DB) (app.UsedTokenRepository, error) {
+	repo := &UsedTokenRepo{db: db}
+	err := repo.db.AutoMigrate(&UsedTokenPG{})
+	return repo, err
+}
+
+func (tr *UsedTokenRepo) Use(ctx context.Context, id string, expiresAt time.Time) (bool, error) {
+	// Expired tokens are rejected anyway; forget them.
+	err := tr.db.WithContext(ctx).Where("expires_at < ?", time.Now()).Delete(&UsedTokenPG{}).Error
+	if err != nil {
+		return false, err
+	}
+
+	result := tr.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&UsedTokenPG{ID: id, ExpiresAt: expiresAt})
+	return result.RowsAffected > 0, result.Error
+}
@@ -0,0 +1,120 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	perrors "github.com/Sergey-Polishchenko/simple-api/internal/pkg/errors"
+)
+
+type APIKeyPG struct {
+	ID       string `gorm:"primaryKey"`
+	TenantID string `gorm:"not null;default:'default';index"`
+	Name     string `gorm:"not null"`
+	Prefix   string `gorm:"uniqueIndex;not null"`
+	Hash     []byte `gorm:"not null"`
+	// Scopes are space-separated.
+	Scopes     string `gorm:"not null"`
+	CreatedAt  time.Time
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+}
+
+func (APIKeyPG) TableName() string {
+	return "api_keys"
+}
+
+type APIKeyRepo struct {
+	db *gorm.DB
+}
+
+func NewAPIKeyRepo(db *gorm.DB) (app.APIKeyRepository, error) {
+	repo := &APIKeyRepo{db: db}
+	err := repo.db.AutoMigrate(&APIKeyPG{})
+	return repo, err
+}
+
+func (kr *APIKeyRepo) Create(ctx context.Context, key *app.APIKey) error {
+	pgKey := &APIKeyPG{
+		ID:        key.ID,
+		TenantID:  key.TenantID,
+		Name:      key.Name,
+		Prefix:    key.Prefix,
+		Hash:      key.Hash,
+		Scopes:    strings.Join(key.Scopes, " "),
+		CreatedAt: key.CreatedAt,
+		ExpiresAt: key.ExpiresAt,
+	}
+
+	return kr.db.WithContext(ctx).Create(pgKey).Error
+}
+
+func (kr *APIKeyRepo) GetByPrefix(ctx context.Context, prefix string) (*app.APIKey, error) {
+	var pgKey APIKeyPG
+	err := kr.db.WithContext(ctx).Where("prefix = ?", prefix).First(&pgKey).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, perrors.ErrAPIKeyNotFound
+		}
+		return nil, err
+	}
+
+	return toAPIKey(pgKey), nil
+}
+
+func (kr *APIKeyRepo) GetAll(ctx context.Context) ([]*app.APIKey, error) {
+	var pgKeys []APIKeyPG
+	err := kr.db.WithContext(ctx).
+		Where("tenant_id = ?", app.TenantFromContext(ctx)).
+		Order("created_at, id").
+		Find(&pgKeys).Error
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]*app.APIKey, 0, len(pgKeys))
+	for _, k := range pgKeys {
+		keys = append(keys, toAPIKey(k))
+	}
+	return keys, nil
+}
+
+func (kr *APIKeyRepo) Revoke(ctx context.Context, id string, at time.Time) error {
+	result := kr.db.WithContext(ctx).Model(&APIKeyPG{}).
+		Where("id = ? AND tenant_id = ?", id, app.TenantFromContext(ctx)).
+		Update("revoked_at", gorm.Expr("COALESCE(revoked_at, ?)", at))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return perrors.ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+func (kr *APIKeyRepo) Touch(ctx context.Context, id string, at time.Time) error {
+	return kr.db.WithContext(ctx).Model(&APIKeyPG{}).
+		Where("id = ?", id).
+		Update("last_used_at", at).Error
+}
+
+func toAPIKey(k APIKeyPG) *app.APIKey {
+	return &app.APIKey{
+		ID:         k.ID,
+		TenantID:   k.TenantID,
+		Name:       k.Name,
+		Prefix:     k.Prefix,
+		Hash:       k.Hash,
+		Scopes:     strings.Fields(k.Scopes),
+		CreatedAt:  k.CreatedAt,
+		ExpiresAt:  k.ExpiresAt,
+		LastUsedAt: k.LastUsedAt,
+		RevokedAt:  k.RevokedAt,
+	}
+}
@@ -0,0 +1,95 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	perrors "github.com/Sergey-Polishchenko/simple-api/internal/pkg/errors"
+)
+
+type AttributeDefinitionPG struct {
+	TenantID  string   `gorm:"primaryKey"`
+	Name      string   `gorm:"primaryKey"`
+	Type      string   `gorm:"not null"`
+	Required  bool     `gorm:"not null;default:false"`
+	Enum      []string `gorm:"serializer:json;type:jsonb"`
+	Pattern   string   `gorm:"not null;default:''"`
+	UpdatedAt time.Time
+}
+
+func (AttributeDefinitionPG) TableName() string {
+	return "attribute_definitions"
+}
+
+// AttributeSchemaRepo scopes definitions to the tenant of the context.
+type AttributeSchemaRepo struct {
+	db *gorm.DB
+}
+
+func NewAttributeSchemaRepo(db *gorm.DB) (app.AttributeSchemaRepository, error) {
+	repo := &AttributeSchemaRepo{db: db}
+	err := repo.db.AutoMigrate(&AttributeDefinitionPG{})
+	return repo, err
+}
+
+func (ar *AttributeSchemaRepo) GetAll(ctx context.Context) ([]*app.AttributeDefinition, error) {
+	var pgDefinitions []AttributeDefinitionPG
+	err := ar.db.WithContext(ctx).
+		Where("tenant_id = ?", app.TenantFromContext(ctx)).
+		Order("name").
+		Find(&pgDefinitions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	definitions := make([]*app.AttributeDefinition, 0, len(pgDefinitions))
+	for _, d := range pgDefinitions {
+		definitions = append(definitions, &app.AttributeDefinition{
+			Name:      d.Name,
+			Type:      app.AttributeType(d.Type),
+			Required:  d.Required,
+			Enum:      d.Enum,
+			Pattern:   d.Pattern,
+			UpdatedAt: d.UpdatedAt,
+		})
+	}
+	return definitions, nil
+}
+
+func (ar *AttributeSchemaRepo) Put(ctx context.Context, definition *app.AttributeDefinition) error {
+	pgDefinition := &AttributeDefinitionPG{
+		TenantID:  app.TenantFromContext(ctx),
+		Name:      definition.Name,
+		Type:      string(definition.Type),
+		Required:  definition.Required,
+		Enum:      definition.Enum,
+		Pattern:   definition.Pattern,
+		UpdatedAt: definition.UpdatedAt,
+	}
+
+	return ar.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "tenant_id"}, {Name: "name"}},
+			DoUpdates: clause.AssignmentColumns([]string{"type", "required", "enum", "pattern", "updated_at"}),
+		}).
+		Create(pgDefinition).Error
+}
+
+func (ar *AttributeSchemaRepo) Remove(ctx context.Context, name string) error {
+	result := ar.db.WithContext(ctx).
+		Where("tenant_id = ? AND name = ?", app.TenantFromContext(ctx), name).
+		Delete(&AttributeDefinitionPG{})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return perrors.ErrAttributeNotFound
+	}
+
+	return nil
+}
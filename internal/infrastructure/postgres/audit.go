@@ -0,0 +1,267 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+)
+
+// AuditEntryPG is an entry of the audit log. A trigger rejects updates
+// and deletions, so that entries can only be appended; the hash chain
+// still gives away whoever drops the trigger to change them.
+type AuditEntryPG struct {
+	Sequence    int64     `gorm:"primaryKey;autoIncrement"`
+	ID          string    `gorm:"not null;uniqueIndex"`
+	TenantID    string    `gorm:"not null;index;index:idx_audit_entries_tenant_target,priority:1"`
+	ActorKind   string    `gorm:"not null"`
+	ActorID     string    `gorm:"not null;default:''"`
+	Action      string    `gorm:"not null"`
+	TargetID    string    `gorm:"not null;index:idx_audit_entries_tenant_target,priority:2"`
+	ChangesHash string    `gorm:"not null;default:''"`
+	RequestHash string    `gorm:"not null;default:''"`
+	CreatedAt   time.Time `gorm:"not null"`
+	PrevHash    string    `gorm:"not null;default:''"`
+	Hash        string    `gorm:"not null"`
+}
+
+func (AuditEntryPG) TableName() string {
+	return "audit_entries"
+}
+
+// AuditDetailsPG holds the personal data of an entry apart from it, with
+// no trigger, so that it can be erased: the changes along with the user
+// they are about, the request along with the user who made it. Erased
+// values are empty, like their salts.
+type AuditDetailsPG struct {
+	EntryID     string            `gorm:"primaryKey"`
+	TenantID    string            `gorm:"not null;index:idx_audit_entry_details_tenant_target,priority:1;index:idx_audit_entry_details_tenant_actor,priority:1"`
+	TargetID    string            `gorm:"not null;index:idx_audit_entry_details_tenant_target,priority:2"`
+	ActorKind   string            `gorm:"not null"`
+	ActorID     string            `gorm:"not null;default:'';index:idx_audit_entry_details_tenant_actor,priority:2"`
+	ChangesSalt string            `gorm:"not null;default:''"`
+	Changes     []app.AuditChange `gorm:"serializer:json;type:jsonb"`
+	RequestSalt string            `gorm:"not null;default:''"`
+	RequestID   string            `gorm:"not null;default:''"`
+	ClientIP    string            `gorm:"not null;default:''"`
+}
+
+func (AuditDetailsPG) TableName() string {
+	return "audit_entry_details"
+}
+
+// AuditRepo keeps a chain of entries per tenant.
+type AuditRepo struct {
+	db *gorm.DB
+}
+
+// NewAuditRepo migrates the audit log and makes it append-only.
+func NewAuditRepo(db *gorm.DB) (app.AuditRepository, error) {
+	repo := &AuditRepo{db: db}
+	if err := repo.db.AutoMigrate(&AuditEntryPG{}, &AuditDetailsPG{}); err != nil {
+		return nil, err
+	}
+
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		for _, statement := range []string{
+			`CREATE OR REPLACE FUNCTION audit_entries_append_only() RETURNS trigger AS $$
+			BEGIN
+				RAISE EXCEPTION 'audit entries can only be appended';
+			END;
+			$$ LANGUAGE plpgsql`,
+			"DROP TRIGGER IF EXISTS audit_entries_append_only ON audit_entries",
+			"CREATE TRIGGER audit_entries_append_only BEFORE UPDATE OR DELETE ON audit_entries " +
+				"FOR EACH ROW EXECUTE FUNCTION audit_entries_append_only()",
+			"DROP TRIGGER IF EXISTS audit_entries_no_truncate ON audit_entries",
+			"CREATE TRIGGER audit_entries_no_truncate BEFORE TRUNCATE ON audit_entries " +
+				"FOR EACH STATEMENT EXECUTE FUNCTION audit_entries_append_only()",
+		} {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return repo, err
+}
+
+func (ar *AuditRepo) Append(ctx context.Context, entry *app.AuditEntry) error {
+	tenant := app.TenantFromContext(ctx)
+	return conn(ctx, ar.db).Transaction(func(tx *gorm.DB) error {
+		// Serialize the appends to the chain of the tenant, so that two
+		// entries can't follow the same one; other tenants go on. In the
+		// transaction of a change, the lock is held until it ends.
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", "audit_entries:"+tenant).Error; err != nil {
+			return err
+		}
+
+		var last AuditEntryPG
+		if err := tx.Where("tenant_id = ?", tenant).Order("sequence DESC").Limit(1).Find(&last).Error; err != nil {
+			return err
+		}
+
+		entry.TenantID = tenant
+		entry.Seal(last.Hash)
+		pgEntry := toAuditEntryPG(entry)
+		if err := tx.Create(pgEntry).Error; err != nil {
+			return err
+		}
+		if entry.ChangesHash != "" || entry.RequestHash != "" {
+			err := tx.Create(&AuditDetailsPG{
+				EntryID:     entry.ID,
+				TenantID:    entry.TenantID,
+				TargetID:    entry.TargetID,
+				ActorKind:   entry.ActorKind,
+				ActorID:     entry.ActorID,
+				ChangesSalt: entry.ChangesSalt,
+				Changes:     entry.Changes,
+				RequestSalt: entry.RequestSalt,
+				RequestID:   entry.RequestID,
+				ClientIP:    entry.ClientIP,
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+		entry.Sequence = pgEntry.Sequence
+		return nil
+	})
+}
+
+func (ar *AuditRepo) List(ctx context.Context, params app.AuditListParams) ([]*app.AuditEntry, error) {
+	query := ar.scoped(ctx)
+	if params.After != 0 {
+		query = query.Where("sequence < ?", params.After)
+	}
+
+	filter := params.Filter
+	if filter.ActorID != "" {
+		query = query.Where("actor_id = ?", filter.ActorID)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", string(filter.Action))
+	}
+	if filter.TargetID != "" {
+		query = query.Where("target_id = ?", filter.TargetID)
+	}
+	if !filter.Since.IsZero() {
+		query = query.Where("created_at >= ?", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		query = query.Where("created_at < ?", filter.Until)
+	}
+
+	var pgEntries []AuditEntryPG
+	if err := query.Order("sequence DESC").Limit(params.Limit).Find(&pgEntries).Error; err != nil {
+		return nil, err
+	}
+	return ar.withDetails(ctx, pgEntries)
+}
+
+func (ar *AuditRepo) Chain(ctx context.Context, after int64, limit int) ([]*app.AuditEntry, error) {
+	var pgEntries []AuditEntryPG
+	err := ar.scoped(ctx).Where("sequence > ?", after).Order("sequence").Limit(limit).Find(&pgEntries).Error
+	if err != nil {
+		return nil, err
+	}
+	return ar.withDetails(ctx, pgEntries)
+}
+
+func (ar *AuditRepo) Erase(ctx context.Context, userID string) (int, error) {
+	about := "target_id = ? AND changes_salt <> ''"
+	madeBy := "actor_kind = ? AND actor_id = ? AND request_salt <> ''"
+	actorKind := app.PrincipalUser.String()
+
+	var erased int64
+	err := conn(ctx, ar.db).Transaction(func(tx *gorm.DB) error {
+		scoped := func() *gorm.DB {
+			return tx.Model(&AuditDetailsPG{}).Where("tenant_id = ?", app.TenantFromContext(ctx))
+		}
+		err := scoped().Where(tx.Where(about, userID).Or(madeBy, actorKind, userID)).Count(&erased).Error
+		if err != nil {
+			return err
+		}
+		err = scoped().Where(about, userID).
+			Updates(map[string]interface{}{"changes": gorm.Expr("NULL"), "changes_salt": ""}).Error
+		if err != nil {
+			return err
+		}
+		return scoped().Where(madeBy, actorKind, userID).
+			Updates(map[string]interface{}{"request_id": "", "client_ip": "", "request_salt": ""}).Error
+	})
+	return int(erased), err
+}
+
+// withDetails converts entries, attaching the personal data that is left.
+func (ar *AuditRepo) withDetails(ctx context.Context, pgEntries []AuditEntryPG) ([]*app.AuditEntry, error) {
+	entries := toAuditEntries(pgEntries)
+
+	var ids []string
+	for _, entry := range entries {
+		if entry.ChangesHash != "" || entry.RequestHash != "" {
+			ids = append(ids, entry.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return entries, nil
+	}
+
+	var pgDetails []AuditDetailsPG
+	if err := conn(ctx, ar.db).Where("entry_id IN ?", ids).Find(&pgDetails).Error; err != nil {
+		return nil, err
+	}
+	byEntry := make(map[string]AuditDetailsPG, len(pgDetails))
+	for _, details := range pgDetails {
+		byEntry[details.EntryID] = details
+	}
+	for _, entry := range entries {
+		if details, ok := byEntry[entry.ID]; ok {
+			entry.Changes, entry.ChangesSalt = details.Changes, details.ChangesSalt
+			entry.RequestID, entry.ClientIP, entry.RequestSalt = details.RequestID, details.ClientIP, details.RequestSalt
+		}
+	}
+	return entries, nil
+}
+
+func (ar *AuditRepo) scoped(ctx context.Context) *gorm.DB {
+	return conn(ctx, ar.db).Where("tenant_id = ?", app.TenantFromContext(ctx))
+}
+
+func toAuditEntryPG(entry *app.AuditEntry) *AuditEntryPG {
+	return &AuditEntryPG{
+		ID:          entry.ID,
+		TenantID:    entry.TenantID,
+		ActorKind:   entry.ActorKind,
+		ActorID:     entry.ActorID,
+		Action:      string(entry.Action),
+		TargetID:    entry.TargetID,
+		ChangesHash: entry.ChangesHash,
+		RequestHash: entry.RequestHash,
+		CreatedAt:   entry.CreatedAt,
+		PrevHash:    entry.PrevHash,
+		Hash:        entry.Hash,
+	}
+}
+
+func toAuditEntries(pgEntries []AuditEntryPG) []*app.AuditEntry {
+	entries := make([]*app.AuditEntry, len(pgEntries))
+	for i, e := range pgEntries {
+		entries[i] = &app.AuditEntry{
+			Sequence:    e.Sequence,
+			ID:          e.ID,
+			TenantID:    e.TenantID,
+			ActorKind:   e.ActorKind,
+			ActorID:     e.ActorID,
+			Action:      app.AuditAction(e.Action),
+			TargetID:    e.TargetID,
+			ChangesHash: e.ChangesHash,
+			RequestHash: e.RequestHash,
+			CreatedAt:   e.CreatedAt,
+			PrevHash:    e.PrevHash,
+			Hash:        e.Hash,
+		}
+	}
+	return entries
+}
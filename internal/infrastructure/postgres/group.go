@@ -0,0 +1,263 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	"github.com/Sergey-Polishchenko/simple-api/internal/domain"
+	perrors "github.com/Sergey-Polishchenko/simple-api/internal/pkg/errors"
+)
+
+type GroupPG struct {
+	ID          string `gorm:"primaryKey"`
+	TenantID    string `gorm:"not null;default:'default';uniqueIndex:idx_groups_tenant_name,priority:1"`
+	Name        string `gorm:"not null;uniqueIndex:idx_groups_tenant_name,priority:2"`
+	Description string `gorm:"not null;default:''"`
+	CreatedAt   time.Time
+}
+
+func (GroupPG) TableName() string {
+	return "groups"
+}
+
+// GroupUserPG makes a user a direct member of a group. Removing either
+// removes the membership.
+type GroupUserPG struct {
+	GroupID string  `gorm:"primaryKey"`
+	UserID  string  `gorm:"primaryKey;index"`
+	Group   GroupPG `gorm:"foreignKey:GroupID;constraint:OnDelete:CASCADE"`
+	User    UserPG  `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+}
+
+func (GroupUserPG) TableName() string {
+	return "group_users"
+}
+
+// GroupGroupPG nests a group in another. The index on ChildID serves the
+// walks up the hierarchy, the primary key the walks down.
+type GroupGroupPG struct {
+	ParentID string  `gorm:"primaryKey"`
+	ChildID  string  `gorm:"primaryKey;index"`
+	Parent   GroupPG `gorm:"foreignKey:ParentID;constraint:OnDelete:CASCADE"`
+	Child    GroupPG `gorm:"foreignKey:ChildID;constraint:OnDelete:CASCADE"`
+}
+
+func (GroupGroupPG) TableName() string {
+	return "group_groups"
+}
+
+type GroupRepo struct {
+	db *gorm.DB
+}
+
+// NewGroupRepo migrates the group tables, which reference the users table
+// of NewUserRepo.
+func NewGroupRepo(db *gorm.DB) (app.GroupRepository, error) {
+	repo := &GroupRepo{db: db}
+	err := repo.db.AutoMigrate(&GroupPG{}, &GroupUserPG{}, &GroupGroupPG{})
+	return repo, err
+}
+
+func (gr *GroupRepo) Create(ctx context.Context, group *domain.Group) error {
+	pgGroup := &GroupPG{
+		ID:          group.ID(),
+		TenantID:    app.TenantFromContext(ctx),
+		Name:        group.Name(),
+		Description: group.Description(),
+		CreatedAt:   time.Now().UTC(),
+	}
+
+	return gr.translate(gr.db.WithContext(ctx).Create(pgGroup).Error)
+}
+
+func (gr *GroupRepo) GetByID(ctx context.Context, id string) (*domain.Group, error) {
+	var pgGroup GroupPG
+	err := gr.scoped(ctx).Where("id = ?", id).First(&pgGroup).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, perrors.ErrGroupNotFound
+		}
+		return nil, err
+	}
+
+	return toDomainGroup(pgGroup), nil
+}
+
+func (gr *GroupRepo) GetAll(ctx context.Context) ([]*domain.Group, error) {
+	var pgGroups []GroupPG
+	if err := gr.scoped(ctx).Order("name, id").Find(&pgGroups).Error; err != nil {
+		return nil, err
+	}
+
+	return toDomainGroups(pgGroups), nil
+}
+
+func (gr *GroupRepo) Update(ctx context.Context, group *domain.Group) error {
+	result := gr.scoped(ctx).
+		Model(&GroupPG{}).
+		Where("id = ?", group.ID()).
+		Updates(map[string]interface{}{"name": group.Name(), "description": group.Description()})
+	if result.Error != nil {
+		return gr.translate(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return perrors.ErrGroupNotFound
+	}
+	return nil
+}
+
+func (gr *GroupRepo) Remove(ctx context.Context, id string) error {
+	// The memberships go along, see GroupUserPG and GroupGroupPG.
+	result := gr.scoped(ctx).Where("id = ?", id).Delete(&GroupPG{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return perrors.ErrGroupNotFound
+	}
+	return nil
+}
+
+func (gr *GroupRepo) AddUser(ctx context.Context, groupID, userID string) error {
+	return gr.db.WithContext(ctx).
+		Omit(clause.Associations).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&GroupUserPG{GroupID: groupID, UserID: userID}).Error
+}
+
+func (gr *GroupRepo) AddGroup(ctx context.Context, parentID, childID string) error {
+	return gr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Serialize nestings, so that two of them can't close a cycle
+		// together; reads go on.
+		if err := tx.Exec("LOCK TABLE group_groups IN SHARE ROW EXCLUSIVE MODE").Error; err != nil {
+			return err
+		}
+
+		var cycle bool
+		err := tx.Raw(`
+			WITH RECURSIVE descendants(id) AS (
+				SELECT CAST(? AS text)
+				UNION
+				SELECT gg.child_id FROM group_groups gg JOIN descendants d ON gg.parent_id = d.id
+			)
+			SELECT EXISTS (SELECT 1 FROM descendants WHERE id = ?)`, childID, parentID).
+			Scan(&cycle).Error
+		if err != nil {
+			return err
+		}
+		if cycle {
+			return perrors.ErrGroupCycle
+		}
+
+		return tx.
+			Omit(clause.Associations).
+			Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&GroupGroupPG{ParentID: parentID, ChildID: childID}).Error
+	})
+}
+
+func (gr *GroupRepo) RemoveMember(ctx context.Context, groupID, memberID string) error {
+	return gr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("group_id = ? AND user_id = ?", groupID, memberID).Delete(&GroupUserPG{})
+		if result.Error != nil || result.RowsAffected > 0 {
+			return result.Error
+		}
+
+		result = tx.Where("parent_id = ? AND child_id = ?", groupID, memberID).Delete(&GroupGroupPG{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return perrors.ErrGroupMemberNotFound
+		}
+		return nil
+	})
+}
+
+func (gr *GroupRepo) Members(ctx context.Context, groupID string) ([]string, []*domain.Group, error) {
+	var userIDs []string
+	err := gr.db.WithContext(ctx).
+		Model(&GroupUserPG{}).
+		Where("group_id = ?", groupID).
+		Order("user_id").
+		Pluck("user_id", &userIDs).Error
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var pgGroups []GroupPG
+	err = gr.scoped(ctx).
+		Joins("JOIN group_groups ON group_groups.child_id = groups.id").
+		Where("group_groups.parent_id = ?", groupID).
+		Order("groups.name, groups.id").
+		Find(&pgGroups).Error
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return userIDs, toDomainGroups(pgGroups), nil
+}
+
+func (gr *GroupRepo) GetByUser(ctx context.Context, userID string, direct bool) ([]*domain.Group, error) {
+	var pgGroups []GroupPG
+	if direct {
+		err := gr.scoped(ctx).
+			Joins("JOIN group_users ON group_users.group_id = groups.id").
+			Where("group_users.user_id = ?", userID).
+			Order("groups.name, groups.id").
+			Find(&pgGroups).Error
+		if err != nil {
+			return nil, err
+		}
+		return toDomainGroups(pgGroups), nil
+	}
+
+	// UNION rather than UNION ALL visits every group once, however many
+	// paths lead to it.
+	err := gr.db.WithContext(ctx).Raw(`
+		WITH RECURSIVE ancestors(id) AS (
+			SELECT group_id FROM group_users WHERE user_id = ?
+			UNION
+			SELECT gg.parent_id FROM group_groups gg JOIN ancestors a ON gg.child_id = a.id
+		)
+		SELECT groups.* FROM groups JOIN ancestors ON ancestors.id = groups.id
+		WHERE groups.tenant_id = ?
+		ORDER BY groups.name, groups.id`, userID, app.TenantFromContext(ctx)).
+		Scan(&pgGroups).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return toDomainGroups(pgGroups), nil
+}
+
+// scoped returns a session restricted to the groups of the tenant of ctx.
+func (gr *GroupRepo) scoped(ctx context.Context) *gorm.DB {
+	return gr.db.WithContext(ctx).Where("groups.tenant_id = ?", app.TenantFromContext(ctx))
+}
+
+// translate turns unique violations into ErrGroupNameTaken, the only
+// index that can be violated with UUIDs for IDs.
+func (gr *GroupRepo) translate(err error) error {
+	if isDuplicate(gr.db, err) {
+		return perrors.ErrGroupNameTaken
+	}
+	return err
+}
+
+func toDomainGroups(pgGroups []GroupPG) []*domain.Group {
+	groups := make([]*domain.Group, 0, len(pgGroups))
+	for _, g := range pgGroups {
+		groups = append(groups, toDomainGroup(g))
+	}
+	return groups
+}
+
+func toDomainGroup(g GroupPG) *domain.Group {
+	return domain.NewGroup(g.ID, g.Name, g.Description)
+}
@@ -0,0 +1,169 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	perrors "github.com/Sergey-Polishchenko/simple-api/internal/pkg/errors"
+)
+
+// UserVersionPG is a version of a user, valid from ValidFrom until ValidTo,
+// NULL for the current version. A trigger on user_pgs keeps the versions,
+// so that no write of a user escapes the history, whoever makes it.
+type UserVersionPG struct {
+	UserID        string `gorm:"primaryKey"`
+	Version       int    `gorm:"primaryKey;autoIncrement:false"`
+	TenantID      string `gorm:"not null;index"`
+	Name          string `gorm:"not null;default:''"`
+	Email         *string
+	EmailVerified bool           `gorm:"not null;default:false"`
+	Attributes    jsonAttributes `gorm:"type:jsonb;not null;default:'{}'"`
+	ValidFrom     time.Time      `gorm:"not null"`
+	ValidTo       *time.Time
+}
+
+func (UserVersionPG) TableName() string {
+	return "user_pg_versions"
+}
+
+// versioningFunction starts a new version of a user on every insert and
+// update and ends the current one on every update and delete. Updates
+// leaving the name, email address and attributes alone, like password
+// changes, are skipped, as versions don't keep password hashes.
+const versioningFunction = `CREATE OR REPLACE FUNCTION user_pgs_versioning() RETURNS trigger AS $$
+DECLARE
+	ts timestamptz := clock_timestamp();
+BEGIN
+	IF TG_OP = 'UPDATE' AND (OLD.tenant_id, OLD.name, OLD.email, OLD.email_verified, OLD.attributes)
+		IS NOT DISTINCT FROM (NEW.tenant_id, NEW.name, NEW.email, NEW.email_verified, NEW.attributes) THEN
+		RETURN NEW;
+	END IF;
+	IF TG_OP IN ('UPDATE', 'DELETE') THEN
+		UPDATE user_pg_versions SET valid_to = ts WHERE user_id = OLD.id AND valid_to IS NULL;
+	END IF;
+	IF TG_OP = 'DELETE' THEN
+		RETURN OLD;
+	END IF;
+	INSERT INTO user_pg_versions (user_id, version, tenant_id, name, email, email_verified, attributes, valid_from)
+	SELECT NEW.id, COALESCE(MAX(version), 0) + 1, NEW.tenant_id, COALESCE(NEW.name, ''), NEW.email,
+		NEW.email_verified, NEW.attributes, ts
+	FROM user_pg_versions WHERE user_id = NEW.id;
+	RETURN NEW;
+END;
+$$ LANGUAGE plpgsql`
+
+// backfillVersions starts the history of the users that predate it with
+// the migration. It sees every tenant only with row-level security lifted.
+const backfillVersions = "INSERT INTO user_pg_versions " +
+	"(user_id, version, tenant_id, name, email, email_verified, attributes, valid_from) " +
+	"SELECT id, 1, tenant_id, COALESCE(name, ''), email, email_verified, attributes, now() FROM user_pgs u " +
+	"WHERE NOT EXISTS (SELECT 1 FROM user_pg_versions v WHERE v.user_id = u.id)"
+
+// migrateHistory creates the versions table and the trigger keeping it.
+func (ur *UserRepo) migrateHistory() error {
+	if err := ur.db.AutoMigrate(&UserVersionPG{}); err != nil {
+		return err
+	}
+
+	return ur.db.Transaction(func(tx *gorm.DB) error {
+		for _, statement := range []string{
+			versioningFunction,
+			"DROP TRIGGER IF EXISTS user_pgs_versioning ON user_pgs",
+			"CREATE TRIGGER user_pgs_versioning AFTER INSERT OR UPDATE OR DELETE ON user_pgs " +
+				"FOR EACH ROW EXECUTE FUNCTION user_pgs_versioning()",
+		} {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// UserHistoryRepo reads the versions the trigger installed by NewUserRepo
+// keeps, scoped to the tenant of the context like UserRepo.
+type UserHistoryRepo struct {
+	users *UserRepo
+}
+
+// NewUserHistoryRepo reads the history of users; it takes the options
+// NewUserRepo was given.
+func NewUserHistoryRepo(db *gorm.DB, opts ...UserRepoOption) app.UserHistoryRepository {
+	users := &UserRepo{db: db}
+	for _, opt := range opts {
+		opt(users)
+	}
+	return &UserHistoryRepo{users: users}
+}
+
+func (hr *UserHistoryRepo) GetAsOf(ctx context.Context, id string, at time.Time) (*app.UserVersion, error) {
+	var pgVersion UserVersionPG
+	err := hr.users.scoped(ctx, func(db *gorm.DB) error {
+		return db.Where("user_id = ? AND valid_from <= ? AND (valid_to IS NULL OR valid_to > ?)", id, at, at).
+			First(&pgVersion).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, perrors.ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return toUserVersion(pgVersion), nil
+}
+
+func (hr *UserHistoryRepo) GetVersion(ctx context.Context, id string, version int) (*app.UserVersion, error) {
+	var pgVersion UserVersionPG
+	err := hr.users.scoped(ctx, func(db *gorm.DB) error {
+		return db.Where("user_id = ? AND version = ?", id, version).First(&pgVersion).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, perrors.ErrUserVersionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return toUserVersion(pgVersion), nil
+}
+
+func (hr *UserHistoryRepo) ListVersions(ctx context.Context, id string, params app.VersionListParams) ([]*app.UserVersion, error) {
+	var pgVersions []UserVersionPG
+	err := hr.users.scoped(ctx, func(db *gorm.DB) error {
+		query := db.Where("user_id = ?", id)
+		if params.After > 0 {
+			query = query.Where("version < ?", params.After)
+		}
+		return query.Order("version DESC").Limit(params.Limit).Find(&pgVersions).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]*app.UserVersion, len(pgVersions))
+	for i, v := range pgVersions {
+		versions[i] = toUserVersion(v)
+	}
+	return versions, nil
+}
+
+func (hr *UserHistoryRepo) Erase(ctx context.Context, id string) (int, error) {
+	var erased int64
+	err := hr.users.scoped(ctx, func(db *gorm.DB) error {
+		result := db.Where("user_id = ?", id).Delete(&UserVersionPG{})
+		erased = result.RowsAffected
+		return result.Error
+	})
+	return int(erased), err
+}
+
+func toUserVersion(v UserVersionPG) *app.UserVersion {
+	user := UserPG{ID: v.UserID, Name: v.Name, Email: v.Email, EmailVerified: v.EmailVerified, Attributes: v.Attributes}
+	version := &app.UserVersion{Version: v.Version, User: toDomainUser(user), ValidFrom: v.ValidFrom}
+	if v.ValidTo != nil {
+		version.ValidTo = *v.ValidTo
+	}
+	return version
+}
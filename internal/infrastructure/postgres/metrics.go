@@ -0,0 +1,70 @@
+package postgres
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/metrics"
+)
+
+// queryStartKey keeps the start time of a statement in its gorm instance.
+const queryStartKey = "metrics:query_start"
+
+// QueryMetrics is a gorm plugin measuring the latency of queries by
+// operation and table.
+type QueryMetrics struct {
+	duration *metrics.HistogramVec
+}
+
+// NewQueryMetrics registers the metrics of database queries; install it
+// with gorm.DB.Use.
+func NewQueryMetrics(registry *metrics.Registry) *QueryMetrics {
+	return &QueryMetrics{
+		duration: registry.NewHistogramVec("db_query_duration_seconds",
+			"Latency of database queries.", nil, "operation", "table"),
+	}
+}
+
+func (m *QueryMetrics) Name() string {
+	return "metrics"
+}
+
+func (m *QueryMetrics) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	return errors.Join(
+		callbacks.Create().Before("gorm:create").Register("metrics:before_create", m.before),
+		callbacks.Create().After("gorm:create").Register("metrics:after_create", m.after("create")),
+		callbacks.Query().Before("gorm:query").Register("metrics:before_query", m.before),
+		callbacks.Query().After("gorm:query").Register("metrics:after_query", m.after("query")),
+		callbacks.Update().Before("gorm:update").Register("metrics:before_update", m.before),
+		callbacks.Update().After("gorm:update").Register("metrics:after_update", m.after("update")),
+		callbacks.Delete().Before("gorm:delete").Register("metrics:before_delete", m.before),
+		callbacks.Delete().After("gorm:delete").Register("metrics:after_delete", m.after("delete")),
+		callbacks.Row().Before("gorm:row").Register("metrics:before_row", m.before),
+		callbacks.Row().After("gorm:row").Register("metrics:after_row", m.after("row")),
+		callbacks.Raw().Before("gorm:raw").Register("metrics:before_raw", m.before),
+		callbacks.Raw().After("gorm:raw").Register("metrics:after_raw", m.after("raw")),
+	)
+}
+
+func (m *QueryMetrics) before(db *gorm.DB) {
+	db.InstanceSet(queryStartKey, time.Now())
+}
+
+func (m *QueryMetrics) after(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		start, ok := db.InstanceGet(queryStartKey)
+		if !ok {
+			return
+		}
+		table := db.Statement.Table
+		if table == "" {
+			table = "unknown"
+		}
+		m.duration.With(operation, table).Observe(time.Since(start.(time.Time)).Seconds())
+	}
+}
+
+var _ gorm.Plugin = (*QueryMetrics)(nil)
@@ -0,0 +1,144 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	perrors "github.com/Sergey-Polishchenko/simple-api/internal/pkg/errors"
+)
+
+type MFAEnrollmentPG struct {
+	UserID       string `gorm:"primaryKey"`
+	TenantID     string `gorm:"not null;default:'default'"`
+	Secret       string `gorm:"not null"`
+	ConfirmedAt  *time.Time
+	LastUsedStep int64 `gorm:"not null;default:0"`
+	CreatedAt    time.Time
+}
+
+func (MFAEnrollmentPG) TableName() string {
+	return "mfa_enrollments"
+}
+
+type RecoveryCodePG struct {
+	UserID string `gorm:"primaryKey"`
+	Hash   []byte `gorm:"primaryKey"`
+}
+
+func (RecoveryCodePG) TableName() string {
+	return "mfa_recovery_codes"
+}
+
+type MFARepo struct {
+	db *gorm.DB
+}
+
+func NewMFARepo(db *gorm.DB) (app.MFARepository, error) {
+	repo := &MFARepo{db: db}
+	err := repo.db.AutoMigrate(&MFAEnrollmentPG{}, &RecoveryCodePG{})
+	return repo, err
+}
+
+func (mr *MFARepo) Get(ctx context.Context, userID string) (*app.MFAEnrollment, error) {
+	var pgEnrollment MFAEnrollmentPG
+	err := mr.db.WithContext(ctx).Where("user_id = ?", userID).First(&pgEnrollment).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, perrors.ErrMFANotEnrolled
+		}
+		return nil, err
+	}
+
+	return &app.MFAEnrollment{
+		UserID:       pgEnrollment.UserID,
+		TenantID:     pgEnrollment.TenantID,
+		Secret:       pgEnrollment.Secret,
+		ConfirmedAt:  pgEnrollment.ConfirmedAt,
+		LastUsedStep: pgEnrollment.LastUsedStep,
+		CreatedAt:    pgEnrollment.CreatedAt,
+	}, nil
+}
+
+func (mr *MFARepo) Save(ctx context.Context, enrollment *app.MFAEnrollment) error {
+	return mr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := deleteMFA(tx, enrollment.UserID); err != nil {
+			return err
+		}
+
+		return tx.Create(&MFAEnrollmentPG{
+			UserID:       enrollment.UserID,
+			TenantID:     enrollment.TenantID,
+			Secret:       enrollment.Secret,
+			ConfirmedAt:  enrollment.ConfirmedAt,
+			LastUsedStep: enrollment.LastUsedStep,
+			CreatedAt:    enrollment.CreatedAt,
+		}).Error
+	})
+}
+
+func (mr *MFARepo) Confirm(ctx context.Context, userID string, at time.Time, recoveryCodes [][]byte) error {
+	return mr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&MFAEnrollmentPG{}).Where("user_id = ?", userID).Update("confirmed_at", at)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return perrors.ErrMFANotEnrolled
+		}
+
+		codes := make([]RecoveryCodePG, len(recoveryCodes))
+		for i, hash := range recoveryCodes {
+			codes[i] = RecoveryCodePG{UserID: userID, Hash: hash}
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+func (mr *MFARepo) UseStep(ctx context.Context, userID string, step int64) (bool, error) {
+	result := mr.db.WithContext(ctx).
+		Model(&MFAEnrollmentPG{}).
+		Where("user_id = ? AND last_used_step < ?", userID, step).
+		Update("last_used_step", step)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (mr *MFARepo) UseRecoveryCode(ctx context.Context, userID string, hash []byte) (bool, error) {
+	result := mr.db.WithContext(ctx).Where("user_id = ? AND hash = ?", userID, hash).Delete(&RecoveryCodePG{})
+	return result.RowsAffected == 1, result.Error
+}
+
+func (mr *MFARepo) CountRecoveryCodes(ctx context.Context, userID string) (int, error) {
+	var count int64
+	err := mr.db.WithContext(ctx).Model(&RecoveryCodePG{}).Where("user_id = ?", userID).Count(&count).Error
+	return int(count), err
+}
+
+func (mr *MFARepo) Delete(ctx context.Context, userID string) error {
+	var deleted bool
+	err := mr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("user_id = ?", userID).Delete(&MFAEnrollmentPG{})
+		if result.Error != nil {
+			return result.Error
+		}
+		deleted = result.RowsAffected > 0
+		return tx.Where("user_id = ?", userID).Delete(&RecoveryCodePG{}).Error
+	})
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return perrors.ErrMFANotEnrolled
+	}
+	return nil
+}
+
+func deleteMFA(tx *gorm.DB, userID string) error {
+	if err := tx.Where("user_id = ?", userID).Delete(&RecoveryCodePG{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("user_id = ?", userID).Delete(&MFAEnrollmentPG{}).Error
+}
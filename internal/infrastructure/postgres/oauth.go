@@ -0,0 +1,216 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	perrors "github.com/Sergey-Polishchenko/simple-api/internal/pkg/errors"
+)
+
+type OAuthClientPG struct {
+	ID         string `gorm:"primaryKey"`
+	Name       string `gorm:"not null"`
+	SecretHash []byte
+	// RedirectURIs and Scopes are space-separated.
+	RedirectURIs string `gorm:"not null"`
+	Scopes       string `gorm:"not null"`
+	CreatedAt    time.Time
+}
+
+func (OAuthClientPG) TableName() string {
+	return "oauth_clients"
+}
+
+type AuthorizationCodePG struct {
+	Hash          []byte `gorm:"primaryKey"`
+	ClientID      string `gorm:"not null"`
+	UserID        string `gorm:"not null"`
+	TenantID      string `gorm:"not null;default:'default'"`
+	RedirectURI   string `gorm:"not null"`
+	Scope         string `gorm:"not null"`
+	Nonce         string `gorm:"not null"`
+	CodeChallenge string `gorm:"not null"`
+	AuthTime      time.Time
+	ExpiresAt     time.Time `gorm:"index;not null"`
+}
+
+func (AuthorizationCodePG) TableName() string {
+	return "oauth_authorization_codes"
+}
+
+type RevokedTokenPG struct {
+	ID        string    `gorm:"primaryKey"`
+	ExpiresAt time.Time `gorm:"index;not null"`
+}
+
+func (RevokedTokenPG) TableName() string {
+	return "oauth_revoked_tokens"
+}
+
+type OAuthClientRepo struct {
+	db *gorm.DB
+}
+
+func NewOAuthClientRepo(db *gorm.DB) (app.OAuthClientRepository, error) {
+	repo := &OAuthClientRepo{db: db}
+	err := repo.db.AutoMigrate(&OAuthClientPG{})
+	return repo, err
+}
+
+func (cr *OAuthClientRepo) Create(ctx context.Context, client *app.OAuthClient) error {
+	pgClient := &OAuthClientPG{
+		ID:           client.ID,
+		Name:         client.Name,
+		SecretHash:   client.SecretHash,
+		RedirectURIs: strings.Join(client.RedirectURIs, " "),
+		Scopes:       strings.Join(client.Scopes, " "),
+		CreatedAt:    client.CreatedAt,
+	}
+
+	return cr.db.WithContext(ctx).Create(pgClient).Error
+}
+
+func (cr *OAuthClientRepo) GetByID(ctx context.Context, id string) (*app.OAuthClient, error) {
+	var pgClient OAuthClientPG
+	err := cr.db.WithContext(ctx).Where("id = ?", id).First(&pgClient).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, perrors.ErrOAuthClientNotFound
+		}
+		return nil, err
+	}
+
+	return toOAuthClient(pgClient), nil
+}
+
+func (cr *OAuthClientRepo) GetAll(ctx context.Context) ([]*app.OAuthClient, error) {
+	var pgClients []OAuthClientPG
+	if err := cr.db.WithContext(ctx).Order("created_at, id").Find(&pgClients).Error; err != nil {
+		return nil, err
+	}
+
+	clients := make([]*app.OAuthClient, 0, len(pgClients))
+	for _, c := range pgClients {
+		clients = append(clients, toOAuthClient(c))
+	}
+	return clients, nil
+}
+
+func (cr *OAuthClientRepo) Remove(ctx context.Context, id string) error {
+	result := cr.db.WithContext(ctx).Where("id = ?", id).Delete(&OAuthClientPG{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return perrors.ErrOAuthClientNotFound
+	}
+	return nil
+}
+
+func toOAuthClient(c OAuthClientPG) *app.OAuthClient {
+	return &app.OAuthClient{
+		ID:           c.ID,
+		Name:         c.Name,
+		SecretHash:   c.SecretHash,
+		RedirectURIs: strings.Fields(c.RedirectURIs),
+		Scopes:       strings.Fields(c.Scopes),
+		CreatedAt:    c.CreatedAt,
+	}
+}
+
+type AuthorizationCodeRepo struct {
+	db *gorm.DB
+}
+
+func NewAuthorizationCodeRepo(db *gorm.DB) (app.AuthorizationCodeRepository, error) {
+	repo := &AuthorizationCodeRepo{db: db}
+	err := repo.db.AutoMigrate(&AuthorizationCodePG{})
+	return repo, err
+}
+
+func (cr *AuthorizationCodeRepo) Create(ctx context.Context, code *app.AuthorizationCode) error {
+	// Expired codes are never consumed; drop them as new ones come.
+	err := cr.db.WithContext(ctx).Where("expires_at < ?", time.Now()).Delete(&AuthorizationCodePG{}).Error
+	if err != nil {
+		return err
+	}
+
+	pgCode := &AuthorizationCodePG{
+		Hash:          code.Hash,
+		ClientID:      code.ClientID,
+		UserID:        code.UserID,
+		TenantID:      code.TenantID,
+		RedirectURI:   code.RedirectURI,
+		Scope:         code.Scope,
+		Nonce:         code.Nonce,
+		CodeChallenge: code.CodeChallenge,
+		AuthTime:      code.AuthTime,
+		ExpiresAt:     code.ExpiresAt,
+	}
+
+	return cr.db.WithContext(ctx).Create(pgCode).Error
+}
+
+// Consume deletes the code and returns the deleted row, so that of two
+// concurrent exchanges of the same code exactly one gets it.
+func (cr *AuthorizationCodeRepo) Consume(ctx context.Context, hash []byte) (*app.AuthorizationCode, error) {
+	var deleted []AuthorizationCodePG
+	result := cr.db.WithContext(ctx).
+		Clauses(clause.Returning{}).
+		Where("hash = ?", hash).
+		Delete(&deleted)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if len(deleted) == 0 {
+		return nil, perrors.ErrAuthorizationCodeNotFound
+	}
+
+	c := deleted[0]
+	return &app.AuthorizationCode{
+		Hash:          c.Hash,
+		ClientID:      c.ClientID,
+		UserID:        c.UserID,
+		TenantID:      c.TenantID,
+		RedirectURI:   c.RedirectURI,
+		Scope:         c.Scope,
+		Nonce:         c.Nonce,
+		CodeChallenge: c.CodeChallenge,
+		AuthTime:      c.AuthTime,
+		ExpiresAt:     c.ExpiresAt,
+	}, nil
+}
+
+type RevokedTokenRepo struct {
+	db *gorm.DB
+}
+
+func NewRevokedTokenRepo(db *gorm.DB) (app.RevokedTokenRepository, error) {
+	repo := &RevokedTokenRepo{db: db}
+	err := repo.db.AutoMigrate(&RevokedTokenPG{})
+	return repo, err
+}
+
+func (tr *RevokedTokenRepo) Revoke(ctx context.Context, id string, expiresAt time.Time) error {
+	// Expired tokens are rejected anyway; forget them.
+	err := tr.db.WithContext(ctx).Where("expires_at < ?", time.Now()).Delete(&RevokedTokenPG{}).Error
+	if err != nil {
+		return err
+	}
+
+	return tr.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&RevokedTokenPG{ID: id, ExpiresAt: expiresAt}).Error
+}
+
+func (tr *RevokedTokenRepo) IsRevoked(ctx context.Context, id string) (bool, error) {
+	var count int64
+	err := tr.db.WithContext(ctx).Model(&RevokedTokenPG{}).Where("id = ?", id).Count(&count).Error
+	return count > 0, err
+}
@@ -2,9 +2,14 @@ package postgres
 
 import (
 	"context"
+	"database/sql/driver"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"strings"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
 	"github.com/Sergey-Polishchenko/simple-api/internal/domain"
@@ -12,50 +17,305 @@ import (
 )
 
 type UserPG struct {
-	ID   string `gorm:"primaryKey"`
-	Name string
+	ID string `gorm:"primaryKey"`
+	// TenantID defaults to the default tenant for the users that predate
+	// multi-tenancy.
+	TenantID     string `gorm:"not null;default:'default';index;uniqueIndex:idx_user_pgs_tenant_email,priority:1"`
+	Name         string
+	PasswordHash string
+	// Email is NULL for users without one, as the unique index allows
+	// any number of NULLs.
+	Email         *string `gorm:"uniqueIndex:idx_user_pgs_tenant_email,priority:2"`
+	EmailVerified bool    `gorm:"not null;default:false"`
+	// Attributes holds the custom attributes as a jsonb object, with a GIN
+	// index serving the containment queries of attribute filters.
+	Attributes jsonAttributes `gorm:"type:jsonb;not null;default:'{}'"`
 }
 
+// jsonAttributes stores custom attributes as a JSON object.
+type jsonAttributes map[string]interface{}
+
+func (a jsonAttributes) Value() (driver.Value, error) {
+	if a == nil {
+		return "{}", nil
+	}
+	data, err := json.Marshal(map[string]interface{}(a))
+	return string(data), err
+}
+
+func (a *jsonAttributes) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*a = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("can't scan %T into attributes", value)
+	}
+	return json.Unmarshal(data, (*map[string]interface{})(a))
+}
+
+// attributeKey selects the custom attribute given as a parameter, as jsonb
+// null when the user doesn't have it.
+const attributeKey = "COALESCE(attributes -> ?, 'null'::jsonb)"
+
+// tenantSetting is the setting row-level security reads the tenant from.
+const tenantSetting = "app.tenant_id"
+
+// UserRepo scopes every query to the tenant of the context, see
+// app.TenantFromContext.
 type UserRepo struct {
-	db *gorm.DB
+	db               *gorm.DB
+	rowLevelSecurity bool
+}
+
+// UserRepoOption configures optional UserRepo behavior.
+type UserRepoOption func(*UserRepo)
+
+// WithRowLevelSecurity makes Postgres enforce the tenant scope as well, with
+// a row-level security policy on the users table. Queries then run in
+// transactions setting the tenant. Superusers and roles with BYPASSRLS are
+// not subject to the policy, so the application should not connect as one.
+func WithRowLevelSecurity() UserRepoOption {
+	return func(ur *UserRepo) {
+		ur.rowLevelSecurity = true
+	}
 }
 
-func NewUserRepo(db *gorm.DB) (app.UserRepository, error) {
+func NewUserRepo(db *gorm.DB, opts ...UserRepoOption) (app.UserRepository, error) {
 	repo := &UserRepo{db: db}
+	for _, opt := range opts {
+		opt(repo)
+	}
 	err := repo.migrate()
 	return repo, err
 }
 
 func (ur *UserRepo) migrate() error {
-	return ur.db.AutoMigrate(&UserPG{})
+	if err := ur.db.AutoMigrate(&UserPG{}); err != nil {
+		return err
+	}
+
+	if err := ur.db.Exec("CREATE INDEX IF NOT EXISTS idx_user_pgs_attributes ON user_pgs USING GIN (attributes jsonb_path_ops)").Error; err != nil {
+		return err
+	}
+
+	// Emails used to be unique across the whole table.
+	if ur.db.Migrator().HasIndex(&UserPG{}, "idx_user_pgs_email") {
+		if err := ur.db.Migrator().DropIndex(&UserPG{}, "idx_user_pgs_email"); err != nil {
+			return err
+		}
+	}
+
+	if err := ur.migrateHistory(); err != nil {
+		return err
+	}
+
+	// The versions of users are isolated like the users themselves.
+	tables := []string{"user_pgs", "user_pg_versions"}
+	return ur.db.Transaction(func(tx *gorm.DB) error {
+		statements := []string{}
+		for _, table := range tables {
+			statements = append(statements, "ALTER TABLE "+table+" NO FORCE ROW LEVEL SECURITY, DISABLE ROW LEVEL SECURITY")
+		}
+		statements = append(statements, backfillVersions)
+		if ur.rowLevelSecurity {
+			for _, table := range tables {
+				statements = append(statements,
+					"ALTER TABLE "+table+" ENABLE ROW LEVEL SECURITY, FORCE ROW LEVEL SECURITY",
+					"DROP POLICY IF EXISTS tenant_isolation ON "+table,
+					"CREATE POLICY tenant_isolation ON "+table+" "+
+						"USING (tenant_id = current_setting('"+tenantSetting+"', true)) "+
+						"WITH CHECK (tenant_id = current_setting('"+tenantSetting+"', true))",
+				)
+			}
+		}
+		for _, statement := range statements {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// scoped runs fn with a session restricted to the users of the tenant of
+// ctx, in the transaction of ctx if there is one, setting the tenant for
+// row-level security if it is enforced.
+func (ur *UserRepo) scoped(ctx context.Context, fn func(db *gorm.DB) error) error {
+	tenant := app.TenantFromContext(ctx)
+	if !ur.rowLevelSecurity {
+		return fn(conn(ctx, ur.db).Where("tenant_id = ?", tenant))
+	}
+
+	return conn(ctx, ur.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT set_config(?, ?, true)", tenantSetting, tenant).Error; err != nil {
+			return err
+		}
+		return fn(tx.Where("tenant_id = ?", tenant))
+	})
 }
 
 func (ur *UserRepo) Create(ctx context.Context, user *domain.User) error {
 	pgUser := &UserPG{
-		ID:   user.ID(),
-		Name: user.Name(),
+		ID:            user.ID(),
+		TenantID:      app.TenantFromContext(ctx),
+		Name:          user.Name(),
+		PasswordHash:  user.PasswordHash(),
+		Email:         nullable(user.Email()),
+		EmailVerified: user.EmailVerified(),
+		Attributes:    user.Attributes(),
 	}
 
-	return ur.db.WithContext(ctx).Create(pgUser).Error
+	return ur.translate(ur.scoped(ctx, func(db *gorm.DB) error {
+		return db.Create(pgUser).Error
+	}))
 }
 
 func (ur *UserRepo) GetAll(ctx context.Context) ([]*domain.User, error) {
 	var pgUsers []UserPG
-	if err := ur.db.WithContext(ctx).Find(&pgUsers).Error; err != nil {
+	err := ur.scoped(ctx, func(db *gorm.DB) error {
+		return db.Find(&pgUsers).Error
+	})
+	if err != nil {
 		return nil, err
 	}
 
-	users := make([]*domain.User, 0, len(pgUsers))
-	for _, u := range pgUsers {
-		users = append(users, domain.NewUser(u.ID, u.Name))
+	return toDomainUsers(pgUsers), nil
+}
+
+func (ur *UserRepo) GetByIDs(ctx context.Context, ids []string) ([]*domain.User, error) {
+	var pgUsers []UserPG
+	err := ur.scoped(ctx, func(db *gorm.DB) error {
+		return db.Where("id IN ?", ids).Find(&pgUsers).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return toDomainUsers(pgUsers), nil
+}
+
+func (ur *UserRepo) List(ctx context.Context, params app.ListParams) ([]*domain.User, error) {
+	var pgUsers []UserPG
+	err := ur.scoped(ctx, func(db *gorm.DB) error {
+		query := db.Model(&UserPG{})
+
+		if len(params.Filter.IDs) > 0 {
+			query = query.Where("id IN ?", params.Filter.IDs)
+		}
+		if params.Filter.NameContains != "" {
+			query = query.Where("name ILIKE ?", "%"+escapeLike(params.Filter.NameContains)+"%")
+		}
+		if len(params.Filter.Attributes) > 0 {
+			query = query.Where("attributes @> ?::jsonb", jsonAttributes(params.Filter.Attributes))
+		}
+
+		direction, after := "ASC", ">"
+		if params.Descending {
+			direction, after = "DESC", "<"
+		}
+
+		if params.SortAttribute == "" {
+			if params.After != "" {
+				query = query.Where("id "+after+" ?", params.After)
+			}
+			return query.Order("id " + direction).Limit(params.Limit).Find(&pgUsers).Error
+		}
+
+		// Missing attributes sort as jsonb null, before any value. The
+		// position of the previous page is that of its last user.
+		if params.After != "" {
+			query = query.Where(
+				"("+attributeKey+", id) "+after+" (SELECT "+attributeKey+", id FROM user_pgs WHERE id = ? AND tenant_id = ?)",
+				params.SortAttribute, params.SortAttribute, params.After, app.TenantFromContext(ctx),
+			)
+		}
+		return query.
+			Order(clause.OrderBy{Expression: clause.Expr{
+				SQL:  attributeKey + " " + direction + ", id " + direction,
+				Vars: []interface{}{params.SortAttribute},
+			}}).
+			Limit(params.Limit).
+			Find(&pgUsers).Error
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return users, nil
+	return toDomainUsers(pgUsers), nil
 }
 
 func (ur *UserRepo) GetByID(ctx context.Context, id string) (*domain.User, error) {
+	return ur.first(ctx, "id = ?", id)
+}
+
+func (ur *UserRepo) Update(ctx context.Context, user *domain.User) error {
+	values := map[string]interface{}{"attributes": jsonAttributes(user.Attributes())}
+	if user.Name() != "" {
+		values["name"] = user.Name()
+	}
+	return ur.update(ctx, user.ID(), values)
+}
+
+func (ur *UserRepo) SetPasswordHash(ctx context.Context, id, hash string) error {
+	return ur.update(ctx, id, map[string]interface{}{"password_hash": hash})
+}
+
+func (ur *UserRepo) GetByEmail(ctx context.Context, email string) (*domain.User, error) {
+	return ur.first(ctx, "email = ?", email)
+}
+
+func (ur *UserRepo) SetEmail(ctx context.Context, id, email string) error {
+	return ur.translate(ur.update(ctx, id, map[string]interface{}{"email": nullable(email), "email_verified": false}))
+}
+
+func (ur *UserRepo) VerifyEmail(ctx context.Context, id, email string) (bool, error) {
+	var verified bool
+	err := ur.scoped(ctx, func(db *gorm.DB) error {
+		result := db.Model(&UserPG{}).
+			Where("id = ? AND email = ?", id, email).
+			Update("email_verified", true)
+		verified = result.RowsAffected > 0
+		return result.Error
+	})
+
+	return verified, err
+}
+
+func (ur *UserRepo) Remove(ctx context.Context, id string) error {
+	var removed bool
+	err := ur.scoped(ctx, func(db *gorm.DB) error {
+		result := db.Where("id = ?", id).Delete(&UserPG{})
+		removed = result.RowsAffected > 0
+		return result.Error
+	})
+	if err != nil {
+		return err
+	}
+
+	if !removed {
+		return perrors.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// first fetches the user matching a condition. Within a transaction, the
+// user is locked until it ends, so that the user changed in it is the
+// one read.
+func (ur *UserRepo) first(ctx context.Context, query string, args ...interface{}) (*domain.User, error) {
 	var pgUser UserPG
-	err := ur.db.WithContext(ctx).Where("id = ?", id).First(&pgUser).Error
+	err := ur.scoped(ctx, func(db *gorm.DB) error {
+		if inTransaction(ctx) {
+			db = db.Clauses(clause.Locking{Strength: "UPDATE"})
+		}
+		return db.Where(query, args...).First(&pgUser).Error
+	})
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, perrors.ErrUserNotFound
@@ -63,37 +323,71 @@ func (ur *UserRepo) GetByID(ctx context.Context, id string) (*domain.User, error
 		return nil, err
 	}
 
-	return domain.NewUser(pgUser.ID, pgUser.Name), nil
+	return toDomainUser(pgUser), nil
 }
 
-func (ur *UserRepo) Update(ctx context.Context, user *domain.User) error {
-	result := ur.db.WithContext(ctx).
-		Model(&UserPG{}).
-		Where("id = ?", user.ID()).
-		Updates(UserPG{
-			Name: user.Name(),
-		})
-
-	if result.Error != nil {
+// update applies values to a user, which must exist.
+func (ur *UserRepo) update(ctx context.Context, id string, values interface{}) error {
+	var updated bool
+	err := ur.scoped(ctx, func(db *gorm.DB) error {
+		result := db.Model(&UserPG{}).Where("id = ?", id).Updates(values)
+		updated = result.RowsAffected > 0
 		return result.Error
+	})
+	if err != nil {
+		return err
 	}
 
-	if result.RowsAffected == 0 {
+	if !updated {
 		return perrors.ErrUserNotFound
 	}
 
 	return nil
 }
 
-func (ur *UserRepo) Remove(ctx context.Context, id string) error {
-	result := ur.db.WithContext(ctx).Where("id = ?", id).Delete(&UserPG{})
-	if result.Error != nil {
-		return result.Error
+func toDomainUsers(pgUsers []UserPG) []*domain.User {
+	users := make([]*domain.User, 0, len(pgUsers))
+	for _, u := range pgUsers {
+		users = append(users, toDomainUser(u))
+	}
+	return users
+}
+
+func toDomainUser(u UserPG) *domain.User {
+	user := domain.NewUser(u.ID, u.Name).WithPasswordHash(u.PasswordHash).WithAttributes(u.Attributes)
+	if u.Email != nil {
+		user = user.WithEmail(*u.Email, u.EmailVerified)
 	}
+	return user
+}
 
-	if result.RowsAffected == 0 {
-		return perrors.ErrUserNotFound
+func nullable(s string) *string {
+	if s == "" {
+		return nil
 	}
+	return &s
+}
 
-	return nil
+// translate turns unique violations into ErrEmailTaken; with UUIDs for
+// IDs, only the email index is ever violated.
+func (ur *UserRepo) translate(err error) error {
+	if isDuplicate(ur.db, err) {
+		return perrors.ErrEmailTaken
+	}
+	return err
+}
+
+// isDuplicate reports whether err is a unique violation.
+func isDuplicate(db *gorm.DB, err error) bool {
+	if translator, ok := db.Dialector.(gorm.ErrorTranslator); ok && err != nil {
+		err = translator.Translate(err)
+	}
+	return errors.Is(err, gorm.ErrDuplicatedKey)
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// escapeLike escapes LIKE wildcards so user input is matched literally.
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
 }
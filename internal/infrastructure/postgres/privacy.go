@@ -0,0 +1,87 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	perrors "github.com/Sergey-Polishchenko/simple-api/internal/pkg/errors"
+)
+
+// ErasureReceiptPG records the erasure of a user. Unlike the tables it
+// vouches for, it has no foreign keys: the user is gone.
+type ErasureReceiptPG struct {
+	ID                   string    `gorm:"primaryKey"`
+	TenantID             string    `gorm:"not null;index"`
+	UserID               string    `gorm:"not null;index"`
+	ActorKind            string    `gorm:"not null"`
+	ActorID              string    `gorm:"not null;default:''"`
+	ErasedAt             time.Time `gorm:"not null"`
+	Versions             int       `gorm:"not null"`
+	Sessions             int       `gorm:"not null"`
+	GroupMemberships     int       `gorm:"not null"`
+	MFA                  bool      `gorm:"not null"`
+	AuditEntriesRetained int       `gorm:"not null"`
+}
+
+func (ErasureReceiptPG) TableName() string {
+	return "erasure_receipts"
+}
+
+type ErasureReceiptRepo struct {
+	db *gorm.DB
+}
+
+func NewErasureReceiptRepo(db *gorm.DB) (app.ErasureReceiptRepository, error) {
+	repo := &ErasureReceiptRepo{db: db}
+	err := repo.db.AutoMigrate(&ErasureReceiptPG{})
+	return repo, err
+}
+
+func (rr *ErasureReceiptRepo) Create(ctx context.Context, receipt *app.ErasureReceipt) error {
+	pgReceipt := &ErasureReceiptPG{
+		ID:                   receipt.ID,
+		TenantID:             receipt.TenantID,
+		UserID:               receipt.UserID,
+		ActorKind:            receipt.ActorKind,
+		ActorID:              receipt.ActorID,
+		ErasedAt:             receipt.ErasedAt,
+		Versions:             receipt.Versions,
+		Sessions:             receipt.Sessions,
+		GroupMemberships:     receipt.GroupMemberships,
+		MFA:                  receipt.MFA,
+		AuditEntriesRetained: receipt.AuditEntriesRetained,
+	}
+
+	return rr.db.WithContext(ctx).Create(pgReceipt).Error
+}
+
+func (rr *ErasureReceiptRepo) GetByID(ctx context.Context, id string) (*app.ErasureReceipt, error) {
+	var pgReceipt ErasureReceiptPG
+	err := rr.db.WithContext(ctx).
+		Where("id = ? AND tenant_id = ?", id, app.TenantFromContext(ctx)).
+		First(&pgReceipt).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, perrors.ErrErasureReceiptNotFound
+		}
+		return nil, err
+	}
+
+	return &app.ErasureReceipt{
+		ID:                   pgReceipt.ID,
+		TenantID:             pgReceipt.TenantID,
+		UserID:               pgReceipt.UserID,
+		ActorKind:            pgReceipt.ActorKind,
+		ActorID:              pgReceipt.ActorID,
+		ErasedAt:             pgReceipt.ErasedAt,
+		Versions:             pgReceipt.Versions,
+		Sessions:             pgReceipt.Sessions,
+		GroupMemberships:     pgReceipt.GroupMemberships,
+		MFA:                  pgReceipt.MFA,
+		AuditEntriesRetained: pgReceipt.AuditEntriesRetained,
+	}, nil
+}
@@ -0,0 +1,126 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	perrors "github.com/Sergey-Polishchenko/simple-api/internal/pkg/errors"
+)
+
+type RefreshTokenPG struct {
+	ID        string `gorm:"primaryKey"`
+	FamilyID  string `gorm:"index;not null"`
+	UserID    string `gorm:"index;not null"`
+	Hash      []byte `gorm:"uniqueIndex;not null"`
+	CreatedAt time.Time
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	RevokedAt *time.Time
+}
+
+func (RefreshTokenPG) TableName() string {
+	return "refresh_tokens"
+}
+
+type RefreshTokenRepo struct {
+	db *gorm.DB
+}
+
+func NewRefreshTokenRepo(db *gorm.DB) (app.RefreshTokenRepository, error) {
+	repo := &RefreshTokenRepo{db: db}
+	err := repo.db.AutoMigrate(&RefreshTokenPG{})
+	return repo, err
+}
+
+func (tr *RefreshTokenRepo) Create(ctx context.Context, token *app.RefreshToken) error {
+	pgToken := &RefreshTokenPG{
+		ID:        token.ID,
+		FamilyID:  token.FamilyID,
+		UserID:    token.UserID,
+		Hash:      token.Hash,
+		CreatedAt: token.CreatedAt,
+		ExpiresAt: token.ExpiresAt,
+	}
+
+	return conn(ctx, tr.db).Create(pgToken).Error
+}
+
+func (tr *RefreshTokenRepo) GetByHash(ctx context.Context, hash []byte) (*app.RefreshToken, error) {
+	var pgToken RefreshTokenPG
+	err := conn(ctx, tr.db).Where("hash = ?", hash).First(&pgToken).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, perrors.ErrRefreshTokenNotFound
+		}
+		return nil, err
+	}
+
+	return &app.RefreshToken{
+		ID:        pgToken.ID,
+		FamilyID:  pgToken.FamilyID,
+		UserID:    pgToken.UserID,
+		Hash:      pgToken.Hash,
+		CreatedAt: pgToken.CreatedAt,
+		ExpiresAt: pgToken.ExpiresAt,
+		UsedAt:    pgToken.UsedAt,
+		RevokedAt: pgToken.RevokedAt,
+	}, nil
+}
+
+// MarkUsed sets used_at only if it is still null, so that of two concurrent
+// refreshes with the same token exactly one wins.
+func (tr *RefreshTokenRepo) MarkUsed(ctx context.Context, id string, at time.Time) (bool, error) {
+	result := conn(ctx, tr.db).Model(&RefreshTokenPG{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", at)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (tr *RefreshTokenRepo) RevokeFamily(ctx context.Context, familyID string, at time.Time) error {
+	return conn(ctx, tr.db).Model(&RefreshTokenPG{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", at).Error
+}
+
+func (tr *RefreshTokenRepo) RevokeByUser(ctx context.Context, userID, exceptFamilyID string, at time.Time) error {
+	query := conn(ctx, tr.db).Model(&RefreshTokenPG{}).Where("user_id = ? AND revoked_at IS NULL", userID)
+	if exceptFamilyID != "" {
+		query = query.Where("family_id <> ?", exceptFamilyID)
+	}
+	return query.Update("revoked_at", at).Error
+}
+
+func (tr *RefreshTokenRepo) ListByUser(ctx context.Context, userID string) ([]*app.RefreshToken, error) {
+	var pgTokens []RefreshTokenPG
+	err := conn(ctx, tr.db).Where("user_id = ?", userID).Order("created_at").Find(&pgTokens).Error
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := make([]*app.RefreshToken, len(pgTokens))
+	for i, pgToken := range pgTokens {
+		tokens[i] = &app.RefreshToken{
+			ID:        pgToken.ID,
+			FamilyID:  pgToken.FamilyID,
+			UserID:    pgToken.UserID,
+			Hash:      pgToken.Hash,
+			CreatedAt: pgToken.CreatedAt,
+			ExpiresAt: pgToken.ExpiresAt,
+			UsedAt:    pgToken.UsedAt,
+			RevokedAt: pgToken.RevokedAt,
+		}
+	}
+	return tokens, nil
+}
+
+func (tr *RefreshTokenRepo) DeleteByUser(ctx context.Context, userID string) (int, error) {
+	result := conn(ctx, tr.db).Where("user_id = ?", userID).Delete(&RefreshTokenPG{})
+	return int(result.RowsAffected), result.Error
+}
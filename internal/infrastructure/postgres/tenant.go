@@ -0,0 +1,100 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	perrors "github.com/Sergey-Polishchenko/simple-api/internal/pkg/errors"
+)
+
+type TenantPG struct {
+	ID        string `gorm:"primaryKey"`
+	Name      string `gorm:"not null"`
+	CreatedAt time.Time
+}
+
+func (TenantPG) TableName() string {
+	return "tenants"
+}
+
+type TenantRepo struct {
+	db *gorm.DB
+}
+
+// NewTenantRepo migrates the tenants table and creates the default tenant,
+// which the users that predate multi-tenancy belong to.
+func NewTenantRepo(db *gorm.DB) (app.TenantRepository, error) {
+	repo := &TenantRepo{db: db}
+	if err := repo.db.AutoMigrate(&TenantPG{}); err != nil {
+		return nil, err
+	}
+
+	err := repo.db.
+		Where(TenantPG{ID: app.DefaultTenantID}).
+		Attrs(TenantPG{Name: "Default", CreatedAt: time.Now().UTC()}).
+		FirstOrCreate(&TenantPG{}).Error
+	return repo, err
+}
+
+func (tr *TenantRepo) Create(ctx context.Context, tenant *app.Tenant) error {
+	pgTenant := &TenantPG{
+		ID:        tenant.ID,
+		Name:      tenant.Name,
+		CreatedAt: tenant.CreatedAt,
+	}
+
+	err := tr.db.WithContext(ctx).Create(pgTenant).Error
+	if isDuplicate(tr.db, err) {
+		return perrors.ErrTenantExists
+	}
+	return err
+}
+
+func (tr *TenantRepo) GetByID(ctx context.Context, id string) (*app.Tenant, error) {
+	var pgTenant TenantPG
+	err := tr.db.WithContext(ctx).Where("id = ?", id).First(&pgTenant).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, perrors.ErrTenantNotFound
+		}
+		return nil, err
+	}
+
+	return toTenant(pgTenant), nil
+}
+
+func (tr *TenantRepo) GetAll(ctx context.Context) ([]*app.Tenant, error) {
+	var pgTenants []TenantPG
+	if err := tr.db.WithContext(ctx).Order("id").Find(&pgTenants).Error; err != nil {
+		return nil, err
+	}
+
+	tenants := make([]*app.Tenant, 0, len(pgTenants))
+	for _, t := range pgTenants {
+		tenants = append(tenants, toTenant(t))
+	}
+	return tenants, nil
+}
+
+func (tr *TenantRepo) Remove(ctx context.Context, id string) error {
+	result := tr.db.WithContext(ctx).Where("id = ?", id).Delete(&TenantPG{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return perrors.ErrTenantNotFound
+	}
+	return nil
+}
+
+func toTenant(t TenantPG) *app.Tenant {
+	return &app.Tenant{
+		ID:        t.ID,
+		Name:      t.Name,
+		CreatedAt: t.CreatedAt,
+	}
+}
@@ -0,0 +1,110 @@
+package postgres
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/tracing"
+)
+
+// querySpanKey keeps the span of a statement in its gorm instance.
+const querySpanKey = "tracing:span"
+
+// maxStatementLength bounds the SQL recorded in spans.
+const maxStatementLength = 2048
+
+// QueryTracing is a gorm plugin recording a client span for every query
+// run with a context carrying a span, as a child of that span.
+type QueryTracing struct {
+	tracer *tracing.Tracer
+}
+
+// NewQueryTracing initializes a QueryTracing instance; install it with
+// gorm.DB.Use.
+func NewQueryTracing(tracer *tracing.Tracer) *QueryTracing {
+	return &QueryTracing{tracer: tracer}
+}
+
+func (t *QueryTracing) Name() string {
+	return "tracing"
+}
+
+func (t *QueryTracing) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	return errors.Join(
+		callbacks.Create().Before("gorm:create").Register("tracing:before_create", t.before("create")),
+		callbacks.Create().After("gorm:create").Register("tracing:after_create", t.after),
+		callbacks.Query().Before("gorm:query").Register("tracing:before_query", t.before("query")),
+		callbacks.Query().After("gorm:query").Register("tracing:after_query", t.after),
+		callbacks.Update().Before("gorm:update").Register("tracing:before_update", t.before("update")),
+		callbacks.Update().After("gorm:update").Register("tracing:after_update", t.after),
+		callbacks.Delete().Before("gorm:delete").Register("tracing:before_delete", t.before("delete")),
+		callbacks.Delete().After("gorm:delete").Register("tracing:after_delete", t.after),
+		callbacks.Row().Before("gorm:row").Register("tracing:before_row", t.before("row")),
+		callbacks.Row().After("gorm:row").Register("tracing:after_row", t.after),
+		callbacks.Raw().Before("gorm:raw").Register("tracing:before_raw", t.before("raw")),
+		callbacks.Raw().After("gorm:raw").Register("tracing:after_raw", t.after),
+	)
+}
+
+func (t *QueryTracing) before(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		// Queries outside of traces, like migrations, would start a trace
+		// of their own each.
+		if tracing.SpanFromContext(db.Statement.Context) == nil {
+			return
+		}
+		_, span := t.tracer.Start(db.Statement.Context, "db."+operation, tracing.KindClient,
+			tracing.String("db.system", "postgresql"),
+			tracing.String("db.operation", operation),
+		)
+		db.InstanceSet(querySpanKey, span)
+	}
+}
+
+func (t *QueryTracing) after(db *gorm.DB) {
+	value, ok := db.InstanceGet(querySpanKey)
+	if !ok {
+		return
+	}
+	span := value.(*tracing.Span)
+	defer span.End()
+
+	span.SetAttributes(
+		tracing.String("db.sql.table", db.Statement.Table),
+		tracing.String("db.statement", sanitizeSQL(db.Statement.SQL.String())),
+		tracing.Int("db.rows_affected", db.RowsAffected),
+	)
+	if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+		span.RecordError(db.Error)
+	}
+}
+
+var (
+	// sqlLiterals match the string and number literals of a statement,
+	// and its parameters to keep them; values are bound as parameters,
+	// but raw statements may inline some.
+	sqlLiterals = regexp.MustCompile(`\$\d+|'(?:[^']|'')*'|\b\d+(?:\.\d+)?\b`)
+	sqlSpaces   = regexp.MustCompile(`\s+`)
+)
+
+// sanitizeSQL replaces the literals of a statement with placeholders, so
+// that spans don't carry personal data, and squeezes its whitespace.
+func sanitizeSQL(statement string) string {
+	statement = sqlLiterals.ReplaceAllStringFunc(statement, func(literal string) string {
+		if strings.HasPrefix(literal, "$") {
+			return literal
+		}
+		return "?"
+	})
+	statement = strings.TrimSpace(sqlSpaces.ReplaceAllString(statement, " "))
+	if len(statement) > maxStatementLength {
+		statement = statement[:maxStatementLength] + "..."
+	}
+	return statement
+}
+
+var _ gorm.Plugin = (*QueryTracing)(nil)
@@ -0,0 +1,50 @@
+package postgres_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	pgdriver "gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"github.com/Sergey-Polishchenko/simple-api/internal/infrastructure/postgres"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/tracing"
+)
+
+// newDryRunDB builds statements without running them, so that no
+// database is needed.
+func newDryRunDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(pgdriver.New(pgdriver.Config{DSN: "host=localhost"}), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	require.NoError(t, err)
+	return db
+}
+
+func TestQueryTracing(t *testing.T) {
+	var buf bytes.Buffer
+	tracer := tracing.NewTracer(tracing.Config{ServiceName: "simple-api", Exporter: tracing.NewWriterExporter(&buf)})
+	db := newDryRunDB(t)
+	require.NoError(t, db.Use(postgres.NewQueryTracing(tracer)))
+
+	var users []postgres.UserPG
+	db.WithContext(context.Background()).Where("name = ?", "untraced").Find(&users)
+
+	ctx, span := tracer.Start(context.Background(), "GET /users", tracing.KindServer)
+	db.WithContext(ctx).Where("email = ?", "john@example.com").Where("name = 'John' AND 1 = 1").Find(&users)
+	span.End()
+	require.NoError(t, tracer.Shutdown(context.Background()))
+
+	output := buf.String()
+	assert.Contains(t, output, `"name":"db.query"`)
+	assert.Contains(t, output, `"kind":3`)
+	assert.Contains(t, output, `"parentSpanId":"`+span.SpanContext().SpanID.String()+`"`)
+	assert.Contains(t, output, `{"key":"db.sql.table","value":{"stringValue":"user_pgs"}}`)
+	assert.Contains(t, output, `{"key":"db.statement","value":{"stringValue":"SELECT * FROM \"user_pgs\" WHERE email = $1 AND (name = ? AND ? = ?)"}}`)
+	assert.NotContains(t, output, "John")
+	assert.NotContains(t, output, "john@example.com")
+	assert.Equal(t, 2, strings.Count(output, `"spanId"`), "queries outside of traces are not traced")
+}
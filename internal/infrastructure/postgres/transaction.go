@@ -0,0 +1,46 @@
+package postgres
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+)
+
+// Transactor runs transactions carried by the context, which the
+// repositories of this package run their queries in.
+type Transactor struct {
+	db *gorm.DB
+}
+
+func NewTransactor(db *gorm.DB) app.Transactor {
+	return &Transactor{db: db}
+}
+
+type transactionKey struct{}
+
+func (t *Transactor) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if inTransaction(ctx) {
+		return fn(ctx)
+	}
+
+	return t.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(context.WithValue(ctx, transactionKey{}, tx))
+	})
+}
+
+// conn returns the session to query db with: the transaction of ctx, if
+// there is one.
+func conn(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := ctx.Value(transactionKey{}).(*gorm.DB); ok {
+		return tx.WithContext(ctx)
+	}
+	return db.WithContext(ctx)
+}
+
+// inTransaction reports whether ctx carries a transaction.
+func inTransaction(ctx context.Context) bool {
+	_, ok := ctx.Value(transactionKey{}).(*gorm.DB)
+	return ok
+}
@@ -0,0 +1,159 @@
+// Package graphql exposes the user API over GraphQL.
+package graphql
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	graphqlgo "github.com/graph-gophers/graphql-go"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+)
+
+//go:embed schema.graphql
+var schemaSource string
+
+var errMutationOverGet = errors.New("mutations are not allowed over GET")
+
+// Config limits the cost of GraphQL operations.
+type Config struct {
+	MaxDepth      int
+	MaxComplexity int
+}
+
+// request is a GraphQL-over-HTTP request body.
+type request struct {
+	Query         string                 `json:"query" form:"query"`
+	OperationName string                 `json:"operationName" form:"operationName"`
+	Variables     map[string]interface{} `json:"variables" form:"-"`
+}
+
+// Handler serves GraphQL queries, mutations and subscriptions.
+type Handler struct {
+	schema        *graphqlgo.Schema
+	service       app.UserService
+	maxComplexity int
+}
+
+// NewHandler builds the GraphQL schema on top of the user service.
+// Subscriptions are disabled when events is nil.
+func NewHandler(service app.UserService, events app.UserEventSubscriber, cfg Config) *Handler {
+	opts := []graphqlgo.SchemaOpt{graphqlgo.UseStringDescriptions()}
+	if cfg.MaxDepth > 0 {
+		opts = append(opts, graphqlgo.MaxDepth(cfg.MaxDepth))
+	}
+
+	return &Handler{
+		schema:        graphqlgo.MustParseSchema(schemaSource, &resolver{service: service, events: events}, opts...),
+		service:       service,
+		maxComplexity: cfg.MaxComplexity,
+	}
+}
+
+// RegisterRoutes mounts the GraphQL endpoint.
+func RegisterRoutes(router *gin.Engine, h *Handler) {
+	router.POST("/graphql", h.Serve)
+	router.GET("/graphql", h.Serve)
+}
+
+// Serve executes a single GraphQL operation. Subscriptions are streamed
+// as server-sent events and require "Accept: text/event-stream".
+func (h *Handler) Serve(c *gin.Context) {
+	req, err := bindRequest(c)
+	if err != nil {
+		writeError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	op, err := parseOperation(req.Query, req.OperationName)
+	if err != nil {
+		writeError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	if c.Request.Method == http.MethodGet && op.isMutation() {
+		writeError(c, http.StatusMethodNotAllowed, errMutationOverGet)
+		return
+	}
+
+	if h.maxComplexity > 0 {
+		if cost := op.complexity(req.Variables); cost > h.maxComplexity {
+			writeError(c, http.StatusBadRequest, complexityError(cost, h.maxComplexity))
+			return
+		}
+	}
+
+	if op.isSubscription() {
+		h.subscribe(c, req)
+		return
+	}
+
+	ctx := withLoader(c.Request.Context(), h.service)
+	c.JSON(http.StatusOK, h.schema.Exec(ctx, req.Query, req.OperationName, req.Variables))
+}
+
+func (h *Handler) subscribe(c *gin.Context, req *request) {
+	if !strings.Contains(c.GetHeader("Accept"), "text/event-stream") {
+		writeError(c, http.StatusNotAcceptable, errors.New("subscriptions require Accept: text/event-stream"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	responses, err := h.schema.Subscribe(ctx, req.Query, req.OperationName, req.Variables)
+	if err != nil {
+		writeError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	c.Stream(func(io.Writer) bool {
+		select {
+		case resp, ok := <-responses:
+			if !ok {
+				return false
+			}
+			c.SSEvent("next", resp)
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	})
+	c.SSEvent("complete", "")
+}
+
+func bindRequest(c *gin.Context) (*request, error) {
+	req := &request{}
+
+	if c.Request.Method == http.MethodGet {
+		if err := c.ShouldBindQuery(req); err != nil {
+			return nil, err
+		}
+		if vars := c.Query("variables"); vars != "" {
+			if err := json.Unmarshal([]byte(vars), &req.Variables); err != nil {
+				return nil, errors.New("invalid variables")
+			}
+		}
+	} else if err := c.ShouldBindJSON(req); err != nil {
+		return nil, errors.New("invalid request")
+	}
+
+	if req.Query == "" {
+		return nil, errors.New("query is required")
+	}
+
+	return req, nil
+}
+
+// writeError writes a GraphQL-shaped error response.
+func writeError(c *gin.Context, status int, err error) {
+	c.JSON(status, gin.H{"errors": []gin.H{{"message": err.Error()}}})
+}
@@ -0,0 +1,276 @@
+package graphql_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	"github.com/Sergey-Polishchenko/simple-api/internal/domain"
+	"github.com/Sergey-Polishchenko/simple-api/internal/interfaces/graphql"
+	"github.com/Sergey-Polishchenko/simple-api/internal/interfaces/http/handlers/mocks"
+)
+
+func newRouter(service app.UserService, events app.UserEventSubscriber) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	graphql.RegisterRoutes(router, graphql.NewHandler(service, events, graphql.Config{
+		MaxDepth:      3,
+		MaxComplexity: 50,
+	}))
+	return router
+}
+
+func post(router *gin.Engine, query string, variables map[string]interface{}) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(map[string]interface{}{"query": query, "variables": variables})
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/graphql", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestHandler_User(t *testing.T) {
+	mockService := new(mocks.MockUserService)
+	mockService.On("GetByIDs", mock.Anything, []string{"123"}).
+		Return([]*domain.User{domain.NewUser("123", "John")}, nil)
+
+	w := post(newRouter(mockService, nil), `{ user(id: "123") { id name } }`, nil)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"data":{"user":{"id":"123","name":"John"}}}`, w.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_UserNotFound(t *testing.T) {
+	mockService := new(mocks.MockUserService)
+	mockService.On("GetByIDs", mock.Anything, []string{"404"}).
+		Return([]*domain.User{}, nil)
+
+	w := post(newRouter(mockService, nil), `{ user(id: "404") { id } }`, nil)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"data":{"user":null}}`, w.Body.String())
+}
+
+func TestHandler_BatchesUserLookups(t *testing.T) {
+	mockService := new(mocks.MockUserService)
+	mockService.On("GetByIDs", mock.Anything, mock.MatchedBy(func(ids []string) bool {
+		sorted := append([]string(nil), ids...)
+		sort.Strings(sorted)
+		return strings.Join(sorted, ",") == "1,2"
+	})).Return([]*domain.User{
+		domain.NewUser("1", "John"),
+		domain.NewUser("2", "Jane"),
+	}, nil).Once()
+
+	w := post(newRouter(mockService, nil), `{
+		a: user(id: "1") { name }
+		b: user(id: "2") { name }
+		c: user(id: "1") { id }
+	}`, nil)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t,
+		`{"data":{"a":{"name":"John"},"b":{"name":"Jane"},"c":{"id":"1"}}}`,
+		w.Body.String(),
+	)
+	mockService.AssertNumberOfCalls(t, "GetByIDs", 1)
+}
+
+func TestHandler_Users(t *testing.T) {
+	mockService := new(mocks.MockUserService)
+	mockService.On("List", mock.Anything, app.ListParams{
+		Filter: app.UserFilter{NameContains: "jo"},
+		After:  "1",
+		Limit:  2,
+	}).Return(&app.UserPage{
+		Users:       []*domain.User{domain.NewUser("2", "John"), domain.NewUser("3", "Joe")},
+		HasNextPage: true,
+	}, nil)
+
+	w := post(newRouter(mockService, nil),
+		`query($after: String) {
+			users(first: 2, after: $after, filter: {nameContains: "jo"}) {
+				nodes { id }
+				pageInfo { hasNextPage endCursor }
+			}
+		}`,
+		map[string]interface{}{"after": "MQ"},
+	)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"data":{"users":{
+		"nodes":[{"id":"2"},{"id":"3"}],
+		"pageInfo":{"hasNextPage":true,"endCursor":"Mw"}
+	}}}`, w.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_Mutations(t *testing.T) {
+	tests := []struct {
+		name         string
+		query        string
+		mockSetup    func(*mocks.MockUserService)
+		expectedBody string
+	}{
+		{
+			name:  "create",
+			query: `mutation { createUser(input: {name: " John "}) { id name } }`,
+			mockSetup: func(m *mocks.MockUserService) {
+				m.On("Create", mock.Anything, domain.NewUser("", "John")).
+					Return(domain.NewUser("123", "John"), nil)
+			},
+			expectedBody: `{"data":{"createUser":{"id":"123","name":"John"}}}`,
+		},
+		{
+			name:  "update",
+			query: `mutation { updateUser(input: {id: "123", name: "Jane"}) { name } }`,
+			mockSetup: func(m *mocks.MockUserService) {
+				m.On("Update", mock.Anything, domain.NewUser("123", "Jane")).Return(nil)
+			},
+			expectedBody: `{"data":{"updateUser":{"name":"Jane"}}}`,
+		},
+		{
+			name:  "delete",
+			query: `mutation { deleteUser(id: "123") }`,
+			mockSetup: func(m *mocks.MockUserService) {
+				m.On("Remove", mock.Anything, "123").Return(nil)
+			},
+			expectedBody: `{"data":{"deleteUser":"123"}}`,
+		},
+		{
+			name:         "empty name",
+			query:        `mutation { createUser(input: {name: ""}) { id } }`,
+			mockSetup:    func(*mocks.MockUserService) {},
+			expectedBody: `{"data":null,"errors":[{"message":"name is required","path":["createUser"]}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(mocks.MockUserService)
+			tt.mockSetup(mockService)
+
+			w := post(newRouter(mockService, nil), tt.query, nil)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.JSONEq(t, tt.expectedBody, w.Body.String())
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandler_Limits(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		message string
+	}{
+		{
+			name:    "depth",
+			query:   `{ users { edges { node { id } } pageInfo { hasNextPage } } }`,
+			message: "exceeds max depth 3",
+		},
+		{
+			name:    "complexity",
+			query:   `{ users(first: 100) { nodes { id name } } }`,
+			message: "operation complexity 301 exceeds the limit of 50",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(mocks.MockUserService)
+
+			w := post(newRouter(mockService, nil), tt.query, nil)
+
+			assert.Contains(t, w.Body.String(), tt.message)
+			mockService.AssertNotCalled(t, "List", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestHandler_RejectsMutationOverGet(t *testing.T) {
+	mockService := new(mocks.MockUserService)
+	router := newRouter(mockService, nil)
+
+	w := httptest.NewRecorder()
+	query := url.QueryEscape(`mutation { deleteUser(id: "1") }`)
+	req, _ := http.NewRequest(http.MethodGet, "/graphql?query="+query, nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	mockService.AssertNotCalled(t, "Remove", mock.Anything, mock.Anything)
+}
+
+func TestHandler_UserChangedSubscription(t *testing.T) {
+	broker := app.NewUserEventBroker()
+	server := httptest.NewServer(newRouter(new(mocks.MockUserService), broker))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	body := `{"query":"subscription { userChanged(id: \"1\") { type id user { name } } }"}`
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, server.URL+"/graphql", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// The subscription is registered asynchronously; keep publishing until it arrives.
+	go func() {
+		ticker := time.NewTicker(10 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				broker.Publish(ctx, app.UserEvent{Type: app.UserUpdated, UserID: "2", TenantID: app.DefaultTenantID})
+				broker.Publish(ctx, app.UserEvent{
+					Type:     app.UserUpdated,
+					UserID:   "1",
+					TenantID: "acme",
+					User:     domain.NewUser("1", "Jane"),
+				})
+				broker.Publish(ctx, app.UserEvent{
+					Type:     app.UserUpdated,
+					UserID:   "1",
+					TenantID: app.DefaultTenantID,
+					User:     domain.NewUser("1", "John"),
+				})
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if data, ok := strings.CutPrefix(line, "data:"); ok {
+			assert.JSONEq(t,
+				`{"data":{"userChanged":{"type":"UPDATED","id":"1","user":{"name":"John"}}}}`,
+				data,
+			)
+			return
+		}
+	}
+	t.Fatal("no event received")
+}
@@ -0,0 +1,139 @@
+package graphql
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	"github.com/Sergey-Polishchenko/simple-api/internal/domain"
+)
+
+const (
+	// loaderWait is how long the loader collects keys before fetching them.
+	loaderWait = 2 * time.Millisecond
+	// loaderMaxBatch dispatches a batch early once it holds this many keys.
+	loaderMaxBatch = app.MaxPageSize
+)
+
+type loaderKey struct{}
+
+// userLoader batches and caches GetByID lookups within a single request.
+type userLoader struct {
+	service app.UserService
+
+	mu    sync.Mutex
+	batch *userBatch
+	cache map[string]*userBatch
+}
+
+// userBatch is a set of IDs fetched with one GetByIDs call.
+type userBatch struct {
+	ids   []string
+	once  sync.Once
+	done  chan struct{}
+	users map[string]*domain.User
+	err   error
+}
+
+func newUserLoader(service app.UserService) *userLoader {
+	return &userLoader{
+		service: service,
+		cache:   make(map[string]*userBatch),
+	}
+}
+
+// withLoader attaches a fresh userLoader to the request context.
+func withLoader(ctx context.Context, service app.UserService) context.Context {
+	return context.WithValue(ctx, loaderKey{}, newUserLoader(service))
+}
+
+// loaderFrom returns the request's loader, or an unshared one if none is attached.
+func loaderFrom(ctx context.Context, service app.UserService) *userLoader {
+	if l, ok := ctx.Value(loaderKey{}).(*userLoader); ok {
+		return l
+	}
+	return newUserLoader(service)
+}
+
+// Load returns the user with the given ID, or nil if it does not exist.
+func (l *userLoader) Load(ctx context.Context, id string) (*domain.User, error) {
+	l.mu.Lock()
+	b, ok := l.cache[id]
+	if !ok {
+		b = l.enqueue(ctx, id)
+	}
+	l.mu.Unlock()
+
+	select {
+	case <-b.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	if b.err != nil {
+		return nil, b.err
+	}
+	return b.users[id], nil
+}
+
+// Prime stores already fetched users so later loads skip the repository.
+func (l *userLoader) Prime(users ...*domain.User) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, user := range users {
+		if _, ok := l.cache[user.ID()]; ok {
+			continue
+		}
+		b := &userBatch{
+			done:  make(chan struct{}),
+			users: map[string]*domain.User{user.ID(): user},
+		}
+		close(b.done)
+		l.cache[user.ID()] = b
+	}
+}
+
+// enqueue adds id to the pending batch. It must be called with l.mu held.
+func (l *userLoader) enqueue(ctx context.Context, id string) *userBatch {
+	b := l.batch
+	if b == nil {
+		b = &userBatch{done: make(chan struct{})}
+		l.batch = b
+		time.AfterFunc(loaderWait, func() { l.dispatch(ctx, b) })
+	}
+
+	b.ids = append(b.ids, id)
+	l.cache[id] = b
+
+	if len(b.ids) >= loaderMaxBatch {
+		l.batch = nil
+		go l.dispatch(ctx, b)
+	}
+
+	return b
+}
+
+func (l *userLoader) dispatch(ctx context.Context, b *userBatch) {
+	b.once.Do(func() {
+		l.mu.Lock()
+		if l.batch == b {
+			l.batch = nil
+		}
+		l.mu.Unlock()
+
+		defer close(b.done)
+
+		users, err := l.service.GetByIDs(ctx, b.ids)
+		if err != nil {
+			b.err = err
+			return
+		}
+
+		b.users = make(map[string]*domain.User, len(users))
+		for _, user := range users {
+			b.users[user.ID()] = user
+		}
+	})
+}
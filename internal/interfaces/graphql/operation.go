@@ -0,0 +1,111 @@
+package graphql
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/parser"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+)
+
+var errUnknownOperation = errors.New("unknown operation")
+
+// operation is the parsed operation selected by a request.
+type operation struct {
+	doc *ast.QueryDocument
+	def *ast.OperationDefinition
+}
+
+// parseOperation parses query and picks the operation named operationName.
+func parseOperation(query, operationName string) (*operation, error) {
+	doc, err := parser.ParseQuery(&ast.Source{Input: query})
+	if err != nil {
+		return nil, err
+	}
+
+	var def *ast.OperationDefinition
+	switch {
+	case operationName != "":
+		def = doc.Operations.ForName(operationName)
+	case len(doc.Operations) == 1:
+		def = doc.Operations[0]
+	}
+	if def == nil {
+		return nil, errUnknownOperation
+	}
+
+	return &operation{doc: doc, def: def}, nil
+}
+
+// isMutation reports whether the operation is a mutation.
+func (op *operation) isMutation() bool {
+	return op.def.Operation == ast.Mutation
+}
+
+// isSubscription reports whether the operation is a subscription.
+func (op *operation) isSubscription() bool {
+	return op.def.Operation == ast.Subscription
+}
+
+// complexity estimates the cost of executing the operation.
+// Every field costs one point, and the selections of a field taking
+// a "first" argument are multiplied by the requested page size.
+func (op *operation) complexity(variables map[string]interface{}) int {
+	return op.selectionCost(op.def.SelectionSet, variables, map[string]bool{})
+}
+
+func (op *operation) selectionCost(set ast.SelectionSet, vars map[string]interface{}, visiting map[string]bool) int {
+	cost := 0
+	for _, sel := range set {
+		switch sel := sel.(type) {
+		case *ast.Field:
+			children := op.selectionCost(sel.SelectionSet, vars, visiting)
+			cost += 1 + children*multiplier(sel, vars)
+		case *ast.InlineFragment:
+			cost += op.selectionCost(sel.SelectionSet, vars, visiting)
+		case *ast.FragmentSpread:
+			fragment := op.doc.Fragments.ForName(sel.Name)
+			// Cyclic spreads are rejected by validation; just stop here.
+			if fragment == nil || visiting[sel.Name] {
+				continue
+			}
+			visiting[sel.Name] = true
+			cost += op.selectionCost(fragment.SelectionSet, vars, visiting)
+			delete(visiting, sel.Name)
+		}
+	}
+	return cost
+}
+
+// multiplier returns the page size requested by a field's "first" argument.
+func multiplier(field *ast.Field, vars map[string]interface{}) int {
+	arg := field.Arguments.ForName("first")
+	if arg == nil {
+		return 1
+	}
+
+	n := app.DefaultPageSize
+	switch arg.Value.Kind {
+	case ast.IntValue:
+		if v, err := strconv.Atoi(arg.Value.Raw); err == nil {
+			n = v
+		}
+	case ast.Variable:
+		switch v := vars[arg.Value.Raw].(type) {
+		case float64:
+			n = int(v)
+		case int:
+			n = v
+		}
+	}
+
+	return min(max(n, 1), app.MaxPageSize)
+}
+
+// complexityError is returned when an operation exceeds the configured limit.
+func complexityError(cost, limit int) error {
+	return fmt.Errorf("operation complexity %d exceeds the limit of %d", cost, limit)
+}
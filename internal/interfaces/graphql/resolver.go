@@ -0,0 +1,254 @@
+package graphql
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+
+	graphqlgo "github.com/graph-gophers/graphql-go"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	"github.com/Sergey-Polishchenko/simple-api/internal/domain"
+)
+
+var (
+	errNameRequired        = errors.New("name is required")
+	errInvalidCursor       = errors.New("invalid cursor")
+	errSubscriptionsClosed = errors.New("subscriptions are disabled")
+)
+
+// resolver is the root resolver for queries, mutations and subscriptions.
+type resolver struct {
+	service app.UserService
+	events  app.UserEventSubscriber
+}
+
+type userArgs struct {
+	ID graphqlgo.ID
+}
+
+func (r *resolver) User(ctx context.Context, args userArgs) (*userResolver, error) {
+	user, err := loaderFrom(ctx, r.service).Load(ctx, string(args.ID))
+	if err != nil || user == nil {
+		return nil, err
+	}
+	return &userResolver{user: user}, nil
+}
+
+type usersArgs struct {
+	First  int32
+	After  *string
+	Filter *userFilterInput
+}
+
+type userFilterInput struct {
+	NameContains *string
+	IDs          *[]graphqlgo.ID
+}
+
+func (r *resolver) Users(ctx context.Context, args usersArgs) (*userConnectionResolver, error) {
+	params := app.ListParams{Limit: int(args.First)}
+	if args.After != nil {
+		after, err := decodeCursor(*args.After)
+		if err != nil {
+			return nil, err
+		}
+		params.After = after
+	}
+	if f := args.Filter; f != nil {
+		if f.NameContains != nil {
+			params.Filter.NameContains = strings.TrimSpace(*f.NameContains)
+		}
+		if f.IDs != nil {
+			for _, id := range *f.IDs {
+				params.Filter.IDs = append(params.Filter.IDs, string(id))
+			}
+		}
+	}
+
+	page, err := r.service.List(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	loaderFrom(ctx, r.service).Prime(page.Users...)
+
+	return &userConnectionResolver{page: page}, nil
+}
+
+type createUserArgs struct {
+	Input struct {
+		Name string
+	}
+}
+
+func (r *resolver) CreateUser(ctx context.Context, args createUserArgs) (*userResolver, error) {
+	user := domain.NewUser("", args.Input.Name)
+	if user.Name() == "" {
+		return nil, errNameRequired
+	}
+
+	user, err := r.service.Create(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	return &userResolver{user: user}, nil
+}
+
+type updateUserArgs struct {
+	Input struct {
+		ID   graphqlgo.ID
+		Name string
+	}
+}
+
+func (r *resolver) UpdateUser(ctx context.Context, args updateUserArgs) (*userResolver, error) {
+	user := domain.NewUser(string(args.Input.ID), args.Input.Name)
+	if user.Name() == "" {
+		return nil, errNameRequired
+	}
+
+	if err := r.service.Update(ctx, user); err != nil {
+		return nil, err
+	}
+	return &userResolver{user: user}, nil
+}
+
+func (r *resolver) DeleteUser(ctx context.Context, args userArgs) (graphqlgo.ID, error) {
+	if err := r.service.Remove(ctx, string(args.ID)); err != nil {
+		return "", err
+	}
+	return args.ID, nil
+}
+
+type userChangedArgs struct {
+	ID *graphqlgo.ID
+}
+
+func (r *resolver) UserChanged(ctx context.Context, args userChangedArgs) (<-chan *userChangeResolver, error) {
+	if r.events == nil {
+		return nil, errSubscriptionsClosed
+	}
+
+	events := r.events.Subscribe(ctx)
+	out := make(chan *userChangeResolver)
+	tenant := app.TenantFromContext(ctx)
+
+	go func() {
+		defer close(out)
+		for event := range events {
+			if event.TenantID != tenant || args.ID != nil && event.UserID != string(*args.ID) {
+				continue
+			}
+			if app.Authorize(ctx, app.ActionUserRead, event.UserID) != nil {
+				continue
+			}
+			select {
+			case out <- &userChangeResolver{event: event}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+type userResolver struct {
+	user *domain.User
+}
+
+func (r *userResolver) ID() graphqlgo.ID {
+	return graphqlgo.ID(r.user.ID())
+}
+
+func (r *userResolver) Name() string {
+	return r.user.Name()
+}
+
+type userConnectionResolver struct {
+	page *app.UserPage
+}
+
+func (r *userConnectionResolver) Edges() []*userEdgeResolver {
+	edges := make([]*userEdgeResolver, len(r.page.Users))
+	for i, user := range r.page.Users {
+		edges[i] = &userEdgeResolver{user: user}
+	}
+	return edges
+}
+
+func (r *userConnectionResolver) Nodes() []*userResolver {
+	nodes := make([]*userResolver, len(r.page.Users))
+	for i, user := range r.page.Users {
+		nodes[i] = &userResolver{user: user}
+	}
+	return nodes
+}
+
+func (r *userConnectionResolver) PageInfo() *pageInfoResolver {
+	info := &pageInfoResolver{hasNextPage: r.page.HasNextPage}
+	if n := len(r.page.Users); n > 0 {
+		cursor := encodeCursor(r.page.Users[n-1].ID())
+		info.endCursor = &cursor
+	}
+	return info
+}
+
+type userEdgeResolver struct {
+	user *domain.User
+}
+
+func (r *userEdgeResolver) Cursor() string {
+	return encodeCursor(r.user.ID())
+}
+
+func (r *userEdgeResolver) Node() *userResolver {
+	return &userResolver{user: r.user}
+}
+
+type pageInfoResolver struct {
+	hasNextPage bool
+	endCursor   *string
+}
+
+func (r *pageInfoResolver) HasNextPage() bool {
+	return r.hasNextPage
+}
+
+func (r *pageInfoResolver) EndCursor() *string {
+	return r.endCursor
+}
+
+type userChangeResolver struct {
+	event app.UserEvent
+}
+
+func (r *userChangeResolver) Type() string {
+	return string(r.event.Type)
+}
+
+func (r *userChangeResolver) ID() graphqlgo.ID {
+	return graphqlgo.ID(r.event.UserID)
+}
+
+func (r *userChangeResolver) User() *userResolver {
+	if r.event.User == nil {
+		return nil
+	}
+	return &userResolver{user: r.event.User}
+}
+
+// encodeCursor turns a user ID into an opaque pagination cursor.
+func encodeCursor(id string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(id))
+}
+
+func decodeCursor(cursor string) (string, error) {
+	id, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || len(id) == 0 {
+		return "", errInvalidCursor
+	}
+	return string(id), nil
+}
@@ -0,0 +1,157 @@
+// Package admin serves the debugging endpoints of the admin listener:
+// pprof profiles, build information, the effective configuration,
+// goroutine dumps and the level of the logs.
+package admin
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"runtime/debug"
+	rpprof "runtime/pprof"
+	"strings"
+
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/logger"
+)
+
+// maxLevelBodySize bounds the body of log level changes.
+const maxLevelBodySize = 1 << 10
+
+// Config configures the admin endpoints.
+type Config struct {
+	// Token authenticates every request as a bearer token.
+	Token string
+	// Version is the version the binary was built as.
+	Version string
+	// Settings is the effective configuration, shown by Settings with its
+	// secrets masked.
+	Settings interface{}
+	// Levels changes the level of the logs.
+	Levels logger.LevelController
+	Logger logger.Logger
+}
+
+// Handler serves the admin endpoints.
+type Handler struct {
+	config    Config
+	tokenHash [sha256.Size]byte
+	mux       *http.ServeMux
+}
+
+// NewHandler initializes a Handler instance.
+func NewHandler(config Config) *Handler {
+	h := &Handler{config: config, tokenHash: sha256.Sum256([]byte(config.Token)), mux: http.NewServeMux()}
+
+	h.mux.HandleFunc("GET /debug/pprof/", pprof.Index)
+	h.mux.HandleFunc("GET /debug/pprof/cmdline", pprof.Cmdline)
+	h.mux.HandleFunc("GET /debug/pprof/profile", pprof.Profile)
+	h.mux.HandleFunc("GET /debug/pprof/symbol", pprof.Symbol)
+	h.mux.HandleFunc("POST /debug/pprof/symbol", pprof.Symbol)
+	h.mux.HandleFunc("GET /debug/pprof/trace", pprof.Trace)
+
+	h.mux.HandleFunc("GET /admin/buildinfo", h.BuildInfo)
+	h.mux.HandleFunc("GET /admin/config", h.Settings)
+	h.mux.HandleFunc("GET /admin/goroutines", h.Goroutines)
+	h.mux.HandleFunc("GET /admin/loglevel", h.LogLevel)
+	h.mux.HandleFunc("PUT /admin/loglevel", h.SetLogLevel)
+	return h
+}
+
+// ServeHTTP serves the requests bearing the admin token.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Hashes make the comparison constant time whatever the length of
+	// the token given.
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	tokenHash := sha256.Sum256([]byte(token))
+	if !ok || subtle.ConstantTimeCompare(tokenHash[:], h.tokenHash[:]) != 1 {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		return
+	}
+	h.mux.ServeHTTP(w, r)
+}
+
+// BuildInfo describes the binary.
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	CommitAt  string `json:"commit_time,omitempty"`
+	Modified  bool   `json:"modified,omitempty"`
+	GoVersion string `json:"go_version"`
+	Module    string `json:"module,omitempty"`
+}
+
+// BuildInfo returns the version, commit and Go version of the binary.
+// The commit is known when the binary is built from a git checkout.
+func (h *Handler) BuildInfo(w http.ResponseWriter, _ *http.Request) {
+	info := BuildInfo{Version: h.config.Version, GoVersion: runtime.Version()}
+	if build, ok := debug.ReadBuildInfo(); ok {
+		info.Module = build.Main.Path
+		for _, setting := range build.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				info.Commit = setting.Value
+			case "vcs.time":
+				info.CommitAt = setting.Value
+			case "vcs.modified":
+				info.Modified = setting.Value == "true"
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, info)
+}
+
+// Settings returns the effective configuration by environment variable,
+// with secrets masked.
+func (h *Handler) Settings(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, Settings(h.config.Settings))
+}
+
+// Goroutines dumps the stacks of all goroutines as text.
+func (h *Handler) Goroutines(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_ = rpprof.Lookup("goroutine").WriteTo(w, 2)
+}
+
+// logLevel is the body of the log level endpoints.
+type logLevel struct {
+	Level string `json:"level"`
+}
+
+// LogLevel returns the level of the logs.
+func (h *Handler) LogLevel(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, logLevel{Level: h.config.Levels.Level()})
+}
+
+// SetLogLevel changes the level of the logs until the next restart.
+func (h *Handler) SetLogLevel(w http.ResponseWriter, r *http.Request) {
+	var body logLevel
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxLevelBodySize)).Decode(&body); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		return
+	}
+
+	previous := h.config.Levels.Level()
+	if err := h.config.Levels.SetLevel(body.Level); err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, logger.ErrFixedLevel) {
+			status = http.StatusNotImplemented
+		}
+		writeJSON(w, status, map[string]string{"error": err.Error()})
+		return
+	}
+
+	h.config.Logger.WithContext(r.Context()).Warn("Log level changed",
+		"from", previous, "to", h.config.Levels.Level(), "client_ip", r.RemoteAddr)
+	writeJSON(w, http.StatusOK, logLevel{Level: h.config.Levels.Level()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
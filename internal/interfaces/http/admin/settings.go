@@ -0,0 +1,57 @@
+package admin
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/logger"
+)
+
+// Settings flattens a configuration struct into its values by environment
+// variable, following the `env` tags of its fields and of the structs
+// nested in it. Secrets are masked like in logs: the values of variables
+// named like credentials, the fields tagged `redact:"true"` and the
+// credentials embedded in strings, like URLs. Unset secrets are shown
+// empty, so that missing ones can be told apart.
+func Settings(config interface{}) map[string]interface{} {
+	settings := map[string]interface{}{}
+	flatten(reflect.ValueOf(config), settings)
+	return settings
+}
+
+func flatten(v reflect.Value, settings map[string]interface{}) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return
+	}
+
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field, value := t.Field(i), v.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("env"), ",")
+		if name == "" {
+			flatten(value, settings)
+			continue
+		}
+
+		switch {
+		case value.IsZero():
+			settings[name] = value.Interface()
+		case field.Tag.Get("redact") == "true":
+			settings[name] = logger.Redacted
+		default:
+			var setting interface{} = value.Interface()
+			// Durations and the like read better as text.
+			if stringer, ok := setting.(fmt.Stringer); ok {
+				setting = stringer.String()
+			}
+			settings[name] = logger.DefaultRedactor.Value(name, setting)
+		}
+	}
+}
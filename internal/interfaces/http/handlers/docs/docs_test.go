@@ -0,0 +1,52 @@
+package docs_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Sergey-Polishchenko/simple-api/api/openapi"
+	"github.com/Sergey-Polishchenko/simple-api/internal/interfaces/http/handlers/docs"
+)
+
+func TestHandler_UI(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	spec, err := openapi.Load()
+	require.NoError(t, err)
+	h, err := docs.NewHandler(spec)
+	require.NoError(t, err)
+	router := gin.New()
+	docs.RegisterRoutes(router, h)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	page := get("/docs")
+	require.Equal(t, http.StatusOK, page.Code)
+	assert.Contains(t, page.Body.String(), `<script src="/docs/swagger-ui-bundle.js">`)
+	assert.Contains(t, page.Body.String(), `<link rel="stylesheet" href="/docs/swagger-ui.css" />`)
+	assert.Contains(t, page.Body.String(), `url: "/openapi.json"`)
+	assert.NotContains(t, page.Body.String(), "https://")
+
+	bundle := get("/docs/swagger-ui-bundle.js")
+	require.Equal(t, http.StatusOK, bundle.Code)
+	assert.Equal(t, "text/javascript; charset=utf-8", bundle.Header().Get("Content-Type"))
+	assert.Greater(t, bundle.Body.Len(), 1<<20, "the real bundle is served, not a stub")
+	assert.Contains(t, bundle.Body.String(), "SwaggerUIBundle")
+	assert.Contains(t, bundle.Body.String(), `PACKAGE_VERSION:"5.18.2"`)
+
+	styles := get("/docs/swagger-ui.css")
+	require.Equal(t, http.StatusOK, styles.Code)
+	assert.Equal(t, "text/css; charset=utf-8", styles.Header().Get("Content-Type"))
+	assert.True(t, strings.HasPrefix(styles.Body.String(), ".swagger-ui{"))
+
+	assert.Equal(t, http.StatusOK, get("/openapi.json").Code)
+}
@@ -0,0 +1,53 @@
+// Package health serves the liveness, readiness and startup probes.
+package health
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/health"
+)
+
+// Handler serves the probes of a health registry.
+type Handler struct {
+	registry *health.Registry
+}
+
+// NewHandler initializes a Handler instance.
+func NewHandler(registry *health.Registry) *Handler {
+	return &Handler{registry: registry}
+}
+
+// Live answers the liveness probe.
+func (h *Handler) Live(c *gin.Context) {
+	respond(c, h.registry.Live())
+}
+
+// Ready answers the readiness probe, with the state of every dependency.
+func (h *Handler) Ready(c *gin.Context) {
+	respond(c, h.registry.Ready(c.Request.Context()))
+}
+
+// Started answers the startup probe.
+func (h *Handler) Started(c *gin.Context) {
+	respond(c, h.registry.Started())
+}
+
+// respond answers 200 when the probe succeeds and 503 otherwise, with the
+// report as JSON.
+func respond(c *gin.Context, report health.Report) {
+	status := http.StatusOK
+	if !report.Up() {
+		status = http.StatusServiceUnavailable
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(status, report)
+}
+
+// RegisterRoutes mounts the probes.
+func RegisterRoutes(router *gin.Engine, h *Handler) {
+	router.GET("/healthz", h.Live)
+	router.GET("/readyz", h.Ready)
+	router.GET("/startupz", h.Started)
+}
@@ -0,0 +1,58 @@
+package health_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	handlers "github.com/Sergey-Polishchenko/simple-api/internal/interfaces/http/handlers/health"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/health"
+)
+
+func TestHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var dbErr error
+	registry := health.NewRegistry(time.Second)
+	registry.Register("database", health.CheckerFunc(func(context.Context) error { return dbErr }))
+	router := gin.New()
+	handlers.RegisterRoutes(router, handlers.NewHandler(registry))
+
+	probe := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	w := probe("/startupz")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.JSONEq(t, `{"status":"down","error":"starting"}`, w.Body.String())
+	assert.Equal(t, http.StatusServiceUnavailable, probe("/readyz").Code)
+
+	registry.MarkStarted()
+	assert.Equal(t, http.StatusOK, probe("/startupz").Code)
+	w = probe("/readyz")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"checks":{"database":{"status":"up"`)
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+
+	dbErr = errors.New("connection refused")
+	w = probe("/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), `"database":{"status":"down","error":"connection refused"`)
+	w = probe("/healthz")
+	assert.Equal(t, http.StatusOK, w.Code, "liveness doesn't depend on the database")
+	assert.JSONEq(t, `{"status":"up"}`, w.Body.String())
+
+	dbErr = nil
+	registry.MarkShuttingDown()
+	w = probe("/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), `"error":"shutting down"`)
+}
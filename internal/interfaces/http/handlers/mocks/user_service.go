@@ -2,6 +2,7 @@ package mocks
 
 import (
 	"context"
+	"time"
 
 	"github.com/stretchr/testify/mock"
 
@@ -31,6 +32,22 @@ func (m *MockUserService) GetUser(ctx context.Context, id string) (*domain.User,
 	return args.Get(0).(*domain.User), args.Error(1)
 }
 
+func (m *MockUserService) GetByIDs(ctx context.Context, ids []string) ([]*domain.User, error) {
+	args := m.Called(ctx, ids)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*domain.User), args.Error(1)
+}
+
+func (m *MockUserService) List(ctx context.Context, params app.ListParams) (*app.UserPage, error) {
+	args := m.Called(ctx, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*app.UserPage), args.Error(1)
+}
+
 func (m *MockUserService) Update(ctx context.Context, user *domain.User) error {
 	return m.Called(ctx, user).Error(0)
 }
@@ -39,4 +56,41 @@ func (m *MockUserService) Remove(ctx context.Context, id string) error {
 	return m.Called(ctx, id).Error(0)
 }
 
+func (m *MockUserService) SetPassword(ctx context.Context, id, current, password string) error {
+	return m.Called(ctx, id, current, password).Error(0)
+}
+
+func (m *MockUserService) SetEmail(ctx context.Context, id, current, email string) error {
+	return m.Called(ctx, id, current, email).Error(0)
+}
+
+func (m *MockUserService) GetUserAsOf(ctx context.Context, id string, at time.Time) (*domain.User, error) {
+	args := m.Called(ctx, id, at)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.User), args.Error(1)
+}
+
+func (m *MockUserService) Versions(ctx context.Context, id string, params app.VersionListParams) (*app.UserVersionPage, error) {
+	args := m.Called(ctx, id, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*app.UserVersionPage), args.Error(1)
+}
+
+func (m *MockUserService) Revert(ctx context.Context, id string, version int) (*domain.User, error) {
+	args := m.Called(ctx, id, version)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.User), args.Error(1)
+}
+
+func (m *MockUserService) Erase(ctx context.Context, id string) (int, error) {
+	args := m.Called(ctx, id)
+	return args.Int(0), args.Error(1)
+}
+
 var _ app.UserService = (*MockUserService)(nil)
@@ -0,0 +1,222 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	_ "embed"
+	"encoding/base64"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	perrors "github.com/Sergey-Polishchenko/simple-api/internal/pkg/errors"
+)
+
+//go:embed authorize.html
+var authorizeHTML string
+
+var pages = template.Must(template.New("").Parse(authorizeHTML))
+
+// The sign-in form carries a fresh random token that must match the one
+// in a cookie set along with the form, so other sites can't post to it.
+const (
+	csrfCookie = "oidc_csrf"
+	csrfField  = "csrf_token"
+)
+
+type authorizePage struct {
+	Request   app.AuthorizationRequest
+	CSRFToken string
+	UserID    string
+	Error     string
+}
+
+// AuthorizeForm starts an authorization request by asking the user to sign in.
+func (h *Handler) AuthorizeForm(c *gin.Context) {
+	req := authorizationRequest(c.Query)
+	if !h.validate(c, req) {
+		return
+	}
+
+	token, err := issueCSRFToken(c)
+	if err != nil {
+		h.serverError(c, err)
+		return
+	}
+	h.render(c, http.StatusOK, "authorize", authorizePage{Request: req, CSRFToken: token})
+}
+
+// Authorize signs the user in and redirects back to the client with an
+// authorization code.
+func (h *Handler) Authorize(c *gin.Context) {
+	token, ok := checkCSRFToken(c)
+	if !ok {
+		h.render(c, http.StatusForbidden, "error", authorizePage{Error: "The sign-in form has expired, please start again."})
+		return
+	}
+
+	req := authorizationRequest(c.PostForm)
+	if !h.validate(c, req) {
+		return
+	}
+
+	ctx := c.Request.Context()
+	userID := c.PostForm("user_id")
+	err := h.auth.VerifyCredentials(ctx, app.Credentials{
+		UserID:   userID,
+		Password: c.PostForm("password"),
+		MFACode:  c.PostForm("mfa_code"),
+	})
+	switch {
+	case errors.Is(err, perrors.ErrInvalidCredentials), errors.Is(err, perrors.ErrInvalidMFACode):
+		h.render(c, http.StatusUnauthorized, "authorize", authorizePage{
+			Request:   req,
+			CSRFToken: token,
+			UserID:    userID,
+			Error:     "Invalid user ID, password or authentication code.",
+		})
+		return
+	case errors.Is(err, perrors.ErrMFARequired):
+		h.render(c, http.StatusUnauthorized, "authorize", authorizePage{
+			Request:   req,
+			CSRFToken: token,
+			UserID:    userID,
+			Error:     "Enter the code from your authenticator app.",
+		})
+		return
+	case errors.Is(err, perrors.ErrTooManyAttempts):
+		h.render(c, http.StatusTooManyRequests, "authorize", authorizePage{
+			Request:   req,
+			CSRFToken: token,
+			UserID:    userID,
+			Error:     "Too many attempts, please try again later.",
+		})
+		return
+	case err != nil:
+		h.serverError(c, err)
+		return
+	}
+
+	code, err := h.service.Authorize(app.ContextWithClaims(ctx, &app.Claims{Subject: userID}), req)
+	var oauthErr *app.OAuthError
+	if errors.As(err, &oauthErr) {
+		h.redirect(c, req, url.Values{"error": {oauthErr.Code}, "error_description": {oauthErr.Description}})
+		return
+	}
+	if err != nil {
+		h.serverError(c, err)
+		return
+	}
+
+	// The form is used up.
+	c.SetCookie(csrfCookie, "", -1, authorizePath, "", c.Request.TLS != nil, true)
+	h.redirect(c, req, url.Values{"code": {code}})
+}
+
+// validate checks an authorization request. Errors about the client or
+// redirect URI are shown to the user, the rest is reported to the client.
+func (h *Handler) validate(c *gin.Context, req app.AuthorizationRequest) bool {
+	ctx := c.Request.Context()
+
+	err := h.service.ValidateClient(ctx, req.ClientID, req.RedirectURI)
+	var oauthErr *app.OAuthError
+	if errors.As(err, &oauthErr) {
+		h.render(c, http.StatusBadRequest, "error", authorizePage{Error: oauthErr.Description})
+		return false
+	}
+	if err != nil {
+		h.serverError(c, err)
+		return false
+	}
+
+	err = h.service.ValidateAuthorization(ctx, req)
+	if errors.As(err, &oauthErr) {
+		h.redirect(c, req, url.Values{"error": {oauthErr.Code}, "error_description": {oauthErr.Description}})
+		return false
+	}
+	if err != nil {
+		h.serverError(c, err)
+		return false
+	}
+	return true
+}
+
+// redirect sends the user back to the client with the response parameters,
+// the state and the issuer, see RFC 9207.
+func (h *Handler) redirect(c *gin.Context, req app.AuthorizationRequest, params url.Values) {
+	target, err := url.Parse(req.RedirectURI)
+	if err != nil {
+		h.serverError(c, err)
+		return
+	}
+
+	query := target.Query()
+	for name, values := range params {
+		query[name] = values
+	}
+	if req.State != "" {
+		query.Set("state", req.State)
+	}
+	query.Set("iss", h.issuer)
+	target.RawQuery = query.Encode()
+
+	c.Redirect(http.StatusSeeOther, target.String())
+}
+
+func (h *Handler) render(c *gin.Context, status int, name string, page authorizePage) {
+	// The sign-in form must not be framed by other sites.
+	c.Header("X-Frame-Options", "DENY")
+	c.Header("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; frame-ancestors 'none'")
+	noStore(c)
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Status(status)
+	if err := pages.ExecuteTemplate(c.Writer, name, page); err != nil {
+		h.logger.WithContext(c.Request.Context()).Error("can't render page", "page", name, "error", err)
+	}
+}
+
+func (h *Handler) serverError(c *gin.Context, err error) {
+	h.logger.WithContext(c.Request.Context()).Error("can't serve authorization request", "error", err)
+	h.render(c, http.StatusInternalServerError, "error", authorizePage{Error: "Something went wrong, please try again later."})
+}
+
+// issueCSRFToken binds a new sign-in form to the browser.
+func issueCSRFToken(c *gin.Context) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	c.SetSameSite(http.SameSiteStrictMode)
+	c.SetCookie(csrfCookie, token, 0, authorizePath, "", c.Request.TLS != nil, true)
+	return token, nil
+}
+
+// checkCSRFToken returns the token of a posted sign-in form if it matches
+// the cookie of the browser.
+func checkCSRFToken(c *gin.Context) (string, bool) {
+	cookie, err := c.Cookie(csrfCookie)
+	if err != nil || cookie == "" {
+		return "", false
+	}
+	token := c.PostForm(csrfField)
+	return token, subtle.ConstantTimeCompare([]byte(token), []byte(cookie)) == 1
+}
+
+func authorizationRequest(param func(string) string) app.AuthorizationRequest {
+	return app.AuthorizationRequest{
+		ClientID:            param("client_id"),
+		RedirectURI:         param("redirect_uri"),
+		ResponseType:        param("response_type"),
+		Scope:               param("scope"),
+		State:               param("state"),
+		Nonce:               param("nonce"),
+		CodeChallenge:       param("code_challenge"),
+		CodeChallengeMethod: param("code_challenge_method"),
+	}
+}
@@ -0,0 +1,213 @@
+// Package oidc serves the endpoints of the built-in OpenID Connect provider.
+package oidc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/jwks"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/logger"
+)
+
+// Endpoint paths, relative to the issuer.
+const (
+	discoveryPath     = "/.well-known/openid-configuration"
+	jwksPath          = "/oauth2/jwks"
+	authorizePath     = "/oauth2/authorize"
+	tokenPath         = "/oauth2/token"
+	introspectionPath = "/oauth2/introspect"
+	revocationPath    = "/oauth2/revoke"
+)
+
+// Handler serves the provider. Users sign in on the authorization
+// endpoint with their password, checked by an app.AuthService.
+type Handler struct {
+	service   app.OIDCService
+	auth      app.AuthService
+	logger    logger.Logger
+	issuer    string
+	discovery gin.H
+	jwks      []byte
+}
+
+// NewHandler initializes a Handler for the provider at issuer, whose tokens
+// are verified with key. The key is published, so it must be asymmetric.
+func NewHandler(
+	service app.OIDCService,
+	auth app.AuthService,
+	issuer string,
+	key jwks.Key,
+	logger logger.Logger,
+) (*Handler, error) {
+	set, err := jwks.Encode([]jwks.Key{key})
+	if err != nil {
+		return nil, err
+	}
+
+	base := strings.TrimSuffix(issuer, "/")
+	return &Handler{
+		service: service,
+		auth:    auth,
+		logger:  logger,
+		issuer:  issuer,
+		jwks:    set,
+		discovery: gin.H{
+			"issuer":                                         issuer,
+			"authorization_endpoint":                         base + authorizePath,
+			"token_endpoint":                                 base + tokenPath,
+			"jwks_uri":                                       base + jwksPath,
+			"introspection_endpoint":                         base + introspectionPath,
+			"revocation_endpoint":                            base + revocationPath,
+			"response_types_supported":                       []string{"code"},
+			"grant_types_supported":                          []string{app.GrantAuthorizationCode, app.GrantClientCredentials},
+			"subject_types_supported":                        []string{"public"},
+			"id_token_signing_alg_values_supported":          []string{key.Algorithm},
+			"scopes_supported":                               []string{app.ScopeOpenID, app.ScopeProfile},
+			"claims_supported":                               []string{"iss", "sub", "aud", "iat", "exp", "auth_time", "nonce", "name"},
+			"token_endpoint_auth_methods_supported":          []string{"client_secret_basic", "client_secret_post", "none"},
+			"code_challenge_methods_supported":               []string{"S256"},
+			"authorization_response_iss_parameter_supported": true,
+		},
+	}, nil
+}
+
+// Discovery returns the provider metadata, see OpenID Connect Discovery 1.0.
+func (h *Handler) Discovery(c *gin.Context) {
+	c.JSON(http.StatusOK, h.discovery)
+}
+
+// JWKS returns the keys verifying the tokens of the provider.
+func (h *Handler) JWKS(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json", h.jwks)
+}
+
+// Token serves the token endpoint.
+func (h *Handler) Token(c *gin.Context) {
+	clientID, secret := clientCredentials(c)
+	tokens, err := h.service.Token(c.Request.Context(), app.TokenRequest{
+		GrantType:    c.PostForm("grant_type"),
+		ClientID:     clientID,
+		ClientSecret: secret,
+		Code:         c.PostForm("code"),
+		RedirectURI:  c.PostForm("redirect_uri"),
+		CodeVerifier: c.PostForm("code_verifier"),
+		Scope:        c.PostForm("scope"),
+	})
+	if err != nil {
+		h.oauthError(c, err)
+		return
+	}
+
+	response := gin.H{
+		"access_token": tokens.AccessToken,
+		"token_type":   "Bearer",
+		"expires_in":   int(tokens.ExpiresIn.Seconds()),
+	}
+	if tokens.Scope != "" {
+		response["scope"] = tokens.Scope
+	}
+	if tokens.IDToken != "" {
+		response["id_token"] = tokens.IDToken
+	}
+	noStore(c)
+	c.JSON(http.StatusOK, response)
+}
+
+// Introspect describes a token to a confidential client, see RFC 7662.
+func (h *Handler) Introspect(c *gin.Context) {
+	clientID, secret := clientCredentials(c)
+	introspection, err := h.service.Introspect(c.Request.Context(), clientID, secret, c.PostForm("token"))
+	if err != nil {
+		h.oauthError(c, err)
+		return
+	}
+
+	noStore(c)
+	if !introspection.Active {
+		c.JSON(http.StatusOK, gin.H{"active": false})
+		return
+	}
+
+	response := gin.H{
+		"active":     true,
+		"sub":        introspection.Subject,
+		"client_id":  introspection.ClientID,
+		"iss":        introspection.Issuer,
+		"iat":        introspection.IssuedAt.Unix(),
+		"exp":        introspection.ExpiresAt.Unix(),
+		"token_type": "Bearer",
+	}
+	if introspection.Scope != "" {
+		response["scope"] = introspection.Scope
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// Revoke revokes an access token, see RFC 7009.
+func (h *Handler) Revoke(c *gin.Context) {
+	clientID, secret := clientCredentials(c)
+	if err := h.service.Revoke(c.Request.Context(), clientID, secret, c.PostForm("token")); err != nil {
+		h.oauthError(c, err)
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+// clientCredentials reads the client authentication of a request, from
+// HTTP Basic authentication or the request body.
+func clientCredentials(c *gin.Context) (string, string) {
+	if id, secret, ok := c.Request.BasicAuth(); ok {
+		// RFC 6749 form-encodes both before the Basic encoding.
+		if unescaped, err := url.QueryUnescape(id); err == nil {
+			id = unescaped
+		}
+		if unescaped, err := url.QueryUnescape(secret); err == nil {
+			secret = unescaped
+		}
+		return id, secret
+	}
+	return c.PostForm("client_id"), c.PostForm("client_secret")
+}
+
+// oauthError responds with an error of RFC 6749, section 5.2.
+func (h *Handler) oauthError(c *gin.Context, err error) {
+	var oauthErr *app.OAuthError
+	if !errors.As(err, &oauthErr) {
+		if !errors.Is(err, context.Canceled) {
+			h.logger.WithContext(c.Request.Context()).Error("can't serve oauth request", "path", c.FullPath(), "error", err)
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+		return
+	}
+
+	status := http.StatusBadRequest
+	if oauthErr.Code == app.OAuthInvalidClient {
+		status = http.StatusUnauthorized
+		c.Header("WWW-Authenticate", `Basic realm="oauth2"`)
+	}
+	noStore(c)
+	c.JSON(status, gin.H{"error": oauthErr.Code, "error_description": oauthErr.Description})
+}
+
+func noStore(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+}
+
+// RegisterRoutes mounts the provider endpoints. They authenticate clients
+// and users on their own, so they must not be behind the API authentication.
+func RegisterRoutes(router *gin.Engine, h *Handler) {
+	router.GET(discoveryPath, h.Discovery)
+	router.GET(jwksPath, h.JWKS)
+	router.GET(authorizePath, h.AuthorizeForm)
+	router.POST(authorizePath, h.Authorize)
+	router.POST(tokenPath, h.Token)
+	router.POST(introspectionPath, h.Introspect)
+	router.POST(revocationPath, h.Revoke)
+}
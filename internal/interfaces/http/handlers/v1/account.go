@@ -0,0 +1,111 @@
+package v1
+
+import (
+	"context"
+	"errors"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	perrors "github.com/Sergey-Polishchenko/simple-api/internal/pkg/errors"
+)
+
+const (
+	errAccountEmailsDisabled = "account emails are disabled"
+	// msgAccountEmailSent doesn't tell whether the address is known.
+	msgAccountEmailSent = "if the address is known, an email is on its way"
+)
+
+// AccountHandler handles the email verification and password reset flows.
+type AccountHandler struct {
+	service app.AccountService
+}
+
+// NewAccountHandler initializes a new AccountHandler. With a nil service
+// the flows are disabled and every request gets 404.
+func NewAccountHandler(service app.AccountService) *AccountHandler {
+	return &AccountHandler{service: service}
+}
+
+// RequestEmailVerification emails a verification token.
+func (h *AccountHandler) RequestEmailVerification(
+	ctx context.Context,
+	request RequestEmailVerificationRequestObject,
+) (RequestEmailVerificationResponseObject, error) {
+	if h.service == nil {
+		return RequestEmailVerification404JSONResponse{NotFoundJSONResponse{Error: errAccountEmailsDisabled}}, nil
+	}
+
+	err := h.service.RequestEmailVerification(requestContext(ctx), request.Body.Email)
+	var invalid *app.InvalidEmailError
+	switch {
+	case errors.As(err, &invalid):
+		return RequestEmailVerification400JSONResponse{BadRequestJSONResponse{Error: invalid.Error()}}, nil
+	case err != nil:
+		return RequestEmailVerification500JSONResponse{internalError(ctx, err)}, nil
+	}
+
+	return RequestEmailVerification202JSONResponse{Message: msgAccountEmailSent}, nil
+}
+
+// VerifyEmail confirms an email address with a token.
+func (h *AccountHandler) VerifyEmail(
+	ctx context.Context,
+	request VerifyEmailRequestObject,
+) (VerifyEmailResponseObject, error) {
+	if h.service == nil {
+		return VerifyEmail404JSONResponse{NotFoundJSONResponse{Error: errAccountEmailsDisabled}}, nil
+	}
+
+	err := h.service.VerifyEmail(requestContext(ctx), request.Body.Token)
+	if errors.Is(err, perrors.ErrInvalidAccountToken) {
+		return VerifyEmail400JSONResponse{BadRequestJSONResponse{Error: err.Error()}}, nil
+	}
+	if err != nil {
+		return VerifyEmail500JSONResponse{internalError(ctx, err)}, nil
+	}
+
+	return VerifyEmail200JSONResponse{Message: "email verified"}, nil
+}
+
+// RequestPasswordReset emails a password reset token.
+func (h *AccountHandler) RequestPasswordReset(
+	ctx context.Context,
+	request RequestPasswordResetRequestObject,
+) (RequestPasswordResetResponseObject, error) {
+	if h.service == nil {
+		return RequestPasswordReset404JSONResponse{NotFoundJSONResponse{Error: errAccountEmailsDisabled}}, nil
+	}
+
+	err := h.service.RequestPasswordReset(requestContext(ctx), request.Body.Email)
+	var invalid *app.InvalidEmailError
+	switch {
+	case errors.As(err, &invalid):
+		return RequestPasswordReset400JSONResponse{BadRequestJSONResponse{Error: invalid.Error()}}, nil
+	case err != nil:
+		return RequestPasswordReset500JSONResponse{internalError(ctx, err)}, nil
+	}
+
+	return RequestPasswordReset202JSONResponse{Message: msgAccountEmailSent}, nil
+}
+
+// ResetPassword sets a new password with a token.
+func (h *AccountHandler) ResetPassword(
+	ctx context.Context,
+	request ResetPasswordRequestObject,
+) (ResetPasswordResponseObject, error) {
+	if h.service == nil {
+		return ResetPassword404JSONResponse{NotFoundJSONResponse{Error: errAccountEmailsDisabled}}, nil
+	}
+
+	err := h.service.ResetPassword(requestContext(ctx), request.Body.Token, request.Body.Password)
+	var invalid *app.InvalidPasswordError
+	switch {
+	case errors.As(err, &invalid):
+		return ResetPassword400JSONResponse{BadRequestJSONResponse{Error: invalid.Error()}}, nil
+	case errors.Is(err, perrors.ErrInvalidAccountToken):
+		return ResetPassword400JSONResponse{BadRequestJSONResponse{Error: err.Error()}}, nil
+	case err != nil:
+		return ResetPassword500JSONResponse{internalError(ctx, err)}, nil
+	}
+
+	return ResetPassword200JSONResponse{Message: "password changed"}, nil
+}
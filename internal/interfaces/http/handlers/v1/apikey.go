@@ -0,0 +1,129 @@
+package v1
+
+import (
+	"context"
+	"errors"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	perrors "github.com/Sergey-Polishchenko/simple-api/internal/pkg/errors"
+)
+
+const errAPIKeysDisabled = "api keys are disabled"
+
+// APIKeyHandler handles HTTP requests related to API keys.
+type APIKeyHandler struct {
+	service app.APIKeyService
+}
+
+// NewAPIKeyHandler initializes a new APIKeyHandler. With a nil service
+// API keys are disabled and every request is forbidden.
+func NewAPIKeyHandler(service app.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{service: service}
+}
+
+// MintAPIKey processes API key creation requests.
+func (h *APIKeyHandler) MintAPIKey(
+	ctx context.Context,
+	request MintAPIKeyRequestObject,
+) (MintAPIKeyResponseObject, error) {
+	if h.service == nil {
+		return MintAPIKey403JSONResponse{ForbiddenJSONResponse{Error: errAPIKeysDisabled}}, nil
+	}
+
+	params := app.MintAPIKeyParams{
+		Name:      request.Body.Name,
+		Scopes:    make([]string, len(request.Body.Scopes)),
+		ExpiresAt: request.Body.ExpiresAt,
+	}
+	for i, scope := range request.Body.Scopes {
+		params.Scopes[i] = string(scope)
+	}
+
+	key, secret, err := h.service.Mint(requestContext(ctx), params)
+	var invalid *app.InvalidAPIKeyParamsError
+	switch {
+	case errors.As(err, &invalid):
+		return MintAPIKey400JSONResponse{BadRequestJSONResponse{Error: invalid.Error()}}, nil
+	case errors.Is(err, perrors.ErrForbidden):
+		return MintAPIKey403JSONResponse{ForbiddenJSONResponse{Error: err.Error()}}, nil
+	case err != nil:
+		return MintAPIKey500JSONResponse{internalError(ctx, err)}, nil
+	}
+
+	keyJSON := toAPIKeyJSON(key)
+	return MintAPIKey201JSONResponse{
+		Id:         keyJSON.Id,
+		Name:       keyJSON.Name,
+		Prefix:     keyJSON.Prefix,
+		Scopes:     keyJSON.Scopes,
+		CreatedAt:  keyJSON.CreatedAt,
+		ExpiresAt:  keyJSON.ExpiresAt,
+		LastUsedAt: keyJSON.LastUsedAt,
+		RevokedAt:  keyJSON.RevokedAt,
+		Key:        secret,
+	}, nil
+}
+
+// ListAPIKeys retrieves all API keys.
+func (h *APIKeyHandler) ListAPIKeys(
+	ctx context.Context,
+	_ ListAPIKeysRequestObject,
+) (ListAPIKeysResponseObject, error) {
+	if h.service == nil {
+		return ListAPIKeys403JSONResponse{ForbiddenJSONResponse{Error: errAPIKeysDisabled}}, nil
+	}
+
+	keys, err := h.service.List(requestContext(ctx))
+	if errors.Is(err, perrors.ErrForbidden) {
+		return ListAPIKeys403JSONResponse{ForbiddenJSONResponse{Error: err.Error()}}, nil
+	}
+	if err != nil {
+		return ListAPIKeys500JSONResponse{internalError(ctx, err)}, nil
+	}
+
+	response := make(ListAPIKeys200JSONResponse, len(keys))
+	for i, key := range keys {
+		response[i] = toAPIKeyJSON(key)
+	}
+	return response, nil
+}
+
+// RevokeAPIKey revokes an API key by ID.
+func (h *APIKeyHandler) RevokeAPIKey(
+	ctx context.Context,
+	request RevokeAPIKeyRequestObject,
+) (RevokeAPIKeyResponseObject, error) {
+	if h.service == nil {
+		return RevokeAPIKey403JSONResponse{ForbiddenJSONResponse{Error: errAPIKeysDisabled}}, nil
+	}
+
+	err := h.service.Revoke(requestContext(ctx), request.Id)
+	switch {
+	case errors.Is(err, perrors.ErrAPIKeyNotFound):
+		return RevokeAPIKey404JSONResponse{NotFoundJSONResponse{Error: err.Error()}}, nil
+	case errors.Is(err, perrors.ErrForbidden):
+		return RevokeAPIKey403JSONResponse{ForbiddenJSONResponse{Error: err.Error()}}, nil
+	case err != nil:
+		return RevokeAPIKey500JSONResponse{internalError(ctx, err)}, nil
+	}
+
+	return RevokeAPIKey200JSONResponse{Message: "api key revoked"}, nil
+}
+
+func toAPIKeyJSON(key *app.APIKey) APIKeyJSON {
+	scopes := make([]APIKeyScope, len(key.Scopes))
+	for i, scope := range key.Scopes {
+		scopes[i] = APIKeyScope(scope)
+	}
+
+	return APIKeyJSON{
+		Id:         key.ID,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     scopes,
+		CreatedAt:  key.CreatedAt,
+		ExpiresAt:  key.ExpiresAt,
+		LastUsedAt: key.LastUsedAt,
+		RevokedAt:  key.RevokedAt,
+	}
+}
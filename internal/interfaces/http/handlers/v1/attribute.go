@@ -0,0 +1,122 @@
+package v1
+
+import (
+	"context"
+	"errors"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	perrors "github.com/Sergey-Polishchenko/simple-api/internal/pkg/errors"
+)
+
+const errAttributesDisabled = "custom attributes are disabled"
+
+// AttributeHandler handles HTTP requests related to the schema of the
+// custom attributes of users.
+type AttributeHandler struct {
+	service app.AttributeSchemaService
+}
+
+// NewAttributeHandler initializes a new AttributeHandler. With a nil
+// service custom attributes are disabled and every request is forbidden.
+func NewAttributeHandler(service app.AttributeSchemaService) *AttributeHandler {
+	return &AttributeHandler{service: service}
+}
+
+// ListAttributes retrieves all attribute definitions.
+func (h *AttributeHandler) ListAttributes(
+	ctx context.Context,
+	_ ListAttributesRequestObject,
+) (ListAttributesResponseObject, error) {
+	if h.service == nil {
+		return ListAttributes403JSONResponse{ForbiddenJSONResponse{Error: errAttributesDisabled}}, nil
+	}
+
+	definitions, err := h.service.List(requestContext(ctx))
+	if errors.Is(err, perrors.ErrForbidden) {
+		return ListAttributes403JSONResponse{ForbiddenJSONResponse{Error: err.Error()}}, nil
+	}
+	if err != nil {
+		return ListAttributes500JSONResponse{internalError(ctx, err)}, nil
+	}
+
+	result := make(ListAttributes200JSONResponse, len(definitions))
+	for i, definition := range definitions {
+		result[i] = toAttributeDefinitionJSON(definition)
+	}
+	return result, nil
+}
+
+// DefineAttribute creates or replaces an attribute definition.
+func (h *AttributeHandler) DefineAttribute(
+	ctx context.Context,
+	request DefineAttributeRequestObject,
+) (DefineAttributeResponseObject, error) {
+	if h.service == nil {
+		return DefineAttribute403JSONResponse{ForbiddenJSONResponse{Error: errAttributesDisabled}}, nil
+	}
+
+	definition := &app.AttributeDefinition{
+		Name: request.Name,
+		Type: app.AttributeType(request.Body.Type),
+	}
+	if request.Body.Required != nil {
+		definition.Required = *request.Body.Required
+	}
+	if request.Body.Enum != nil {
+		definition.Enum = *request.Body.Enum
+	}
+	if request.Body.Pattern != nil {
+		definition.Pattern = *request.Body.Pattern
+	}
+
+	definition, err := h.service.Define(requestContext(ctx), definition)
+	var invalid *app.InvalidAttributesError
+	switch {
+	case errors.As(err, &invalid):
+		return DefineAttribute400JSONResponse{BadRequestJSONResponse{Error: invalid.Error()}}, nil
+	case errors.Is(err, perrors.ErrForbidden):
+		return DefineAttribute403JSONResponse{ForbiddenJSONResponse{Error: err.Error()}}, nil
+	case err != nil:
+		return DefineAttribute500JSONResponse{internalError(ctx, err)}, nil
+	}
+
+	return DefineAttribute200JSONResponse(toAttributeDefinitionJSON(definition)), nil
+}
+
+// RemoveAttribute deletes an attribute definition.
+func (h *AttributeHandler) RemoveAttribute(
+	ctx context.Context,
+	request RemoveAttributeRequestObject,
+) (RemoveAttributeResponseObject, error) {
+	if h.service == nil {
+		return RemoveAttribute403JSONResponse{ForbiddenJSONResponse{Error: errAttributesDisabled}}, nil
+	}
+
+	err := h.service.Remove(requestContext(ctx), request.Name)
+	switch {
+	case errors.Is(err, perrors.ErrAttributeNotFound):
+		return RemoveAttribute404JSONResponse{NotFoundJSONResponse{Error: err.Error()}}, nil
+	case errors.Is(err, perrors.ErrForbidden):
+		return RemoveAttribute403JSONResponse{ForbiddenJSONResponse{Error: err.Error()}}, nil
+	case err != nil:
+		return RemoveAttribute500JSONResponse{internalError(ctx, err)}, nil
+	}
+
+	return RemoveAttribute200JSONResponse{Message: "attribute removed"}, nil
+}
+
+func toAttributeDefinitionJSON(definition *app.AttributeDefinition) AttributeDefinitionJSON {
+	result := AttributeDefinitionJSON{
+		Name:      definition.Name,
+		Type:      AttributeDefinitionJSONType(definition.Type),
+		Required:  definition.Required,
+		UpdatedAt: definition.UpdatedAt,
+	}
+	if len(definition.Enum) > 0 {
+		result.Enum = &definition.Enum
+	}
+	if definition.Pattern != "" {
+		result.Pattern = &definition.Pattern
+	}
+	return result
+}
@@ -0,0 +1,173 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	perrors "github.com/Sergey-Polishchenko/simple-api/internal/pkg/errors"
+)
+
+const (
+	errAuditDisabled = "the audit log is disabled"
+	errInvalidCursor = "invalid cursor"
+)
+
+// AuditHandler handles HTTP requests related to the audit log.
+type AuditHandler struct {
+	service app.AuditService
+}
+
+// NewAuditHandler initializes a new AuditHandler. With a nil service the
+// audit log is disabled and every request is forbidden.
+func NewAuditHandler(service app.AuditService) *AuditHandler {
+	return &AuditHandler{service: service}
+}
+
+// ListAuditEntries retrieves a page of audit entries matching the filter.
+func (h *AuditHandler) ListAuditEntries(
+	ctx context.Context,
+	request ListAuditEntriesRequestObject,
+) (ListAuditEntriesResponseObject, error) {
+	if h.service == nil {
+		return ListAuditEntries403JSONResponse{ForbiddenJSONResponse{Error: errAuditDisabled}}, nil
+	}
+
+	params, ok := toAuditListParams(request.Params.Limit, request.Params.After)
+	if !ok {
+		return ListAuditEntries400JSONResponse{BadRequestJSONResponse{Error: errInvalidCursor}}, nil
+	}
+	if request.Params.Actor != nil {
+		params.Filter.ActorID = *request.Params.Actor
+	}
+	if request.Params.Action != nil {
+		params.Filter.Action = app.AuditAction(*request.Params.Action)
+	}
+	if request.Params.Target != nil {
+		params.Filter.TargetID = *request.Params.Target
+	}
+	if request.Params.Since != nil {
+		params.Filter.Since = *request.Params.Since
+	}
+	if request.Params.Until != nil {
+		params.Filter.Until = *request.Params.Until
+	}
+
+	page, err := h.service.List(requestContext(ctx), params)
+	switch {
+	case errors.Is(err, perrors.ErrForbidden):
+		return ListAuditEntries403JSONResponse{ForbiddenJSONResponse{Error: err.Error()}}, nil
+	case err != nil:
+		return ListAuditEntries500JSONResponse{internalError(ctx, err)}, nil
+	}
+
+	return ListAuditEntries200JSONResponse{toAuditEntriesResponse(page)}, nil
+}
+
+// GetUserHistory retrieves a page of the audit entries about a user.
+func (h *AuditHandler) GetUserHistory(
+	ctx context.Context,
+	request GetUserHistoryRequestObject,
+) (GetUserHistoryResponseObject, error) {
+	if h.service == nil {
+		return GetUserHistory403JSONResponse{ForbiddenJSONResponse{Error: errAuditDisabled}}, nil
+	}
+
+	params, ok := toAuditListParams(request.Params.Limit, request.Params.After)
+	if !ok {
+		return GetUserHistory400JSONResponse{BadRequestJSONResponse{Error: errInvalidCursor}}, nil
+	}
+
+	page, err := h.service.History(requestContext(ctx), request.Id, params)
+	switch {
+	case errors.Is(err, perrors.ErrForbidden):
+		return GetUserHistory403JSONResponse{ForbiddenJSONResponse{Error: err.Error()}}, nil
+	case err != nil:
+		return GetUserHistory500JSONResponse{internalError(ctx, err)}, nil
+	}
+
+	return GetUserHistory200JSONResponse{toAuditEntriesResponse(page)}, nil
+}
+
+// VerifyAuditLog checks the hash chain of the audit log.
+func (h *AuditHandler) VerifyAuditLog(
+	ctx context.Context,
+	_ VerifyAuditLogRequestObject,
+) (VerifyAuditLogResponseObject, error) {
+	if h.service == nil {
+		return VerifyAuditLog403JSONResponse{ForbiddenJSONResponse{Error: errAuditDisabled}}, nil
+	}
+
+	verification, err := h.service.Verify(requestContext(ctx))
+	switch {
+	case errors.Is(err, perrors.ErrForbidden):
+		return VerifyAuditLog403JSONResponse{ForbiddenJSONResponse{Error: err.Error()}}, nil
+	case err != nil:
+		return VerifyAuditLog500JSONResponse{internalError(ctx, err)}, nil
+	}
+
+	result := VerifyAuditLog200JSONResponse{Valid: verification.Valid(), Entries: verification.Entries}
+	if !verification.Valid() {
+		result.BrokenAt = &verification.BrokenAt.ID
+		result.Reason = &verification.Reason
+	}
+	return result, nil
+}
+
+// toAuditListParams parses the pagination parameters; cursors are
+// sequence numbers.
+func toAuditListParams(limit *PageLimit, after *AuditCursor) (app.AuditListParams, bool) {
+	var params app.AuditListParams
+	if limit != nil {
+		params.Limit = *limit
+	}
+	if after != nil && *after != "" {
+		sequence, err := strconv.ParseInt(*after, 10, 64)
+		if err != nil || sequence <= 0 {
+			return params, false
+		}
+		params.After = sequence
+	}
+	return params, true
+}
+
+func toAuditEntriesResponse(page *app.AuditPage) AuditEntriesJSONResponse {
+	response := AuditEntriesJSONResponse{Body: make([]AuditEntryJSON, len(page.Entries))}
+	for i, entry := range page.Entries {
+		response.Body[i] = toAuditEntryJSON(entry)
+	}
+	if page.HasNextPage {
+		response.Headers.XNextCursor = strconv.FormatInt(page.Entries[len(page.Entries)-1].Sequence, 10)
+	}
+	return response
+}
+
+func toAuditEntryJSON(entry *app.AuditEntry) AuditEntryJSON {
+	changes := make([]AuditChangeJSON, len(entry.Changes))
+	for i, change := range entry.Changes {
+		changes[i] = AuditChangeJSON{Field: change.Field}
+		if change.Before != nil {
+			changes[i].Before = &change.Before
+		}
+		if change.After != nil {
+			changes[i].After = &change.After
+		}
+	}
+	return AuditEntryJSON{
+		Id:            entry.ID,
+		Sequence:      entry.Sequence,
+		ActorType:     AuditEntryJSONActorType(entry.ActorKind),
+		ActorId:       entry.ActorID,
+		Action:        AuditAction(entry.Action),
+		TargetId:      entry.TargetID,
+		Changes:       changes,
+		ChangesErased: entry.ChangesErased(),
+		RequestId:     entry.RequestID,
+		ClientIp:      entry.ClientIP,
+		RequestErased: entry.RequestErased(),
+		CreatedAt:     entry.CreatedAt,
+		PrevHash:      entry.PrevHash,
+		Hash:          entry.Hash,
+	}
+}
@@ -0,0 +1,96 @@
+package v1
+
+import (
+	"context"
+	"errors"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	perrors "github.com/Sergey-Polishchenko/simple-api/internal/pkg/errors"
+)
+
+const errLoginDisabled = "password login is disabled"
+
+// AuthHandler handles password logins and sessions.
+type AuthHandler struct {
+	service app.AuthService
+}
+
+// NewAuthHandler initializes a new AuthHandler. With a nil service
+// password login is disabled and every request gets 404.
+func NewAuthHandler(service app.AuthService) *AuthHandler {
+	return &AuthHandler{service: service}
+}
+
+// Login processes password logins.
+func (h *AuthHandler) Login(
+	ctx context.Context,
+	request LoginRequestObject,
+) (LoginResponseObject, error) {
+	if h.service == nil {
+		return Login404JSONResponse{NotFoundJSONResponse{Error: errLoginDisabled}}, nil
+	}
+
+	credentials := app.Credentials{UserID: request.Body.UserId, Password: request.Body.Password}
+	if request.Body.MfaCode != nil {
+		credentials.MFACode = *request.Body.MfaCode
+	}
+
+	pair, err := h.service.Login(requestContext(ctx), credentials)
+	switch {
+	case errors.Is(err, perrors.ErrInvalidCredentials), errors.Is(err, perrors.ErrInvalidMFACode):
+		return Login401JSONResponse{InvalidCredentialsJSONResponse{Error: err.Error()}}, nil
+	case errors.Is(err, perrors.ErrMFARequired):
+		return Login403JSONResponse{MFARequiredJSONResponse{Error: err.Error()}}, nil
+	case errors.Is(err, perrors.ErrTooManyAttempts):
+		return Login429JSONResponse{TooManyRequestsJSONResponse{Error: err.Error()}}, nil
+	case err != nil:
+		return Login500JSONResponse{internalError(ctx, err)}, nil
+	}
+
+	return Login200JSONResponse(toTokenJSON(pair)), nil
+}
+
+// RefreshToken exchanges a refresh token for new tokens.
+func (h *AuthHandler) RefreshToken(
+	ctx context.Context,
+	request RefreshTokenRequestObject,
+) (RefreshTokenResponseObject, error) {
+	if h.service == nil {
+		return RefreshToken404JSONResponse{NotFoundJSONResponse{Error: errLoginDisabled}}, nil
+	}
+
+	pair, err := h.service.Refresh(requestContext(ctx), request.Body.RefreshToken)
+	if errors.Is(err, perrors.ErrInvalidRefreshToken) {
+		return RefreshToken401JSONResponse{InvalidCredentialsJSONResponse{Error: err.Error()}}, nil
+	}
+	if err != nil {
+		return RefreshToken500JSONResponse{internalError(ctx, err)}, nil
+	}
+
+	return RefreshToken200JSONResponse(toTokenJSON(pair)), nil
+}
+
+// Logout ends the session of a refresh token.
+func (h *AuthHandler) Logout(
+	ctx context.Context,
+	request LogoutRequestObject,
+) (LogoutResponseObject, error) {
+	if h.service == nil {
+		return Logout404JSONResponse{NotFoundJSONResponse{Error: errLoginDisabled}}, nil
+	}
+
+	if err := h.service.Logout(requestContext(ctx), request.Body.RefreshToken); err != nil {
+		return Logout500JSONResponse{internalError(ctx, err)}, nil
+	}
+
+	return Logout200JSONResponse{Message: "logged out"}, nil
+}
+
+func toTokenJSON(pair *app.TokenPair) TokenJSON {
+	return TokenJSON{
+		AccessToken:  pair.AccessToken,
+		TokenType:    Bearer,
+		ExpiresIn:    int(pair.AccessExpiresIn.Seconds()),
+		RefreshToken: pair.RefreshToken,
+	}
+}
@@ -0,0 +1,268 @@
+package v1
+
+import (
+	"context"
+	"errors"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	"github.com/Sergey-Polishchenko/simple-api/internal/domain"
+	perrors "github.com/Sergey-Polishchenko/simple-api/internal/pkg/errors"
+)
+
+const errGroupsDisabled = "groups are disabled"
+
+// GroupHandler handles HTTP requests related to groups and their members.
+type GroupHandler struct {
+	service app.GroupService
+}
+
+// NewGroupHandler initializes a new GroupHandler. With a nil service groups
+// are disabled and every request is forbidden.
+func NewGroupHandler(service app.GroupService) *GroupHandler {
+	return &GroupHandler{service: service}
+}
+
+// CreateGroup processes group creation requests.
+func (h *GroupHandler) CreateGroup(
+	ctx context.Context,
+	request CreateGroupRequestObject,
+) (CreateGroupResponseObject, error) {
+	if h.service == nil {
+		return CreateGroup403JSONResponse{ForbiddenJSONResponse{Error: errGroupsDisabled}}, nil
+	}
+
+	group, err := h.service.Create(requestContext(ctx), toDomainGroup("", *request.Body))
+	var invalid *app.InvalidGroupError
+	switch {
+	case errors.As(err, &invalid):
+		return CreateGroup400JSONResponse{BadRequestJSONResponse{Error: invalid.Error()}}, nil
+	case errors.Is(err, perrors.ErrForbidden):
+		return CreateGroup403JSONResponse{ForbiddenJSONResponse{Error: err.Error()}}, nil
+	case errors.Is(err, perrors.ErrGroupNameTaken):
+		return CreateGroup409JSONResponse{ConflictJSONResponse{Error: err.Error()}}, nil
+	case err != nil:
+		return CreateGroup500JSONResponse{internalError(ctx, err)}, nil
+	}
+
+	return CreateGroup201JSONResponse(toGroupJSON(group)), nil
+}
+
+// ListGroups retrieves all groups.
+func (h *GroupHandler) ListGroups(
+	ctx context.Context,
+	_ ListGroupsRequestObject,
+) (ListGroupsResponseObject, error) {
+	if h.service == nil {
+		return ListGroups403JSONResponse{ForbiddenJSONResponse{Error: errGroupsDisabled}}, nil
+	}
+
+	groups, err := h.service.List(requestContext(ctx))
+	if errors.Is(err, perrors.ErrForbidden) {
+		return ListGroups403JSONResponse{ForbiddenJSONResponse{Error: err.Error()}}, nil
+	}
+	if err != nil {
+		return ListGroups500JSONResponse{internalError(ctx, err)}, nil
+	}
+
+	return ListGroups200JSONResponse(toGroupsJSON(groups)), nil
+}
+
+// GetGroup retrieves a group by ID.
+func (h *GroupHandler) GetGroup(
+	ctx context.Context,
+	request GetGroupRequestObject,
+) (GetGroupResponseObject, error) {
+	if h.service == nil {
+		return GetGroup403JSONResponse{ForbiddenJSONResponse{Error: errGroupsDisabled}}, nil
+	}
+
+	group, err := h.service.Get(requestContext(ctx), request.Id)
+	switch {
+	case errors.Is(err, perrors.ErrGroupNotFound):
+		return GetGroup404JSONResponse{NotFoundJSONResponse{Error: err.Error()}}, nil
+	case errors.Is(err, perrors.ErrForbidden):
+		return GetGroup403JSONResponse{ForbiddenJSONResponse{Error: err.Error()}}, nil
+	case err != nil:
+		return GetGroup500JSONResponse{internalError(ctx, err)}, nil
+	}
+
+	return GetGroup200JSONResponse(toGroupJSON(group)), nil
+}
+
+// UpdateGroup updates the name and description of a group.
+func (h *GroupHandler) UpdateGroup(
+	ctx context.Context,
+	request UpdateGroupRequestObject,
+) (UpdateGroupResponseObject, error) {
+	if h.service == nil {
+		return UpdateGroup403JSONResponse{ForbiddenJSONResponse{Error: errGroupsDisabled}}, nil
+	}
+
+	err := h.service.Update(requestContext(ctx), toDomainGroup(request.Id, *request.Body))
+	var invalid *app.InvalidGroupError
+	switch {
+	case errors.As(err, &invalid):
+		return UpdateGroup400JSONResponse{BadRequestJSONResponse{Error: invalid.Error()}}, nil
+	case errors.Is(err, perrors.ErrGroupNotFound):
+		return UpdateGroup404JSONResponse{NotFoundJSONResponse{Error: err.Error()}}, nil
+	case errors.Is(err, perrors.ErrForbidden):
+		return UpdateGroup403JSONResponse{ForbiddenJSONResponse{Error: err.Error()}}, nil
+	case errors.Is(err, perrors.ErrGroupNameTaken):
+		return UpdateGroup409JSONResponse{ConflictJSONResponse{Error: err.Error()}}, nil
+	case err != nil:
+		return UpdateGroup500JSONResponse{internalError(ctx, err)}, nil
+	}
+
+	return UpdateGroup200JSONResponse{Message: "group updated"}, nil
+}
+
+// DeleteGroup deletes a group by ID.
+func (h *GroupHandler) DeleteGroup(
+	ctx context.Context,
+	request DeleteGroupRequestObject,
+) (DeleteGroupResponseObject, error) {
+	if h.service == nil {
+		return DeleteGroup403JSONResponse{ForbiddenJSONResponse{Error: errGroupsDisabled}}, nil
+	}
+
+	err := h.service.Remove(requestContext(ctx), request.Id)
+	switch {
+	case errors.Is(err, perrors.ErrGroupNotFound):
+		return DeleteGroup404JSONResponse{NotFoundJSONResponse{Error: err.Error()}}, nil
+	case errors.Is(err, perrors.ErrForbidden):
+		return DeleteGroup403JSONResponse{ForbiddenJSONResponse{Error: err.Error()}}, nil
+	case err != nil:
+		return DeleteGroup500JSONResponse{internalError(ctx, err)}, nil
+	}
+
+	return DeleteGroup200JSONResponse{Message: "group deleted"}, nil
+}
+
+// ListGroupMembers retrieves the direct members of a group.
+func (h *GroupHandler) ListGroupMembers(
+	ctx context.Context,
+	request ListGroupMembersRequestObject,
+) (ListGroupMembersResponseObject, error) {
+	if h.service == nil {
+		return ListGroupMembers403JSONResponse{ForbiddenJSONResponse{Error: errGroupsDisabled}}, nil
+	}
+
+	members, err := h.service.Members(requestContext(ctx), request.Id)
+	switch {
+	case errors.Is(err, perrors.ErrGroupNotFound):
+		return ListGroupMembers404JSONResponse{NotFoundJSONResponse{Error: err.Error()}}, nil
+	case errors.Is(err, perrors.ErrForbidden):
+		return ListGroupMembers403JSONResponse{ForbiddenJSONResponse{Error: err.Error()}}, nil
+	case err != nil:
+		return ListGroupMembers500JSONResponse{internalError(ctx, err)}, nil
+	}
+
+	return ListGroupMembers200JSONResponse{
+		Users:  toUsersJSON(members.Users),
+		Groups: toGroupsJSON(members.Groups),
+	}, nil
+}
+
+// AddGroupMember adds a user or a group to a group.
+func (h *GroupHandler) AddGroupMember(
+	ctx context.Context,
+	request AddGroupMemberRequestObject,
+) (AddGroupMemberResponseObject, error) {
+	if h.service == nil {
+		return AddGroupMember403JSONResponse{ForbiddenJSONResponse{Error: errGroupsDisabled}}, nil
+	}
+
+	var member app.GroupMember
+	if request.Body.UserId != nil {
+		member.UserID = *request.Body.UserId
+	}
+	if request.Body.GroupId != nil {
+		member.GroupID = *request.Body.GroupId
+	}
+
+	err := h.service.AddMember(requestContext(ctx), request.Id, member)
+	var invalid *app.InvalidGroupError
+	switch {
+	case errors.As(err, &invalid):
+		return AddGroupMember400JSONResponse{BadRequestJSONResponse{Error: invalid.Error()}}, nil
+	case errors.Is(err, perrors.ErrGroupNotFound), errors.Is(err, perrors.ErrUserNotFound):
+		return AddGroupMember404JSONResponse{NotFoundJSONResponse{Error: err.Error()}}, nil
+	case errors.Is(err, perrors.ErrForbidden):
+		return AddGroupMember403JSONResponse{ForbiddenJSONResponse{Error: err.Error()}}, nil
+	case errors.Is(err, perrors.ErrGroupCycle):
+		return AddGroupMember409JSONResponse{ConflictJSONResponse{Error: err.Error()}}, nil
+	case err != nil:
+		return AddGroupMember500JSONResponse{internalError(ctx, err)}, nil
+	}
+
+	return AddGroupMember200JSONResponse{Message: "member added"}, nil
+}
+
+// RemoveGroupMember removes a direct member from a group.
+func (h *GroupHandler) RemoveGroupMember(
+	ctx context.Context,
+	request RemoveGroupMemberRequestObject,
+) (RemoveGroupMemberResponseObject, error) {
+	if h.service == nil {
+		return RemoveGroupMember403JSONResponse{ForbiddenJSONResponse{Error: errGroupsDisabled}}, nil
+	}
+
+	err := h.service.RemoveMember(requestContext(ctx), request.Id, request.MemberId)
+	switch {
+	case errors.Is(err, perrors.ErrGroupNotFound), errors.Is(err, perrors.ErrGroupMemberNotFound):
+		return RemoveGroupMember404JSONResponse{NotFoundJSONResponse{Error: err.Error()}}, nil
+	case errors.Is(err, perrors.ErrForbidden):
+		return RemoveGroupMember403JSONResponse{ForbiddenJSONResponse{Error: err.Error()}}, nil
+	case err != nil:
+		return RemoveGroupMember500JSONResponse{internalError(ctx, err)}, nil
+	}
+
+	return RemoveGroupMember200JSONResponse{Message: "member removed"}, nil
+}
+
+// GetUserGroups retrieves the groups a user belongs to.
+func (h *GroupHandler) GetUserGroups(
+	ctx context.Context,
+	request GetUserGroupsRequestObject,
+) (GetUserGroupsResponseObject, error) {
+	if h.service == nil {
+		return GetUserGroups403JSONResponse{ForbiddenJSONResponse{Error: errGroupsDisabled}}, nil
+	}
+
+	direct := request.Params.Direct != nil && *request.Params.Direct
+	groups, err := h.service.UserGroups(requestContext(ctx), request.Id, direct)
+	switch {
+	case errors.Is(err, perrors.ErrUserNotFound):
+		return GetUserGroups404JSONResponse{NotFoundJSONResponse{Error: err.Error()}}, nil
+	case errors.Is(err, perrors.ErrForbidden):
+		return GetUserGroups403JSONResponse{ForbiddenJSONResponse{Error: err.Error()}}, nil
+	case err != nil:
+		return GetUserGroups500JSONResponse{internalError(ctx, err)}, nil
+	}
+
+	return GetUserGroups200JSONResponse(toGroupsJSON(groups)), nil
+}
+
+func toDomainGroup(id string, body GroupInputJSON) *domain.Group {
+	var description string
+	if body.Description != nil {
+		description = *body.Description
+	}
+	return domain.NewGroup(id, body.Name, description)
+}
+
+func toGroupsJSON(groups []*domain.Group) []GroupJSON {
+	result := make([]GroupJSON, len(groups))
+	for i, group := range groups {
+		result[i] = toGroupJSON(group)
+	}
+	return result
+}
+
+func toGroupJSON(group *domain.Group) GroupJSON {
+	return GroupJSON{
+		Id:          group.ID(),
+		Name:        group.Name(),
+		Description: group.Description(),
+	}
+}
@@ -0,0 +1,82 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	perrors "github.com/Sergey-Polishchenko/simple-api/internal/pkg/errors"
+)
+
+// ListUserVersions retrieves a page of the versions of a user.
+func (h *UserHandler) ListUserVersions(
+	ctx context.Context,
+	request ListUserVersionsRequestObject,
+) (ListUserVersionsResponseObject, error) {
+	var params app.VersionListParams
+	if request.Params.Limit != nil {
+		params.Limit = *request.Params.Limit
+	}
+	if request.Params.After != nil && *request.Params.After != "" {
+		after, err := strconv.Atoi(*request.Params.After)
+		if err != nil || after <= 0 {
+			return ListUserVersions400JSONResponse{BadRequestJSONResponse{Error: errInvalidCursor}}, nil
+		}
+		params.After = after
+	}
+
+	page, err := h.service.Versions(requestContext(ctx), request.Id, params)
+	switch {
+	case errors.Is(err, perrors.ErrUserNotFound):
+		return ListUserVersions404JSONResponse{NotFoundJSONResponse{Error: err.Error()}}, nil
+	case errors.Is(err, perrors.ErrForbidden), errors.Is(err, perrors.ErrHistoryDisabled):
+		return ListUserVersions403JSONResponse{ForbiddenJSONResponse{Error: err.Error()}}, nil
+	case err != nil:
+		return ListUserVersions500JSONResponse{internalError(ctx, err)}, nil
+	}
+
+	response := ListUserVersions200JSONResponse{Body: make([]UserVersionJSON, len(page.Versions))}
+	for i, version := range page.Versions {
+		response.Body[i] = toUserVersionJSON(version)
+	}
+	if page.HasNextPage {
+		response.Headers.XNextCursor = strconv.Itoa(page.Versions[len(page.Versions)-1].Version)
+	}
+	return response, nil
+}
+
+// RevertUser restores a user to one of its versions.
+func (h *UserHandler) RevertUser(
+	ctx context.Context,
+	request RevertUserRequestObject,
+) (RevertUserResponseObject, error) {
+	user, err := h.service.Revert(requestContext(ctx), request.Id, request.Version)
+	var invalidAttributes *app.InvalidAttributesError
+	switch {
+	case errors.As(err, &invalidAttributes):
+		return RevertUser400JSONResponse{BadRequestJSONResponse{Error: invalidAttributes.Error()}}, nil
+	case errors.Is(err, perrors.ErrUserNotFound), errors.Is(err, perrors.ErrUserVersionNotFound):
+		return RevertUser404JSONResponse{NotFoundJSONResponse{Error: err.Error()}}, nil
+	case errors.Is(err, perrors.ErrEmailTaken):
+		return RevertUser409JSONResponse{ConflictJSONResponse{Error: err.Error()}}, nil
+	case errors.Is(err, perrors.ErrForbidden), errors.Is(err, perrors.ErrHistoryDisabled):
+		return RevertUser403JSONResponse{ForbiddenJSONResponse{Error: err.Error()}}, nil
+	case err != nil:
+		return RevertUser500JSONResponse{internalError(ctx, err)}, nil
+	}
+
+	return RevertUser200JSONResponse(toUserJSON(user)), nil
+}
+
+func toUserVersionJSON(version *app.UserVersion) UserVersionJSON {
+	result := UserVersionJSON{
+		Version:   version.Version,
+		User:      toUserJSON(version.User),
+		ValidFrom: version.ValidFrom,
+	}
+	if !version.Current() {
+		result.ValidTo = &version.ValidTo
+	}
+	return result
+}
@@ -0,0 +1,114 @@
+package v1
+
+import (
+	"context"
+	"errors"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	perrors "github.com/Sergey-Polishchenko/simple-api/internal/pkg/errors"
+)
+
+const errMFADisabled = "mfa is disabled"
+
+// MFAHandler handles HTTP requests related to second factors.
+type MFAHandler struct {
+	service app.MFAService
+}
+
+// NewMFAHandler initializes a new MFAHandler. With a nil service MFA is
+// disabled and every request is forbidden.
+func NewMFAHandler(service app.MFAService) *MFAHandler {
+	return &MFAHandler{service: service}
+}
+
+// GetMFAStatus describes the second factor of a user.
+func (h *MFAHandler) GetMFAStatus(
+	ctx context.Context,
+	request GetMFAStatusRequestObject,
+) (GetMFAStatusResponseObject, error) {
+	if h.service == nil {
+		return GetMFAStatus403JSONResponse{ForbiddenJSONResponse{Error: errMFADisabled}}, nil
+	}
+
+	status, err := h.service.Status(requestContext(ctx), request.Id)
+	if errors.Is(err, perrors.ErrForbidden) {
+		return GetMFAStatus403JSONResponse{ForbiddenJSONResponse{Error: err.Error()}}, nil
+	}
+	if err != nil {
+		return GetMFAStatus500JSONResponse{internalError(ctx, err)}, nil
+	}
+
+	return GetMFAStatus200JSONResponse{Enabled: status.Enabled, RecoveryCodesLeft: status.RecoveryCodesLeft}, nil
+}
+
+// EnrollMFA starts the enrollment of a TOTP second factor.
+func (h *MFAHandler) EnrollMFA(
+	ctx context.Context,
+	request EnrollMFARequestObject,
+) (EnrollMFAResponseObject, error) {
+	if h.service == nil {
+		return EnrollMFA403JSONResponse{ForbiddenJSONResponse{Error: errMFADisabled}}, nil
+	}
+
+	setup, err := h.service.Enroll(requestContext(ctx), request.Id)
+	switch {
+	case errors.Is(err, perrors.ErrForbidden):
+		return EnrollMFA403JSONResponse{ForbiddenJSONResponse{Error: err.Error()}}, nil
+	case errors.Is(err, perrors.ErrMFAEnabled):
+		return EnrollMFA409JSONResponse{ConflictJSONResponse{Error: err.Error()}}, nil
+	case err != nil:
+		return EnrollMFA500JSONResponse{internalError(ctx, err)}, nil
+	}
+
+	return EnrollMFA200JSONResponse{Secret: setup.Secret, OtpauthUri: setup.URI}, nil
+}
+
+// ConfirmMFA enables MFA with a code of the enrolled secret.
+func (h *MFAHandler) ConfirmMFA(
+	ctx context.Context,
+	request ConfirmMFARequestObject,
+) (ConfirmMFAResponseObject, error) {
+	if h.service == nil {
+		return ConfirmMFA403JSONResponse{ForbiddenJSONResponse{Error: errMFADisabled}}, nil
+	}
+
+	codes, err := h.service.Confirm(requestContext(ctx), request.Id, request.Body.Code)
+	switch {
+	case errors.Is(err, perrors.ErrInvalidMFACode):
+		return ConfirmMFA400JSONResponse{BadRequestJSONResponse{Error: err.Error()}}, nil
+	case errors.Is(err, perrors.ErrForbidden):
+		return ConfirmMFA403JSONResponse{ForbiddenJSONResponse{Error: err.Error()}}, nil
+	case errors.Is(err, perrors.ErrMFANotEnrolled):
+		return ConfirmMFA404JSONResponse{NotFoundJSONResponse{Error: err.Error()}}, nil
+	case errors.Is(err, perrors.ErrMFAEnabled):
+		return ConfirmMFA409JSONResponse{ConflictJSONResponse{Error: err.Error()}}, nil
+	case errors.Is(err, perrors.ErrTooManyAttempts):
+		return ConfirmMFA429JSONResponse{TooManyRequestsJSONResponse{Error: err.Error()}}, nil
+	case err != nil:
+		return ConfirmMFA500JSONResponse{internalError(ctx, err)}, nil
+	}
+
+	return ConfirmMFA200JSONResponse{RecoveryCodes: codes}, nil
+}
+
+// ResetMFA disables the second factor of a user.
+func (h *MFAHandler) ResetMFA(
+	ctx context.Context,
+	request ResetMFARequestObject,
+) (ResetMFAResponseObject, error) {
+	if h.service == nil {
+		return ResetMFA403JSONResponse{ForbiddenJSONResponse{Error: errMFADisabled}}, nil
+	}
+
+	err := h.service.Reset(requestContext(ctx), request.Id)
+	switch {
+	case errors.Is(err, perrors.ErrMFANotEnrolled):
+		return ResetMFA404JSONResponse{NotFoundJSONResponse{Error: err.Error()}}, nil
+	case errors.Is(err, perrors.ErrForbidden):
+		return ResetMFA403JSONResponse{ForbiddenJSONResponse{Error: err.Error()}}, nil
+	case err != nil:
+		return ResetMFA500JSONResponse{internalError(ctx, err)}, nil
+	}
+
+	return ResetMFA200JSONResponse{Message: "mfa reset"}, nil
+}
@@ -0,0 +1,136 @@
+package v1
+
+import (
+	"context"
+	"errors"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	perrors "github.com/Sergey-Polishchenko/simple-api/internal/pkg/errors"
+)
+
+const errOAuthClientsDisabled = "oauth clients are disabled"
+
+// OAuthClientHandler handles HTTP requests related to OAuth clients.
+type OAuthClientHandler struct {
+	service app.OAuthClientService
+}
+
+// NewOAuthClientHandler initializes a new OAuthClientHandler. With a nil
+// service the OpenID Connect provider is disabled and every request is forbidden.
+func NewOAuthClientHandler(service app.OAuthClientService) *OAuthClientHandler {
+	return &OAuthClientHandler{service: service}
+}
+
+// RegisterOAuthClient processes OAuth client registrations.
+func (h *OAuthClientHandler) RegisterOAuthClient(
+	ctx context.Context,
+	request RegisterOAuthClientRequestObject,
+) (RegisterOAuthClientResponseObject, error) {
+	if h.service == nil {
+		return RegisterOAuthClient403JSONResponse{ForbiddenJSONResponse{Error: errOAuthClientsDisabled}}, nil
+	}
+
+	params := app.RegisterOAuthClientParams{
+		Name:         request.Body.Name,
+		Confidential: request.Body.Confidential,
+	}
+	if request.Body.RedirectUris != nil {
+		params.RedirectURIs = *request.Body.RedirectUris
+	}
+	if request.Body.Scopes != nil {
+		for _, scope := range *request.Body.Scopes {
+			params.Scopes = append(params.Scopes, string(scope))
+		}
+	}
+
+	client, secret, err := h.service.Register(requestContext(ctx), params)
+	var invalid *app.InvalidOAuthClientParamsError
+	switch {
+	case errors.As(err, &invalid):
+		return RegisterOAuthClient400JSONResponse{BadRequestJSONResponse{Error: invalid.Error()}}, nil
+	case errors.Is(err, perrors.ErrForbidden):
+		return RegisterOAuthClient403JSONResponse{ForbiddenJSONResponse{Error: err.Error()}}, nil
+	case err != nil:
+		return RegisterOAuthClient500JSONResponse{internalError(ctx, err)}, nil
+	}
+
+	clientJSON := toOAuthClientJSON(client)
+	response := RegisterOAuthClient201JSONResponse{
+		Id:           clientJSON.Id,
+		Name:         clientJSON.Name,
+		RedirectUris: clientJSON.RedirectUris,
+		Scopes:       clientJSON.Scopes,
+		Confidential: clientJSON.Confidential,
+		CreatedAt:    clientJSON.CreatedAt,
+	}
+	if secret != "" {
+		response.Secret = &secret
+	}
+	return response, nil
+}
+
+// ListOAuthClients retrieves all OAuth clients.
+func (h *OAuthClientHandler) ListOAuthClients(
+	ctx context.Context,
+	_ ListOAuthClientsRequestObject,
+) (ListOAuthClientsResponseObject, error) {
+	if h.service == nil {
+		return ListOAuthClients403JSONResponse{ForbiddenJSONResponse{Error: errOAuthClientsDisabled}}, nil
+	}
+
+	clients, err := h.service.List(requestContext(ctx))
+	if errors.Is(err, perrors.ErrForbidden) {
+		return ListOAuthClients403JSONResponse{ForbiddenJSONResponse{Error: err.Error()}}, nil
+	}
+	if err != nil {
+		return ListOAuthClients500JSONResponse{internalError(ctx, err)}, nil
+	}
+
+	response := make(ListOAuthClients200JSONResponse, len(clients))
+	for i, client := range clients {
+		response[i] = toOAuthClientJSON(client)
+	}
+	return response, nil
+}
+
+// RemoveOAuthClient removes an OAuth client by ID.
+func (h *OAuthClientHandler) RemoveOAuthClient(
+	ctx context.Context,
+	request RemoveOAuthClientRequestObject,
+) (RemoveOAuthClientResponseObject, error) {
+	if h.service == nil {
+		return RemoveOAuthClient403JSONResponse{ForbiddenJSONResponse{Error: errOAuthClientsDisabled}}, nil
+	}
+
+	err := h.service.Remove(requestContext(ctx), request.Id)
+	switch {
+	case errors.Is(err, perrors.ErrOAuthClientNotFound):
+		return RemoveOAuthClient404JSONResponse{NotFoundJSONResponse{Error: err.Error()}}, nil
+	case errors.Is(err, perrors.ErrForbidden):
+		return RemoveOAuthClient403JSONResponse{ForbiddenJSONResponse{Error: err.Error()}}, nil
+	case err != nil:
+		return RemoveOAuthClient500JSONResponse{internalError(ctx, err)}, nil
+	}
+
+	return RemoveOAuthClient200JSONResponse{Message: "oauth client removed"}, nil
+}
+
+func toOAuthClientJSON(client *app.OAuthClient) OAuthClientJSON {
+	scopes := make([]APIKeyScope, len(client.Scopes))
+	for i, scope := range client.Scopes {
+		scopes[i] = APIKeyScope(scope)
+	}
+	redirectURIs := client.RedirectURIs
+	if redirectURIs == nil {
+		redirectURIs = []string{}
+	}
+
+	return OAuthClientJSON{
+		Id:           client.ID,
+		Name:         client.Name,
+		RedirectUris: redirectURIs,
+		Scopes:       scopes,
+		Confidential: client.Confidential(),
+		CreatedAt:    client.CreatedAt,
+	}
+}
@@ -0,0 +1,131 @@
+package v1
+
+import (
+	"context"
+	"errors"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	perrors "github.com/Sergey-Polishchenko/simple-api/internal/pkg/errors"
+)
+
+const errPrivacyDisabled = "data subject requests are disabled"
+
+// PrivacyHandler handles HTTP requests related to the export and the
+// erasure of the data stored about users.
+type PrivacyHandler struct {
+	service app.PrivacyService
+}
+
+// NewPrivacyHandler initializes a new PrivacyHandler. With a nil service
+// data subject requests are disabled and every request is forbidden.
+func NewPrivacyHandler(service app.PrivacyService) *PrivacyHandler {
+	return &PrivacyHandler{service: service}
+}
+
+// ExportUser collects everything stored about a user.
+func (h *PrivacyHandler) ExportUser(
+	ctx context.Context,
+	request ExportUserRequestObject,
+) (ExportUserResponseObject, error) {
+	if h.service == nil {
+		return ExportUser403JSONResponse{ForbiddenJSONResponse{Error: errPrivacyDisabled}}, nil
+	}
+
+	export, err := h.service.Export(requestContext(ctx), request.Id)
+	switch {
+	case errors.Is(err, perrors.ErrUserNotFound):
+		return ExportUser404JSONResponse{NotFoundJSONResponse{Error: err.Error()}}, nil
+	case errors.Is(err, perrors.ErrForbidden):
+		return ExportUser403JSONResponse{ForbiddenJSONResponse{Error: err.Error()}}, nil
+	case err != nil:
+		return ExportUser500JSONResponse{internalError(ctx, err)}, nil
+	}
+
+	response := ExportUser200JSONResponse{
+		ExportedAt:   export.ExportedAt,
+		User:         toUserJSON(export.User),
+		Versions:     make([]UserVersionJSON, len(export.Versions)),
+		Groups:       make([]GroupMembershipJSON, len(export.Groups)),
+		Sessions:     make([]SessionJSON, len(export.Sessions)),
+		Mfa:          MFAStatusJSON{Enabled: export.MFA.Enabled, RecoveryCodesLeft: export.MFA.RecoveryCodesLeft},
+		AuditEntries: make([]AuditEntryJSON, len(export.AuditEntries)),
+	}
+	for i, version := range export.Versions {
+		response.Versions[i] = toUserVersionJSON(version)
+	}
+	for i, membership := range export.Groups {
+		response.Groups[i] = GroupMembershipJSON{Group: toGroupJSON(membership.Group), Direct: membership.Direct}
+	}
+	for i, session := range export.Sessions {
+		response.Sessions[i] = SessionJSON{
+			Id:          session.ID,
+			StartedAt:   session.StartedAt,
+			RefreshedAt: session.RefreshedAt,
+			ExpiresAt:   session.ExpiresAt,
+			RevokedAt:   session.RevokedAt,
+		}
+	}
+	for i, entry := range export.AuditEntries {
+		response.AuditEntries[i] = toAuditEntryJSON(entry)
+	}
+	return response, nil
+}
+
+// EraseUser erases a user and returns the receipt of the erasure.
+func (h *PrivacyHandler) EraseUser(
+	ctx context.Context,
+	request EraseUserRequestObject,
+) (EraseUserResponseObject, error) {
+	if h.service == nil {
+		return EraseUser403JSONResponse{ForbiddenJSONResponse{Error: errPrivacyDisabled}}, nil
+	}
+
+	receipt, err := h.service.Erase(requestContext(ctx), request.Id)
+	switch {
+	case errors.Is(err, perrors.ErrUserNotFound):
+		return EraseUser404JSONResponse{NotFoundJSONResponse{Error: err.Error()}}, nil
+	case errors.Is(err, perrors.ErrForbidden):
+		return EraseUser403JSONResponse{ForbiddenJSONResponse{Error: err.Error()}}, nil
+	case err != nil:
+		return EraseUser500JSONResponse{internalError(ctx, err)}, nil
+	}
+
+	return EraseUser201JSONResponse(toErasureReceiptJSON(receipt)), nil
+}
+
+// GetErasureReceipt fetches the receipt of an erasure.
+func (h *PrivacyHandler) GetErasureReceipt(
+	ctx context.Context,
+	request GetErasureReceiptRequestObject,
+) (GetErasureReceiptResponseObject, error) {
+	if h.service == nil {
+		return GetErasureReceipt403JSONResponse{ForbiddenJSONResponse{Error: errPrivacyDisabled}}, nil
+	}
+
+	receipt, err := h.service.Receipt(requestContext(ctx), request.Id)
+	switch {
+	case errors.Is(err, perrors.ErrErasureReceiptNotFound):
+		return GetErasureReceipt404JSONResponse{NotFoundJSONResponse{Error: err.Error()}}, nil
+	case errors.Is(err, perrors.ErrForbidden):
+		return GetErasureReceipt403JSONResponse{ForbiddenJSONResponse{Error: err.Error()}}, nil
+	case err != nil:
+		return GetErasureReceipt500JSONResponse{internalError(ctx, err)}, nil
+	}
+
+	return GetErasureReceipt200JSONResponse(toErasureReceiptJSON(receipt)), nil
+}
+
+func toErasureReceiptJSON(receipt *app.ErasureReceipt) ErasureReceiptJSON {
+	return ErasureReceiptJSON{
+		Id:                   receipt.ID,
+		UserId:               receipt.UserID,
+		ActorType:            ErasureReceiptJSONActorType(receipt.ActorKind),
+		ActorId:              receipt.ActorID,
+		ErasedAt:             receipt.ErasedAt,
+		Versions:             receipt.Versions,
+		Sessions:             receipt.Sessions,
+		GroupMemberships:     receipt.GroupMemberships,
+		Mfa:                  receipt.MFA,
+		AuditEntriesRetained: receipt.AuditEntriesRetained,
+	}
+}
@@ -1,14 +1,103 @@
 package v1
 
-import "github.com/gin-gonic/gin"
+import (
+	"context"
+	"net/http"
 
-func RegisterRoutes(router *gin.Engine, userHandler *UserHandler) {
-	v1 := router.Group("/api/v1")
-	{
-		v1.POST("/users", userHandler.CreateUser)
-		v1.GET("/users", userHandler.GetUsers)
-		v1.GET("/users/:id", userHandler.GetUser)
-		v1.PUT("/users/:id", userHandler.UpdateUser)
-		v1.DELETE("/users/:id", userHandler.RemoveUser)
+	"github.com/gin-gonic/gin"
+)
+
+// server serves every v1 operation by delegating to the resource handlers.
+type server struct {
+	*UserHandler
+	*APIKeyHandler
+	*AuthHandler
+	*OAuthClientHandler
+	*MFAHandler
+	*AccountHandler
+	*TenantHandler
+	*GroupHandler
+	*AttributeHandler
+	*AuditHandler
+	*PrivacyHandler
+}
+
+// Handlers holds the resource handlers served under /api/v1. Users is
+// required; any other handler left nil is built with a nil service, which
+// disables its operations as its constructor describes.
+type Handlers struct {
+	Users        *UserHandler
+	APIKeys      *APIKeyHandler
+	Auth         *AuthHandler
+	OAuthClients *OAuthClientHandler
+	MFA          *MFAHandler
+	Accounts     *AccountHandler
+	Tenants      *TenantHandler
+	Groups       *GroupHandler
+	Attributes   *AttributeHandler
+	Audit        *AuditHandler
+	Privacy      *PrivacyHandler
+}
+
+// RegisterRoutes mounts the v1 operations on router.
+func RegisterRoutes(router *gin.Engine, handlers Handlers) {
+	v1 := router.Group("", renderErrors)
+	RegisterHandlers(v1, NewStrictHandler(handlers.server(), nil))
+}
+
+// server fills in the handlers that were left out.
+func (h Handlers) server() server {
+	return server{
+		UserHandler:        h.Users,
+		APIKeyHandler:      orDefault(h.APIKeys, NewAPIKeyHandler),
+		AuthHandler:        orDefault(h.Auth, NewAuthHandler),
+		OAuthClientHandler: orDefault(h.OAuthClients, NewOAuthClientHandler),
+		MFAHandler:         orDefault(h.MFA, NewMFAHandler),
+		AccountHandler:     orDefault(h.Accounts, NewAccountHandler),
+		TenantHandler:      orDefault(h.Tenants, NewTenantHandler),
+		GroupHandler:       orDefault(h.Groups, NewGroupHandler),
+		AttributeHandler:   orDefault(h.Attributes, NewAttributeHandler),
+		AuditHandler:       orDefault(h.Audit, NewAuditHandler),
+		PrivacyHandler:     orDefault(h.Privacy, NewPrivacyHandler),
 	}
 }
+
+// orDefault returns handler, or a handler without a service if it is nil.
+func orDefault[H any, S any](handler *H, disabled func(S) *H) *H {
+	if handler != nil {
+		return handler
+	}
+	var none S
+	return disabled(none)
+}
+
+// renderErrors writes a JSON body for failures the generated
+// handlers report only through the status code.
+func renderErrors(c *gin.Context) {
+	c.Next()
+
+	if c.Writer.Written() || len(c.Errors) == 0 {
+		return
+	}
+
+	switch status := c.Writer.Status(); status {
+	case http.StatusBadRequest:
+		c.JSON(status, gin.H{"error": "invalid request"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": errInternal})
+	}
+}
+
+// errInternal is the message of every 500 response.
+const errInternal = "internal server error"
+
+// internalError hides the cause of a failure from the client, as it may
+// hold SQL or connection strings, and leaves it to the request log.
+func internalError(ctx context.Context, err error) InternalErrorJSONResponse {
+	if c, ok := ctx.(*gin.Context); ok {
+		_ = c.Error(err)
+	}
+	return InternalErrorJSONResponse{Error: errInternal}
+}
+
+var _ StrictServerInterface = server{}
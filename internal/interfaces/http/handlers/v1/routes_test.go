@@ -0,0 +1,66 @@
+package v1_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Sergey-Polishchenko/simple-api/api/openapi"
+	"github.com/Sergey-Polishchenko/simple-api/internal/interfaces/http/handlers/mocks"
+	v1 "github.com/Sergey-Polishchenko/simple-api/internal/interfaces/http/handlers/v1"
+)
+
+func TestRegisterRoutes_DocumentedInSpec(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	spec, err := openapi.Load()
+	require.NoError(t, err)
+
+	router := gin.New()
+	v1.RegisterRoutes(router, v1.Handlers{Users: v1.NewUserHandler(new(mocks.MockUserService))})
+
+	routes := router.Routes()
+	require.NotEmpty(t, routes)
+
+	for _, route := range routes {
+		path := ginPathToOpenAPI(route.Path)
+
+		item := spec.Paths.Value(path)
+		if !assert.NotNil(t, item, "path %s is missing from the spec", path) {
+			continue
+		}
+		assert.NotNil(t, item.GetOperation(route.Method),
+			"operation %s %s is missing from the spec", route.Method, path)
+	}
+}
+
+func TestRegisterRoutes_OmittedHandlersDisabled(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	v1.RegisterRoutes(router, v1.Handlers{Users: v1.NewUserHandler(new(mocks.MockUserService))})
+
+	for _, path := range []string{"/api/v1/audit", "/api/v1/groups", "/api/v1/api-keys", "/api/v1/erasures/1"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+
+		assert.Equal(t, http.StatusForbidden, w.Code, path)
+		assert.Contains(t, w.Body.String(), "disabled", path)
+	}
+}
+
+// ginPathToOpenAPI converts "/users/:id" into "/users/{id}".
+func ginPathToOpenAPI(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
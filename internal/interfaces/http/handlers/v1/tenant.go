@@ -0,0 +1,128 @@
+package v1
+
+import (
+	"context"
+	"errors"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	perrors "github.com/Sergey-Polishchenko/simple-api/internal/pkg/errors"
+)
+
+const errTenancyDisabled = "multi-tenancy is disabled"
+
+// TenantHandler handles HTTP requests related to tenants.
+type TenantHandler struct {
+	service app.TenantService
+}
+
+// NewTenantHandler initializes a new TenantHandler. With a nil service
+// multi-tenancy is disabled and every request is forbidden.
+func NewTenantHandler(service app.TenantService) *TenantHandler {
+	return &TenantHandler{service: service}
+}
+
+// CreateTenant processes tenant creation requests.
+func (h *TenantHandler) CreateTenant(
+	ctx context.Context,
+	request CreateTenantRequestObject,
+) (CreateTenantResponseObject, error) {
+	if h.service == nil {
+		return CreateTenant403JSONResponse{ForbiddenJSONResponse{Error: errTenancyDisabled}}, nil
+	}
+
+	tenant, err := h.service.Create(requestContext(ctx), &app.Tenant{ID: request.Body.Id, Name: request.Body.Name})
+	var invalid *app.InvalidTenantError
+	switch {
+	case errors.As(err, &invalid):
+		return CreateTenant400JSONResponse{BadRequestJSONResponse{Error: invalid.Error()}}, nil
+	case errors.Is(err, perrors.ErrForbidden):
+		return CreateTenant403JSONResponse{ForbiddenJSONResponse{Error: err.Error()}}, nil
+	case errors.Is(err, perrors.ErrTenantExists):
+		return CreateTenant409JSONResponse{ConflictJSONResponse{Error: err.Error()}}, nil
+	case err != nil:
+		return CreateTenant500JSONResponse{internalError(ctx, err)}, nil
+	}
+
+	return CreateTenant201JSONResponse(toTenantJSON(tenant)), nil
+}
+
+// ListTenants retrieves all tenants.
+func (h *TenantHandler) ListTenants(
+	ctx context.Context,
+	_ ListTenantsRequestObject,
+) (ListTenantsResponseObject, error) {
+	if h.service == nil {
+		return ListTenants403JSONResponse{ForbiddenJSONResponse{Error: errTenancyDisabled}}, nil
+	}
+
+	tenants, err := h.service.List(requestContext(ctx))
+	if errors.Is(err, perrors.ErrForbidden) {
+		return ListTenants403JSONResponse{ForbiddenJSONResponse{Error: err.Error()}}, nil
+	}
+	if err != nil {
+		return ListTenants500JSONResponse{internalError(ctx, err)}, nil
+	}
+
+	response := make(ListTenants200JSONResponse, len(tenants))
+	for i, tenant := range tenants {
+		response[i] = toTenantJSON(tenant)
+	}
+	return response, nil
+}
+
+// GetTenant retrieves a tenant by ID.
+func (h *TenantHandler) GetTenant(
+	ctx context.Context,
+	request GetTenantRequestObject,
+) (GetTenantResponseObject, error) {
+	if h.service == nil {
+		return GetTenant403JSONResponse{ForbiddenJSONResponse{Error: errTenancyDisabled}}, nil
+	}
+
+	tenant, err := h.service.Get(requestContext(ctx), request.Id)
+	switch {
+	case errors.Is(err, perrors.ErrTenantNotFound):
+		return GetTenant404JSONResponse{NotFoundJSONResponse{Error: err.Error()}}, nil
+	case errors.Is(err, perrors.ErrForbidden):
+		return GetTenant403JSONResponse{ForbiddenJSONResponse{Error: err.Error()}}, nil
+	case err != nil:
+		return GetTenant500JSONResponse{internalError(ctx, err)}, nil
+	}
+
+	return GetTenant200JSONResponse(toTenantJSON(tenant)), nil
+}
+
+// RemoveTenant removes a tenant by ID.
+func (h *TenantHandler) RemoveTenant(
+	ctx context.Context,
+	request RemoveTenantRequestObject,
+) (RemoveTenantResponseObject, error) {
+	if h.service == nil {
+		return RemoveTenant403JSONResponse{ForbiddenJSONResponse{Error: errTenancyDisabled}}, nil
+	}
+
+	err := h.service.Remove(requestContext(ctx), request.Id)
+	var invalid *app.InvalidTenantError
+	switch {
+	case errors.As(err, &invalid):
+		return RemoveTenant400JSONResponse{BadRequestJSONResponse{Error: invalid.Error()}}, nil
+	case errors.Is(err, perrors.ErrTenantNotFound):
+		return RemoveTenant404JSONResponse{NotFoundJSONResponse{Error: err.Error()}}, nil
+	case errors.Is(err, perrors.ErrForbidden):
+		return RemoveTenant403JSONResponse{ForbiddenJSONResponse{Error: err.Error()}}, nil
+	case errors.Is(err, perrors.ErrTenantNotEmpty):
+		return RemoveTenant409JSONResponse{ConflictJSONResponse{Error: err.Error()}}, nil
+	case err != nil:
+		return RemoveTenant500JSONResponse{internalError(ctx, err)}, nil
+	}
+
+	return RemoveTenant200JSONResponse{Message: "tenant removed"}, nil
+}
+
+func toTenantJSON(tenant *app.Tenant) TenantJSON {
+	return TenantJSON{
+		Id:        tenant.ID,
+		Name:      tenant.Name,
+		CreatedAt: tenant.CreatedAt,
+	}
+}
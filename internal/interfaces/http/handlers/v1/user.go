@@ -1,28 +1,21 @@
 // Package handlers defines request handlers for the HTTP API.
 package v1
 
+//go:generate go run github.com/oapi-codegen/oapi-codegen/v2/cmd/oapi-codegen -config oapi-codegen.yaml ../../../../../api/openapi/api.yml
+
 import (
-	"net/http"
+	"context"
+	"errors"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 
 	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
 	"github.com/Sergey-Polishchenko/simple-api/internal/domain"
+	perrors "github.com/Sergey-Polishchenko/simple-api/internal/pkg/errors"
 )
 
-// JSON structures for HTTP request/response handling.
-type CreateUserJSON struct {
-	Name string `json:"name"`
-}
-
-type UpdateUserJSON struct {
-	Name string `json:"name"`
-}
-
-type UserJSON struct {
-	ID   string `json:"id"`
-	Name string `json:"name"`
-}
+const errNameRequired = "name is required"
 
 // UserHandler handles HTTP requests related to users.
 type UserHandler struct {
@@ -35,98 +28,265 @@ func NewUserHandler(service app.UserService) *UserHandler {
 }
 
 // CreateUser processes user creation requests.
-func (h *UserHandler) CreateUser(c *gin.Context) {
-	var createUser CreateUserJSON
-	if err := c.ShouldBindJSON(&createUser); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
-		return
+func (h *UserHandler) CreateUser(
+	ctx context.Context,
+	request CreateUserRequestObject,
+) (CreateUserResponseObject, error) {
+	if request.Body.Name == "" {
+		return CreateUser400JSONResponse{BadRequestJSONResponse{Error: errNameRequired}}, nil
 	}
 
-	if createUser.Name == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
-		return
+	user := domain.NewUser("", request.Body.Name)
+	if request.Body.Email != nil {
+		user = user.WithEmail(*request.Body.Email, false)
 	}
-
-	user, err := h.service.Create(c.Request.Context(), domain.NewUser("", createUser.Name))
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
+	if request.Body.Attributes != nil {
+		user = user.WithAttributes(*request.Body.Attributes)
 	}
 
-	result := &UserJSON{
-		ID:   user.ID(),
-		Name: user.Name(),
+	user, err := h.service.Create(requestContext(ctx), user)
+	var invalid *app.InvalidEmailError
+	var invalidAttributes *app.InvalidAttributesError
+	switch {
+	case errors.As(err, &invalid):
+		return CreateUser400JSONResponse{BadRequestJSONResponse{Error: invalid.Error()}}, nil
+	case errors.As(err, &invalidAttributes):
+		return CreateUser400JSONResponse{BadRequestJSONResponse{Error: invalidAttributes.Error()}}, nil
+	case errors.Is(err, perrors.ErrForbidden):
+		return CreateUser403JSONResponse{ForbiddenJSONResponse{Error: err.Error()}}, nil
+	case errors.Is(err, perrors.ErrEmailTaken):
+		return CreateUser409JSONResponse{ConflictJSONResponse{Error: err.Error()}}, nil
+	case err != nil:
+		return CreateUser500JSONResponse{internalError(ctx, err)}, nil
 	}
 
-	c.JSON(http.StatusCreated, result)
+	return CreateUser201JSONResponse(toUserJSON(user)), nil
 }
 
-// GetUsers retrieves all users.
-func (h *UserHandler) GetUsers(c *gin.Context) {
-	users, err := h.service.GetAll(c.Request.Context())
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
+// GetUsers retrieves all users, or a single page of them when paginated.
+func (h *UserHandler) GetUsers(
+	ctx context.Context,
+	request GetUsersRequestObject,
+) (GetUsersResponseObject, error) {
+	params := request.Params
+	if params.Limit == nil && params.After == nil && params.Name == nil && params.Attr == nil && params.Sort == nil {
+		users, err := h.service.GetAll(requestContext(ctx))
+		if errors.Is(err, perrors.ErrForbidden) {
+			return GetUsers403JSONResponse{ForbiddenJSONResponse{Error: err.Error()}}, nil
+		}
+		if err != nil {
+			return GetUsers500JSONResponse{internalError(ctx, err)}, nil
+		}
+		return GetUsers200JSONResponse{Body: toUsersJSON(users)}, nil
 	}
 
-	result := make([]*UserJSON, len(users))
-	for i, user := range users {
-		result[i] = &UserJSON{
-			ID:   user.ID(),
-			Name: user.Name(),
+	listParams := app.ListParams{}
+	if params.Limit != nil {
+		listParams.Limit = *params.Limit
+	}
+	if params.After != nil {
+		listParams.After = *params.After
+	}
+	if params.Name != nil {
+		listParams.Filter.NameContains = *params.Name
+	}
+	if params.Attr != nil {
+		listParams.Filter.Attributes = make(map[string]interface{}, len(*params.Attr))
+		for _, attr := range *params.Attr {
+			name, value, ok := strings.Cut(attr, "=")
+			if !ok {
+				return GetUsers400JSONResponse{BadRequestJSONResponse{Error: "attribute filters must be name=value"}}, nil
+			}
+			listParams.Filter.Attributes[name] = value
 		}
 	}
+	if params.Sort != nil {
+		listParams.SortAttribute, listParams.Descending = strings.CutPrefix(*params.Sort, "-")
+	}
 
-	c.JSON(http.StatusOK, &result)
+	page, err := h.service.List(requestContext(ctx), listParams)
+	var invalid *app.InvalidAttributesError
+	switch {
+	case errors.As(err, &invalid):
+		return GetUsers400JSONResponse{BadRequestJSONResponse{Error: invalid.Error()}}, nil
+	case errors.Is(err, perrors.ErrForbidden):
+		return GetUsers403JSONResponse{ForbiddenJSONResponse{Error: err.Error()}}, nil
+	case err != nil:
+		return GetUsers500JSONResponse{internalError(ctx, err)}, nil
+	}
+
+	response := GetUsers200JSONResponse{Body: toUsersJSON(page.Users)}
+	if page.HasNextPage {
+		response.Headers.XNextCursor = page.Users[len(page.Users)-1].ID()
+	}
+
+	return response, nil
 }
 
-// GetUser retrieves a user by ID.
-func (h *UserHandler) GetUser(c *gin.Context) {
-	id := c.Param("id")
-	user, err := h.service.GetUser(c.Request.Context(), id)
+// GetUser retrieves a user by ID, as it is or as it was at a given time.
+func (h *UserHandler) GetUser(
+	ctx context.Context,
+	request GetUserRequestObject,
+) (GetUserResponseObject, error) {
+	var (
+		user *domain.User
+		err  error
+	)
+	if request.Params.AsOf != nil {
+		user, err = h.service.GetUserAsOf(requestContext(ctx), request.Id, *request.Params.AsOf)
+	} else {
+		user, err = h.service.GetUser(requestContext(ctx), request.Id)
+	}
+	if errors.Is(err, perrors.ErrUserNotFound) {
+		return GetUser404JSONResponse{NotFoundJSONResponse{Error: err.Error()}}, nil
+	}
+	if errors.Is(err, perrors.ErrForbidden) || errors.Is(err, perrors.ErrHistoryDisabled) {
+		return GetUser403JSONResponse{ForbiddenJSONResponse{Error: err.Error()}}, nil
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
+		return GetUser500JSONResponse{internalError(ctx, err)}, nil
 	}
 
-	result := &UserJSON{
-		ID:   user.ID(),
-		Name: user.Name(),
+	return GetUser200JSONResponse(toUserJSON(user)), nil
+}
+
+// UpdateUser updates an existing user.
+func (h *UserHandler) UpdateUser(
+	ctx context.Context,
+	request UpdateUserRequestObject,
+) (UpdateUserResponseObject, error) {
+	if request.Body.Name == "" {
+		return UpdateUser400JSONResponse{BadRequestJSONResponse{Error: errNameRequired}}, nil
+	}
+
+	user := domain.NewUser(request.Id, request.Body.Name)
+	if request.Body.Attributes != nil {
+		// Without attributes the user keeps its own, an empty object removes them.
+		user = user.WithAttributes(*request.Body.Attributes)
 	}
 
-	c.JSON(http.StatusOK, result)
+	err := h.service.Update(requestContext(ctx), user)
+	var invalid *app.InvalidAttributesError
+	switch {
+	case errors.As(err, &invalid):
+		return UpdateUser400JSONResponse{BadRequestJSONResponse{Error: invalid.Error()}}, nil
+	case errors.Is(err, perrors.ErrUserNotFound):
+		return UpdateUser404JSONResponse{NotFoundJSONResponse{Error: err.Error()}}, nil
+	case errors.Is(err, perrors.ErrForbidden):
+		return UpdateUser403JSONResponse{ForbiddenJSONResponse{Error: err.Error()}}, nil
+	case err != nil:
+		return UpdateUser500JSONResponse{internalError(ctx, err)}, nil
+	}
+
+	return UpdateUser200JSONResponse{Message: "user updated"}, nil
 }
 
-// UpdateUser updates an existing user.
-func (h *UserHandler) UpdateUser(c *gin.Context) {
-	id := c.Param("id")
+// DeleteUser deletes a user by ID.
+func (h *UserHandler) DeleteUser(
+	ctx context.Context,
+	request DeleteUserRequestObject,
+) (DeleteUserResponseObject, error) {
+	err := h.service.Remove(requestContext(ctx), request.Id)
+	if errors.Is(err, perrors.ErrUserNotFound) {
+		return DeleteUser404JSONResponse{NotFoundJSONResponse{Error: err.Error()}}, nil
+	}
+	if errors.Is(err, perrors.ErrForbidden) {
+		return DeleteUser403JSONResponse{ForbiddenJSONResponse{Error: err.Error()}}, nil
+	}
+	if err != nil {
+		return DeleteUser500JSONResponse{internalError(ctx, err)}, nil
+	}
+
+	return DeleteUser200JSONResponse{Message: "user removed"}, nil
+}
 
-	var updateUser UpdateUserJSON
-	if err := c.ShouldBindJSON(&updateUser); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
-		return
+// SetUserPassword processes password changes.
+func (h *UserHandler) SetUserPassword(
+	ctx context.Context,
+	request SetUserPasswordRequestObject,
+) (SetUserPasswordResponseObject, error) {
+	var current string
+	if request.Body.CurrentPassword != nil {
+		current = *request.Body.CurrentPassword
 	}
 
-	if updateUser.Name == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
-		return
+	err := h.service.SetPassword(requestContext(ctx), request.Id, current, request.Body.Password)
+	var invalid *app.InvalidPasswordError
+	switch {
+	case errors.As(err, &invalid):
+		return SetUserPassword400JSONResponse{BadRequestJSONResponse{Error: invalid.Error()}}, nil
+	case errors.Is(err, perrors.ErrUserNotFound):
+		return SetUserPassword404JSONResponse{NotFoundJSONResponse{Error: err.Error()}}, nil
+	case errors.Is(err, perrors.ErrForbidden):
+		return SetUserPassword403JSONResponse{ForbiddenJSONResponse{Error: err.Error()}}, nil
+	case errors.Is(err, perrors.ErrInvalidCredentials):
+		return SetUserPassword403JSONResponse{ForbiddenJSONResponse{Error: "current password is incorrect"}}, nil
+	case err != nil:
+		return SetUserPassword500JSONResponse{internalError(ctx, err)}, nil
 	}
 
-	if err := h.service.Update(c.Request.Context(), domain.NewUser(id, updateUser.Name)); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
+	return SetUserPassword200JSONResponse{Message: "password changed"}, nil
+}
+
+// SetUserEmail processes email address changes.
+func (h *UserHandler) SetUserEmail(
+	ctx context.Context,
+	request SetUserEmailRequestObject,
+) (SetUserEmailResponseObject, error) {
+	var current string
+	if request.Body.CurrentPassword != nil {
+		current = *request.Body.CurrentPassword
+	}
+
+	err := h.service.SetEmail(requestContext(ctx), request.Id, current, request.Body.Email)
+	var invalid *app.InvalidEmailError
+	switch {
+	case errors.As(err, &invalid):
+		return SetUserEmail400JSONResponse{BadRequestJSONResponse{Error: invalid.Error()}}, nil
+	case errors.Is(err, perrors.ErrUserNotFound):
+		return SetUserEmail404JSONResponse{NotFoundJSONResponse{Error: err.Error()}}, nil
+	case errors.Is(err, perrors.ErrForbidden):
+		return SetUserEmail403JSONResponse{ForbiddenJSONResponse{Error: err.Error()}}, nil
+	case errors.Is(err, perrors.ErrInvalidCredentials):
+		return SetUserEmail403JSONResponse{ForbiddenJSONResponse{Error: "current password is incorrect"}}, nil
+	case errors.Is(err, perrors.ErrEmailTaken):
+		return SetUserEmail409JSONResponse{ConflictJSONResponse{Error: err.Error()}}, nil
+	case err != nil:
+		return SetUserEmail500JSONResponse{internalError(ctx, err)}, nil
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "user updated"})
+	return SetUserEmail200JSONResponse{Message: "email changed"}, nil
 }
 
-// RemoveUser deletes a user by ID.
-func (h *UserHandler) RemoveUser(c *gin.Context) {
-	id := c.Param("id")
-	if err := h.service.Remove(c.Request.Context(), id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
+func toUsersJSON(users []*domain.User) []UserJSON {
+	result := make([]UserJSON, len(users))
+	for i, user := range users {
+		result[i] = toUserJSON(user)
 	}
+	return result
+}
 
-	c.JSON(http.StatusOK, gin.H{"message": "user removed"})
+func toUserJSON(user *domain.User) UserJSON {
+	result := UserJSON{
+		Id:   user.ID(),
+		Name: user.Name(),
+	}
+	if email := user.Email(); email != "" {
+		verified := user.EmailVerified()
+		result.Email = &email
+		result.EmailVerified = &verified
+	}
+	if attributes := user.Attributes(); len(attributes) > 0 {
+		result.Attributes = (*AttributesJSON)(&attributes)
+	}
+	return result
+}
+
+// requestContext returns the request context behind a gin.Context,
+// which the generated strict handlers pass in place of it.
+func requestContext(ctx context.Context) context.Context {
+	if c, ok := ctx.(*gin.Context); ok && c.Request != nil {
+		return c.Request.Context()
+	}
+	return ctx
 }
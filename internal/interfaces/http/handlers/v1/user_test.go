@@ -6,14 +6,17 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
 	"github.com/Sergey-Polishchenko/simple-api/internal/domain"
 	"github.com/Sergey-Polishchenko/simple-api/internal/interfaces/http/handlers/mocks"
 	v1 "github.com/Sergey-Polishchenko/simple-api/internal/interfaces/http/handlers/v1"
+	perrors "github.com/Sergey-Polishchenko/simple-api/internal/pkg/errors"
 )
 
 func TestUserHandler_CreateUser(t *testing.T) {
@@ -37,6 +40,26 @@ func TestUserHandler_CreateUser(t *testing.T) {
 			expectedCode: http.StatusCreated,
 			expectedBody: `{"id":"123","name":"John"}`,
 		},
+		{
+			name:        "with email",
+			requestBody: `{"name": "John", "email": "john@example.com"}`,
+			mockSetup: func(m *mocks.MockUserService) {
+				m.On("Create", mock.Anything, mock.MatchedBy(func(u *domain.User) bool {
+					return u.Email() == "john@example.com"
+				})).Return(domain.NewUser("123", "John").WithEmail("john@example.com", false), nil)
+			},
+			expectedCode: http.StatusCreated,
+			expectedBody: `{"id":"123","name":"John","email":"john@example.com","emailVerified":false}`,
+		},
+		{
+			name:        "email taken",
+			requestBody: `{"name": "John", "email": "john@example.com"}`,
+			mockSetup: func(m *mocks.MockUserService) {
+				m.On("Create", mock.Anything, mock.Anything).Return(nil, perrors.ErrEmailTaken)
+			},
+			expectedCode: http.StatusConflict,
+			expectedBody: `{"error":"email is already taken"}`,
+		},
 		{
 			name:        "invalid json",
 			requestBody: `{invalid}`,
@@ -63,7 +86,17 @@ func TestUserHandler_CreateUser(t *testing.T) {
 					Return(nil, errors.New("database error"))
 			},
 			expectedCode: http.StatusInternalServerError,
-			expectedBody: `{"error":"database error"}`,
+			expectedBody: `{"error":"internal server error"}`,
+		},
+		{
+			name:        "connection error",
+			requestBody: `{"name": "John"}`,
+			mockSetup: func(m *mocks.MockUserService) {
+				m.On("Create", mock.Anything, mock.Anything).
+					Return(nil, errors.New(`failed to connect to "host=db user=app password=hunter2": SELECT * FROM "user_pgs"`))
+			},
+			expectedCode: http.StatusInternalServerError,
+			expectedBody: `{"error":"internal server error"}`,
 		},
 	}
 
@@ -74,10 +107,10 @@ func TestUserHandler_CreateUser(t *testing.T) {
 
 			handler := v1.NewUserHandler(mockService)
 			router := gin.Default()
-			router.POST("/users", handler.CreateUser)
+			v1.RegisterRoutes(router, v1.Handlers{Users: handler})
 
 			w := httptest.NewRecorder()
-			req, _ := http.NewRequest("POST", "/users", bytes.NewBufferString(tt.requestBody))
+			req, _ := http.NewRequest("POST", "/api/v1/users", bytes.NewBufferString(tt.requestBody))
 			req.Header.Set("Content-Type", "application/json")
 
 			router.ServeHTTP(w, req)
@@ -101,16 +134,113 @@ func TestUserHandler_GetUser(t *testing.T) {
 
 		handler := v1.NewUserHandler(mockService)
 		router := gin.Default()
-		router.GET("/users/:id", handler.GetUser)
+		v1.RegisterRoutes(router, v1.Handlers{Users: handler})
 
 		w := httptest.NewRecorder()
-		req, _ := http.NewRequest("GET", "/users/123", nil)
+		req, _ := http.NewRequest("GET", "/api/v1/users/123", nil)
 
 		router.ServeHTTP(w, req)
 
 		assert.Equal(t, http.StatusOK, w.Code)
 		assert.JSONEq(t, `{"id":"123","name":"John"}`, w.Body.String())
 	})
+
+	t.Run("Not Found", func(t *testing.T) {
+		mockService := new(mocks.MockUserService)
+		mockService.On("GetUser", mock.Anything, "404").
+			Return((*domain.User)(nil), perrors.ErrUserNotFound)
+
+		handler := v1.NewUserHandler(mockService)
+		router := gin.Default()
+		v1.RegisterRoutes(router, v1.Handlers{Users: handler})
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/v1/users/404", nil)
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.JSONEq(t, `{"error":"user not found"}`, w.Body.String())
+	})
+
+	t.Run("As Of", func(t *testing.T) {
+		asOf := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+		mockService := new(mocks.MockUserService)
+		mockService.On("GetUserAsOf", mock.Anything, "123", mock.MatchedBy(asOf.Equal)).
+			Return(domain.NewUser("123", "Johnny"), nil)
+
+		handler := v1.NewUserHandler(mockService)
+		router := gin.Default()
+		v1.RegisterRoutes(router, v1.Handlers{Users: handler})
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/v1/users/123?as_of=2024-03-01T12:00:00Z", nil)
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"id":"123","name":"Johnny"}`, w.Body.String())
+		mockService.AssertNotCalled(t, "GetUser", mock.Anything, mock.Anything)
+	})
+}
+
+func TestUserHandler_GetUsers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		query          string
+		mockSetup      func(*mocks.MockUserService)
+		expectedBody   string
+		expectedCursor string
+	}{
+		{
+			name:  "All",
+			query: "",
+			mockSetup: func(m *mocks.MockUserService) {
+				m.On("GetAll", mock.Anything).
+					Return([]*domain.User{domain.NewUser("1", "John")}, nil)
+			},
+			expectedBody: `[{"id":"1","name":"John"}]`,
+		},
+		{
+			name:  "Paginated",
+			query: "?limit=1&after=1&name=ja",
+			mockSetup: func(m *mocks.MockUserService) {
+				m.On("List", mock.Anything, app.ListParams{
+					Filter: app.UserFilter{NameContains: "ja"},
+					After:  "1",
+					Limit:  1,
+				}).Return(&app.UserPage{
+					Users:       []*domain.User{domain.NewUser("2", "Jane")},
+					HasNextPage: true,
+				}, nil)
+			},
+			expectedBody:   `[{"id":"2","name":"Jane"}]`,
+			expectedCursor: "2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(mocks.MockUserService)
+			tt.mockSetup(mockService)
+
+			handler := v1.NewUserHandler(mockService)
+			router := gin.Default()
+			v1.RegisterRoutes(router, v1.Handlers{Users: handler})
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/api/v1/users"+tt.query, nil)
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.JSONEq(t, tt.expectedBody, w.Body.String())
+			assert.Equal(t, tt.expectedCursor, w.Header().Get("X-Next-Cursor"))
+			mockService.AssertExpectations(t)
+		})
+	}
 }
 
 func TestUserHandler_UpdateUser(t *testing.T) {
@@ -170,7 +300,7 @@ func TestUserHandler_UpdateUser(t *testing.T) {
 					Return(errors.New("database error"))
 			},
 			expectedCode: http.StatusInternalServerError,
-			expectedBody: `{"error":"database error"}`,
+			expectedBody: `{"error":"internal server error"}`,
 		},
 	}
 
@@ -181,12 +311,12 @@ func TestUserHandler_UpdateUser(t *testing.T) {
 
 			handler := v1.NewUserHandler(mockService)
 			router := gin.Default()
-			router.PUT("/users/:id", handler.UpdateUser)
+			v1.RegisterRoutes(router, v1.Handlers{Users: handler})
 
 			w := httptest.NewRecorder()
 			req, _ := http.NewRequest(
 				http.MethodPut,
-				"/users/"+tt.userID,
+				"/api/v1/users/"+tt.userID,
 				bytes.NewBufferString(tt.requestBody),
 			)
 			req.Header.Set("Content-Type", "application/json")
@@ -211,10 +341,10 @@ func TestUserHandler_RemoveUser(t *testing.T) {
 
 		handler := v1.NewUserHandler(mockService)
 		router := gin.Default()
-		router.DELETE("/users/:id", handler.RemoveUser)
+		v1.RegisterRoutes(router, v1.Handlers{Users: handler})
 
 		w := httptest.NewRecorder()
-		req, _ := http.NewRequest("DELETE", "/users/123", nil)
+		req, _ := http.NewRequest("DELETE", "/api/v1/users/123", nil)
 
 		router.ServeHTTP(w, req)
 
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	perrors "github.com/Sergey-Polishchenko/simple-api/internal/pkg/errors"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/logger"
+)
+
+// APIKeyHeader carries an API key as an alternative to "Authorization: ApiKey".
+const APIKeyHeader = "X-API-Key"
+
+// APIKeyAuth authenticates requests with an API key, see NewAPIKeyVerifier.
+func APIKeyAuth(service app.APIKeyService, logger logger.Logger) gin.HandlerFunc {
+	return Authenticate(logger, NewAPIKeyVerifier(service))
+}
+
+type apiKeyVerifier struct {
+	service app.APIKeyService
+}
+
+// NewAPIKeyVerifier verifies keys sent as "Authorization: ApiKey <key>" or in X-API-Key.
+func NewAPIKeyVerifier(service app.APIKeyService) Verifier {
+	return &apiKeyVerifier{service: service}
+}
+
+func (v *apiKeyVerifier) Scheme() string {
+	return "ApiKey"
+}
+
+func (v *apiKeyVerifier) Verify(r *http.Request) (*app.Claims, error) {
+	key, ok := credentials(r, "ApiKey")
+	if !ok {
+		key = strings.TrimSpace(r.Header.Get(APIKeyHeader))
+	}
+	if key == "" {
+		return nil, errNoCredentials
+	}
+
+	claims, err := v.service.Authenticate(r.Context(), key)
+	if errors.Is(err, perrors.ErrInvalidAPIKey) {
+		return nil, &credentialError{message: "invalid api key", err: err}
+	}
+	return claims, err
+}
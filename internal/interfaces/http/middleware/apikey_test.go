@@ -0,0 +1,113 @@
+package middleware_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	"github.com/Sergey-Polishchenko/simple-api/internal/interfaces/http/middleware"
+	perrors "github.com/Sergey-Polishchenko/simple-api/internal/pkg/errors"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/jwks"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/logger"
+)
+
+// stubAPIKeys accepts the key "good" and fails on "broken".
+type stubAPIKeys struct {
+	app.APIKeyService
+}
+
+func (stubAPIKeys) Authenticate(_ context.Context, key string) (*app.Claims, error) {
+	switch key {
+	case "good":
+		return &app.Claims{Subject: "apikey:1", APIKeyID: "1"}, nil
+	case "broken":
+		return nil, errors.New("db is down")
+	}
+	return nil, perrors.ErrInvalidAPIKey
+}
+
+func TestAuthenticate_APIKeys(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	keys, err := jwks.NewSet([]jwks.Key{{Material: secret}}, "", 0)
+	assert.NoError(t, err)
+
+	router := gin.New()
+	router.Use(middleware.Authenticate(logger.NewZapLogger(),
+		middleware.NewJWTVerifier(middleware.JWTConfig{Keys: keys}),
+		middleware.NewAPIKeyVerifier(stubAPIKeys{}),
+	))
+	router.GET("/me", func(c *gin.Context) {
+		claims, _ := app.ClaimsFromContext(c.Request.Context())
+		c.JSON(http.StatusOK, gin.H{"sub": claims.Subject})
+	})
+
+	tests := []struct {
+		name              string
+		header            string
+		value             string
+		expectedCode      int
+		expectedBody      string
+		expectedChallenge []string
+	}{
+		{
+			name:         "Authorization header",
+			header:       "Authorization",
+			value:        "ApiKey good",
+			expectedCode: http.StatusOK,
+			expectedBody: `{"sub":"apikey:1"}`,
+		},
+		{
+			name:         "X-API-Key header",
+			header:       middleware.APIKeyHeader,
+			value:        "good",
+			expectedCode: http.StatusOK,
+			expectedBody: `{"sub":"apikey:1"}`,
+		},
+		{
+			name:              "Invalid key",
+			header:            middleware.APIKeyHeader,
+			value:             "bad",
+			expectedCode:      http.StatusUnauthorized,
+			expectedBody:      `{"error":"invalid api key"}`,
+			expectedChallenge: []string{`ApiKey error="invalid_token"`},
+		},
+		{
+			name:         "Verification failure",
+			header:       "Authorization",
+			value:        "ApiKey broken",
+			expectedCode: http.StatusInternalServerError,
+			expectedBody: `{"error":"can't verify credentials"}`,
+		},
+		{
+			name:              "No credentials",
+			expectedCode:      http.StatusUnauthorized,
+			expectedBody:      `{"error":"missing credentials"}`,
+			expectedChallenge: []string{"Bearer", "ApiKey"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/me", nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			assert.JSONEq(t, tt.expectedBody, w.Body.String())
+			if tt.expectedChallenge != nil {
+				assert.Equal(t, tt.expectedChallenge, w.Header().Values("WWW-Authenticate"))
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/logger"
+)
+
+// errNoCredentials is returned by a Verifier when the request
+// carries no credentials of its kind.
+var errNoCredentials = errors.New("no credentials")
+
+// credentialError rejects credentials with a message safe to show to the caller.
+type credentialError struct {
+	message string
+	err     error
+}
+
+func (e *credentialError) Error() string {
+	return e.message + ": " + e.err.Error()
+}
+
+func (e *credentialError) Unwrap() error {
+	return e.err
+}
+
+// Verifier checks one kind of credentials.
+type Verifier interface {
+	// Scheme names the credentials in WWW-Authenticate challenges.
+	Scheme() string
+	// Verify returns the caller's claims, errNoCredentials if the request
+	// carries none of its kind, or a *credentialError if they are invalid.
+	Verify(r *http.Request) (*app.Claims, error)
+}
+
+// Authenticate accepts a request authenticated by the first verifier whose
+// credentials it carries, storing the claims in the request context, see
+// app.ClaimsFromContext. Requests without valid credentials get 401.
+func Authenticate(logger logger.Logger, verifiers ...Verifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, verifier := range verifiers {
+			claims, err := verifier.Verify(c.Request)
+			if errors.Is(err, errNoCredentials) {
+				continue
+			}
+
+			var credErr *credentialError
+			if errors.As(err, &credErr) {
+				logger.Info("rejected credentials", "scheme", verifier.Scheme(), "path", c.FullPath(), "error", err)
+				c.Header("WWW-Authenticate", verifier.Scheme()+` error="invalid_token"`)
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": credErr.message})
+				return
+			}
+			if err != nil {
+				logger.Error("can't verify credentials", "scheme", verifier.Scheme(), "error", err)
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "can't verify credentials"})
+				return
+			}
+
+			c.Request = c.Request.WithContext(app.ContextWithClaims(c.Request.Context(), claims))
+			c.Next()
+			return
+		}
+
+		for _, verifier := range verifiers {
+			c.Writer.Header().Add("WWW-Authenticate", verifier.Scheme())
+		}
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing credentials"})
+	}
+}
@@ -0,0 +1,158 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/jwks"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/logger"
+)
+
+// KeyLookup returns the keys that can verify a token with the given key ID and algorithm.
+type KeyLookup interface {
+	Lookup(kid, alg string) ([]jwks.Key, error)
+}
+
+// RevocationList reports whether a token, identified by its "jti" claim, was revoked.
+type RevocationList interface {
+	IsRevoked(ctx context.Context, tokenID string) (bool, error)
+}
+
+// JWTConfig configures JWT bearer authentication.
+type JWTConfig struct {
+	Keys KeyLookup
+	// Issuer and Audience are checked against the "iss" and "aud" claims when set.
+	Issuer   string
+	Audience string
+	// ClockSkew is the leeway applied to the "exp", "nbf" and "iat" claims.
+	ClockSkew time.Duration
+	// Revoked rejects revoked tokens when set; tokens without an ID can't be revoked.
+	Revoked RevocationList
+}
+
+// JWTAuth authenticates requests with a bearer JWT, see NewJWTVerifier.
+func JWTAuth(cfg JWTConfig, logger logger.Logger) gin.HandlerFunc {
+	return Authenticate(logger, NewJWTVerifier(cfg))
+}
+
+type jwtVerifier struct {
+	parser  *jwt.Parser
+	keyFunc jwt.Keyfunc
+	revoked RevocationList
+}
+
+// NewJWTVerifier verifies "Authorization: Bearer" JWTs signed with HS256,
+// RS256 or ES256. Tokens must expire.
+func NewJWTVerifier(cfg JWTConfig) Verifier {
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{jwks.HS256, jwks.RS256, jwks.ES256}),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(cfg.ClockSkew),
+	}
+	if cfg.Issuer != "" {
+		options = append(options, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		options = append(options, jwt.WithAudience(cfg.Audience))
+	}
+
+	keyFunc := func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		keys, err := cfg.Keys.Lookup(kid, token.Method.Alg())
+		if err != nil {
+			return nil, err
+		}
+
+		set := jwt.VerificationKeySet{Keys: make([]jwt.VerificationKey, len(keys))}
+		for i, key := range keys {
+			set.Keys[i] = key.Material
+		}
+		return set, nil
+	}
+
+	return &jwtVerifier{parser: jwt.NewParser(options...), keyFunc: keyFunc, revoked: cfg.Revoked}
+}
+
+func (v *jwtVerifier) Scheme() string {
+	return "Bearer"
+}
+
+func (v *jwtVerifier) Verify(r *http.Request) (*app.Claims, error) {
+	raw, ok := credentials(r, "Bearer")
+	if !ok {
+		return nil, errNoCredentials
+	}
+
+	claims := jwt.MapClaims{}
+	if _, err := v.parser.ParseWithClaims(raw, claims, v.keyFunc); err != nil {
+		return nil, &credentialError{message: tokenError(err), err: err}
+	}
+
+	if id, _ := claims["jti"].(string); id != "" && v.revoked != nil {
+		revoked, err := v.revoked.IsRevoked(r.Context(), id)
+		if err != nil {
+			return nil, err
+		}
+		if revoked {
+			return nil, &credentialError{message: "token is revoked", err: errTokenRevoked}
+		}
+	}
+	return toClaims(claims), nil
+}
+
+var errTokenRevoked = errors.New("token revoked")
+
+// credentials extracts the credentials of an "Authorization: <scheme>" header.
+func credentials(r *http.Request, scheme string) (string, bool) {
+	got, value, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(got, scheme) {
+		return "", false
+	}
+	value = strings.TrimSpace(value)
+	return value, value != ""
+}
+
+// tokenError explains a rejected token without echoing its contents.
+func tokenError(err error) string {
+	switch {
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return "token is expired"
+	case errors.Is(err, jwt.ErrTokenNotValidYet), errors.Is(err, jwt.ErrTokenUsedBeforeIssued):
+		return "token is not valid yet"
+	case errors.Is(err, jwt.ErrTokenInvalidIssuer), errors.Is(err, jwt.ErrTokenInvalidAudience):
+		return "token was not issued for this service"
+	}
+	return "invalid token"
+}
+
+func toClaims(raw jwt.MapClaims) *app.Claims {
+	claims := &app.Claims{Raw: raw}
+	claims.Subject, _ = raw.GetSubject()
+	claims.Issuer, _ = raw.GetIssuer()
+	claims.Audience, _ = raw.GetAudience()
+	if exp, _ := raw.GetExpirationTime(); exp != nil {
+		claims.ExpiresAt = exp.Time
+	}
+	claims.ClientID, _ = raw["client_id"].(string)
+	claims.SessionID, _ = raw["sid"].(string)
+	claims.TenantID, _ = raw["tenant"].(string)
+	if scope, ok := raw["scope"].(string); ok {
+		claims.Scopes = strings.Fields(scope)
+	}
+	if roles, ok := raw["roles"].([]interface{}); ok {
+		for _, role := range roles {
+			if role, ok := role.(string); ok {
+				claims.Roles = append(claims.Roles, role)
+			}
+		}
+	}
+	return claims
+}
@@ -0,0 +1,295 @@
+package middleware_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	"github.com/Sergey-Polishchenko/simple-api/internal/interfaces/http/middleware"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/jwks"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/logger"
+)
+
+var secret = []byte("0123456789abcdef0123456789abcdef")
+
+func newAuthRouter(t *testing.T, keys []jwks.Key) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	set, err := jwks.NewSet(keys, "", 0)
+	require.NoError(t, err)
+
+	router := gin.New()
+	router.Use(middleware.JWTAuth(middleware.JWTConfig{
+		Keys:      set,
+		Issuer:    "https://issuer.example",
+		Audience:  "simple-api",
+		ClockSkew: 30 * time.Second,
+	}, logger.NewZapLogger()))
+	router.GET("/me", func(c *gin.Context) {
+		claims, ok := app.ClaimsFromContext(c.Request.Context())
+		require.True(t, ok)
+		c.JSON(http.StatusOK, gin.H{"sub": claims.Subject, "scopes": claims.Scopes, "roles": claims.Roles})
+	})
+	return router
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, key interface{}, kid string, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+	return signed
+}
+
+func validClaims(overrides jwt.MapClaims) jwt.MapClaims {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"sub":   "user-1",
+		"iss":   "https://issuer.example",
+		"aud":   "simple-api",
+		"exp":   now.Add(time.Minute).Unix(),
+		"iat":   now.Unix(),
+		"scope": "users:read users:write",
+		"roles": []string{"admin"},
+	}
+	for name, value := range overrides {
+		if value == nil {
+			delete(claims, name)
+		} else {
+			claims[name] = value
+		}
+	}
+	return claims
+}
+
+func TestJWTAuth(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	keys := []jwks.Key{
+		{Algorithm: jwks.HS256, Material: secret},
+		{ID: "rsa-1", Material: &rsaKey.PublicKey},
+		{ID: "ec-1", Material: &ecKey.PublicKey},
+	}
+	now := time.Now()
+
+	tests := []struct {
+		name          string
+		authorization string
+		expectedCode  int
+		expectedBody  string
+	}{
+		{
+			name:          "HS256",
+			authorization: "Bearer " + sign(t, jwt.SigningMethodHS256, secret, "", validClaims(nil)),
+			expectedCode:  http.StatusOK,
+			expectedBody:  `{"roles":["admin"],"scopes":["users:read","users:write"],"sub":"user-1"}`,
+		},
+		{
+			name:          "RS256 by kid",
+			authorization: "Bearer " + sign(t, jwt.SigningMethodRS256, rsaKey, "rsa-1", validClaims(nil)),
+			expectedCode:  http.StatusOK,
+		},
+		{
+			name:          "ES256 by kid",
+			authorization: "bearer " + sign(t, jwt.SigningMethodES256, ecKey, "ec-1", validClaims(nil)),
+			expectedCode:  http.StatusOK,
+		},
+		{
+			name:          "ES256 without kid",
+			authorization: "Bearer " + sign(t, jwt.SigningMethodES256, ecKey, "", validClaims(nil)),
+			expectedCode:  http.StatusOK,
+		},
+		{
+			name: "Expired within clock skew",
+			authorization: "Bearer " + sign(t, jwt.SigningMethodHS256, secret, "", validClaims(jwt.MapClaims{
+				"exp": now.Add(-10 * time.Second).Unix(),
+			})),
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "Missing token",
+			expectedCode: http.StatusUnauthorized,
+			expectedBody: `{"error":"missing credentials"}`,
+		},
+		{
+			name:          "Wrong scheme",
+			authorization: "Basic dXNlcjpwYXNz",
+			expectedCode:  http.StatusUnauthorized,
+			expectedBody:  `{"error":"missing credentials"}`,
+		},
+		{
+			name:          "Malformed token",
+			authorization: "Bearer not-a-jwt",
+			expectedCode:  http.StatusUnauthorized,
+			expectedBody:  `{"error":"invalid token"}`,
+		},
+		{
+			name: "Expired",
+			authorization: "Bearer " + sign(t, jwt.SigningMethodHS256, secret, "", validClaims(jwt.MapClaims{
+				"exp": now.Add(-time.Minute).Unix(),
+			})),
+			expectedCode: http.StatusUnauthorized,
+			expectedBody: `{"error":"token is expired"}`,
+		},
+		{
+			name:          "Without expiry",
+			authorization: "Bearer " + sign(t, jwt.SigningMethodHS256, secret, "", validClaims(jwt.MapClaims{"exp": nil})),
+			expectedCode:  http.StatusUnauthorized,
+			expectedBody:  `{"error":"invalid token"}`,
+		},
+		{
+			name: "Not valid yet",
+			authorization: "Bearer " + sign(t, jwt.SigningMethodHS256, secret, "", validClaims(jwt.MapClaims{
+				"nbf": now.Add(time.Minute).Unix(),
+			})),
+			expectedCode: http.StatusUnauthorized,
+			expectedBody: `{"error":"token is not valid yet"}`,
+		},
+		{
+			name: "Wrong issuer",
+			authorization: "Bearer " + sign(t, jwt.SigningMethodHS256, secret, "", validClaims(jwt.MapClaims{
+				"iss": "https://evil.example",
+			})),
+			expectedCode: http.StatusUnauthorized,
+			expectedBody: `{"error":"token was not issued for this service"}`,
+		},
+		{
+			name: "Wrong audience",
+			authorization: "Bearer " + sign(t, jwt.SigningMethodHS256, secret, "", validClaims(jwt.MapClaims{
+				"aud": "another-api",
+			})),
+			expectedCode: http.StatusUnauthorized,
+			expectedBody: `{"error":"token was not issued for this service"}`,
+		},
+		{
+			name:          "Unknown signing key",
+			authorization: "Bearer " + sign(t, jwt.SigningMethodES256, otherKey, "ec-1", validClaims(nil)),
+			expectedCode:  http.StatusUnauthorized,
+			expectedBody:  `{"error":"invalid token"}`,
+		},
+		{
+			name:          "Unknown kid",
+			authorization: "Bearer " + sign(t, jwt.SigningMethodES256, ecKey, "ec-2", validClaims(nil)),
+			expectedCode:  http.StatusUnauthorized,
+			expectedBody:  `{"error":"invalid token"}`,
+		},
+		{
+			name:          "Unsigned",
+			authorization: "Bearer " + sign(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, "", validClaims(nil)),
+			expectedCode:  http.StatusUnauthorized,
+			expectedBody:  `{"error":"invalid token"}`,
+		},
+	}
+
+	router := newAuthRouter(t, keys)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/me", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			if tt.expectedBody != "" {
+				assert.JSONEq(t, tt.expectedBody, w.Body.String())
+			}
+			if tt.expectedCode == http.StatusUnauthorized {
+				assert.Contains(t, w.Header().Get("WWW-Authenticate"), "Bearer")
+			}
+		})
+	}
+}
+
+func TestJWTAuth_RejectsAlgorithmConfusion(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	// An attacker signs HS256 with the RSA public key as the HMAC secret.
+	router := newAuthRouter(t, []jwks.Key{{ID: "rsa-1", Material: &rsaKey.PublicKey}})
+	publicDER := rsaKey.PublicKey.N.Bytes()
+	token := sign(t, jwt.SigningMethodHS256, publicDER, "rsa-1", validClaims(nil))
+
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+type revocationList map[string]bool
+
+func (l revocationList) IsRevoked(_ context.Context, tokenID string) (bool, error) {
+	if tokenID == "broken" {
+		return false, errors.New("connection refused")
+	}
+	return l[tokenID], nil
+}
+
+func TestJWTAuth_Revocation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	set, err := jwks.NewSet([]jwks.Key{{Material: secret}}, "", 0)
+	require.NoError(t, err)
+
+	router := gin.New()
+	router.Use(middleware.JWTAuth(middleware.JWTConfig{
+		Keys:    set,
+		Revoked: revocationList{"revoked": true},
+	}, logger.NewZapLogger()))
+	router.GET("/me", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	tests := []struct {
+		name         string
+		tokenID      interface{}
+		expectedCode int
+		expectedBody string
+	}{
+		{name: "Active", tokenID: "active", expectedCode: http.StatusOK},
+		{name: "Without ID", tokenID: nil, expectedCode: http.StatusOK},
+		{name: "Revoked", tokenID: "revoked", expectedCode: http.StatusUnauthorized, expectedBody: `{"error":"token is revoked"}`},
+		{name: "Lookup error", tokenID: "broken", expectedCode: http.StatusInternalServerError, expectedBody: `{"error":"can't verify credentials"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := sign(t, jwt.SigningMethodHS256, secret, "", validClaims(jwt.MapClaims{"jti": tt.tokenID}))
+			req := httptest.NewRequest(http.MethodGet, "/me", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			if tt.expectedBody != "" {
+				assert.JSONEq(t, tt.expectedBody, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/logger"
+)
+
+// RequestIDHeader carries the ID correlating a request with its log lines.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds the request IDs accepted from clients.
+const maxRequestIDLength = 128
+
+// maxLoggedBodySize bounds the bodies logged by LogBodies.
+const maxLoggedBodySize = 4 << 10
+
+// RequestLoggerOption configures RequestLogger.
+type RequestLoggerOption func(*requestLogger)
+
+type requestLogger struct {
+	bodies bool
+}
+
+// LogBodies logs the JSON bodies of requests and responses at debug level,
+// with the values of sensitive keys masked by logger.DefaultRedactor.
+// Bodies over 4 KiB are left out.
+func LogBodies() RequestLoggerOption {
+	return func(l *requestLogger) {
+		l.bodies = true
+	}
+}
+
+// RequestLogger logs every request once it is served, and makes the log
+// lines written while serving it carry its ID: a client-supplied
+// X-Request-ID if it is sane, else a new one, echoed in the response.
+// The ID is carried by the request context for Logger.WithContext.
+func RequestLogger(log logger.Logger, opts ...RequestLoggerOption) gin.HandlerFunc {
+	var config requestLogger
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.New().String()
+		}
+		c.Header(RequestIDHeader, id)
+
+		c.Request = c.Request.WithContext(logger.ContextWith(c.Request.Context(), "request_id", id))
+
+		var requestBody []byte
+		var responseBody *bodyRecorder
+		if config.bodies {
+			requestBody = readBody(c.Request)
+			responseBody = &bodyRecorder{ResponseWriter: c.Writer}
+			c.Writer = responseBody
+		}
+
+		c.Next()
+
+		// The route template keeps IDs out of the field, so requests
+		// to the same endpoint can be grouped.
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		fields := []interface{}{
+			"method", c.Request.Method,
+			"route", route,
+			"status", c.Writer.Status(),
+			"latency", time.Since(start),
+			"bytes", c.Writer.Size(),
+			"client_ip", c.ClientIP(),
+		}
+		if len(c.Errors) > 0 {
+			fields = append(fields, "errors", c.Errors.String())
+		}
+
+		requestLog := log.WithContext(c.Request.Context())
+		if config.bodies {
+			requestLog.Debug("Request bodies",
+				"request_body", loggedBody(c.Request.Header.Get("Content-Type"), requestBody),
+				"response_body", loggedBody(c.Writer.Header().Get("Content-Type"), responseBody.body.Bytes()))
+		}
+		if c.Writer.Status() >= http.StatusInternalServerError {
+			requestLog.Error("Request failed", fields...)
+			return
+		}
+		requestLog.Info("Request served", fields...)
+	}
+}
+
+// Recovery turns panics into 500 responses, logging them along with the
+// request ID. It must come after RequestLogger.
+func Recovery(log logger.Logger) gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, err any) {
+		log.WithContext(c.Request.Context()).Error("Request panicked", "error", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+	})
+}
+
+// readBody reads the body of a request for logging and puts it back for
+// the handlers.
+func readBody(r *http.Request) []byte {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil
+	}
+	body, err := io.ReadAll(r.Body)
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return nil
+	}
+	return body
+}
+
+// loggedBody redacts a JSON body; other bodies are only described, as
+// there is no telling what they hold.
+func loggedBody(contentType string, body []byte) string {
+	switch {
+	case len(body) == 0:
+		return ""
+	case len(body) > maxLoggedBodySize:
+		return "[TOO LARGE]"
+	case !strings.Contains(contentType, "json"):
+		return "[" + contentType + "]"
+	}
+	return logger.DefaultRedactor.JSON(body)
+}
+
+// bodyRecorder keeps the first bytes of a response for logging, and one
+// more to tell that the body exceeds maxLoggedBodySize.
+type bodyRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *bodyRecorder) Write(data []byte) (int, error) {
+	if room := maxLoggedBodySize + 1 - w.body.Len(); room > 0 {
+		w.body.Write(data[:min(room, len(data))])
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *bodyRecorder) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// validRequestID accepts IDs of visible ASCII characters, so that clients
+// can't forge log lines.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
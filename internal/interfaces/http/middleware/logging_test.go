@@ -0,0 +1,177 @@
+package middleware_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/Sergey-Polishchenko/simple-api/internal/interfaces/http/middleware"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/logger"
+)
+
+func TestRequestLogger(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		path           string
+		requestID      string
+		expectedID     string
+		expectedStatus int
+		expectedRoute  string
+		expectedLevel  zapcore.Level
+	}{
+		{
+			name:           "propagated ID",
+			path:           "/users/42",
+			requestID:      "abc-123",
+			expectedID:     "abc-123",
+			expectedStatus: http.StatusOK,
+			expectedRoute:  "/users/:id",
+			expectedLevel:  zapcore.InfoLevel,
+		},
+		{
+			name:           "generated ID",
+			path:           "/users/42",
+			expectedStatus: http.StatusOK,
+			expectedRoute:  "/users/:id",
+			expectedLevel:  zapcore.InfoLevel,
+		},
+		{
+			name:           "forged ID",
+			path:           "/users/42",
+			requestID:      "abc\tforged=1",
+			expectedStatus: http.StatusOK,
+			expectedRoute:  "/users/:id",
+			expectedLevel:  zapcore.InfoLevel,
+		},
+		{
+			name:           "unmatched route",
+			path:           "/nowhere",
+			expectedStatus: http.StatusNotFound,
+			expectedRoute:  "unmatched",
+			expectedLevel:  zapcore.InfoLevel,
+		},
+		{
+			name:           "panic",
+			path:           "/panic",
+			expectedStatus: http.StatusInternalServerError,
+			expectedRoute:  "/panic",
+			expectedLevel:  zapcore.ErrorLevel,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, logs := observer.New(zapcore.DebugLevel)
+			log := logger.NewFromZap(zap.New(core))
+			router := gin.New()
+			router.Use(middleware.RequestLogger(log), middleware.Recovery(log))
+			router.GET("/users/:id", func(c *gin.Context) {
+				log.WithContext(c.Request.Context()).Info("User retrived", "user_id", c.Param("id"))
+				c.String(http.StatusOK, "ok")
+			})
+			router.GET("/panic", func(*gin.Context) { panic("boom") })
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.requestID != "" {
+				req.Header.Set(middleware.RequestIDHeader, tt.requestID)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			id := w.Header().Get(middleware.RequestIDHeader)
+			require.NotEmpty(t, id)
+			if tt.expectedID != "" {
+				assert.Equal(t, tt.expectedID, id)
+			} else {
+				assert.NotEqual(t, tt.requestID, id)
+			}
+
+			entries := logs.All()
+			require.NotEmpty(t, entries)
+			for _, entry := range entries {
+				assert.Equal(t, id, entry.ContextMap()["request_id"], "every line carries the request ID: %s", entry.Message)
+			}
+
+			access := entries[len(entries)-1]
+			fields := access.ContextMap()
+			assert.Equal(t, tt.expectedLevel, access.Level)
+			assert.Equal(t, http.MethodGet, fields["method"])
+			assert.Equal(t, tt.expectedRoute, fields["route"])
+			assert.EqualValues(t, tt.expectedStatus, fields["status"])
+			assert.Contains(t, fields, "latency")
+			assert.Contains(t, fields, "bytes")
+			assert.Contains(t, fields, "client_ip")
+		})
+	}
+}
+
+func TestRequestLogger_Bodies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name                 string
+		requestBody          string
+		expectedRequestBody  string
+		expectedResponseBody string
+	}{
+		{
+			name:                 "redacted",
+			requestBody:          `{"name":"John","password":"hunter2"}`,
+			expectedRequestBody:  `{"name":"John","password":"[REDACTED]"}`,
+			expectedResponseBody: `{"email":"j***@example.com","token":"[REDACTED]"}`,
+		},
+		{
+			name:                 "too large",
+			requestBody:          `{"name":"` + strings.Repeat("a", 5000) + `"}`,
+			expectedRequestBody:  "[TOO LARGE]",
+			expectedResponseBody: `{"email":"j***@example.com","token":"[REDACTED]"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, logs := observer.New(zapcore.DebugLevel)
+			log := logger.NewFromZap(zap.New(core))
+			router := gin.New()
+			router.Use(middleware.RequestLogger(log, middleware.LogBodies()))
+			router.POST("/login", func(c *gin.Context) {
+				body, err := io.ReadAll(c.Request.Body)
+				require.NoError(t, err)
+				assert.Equal(t, tt.requestBody, string(body), "handlers get the whole body")
+				c.JSON(http.StatusOK, gin.H{"email": "john@example.com", "token": "s3cr3t"})
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(tt.requestBody))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Contains(t, w.Body.String(), "s3cr3t", "responses are not altered")
+
+			entries := logs.FilterMessage("Request bodies").All()
+			require.Len(t, entries, 1)
+			fields := entries[0].ContextMap()
+			assert.Equal(t, tt.expectedRequestBody, fields["request_body"])
+			assert.Equal(t, tt.expectedResponseBody, fields["response_body"])
+			for _, entry := range logs.All() {
+				for _, value := range entry.ContextMap() {
+					assert.NotContains(t, fmt.Sprint(value), "hunter2")
+					assert.NotContains(t, fmt.Sprint(value), "s3cr3t")
+				}
+			}
+		})
+	}
+}
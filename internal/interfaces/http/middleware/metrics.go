@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/metrics"
+)
+
+// Metrics records the rate, errors and duration of requests by method,
+// route template and status, and the number of requests in flight.
+func Metrics(registry *metrics.Registry) gin.HandlerFunc {
+	requests := registry.NewCounterVec("http_requests_total",
+		"Total number of HTTP requests served.", "method", "route", "status")
+	duration := registry.NewHistogramVec("http_request_duration_seconds",
+		"Latency of HTTP requests.", nil, "method", "route", "status")
+	inFlight := registry.NewGaugeVec("http_requests_in_flight",
+		"Number of HTTP requests being served.").With()
+
+	return func(c *gin.Context) {
+		start := time.Now()
+		inFlight.Inc()
+		defer inFlight.Dec()
+
+		c.Next()
+
+		// Like for logs, the route template keeps the number of series
+		// bounded.
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		method := c.Request.Method
+		if !knownMethods[method] {
+			method = "OTHER"
+		}
+		status := strconv.Itoa(c.Writer.Status())
+		requests.With(method, route, status).Inc()
+		duration.With(method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
+
+// knownMethods are the methods counted apart; clients can send any other.
+var knownMethods = map[string]bool{
+	http.MethodGet: true, http.MethodHead: true, http.MethodPost: true, http.MethodPut: true,
+	http.MethodPatch: true, http.MethodDelete: true, http.MethodOptions: true,
+}
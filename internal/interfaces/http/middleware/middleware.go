@@ -1,4 +1,2 @@
 // Package middleware provides HTTP middleware functionalities.
 package middleware
-
-// TODO: Implement authentication and logging middleware.
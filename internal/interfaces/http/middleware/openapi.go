@@ -0,0 +1,127 @@
+package middleware
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers/legacy"
+	"github.com/gin-gonic/gin"
+
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/logger"
+)
+
+// OpenAPIValidator checks requests and responses against the API contract.
+// Routes that are not described by the contract are passed through.
+// When enforce is false violations are only logged; otherwise invalid
+// requests are rejected with 400 and invalid responses replaced with 500.
+func OpenAPIValidator(doc *openapi3.T, logger logger.Logger, enforce bool) (gin.HandlerFunc, error) {
+	// Match on paths only, so the validator works behind any host.
+	routing := *doc
+	routing.Servers = nil
+
+	router, err := legacy.NewRouter(&routing)
+	if err != nil {
+		return nil, err
+	}
+
+	options := &openapi3filter.Options{
+		MultiError:         true,
+		AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+	}
+	// Schema errors print the offending value by default, which may be a
+	// password; log where the value is and what's wrong with it instead.
+	options.WithCustomSchemaErrorFunc(func(err *openapi3.SchemaError) string {
+		return fmt.Sprintf("/%s: %s", strings.Join(err.JSONPointer(), "/"), err.Reason)
+	})
+
+	return func(c *gin.Context) {
+		route, pathParams, err := router.FindRoute(c.Request)
+		if err != nil {
+			c.Next()
+			return
+		}
+
+		input := &openapi3filter.RequestValidationInput{
+			Request:    c.Request,
+			PathParams: pathParams,
+			Route:      route,
+			Options:    options,
+		}
+
+		if err := openapi3filter.ValidateRequest(c.Request.Context(), input); err != nil {
+			logger.WithContext(c.Request.Context()).Error("request violates the API contract",
+				"method", c.Request.Method, "path", route.Path, "error", err)
+			if enforce {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+				return
+			}
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer, status: http.StatusOK}
+		c.Writer = recorder
+		c.Next()
+		c.Writer = recorder.ResponseWriter
+
+		err = openapi3filter.ValidateResponse(c.Request.Context(), &openapi3filter.ResponseValidationInput{
+			RequestValidationInput: input,
+			Status:                 recorder.status,
+			Header:                 recorder.Header(),
+			Body:                   io.NopCloser(bytes.NewReader(recorder.body.Bytes())),
+			Options:                options,
+		})
+		if err != nil {
+			logger.WithContext(c.Request.Context()).Error("response violates the API contract",
+				"method", c.Request.Method, "path", route.Path, "status", recorder.status, "error", err)
+			if enforce {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "response violates the API contract"})
+				return
+			}
+		}
+
+		recorder.flush()
+	}, nil
+}
+
+// responseRecorder buffers a response until it has been validated.
+type responseRecorder struct {
+	gin.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(code int) {
+	r.status = code
+}
+
+func (r *responseRecorder) WriteHeaderNow() {}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	return r.body.Write(b)
+}
+
+func (r *responseRecorder) WriteString(s string) (int, error) {
+	return r.body.WriteString(s)
+}
+
+func (r *responseRecorder) Status() int {
+	return r.status
+}
+
+func (r *responseRecorder) Size() int {
+	return r.body.Len()
+}
+
+func (r *responseRecorder) Written() bool {
+	return r.body.Len() > 0
+}
+
+// flush sends the buffered response to the client.
+func (r *responseRecorder) flush() {
+	r.ResponseWriter.WriteHeader(r.status)
+	_, _ = r.ResponseWriter.Write(r.body.Bytes())
+}
@@ -0,0 +1,162 @@
+package middleware_test
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/Sergey-Polishchenko/simple-api/api/openapi"
+	"github.com/Sergey-Polishchenko/simple-api/internal/interfaces/http/middleware"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/logger"
+)
+
+func newValidatedRouter(t *testing.T, enforce bool, response gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	spec, err := openapi.Load()
+	require.NoError(t, err)
+
+	validator, err := middleware.OpenAPIValidator(spec, logger.NewZapLogger(), enforce)
+	require.NoError(t, err)
+
+	router := gin.New()
+	router.Use(validator)
+	router.POST("/api/v1/users", response)
+	router.GET("/unknown", response)
+
+	return router
+}
+
+func TestOpenAPIValidator(t *testing.T) {
+	validResponse := func(c *gin.Context) {
+		c.JSON(http.StatusCreated, gin.H{"id": "1", "name": "John"})
+	}
+	invalidResponse := func(c *gin.Context) {
+		c.JSON(http.StatusCreated, gin.H{"id": 1})
+	}
+
+	tests := []struct {
+		name         string
+		enforce      bool
+		handler      gin.HandlerFunc
+		method       string
+		path         string
+		body         string
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "valid traffic",
+			enforce:      true,
+			handler:      validResponse,
+			method:       http.MethodPost,
+			path:         "/api/v1/users",
+			body:         `{"name":"John"}`,
+			expectedCode: http.StatusCreated,
+			expectedBody: `{"id":"1","name":"John"}`,
+		},
+		{
+			name:         "invalid request enforced",
+			enforce:      true,
+			handler:      validResponse,
+			method:       http.MethodPost,
+			path:         "/api/v1/users",
+			body:         `{"name":42}`,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"invalid request"}`,
+		},
+		{
+			name:         "invalid request logged only",
+			enforce:      false,
+			handler:      validResponse,
+			method:       http.MethodPost,
+			path:         "/api/v1/users",
+			body:         `{"name":42}`,
+			expectedCode: http.StatusCreated,
+			expectedBody: `{"id":"1","name":"John"}`,
+		},
+		{
+			name:         "invalid response enforced",
+			enforce:      true,
+			handler:      invalidResponse,
+			method:       http.MethodPost,
+			path:         "/api/v1/users",
+			body:         `{"name":"John"}`,
+			expectedCode: http.StatusInternalServerError,
+			expectedBody: `{"error":"response violates the API contract"}`,
+		},
+		{
+			name:         "invalid response logged only",
+			enforce:      false,
+			handler:      invalidResponse,
+			method:       http.MethodPost,
+			path:         "/api/v1/users",
+			body:         `{"name":"John"}`,
+			expectedCode: http.StatusCreated,
+			expectedBody: `{"id":1}`,
+		},
+		{
+			name:         "undocumented route",
+			enforce:      true,
+			handler:      invalidResponse,
+			method:       http.MethodGet,
+			path:         "/unknown",
+			expectedCode: http.StatusCreated,
+			expectedBody: `{"id":1}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newValidatedRouter(t, tt.enforce, tt.handler)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(tt.method, tt.path, bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			assert.JSONEq(t, tt.expectedBody, w.Body.String())
+		})
+	}
+}
+
+func TestOpenAPIValidator_HidesValues(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	spec, err := openapi.Load()
+	require.NoError(t, err)
+	core, logs := observer.New(zapcore.DebugLevel)
+	validator, err := middleware.OpenAPIValidator(spec, logger.NewFromZap(zap.New(core)), true)
+	require.NoError(t, err)
+
+	router := gin.New()
+	router.Use(validator)
+	router.POST("/api/v1/users", func(c *gin.Context) {
+		c.JSON(http.StatusCreated, gin.H{"id": "1", "name": "John"})
+	})
+
+	email := strings.Repeat("j", 250) + "@example.com"
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/api/v1/users", bytes.NewBufferString(`{"name":"John","email":"`+email+`"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	entries := logs.All()
+	require.Len(t, entries, 1)
+	violation := fmt.Sprint(entries[0].ContextMap()["error"])
+	assert.Contains(t, violation, "/email")
+	assert.NotContains(t, violation, email)
+}
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gin-gonic/gin"
+)
+
+// SkipPublic runs handler on every route except the operations the spec
+// declares public with an empty security requirement, like the login.
+func SkipPublic(doc *openapi3.T, handler gin.HandlerFunc) gin.HandlerFunc {
+	public := make(map[string]bool)
+	for path, item := range doc.Paths.Map() {
+		for method, operation := range item.Operations() {
+			if operation.Security != nil && len(*operation.Security) == 0 {
+				public[method+" "+openAPIPathToGin(path)] = true
+			}
+		}
+	}
+
+	return func(c *gin.Context) {
+		if public[c.Request.Method+" "+c.FullPath()] {
+			c.Next()
+			return
+		}
+		handler(c)
+	}
+}
+
+// openAPIPathToGin converts "/users/{id}" into "/users/:id".
+func openAPIPathToGin(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			segments[i] = ":" + segment[1:len(segment)-1]
+		}
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+)
+
+// RequestInfo makes the ID and the client IP of every request available to
+// the application, for the audit log. It must come after RequestLogger,
+// whose request ID it takes.
+func RequestInfo() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(app.ContextWithRequestInfo(c.Request.Context(), app.RequestInfo{
+			ID:       c.Writer.Header().Get(RequestIDHeader),
+			ClientIP: c.ClientIP(),
+		}))
+		c.Next()
+	}
+}
@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/logger"
+)
+
+// DefaultTenantHeader is the header naming the tenant of a request.
+const DefaultTenantHeader = "X-Tenant-ID"
+
+// TenantResolver tells whether a tenant exists.
+type TenantResolver interface {
+	Exists(ctx context.Context, id string) (bool, error)
+}
+
+// TenantConfig configures the resolution of the tenant of requests.
+type TenantConfig struct {
+	Tenants TenantResolver
+	// Header names the tenant, DefaultTenantHeader when empty.
+	Header string
+	// Domain, e.g. "example.com", makes "acme.example.com" resolve to the
+	// tenant "acme"; subdomains are ignored when empty.
+	Domain string
+}
+
+// Tenant scopes requests to the tenant named by their subdomain or header,
+// see app.ContextWithTenant. Requests naming none are scoped to the tenant
+// of their credentials, else the default one. Unknown tenants get 404.
+func Tenant(cfg TenantConfig, logger logger.Logger) gin.HandlerFunc {
+	header := cfg.Header
+	if header == "" {
+		header = DefaultTenantHeader
+	}
+	suffix := ""
+	if cfg.Domain != "" {
+		suffix = "." + strings.ToLower(strings.TrimPrefix(cfg.Domain, "."))
+	}
+
+	return func(c *gin.Context) {
+		fromHeader := c.GetHeader(header)
+		fromHost := ""
+		if suffix != "" {
+			fromHost = subdomain(c.Request.Host, suffix)
+		}
+
+		tenant := fromHeader
+		switch {
+		case fromHost != "" && fromHeader != "" && fromHost != fromHeader:
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "tenant header does not match the host"})
+			return
+		case fromHost != "":
+			tenant = fromHost
+		case tenant == "":
+			c.Next()
+			return
+		}
+
+		exists, err := cfg.Tenants.Exists(c.Request.Context(), tenant)
+		if err != nil {
+			logger.WithContext(c.Request.Context()).Error("can't resolve tenant", "tenant_id", tenant, "error", err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "can't resolve tenant"})
+			return
+		}
+		if !exists {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "unknown tenant"})
+			return
+		}
+
+		c.Request = c.Request.WithContext(app.ContextWithTenant(c.Request.Context(), tenant))
+		c.Next()
+	}
+}
+
+// subdomain returns the single label host has before suffix, if any.
+func subdomain(host, suffix string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	label, ok := strings.CutSuffix(strings.ToLower(host), suffix)
+	if !ok || label == "" || strings.Contains(label, ".") {
+		return ""
+	}
+	return label
+}
@@ -0,0 +1,112 @@
+package middleware_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	"github.com/Sergey-Polishchenko/simple-api/internal/interfaces/http/middleware"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/logger"
+)
+
+// stubTenants knows "acme" and fails on "broken".
+type stubTenants struct{}
+
+func (stubTenants) Exists(_ context.Context, id string) (bool, error) {
+	if id == "broken" {
+		return false, errors.New("db is down")
+	}
+	return id == "acme", nil
+}
+
+func TestTenant(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(middleware.Tenant(middleware.TenantConfig{Tenants: stubTenants{}, Domain: "example.com"}, logger.NewZapLogger()))
+	router.GET("/tenant", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"tenant": app.TenantFromContext(c.Request.Context())})
+	})
+
+	tests := []struct {
+		name         string
+		host         string
+		header       string
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "none",
+			host:         "example.com",
+			expectedCode: http.StatusOK,
+			expectedBody: `{"tenant":"default"}`,
+		},
+		{
+			name:         "header",
+			host:         "api.internal",
+			header:       "acme",
+			expectedCode: http.StatusOK,
+			expectedBody: `{"tenant":"acme"}`,
+		},
+		{
+			name:         "subdomain",
+			host:         "ACME.example.com:8080",
+			expectedCode: http.StatusOK,
+			expectedBody: `{"tenant":"acme"}`,
+		},
+		{
+			name:         "nested subdomain",
+			host:         "www.acme.example.com",
+			expectedCode: http.StatusOK,
+			expectedBody: `{"tenant":"default"}`,
+		},
+		{
+			name:         "matching subdomain and header",
+			host:         "acme.example.com",
+			header:       "acme",
+			expectedCode: http.StatusOK,
+			expectedBody: `{"tenant":"acme"}`,
+		},
+		{
+			name:         "conflicting subdomain and header",
+			host:         "acme.example.com",
+			header:       "globex",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `{"error":"tenant header does not match the host"}`,
+		},
+		{
+			name:         "unknown tenant",
+			host:         "globex.example.com",
+			expectedCode: http.StatusNotFound,
+			expectedBody: `{"error":"unknown tenant"}`,
+		},
+		{
+			name:         "store failure",
+			host:         "example.com",
+			header:       "broken",
+			expectedCode: http.StatusInternalServerError,
+			expectedBody: `{"error":"can't resolve tenant"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/tenant", nil)
+			req.Host = tt.host
+			if tt.header != "" {
+				req.Header.Set(middleware.DefaultTenantHeader, tt.header)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedCode, w.Code)
+			assert.JSONEq(t, tt.expectedBody, w.Body.String())
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/tracing"
+)
+
+// Tracing starts a server span for every request, continuing the trace
+// of the caller named by a valid traceparent header. The span is carried
+// by the request context, so that spans started while serving the
+// request are its children, and its context is echoed in a traceparent
+// response header.
+func Tracing(tracer *tracing.Tracer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		if parent, err := tracing.ParseTraceparent(c.GetHeader(tracing.TraceparentHeader)); err == nil {
+			ctx = tracing.ContextWithRemoteParent(ctx, parent)
+		}
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		ctx, span := tracer.Start(ctx, c.Request.Method+" "+route, tracing.KindServer,
+			tracing.String("http.request.method", c.Request.Method),
+			tracing.String("http.route", route),
+			tracing.String("url.path", c.Request.URL.Path),
+			tracing.String("client.address", c.ClientIP()),
+		)
+		defer span.End()
+		c.Request = c.Request.WithContext(ctx)
+		if sc := span.SpanContext(); sc.IsValid() {
+			c.Header(tracing.TraceparentHeader, sc.Traceparent())
+		}
+
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(tracing.Int("http.response.status_code", int64(status)))
+		if status >= http.StatusInternalServerError {
+			if len(c.Errors) > 0 {
+				span.RecordError(c.Errors.Last())
+			} else {
+				span.RecordError(errors.New(http.StatusText(status)))
+			}
+		}
+	}
+}
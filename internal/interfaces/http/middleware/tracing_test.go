@@ -0,0 +1,99 @@
+package middleware_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Sergey-Polishchenko/simple-api/internal/interfaces/http/middleware"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/tracing"
+)
+
+func TestTracing(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name            string
+		traceparent     string
+		path            string
+		expectedTraceID string
+		expectedName    string
+		expectedError   bool
+	}{
+		{
+			name:            "continued trace",
+			traceparent:     "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			path:            "/users/42",
+			expectedTraceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+			expectedName:    "GET /users/:id",
+		},
+		{
+			name:         "new trace",
+			path:         "/users/42",
+			expectedName: "GET /users/:id",
+		},
+		{
+			name:         "malformed traceparent",
+			traceparent:  "00-xyz-00f067aa0ba902b7-01",
+			path:         "/users/42",
+			expectedName: "GET /users/:id",
+		},
+		{
+			name:          "server error",
+			path:          "/fail",
+			expectedName:  "GET /fail",
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tracer := tracing.NewTracer(tracing.Config{ServiceName: "simple-api", Exporter: tracing.NewWriterExporter(&buf)})
+
+			var handlerSpan tracing.SpanContext
+			router := gin.New()
+			router.Use(middleware.Tracing(tracer))
+			router.GET("/users/:id", func(c *gin.Context) {
+				handlerSpan = tracing.SpanFromContext(c.Request.Context()).SpanContext()
+				c.String(http.StatusOK, "ok")
+			})
+			router.GET("/fail", func(c *gin.Context) {
+				c.String(http.StatusInternalServerError, "fail")
+			})
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.traceparent != "" {
+				req.Header.Set(tracing.TraceparentHeader, tt.traceparent)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			require.NoError(t, tracer.Shutdown(context.Background()))
+
+			echoed, err := tracing.ParseTraceparent(w.Header().Get(tracing.TraceparentHeader))
+			require.NoError(t, err)
+			if tt.expectedTraceID != "" {
+				assert.Equal(t, tt.expectedTraceID, echoed.TraceID.String())
+			}
+			if handlerSpan.IsValid() {
+				assert.Equal(t, echoed, handlerSpan, "handlers see the server span")
+			}
+
+			output := buf.String()
+			assert.Contains(t, output, `"name":"`+tt.expectedName+`"`)
+			assert.Contains(t, output, `"traceId":"`+echoed.TraceID.String()+`"`)
+			assert.Contains(t, output, `"kind":2`)
+			if tt.expectedError {
+				assert.Contains(t, output, `"status":{"code":2,"message":"Internal Server Error"}`)
+			} else {
+				assert.Contains(t, output, `"status":{}`)
+			}
+		})
+	}
+}
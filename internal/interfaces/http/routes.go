@@ -2,18 +2,177 @@
 package http
 
 import (
+	"errors"
+
 	"github.com/gin-gonic/gin"
 
+	"github.com/Sergey-Polishchenko/simple-api/api/openapi"
 	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	"github.com/Sergey-Polishchenko/simple-api/internal/interfaces/graphql"
+	"github.com/Sergey-Polishchenko/simple-api/internal/interfaces/http/handlers/docs"
+	"github.com/Sergey-Polishchenko/simple-api/internal/interfaces/http/handlers/health"
+	"github.com/Sergey-Polishchenko/simple-api/internal/interfaces/http/handlers/oidc"
 	v1 "github.com/Sergey-Polishchenko/simple-api/internal/interfaces/http/handlers/v1"
+	"github.com/Sergey-Polishchenko/simple-api/internal/interfaces/http/middleware"
+	pkghealth "github.com/Sergey-Polishchenko/simple-api/internal/pkg/health"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/jwks"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/logger"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/metrics"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/tracing"
 )
 
+// Config holds optional router dependencies and settings.
+type Config struct {
+	// UserEvents feeds GraphQL subscriptions; they are disabled when nil.
+	UserEvents app.UserEventSubscriber
+	GraphQL    graphql.Config
+	// Health serves the liveness, readiness and startup probes; they are
+	// not served when nil.
+	Health *pkghealth.Registry
+	// Metrics collects the metrics of requests; they are not collected when nil.
+	Metrics *metrics.Registry
+	// Tracer records a span for every request; requests are not traced
+	// when nil.
+	Tracer *tracing.Tracer
+	// LogBodies logs the bodies of requests and responses at debug level,
+	// redacted.
+	LogBodies bool
+	// EnforceContract rejects traffic violating the OpenAPI spec instead of only logging it.
+	EnforceContract bool
+	// JWT enables bearer authentication of the API.
+	JWT *middleware.JWTConfig
+	// APIKeys enables API key authentication and management.
+	// The API is open when neither JWT nor APIKeys is set.
+	APIKeys app.APIKeyService
+	// Auth enables password logins; the tokens it issues must pass JWT.
+	Auth app.AuthService
+	// OIDC enables the OpenID Connect provider, which requires Auth.
+	OIDC *OIDCConfig
+	// MFA enables the management of second factors; Auth should check them too.
+	MFA app.MFAService
+	// Accounts enables the email verification and password reset flows.
+	Accounts app.AccountService
+	// Groups enables the management of groups of users.
+	Groups app.GroupService
+	// Attributes enables the management of the custom attributes of users,
+	// which the user service must validate against the same schema.
+	Attributes app.AttributeSchemaService
+	// Audit serves the audit log of the changes of users.
+	Audit app.AuditService
+	// Privacy enables the export and the erasure of the data stored about
+	// users.
+	Privacy app.PrivacyService
+	// Tenants enables multi-tenancy; every request is scoped to the default
+	// tenant without it.
+	Tenants *TenantConfig
+}
+
+// TenantConfig configures multi-tenancy.
+type TenantConfig struct {
+	Service app.TenantService
+	// Header names the tenant of a request, middleware.DefaultTenantHeader when empty.
+	Header string
+	// Domain resolves the tenant from the subdomain of requests when set.
+	Domain string
+}
+
+// OIDCConfig configures the OpenID Connect provider.
+type OIDCConfig struct {
+	Service app.OIDCService
+	Clients app.OAuthClientService
+	Issuer  string
+	// Key verifies the tokens of the provider and is published as its JWKS.
+	Key jwks.Key
+}
+
 // NewRouter initializes a new HTTP router.
-func NewRouter(userService app.UserService) *gin.Engine {
-	r := gin.Default()
+func NewRouter(userService app.UserService, logger logger.Logger, cfg Config) (*gin.Engine, error) {
+	var loggerOpts []middleware.RequestLoggerOption
+	if cfg.LogBodies {
+		loggerOpts = append(loggerOpts, middleware.LogBodies())
+	}
+
+	r := gin.New()
+	// Probes come first, so that orchestrators calling them every few
+	// seconds don't flood the logs, and need no tenant or credentials.
+	if cfg.Health != nil {
+		health.RegisterRoutes(r, health.NewHandler(cfg.Health))
+	}
+	if cfg.Metrics != nil {
+		r.Use(middleware.Metrics(cfg.Metrics))
+	}
+	if cfg.Tracer != nil {
+		r.Use(middleware.Tracing(cfg.Tracer))
+	}
+	r.Use(middleware.RequestLogger(logger, loggerOpts...), middleware.Recovery(logger), middleware.RequestInfo())
+
+	var tenants app.TenantService
+	if cfg.Tenants != nil {
+		tenants = cfg.Tenants.Service
+		r.Use(middleware.Tenant(middleware.TenantConfig{
+			Tenants: tenants,
+			Header:  cfg.Tenants.Header,
+			Domain:  cfg.Tenants.Domain,
+		}, logger))
+	}
+
+	spec, err := openapi.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	docsHandler, err := docs.NewHandler(spec)
+	if err != nil {
+		return nil, err
+	}
+	docs.RegisterRoutes(r, docsHandler)
+
+	var oauthClients app.OAuthClientService
+	if cfg.OIDC != nil {
+		if cfg.Auth == nil {
+			return nil, errors.New("the OpenID Connect provider requires password logins")
+		}
+		oidcHandler, err := oidc.NewHandler(cfg.OIDC.Service, cfg.Auth, cfg.OIDC.Issuer, cfg.OIDC.Key, logger)
+		if err != nil {
+			return nil, err
+		}
+		oidc.RegisterRoutes(r, oidcHandler)
+		oauthClients = cfg.OIDC.Clients
+	}
+
+	var verifiers []middleware.Verifier
+	if cfg.JWT != nil {
+		verifiers = append(verifiers, middleware.NewJWTVerifier(*cfg.JWT))
+	}
+	if cfg.APIKeys != nil {
+		verifiers = append(verifiers, middleware.NewAPIKeyVerifier(cfg.APIKeys))
+	}
+	if len(verifiers) > 0 {
+		r.Use(middleware.SkipPublic(spec, middleware.Authenticate(logger, verifiers...)))
+	}
+
+	validator, err := middleware.OpenAPIValidator(spec, logger, cfg.EnforceContract)
+	if err != nil {
+		return nil, err
+	}
+	r.Use(validator)
+
+	v1.RegisterRoutes(r, v1.Handlers{
+		Users:        v1.NewUserHandler(userService),
+		APIKeys:      v1.NewAPIKeyHandler(cfg.APIKeys),
+		Auth:         v1.NewAuthHandler(cfg.Auth),
+		OAuthClients: v1.NewOAuthClientHandler(oauthClients),
+		MFA:          v1.NewMFAHandler(cfg.MFA),
+		Accounts:     v1.NewAccountHandler(cfg.Accounts),
+		Tenants:      v1.NewTenantHandler(tenants),
+		Groups:       v1.NewGroupHandler(cfg.Groups),
+		Attributes:   v1.NewAttributeHandler(cfg.Attributes),
+		Audit:        v1.NewAuditHandler(cfg.Audit),
+		Privacy:      v1.NewPrivacyHandler(cfg.Privacy),
+	})
 
-	handler := v1.NewUserHandler(userService)
-	v1.RegisterRoutes(r, handler)
+	graphQLHandler := graphql.NewHandler(userService, cfg.UserEvents, cfg.GraphQL)
+	graphql.RegisterRoutes(r, graphQLHandler)
 
-	return r
+	return r, nil
 }
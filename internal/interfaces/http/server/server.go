@@ -5,8 +5,6 @@ import (
 	"context"
 	"net/http"
 	"time"
-
-	"github.com/gin-gonic/gin"
 )
 
 // Server wraps an HTTP server instance.
@@ -15,11 +13,11 @@ type Server struct {
 }
 
 // New creates a new HTTP server instance.
-func New(addr string, router *gin.Engine) *Server {
+func New(addr string, handler http.Handler) *Server {
 	return &Server{
 		httpServer: &http.Server{
 			Addr:              addr,
-			Handler:           router,
+			Handler:           handler,
 			ReadHeaderTimeout: time.Second,
 		},
 	}
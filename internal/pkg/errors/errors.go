@@ -3,3 +3,73 @@ package errors
 import "fmt"
 
 var ErrUserNotFound = fmt.Errorf("user not found")
+
+var ErrAPIKeyNotFound = fmt.Errorf("api key not found")
+
+// ErrInvalidAPIKey is returned for unknown, revoked and expired API keys alike.
+var ErrInvalidAPIKey = fmt.Errorf("invalid api key")
+
+// ErrForbidden is returned when the caller is not allowed to perform an operation.
+var ErrForbidden = fmt.Errorf("forbidden")
+
+// ErrInvalidCredentials is returned for unknown users and wrong passwords alike.
+var ErrInvalidCredentials = fmt.Errorf("invalid credentials")
+
+var ErrRefreshTokenNotFound = fmt.Errorf("refresh token not found")
+
+// ErrInvalidRefreshToken is returned for unknown, used, revoked and expired refresh tokens alike.
+var ErrInvalidRefreshToken = fmt.Errorf("invalid refresh token")
+
+var ErrOAuthClientNotFound = fmt.Errorf("oauth client not found")
+
+var ErrAuthorizationCodeNotFound = fmt.Errorf("authorization code not found")
+
+var ErrMFANotEnrolled = fmt.Errorf("mfa is not enrolled")
+
+// ErrMFAEnabled is returned when enrolling a user whose MFA is enabled already.
+var ErrMFAEnabled = fmt.Errorf("mfa is already enabled")
+
+// ErrMFARequired is returned when a user with MFA enabled signs in with a password alone.
+var ErrMFARequired = fmt.Errorf("mfa code required")
+
+// ErrInvalidMFACode is returned for wrong, reused and expired codes alike.
+var ErrInvalidMFACode = fmt.Errorf("invalid mfa code")
+
+// ErrTooManyAttempts is returned while MFA verification of a user is locked out.
+var ErrTooManyAttempts = fmt.Errorf("too many attempts, try again later")
+
+var ErrEmailTaken = fmt.Errorf("email is already taken")
+
+// ErrInvalidAccountToken is returned for forged, used and expired email verification
+// and password reset tokens alike.
+var ErrInvalidAccountToken = fmt.Errorf("invalid or expired token")
+
+var ErrTenantNotFound = fmt.Errorf("tenant not found")
+
+var ErrTenantExists = fmt.Errorf("tenant already exists")
+
+// ErrTenantNotEmpty is returned when removing a tenant that still has users.
+var ErrTenantNotEmpty = fmt.Errorf("tenant still has users")
+
+var ErrGroupNotFound = fmt.Errorf("group not found")
+
+var ErrGroupNameTaken = fmt.Errorf("group name is already taken")
+
+// ErrGroupMemberNotFound is returned when removing a user or group that is
+// not a direct member of a group.
+var ErrGroupMemberNotFound = fmt.Errorf("group member not found")
+
+// ErrGroupCycle is returned when nesting a group in itself, directly or
+// through other groups.
+var ErrGroupCycle = fmt.Errorf("group membership would create a cycle")
+
+// ErrAttributeNotFound is returned for custom attributes missing from the schema.
+var ErrAttributeNotFound = fmt.Errorf("attribute not found")
+
+var ErrUserVersionNotFound = fmt.Errorf("user version not found")
+
+// ErrHistoryDisabled is returned when reading the past versions of users
+// without a history repository.
+var ErrHistoryDisabled = fmt.Errorf("user history is disabled")
+
+var ErrErasureReceiptNotFound = fmt.Errorf("erasure receipt not found")
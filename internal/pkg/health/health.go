@@ -0,0 +1,197 @@
+// Package health tracks the lifecycle of the service and checks its
+// dependencies, for the liveness, readiness and startup probes of
+// orchestrators.
+package health
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Status is the outcome of a check.
+type Status string
+
+const (
+	StatusUp   Status = "up"
+	StatusDown Status = "down"
+)
+
+var (
+	// ErrStarting is reported until the service has started.
+	ErrStarting = errors.New("starting")
+	// ErrShuttingDown is reported once the service has begun to shut down.
+	ErrShuttingDown = errors.New("shutting down")
+)
+
+// Checker checks a dependency of the service, like its database.
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// CheckerFunc adapts a function to Checker.
+type CheckerFunc func(ctx context.Context) error
+
+func (f CheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+// Pinger is a dependency that can be pinged, like *sql.DB.
+type Pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+// PingChecker checks a dependency by pinging it.
+func PingChecker(p Pinger) Checker {
+	return CheckerFunc(p.PingContext)
+}
+
+// Result is the outcome of the check of a dependency.
+type Result struct {
+	Status Status `json:"status"`
+	// Error tells why the dependency is down.
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// Report is the outcome of a probe.
+type Report struct {
+	Status Status `json:"status"`
+	// Error tells why the service is down regardless of its dependencies.
+	Error  string            `json:"error,omitempty"`
+	Checks map[string]Result `json:"checks,omitempty"`
+}
+
+// Up reports whether the probe succeeded.
+func (r Report) Up() bool {
+	return r.Status == StatusUp
+}
+
+// Registry holds the checkers of the dependencies the service needs to
+// serve requests, and tracks whether it has started and is shutting down.
+type Registry struct {
+	timeout time.Duration
+
+	mu       sync.RWMutex
+	checkers map[string]Checker
+
+	started      atomic.Bool
+	shuttingDown atomic.Bool
+}
+
+// NewRegistry initializes a Registry giving every check the given time.
+func NewRegistry(timeout time.Duration) *Registry {
+	return &Registry{timeout: timeout, checkers: map[string]Checker{}}
+}
+
+// Register adds the checker of a dependency, replacing the one of the same
+// name.
+func (r *Registry) Register(name string, checker Checker) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checkers[name] = checker
+}
+
+// MarkStarted marks the service as started, once it is fully initialized.
+func (r *Registry) MarkStarted() {
+	r.started.Store(true)
+}
+
+// MarkShuttingDown makes the service unready, so that traffic is routed
+// away from it before it stops.
+func (r *Registry) MarkShuttingDown() {
+	r.shuttingDown.Store(true)
+}
+
+// Live reports whether the process is alive; it has no dependencies, so
+// that a failing database doesn't get the service restarted.
+func (r *Registry) Live() Report {
+	return Report{Status: StatusUp}
+}
+
+// Started reports whether the service has finished starting.
+func (r *Registry) Started() Report {
+	if !r.started.Load() {
+		return Report{Status: StatusDown, Error: ErrStarting.Error()}
+	}
+	return Report{Status: StatusUp}
+}
+
+// Ready reports whether the service can serve requests: it has started,
+// isn't shutting down and all its dependencies are up. The dependencies
+// are checked concurrently.
+func (r *Registry) Ready(ctx context.Context) Report {
+	report := Report{Status: StatusUp, Checks: r.check(ctx)}
+	for _, result := range report.Checks {
+		if result.Status != StatusUp {
+			report.Status = StatusDown
+		}
+	}
+	switch {
+	case r.shuttingDown.Load():
+		report.Status, report.Error = StatusDown, ErrShuttingDown.Error()
+	case !r.started.Load():
+		report.Status, report.Error = StatusDown, ErrStarting.Error()
+	}
+	return report
+}
+
+func (r *Registry) check(ctx context.Context) map[string]Result {
+	r.mu.RLock()
+	names := make([]string, 0, len(r.checkers))
+	for name := range r.checkers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	checkers := make([]Checker, len(names))
+	for i, name := range names {
+		checkers[i] = r.checkers[name]
+	}
+	r.mu.RUnlock()
+
+	results := make([]Result, len(names))
+	var wg sync.WaitGroup
+	for i, checker := range checkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = r.run(ctx, checker)
+		}()
+	}
+	wg.Wait()
+
+	byName := make(map[string]Result, len(names))
+	for i, name := range names {
+		byName[name] = results[i]
+	}
+	return byName
+}
+
+// run runs a check, giving up once the timeout has passed even if the
+// checker ignores its context.
+func (r *Registry) run(ctx context.Context, checker Checker) Result {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- checker.Check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result := Result{Status: StatusUp, Duration: time.Since(start).Round(time.Microsecond).String()}
+	if err != nil {
+		result.Status, result.Error = StatusDown, err.Error()
+	}
+	return result
+}
@@ -0,0 +1,111 @@
+package health_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/health"
+)
+
+func TestRegistry_Ready(t *testing.T) {
+	up := health.CheckerFunc(func(context.Context) error { return nil })
+	down := health.CheckerFunc(func(context.Context) error { return errors.New("connection refused") })
+	hanging := health.CheckerFunc(func(context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	})
+
+	tests := []struct {
+		name           string
+		checkers       map[string]health.Checker
+		started        bool
+		shuttingDown   bool
+		expectedStatus health.Status
+		expectedError  string
+		expectedChecks map[string]health.Status
+	}{
+		{
+			name:           "ready",
+			checkers:       map[string]health.Checker{"database": up, "cache": up},
+			started:        true,
+			expectedStatus: health.StatusUp,
+			expectedChecks: map[string]health.Status{"database": health.StatusUp, "cache": health.StatusUp},
+		},
+		{
+			name:           "dependency down",
+			checkers:       map[string]health.Checker{"database": down, "cache": up},
+			started:        true,
+			expectedStatus: health.StatusDown,
+			expectedChecks: map[string]health.Status{"database": health.StatusDown, "cache": health.StatusUp},
+		},
+		{
+			name:           "dependency timing out",
+			checkers:       map[string]health.Checker{"database": hanging},
+			started:        true,
+			expectedStatus: health.StatusDown,
+			expectedChecks: map[string]health.Status{"database": health.StatusDown},
+		},
+		{
+			name:           "starting",
+			checkers:       map[string]health.Checker{"database": up},
+			expectedStatus: health.StatusDown,
+			expectedError:  "starting",
+			expectedChecks: map[string]health.Status{"database": health.StatusUp},
+		},
+		{
+			name:           "shutting down",
+			checkers:       map[string]health.Checker{"database": up},
+			started:        true,
+			shuttingDown:   true,
+			expectedStatus: health.StatusDown,
+			expectedError:  "shutting down",
+			expectedChecks: map[string]health.Status{"database": health.StatusUp},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := health.NewRegistry(50 * time.Millisecond)
+			for name, checker := range tt.checkers {
+				registry.Register(name, checker)
+			}
+			if tt.started {
+				registry.MarkStarted()
+			}
+			if tt.shuttingDown {
+				registry.MarkShuttingDown()
+			}
+
+			start := time.Now()
+			report := registry.Ready(context.Background())
+			assert.Less(t, time.Since(start), 500*time.Millisecond, "checks are bounded by the timeout")
+
+			assert.Equal(t, tt.expectedStatus, report.Status)
+			assert.Equal(t, tt.expectedError, report.Error)
+			checks := make(map[string]health.Status, len(report.Checks))
+			for name, result := range report.Checks {
+				checks[name] = result.Status
+				assert.Equal(t, result.Status == health.StatusDown, result.Error != "")
+				assert.NotEmpty(t, result.Duration)
+			}
+			assert.Equal(t, tt.expectedChecks, checks)
+		})
+	}
+}
+
+func TestRegistry_Lifecycle(t *testing.T) {
+	registry := health.NewRegistry(time.Second)
+	assert.True(t, registry.Live().Up())
+	assert.False(t, registry.Started().Up())
+
+	registry.MarkStarted()
+	assert.True(t, registry.Started().Up())
+
+	registry.MarkShuttingDown()
+	assert.True(t, registry.Live().Up(), "the process stays alive while shutting down")
+	assert.True(t, registry.Started().Up())
+}
@@ -0,0 +1,10 @@
+package jwks
+
+import "time"
+
+// SetClock replaces the clock used to schedule reloads.
+func (s *Set) SetClock(now func() time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.now = now
+}
@@ -0,0 +1,224 @@
+// Package jwks loads the keys used to verify JSON Web Tokens, either
+// configured statically or read from a JWKS file that may be rotated.
+package jwks
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// Supported signing algorithms.
+const (
+	HS256 = "HS256"
+	RS256 = "RS256"
+	ES256 = "ES256"
+)
+
+// Key is a verification key. Material is a []byte secret,
+// an *rsa.PublicKey or an *ecdsa.PublicKey.
+type Key struct {
+	// ID matches the "kid" header of the tokens signed with the key.
+	ID string
+	// Algorithm restricts the key to one algorithm; any compatible one if empty.
+	Algorithm string
+	Material  interface{}
+}
+
+// Supports reports whether the key can verify tokens signed with alg.
+func (k Key) Supports(alg string) bool {
+	if k.Algorithm != "" && k.Algorithm != alg {
+		return false
+	}
+
+	switch key := k.Material.(type) {
+	case []byte:
+		return alg == HS256
+	case *rsa.PublicKey:
+		return alg == RS256
+	case *ecdsa.PublicKey:
+		return alg == ES256 && key.Curve == elliptic.P256()
+	}
+	return false
+}
+
+// ErrNoKey is returned when no key matches a token.
+var ErrNoKey = errors.New("jwks: no matching key")
+
+// Match returns the keys that can verify a token with the given key ID and
+// algorithm. A token without a key ID matches every key supporting alg.
+func Match(keys []Key, kid, alg string) []Key {
+	var matched []Key
+	for _, key := range keys {
+		if kid != "" && key.ID != kid {
+			continue
+		}
+		if key.Supports(alg) {
+			matched = append(matched, key)
+		}
+	}
+	return matched
+}
+
+// jsonWebKey is the subset of RFC 7517 needed for verification keys.
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	Use string `json:"use,omitempty"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// EC
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+	// Symmetric
+	K string `json:"k,omitempty"`
+}
+
+// Parse decodes a JWKS document. Keys meant for encryption are skipped.
+func Parse(data []byte) ([]Key, error) {
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("jwks: %w", err)
+	}
+
+	keys := make([]Key, 0, len(set.Keys))
+	for i, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		material, err := jwk.material()
+		if err != nil {
+			return nil, fmt.Errorf("jwks: key %d (%q): %w", i, jwk.Kid, err)
+		}
+		keys = append(keys, Key{ID: jwk.Kid, Algorithm: jwk.Alg, Material: material})
+	}
+	return keys, nil
+}
+
+// Encode renders public keys as a JWKS document, e.g. for publishing the
+// keys of a Signer. Secrets are never published, so symmetric keys are an error.
+func Encode(keys []Key) ([]byte, error) {
+	set := struct {
+		Keys []jsonWebKey `json:"keys"`
+	}{Keys: make([]jsonWebKey, 0, len(keys))}
+
+	for _, key := range keys {
+		jwk := jsonWebKey{Kid: key.ID, Alg: key.Algorithm, Use: "sig"}
+		switch material := key.Material.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(material.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(material.E)).Bytes())
+		case *ecdsa.PublicKey:
+			if material.Curve != elliptic.P256() {
+				return nil, errors.New("jwks: unsupported curve")
+			}
+			jwk.Kty = "EC"
+			jwk.Crv = "P-256"
+			jwk.X = base64.RawURLEncoding.EncodeToString(material.X.FillBytes(make([]byte, 32)))
+			jwk.Y = base64.RawURLEncoding.EncodeToString(material.Y.FillBytes(make([]byte, 32)))
+		default:
+			return nil, fmt.Errorf("jwks: can't publish a key of type %T", key.Material)
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return json.Marshal(set)
+}
+
+func (jwk jsonWebKey) material() (interface{}, error) {
+	switch jwk.Kty {
+	case "oct":
+		return decode(jwk.K)
+	case "RSA":
+		n, err := decodeInt(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeInt(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		if jwk.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %q", jwk.Crv)
+		}
+		x, err := decodeInt(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeInt(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+		if !key.Curve.IsOnCurve(x, y) {
+			return nil, errors.New("point is not on the curve")
+		}
+		return key, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", jwk.Kty)
+}
+
+func decode(s string) ([]byte, error) {
+	if s == "" {
+		return nil, errors.New("missing key parameter")
+	}
+	return base64.RawURLEncoding.DecodeString(s)
+}
+
+func decodeInt(s string) (*big.Int, error) {
+	b, err := decode(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// ParsePublicKeyPEM decodes an RSA or ECDSA public key from a PEM
+// "PUBLIC KEY" block or a certificate.
+func ParsePublicKeyPEM(data []byte) (interface{}, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("jwks: no PEM block found")
+	}
+
+	var key interface{}
+	switch block.Type {
+	case "PUBLIC KEY":
+		var err error
+		if key, err = x509.ParsePKIXPublicKey(block.Bytes); err != nil {
+			return nil, fmt.Errorf("jwks: %w", err)
+		}
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("jwks: %w", err)
+		}
+		key = cert.PublicKey
+	default:
+		return nil, fmt.Errorf("jwks: unsupported PEM block %q", block.Type)
+	}
+
+	switch key.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey:
+		return key, nil
+	}
+	return nil, fmt.Errorf("jwks: unsupported public key type %T", key)
+}
@@ -0,0 +1,171 @@
+package jwks_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/jwks"
+)
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func ecJWK(t *testing.T, kid string) (map[string]string, *ecdsa.PublicKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	return map[string]string{
+		"kty": "EC", "kid": kid, "crv": "P-256",
+		"x": b64(key.X.FillBytes(make([]byte, 32))),
+		"y": b64(key.Y.FillBytes(make([]byte, 32))),
+	}, &key.PublicKey
+}
+
+func jwksDocument(t *testing.T, keys ...map[string]string) []byte {
+	data, err := json.Marshal(map[string]interface{}{"keys": keys})
+	require.NoError(t, err)
+	return data
+}
+
+func TestParse(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ec, ecPublic := ecJWK(t, "ec-1")
+
+	data := jwksDocument(t,
+		map[string]string{
+			"kty": "RSA", "kid": "rsa-1", "alg": "RS256", "use": "sig",
+			"n": b64(rsaKey.N.Bytes()), "e": b64(big.NewInt(int64(rsaKey.E)).Bytes()),
+		},
+		ec,
+		map[string]string{"kty": "oct", "kid": "hmac-1", "k": b64([]byte("secret"))},
+		map[string]string{"kty": "RSA", "kid": "enc-1", "use": "enc"},
+	)
+
+	keys, err := jwks.Parse(data)
+	require.NoError(t, err)
+	require.Len(t, keys, 3, "encryption keys are skipped")
+
+	assert.Equal(t, jwks.Key{ID: "rsa-1", Algorithm: "RS256", Material: &rsaKey.PublicKey}, keys[0])
+	assert.True(t, ecPublic.Equal(keys[1].Material))
+	assert.Equal(t, []byte("secret"), keys[2].Material)
+
+	assert.Len(t, jwks.Match(keys, "", jwks.ES256), 1)
+	assert.Len(t, jwks.Match(keys, "rsa-1", jwks.RS256), 1)
+	assert.Empty(t, jwks.Match(keys, "rsa-1", jwks.ES256))
+	assert.Empty(t, jwks.Match(keys, "ec-2", jwks.ES256))
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "Not JSON", data: `keys`},
+		{name: "Unknown key type", data: `{"keys":[{"kty":"OKP"}]}`},
+		{name: "Unsupported curve", data: `{"keys":[{"kty":"EC","crv":"P-384","x":"AQ","y":"AQ"}]}`},
+		{name: "Point off the curve", data: `{"keys":[{"kty":"EC","crv":"P-256","x":"AQ","y":"AQ"}]}`},
+		{name: "Missing modulus", data: `{"keys":[{"kty":"RSA","e":"AQAB"}]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := jwks.Parse([]byte(tt.data))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestParsePublicKeyPEM(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+
+	parsed, err := jwks.ParsePublicKeyPEM(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	require.NoError(t, err)
+	assert.True(t, key.PublicKey.Equal(parsed))
+
+	_, err = jwks.ParsePublicKeyPEM([]byte("not pem"))
+	assert.Error(t, err)
+}
+
+func TestSet_RotatesFileKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	oldKey, _ := ecJWK(t, "old")
+	newKey, _ := ecJWK(t, "new")
+
+	require.NoError(t, os.WriteFile(path, jwksDocument(t, oldKey), 0o600))
+
+	set, err := jwks.NewSet(nil, path, time.Hour)
+	require.NoError(t, err)
+
+	_, err = set.Lookup("old", jwks.ES256)
+	require.NoError(t, err)
+	_, err = set.Lookup("new", jwks.ES256)
+	assert.ErrorIs(t, err, jwks.ErrNoKey)
+
+	// Publish the new key; an unknown kid triggers a reload
+	// once the minimum reload interval has passed.
+	require.NoError(t, os.WriteFile(path, jwksDocument(t, newKey), 0o600))
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(path, later, later))
+	set.SetClock(func() time.Time { return later })
+
+	_, err = set.Lookup("new", jwks.ES256)
+	require.NoError(t, err)
+	_, err = set.Lookup("old", jwks.ES256)
+	assert.ErrorIs(t, err, jwks.ErrNoKey, "the retired key is dropped")
+
+	// A broken file keeps the previous keys.
+	require.NoError(t, os.WriteFile(path, []byte("{"), 0o600))
+	muchLater := later.Add(2 * time.Hour)
+	require.NoError(t, os.Chtimes(path, muchLater, muchLater))
+	set.SetClock(func() time.Time { return muchLater })
+
+	_, err = set.Lookup("new", jwks.ES256)
+	require.NoError(t, err)
+	assert.Error(t, set.Err())
+}
+
+func TestNewSet_MissingFile(t *testing.T) {
+	_, err := jwks.NewSet(nil, filepath.Join(t.TempDir(), "missing.json"), time.Minute)
+	assert.Error(t, err)
+}
+
+func TestEncode(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	keys := []jwks.Key{
+		{ID: "rsa", Algorithm: jwks.RS256, Material: &rsaKey.PublicKey},
+		{ID: "ec", Algorithm: jwks.ES256, Material: &ecKey.PublicKey},
+	}
+	data, err := jwks.Encode(keys)
+	require.NoError(t, err)
+
+	parsed, err := jwks.Parse(data)
+	require.NoError(t, err)
+	assert.Equal(t, keys, parsed)
+
+	_, err = jwks.Encode([]jwks.Key{{Algorithm: jwks.HS256, Material: []byte("secret")}})
+	assert.Error(t, err, "secrets are never published")
+}
@@ -0,0 +1,112 @@
+package jwks
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// minReload limits how often an unknown key ID may trigger a reload,
+// so that tokens with made-up key IDs can't make us hammer the disk.
+const minReload = 5 * time.Second
+
+// Set holds static keys and, optionally, the keys of a JWKS file. The file
+// is reloaded when it changes, so keys can be rotated without a restart:
+// publish the new key alongside the old one, switch signing to it, and
+// remove the old key once its tokens have expired.
+type Set struct {
+	static  []Key
+	path    string
+	refresh time.Duration
+	now     func() time.Time
+
+	mu       sync.Mutex
+	file     []Key
+	modTime  time.Time
+	checked  time.Time
+	lastLoad error
+}
+
+// NewSet creates a key set. If path is not empty, the JWKS file is read
+// immediately and checked for changes every refresh interval.
+func NewSet(static []Key, path string, refresh time.Duration) (*Set, error) {
+	s := &Set{static: static, path: path, refresh: refresh, now: time.Now}
+	if path == "" {
+		return s, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Lookup returns the keys that can verify a token with the given key ID and algorithm.
+func (s *Set) Lookup(kid, alg string) ([]Key, error) {
+	if s.path == "" {
+		return s.match(kid, alg, nil)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	since := s.now().Sub(s.checked)
+	if since >= s.refresh || (kid != "" && !s.known(kid) && since >= minReload) {
+		// A failed reload keeps the previous keys: the file may be mid-rewrite.
+		s.lastLoad = s.reload()
+	}
+	return s.match(kid, alg, s.file)
+}
+
+// Err returns the error of the last failed reload, if any.
+func (s *Set) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastLoad
+}
+
+func (s *Set) match(kid, alg string, file []Key) ([]Key, error) {
+	keys := Match(append(Match(s.static, kid, alg), file...), kid, alg)
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%w for kid %q and alg %s", ErrNoKey, kid, alg)
+	}
+	return keys, nil
+}
+
+func (s *Set) known(kid string) bool {
+	for _, key := range s.file {
+		if key.ID == kid {
+			return true
+		}
+	}
+	return false
+}
+
+// reload reads the JWKS file if it changed. It must be called with s.mu held.
+func (s *Set) reload() error {
+	s.checked = s.now()
+
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return err
+	}
+	if s.file != nil && info.ModTime().Equal(s.modTime) {
+		return nil
+	}
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return err
+	}
+	keys, err := Parse(data)
+	if err != nil {
+		return err
+	}
+
+	s.file = keys
+	s.modTime = info.ModTime()
+	return nil
+}
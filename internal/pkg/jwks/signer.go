@@ -0,0 +1,105 @@
+package jwks
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Signer signs tokens with an HS256 secret or an RS256/ES256 private key.
+type Signer struct {
+	key    interface{}
+	keyID  string
+	method jwt.SigningMethod
+	public Key
+}
+
+// NewSigner creates a Signer for a []byte secret, an *rsa.PrivateKey or a
+// P-256 *ecdsa.PrivateKey. A non-empty keyID is set as the "kid" header.
+func NewSigner(key interface{}, keyID string) (*Signer, error) {
+	s := &Signer{key: key, keyID: keyID}
+
+	switch k := key.(type) {
+	case []byte:
+		s.method = jwt.SigningMethodHS256
+		s.public = Key{ID: keyID, Algorithm: HS256, Material: k}
+	case *rsa.PrivateKey:
+		s.method = jwt.SigningMethodRS256
+		s.public = Key{ID: keyID, Algorithm: RS256, Material: &k.PublicKey}
+	case *ecdsa.PrivateKey:
+		if k.Curve != elliptic.P256() {
+			return nil, errors.New("jwks: ES256 requires a P-256 key")
+		}
+		s.method = jwt.SigningMethodES256
+		s.public = Key{ID: keyID, Algorithm: ES256, Material: &k.PublicKey}
+	default:
+		return nil, fmt.Errorf("jwks: unsupported signing key type %T", key)
+	}
+	return s, nil
+}
+
+// Sign returns a signed JWT carrying claims.
+func (s *Signer) Sign(claims map[string]interface{}) (string, error) {
+	token := jwt.NewWithClaims(s.method, jwt.MapClaims(claims))
+	if s.keyID != "" {
+		token.Header["kid"] = s.keyID
+	}
+	return token.SignedString(s.key)
+}
+
+// Verify checks a token signed by the signer and returns its claims.
+// Tokens must expire.
+func (s *Signer) Verify(token string) (map[string]interface{}, error) {
+	parser := jwt.NewParser(
+		jwt.WithValidMethods([]string{s.method.Alg()}),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
+
+	claims := jwt.MapClaims{}
+	_, err := parser.ParseWithClaims(token, claims, func(*jwt.Token) (interface{}, error) {
+		return s.public.Material, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// VerificationKey returns the key verifying the tokens of the signer.
+func (s *Signer) VerificationKey() Key {
+	return s.public
+}
+
+// ParsePrivateKeyPEM decodes an RSA or ECDSA private key from a PKCS #8,
+// PKCS #1 or SEC 1 PEM block.
+func ParsePrivateKeyPEM(data []byte) (interface{}, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("jwks: no PEM block found")
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("jwks: %w", err)
+		}
+		switch key.(type) {
+		case *rsa.PrivateKey, *ecdsa.PrivateKey:
+			return key, nil
+		}
+		return nil, fmt.Errorf("jwks: unsupported private key type %T", key)
+	}
+	return nil, fmt.Errorf("jwks: unsupported PEM block %q", block.Type)
+}
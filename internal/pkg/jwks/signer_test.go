@@ -0,0 +1,73 @@
+package jwks_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/jwks"
+)
+
+func TestSigner(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		key  interface{}
+		alg  string
+	}{
+		{name: "HS256", key: []byte("0123456789abcdef0123456789abcdef"), alg: jwks.HS256},
+		{name: "RS256", key: rsaKey, alg: jwks.RS256},
+		{name: "ES256", key: ecKey, alg: jwks.ES256},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signer, err := jwks.NewSigner(tt.key, "key-1")
+			require.NoError(t, err)
+			assert.Equal(t, "key-1", signer.VerificationKey().ID)
+			assert.True(t, signer.VerificationKey().Supports(tt.alg))
+
+			token, err := signer.Sign(map[string]interface{}{"sub": "1", "exp": time.Now().Add(time.Minute).Unix()})
+			require.NoError(t, err)
+
+			claims, err := signer.Verify(token)
+			require.NoError(t, err)
+			assert.Equal(t, "1", claims["sub"])
+
+			expired, err := signer.Sign(map[string]interface{}{"sub": "1", "exp": time.Now().Add(-time.Minute).Unix()})
+			require.NoError(t, err)
+			_, err = signer.Verify(expired)
+			assert.Error(t, err)
+		})
+	}
+
+	_, err = jwks.NewSigner("secret", "")
+	assert.Error(t, err)
+}
+
+func TestParsePrivateKeyPEM(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	parsed, err := jwks.ParsePrivateKeyPEM(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	require.NoError(t, err)
+	assert.True(t, key.Equal(parsed))
+
+	_, err = jwks.ParsePrivateKeyPEM([]byte("not pem"))
+	assert.Error(t, err)
+}
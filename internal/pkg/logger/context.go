@@ -0,0 +1,51 @@
+package logger
+
+import (
+	"context"
+	"slices"
+)
+
+type contextKey struct{}
+
+// ContextWith returns a copy of ctx carrying key-value pairs, in addition
+// to the ones ctx already carries, for Logger.WithContext to add to lines.
+// A value replaces the one ctx carries for the same key, so that nested
+// scopes, like the spans of a trace, can update a field.
+func ContextWith(ctx context.Context, keysAndValues ...interface{}) context.Context {
+	fields := slices.Clone(Fields(ctx))
+	for i := 0; i < len(keysAndValues); i++ {
+		key, ok := keysAndValues[i].(string)
+		if !ok || i+1 == len(keysAndValues) {
+			fields = append(fields, keysAndValues[i])
+			continue
+		}
+		if j := indexOfKey(fields, key); j >= 0 {
+			fields[j+1] = keysAndValues[i+1]
+		} else {
+			fields = append(fields, key, keysAndValues[i+1])
+		}
+		i++
+	}
+	return context.WithValue(ctx, contextKey{}, fields)
+}
+
+// indexOfKey returns the index of a key in key-value pairs, or -1.
+func indexOfKey(keysAndValues []interface{}, key string) int {
+	for i := 0; i < len(keysAndValues); i++ {
+		k, ok := keysAndValues[i].(string)
+		if !ok {
+			continue
+		}
+		if k == key && i+1 < len(keysAndValues) {
+			return i
+		}
+		i++
+	}
+	return -1
+}
+
+// Fields returns the key-value pairs carried by ctx.
+func Fields(ctx context.Context) []interface{} {
+	fields, _ := ctx.Value(contextKey{}).([]interface{})
+	return fields
+}
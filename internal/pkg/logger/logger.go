@@ -1,7 +1,18 @@
+// Package logger defines the structured logger of the application.
 package logger
 
+import "context"
+
+// Logger writes structured log lines. Fields are given as alternating keys
+// and values, e.g. Info("User created", "user_id", id).
 type Logger interface {
-	Debug(msg string, fields ...interface{})
-	Info(msg string, fields ...interface{})
-	Error(msg string, fields ...interface{})
+	Debug(msg string, keysAndValues ...interface{})
+	Info(msg string, keysAndValues ...interface{})
+	Warn(msg string, keysAndValues ...interface{})
+	Error(msg string, keysAndValues ...interface{})
+	// With returns a child logger adding the key-value pairs to every line.
+	With(keysAndValues ...interface{}) Logger
+	// WithContext returns a child logger adding the fields ctx carries,
+	// like the ID of the request behind it, see ContextWith.
+	WithContext(ctx context.Context) Logger
 }
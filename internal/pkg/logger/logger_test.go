@@ -0,0 +1,129 @@
+package logger_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/logger"
+)
+
+func newObservedLogger(level zapcore.Level) (logger.Logger, *observer.ObservedLogs) {
+	core, logs := observer.New(level)
+	return logger.NewFromZap(zap.New(core)), logs
+}
+
+func TestZapLogger_Fields(t *testing.T) {
+	log, logs := newObservedLogger(zapcore.DebugLevel)
+	ctx := logger.ContextWith(context.Background(), "request_id", "r1")
+	ctx = logger.ContextWith(ctx, "tenant_id", "acme")
+
+	log.With("component", "users").WithContext(ctx).Warn("User locked", "user_id", "42", "error", errors.New("too many attempts"))
+
+	entries := logs.All()
+	require.Len(t, entries, 1)
+	assert.Equal(t, zapcore.WarnLevel, entries[0].Level)
+	assert.Equal(t, map[string]interface{}{
+		"component":  "users",
+		"request_id": "r1",
+		"tenant_id":  "acme",
+		"user_id":    "42",
+		"error":      "too many attempts",
+	}, entries[0].ContextMap())
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      logger.Config
+		expectedErr string
+	}{
+		{name: "json", config: logger.Config{Level: "info", Format: "json"}},
+		{name: "console", config: logger.Config{Level: "debug", Format: "console"}},
+		{name: "unknown level", config: logger.Config{Level: "verbose"}, expectedErr: "unrecognized level"},
+		{name: "unknown format", config: logger.Config{Level: "info", Format: "xml"}, expectedErr: `unknown log format "xml"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log, err := logger.New(tt.config)
+			if tt.expectedErr != "" {
+				assert.ErrorContains(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.NotNil(t, log)
+		})
+	}
+}
+
+func TestZapLogger_SetLevel(t *testing.T) {
+	tests := []struct {
+		name          string
+		level         string
+		expectedLevel string
+		expectedErr   string
+	}{
+		{name: "debug", level: "debug", expectedLevel: "debug"},
+		{name: "error", level: "error", expectedLevel: "error"},
+		{name: "unknown level", level: "verbose", expectedLevel: "info", expectedErr: "unrecognized level"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log, err := logger.New(logger.Config{Level: "info"})
+			require.NoError(t, err)
+			child := log.With("component", "users")
+
+			err = log.(logger.LevelController).SetLevel(tt.level)
+			if tt.expectedErr != "" {
+				assert.ErrorContains(t, err, tt.expectedErr)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.expectedLevel, log.(logger.LevelController).Level())
+			assert.Equal(t, tt.expectedLevel, child.(logger.LevelController).Level())
+		})
+	}
+
+	t.Run("fixed level", func(t *testing.T) {
+		log, _ := newObservedLogger(zapcore.InfoLevel)
+		assert.ErrorIs(t, log.(logger.LevelController).SetLevel("debug"), logger.ErrFixedLevel)
+	})
+}
+
+func TestSlogHandler(t *testing.T) {
+	log, logs := newObservedLogger(zapcore.InfoLevel)
+	slogger := slog.New(logger.NewSlogHandler(log))
+	ctx := logger.ContextWith(context.Background(), "request_id", "r1")
+
+	slogger.DebugContext(ctx, "dropped")
+	slogger.With("lib", "cache").WithGroup("db").ErrorContext(ctx, "query failed",
+		"table", "users", slog.Group("pool", "size", 4))
+
+	entries := logs.All()
+	require.Len(t, entries, 1, "debug lines are below the level of the logger")
+	assert.Equal(t, zapcore.ErrorLevel, entries[0].Level)
+	assert.Equal(t, "query failed", entries[0].Message)
+	assert.Equal(t, map[string]interface{}{
+		"lib":          "cache",
+		"request_id":   "r1",
+		"db.table":     "users",
+		"db.pool.size": int64(4),
+	}, entries[0].ContextMap())
+}
+
+func TestContextWith(t *testing.T) {
+	ctx := logger.ContextWith(context.Background(), "request_id", "r1", "span_id", "s1")
+	nested := logger.ContextWith(ctx, "span_id", "s2", "user_id", "42")
+
+	assert.Equal(t, []interface{}{"request_id", "r1", "span_id", "s1"}, logger.Fields(ctx), "the parent context is left alone")
+	assert.Equal(t, []interface{}{"request_id", "r1", "span_id", "s2", "user_id", "42"}, logger.Fields(nested))
+}
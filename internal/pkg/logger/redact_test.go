@@ -0,0 +1,134 @@
+package logger_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/logger"
+)
+
+// newBufferLogger logs JSON lines to a buffer, so that tests see exactly
+// what would be written.
+func newBufferLogger() (logger.Logger, *bytes.Buffer) {
+	var buf bytes.Buffer
+	core := zapcore.NewCore(zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()), zapcore.AddSync(&buf), zapcore.DebugLevel)
+	return logger.NewFromZap(zap.New(core)), &buf
+}
+
+type credentials struct {
+	Username string `json:"username"`
+	Secret   string `json:"pin" redact:"true"`
+}
+
+func TestZapLogger_Redaction(t *testing.T) {
+	const secret = "hunter2"
+
+	tests := []struct {
+		name     string
+		log      func(logger.Logger)
+		contains []string
+	}{
+		{
+			name: "sensitive keys",
+			log: func(l logger.Logger) {
+				l.Info("Login", "password", secret, "access_token", secret, "Authorization", "Bearer "+secret, "api_key_id", "k1")
+			},
+			contains: []string{`"password":"[REDACTED]"`, `"access_token":"[REDACTED]"`, `"api_key_id":"k1"`},
+		},
+		{
+			name: "nested keys",
+			log: func(l logger.Logger) {
+				l.Info("Request", "body", map[string]interface{}{"user": map[string]interface{}{"name": "John", "new_password": secret}})
+			},
+			contains: []string{`"name":"John"`, `"new_password":"[REDACTED]"`},
+		},
+		{
+			name: "tagged struct fields",
+			log: func(l logger.Logger) {
+				l.Info("Credentials", "credentials", credentials{Username: "john", Secret: secret})
+			},
+			contains: []string{`"username":"john"`, `"pin":"[REDACTED]"`},
+		},
+		{
+			name: "connection string in an error",
+			log: func(l logger.Logger) {
+				l.Error("Can't connect", "error", errors.New("failed to connect to `host=db user=app password="+secret+" dbname=users`"))
+			},
+			contains: []string{"password=[REDACTED] dbname=users"},
+		},
+		{
+			name: "URL in a message",
+			log: func(l logger.Logger) {
+				l.Warn("Retrying postgres://app:" + secret + "@db:5432/users")
+			},
+			contains: []string{"postgres://app:[REDACTED]@db:5432/users"},
+		},
+		{
+			name: "token in a link",
+			log: func(l logger.Logger) {
+				l.Info("Email", "body", "Open https://example.com/reset?token="+secret+"&lang=en")
+			},
+			contains: []string{"token=[REDACTED]&lang=en"},
+		},
+		{
+			name: "child and context fields",
+			log: func(l logger.Logger) {
+				ctx := logger.ContextWith(context.Background(), "session_secret", secret)
+				l.With("refresh_token", secret).WithContext(ctx).Debug("Session refreshed")
+			},
+			contains: []string{`"refresh_token":"[REDACTED]"`, `"session_secret":"[REDACTED]"`},
+		},
+		{
+			name: "masked email",
+			log: func(l logger.Logger) {
+				l.Info("Email sent", "email", "john@example.com")
+			},
+			contains: []string{`"email":"j***@example.com"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log, buf := newBufferLogger()
+			tt.log(log)
+
+			output := buf.String()
+			assert.NotContains(t, output, secret)
+			assert.NotContains(t, output, "john@example.com")
+			for _, s := range tt.contains {
+				assert.Contains(t, output, s)
+			}
+		})
+	}
+}
+
+func TestRedactor_JSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		expected string
+	}{
+		{
+			name:     "object",
+			data:     `{"name":"John","password":"hunter2","factors":[{"secret":"JBSWY3DP","type":"totp"}]}`,
+			expected: `{"factors":[{"secret":"[REDACTED]","type":"totp"}],"name":"John","password":"[REDACTED]"}`,
+		},
+		{
+			name:     "not JSON",
+			data:     `password=hunter2`,
+			expected: logger.Redacted,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, logger.DefaultRedactor.JSON([]byte(tt.data)))
+		})
+	}
+}
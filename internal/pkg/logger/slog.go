@@ -0,0 +1,82 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+)
+
+// NewSlogHandler returns a slog.Handler writing to the logger, so that
+// libraries logging with log/slog feed the same sink. Attributes in groups
+// get dotted keys, like "group.key".
+func NewSlogHandler(logger Logger) slog.Handler {
+	return &slogHandler{logger: logger}
+}
+
+type slogHandler struct {
+	logger Logger
+	// prefix is the dotted path of the open groups, ending with a dot.
+	prefix string
+}
+
+func (h *slogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	if leveled, ok := h.logger.(interface{ Enabled(slog.Level) bool }); ok {
+		return leveled.Enabled(level)
+	}
+	return true
+}
+
+func (h *slogHandler) Handle(ctx context.Context, record slog.Record) error {
+	keysAndValues := make([]interface{}, 0, 2*record.NumAttrs())
+	record.Attrs(func(attr slog.Attr) bool {
+		keysAndValues = appendAttr(keysAndValues, h.prefix, attr)
+		return true
+	})
+
+	logger := h.logger.WithContext(ctx)
+	switch {
+	case record.Level >= slog.LevelError:
+		logger.Error(record.Message, keysAndValues...)
+	case record.Level >= slog.LevelWarn:
+		logger.Warn(record.Message, keysAndValues...)
+	case record.Level >= slog.LevelInfo:
+		logger.Info(record.Message, keysAndValues...)
+	default:
+		logger.Debug(record.Message, keysAndValues...)
+	}
+	return nil
+}
+
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var keysAndValues []interface{}
+	for _, attr := range attrs {
+		keysAndValues = appendAttr(keysAndValues, h.prefix, attr)
+	}
+	return &slogHandler{logger: h.logger.With(keysAndValues...), prefix: h.prefix}
+}
+
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &slogHandler{logger: h.logger, prefix: h.prefix + name + "."}
+}
+
+// appendAttr flattens an attribute into key-value pairs.
+func appendAttr(keysAndValues []interface{}, prefix string, attr slog.Attr) []interface{} {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return keysAndValues
+	}
+
+	if attr.Value.Kind() == slog.KindGroup {
+		// Attributes of groups without a key are inlined.
+		if attr.Key != "" {
+			prefix += attr.Key + "."
+		}
+		for _, member := range attr.Value.Group() {
+			keysAndValues = appendAttr(keysAndValues, prefix, member)
+		}
+		return keysAndValues
+	}
+	return append(keysAndValues, prefix+attr.Key, attr.Value.Any())
+}
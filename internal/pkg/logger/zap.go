@@ -1,24 +1,146 @@
 package logger
 
-import "go.uber.org/zap"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
 
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Config configures the logger built by New.
+type Config struct {
+	// Level is the minimum level written: "debug", "info", "warn" or "error".
+	Level string
+	// Format is "json", or "console" for human-readable lines.
+	Format string
+	// Redactor masks sensitive values, DefaultRedactor when nil.
+	Redactor *Redactor
+}
+
+// ZapLogger writes to a zap logger, redacting the fields of every line.
 type ZapLogger struct {
-	logger *zap.Logger
+	logger   *zap.SugaredLogger
+	redactor *Redactor
+	// level changes the level of loggers built by New at runtime.
+	level *zap.AtomicLevel
 }
 
+// LevelController reads and changes the level of a logger at runtime.
+type LevelController interface {
+	// Level returns the minimum level written, e.g. "info".
+	Level() string
+	// SetLevel changes the minimum level written, for the logger and all
+	// the loggers derived from it.
+	SetLevel(level string) error
+}
+
+// ErrFixedLevel is returned when changing the level of a logger wrapping
+// a zap logger built elsewhere.
+var ErrFixedLevel = errors.New("the level of the logger can't be changed")
+
+// New builds a logger writing to stderr.
+func New(cfg Config) (Logger, error) {
+	level, err := zapcore.ParseLevel(cfg.Level)
+	if err != nil {
+		return nil, err
+	}
+
+	zapConfig := zap.NewProductionConfig()
+	atomicLevel := zap.NewAtomicLevelAt(level)
+	zapConfig.Level = atomicLevel
+	switch cfg.Format {
+	case "", "json":
+	case "console":
+		zapConfig.Encoding = "console"
+		zapConfig.EncoderConfig = zap.NewDevelopmentEncoderConfig()
+	default:
+		return nil, fmt.Errorf("unknown log format %q", cfg.Format)
+	}
+
+	logger, err := zapConfig.Build()
+	if err != nil {
+		return nil, err
+	}
+	redactor := cfg.Redactor
+	if redactor == nil {
+		redactor = DefaultRedactor
+	}
+	return &ZapLogger{logger: logger.Sugar(), redactor: redactor, level: &atomicLevel}, nil
+}
+
+// NewZapLogger builds a logger writing JSON lines of level info and above.
 func NewZapLogger() Logger {
 	logger, _ := zap.NewProduction()
-	return &ZapLogger{logger: logger}
+	return NewFromZap(logger)
 }
 
-func (z *ZapLogger) Info(msg string, fields ...interface{}) {
-	z.logger.Info(msg, zap.Any("fields", fields))
+// NewFromZap wraps a zap logger, redacting with DefaultRedactor.
+func NewFromZap(logger *zap.Logger) Logger {
+	return &ZapLogger{logger: logger.Sugar(), redactor: DefaultRedactor}
 }
 
-func (z *ZapLogger) Debug(msg string, fields ...interface{}) {
-	z.logger.Debug(msg, zap.Any("fields", fields))
+func (z *ZapLogger) Debug(msg string, keysAndValues ...interface{}) {
+	z.logger.Debugw(z.redactor.String(msg), z.redactor.Fields(keysAndValues)...)
 }
 
-func (z *ZapLogger) Error(msg string, fields ...interface{}) {
-	z.logger.Error(msg, zap.Any("fields", fields))
+func (z *ZapLogger) Info(msg string, keysAndValues ...interface{}) {
+	z.logger.Infow(z.redactor.String(msg), z.redactor.Fields(keysAndValues)...)
 }
+
+func (z *ZapLogger) Warn(msg string, keysAndValues ...interface{}) {
+	z.logger.Warnw(z.redactor.String(msg), z.redactor.Fields(keysAndValues)...)
+}
+
+func (z *ZapLogger) Error(msg string, keysAndValues ...interface{}) {
+	z.logger.Errorw(z.redactor.String(msg), z.redactor.Fields(keysAndValues)...)
+}
+
+func (z *ZapLogger) With(keysAndValues ...interface{}) Logger {
+	return &ZapLogger{logger: z.logger.With(z.redactor.Fields(keysAndValues)...), redactor: z.redactor, level: z.level}
+}
+
+func (z *ZapLogger) Level() string {
+	return z.logger.Level().String()
+}
+
+func (z *ZapLogger) SetLevel(level string) error {
+	if z.level == nil {
+		return ErrFixedLevel
+	}
+	parsed, err := zapcore.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	z.level.SetLevel(parsed)
+	return nil
+}
+
+func (z *ZapLogger) WithContext(ctx context.Context) Logger {
+	fields := Fields(ctx)
+	if len(fields) == 0 {
+		return z
+	}
+	return z.With(fields...)
+}
+
+// Enabled reports whether lines of the slog level are written.
+func (z *ZapLogger) Enabled(level slog.Level) bool {
+	zapLevel := zapcore.DebugLevel
+	switch {
+	case level >= slog.LevelError:
+		zapLevel = zapcore.ErrorLevel
+	case level >= slog.LevelWarn:
+		zapLevel = zapcore.WarnLevel
+	case level >= slog.LevelInfo:
+		zapLevel = zapcore.InfoLevel
+	}
+	return z.logger.Desugar().Core().Enabled(zapLevel)
+}
+
+var (
+	_ Logger          = (*ZapLogger)(nil)
+	_ LevelController = (*ZapLogger)(nil)
+)
@@ -0,0 +1,385 @@
+// Package metrics collects counters, gauges and histograms and exposes them
+// in the Prometheus text format, so that Prometheus can scrape the service.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultBuckets are the upper bounds of histogram buckets suited to
+// request latencies, in seconds.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// labelSeparator joins label values into the keys of the series of a
+// metric; it can't appear in valid UTF-8.
+const labelSeparator = "\xff"
+
+// Registry holds metrics and writes them in the Prometheus text format.
+type Registry struct {
+	mu      sync.Mutex
+	metrics map[string]metric
+}
+
+// metric is a family of series sharing a name.
+type metric interface {
+	write(w *bufio.Writer, name string)
+}
+
+// NewRegistry initializes an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{metrics: map[string]metric{}}
+}
+
+// register adds a metric, panicking on duplicate names like a misspelt
+// metric would go unnoticed otherwise.
+func (r *Registry) register(name, help, kind string, m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.metrics[name]; ok {
+		panic(fmt.Sprintf("metrics: %q is already registered", name))
+	}
+	r.metrics[name] = &family{help: help, kind: kind, metric: m}
+}
+
+// family adds the HELP and TYPE lines to a metric.
+type family struct {
+	help   string
+	kind   string
+	metric metric
+}
+
+func (f *family) write(w *bufio.Writer, name string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, escapeHelp(f.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, f.kind)
+	f.metric.write(w, name)
+}
+
+// ServeHTTP writes the metrics sorted by name.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	r.mu.Lock()
+	names := make([]string, 0, len(r.metrics))
+	for name := range r.metrics {
+		names = append(names, name)
+	}
+	snapshot := make(map[string]metric, len(r.metrics))
+	for name, m := range r.metrics {
+		snapshot[name] = m
+	}
+	r.mu.Unlock()
+	sort.Strings(names)
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	buf := bufio.NewWriter(w)
+	for _, name := range names {
+		snapshot[name].write(buf, name)
+	}
+	_ = buf.Flush()
+}
+
+// vec holds the series of a metric by their label values.
+type vec[T any] struct {
+	labels []string
+	newT   func() T
+
+	mu     sync.RWMutex
+	series map[string]T
+}
+
+func newVec[T any](labels []string, newT func() T) *vec[T] {
+	return &vec[T]{labels: labels, newT: newT, series: map[string]T{}}
+}
+
+func (v *vec[T]) with(values []string) T {
+	if len(values) != len(v.labels) {
+		panic(fmt.Sprintf("metrics: got %d label values for %d labels", len(values), len(v.labels)))
+	}
+	key := strings.Join(values, labelSeparator)
+
+	v.mu.RLock()
+	s, ok := v.series[key]
+	v.mu.RUnlock()
+	if ok {
+		return s
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if s, ok := v.series[key]; ok {
+		return s
+	}
+	s = v.newT()
+	v.series[key] = s
+	return s
+}
+
+// each calls fn with the series sorted by their label values.
+func (v *vec[T]) each(fn func(values []string, s T)) {
+	v.mu.RLock()
+	keys := make([]string, 0, len(v.series))
+	for key := range v.series {
+		keys = append(keys, key)
+	}
+	series := make([]T, len(keys))
+	sort.Strings(keys)
+	for i, key := range keys {
+		series[i] = v.series[key]
+	}
+	v.mu.RUnlock()
+
+	for i, key := range keys {
+		var values []string
+		if len(v.labels) > 0 {
+			values = strings.Split(key, labelSeparator)
+		}
+		fn(values, series[i])
+	}
+}
+
+// value is a float64 updated atomically.
+type value struct {
+	bits atomic.Uint64
+}
+
+func (v *value) add(delta float64) {
+	for {
+		old := v.bits.Load()
+		if v.bits.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+delta)) {
+			return
+		}
+	}
+}
+
+func (v *value) set(f float64) {
+	v.bits.Store(math.Float64bits(f))
+}
+
+func (v *value) get() float64 {
+	return math.Float64frombits(v.bits.Load())
+}
+
+// Counter is a value that only goes up.
+type Counter struct {
+	value value
+}
+
+// Inc adds one to the counter.
+func (c *Counter) Inc() {
+	c.value.add(1)
+}
+
+// Add adds a non-negative delta to the counter.
+func (c *Counter) Add(delta float64) {
+	if delta < 0 {
+		panic("metrics: counters can't decrease")
+	}
+	c.value.add(delta)
+}
+
+// CounterVec is a counter partitioned by labels.
+type CounterVec struct {
+	*vec[*Counter]
+}
+
+// NewCounterVec registers a counter with the given labels.
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{newVec(labels, func() *Counter { return &Counter{} })}
+	r.register(name, help, "counter", c)
+	return c
+}
+
+// With returns the counter of the given label values.
+func (c *CounterVec) With(values ...string) *Counter {
+	return c.with(values)
+}
+
+func (c *CounterVec) write(w *bufio.Writer, name string) {
+	c.each(func(values []string, counter *Counter) {
+		writeSample(w, name, formatLabels(c.labels, values), counter.value.get())
+	})
+}
+
+// Gauge is a value that goes up and down.
+type Gauge struct {
+	value value
+}
+
+// Set sets the gauge.
+func (g *Gauge) Set(f float64) {
+	g.value.set(f)
+}
+
+// Inc adds one to the gauge.
+func (g *Gauge) Inc() {
+	g.value.add(1)
+}
+
+// Dec subtracts one from the gauge.
+func (g *Gauge) Dec() {
+	g.value.add(-1)
+}
+
+// GaugeVec is a gauge partitioned by labels.
+type GaugeVec struct {
+	*vec[*Gauge]
+}
+
+// NewGaugeVec registers a gauge with the given labels.
+func (r *Registry) NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	g := &GaugeVec{newVec(labels, func() *Gauge { return &Gauge{} })}
+	r.register(name, help, "gauge", g)
+	return g
+}
+
+// With returns the gauge of the given label values.
+func (g *GaugeVec) With(values ...string) *Gauge {
+	return g.with(values)
+}
+
+func (g *GaugeVec) write(w *bufio.Writer, name string) {
+	g.each(func(values []string, gauge *Gauge) {
+		writeSample(w, name, formatLabels(g.labels, values), gauge.value.get())
+	})
+}
+
+// Histogram counts observations in buckets.
+type Histogram struct {
+	buckets []float64
+
+	mu     sync.Mutex
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// Observe records an observation.
+func (h *Histogram) Observe(f float64) {
+	i := sort.SearchFloat64s(h.buckets, f)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if i < len(h.counts) {
+		h.counts[i]++
+	}
+	h.count++
+	h.sum += f
+}
+
+// HistogramVec is a histogram partitioned by labels.
+type HistogramVec struct {
+	*vec[*Histogram]
+}
+
+// NewHistogramVec registers a histogram with the given bucket upper
+// bounds, DefaultBuckets when nil, and labels.
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+
+	h := &HistogramVec{newVec(labels, func() *Histogram {
+		return &Histogram{buckets: buckets, counts: make([]uint64, len(buckets))}
+	})}
+	r.register(name, help, "histogram", h)
+	return h
+}
+
+// With returns the histogram of the given label values.
+func (h *HistogramVec) With(values ...string) *Histogram {
+	return h.with(values)
+}
+
+func (h *HistogramVec) write(w *bufio.Writer, name string) {
+	// Buckets are cumulative, and the le label comes last.
+	bucketLabels := append(append([]string(nil), h.labels...), "le")
+	h.each(func(values []string, histogram *Histogram) {
+		histogram.mu.Lock()
+		counts := append([]uint64(nil), histogram.counts...)
+		count, sum := histogram.count, histogram.sum
+		histogram.mu.Unlock()
+
+		bucketValues := append(append([]string(nil), values...), "")
+		var cumulative uint64
+		for i, bound := range histogram.buckets {
+			cumulative += counts[i]
+			bucketValues[len(values)] = formatValue(bound)
+			writeSample(w, name+"_bucket", formatLabels(bucketLabels, bucketValues), float64(cumulative))
+		}
+		bucketValues[len(values)] = "+Inf"
+		writeSample(w, name+"_bucket", formatLabels(bucketLabels, bucketValues), float64(count))
+
+		labels := formatLabels(h.labels, values)
+		writeSample(w, name+"_sum", labels, sum)
+		writeSample(w, name+"_count", labels, float64(count))
+	})
+}
+
+// funcMetric reads its value when scraped.
+type funcMetric func() float64
+
+// NewGaugeFunc registers a gauge whose value fn returns when scraped.
+func (r *Registry) NewGaugeFunc(name, help string, fn func() float64) {
+	r.register(name, help, "gauge", funcMetric(fn))
+}
+
+// NewCounterFunc registers a counter whose value fn returns when scraped.
+func (r *Registry) NewCounterFunc(name, help string, fn func() float64) {
+	r.register(name, help, "counter", funcMetric(fn))
+}
+
+func (f funcMetric) write(w *bufio.Writer, name string) {
+	writeSample(w, name, "", f())
+}
+
+func writeSample(w *bufio.Writer, name, labels string, v float64) {
+	fmt.Fprintf(w, "%s%s %s\n", name, labels, formatValue(v))
+}
+
+func formatLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(name)
+		b.WriteString(`="`)
+		b.WriteString(labelValueEscaper.Replace(values[i]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpEscaper       = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeHelp(help string) string {
+	return helpEscaper.Replace(help)
+}
@@ -0,0 +1,94 @@
+package metrics_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/metrics"
+)
+
+func scrape(t *testing.T, registry *metrics.Registry) string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	registry.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", w.Header().Get("Content-Type"))
+	return w.Body.String()
+}
+
+func TestRegistry(t *testing.T) {
+	registry := metrics.NewRegistry()
+	requests := registry.NewCounterVec("requests_total", "Total number of requests.", "method", "path")
+	inFlight := registry.NewGaugeVec("in_flight", "Requests in flight.")
+	latency := registry.NewHistogramVec("latency_seconds", "Latency.\nIn seconds.", []float64{1, 0.1}, "method")
+	registry.NewGaugeFunc("answer", "The answer.", func() float64 { return 42 })
+
+	requests.With("GET", "/users").Inc()
+	requests.With("GET", "/users").Add(2)
+	requests.With("POST", `/a"b\c`).Inc()
+	inFlight.With().Inc()
+	inFlight.With().Inc()
+	inFlight.With().Dec()
+	latency.With("GET").Observe(0.05)
+	latency.With("GET").Observe(0.5)
+	latency.With("GET").Observe(3)
+
+	assert.Equal(t, `# HELP answer The answer.
+# TYPE answer gauge
+answer 42
+# HELP in_flight Requests in flight.
+# TYPE in_flight gauge
+in_flight 1
+# HELP latency_seconds Latency.\nIn seconds.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{method="GET",le="0.1"} 1
+latency_seconds_bucket{method="GET",le="1"} 2
+latency_seconds_bucket{method="GET",le="+Inf"} 3
+latency_seconds_sum{method="GET"} 3.55
+latency_seconds_count{method="GET"} 3
+# HELP requests_total Total number of requests.
+# TYPE requests_total counter
+requests_total{method="GET",path="/users"} 3
+requests_total{method="POST",path="/a\"b\\c"} 1
+`, scrape(t, registry))
+}
+
+func TestRegistry_Misuse(t *testing.T) {
+	registry := metrics.NewRegistry()
+	requests := registry.NewCounterVec("requests_total", "Total number of requests.", "method")
+
+	assert.Panics(t, func() { registry.NewGaugeVec("requests_total", "Duplicate.") }, "duplicate name")
+	assert.Panics(t, func() { requests.With("GET", "/users") }, "wrong number of labels")
+	assert.Panics(t, func() { requests.With("GET").Add(-1) }, "decreasing counter")
+}
+
+func TestRegisterRuntime(t *testing.T) {
+	registry := metrics.NewRegistry()
+	registry.RegisterRuntime()
+
+	body := scrape(t, registry)
+	for _, name := range []string{"go_goroutines ", "go_info{version=", "go_memstats_alloc_bytes ", "go_gc_cycles_total ", "process_start_time_seconds "} {
+		assert.Contains(t, body, "\n"+name)
+	}
+}
+
+func TestOperations(t *testing.T) {
+	registry := metrics.NewRegistry()
+	operations := registry.NewOperations()
+
+	for _, err := range []error{nil, nil, errors.New("boom")} {
+		_, end := operations.StartOperation(context.Background(), "user", "create")
+		end(err)
+	}
+
+	body := scrape(t, registry)
+	assert.Contains(t, body, `app_operations_total{service="user",operation="create"} 3`)
+	assert.Contains(t, body, `app_operation_errors_total{service="user",operation="create"} 1`)
+	assert.Contains(t, body, `app_operation_duration_seconds_count{service="user",operation="create"} 3`)
+}
@@ -0,0 +1,38 @@
+package metrics
+
+import (
+	"context"
+	"time"
+)
+
+// Operations counts the operations of application services, their errors
+// and their latency.
+type Operations struct {
+	total    *CounterVec
+	errors   *CounterVec
+	duration *HistogramVec
+}
+
+// NewOperations registers the metrics of service operations.
+func (r *Registry) NewOperations() *Operations {
+	return &Operations{
+		total: r.NewCounterVec("app_operations_total",
+			"Total number of service operations.", "service", "operation"),
+		errors: r.NewCounterVec("app_operation_errors_total",
+			"Total number of service operations that failed.", "service", "operation"),
+		duration: r.NewHistogramVec("app_operation_duration_seconds",
+			"Latency of service operations.", nil, "service", "operation"),
+	}
+}
+
+// StartOperation implements app.OperationObserver.
+func (o *Operations) StartOperation(ctx context.Context, service, operation string) (context.Context, func(error)) {
+	start := time.Now()
+	return ctx, func(err error) {
+		o.duration.With(service, operation).Observe(time.Since(start).Seconds())
+		o.total.With(service, operation).Inc()
+		if err != nil {
+			o.errors.With(service, operation).Inc()
+		}
+	}
+}
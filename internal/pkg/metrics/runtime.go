@@ -0,0 +1,93 @@
+package metrics
+
+import (
+	"database/sql"
+	"runtime"
+	"runtime/pprof"
+	"sync"
+	"time"
+)
+
+// memStatsMaxAge bounds how stale the memory statistics of a scrape can
+// be; reading them stops the world, so one read serves all the gauges.
+const memStatsMaxAge = time.Second
+
+// RegisterRuntime registers the usual Go runtime metrics: goroutines,
+// threads, memory and garbage collection.
+func (r *Registry) RegisterRuntime() {
+	var (
+		mu     sync.Mutex
+		stats  runtime.MemStats
+		readAt time.Time
+	)
+	memStats := func(fn func(*runtime.MemStats) float64) func() float64 {
+		return func() float64 {
+			mu.Lock()
+			defer mu.Unlock()
+			if time.Since(readAt) > memStatsMaxAge {
+				runtime.ReadMemStats(&stats)
+				readAt = time.Now()
+			}
+			return fn(&stats)
+		}
+	}
+
+	r.NewGaugeVec("go_info", "Version of the Go runtime.", "version").With(runtime.Version()).Set(1)
+	r.NewGaugeFunc("go_goroutines", "Number of goroutines that currently exist.", func() float64 {
+		return float64(runtime.NumGoroutine())
+	})
+	r.NewGaugeFunc("go_threads", "Number of OS threads created.", func() float64 {
+		return float64(pprof.Lookup("threadcreate").Count())
+	})
+	r.NewGaugeFunc("go_memstats_alloc_bytes", "Number of bytes allocated and still in use.",
+		memStats(func(s *runtime.MemStats) float64 { return float64(s.Alloc) }))
+	r.NewCounterFunc("go_memstats_alloc_bytes_total", "Total number of bytes allocated, even if freed.",
+		memStats(func(s *runtime.MemStats) float64 { return float64(s.TotalAlloc) }))
+	r.NewGaugeFunc("go_memstats_sys_bytes", "Number of bytes obtained from the system.",
+		memStats(func(s *runtime.MemStats) float64 { return float64(s.Sys) }))
+	r.NewGaugeFunc("go_memstats_heap_inuse_bytes", "Number of heap bytes that are in use.",
+		memStats(func(s *runtime.MemStats) float64 { return float64(s.HeapInuse) }))
+	r.NewGaugeFunc("go_memstats_heap_objects", "Number of allocated objects.",
+		memStats(func(s *runtime.MemStats) float64 { return float64(s.HeapObjects) }))
+	r.NewCounterFunc("go_memstats_mallocs_total", "Total number of mallocs.",
+		memStats(func(s *runtime.MemStats) float64 { return float64(s.Mallocs) }))
+	r.NewCounterFunc("go_memstats_frees_total", "Total number of frees.",
+		memStats(func(s *runtime.MemStats) float64 { return float64(s.Frees) }))
+	r.NewGaugeFunc("go_memstats_next_gc_bytes", "Heap size at which the next garbage collection takes place.",
+		memStats(func(s *runtime.MemStats) float64 { return float64(s.NextGC) }))
+	r.NewCounterFunc("go_gc_cycles_total", "Number of completed garbage collection cycles.",
+		memStats(func(s *runtime.MemStats) float64 { return float64(s.NumGC) }))
+	r.NewCounterFunc("go_gc_pause_seconds_total", "Total time the world was stopped for garbage collection.",
+		memStats(func(s *runtime.MemStats) float64 { return float64(s.PauseTotalNs) / float64(time.Second) }))
+
+	start := float64(time.Now().UnixNano()) / float64(time.Second)
+	r.NewGaugeFunc("process_start_time_seconds", "Start time of the process since the Unix epoch.", func() float64 {
+		return start
+	})
+}
+
+// RegisterDBStats registers the connection pool statistics of a database.
+func (r *Registry) RegisterDBStats(db *sql.DB) {
+	stat := func(fn func(sql.DBStats) float64) func() float64 {
+		return func() float64 {
+			return fn(db.Stats())
+		}
+	}
+
+	r.NewGaugeFunc("db_pool_max_open_connections", "Maximum number of open connections, 0 for unlimited.",
+		stat(func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) }))
+	r.NewGaugeFunc("db_pool_open_connections", "Number of open connections, in use or idle.",
+		stat(func(s sql.DBStats) float64 { return float64(s.OpenConnections) }))
+	r.NewGaugeFunc("db_pool_in_use_connections", "Number of connections in use.",
+		stat(func(s sql.DBStats) float64 { return float64(s.InUse) }))
+	r.NewGaugeFunc("db_pool_idle_connections", "Number of idle connections.",
+		stat(func(s sql.DBStats) float64 { return float64(s.Idle) }))
+	r.NewCounterFunc("db_pool_wait_count_total", "Total number of waits for a connection.",
+		stat(func(s sql.DBStats) float64 { return float64(s.WaitCount) }))
+	r.NewCounterFunc("db_pool_wait_duration_seconds_total", "Total time spent waiting for a connection.",
+		stat(func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() }))
+	r.NewCounterFunc("db_pool_max_idle_closed_total", "Total number of connections closed for exceeding the idle limit.",
+		stat(func(s sql.DBStats) float64 { return float64(s.MaxIdleClosed + s.MaxIdleTimeClosed) }))
+	r.NewCounterFunc("db_pool_max_lifetime_closed_total", "Total number of connections closed for exceeding their lifetime.",
+		stat(func(s sql.DBStats) float64 { return float64(s.MaxLifetimeClosed) }))
+}
@@ -0,0 +1,70 @@
+package password_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/password"
+)
+
+// cheap keeps the tests fast.
+var cheap = password.Params{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func TestHasher(t *testing.T) {
+	hasher := password.NewHasher(cheap)
+
+	hash, err := hasher.Hash("correct horse")
+	require.NoError(t, err)
+	assert.Regexp(t, `^\$argon2id\$v=19\$m=1024,t=1,p=1\$[A-Za-z0-9+/]{22}\$[A-Za-z0-9+/]{43}$`, hash)
+
+	other, err := hasher.Hash("correct horse")
+	require.NoError(t, err)
+	assert.NotEqual(t, hash, other, "hashes are salted")
+
+	match, rehash, err := hasher.Verify("correct horse", hash)
+	require.NoError(t, err)
+	assert.True(t, match)
+	assert.False(t, rehash)
+
+	match, _, err = hasher.Verify("battery staple", hash)
+	require.NoError(t, err)
+	assert.False(t, match)
+}
+
+func TestHasher_Rehash(t *testing.T) {
+	hash, err := password.NewHasher(cheap).Hash("correct horse")
+	require.NoError(t, err)
+
+	stronger := cheap
+	stronger.Iterations = 2
+
+	match, rehash, err := password.NewHasher(stronger).Verify("correct horse", hash)
+	require.NoError(t, err)
+	assert.True(t, match, "old hashes keep working")
+	assert.True(t, rehash)
+
+	match, rehash, err = password.NewHasher(stronger).Verify("wrong", hash)
+	require.NoError(t, err)
+	assert.False(t, match)
+	assert.False(t, rehash)
+}
+
+func TestHasher_MalformedHash(t *testing.T) {
+	hasher := password.NewHasher(cheap)
+
+	for _, hash := range []string{
+		"",
+		"plain text",
+		"$2a$10$abcdefghijklmnopqrstuu",
+		"$argon2i$v=19$m=1024,t=1,p=1$c2FsdA$a2V5",
+		"$argon2id$v=16$m=1024,t=1,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=x,t=1,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=1024,t=1,p=1$!!$a2V5",
+		"$argon2id$v=19$m=1024,t=1,p=1$c2FsdA$",
+	} {
+		_, _, err := hasher.Verify("password", hash)
+		assert.ErrorIs(t, err, password.ErrMalformedHash, hash)
+	}
+}
@@ -0,0 +1,95 @@
+package totp_test
+
+import (
+	"encoding/base32"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/totp"
+)
+
+// rfcSecret is the SHA-1 key of the RFC 6238 test vectors.
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestCode(t *testing.T) {
+	// The last 6 digits of the 8 digit test vectors of RFC 6238, appendix B.
+	tests := []struct {
+		time     int64
+		expected string
+	}{
+		{time: 59, expected: "287082"},
+		{time: 1111111109, expected: "081804"},
+		{time: 1111111111, expected: "050471"},
+		{time: 1234567890, expected: "005924"},
+		{time: 2000000000, expected: "279037"},
+		{time: 20000000000, expected: "353130"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			code, err := totp.Code(rfcSecret, totp.Step(time.Unix(tt.time, 0)))
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, code)
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+
+	tests := []struct {
+		name         string
+		code         string
+		expectedStep int64
+		expectedOK   bool
+	}{
+		{name: "current step", code: "081804", expectedStep: totp.Step(now), expectedOK: true},
+		{name: "previous step", code: mustCode(t, totp.Step(now)-1), expectedStep: totp.Step(now) - 1, expectedOK: true},
+		{name: "next step", code: mustCode(t, totp.Step(now)+1), expectedStep: totp.Step(now) + 1, expectedOK: true},
+		{name: "two steps ago", code: mustCode(t, totp.Step(now)-2)},
+		{name: "wrong code", code: "000000"},
+		{name: "wrong length", code: "81804"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := totp.Validate(rfcSecret, tt.code, now)
+			assert.Equal(t, tt.expectedOK, ok)
+			if tt.expectedOK {
+				assert.Equal(t, tt.expectedStep, step)
+			}
+		})
+	}
+}
+
+func mustCode(t *testing.T, step int64) string {
+	code, err := totp.Code(rfcSecret, step)
+	require.NoError(t, err)
+	return code
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := totp.GenerateSecret()
+	require.NoError(t, err)
+	assert.Len(t, secret, 32)
+
+	other, err := totp.GenerateSecret()
+	require.NoError(t, err)
+	assert.NotEqual(t, secret, other)
+}
+
+func TestURI(t *testing.T) {
+	uri, err := url.Parse(totp.URI("Simple API", "john@example.com", "JBSWY3DPEHPK3PXP"))
+	require.NoError(t, err)
+
+	assert.Equal(t, "otpauth", uri.Scheme)
+	assert.Equal(t, "totp", uri.Host)
+	assert.Equal(t, "/Simple API:john@example.com", uri.Path)
+	assert.Equal(t, "JBSWY3DPEHPK3PXP", uri.Query().Get("secret"))
+	assert.Equal(t, "Simple API", uri.Query().Get("issuer"))
+	assert.Equal(t, "6", uri.Query().Get("digits"))
+}
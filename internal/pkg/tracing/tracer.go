@@ -0,0 +1,284 @@
+package tracing
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/logger"
+)
+
+const (
+	// maxQueuedSpans bounds the spans waiting for export; spans ended
+	// while the queue is full are dropped.
+	maxQueuedSpans = 2048
+	// maxBatchSize is the number of spans exported at once.
+	maxBatchSize = 512
+	// exportInterval is how long ended spans wait for a batch to fill.
+	exportInterval = 5 * time.Second
+)
+
+// SpanKind tells the role of a span in a trace.
+type SpanKind int
+
+// The values are the ones of OTLP.
+const (
+	KindInternal SpanKind = 1
+	KindServer   SpanKind = 2
+	KindClient   SpanKind = 3
+)
+
+// Attribute describes a span. Values are strings, bools, ints or floats.
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// String returns a string attribute.
+func String(key, value string) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Int returns an integer attribute.
+func Int(key string, value int64) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// Bool returns a boolean attribute.
+func Bool(key string, value bool) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+// SpanData is an ended span, as exported.
+type SpanData struct {
+	Context    SpanContext
+	Parent     SpanID
+	Name       string
+	Kind       SpanKind
+	Start, End time.Time
+	Attributes []Attribute
+	// Err is the error the span failed with, if any.
+	Err error
+}
+
+// Config configures a Tracer.
+type Config struct {
+	// ServiceName names the service in the exported spans.
+	ServiceName string
+	// Sampler decides which new traces are recorded, AlwaysSample when
+	// nil. Traces started by other services are recorded if they are.
+	Sampler Sampler
+	// Exporter receives the recorded spans in batches.
+	Exporter Exporter
+	// Logger reports failed exports.
+	Logger logger.Logger
+}
+
+// Tracer starts spans and exports the recorded ones in the background.
+// A nil Tracer starts no spans.
+type Tracer struct {
+	config Config
+	spans  chan SpanData
+
+	closeOnce sync.Once
+	closed    chan struct{}
+	done      chan struct{}
+}
+
+// NewTracer initializes a Tracer and starts exporting spans.
+func NewTracer(config Config) *Tracer {
+	if config.Sampler == nil {
+		config.Sampler = AlwaysSample()
+	}
+	t := &Tracer{
+		config: config,
+		spans:  make(chan SpanData, maxQueuedSpans),
+		closed: make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go t.export()
+	return t
+}
+
+// Start starts a span, a child of the span ctx carries if any, and
+// returns a copy of ctx carrying it. The IDs of the span are added to
+// the lines logged with the returned context.
+func (t *Tracer) Start(ctx context.Context, name string, kind SpanKind, attributes ...Attribute) (context.Context, *Span) {
+	if t == nil {
+		return ctx, nil
+	}
+
+	parent := parentFromContext(ctx)
+	span := &Span{
+		tracer:     t,
+		name:       name,
+		kind:       kind,
+		start:      time.Now(),
+		attributes: attributes,
+	}
+	if parent.IsValid() {
+		span.context = SpanContext{TraceID: parent.TraceID, SpanID: newSpanID(), Sampled: parent.Sampled}
+		span.parent = parent.SpanID
+	} else {
+		traceID := newTraceID()
+		span.context = SpanContext{TraceID: traceID, SpanID: newSpanID(), Sampled: t.config.Sampler(traceID)}
+	}
+
+	ctx = context.WithValue(ctx, spanKey{}, span)
+	ctx = logger.ContextWith(ctx, "trace_id", span.context.TraceID.String(), "span_id", span.context.SpanID.String())
+	return ctx, span
+}
+
+// StartOperation starts an internal span named after the service and the
+// operation; it implements app.OperationObserver.
+func (t *Tracer) StartOperation(ctx context.Context, service, operation string) (context.Context, func(error)) {
+	ctx, span := t.Start(ctx, service+"."+operation, KindInternal)
+	return ctx, func(err error) {
+		span.RecordError(err)
+		span.End()
+	}
+}
+
+// Shutdown exports the spans ended so far and stops the Tracer; spans
+// ended afterwards are dropped.
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+	t.closeOnce.Do(func() { close(t.closed) })
+	select {
+	case <-t.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// enqueue queues an ended span for export without ever blocking.
+func (t *Tracer) enqueue(span SpanData) {
+	select {
+	case <-t.closed:
+		return
+	default:
+	}
+	select {
+	case t.spans <- span:
+	default:
+	}
+}
+
+// export sends spans in batches, once a batch is full or has waited for
+// exportInterval.
+func (t *Tracer) export() {
+	defer close(t.done)
+
+	ticker := time.NewTicker(exportInterval)
+	defer ticker.Stop()
+
+	batch := make([]SpanData, 0, maxBatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), exportInterval)
+		defer cancel()
+		if err := t.config.Exporter.Export(ctx, t.config.ServiceName, batch); err != nil && t.config.Logger != nil {
+			t.config.Logger.Warn("can't export spans", "spans", len(batch), "error", err)
+		}
+		batch = make([]SpanData, 0, maxBatchSize)
+	}
+
+	for {
+		select {
+		case span := <-t.spans:
+			if batch = append(batch, span); len(batch) == maxBatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-t.closed:
+			for {
+				select {
+				case span := <-t.spans:
+					if batch = append(batch, span); len(batch) == maxBatchSize {
+						flush()
+					}
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+// Span is an operation within a trace. A nil Span records nothing.
+type Span struct {
+	tracer  *Tracer
+	context SpanContext
+	parent  SpanID
+	name    string
+	kind    SpanKind
+	start   time.Time
+
+	mu         sync.Mutex
+	attributes []Attribute
+	err        error
+	ended      bool
+}
+
+// SpanContext returns the IDs of the span.
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.context
+}
+
+// SetAttributes adds attributes to the span.
+func (s *Span) SetAttributes(attributes ...Attribute) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attributes = append(s.attributes, attributes...)
+}
+
+// RecordError marks the span as failed if err is not nil.
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+}
+
+// End ends the span, queueing it for export if its trace is sampled.
+// Only the first call has an effect.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ended {
+		return
+	}
+	s.ended = true
+	if !s.context.Sampled {
+		return
+	}
+
+	s.tracer.enqueue(SpanData{
+		Context:    s.context,
+		Parent:     s.parent,
+		Name:       s.name,
+		Kind:       s.kind,
+		Start:      s.start,
+		End:        time.Now(),
+		Attributes: append([]Attribute(nil), s.attributes...),
+		Err:        s.err,
+	})
+}
@@ -0,0 +1,168 @@
+// Package tracing records the spans of requests as they cross the layers
+// of the service, propagates traces with W3C Trace Context headers and
+// exports spans in the OpenTelemetry protocol (OTLP).
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// TraceparentHeader carries the trace context of a request, see
+// https://www.w3.org/TR/trace-context/.
+const TraceparentHeader = "traceparent"
+
+// TraceID identifies a trace.
+type TraceID [16]byte
+
+func (id TraceID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// IsValid reports whether the ID is not all zeros.
+func (id TraceID) IsValid() bool {
+	return id != TraceID{}
+}
+
+// SpanID identifies a span within a trace.
+type SpanID [8]byte
+
+func (id SpanID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// IsValid reports whether the ID is not all zeros.
+func (id SpanID) IsValid() bool {
+	return id != SpanID{}
+}
+
+// SpanContext is the part of a span propagated to its children, possibly
+// in other services.
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	// Sampled tells whether the trace is recorded.
+	Sampled bool
+}
+
+// IsValid reports whether the span context identifies a span.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// Traceparent formats the span context as a traceparent header.
+func (sc SpanContext) Traceparent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return fmt.Sprintf("00-%s-%s-%s", sc.TraceID, sc.SpanID, flags)
+}
+
+// ErrInvalidTraceparent is returned for malformed traceparent headers.
+var ErrInvalidTraceparent = errors.New("invalid traceparent")
+
+// ParseTraceparent parses a traceparent header. Headers of future versions
+// are read as version 00, as the specification requires.
+func ParseTraceparent(header string) (SpanContext, error) {
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return SpanContext{}, ErrInvalidTraceparent
+	}
+
+	var sc SpanContext
+	var flags [1]byte
+	for _, field := range []struct {
+		hex string
+		dst []byte
+	}{
+		{parts[0], make([]byte, 1)},
+		{parts[1], sc.TraceID[:]},
+		{parts[2], sc.SpanID[:]},
+		{parts[3], flags[:]},
+	} {
+		if len(field.hex) != 2*len(field.dst) || strings.ToLower(field.hex) != field.hex {
+			return SpanContext{}, ErrInvalidTraceparent
+		}
+		if _, err := hex.Decode(field.dst, []byte(field.hex)); err != nil {
+			return SpanContext{}, ErrInvalidTraceparent
+		}
+	}
+	if !sc.IsValid() {
+		return SpanContext{}, ErrInvalidTraceparent
+	}
+	sc.Sampled = flags[0]&1 == 1
+	return sc, nil
+}
+
+type (
+	spanKey   struct{}
+	remoteKey struct{}
+)
+
+// ContextWithRemoteParent returns a copy of ctx making spans started from
+// it children of a span of another service, e.g. read from the
+// traceparent header of a request.
+func ContextWithRemoteParent(ctx context.Context, parent SpanContext) context.Context {
+	return context.WithValue(ctx, remoteKey{}, parent)
+}
+
+// SpanFromContext returns the span ctx carries, or nil.
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// parentFromContext returns the span context of the parent of the spans
+// started from ctx: the one of its span, else the remote one it carries.
+func parentFromContext(ctx context.Context) SpanContext {
+	if span := SpanFromContext(ctx); span != nil {
+		return span.context
+	}
+	parent, _ := ctx.Value(remoteKey{}).(SpanContext)
+	return parent
+}
+
+func newTraceID() TraceID {
+	var id TraceID
+	for !id.IsValid() {
+		_, _ = rand.Read(id[:])
+	}
+	return id
+}
+
+func newSpanID() SpanID {
+	var id SpanID
+	for !id.IsValid() {
+		_, _ = rand.Read(id[:])
+	}
+	return id
+}
+
+// Sampler decides whether the spans of a new trace are recorded.
+type Sampler func(traceID TraceID) bool
+
+// AlwaysSample records every trace.
+func AlwaysSample() Sampler {
+	return func(TraceID) bool { return true }
+}
+
+// RatioSampler records the given fraction of traces, deciding on the
+// trace ID so that services sampling at the same ratio agree.
+func RatioSampler(ratio float64) Sampler {
+	switch {
+	case ratio >= 1:
+		return AlwaysSample()
+	case ratio <= 0:
+		return func(TraceID) bool { return false }
+	}
+	bound := uint64(ratio * (1 << 63))
+	return func(id TraceID) bool {
+		return binary.BigEndian.Uint64(id[8:])>>1 < bound
+	}
+}
@@ -0,0 +1,239 @@
+package tracing_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/logger"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/tracing"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	tests := []struct {
+		name        string
+		header      string
+		expected    string
+		sampled     bool
+		expectedErr error
+	}{
+		{
+			name:     "sampled",
+			header:   "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			expected: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			sampled:  true,
+		},
+		{
+			name:     "not sampled",
+			header:   "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00",
+			expected: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00",
+		},
+		{
+			name:     "future version",
+			header:   "cc-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-what-the-future-holds",
+			expected: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			sampled:  true,
+		},
+		{name: "empty", header: "", expectedErr: tracing.ErrInvalidTraceparent},
+		{name: "invalid version", header: "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", expectedErr: tracing.ErrInvalidTraceparent},
+		{name: "extra fields", header: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-00", expectedErr: tracing.ErrInvalidTraceparent},
+		{name: "zero trace ID", header: "00-00000000000000000000000000000000-00f067aa0ba902b7-01", expectedErr: tracing.ErrInvalidTraceparent},
+		{name: "zero span ID", header: "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", expectedErr: tracing.ErrInvalidTraceparent},
+		{name: "uppercase", header: "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", expectedErr: tracing.ErrInvalidTraceparent},
+		{name: "short span ID", header: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902-01", expectedErr: tracing.ErrInvalidTraceparent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc, err := tracing.ParseTraceparent(tt.header)
+			if tt.expectedErr != nil {
+				assert.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.sampled, sc.Sampled)
+			assert.Equal(t, tt.expected, sc.Traceparent())
+		})
+	}
+}
+
+func TestRatioSampler(t *testing.T) {
+	assert.True(t, tracing.RatioSampler(1)(tracing.TraceID{0xff}))
+	assert.False(t, tracing.RatioSampler(0)(tracing.TraceID{}))
+
+	low := tracing.TraceID{8: 0x10}
+	high := tracing.TraceID{8: 0xf0}
+	sampler := tracing.RatioSampler(0.5)
+	assert.True(t, sampler(low))
+	assert.False(t, sampler(high))
+}
+
+// exportedSpan holds the fields of an OTLP span checked by the tests.
+type exportedSpan struct {
+	TraceID      string `json:"traceId"`
+	SpanID       string `json:"spanId"`
+	ParentSpanID string `json:"parentSpanId"`
+	Name         string `json:"name"`
+	Kind         int    `json:"kind"`
+	Attributes   []struct {
+		Key   string                 `json:"key"`
+		Value map[string]interface{} `json:"value"`
+	} `json:"attributes"`
+	Status struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"status"`
+}
+
+// decodeSpans decodes the OTLP JSON lines of a WriterExporter.
+func decodeSpans(t *testing.T, data []byte) []exportedSpan {
+	t.Helper()
+	var spans []exportedSpan
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	for decoder.More() {
+		var request struct {
+			ResourceSpans []struct {
+				Resource struct {
+					Attributes []struct {
+						Key   string            `json:"key"`
+						Value map[string]string `json:"value"`
+					} `json:"attributes"`
+				} `json:"resource"`
+				ScopeSpans []struct {
+					Spans []exportedSpan `json:"spans"`
+				} `json:"scopeSpans"`
+			} `json:"resourceSpans"`
+		}
+		require.NoError(t, decoder.Decode(&request))
+		require.Len(t, request.ResourceSpans, 1)
+		assert.Equal(t, "simple-api", request.ResourceSpans[0].Resource.Attributes[0].Value["stringValue"])
+		for _, scope := range request.ResourceSpans[0].ScopeSpans {
+			spans = append(spans, scope.Spans...)
+		}
+	}
+	return spans
+}
+
+func TestTracer(t *testing.T) {
+	var buf bytes.Buffer
+	tracer := tracing.NewTracer(tracing.Config{ServiceName: "simple-api", Exporter: tracing.NewWriterExporter(&buf)})
+
+	remote, err := tracing.ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	require.NoError(t, err)
+	ctx := tracing.ContextWithRemoteParent(context.Background(), remote)
+
+	ctx, server := tracer.Start(ctx, "GET /users/:id", tracing.KindServer, tracing.String("http.route", "/users/:id"))
+	childCtx, end := tracer.StartOperation(ctx, "user", "get")
+	child := tracing.SpanFromContext(childCtx)
+	end(errors.New("can't connect to host=db user=app password=hunter2 dbname=users"))
+	server.SetAttributes(tracing.Int("http.response.status_code", 500))
+	server.End()
+	server.End()
+
+	fields := logger.Fields(childCtx)
+	assert.Equal(t, []interface{}{
+		"trace_id", "4bf92f3577b34da6a3ce929d0e0e4736",
+		"span_id", child.SpanContext().SpanID.String(),
+	}, fields, "children replace the span ID logged")
+
+	require.NoError(t, tracer.Shutdown(context.Background()))
+	assert.NotContains(t, buf.String(), "hunter2")
+
+	spans := decodeSpans(t, buf.Bytes())
+	require.Len(t, spans, 2, "spans are exported once")
+
+	assert.Equal(t, "user.get", spans[0].Name)
+	assert.Equal(t, 1, spans[0].Kind)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spans[0].TraceID)
+	assert.Equal(t, server.SpanContext().SpanID.String(), spans[0].ParentSpanID)
+	assert.Equal(t, 2, spans[0].Status.Code)
+	assert.Equal(t, "can't connect to host=db user=app password=[REDACTED] dbname=users", spans[0].Status.Message)
+
+	assert.Equal(t, "GET /users/:id", spans[1].Name)
+	assert.Equal(t, 2, spans[1].Kind)
+	assert.Equal(t, "00f067aa0ba902b7", spans[1].ParentSpanID)
+	assert.Equal(t, 0, spans[1].Status.Code)
+	require.Len(t, spans[1].Attributes, 2)
+	assert.Equal(t, "http.route", spans[1].Attributes[0].Key)
+	assert.Equal(t, "/users/:id", spans[1].Attributes[0].Value["stringValue"])
+	assert.Equal(t, "500", spans[1].Attributes[1].Value["intValue"])
+}
+
+func TestTracer_Sampling(t *testing.T) {
+	var buf bytes.Buffer
+	tracer := tracing.NewTracer(tracing.Config{
+		ServiceName: "simple-api",
+		Sampler:     tracing.RatioSampler(0),
+		Exporter:    tracing.NewWriterExporter(&buf),
+	})
+
+	ctx, root := tracer.Start(context.Background(), "root", tracing.KindServer)
+	_, child := tracer.Start(ctx, "child", tracing.KindInternal)
+	assert.False(t, child.SpanContext().Sampled, "children follow their parent")
+	assert.Equal(t, root.SpanContext().TraceID, child.SpanContext().TraceID)
+	child.End()
+	root.End()
+
+	sampled, err := tracing.ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	require.NoError(t, err)
+	_, remote := tracer.Start(tracing.ContextWithRemoteParent(context.Background(), sampled), "remote", tracing.KindServer)
+	assert.True(t, remote.SpanContext().Sampled, "sampled callers are followed")
+	remote.End()
+
+	require.NoError(t, tracer.Shutdown(context.Background()))
+	spans := decodeSpans(t, buf.Bytes())
+	require.Len(t, spans, 1)
+	assert.Equal(t, "remote", spans[0].Name)
+}
+
+func TestTracer_Nil(t *testing.T) {
+	var tracer *tracing.Tracer
+	ctx, span := tracer.Start(context.Background(), "noop", tracing.KindInternal)
+	assert.Nil(t, span)
+	assert.Nil(t, tracing.SpanFromContext(ctx))
+	span.SetAttributes(tracing.Bool("ok", true))
+	span.RecordError(errors.New("boom"))
+	span.End()
+	assert.NoError(t, tracer.Shutdown(context.Background()))
+}
+
+func TestOTLPExporter(t *testing.T) {
+	var (
+		path, contentType string
+		body              []byte
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, contentType = r.URL.Path, r.Header.Get("Content-Type")
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	tracer := tracing.NewTracer(tracing.Config{ServiceName: "simple-api", Exporter: tracing.NewOTLPExporter(server.URL+"/", nil)})
+	_, span := tracer.Start(context.Background(), "root", tracing.KindServer)
+	span.End()
+	require.NoError(t, tracer.Shutdown(context.Background()))
+
+	assert.Equal(t, "/v1/traces", path)
+	assert.Equal(t, "application/json", contentType)
+	spans := decodeSpans(t, body)
+	require.Len(t, spans, 1)
+	assert.Equal(t, span.SpanContext().SpanID.String(), spans[0].SpanID)
+}
+
+func TestOTLPExporter_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	err := tracing.NewOTLPExporter(server.URL, nil).Export(context.Background(), "simple-api", []tracing.SpanData{{Name: "root"}})
+	assert.EqualError(t, err, "OTLP endpoint responded with 503 Service Unavailable")
+}
@@ -0,0 +1,66 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+type setEmailRequest struct {
+	CurrentPassword string `json:"currentPassword,omitempty"`
+	Email           string `json:"email"`
+}
+
+type emailRequest struct {
+	Email string `json:"email"`
+}
+
+type accountTokenRequest struct {
+	Token string `json:"token"`
+}
+
+type resetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// SetEmail changes the email of a user, which stays unverified until
+// confirmed with VerifyEmail; an empty email removes it. current is
+// required when users change their own email and is ignored for admins. It
+// fails with ErrConflict if another user has the email.
+func (c *Client) SetEmail(ctx context.Context, id, current, email string) error {
+	path := usersPath + "/" + url.PathEscape(id) + "/email"
+	input := setEmailRequest{CurrentPassword: current, Email: email}
+	_, err := c.do(ctx, http.MethodPut, path, nil, input, &messageResponse{})
+	return err
+}
+
+// RequestEmailVerification sends a verification token to an email. It
+// succeeds whether or not a user has the email.
+func (c *Client) RequestEmailVerification(ctx context.Context, email string) error {
+	_, err := c.do(ctx, http.MethodPost, authPath+"/email-verification", nil, emailRequest{Email: email}, &messageResponse{})
+	return err
+}
+
+// VerifyEmail confirms an email with the token sent to it. It fails with
+// ErrBadRequest if the token is invalid, expired or used already.
+func (c *Client) VerifyEmail(ctx context.Context, token string) error {
+	_, err := c.do(ctx, http.MethodPost, authPath+"/email-verification/confirm", nil, accountTokenRequest{Token: token}, &messageResponse{})
+	return err
+}
+
+// RequestPasswordReset sends a password reset token to a verified email. It
+// succeeds whether or not a user has the email.
+func (c *Client) RequestPasswordReset(ctx context.Context, email string) error {
+	_, err := c.do(ctx, http.MethodPost, authPath+"/password-reset", nil, emailRequest{Email: email}, &messageResponse{})
+	return err
+}
+
+// ResetPassword sets a new password with the token sent by
+// RequestPasswordReset. It fails with ErrBadRequest if the token is
+// invalid, expired or used already, or if the password is too weak.
+func (c *Client) ResetPassword(ctx context.Context, token, password string) error {
+	input := resetPasswordRequest{Token: token, Password: password}
+	_, err := c.do(ctx, http.MethodPost, authPath+"/password-reset/confirm", nil, input, &messageResponse{})
+	return err
+}
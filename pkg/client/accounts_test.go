@@ -0,0 +1,67 @@
+package client_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	"github.com/Sergey-Polishchenko/simple-api/pkg/client"
+)
+
+func (m mailbox) receive(t *testing.T) app.Email {
+	t.Helper()
+
+	select {
+	case email := <-m:
+		return email
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "no email sent")
+		return app.Email{}
+	}
+}
+
+func TestClient_Accounts(t *testing.T) {
+	url, mail := newAccountServer(t)
+	ctx := context.Background()
+
+	anonymous, err := client.New(url)
+	require.NoError(t, err)
+	tokens, err := anonymous.Login(ctx, "1", "correct horse")
+	require.NoError(t, err)
+	user, err := client.New(url, client.WithAuth(client.BearerToken(tokens.AccessToken)))
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, user.SetEmail(ctx, "1", "battery staple", "john@example.com"), client.ErrForbidden)
+	assert.ErrorIs(t, user.SetEmail(ctx, "1", "correct horse", "john"), client.ErrBadRequest)
+	require.NoError(t, user.SetEmail(ctx, "1", "correct horse", "John@Example.com"))
+
+	me, err := user.GetUser(ctx, "1")
+	require.NoError(t, err)
+	assert.Equal(t, &client.User{ID: "1", Name: "John", Email: "john@example.com"}, me)
+
+	// Resets go to verified addresses only.
+	require.NoError(t, anonymous.RequestPasswordReset(ctx, "john@example.com"))
+	require.NoError(t, anonymous.RequestEmailVerification(ctx, "nobody@example.com"))
+
+	require.NoError(t, anonymous.RequestEmailVerification(ctx, "john@example.com"))
+	email := mail.receive(t)
+	assert.Equal(t, app.TemplateVerifyEmail, email.Template, "no other email was sent")
+	require.NoError(t, anonymous.VerifyEmail(ctx, email.Data["Token"]))
+	assert.ErrorIs(t, anonymous.VerifyEmail(ctx, email.Data["Token"]), client.ErrBadRequest)
+
+	me, err = user.GetUser(ctx, "1")
+	require.NoError(t, err)
+	assert.True(t, me.EmailVerified)
+
+	require.NoError(t, anonymous.RequestPasswordReset(ctx, "john@example.com"))
+	token := mail.receive(t).Data["Token"]
+	assert.ErrorIs(t, anonymous.ResetPassword(ctx, token, "short"), client.ErrBadRequest)
+	require.NoError(t, anonymous.ResetPassword(ctx, token, "battery staple"))
+
+	_, err = anonymous.Login(ctx, "1", "battery staple")
+	require.NoError(t, err)
+}
@@ -0,0 +1,63 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const apiKeysPath = "/api/v1/api-keys"
+
+// API key scopes.
+const (
+	ScopeUsersRead  = "users:read"
+	ScopeUsersWrite = "users:write"
+	ScopeUsersAdmin = "users:admin"
+)
+
+// APIKeyInfo describes an API key. Key is set only in the response to MintAPIKey.
+type APIKeyInfo struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	ExpiresAt  *time.Time `json:"expiresAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	RevokedAt  *time.Time `json:"revokedAt"`
+	Key        string     `json:"key,omitempty"`
+}
+
+// MintAPIKeyInput describes a new API key.
+type MintAPIKeyInput struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+	// ExpiresAt is optional; the key never expires without it.
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// MintAPIKey creates an API key. The returned Key is not shown again.
+func (c *Client) MintAPIKey(ctx context.Context, input MintAPIKeyInput) (*APIKeyInfo, error) {
+	key := &APIKeyInfo{}
+	if _, err := c.do(ctx, http.MethodPost, apiKeysPath, nil, input, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// ListAPIKeys fetches all API keys, revoked ones included.
+func (c *Client) ListAPIKeys(ctx context.Context) ([]*APIKeyInfo, error) {
+	var keys []*APIKeyInfo
+	if _, err := c.do(ctx, http.MethodGet, apiKeysPath, nil, nil, &keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey revokes an API key by ID.
+func (c *Client) RevokeAPIKey(ctx context.Context, id string) error {
+	path := apiKeysPath + "/" + url.PathEscape(id)
+	_, err := c.do(ctx, http.MethodDelete, path, nil, nil, &messageResponse{})
+	return err
+}
@@ -0,0 +1,95 @@
+package client_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	"github.com/Sergey-Polishchenko/simple-api/internal/infrastructure/memory"
+	httpapi "github.com/Sergey-Polishchenko/simple-api/internal/interfaces/http"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/logger"
+	"github.com/Sergey-Polishchenko/simple-api/pkg/client"
+)
+
+// newAPIKeyServer serves the API with API key authentication
+// and returns its URL along with an admin key.
+func newAPIKeyServer(t *testing.T) (string, string) {
+	gin.SetMode(gin.TestMode)
+
+	logger := logger.NewZapLogger()
+	service := app.NewUserApp(memory.NewUserRepo(), logger)
+	apiKeys := app.NewAPIKeyApp(memory.NewAPIKeyRepo(), logger, []byte("0123456789abcdef0123456789abcdef"))
+
+	admin := app.ContextWithClaims(context.Background(), &app.Claims{Roles: []string{app.RoleAdmin}})
+	_, adminKey, err := apiKeys.Mint(admin, app.MintAPIKeyParams{Name: "bootstrap", Scopes: []string{app.ScopeUsersAdmin}})
+	require.NoError(t, err)
+
+	router, err := httpapi.NewRouter(service, logger, httpapi.Config{EnforceContract: true, APIKeys: apiKeys})
+	require.NoError(t, err)
+
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return server.URL, adminKey
+}
+
+func TestClient_APIKeys(t *testing.T) {
+	url, adminKey := newAPIKeyServer(t)
+	ctx := context.Background()
+
+	admin, err := client.New(url, client.WithAuth(client.APIKey(adminKey)))
+	require.NoError(t, err)
+
+	expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	minted, err := admin.MintAPIKey(ctx, client.MintAPIKeyInput{
+		Name:      "reporting",
+		Scopes:    []string{client.ScopeUsersRead},
+		ExpiresAt: &expiresAt,
+	})
+	require.NoError(t, err)
+	assert.NotEmpty(t, minted.Key)
+	assert.Equal(t, []string{client.ScopeUsersRead}, minted.Scopes)
+	assert.True(t, expiresAt.Equal(*minted.ExpiresAt))
+
+	_, err = admin.MintAPIKey(ctx, client.MintAPIKeyInput{Name: "bad", Scopes: []string{"users:all"}})
+	assert.ErrorIs(t, err, client.ErrBadRequest)
+
+	reader, err := client.New(url, client.WithAuth(client.AuthenticatorFunc(func(req *http.Request) error {
+		req.Header.Set("X-API-Key", minted.Key)
+		return nil
+	})))
+	require.NoError(t, err)
+
+	_, err = reader.ListUsers(ctx, client.ListOptions{})
+	require.NoError(t, err)
+
+	_, err = reader.CreateUser(ctx, client.CreateUserInput{Name: "John"})
+	assert.ErrorIs(t, err, client.ErrForbidden)
+
+	_, err = reader.ListAPIKeys(ctx)
+	assert.ErrorIs(t, err, client.ErrForbidden)
+
+	keys, err := admin.ListAPIKeys(ctx)
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	for _, key := range keys {
+		assert.Empty(t, key.Key, "keys are never shown again")
+	}
+
+	require.NoError(t, admin.RevokeAPIKey(ctx, minted.ID))
+	assert.ErrorIs(t, admin.RevokeAPIKey(ctx, "missing"), client.ErrNotFound)
+
+	_, err = reader.ListUsers(ctx, client.ListOptions{})
+	assert.ErrorIs(t, err, client.ErrUnauthorized, "revoked keys are rejected")
+
+	anonymous, err := client.New(url)
+	require.NoError(t, err)
+	_, err = anonymous.ListUsers(ctx, client.ListOptions{})
+	assert.ErrorIs(t, err, client.ErrUnauthorized)
+}
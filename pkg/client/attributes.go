@@ -0,0 +1,62 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const attributesPath = "/api/v1/attributes"
+
+// Attribute types.
+const (
+	AttributeString  = "string"
+	AttributeNumber  = "number"
+	AttributeBoolean = "boolean"
+)
+
+// AttributeDefinition describes a custom attribute users can have.
+type AttributeDefinition struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Required bool   `json:"required"`
+	// Enum lists the values a string attribute is restricted to, if any.
+	Enum []string `json:"enum,omitempty"`
+	// Pattern is a regular expression string values must match, if any.
+	Pattern   string    `json:"pattern,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// AttributeDefinitionInput describes a custom attribute to define.
+type AttributeDefinitionInput struct {
+	Type     string   `json:"type"`
+	Required bool     `json:"required,omitempty"`
+	Enum     []string `json:"enum,omitempty"`
+	Pattern  string   `json:"pattern,omitempty"`
+}
+
+// ListAttributes fetches the definitions of the custom attributes of users.
+func (c *Client) ListAttributes(ctx context.Context) ([]*AttributeDefinition, error) {
+	var definitions []*AttributeDefinition
+	if _, err := c.do(ctx, http.MethodGet, attributesPath, nil, nil, &definitions); err != nil {
+		return nil, err
+	}
+	return definitions, nil
+}
+
+// DefineAttribute creates or replaces the definition of a custom attribute.
+func (c *Client) DefineAttribute(ctx context.Context, name string, input AttributeDefinitionInput) (*AttributeDefinition, error) {
+	definition := &AttributeDefinition{}
+	if _, err := c.do(ctx, http.MethodPut, attributesPath+"/"+url.PathEscape(name), nil, input, definition); err != nil {
+		return nil, err
+	}
+	return definition, nil
+}
+
+// RemoveAttribute removes the definition of a custom attribute. Users keep
+// their values, which the next write of their attributes must drop.
+func (c *Client) RemoveAttribute(ctx context.Context, name string) error {
+	_, err := c.do(ctx, http.MethodDelete, attributesPath+"/"+url.PathEscape(name), nil, nil, &messageResponse{})
+	return err
+}
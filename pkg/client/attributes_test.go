@@ -0,0 +1,70 @@
+package client_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Sergey-Polishchenko/simple-api/pkg/client"
+)
+
+func TestClient_Attributes(t *testing.T) {
+	c := newTestClient(t)
+	ctx := context.Background()
+
+	department, err := c.DefineAttribute(ctx, "department", client.AttributeDefinitionInput{
+		Type: client.AttributeString, Required: true, Enum: []string{"eng", "sales"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"eng", "sales"}, department.Enum)
+	_, err = c.DefineAttribute(ctx, "level", client.AttributeDefinitionInput{Type: client.AttributeNumber})
+	require.NoError(t, err)
+	_, err = c.DefineAttribute(ctx, "Bad Name", client.AttributeDefinitionInput{Type: client.AttributeString})
+	assert.ErrorIs(t, err, client.ErrBadRequest)
+
+	definitions, err := c.ListAttributes(ctx)
+	require.NoError(t, err)
+	require.Len(t, definitions, 2)
+	assert.Equal(t, "department", definitions[0].Name)
+
+	_, err = c.CreateUser(ctx, client.CreateUserInput{Name: "Nobody"})
+	assert.ErrorIs(t, err, client.ErrBadRequest, "department is required")
+
+	ann, err := c.CreateUser(ctx, client.CreateUserInput{Name: "Ann", Attributes: map[string]interface{}{"department": "eng", "level": 2}})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"department": "eng", "level": 2.0}, ann.Attributes)
+	_, err = c.CreateUser(ctx, client.CreateUserInput{Name: "Bob", Attributes: map[string]interface{}{"department": "eng", "level": 1}})
+	require.NoError(t, err)
+	_, err = c.CreateUser(ctx, client.CreateUserInput{Name: "Cid", Attributes: map[string]interface{}{"department": "sales", "level": 3}})
+	require.NoError(t, err)
+
+	page, err := c.ListUsers(ctx, client.ListOptions{Attributes: map[string]string{"department": "eng"}, Sort: "level"})
+	require.NoError(t, err)
+	require.Len(t, page.Users, 2)
+	assert.Equal(t, "Bob", page.Users[0].Name)
+	assert.Equal(t, "Ann", page.Users[1].Name)
+
+	var names []string
+	for user, err := range c.Users(ctx, client.ListOptions{Limit: 1, Sort: "-level"}) {
+		require.NoError(t, err)
+		names = append(names, user.Name)
+	}
+	assert.Equal(t, []string{"Cid", "Ann", "Bob"}, names)
+
+	_, err = c.ListUsers(ctx, client.ListOptions{Sort: "shoe_size"})
+	assert.ErrorIs(t, err, client.ErrBadRequest)
+
+	require.NoError(t, c.UpdateUser(ctx, ann.ID, client.UpdateUserInput{Name: "Anne"}))
+	fetched, err := c.GetUser(ctx, ann.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "eng", fetched.Attributes["department"], "attributes are kept")
+
+	require.NoError(t, c.RemoveAttribute(ctx, "department"))
+	assert.ErrorIs(t, c.RemoveAttribute(ctx, "department"), client.ErrNotFound)
+	require.NoError(t, c.UpdateUser(ctx, ann.ID, client.UpdateUserInput{Name: "Anne", Attributes: map[string]interface{}{}}))
+	fetched, err = c.GetUser(ctx, ann.ID)
+	require.NoError(t, err)
+	assert.Empty(t, fetched.Attributes)
+}
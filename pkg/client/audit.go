@@ -0,0 +1,163 @@
+package client
+
+import (
+	"context"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const auditPath = "/api/v1/audit"
+
+// AuditEntry records a change of a user: who made it, from which request,
+// and the fields it changed.
+type AuditEntry struct {
+	ID string `json:"id"`
+	// Sequence orders the entries of the log.
+	Sequence int64 `json:"sequence"`
+	// ActorType is "system", "user" or "service".
+	ActorType string `json:"actorType"`
+	ActorID   string `json:"actorId"`
+	// Action is one of "user.created", "user.updated", "user.removed",
+	// "user.password_changed", "user.email_changed", "user.email_verified",
+	// "user.password_reset", "user.reverted" and "user.erased".
+	Action   string        `json:"action"`
+	TargetID string        `json:"targetId"`
+	Changes  []AuditChange `json:"changes"`
+	// ChangesErased is set once the user the entry is about is erased.
+	ChangesErased bool   `json:"changesErased"`
+	RequestID     string `json:"requestId"`
+	ClientIP      string `json:"clientIp"`
+	// RequestErased is set once the user who made the change is erased.
+	RequestErased bool      `json:"requestErased"`
+	CreatedAt     time.Time `json:"createdAt"`
+	PrevHash      string    `json:"prevHash"`
+	Hash          string    `json:"hash"`
+}
+
+// AuditChange is the change of a field; Before is nil for fields the user
+// gains, After for the ones it loses.
+type AuditChange struct {
+	Field  string `json:"field"`
+	Before any    `json:"before"`
+	After  any    `json:"after"`
+}
+
+// AuditVerification is the outcome of the verification of the log.
+type AuditVerification struct {
+	Valid bool `json:"valid"`
+	// Entries is the number of entries verified.
+	Entries int `json:"entries"`
+	// BrokenAt is the ID of the first entry breaking the chain.
+	BrokenAt string `json:"brokenAt,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// AuditOptions selects a page of audit entries.
+type AuditOptions struct {
+	// Limit is the page size; DefaultPageSize if zero.
+	Limit int
+	// After is the cursor returned as NextCursor by the previous page.
+	After string
+	// ActorID, Action and TargetID keep the entries with the given values.
+	ActorID  string
+	Action   string
+	TargetID string
+	// Since and Until bound the time of the entries when set; Until is
+	// exclusive.
+	Since, Until time.Time
+}
+
+// AuditPage is one page of audit entries, newest first.
+type AuditPage struct {
+	Entries []*AuditEntry
+	// NextCursor is empty on the last page.
+	NextCursor string
+}
+
+// ListAuditEntries fetches a single page of the audit log.
+func (c *Client) ListAuditEntries(ctx context.Context, opts AuditOptions) (*AuditPage, error) {
+	query := pageQuery(opts.Limit, opts.After)
+	if opts.ActorID != "" {
+		query.Set("actor", opts.ActorID)
+	}
+	if opts.Action != "" {
+		query.Set("action", opts.Action)
+	}
+	if opts.TargetID != "" {
+		query.Set("target", opts.TargetID)
+	}
+	if !opts.Since.IsZero() {
+		query.Set("since", opts.Since.Format(time.RFC3339Nano))
+	}
+	if !opts.Until.IsZero() {
+		query.Set("until", opts.Until.Format(time.RFC3339Nano))
+	}
+	return c.auditPage(ctx, auditPath, query)
+}
+
+// AuditEntries iterates over the audit log, fetching pages on demand.
+// Iteration stops after the first error, which is yielded with a nil entry.
+func (c *Client) AuditEntries(ctx context.Context, opts AuditOptions) iter.Seq2[*AuditEntry, error] {
+	return func(yield func(*AuditEntry, error) bool) {
+		for {
+			page, err := c.ListAuditEntries(ctx, opts)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			for _, entry := range page.Entries {
+				if !yield(entry, nil) {
+					return
+				}
+			}
+
+			if page.NextCursor == "" {
+				return
+			}
+			opts.After = page.NextCursor
+		}
+	}
+}
+
+// UserHistory fetches a single page of the audit entries about a user,
+// which outlive the user. Only Limit and After of opts are used.
+func (c *Client) UserHistory(ctx context.Context, userID string, opts AuditOptions) (*AuditPage, error) {
+	return c.auditPage(ctx, userPath(userID)+"/history", pageQuery(opts.Limit, opts.After))
+}
+
+// VerifyAuditLog checks that no entry of the audit log was altered or
+// deleted.
+func (c *Client) VerifyAuditLog(ctx context.Context) (*AuditVerification, error) {
+	verification := &AuditVerification{}
+	if _, err := c.do(ctx, http.MethodGet, auditPath+"/verify", nil, nil, verification); err != nil {
+		return nil, err
+	}
+	return verification, nil
+}
+
+func (c *Client) auditPage(ctx context.Context, path string, query url.Values) (*AuditPage, error) {
+	var entries []*AuditEntry
+	header, err := c.do(ctx, http.MethodGet, path, query, nil, &entries)
+	if err != nil {
+		return nil, err
+	}
+	return &AuditPage{Entries: entries, NextCursor: header.Get(nextCursorHeader)}, nil
+}
+
+// pageQuery selects a page of a paginated list other than the users.
+func pageQuery(limit int, after string) url.Values {
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+
+	query := url.Values{}
+	query.Set("limit", strconv.Itoa(limit))
+	if after != "" {
+		query.Set("after", after)
+	}
+	return query
+}
@@ -0,0 +1,86 @@
+package client_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	"github.com/Sergey-Polishchenko/simple-api/internal/infrastructure/memory"
+	httpapi "github.com/Sergey-Polishchenko/simple-api/internal/interfaces/http"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/logger"
+	"github.com/Sergey-Polishchenko/simple-api/pkg/client"
+)
+
+func newAuditClient(t *testing.T) *client.Client {
+	gin.SetMode(gin.TestMode)
+
+	logger := logger.NewZapLogger()
+	audit := memory.NewAuditRepo()
+	service := app.NewUserApp(memory.NewUserRepo(), logger, app.WithAuditLog(audit))
+
+	router, err := httpapi.NewRouter(service, logger, httpapi.Config{
+		EnforceContract: true,
+		Audit:           app.NewAuditApp(audit, logger),
+	})
+	require.NoError(t, err)
+
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	c, err := client.New(server.URL)
+	require.NoError(t, err)
+	return c
+}
+
+func TestClient_Audit(t *testing.T) {
+	c := newAuditClient(t)
+	ctx := context.Background()
+
+	john, err := c.CreateUser(ctx, client.CreateUserInput{Name: "John"})
+	require.NoError(t, err)
+	jane, err := c.CreateUser(ctx, client.CreateUserInput{Name: "Jane"})
+	require.NoError(t, err)
+	require.NoError(t, c.UpdateUser(ctx, john.ID, client.UpdateUserInput{Name: "Johnny"}))
+	require.NoError(t, c.DeleteUser(ctx, john.ID))
+
+	history, err := c.UserHistory(ctx, john.ID, client.AuditOptions{Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, history.Entries, 2)
+	assert.NotEmpty(t, history.NextCursor)
+	removed, updated := history.Entries[0], history.Entries[1]
+	assert.Equal(t, "user.removed", removed.Action)
+	assert.Equal(t, "system", removed.ActorType)
+	assert.NotEmpty(t, removed.RequestID)
+	assert.Equal(t, updated.Hash, removed.PrevHash)
+	assert.Equal(t, []client.AuditChange{{Field: "name", Before: "John", After: "Johnny"}}, updated.Changes)
+
+	history, err = c.UserHistory(ctx, john.ID, client.AuditOptions{Limit: 2, After: history.NextCursor})
+	require.NoError(t, err)
+	require.Len(t, history.Entries, 1)
+	assert.Equal(t, "user.created", history.Entries[0].Action)
+	assert.Empty(t, history.NextCursor)
+
+	page, err := c.ListAuditEntries(ctx, client.AuditOptions{Action: "user.created"})
+	require.NoError(t, err)
+	require.Len(t, page.Entries, 2)
+	assert.Equal(t, []string{jane.ID, john.ID}, []string{page.Entries[0].TargetID, page.Entries[1].TargetID})
+
+	var actions []string
+	for entry, err := range c.AuditEntries(ctx, client.AuditOptions{Limit: 1}) {
+		require.NoError(t, err)
+		actions = append(actions, entry.Action)
+	}
+	assert.Equal(t, []string{"user.removed", "user.updated", "user.created", "user.created"}, actions)
+
+	_, err = c.ListAuditEntries(ctx, client.AuditOptions{After: "not-a-cursor"})
+	assert.ErrorIs(t, err, client.ErrBadRequest)
+
+	verification, err := c.VerifyAuditLog(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, &client.AuditVerification{Valid: true, Entries: 4}, verification)
+}
@@ -0,0 +1,227 @@
+// Package client is a typed Go client for the user API.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// IdempotencyKeyHeader carries a key that identifies a POST across its
+	// attempts. The server doesn't deduplicate by it, see RetryPolicy.
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// TenantHeader names the tenant requests are scoped to.
+	TenantHeader     = "X-Tenant-ID"
+	nextCursorHeader = "X-Next-Cursor"
+)
+
+// Client calls the user API. It is safe for concurrent use.
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	auth       Authenticator
+	retry      RetryPolicy
+	userAgent  string
+	tenant     string
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient replaces http.DefaultClient.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithAuth sets the authenticator applied to every request.
+func WithAuth(auth Authenticator) Option {
+	return func(c *Client) {
+		c.auth = auth
+	}
+}
+
+// WithRetryPolicy replaces DefaultRetryPolicy.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *Client) {
+		c.retry = policy
+	}
+}
+
+// WithUserAgent sets the User-Agent header.
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// WithTenant scopes every request to a tenant, sending TenantHeader. The
+// server must be configured with the same header name.
+func WithTenant(tenant string) Option {
+	return func(c *Client) {
+		c.tenant = tenant
+	}
+}
+
+// New creates a client for the API served at baseURL, e.g. "http://localhost:8080".
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(strings.TrimRight(baseURL, "/"))
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("client: base URL %q must be absolute", baseURL)
+	}
+
+	c := &Client{
+		baseURL:    u,
+		httpClient: http.DefaultClient,
+		retry:      DefaultRetryPolicy,
+		userAgent:  "simple-api-go-client",
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.retry.MaxAttempts < 1 {
+		c.retry.MaxAttempts = 1
+	}
+
+	return c, nil
+}
+
+type idempotencyKey struct{}
+
+// WithIdempotencyKey makes the POST issued with ctx use key instead of a
+// generated one, so that retries at a higher level carry the same key.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKey{}, key)
+}
+
+// do sends a request to the escaped path, retrying on 429, and on 503
+// unless it is a POST, and decodes a JSON response into out.
+func (c *Client) do(
+	ctx context.Context,
+	method, path string,
+	query url.Values,
+	in, out interface{},
+) (http.Header, error) {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return nil, err
+		}
+	}
+
+	// path is already escaped; resolve it against the base URL's own path.
+	u, err := c.baseURL.Parse(c.baseURL.EscapedPath() + path)
+	if err != nil {
+		return nil, err
+	}
+	u.RawQuery = query.Encode()
+
+	var key string
+	if method == http.MethodPost {
+		key, _ = ctx.Value(idempotencyKey{}).(string)
+		if key == "" {
+			key = uuid.NewString()
+		}
+	}
+
+	for attempt := 1; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("User-Agent", c.userAgent)
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+		if c.tenant != "" {
+			req.Header.Set(TenantHeader, c.tenant)
+		}
+		if c.auth != nil {
+			if err := c.auth.Authenticate(req); err != nil {
+				return nil, err
+			}
+		}
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		apiErr, err := decodeResponse(resp, out)
+		if apiErr == nil || err != nil {
+			return resp.Header, err
+		}
+
+		if !retryable(method, apiErr.StatusCode) || attempt >= c.retry.MaxAttempts {
+			return resp.Header, apiErr
+		}
+
+		delay := apiErr.RetryAfter
+		if delay == 0 {
+			delay = c.retry.backoff(attempt)
+		}
+		if err := sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// decodeResponse closes resp.Body, decoding it into out on success.
+func decodeResponse(resp *http.Response, out interface{}) (*APIError, error) {
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		apiErr := &APIError{
+			StatusCode: resp.StatusCode,
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		}
+		var body struct {
+			Error string `json:"error"`
+		}
+		if json.NewDecoder(io.LimitReader(resp.Body, 1<<16)).Decode(&body) == nil {
+			apiErr.Message = body.Error
+		}
+		return apiErr, nil
+	}
+
+	if out == nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil, nil
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("client: decoding response: %w", err)
+	}
+	return nil, nil
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
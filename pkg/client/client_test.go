@@ -0,0 +1,233 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	"github.com/Sergey-Polishchenko/simple-api/internal/infrastructure/memory"
+	httpapi "github.com/Sergey-Polishchenko/simple-api/internal/interfaces/http"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/logger"
+	"github.com/Sergey-Polishchenko/simple-api/pkg/client"
+)
+
+func newTestClient(t *testing.T) *client.Client {
+	gin.SetMode(gin.TestMode)
+
+	logger := logger.NewZapLogger()
+	users := memory.NewUserRepo()
+	attributes := memory.NewAttributeSchemaRepo()
+	service := app.NewUserApp(users, logger, app.WithAttributeSchema(attributes), app.WithUserHistory(users))
+
+	router, err := httpapi.NewRouter(service, logger, httpapi.Config{
+		EnforceContract: true,
+		Groups:          app.NewGroupApp(memory.NewGroupRepo(), users, logger),
+		Attributes:      app.NewAttributeSchemaApp(attributes, logger),
+	})
+	require.NoError(t, err)
+
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	c, err := client.New(server.URL)
+	require.NoError(t, err)
+	return c
+}
+
+func TestClient_UserLifecycle(t *testing.T) {
+	c := newTestClient(t)
+	ctx := context.Background()
+
+	created, err := c.CreateUser(ctx, client.CreateUserInput{Name: "John"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, created.ID)
+	assert.Equal(t, "John", created.Name)
+
+	require.NoError(t, c.UpdateUser(ctx, created.ID, client.UpdateUserInput{Name: "Jane"}))
+
+	fetched, err := c.GetUser(ctx, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, &client.User{ID: created.ID, Name: "Jane"}, fetched)
+
+	require.NoError(t, c.DeleteUser(ctx, created.ID))
+
+	_, err = c.GetUser(ctx, created.ID)
+	assert.ErrorIs(t, err, client.ErrNotFound)
+}
+
+func TestClient_Errors(t *testing.T) {
+	c := newTestClient(t)
+	ctx := context.Background()
+
+	_, err := c.CreateUser(ctx, client.CreateUserInput{})
+	assert.ErrorIs(t, err, client.ErrBadRequest)
+
+	var apiErr *client.APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+	assert.Equal(t, "name is required", apiErr.Message)
+
+	err = c.UpdateUser(ctx, "missing", client.UpdateUserInput{Name: "John"})
+	assert.ErrorIs(t, err, client.ErrNotFound)
+
+	err = c.DeleteUser(ctx, "missing")
+	assert.ErrorIs(t, err, client.ErrNotFound)
+}
+
+func TestClient_UsersIterator(t *testing.T) {
+	c := newTestClient(t)
+	ctx := context.Background()
+
+	want := map[string]bool{}
+	for i := range 7 {
+		user, err := c.CreateUser(ctx, client.CreateUserInput{Name: fmt.Sprintf("user %d", i)})
+		require.NoError(t, err)
+		want[user.ID] = true
+	}
+	_, err := c.CreateUser(ctx, client.CreateUserInput{Name: "someone else"})
+	require.NoError(t, err)
+
+	page, err := c.ListUsers(ctx, client.ListOptions{Limit: 3, Name: "USER"})
+	require.NoError(t, err)
+	assert.Len(t, page.Users, 3)
+	assert.NotEmpty(t, page.NextCursor)
+
+	got := map[string]bool{}
+	var previous string
+	for user, err := range c.Users(ctx, client.ListOptions{Limit: 3, Name: "user"}) {
+		require.NoError(t, err)
+		assert.Greater(t, user.ID, previous, "users are ordered by ID")
+		previous = user.ID
+		got[user.ID] = true
+	}
+	assert.Equal(t, want, got)
+}
+
+func TestClient_Retries(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		call     func(c *client.Client) error
+		calls    int
+		err      error
+	}{
+		{
+			name:     "GET on 503 and 429",
+			statuses: []int{http.StatusServiceUnavailable, http.StatusTooManyRequests},
+			call: func(c *client.Client) error {
+				_, err := c.GetUser(context.Background(), "1")
+				return err
+			},
+			calls: 3,
+		},
+		{
+			name:     "POST on 429",
+			statuses: []int{http.StatusTooManyRequests, http.StatusTooManyRequests},
+			call: func(c *client.Client) error {
+				_, err := c.CreateUser(context.Background(), client.CreateUserInput{Name: "John"})
+				return err
+			},
+			calls: 3,
+		},
+		{
+			name:     "no POST on 503",
+			statuses: []int{http.StatusServiceUnavailable},
+			call: func(c *client.Client) error {
+				_, err := c.CreateUser(context.Background(), client.CreateUserInput{Name: "John"})
+				return err
+			},
+			calls: 1,
+			err:   client.ErrUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				mu    sync.Mutex
+				calls int
+				keys  []string
+			)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+
+				calls++
+				keys = append(keys, r.Header.Get(client.IdempotencyKeyHeader))
+				if calls <= len(tt.statuses) {
+					w.Header().Set("Retry-After", "0")
+					w.WriteHeader(tt.statuses[calls-1])
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+				_, _ = w.Write([]byte(`{"id":"1","name":"John"}`))
+			}))
+			defer server.Close()
+
+			c, err := client.New(server.URL, client.WithRetryPolicy(client.RetryPolicy{
+				MaxAttempts: 3,
+				BaseDelay:   time.Millisecond,
+				MaxDelay:    10 * time.Millisecond,
+			}))
+			require.NoError(t, err)
+
+			err = tt.call(c)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.calls, calls)
+			for _, key := range keys {
+				assert.Equal(t, keys[0], key, "retries reuse the idempotency key")
+			}
+		})
+	}
+}
+
+func TestClient_RetriesExhausted(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	c, err := client.New(server.URL, client.WithRetryPolicy(client.RetryPolicy{MaxAttempts: 1}))
+	require.NoError(t, err)
+
+	_, err = c.GetUser(context.Background(), "1")
+	assert.ErrorIs(t, err, client.ErrRateLimited)
+	assert.Equal(t, 1, calls)
+
+	var apiErr *client.APIError
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, time.Second, apiErr.RetryAfter)
+}
+
+func TestClient_Auth(t *testing.T) {
+	var header string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Get("Authorization")
+		_, _ = w.Write([]byte(`{"id":"1","name":"John"}`))
+	}))
+	defer server.Close()
+
+	c, err := client.New(server.URL, client.WithAuth(client.BearerToken("secret")))
+	require.NoError(t, err)
+
+	_, err = c.GetUser(context.Background(), "1")
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer secret", header)
+}
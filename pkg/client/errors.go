@@ -0,0 +1,65 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Sentinel errors matching the status codes returned by the server.
+// Use errors.Is to test an *APIError against them.
+var (
+	ErrBadRequest   = errors.New("bad request")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	ErrNotFound     = errors.New("not found")
+	ErrConflict     = errors.New("conflict")
+	ErrRateLimited  = errors.New("rate limited")
+	ErrUnavailable  = errors.New("service unavailable")
+	ErrServer       = errors.New("server error")
+)
+
+// APIError is returned for every non-2xx response.
+type APIError struct {
+	StatusCode int
+	// Message is the "error" field of the response body, if any.
+	Message string
+	// RetryAfter is the delay requested by the server, if any.
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("simple-api: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("simple-api: %d %s", e.StatusCode, e.Message)
+}
+
+// Is maps the status code onto the sentinel errors.
+func (e *APIError) Is(target error) bool {
+	return kindOf(e.StatusCode) == target
+}
+
+func kindOf(status int) error {
+	switch {
+	case status == http.StatusBadRequest, status == http.StatusUnprocessableEntity:
+		return ErrBadRequest
+	case status == http.StatusUnauthorized:
+		return ErrUnauthorized
+	case status == http.StatusForbidden:
+		return ErrForbidden
+	case status == http.StatusNotFound:
+		return ErrNotFound
+	case status == http.StatusConflict:
+		return ErrConflict
+	case status == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case status == http.StatusServiceUnavailable:
+		return ErrUnavailable
+	case status >= http.StatusInternalServerError:
+		return ErrServer
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,123 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+const groupsPath = "/api/v1/groups"
+
+// Group is a named set of users and other groups.
+type Group struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// GroupInput holds the fields of a new or updated group.
+type GroupInput struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// GroupMembers are the direct members of a group.
+type GroupMembers struct {
+	Users  []*User  `json:"users"`
+	Groups []*Group `json:"groups"`
+}
+
+type groupMemberInput struct {
+	UserID  string `json:"userId,omitempty"`
+	GroupID string `json:"groupId,omitempty"`
+}
+
+// CreateGroup creates a group. It fails with ErrConflict if the name is taken.
+func (c *Client) CreateGroup(ctx context.Context, input GroupInput) (*Group, error) {
+	group := &Group{}
+	if _, err := c.do(ctx, http.MethodPost, groupsPath, nil, input, group); err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+// GetGroup fetches a group by ID.
+func (c *Client) GetGroup(ctx context.Context, id string) (*Group, error) {
+	group := &Group{}
+	if _, err := c.do(ctx, http.MethodGet, groupPath(id), nil, nil, group); err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+// ListGroups fetches all groups ordered by name.
+func (c *Client) ListGroups(ctx context.Context) ([]*Group, error) {
+	var groups []*Group
+	if _, err := c.do(ctx, http.MethodGet, groupsPath, nil, nil, &groups); err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+// UpdateGroup replaces the fields of an existing group.
+func (c *Client) UpdateGroup(ctx context.Context, id string, input GroupInput) error {
+	_, err := c.do(ctx, http.MethodPut, groupPath(id), nil, input, &messageResponse{})
+	return err
+}
+
+// DeleteGroup deletes a group by ID.
+func (c *Client) DeleteGroup(ctx context.Context, id string) error {
+	_, err := c.do(ctx, http.MethodDelete, groupPath(id), nil, nil, &messageResponse{})
+	return err
+}
+
+// ListGroupMembers fetches the direct members of a group.
+func (c *Client) ListGroupMembers(ctx context.Context, id string) (*GroupMembers, error) {
+	members := &GroupMembers{}
+	if _, err := c.do(ctx, http.MethodGet, groupPath(id)+"/members", nil, nil, members); err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+// AddUserToGroup adds a user to a group.
+func (c *Client) AddUserToGroup(ctx context.Context, groupID, userID string) error {
+	return c.addGroupMember(ctx, groupID, groupMemberInput{UserID: userID})
+}
+
+// AddGroupToGroup nests a group in another. It fails with ErrConflict if
+// the parent is the child or nested in it.
+func (c *Client) AddGroupToGroup(ctx context.Context, parentID, childID string) error {
+	return c.addGroupMember(ctx, parentID, groupMemberInput{GroupID: childID})
+}
+
+func (c *Client) addGroupMember(ctx context.Context, groupID string, input groupMemberInput) error {
+	_, err := c.do(ctx, http.MethodPost, groupPath(groupID)+"/members", nil, input, &messageResponse{})
+	return err
+}
+
+// RemoveGroupMember removes a direct member, user or group, from a group.
+func (c *Client) RemoveGroupMember(ctx context.Context, groupID, memberID string) error {
+	path := groupPath(groupID) + "/members/" + url.PathEscape(memberID)
+	_, err := c.do(ctx, http.MethodDelete, path, nil, nil, &messageResponse{})
+	return err
+}
+
+// UserGroups fetches the groups a user belongs to, directly or through
+// nested groups, or only directly if direct is set.
+func (c *Client) UserGroups(ctx context.Context, userID string, direct bool) ([]*Group, error) {
+	var query url.Values
+	if direct {
+		query = url.Values{"direct": {"true"}}
+	}
+
+	var groups []*Group
+	if _, err := c.do(ctx, http.MethodGet, userPath(userID)+"/groups", query, nil, &groups); err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+func groupPath(id string) string {
+	return groupsPath + "/" + url.PathEscape(id)
+}
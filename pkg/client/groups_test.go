@@ -0,0 +1,63 @@
+package client_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Sergey-Polishchenko/simple-api/pkg/client"
+)
+
+func TestClient_Groups(t *testing.T) {
+	c := newTestClient(t)
+	ctx := context.Background()
+
+	user, err := c.CreateUser(ctx, client.CreateUserInput{Name: "John"})
+	require.NoError(t, err)
+
+	company, err := c.CreateGroup(ctx, client.GroupInput{Name: "Company"})
+	require.NoError(t, err)
+	team, err := c.CreateGroup(ctx, client.GroupInput{Name: "Team", Description: "Does things"})
+	require.NoError(t, err)
+	assert.Equal(t, "Does things", team.Description)
+
+	_, err = c.CreateGroup(ctx, client.GroupInput{Name: "Team"})
+	assert.ErrorIs(t, err, client.ErrConflict)
+
+	require.NoError(t, c.UpdateGroup(ctx, team.ID, client.GroupInput{Name: "Backend"}))
+	fetched, err := c.GetGroup(ctx, team.ID)
+	require.NoError(t, err)
+	assert.Equal(t, &client.Group{ID: team.ID, Name: "Backend"}, fetched)
+
+	require.NoError(t, c.AddGroupToGroup(ctx, company.ID, team.ID))
+	require.NoError(t, c.AddUserToGroup(ctx, team.ID, user.ID))
+	assert.ErrorIs(t, c.AddGroupToGroup(ctx, team.ID, company.ID), client.ErrConflict)
+	assert.ErrorIs(t, c.AddUserToGroup(ctx, team.ID, "missing"), client.ErrNotFound)
+
+	members, err := c.ListGroupMembers(ctx, company.ID)
+	require.NoError(t, err)
+	assert.Empty(t, members.Users)
+	require.Len(t, members.Groups, 1)
+	assert.Equal(t, team.ID, members.Groups[0].ID)
+
+	groups, err := c.UserGroups(ctx, user.ID, false)
+	require.NoError(t, err)
+	require.Len(t, groups, 2)
+	assert.Equal(t, []string{"Backend", "Company"}, []string{groups[0].Name, groups[1].Name})
+
+	groups, err = c.UserGroups(ctx, user.ID, true)
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+	assert.Equal(t, team.ID, groups[0].ID)
+
+	require.NoError(t, c.RemoveGroupMember(ctx, company.ID, team.ID))
+	assert.ErrorIs(t, c.RemoveGroupMember(ctx, company.ID, team.ID), client.ErrNotFound)
+
+	require.NoError(t, c.DeleteGroup(ctx, team.ID))
+	all, err := c.ListGroups(ctx)
+	require.NoError(t, err)
+	require.Len(t, all, 1)
+	assert.Equal(t, company.ID, all[0].ID)
+}
@@ -0,0 +1,68 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// MFAStatus describes the second factor of a user.
+type MFAStatus struct {
+	Enabled           bool `json:"enabled"`
+	RecoveryCodesLeft int  `json:"recoveryCodesLeft"`
+}
+
+// MFASetup is the TOTP secret of a pending enrollment. OTPAuthURI is
+// usually shown as a QR code for authenticator apps.
+type MFASetup struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauthUri"`
+}
+
+type mfaCodeRequest struct {
+	Code string `json:"code"`
+}
+
+type recoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+func mfaPath(userID string) string {
+	return usersPath + "/" + url.PathEscape(userID) + "/mfa"
+}
+
+// MFAStatus fetches the MFA status of a user.
+func (c *Client) MFAStatus(ctx context.Context, userID string) (*MFAStatus, error) {
+	status := &MFAStatus{}
+	if _, err := c.do(ctx, http.MethodGet, mfaPath(userID), nil, nil, status); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// EnrollMFA starts the TOTP enrollment of the signed-in user. It fails with
+// ErrConflict if MFA is enabled already.
+func (c *Client) EnrollMFA(ctx context.Context, userID string) (*MFASetup, error) {
+	setup := &MFASetup{}
+	if _, err := c.do(ctx, http.MethodPost, mfaPath(userID), nil, nil, setup); err != nil {
+		return nil, err
+	}
+	return setup, nil
+}
+
+// ConfirmMFA enables MFA with a code of the enrolled secret and returns the
+// recovery codes, which are not shown again.
+func (c *Client) ConfirmMFA(ctx context.Context, userID, code string) ([]string, error) {
+	output := &recoveryCodesResponse{}
+	input := mfaCodeRequest{Code: code}
+	if _, err := c.do(ctx, http.MethodPost, mfaPath(userID)+"/confirm", nil, input, output); err != nil {
+		return nil, err
+	}
+	return output.RecoveryCodes, nil
+}
+
+// ResetMFA disables MFA of a user; it requires admin rights.
+func (c *Client) ResetMFA(ctx context.Context, userID string) error {
+	_, err := c.do(ctx, http.MethodDelete, mfaPath(userID), nil, nil, &messageResponse{})
+	return err
+}
@@ -0,0 +1,55 @@
+package client_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/totp"
+	"github.com/Sergey-Polishchenko/simple-api/pkg/client"
+)
+
+func TestClient_MFA(t *testing.T) {
+	url := newSessionServer(t)
+	ctx := context.Background()
+
+	anonymous, err := client.New(url)
+	require.NoError(t, err)
+	tokens, err := anonymous.Login(ctx, "1", "correct horse")
+	require.NoError(t, err)
+	user, err := client.New(url, client.WithAuth(client.BearerToken(tokens.AccessToken)))
+	require.NoError(t, err)
+
+	setup, err := user.EnrollMFA(ctx, "1")
+	require.NoError(t, err)
+	assert.Contains(t, setup.OTPAuthURI, "otpauth://totp/")
+
+	_, err = user.ConfirmMFA(ctx, "1", "12345")
+	assert.ErrorIs(t, err, client.ErrBadRequest)
+
+	code, err := totp.Code(setup.Secret, totp.Step(time.Now()))
+	require.NoError(t, err)
+	recoveryCodes, err := user.ConfirmMFA(ctx, "1", code)
+	require.NoError(t, err)
+	require.Len(t, recoveryCodes, 10)
+
+	_, err = user.EnrollMFA(ctx, "1")
+	assert.ErrorIs(t, err, client.ErrConflict)
+
+	_, err = anonymous.Login(ctx, "1", "correct horse")
+	assert.ErrorIs(t, err, client.ErrForbidden, "a code is required")
+	_, err = anonymous.LoginMFA(ctx, "1", "correct horse", code)
+	assert.ErrorIs(t, err, client.ErrUnauthorized, "the code was used")
+
+	_, err = anonymous.LoginMFA(ctx, "1", "correct horse", recoveryCodes[0])
+	require.NoError(t, err)
+
+	status, err := user.MFAStatus(ctx, "1")
+	require.NoError(t, err)
+	assert.Equal(t, &client.MFAStatus{Enabled: true, RecoveryCodesLeft: 9}, status)
+
+	assert.ErrorIs(t, user.ResetMFA(ctx, "1"), client.ErrForbidden, "only admins reset MFA")
+}
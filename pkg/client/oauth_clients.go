@@ -0,0 +1,56 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const oauthClientsPath = "/api/v1/oauth-clients"
+
+// OAuthClient describes a client of the OpenID Connect provider. Secret is
+// set only in the response to RegisterOAuthClient of a confidential client.
+type OAuthClient struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirectUris"`
+	Scopes       []string  `json:"scopes"`
+	Confidential bool      `json:"confidential"`
+	CreatedAt    time.Time `json:"createdAt"`
+	Secret       string    `json:"secret,omitempty"`
+}
+
+// RegisterOAuthClientInput describes a new OAuth client.
+type RegisterOAuthClientInput struct {
+	Name         string   `json:"name"`
+	RedirectURIs []string `json:"redirectUris,omitempty"`
+	// Scopes can be requested with the client credentials grant by confidential clients.
+	Scopes       []string `json:"scopes,omitempty"`
+	Confidential bool     `json:"confidential"`
+}
+
+// RegisterOAuthClient registers an OAuth client. The returned Secret is not shown again.
+func (c *Client) RegisterOAuthClient(ctx context.Context, input RegisterOAuthClientInput) (*OAuthClient, error) {
+	client := &OAuthClient{}
+	if _, err := c.do(ctx, http.MethodPost, oauthClientsPath, nil, input, client); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// ListOAuthClients fetches all OAuth clients.
+func (c *Client) ListOAuthClients(ctx context.Context) ([]*OAuthClient, error) {
+	var clients []*OAuthClient
+	if _, err := c.do(ctx, http.MethodGet, oauthClientsPath, nil, nil, &clients); err != nil {
+		return nil, err
+	}
+	return clients, nil
+}
+
+// RemoveOAuthClient removes an OAuth client by ID.
+func (c *Client) RemoveOAuthClient(ctx context.Context, id string) error {
+	path := oauthClientsPath + "/" + url.PathEscape(id)
+	_, err := c.do(ctx, http.MethodDelete, path, nil, nil, &messageResponse{})
+	return err
+}
@@ -0,0 +1,313 @@
+package client_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	"github.com/Sergey-Polishchenko/simple-api/internal/domain"
+	"github.com/Sergey-Polishchenko/simple-api/internal/infrastructure/memory"
+	httpapi "github.com/Sergey-Polishchenko/simple-api/internal/interfaces/http"
+	"github.com/Sergey-Polishchenko/simple-api/internal/interfaces/http/middleware"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/jwks"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/logger"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/password"
+	"github.com/Sergey-Polishchenko/simple-api/pkg/client"
+)
+
+const oidcIssuer = "https://id.example"
+
+// newOIDCServer serves the API with the OpenID Connect provider, for user
+// "1" whose password is "correct horse", and returns its URL along with an
+// admin API key.
+func newOIDCServer(t *testing.T) (string, string) {
+	gin.SetMode(gin.TestMode)
+
+	logger := logger.NewZapLogger()
+	hasher := password.NewHasher(password.Params{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 16})
+	users := memory.NewUserRepo()
+
+	hash, err := hasher.Hash("correct horse")
+	require.NoError(t, err)
+	require.NoError(t, users.Create(context.Background(), domain.NewUser("1", "John").WithPasswordHash(hash)))
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	signer, err := jwks.NewSigner(key, "oidc-1")
+	require.NoError(t, err)
+	keys, err := jwks.NewSet([]jwks.Key{signer.VerificationKey()}, "", 0)
+	require.NoError(t, err)
+
+	auth, err := app.NewAuthApp(users, memory.NewRefreshTokenRepo(), hasher, signer, logger, app.AuthConfig{
+		Issuer:          oidcIssuer,
+		Audience:        "simple-api",
+		AccessTokenTTL:  time.Minute,
+		RefreshTokenTTL: time.Hour,
+	})
+	require.NoError(t, err)
+
+	service := app.NewUserApp(users, logger, app.WithPasswordHasher(hasher))
+	clientRepo := memory.NewOAuthClientRepo()
+	oidc := app.NewOIDCApp(
+		clientRepo,
+		memory.NewAuthorizationCodeRepo(),
+		memory.NewRevokedTokenRepo(),
+		service,
+		signer,
+		logger,
+		app.OIDCConfig{Issuer: oidcIssuer, Audience: "simple-api", AccessTokenTTL: time.Minute, IDTokenTTL: time.Hour},
+	)
+
+	apiKeys := app.NewAPIKeyApp(memory.NewAPIKeyRepo(), logger, []byte("0123456789abcdef0123456789abcdef"))
+	admin := app.ContextWithClaims(context.Background(), &app.Claims{Roles: []string{app.RoleAdmin}})
+	_, adminKey, err := apiKeys.Mint(admin, app.MintAPIKeyParams{Name: "bootstrap", Scopes: []string{app.ScopeUsersAdmin}})
+	require.NoError(t, err)
+
+	router, err := httpapi.NewRouter(service, logger, httpapi.Config{
+		EnforceContract: true,
+		JWT:             &middleware.JWTConfig{Keys: keys, Issuer: oidcIssuer, Audience: "simple-api", Revoked: oidc},
+		APIKeys:         apiKeys,
+		Auth:            auth,
+		OIDC: &httpapi.OIDCConfig{
+			Service: oidc,
+			Clients: app.NewOAuthClientApp(clientRepo, logger),
+			Issuer:  oidcIssuer,
+			Key:     signer.VerificationKey(),
+		},
+	})
+	require.NoError(t, err)
+
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return server.URL, adminKey
+}
+
+// postForm posts an OAuth request and decodes its JSON response.
+func postForm(t *testing.T, endpoint string, form url.Values, basic [2]string) (int, map[string]interface{}) {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if basic[0] != "" {
+		req.SetBasicAuth(url.QueryEscape(basic[0]), url.QueryEscape(basic[1]))
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body := map[string]interface{}{}
+	if data, _ := io.ReadAll(resp.Body); len(data) > 0 {
+		require.NoError(t, json.Unmarshal(data, &body))
+	}
+	return resp.StatusCode, body
+}
+
+func TestClient_OIDCDiscovery(t *testing.T) {
+	server, _ := newOIDCServer(t)
+
+	resp, err := http.Get(server + "/.well-known/openid-configuration")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var metadata map[string]interface{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&metadata))
+	assert.Equal(t, oidcIssuer, metadata["issuer"])
+	assert.Equal(t, oidcIssuer+"/oauth2/token", metadata["token_endpoint"])
+	assert.Equal(t, []interface{}{"ES256"}, metadata["id_token_signing_alg_values_supported"])
+	assert.Equal(t, []interface{}{"S256"}, metadata["code_challenge_methods_supported"])
+
+	resp, err = http.Get(server + "/oauth2/jwks")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	keys, err := jwks.Parse(data)
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.Equal(t, "oidc-1", keys[0].ID)
+	assert.True(t, keys[0].Supports(jwks.ES256))
+}
+
+func TestClient_OIDCAuthorizationCode(t *testing.T) {
+	server, adminKey := newOIDCServer(t)
+	ctx := context.Background()
+
+	admin, err := client.New(server, client.WithAuth(client.APIKey(adminKey)))
+	require.NoError(t, err)
+	spa, err := admin.RegisterOAuthClient(ctx, client.RegisterOAuthClientInput{
+		Name:         "SPA",
+		RedirectURIs: []string{"https://app.example/callback"},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, spa.Secret)
+
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	sum := sha256.Sum256([]byte(verifier))
+	authorization := url.Values{
+		"client_id":             {spa.ID},
+		"redirect_uri":          {"https://app.example/callback"},
+		"response_type":         {"code"},
+		"scope":                 {"openid profile"},
+		"state":                 {"af0ifjsldkj"},
+		"nonce":                 {"n-0S6_WzA2Mj"},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(sum[:])},
+		"code_challenge_method": {"S256"},
+	}
+
+	jar, err := cookiejar.New(nil)
+	require.NoError(t, err)
+	browser := &http.Client{Jar: jar, CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+
+	resp, err := browser.Get(server + "/oauth2/authorize?" + authorization.Encode())
+	require.NoError(t, err)
+	page, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, string(page), `name="password"`)
+	assert.Equal(t, "DENY", resp.Header.Get("X-Frame-Options"))
+	match := regexp.MustCompile(`name="csrf_token" value="([^"]+)"`).FindStringSubmatch(string(page))
+	require.Len(t, match, 2)
+
+	post := func(client *http.Client, pw, csrfToken string) *http.Response {
+		form := url.Values{"user_id": {"1"}, "password": {pw}, "csrf_token": {csrfToken}}
+		for name, values := range authorization {
+			form[name] = values
+		}
+		resp, err := client.PostForm(server+"/oauth2/authorize", form)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+	signIn := func(pw string) *http.Response {
+		return post(browser, pw, match[1])
+	}
+
+	// A form posted by another site has neither the cookie nor the token.
+	assert.Equal(t, http.StatusForbidden, post(http.DefaultClient, "correct horse", match[1]).StatusCode)
+	assert.Equal(t, http.StatusForbidden, post(browser, "correct horse", "forged").StatusCode)
+	assert.Equal(t, http.StatusUnauthorized, signIn("battery staple").StatusCode)
+
+	resp = signIn("correct horse")
+	require.Equal(t, http.StatusSeeOther, resp.StatusCode)
+	location, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, "app.example", location.Host)
+	assert.Equal(t, "af0ifjsldkj", location.Query().Get("state"))
+	assert.Equal(t, oidcIssuer, location.Query().Get("iss"))
+	code := location.Query().Get("code")
+	require.NotEmpty(t, code)
+
+	status, tokens := postForm(t, server+"/oauth2/token", url.Values{
+		"grant_type":    {"authorization_code"},
+		"client_id":     {spa.ID},
+		"code":          {code},
+		"redirect_uri":  {"https://app.example/callback"},
+		"code_verifier": {verifier},
+	}, [2]string{})
+	require.Equal(t, http.StatusOK, status, tokens)
+	assert.Equal(t, "Bearer", tokens["token_type"])
+	assert.NotEmpty(t, tokens["id_token"])
+
+	user, err := client.New(server, client.WithAuth(client.BearerToken(tokens["access_token"].(string))))
+	require.NoError(t, err)
+	me, err := user.GetUser(ctx, "1")
+	require.NoError(t, err)
+	assert.Equal(t, "John", me.Name)
+
+	// ID tokens are for the client, not the API.
+	idToken, err := client.New(server, client.WithAuth(client.BearerToken(tokens["id_token"].(string))))
+	require.NoError(t, err)
+	_, err = idToken.GetUser(ctx, "1")
+	assert.ErrorIs(t, err, client.ErrUnauthorized)
+
+	status, body := postForm(t, server+"/oauth2/token", url.Values{
+		"grant_type":    {"authorization_code"},
+		"client_id":     {spa.ID},
+		"code":          {code},
+		"redirect_uri":  {"https://app.example/callback"},
+		"code_verifier": {verifier},
+	}, [2]string{})
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, "invalid_grant", body["error"])
+}
+
+func TestClient_OIDCClientCredentials(t *testing.T) {
+	server, adminKey := newOIDCServer(t)
+	ctx := context.Background()
+
+	admin, err := client.New(server, client.WithAuth(client.APIKey(adminKey)))
+	require.NoError(t, err)
+	billing, err := admin.RegisterOAuthClient(ctx, client.RegisterOAuthClientInput{
+		Name:         "Billing",
+		Scopes:       []string{client.ScopeUsersRead},
+		Confidential: true,
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, billing.Secret)
+
+	_, err = admin.RegisterOAuthClient(ctx, client.RegisterOAuthClientInput{Name: "Nothing"})
+	assert.ErrorIs(t, err, client.ErrBadRequest)
+
+	status, body := postForm(t, server+"/oauth2/token", url.Values{"grant_type": {"client_credentials"}}, [2]string{billing.ID, "scs_wrong"})
+	assert.Equal(t, http.StatusUnauthorized, status)
+	assert.Equal(t, "invalid_client", body["error"])
+
+	credentials := [2]string{billing.ID, billing.Secret}
+	status, tokens := postForm(t, server+"/oauth2/token", url.Values{"grant_type": {"client_credentials"}}, credentials)
+	require.Equal(t, http.StatusOK, status, tokens)
+	assert.Equal(t, client.ScopeUsersRead, tokens["scope"])
+	accessToken := tokens["access_token"].(string)
+
+	service, err := client.New(server, client.WithAuth(client.BearerToken(accessToken)))
+	require.NoError(t, err)
+	_, err = service.ListUsers(ctx, client.ListOptions{})
+	require.NoError(t, err)
+	_, err = service.CreateUser(ctx, client.CreateUserInput{Name: "Jane"})
+	assert.ErrorIs(t, err, client.ErrForbidden)
+
+	status, introspection := postForm(t, server+"/oauth2/introspect", url.Values{"token": {accessToken}}, credentials)
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, true, introspection["active"])
+	assert.Equal(t, billing.ID, introspection["client_id"])
+
+	status, _ = postForm(t, server+"/oauth2/revoke", url.Values{"token": {accessToken}}, credentials)
+	require.Equal(t, http.StatusOK, status)
+
+	_, err = service.ListUsers(ctx, client.ListOptions{})
+	assert.ErrorIs(t, err, client.ErrUnauthorized)
+
+	status, introspection = postForm(t, server+"/oauth2/introspect", url.Values{"token": {accessToken}}, credentials)
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, map[string]interface{}{"active": false}, introspection)
+
+	clients, err := admin.ListOAuthClients(ctx)
+	require.NoError(t, err)
+	require.Len(t, clients, 1)
+	assert.Empty(t, clients[0].Secret)
+	require.NoError(t, admin.RemoveOAuthClient(ctx, billing.ID))
+	assert.ErrorIs(t, admin.RemoveOAuthClient(ctx, billing.ID), client.ErrNotFound)
+}
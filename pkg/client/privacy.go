@@ -0,0 +1,85 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// UserExport is everything the server stores about a user.
+type UserExport struct {
+	ExportedAt time.Time `json:"exportedAt"`
+	User       User      `json:"user"`
+	// Versions are newest first.
+	Versions []*UserVersion    `json:"versions"`
+	Groups   []GroupMembership `json:"groups"`
+	Sessions []*Session        `json:"sessions"`
+	MFA      MFAStatus         `json:"mfa"`
+	// AuditEntries are the entries about the user or made by them, newest
+	// first.
+	AuditEntries []*AuditEntry `json:"auditEntries"`
+}
+
+// GroupMembership is a group a user belongs to; Direct is false for
+// groups the user belongs to through nested groups.
+type GroupMembership struct {
+	Group  Group `json:"group"`
+	Direct bool  `json:"direct"`
+}
+
+// Session is a sign-in of a user and the refresh tokens descending from it.
+type Session struct {
+	ID        string    `json:"id"`
+	StartedAt time.Time `json:"startedAt"`
+	// RefreshedAt is when the latest refresh token was issued.
+	RefreshedAt time.Time  `json:"refreshedAt"`
+	ExpiresAt   time.Time  `json:"expiresAt"`
+	RevokedAt   *time.Time `json:"revokedAt"`
+}
+
+// ErasureReceipt records the erasure of a user and how much of it was
+// deleted. Audit entries are retained, as the audit log is append-only.
+type ErasureReceipt struct {
+	ID     string `json:"id"`
+	UserID string `json:"userId"`
+	// ActorType is "system", "user" or "service".
+	ActorType            string    `json:"actorType"`
+	ActorID              string    `json:"actorId"`
+	ErasedAt             time.Time `json:"erasedAt"`
+	Versions             int       `json:"versions"`
+	Sessions             int       `json:"sessions"`
+	GroupMemberships     int       `json:"groupMemberships"`
+	MFA                  bool      `json:"mfa"`
+	AuditEntriesRetained int       `json:"auditEntriesRetained"`
+}
+
+// ExportUser fetches everything stored about a user. Users can export
+// themselves.
+func (c *Client) ExportUser(ctx context.Context, id string) (*UserExport, error) {
+	export := &UserExport{}
+	if _, err := c.do(ctx, http.MethodGet, userPath(id)+"/export", nil, nil, export); err != nil {
+		return nil, err
+	}
+	return export, nil
+}
+
+// EraseUser deletes a user along with its versions, sessions, MFA
+// enrollment and group memberships. Users removed with DeleteUser are
+// erased if they left versions.
+func (c *Client) EraseUser(ctx context.Context, id string) (*ErasureReceipt, error) {
+	receipt := &ErasureReceipt{}
+	if _, err := c.do(ctx, http.MethodPost, userPath(id)+"/erasure", nil, nil, receipt); err != nil {
+		return nil, err
+	}
+	return receipt, nil
+}
+
+// GetErasureReceipt fetches the receipt EraseUser returned.
+func (c *Client) GetErasureReceipt(ctx context.Context, id string) (*ErasureReceipt, error) {
+	receipt := &ErasureReceipt{}
+	if _, err := c.do(ctx, http.MethodGet, "/api/v1/erasures/"+url.PathEscape(id), nil, nil, receipt); err != nil {
+		return nil, err
+	}
+	return receipt, nil
+}
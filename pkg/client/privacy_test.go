@@ -0,0 +1,95 @@
+package client_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	"github.com/Sergey-Polishchenko/simple-api/internal/infrastructure/memory"
+	httpapi "github.com/Sergey-Polishchenko/simple-api/internal/interfaces/http"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/logger"
+	"github.com/Sergey-Polishchenko/simple-api/pkg/client"
+)
+
+func newPrivacyClient(t *testing.T) *client.Client {
+	gin.SetMode(gin.TestMode)
+
+	logger := logger.NewZapLogger()
+	users := memory.NewUserRepo()
+	groups := memory.NewGroupRepo()
+	audit := memory.NewAuditRepo()
+	service := app.NewUserApp(users, logger, app.WithAuditLog(audit), app.WithUserHistory(users))
+
+	router, err := httpapi.NewRouter(service, logger, httpapi.Config{
+		EnforceContract: true,
+		Groups:          app.NewGroupApp(groups, users, logger),
+		Audit:           app.NewAuditApp(audit, logger),
+		Privacy: app.NewPrivacyApp(service, memory.NewErasureReceiptRepo(), app.PrivacySources{
+			Groups: groups,
+			Audit:  audit,
+		}, logger),
+	})
+	require.NoError(t, err)
+
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	c, err := client.New(server.URL)
+	require.NoError(t, err)
+	return c
+}
+
+func TestClient_Privacy(t *testing.T) {
+	c := newPrivacyClient(t)
+	ctx := context.Background()
+
+	john, err := c.CreateUser(ctx, client.CreateUserInput{Name: "John"})
+	require.NoError(t, err)
+	require.NoError(t, c.UpdateUser(ctx, john.ID, client.UpdateUserInput{Name: "Johnny"}))
+	group, err := c.CreateGroup(ctx, client.GroupInput{Name: "eng"})
+	require.NoError(t, err)
+	require.NoError(t, c.AddUserToGroup(ctx, group.ID, john.ID))
+
+	export, err := c.ExportUser(ctx, john.ID)
+	require.NoError(t, err)
+	assert.Equal(t, client.User{ID: john.ID, Name: "Johnny"}, export.User)
+	require.Len(t, export.Versions, 2)
+	assert.Equal(t, "John", export.Versions[1].User.Name)
+	assert.Equal(t, []client.GroupMembership{{Group: *group, Direct: true}}, export.Groups)
+	assert.Empty(t, export.Sessions)
+	assert.Equal(t, client.MFAStatus{}, export.MFA)
+	require.Len(t, export.AuditEntries, 2)
+	assert.Equal(t, "user.updated", export.AuditEntries[0].Action)
+
+	receipt, err := c.EraseUser(ctx, john.ID)
+	require.NoError(t, err)
+	assert.Equal(t, john.ID, receipt.UserID)
+	assert.Equal(t, "system", receipt.ActorType)
+	assert.Equal(t, 2, receipt.Versions)
+	assert.Equal(t, 1, receipt.GroupMemberships)
+	assert.Equal(t, 2, receipt.AuditEntriesRetained)
+
+	fetched, err := c.GetErasureReceipt(ctx, receipt.ID)
+	require.NoError(t, err)
+	assert.Equal(t, receipt, fetched)
+
+	_, err = c.GetUser(ctx, john.ID)
+	assert.ErrorIs(t, err, client.ErrNotFound)
+	_, err = c.ExportUser(ctx, john.ID)
+	assert.ErrorIs(t, err, client.ErrNotFound)
+	_, err = c.EraseUser(ctx, john.ID)
+	assert.ErrorIs(t, err, client.ErrNotFound)
+	_, err = c.GetErasureReceipt(ctx, "missing")
+	assert.ErrorIs(t, err, client.ErrNotFound)
+
+	history, err := c.UserHistory(ctx, john.ID, client.AuditOptions{})
+	require.NoError(t, err)
+	require.Len(t, history.Entries, 3)
+	assert.Equal(t, "user.erased", history.Entries[0].Action)
+	assert.Empty(t, history.Entries[0].Changes)
+}
@@ -0,0 +1,78 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+const authPath = "/api/v1/auth"
+
+// Tokens are returned by Login and Refresh. Send AccessToken with
+// BearerToken; RefreshToken can be used once.
+type Tokens struct {
+	AccessToken string `json:"accessToken"`
+	TokenType   string `json:"tokenType"`
+	// ExpiresIn is the lifetime of AccessToken in seconds.
+	ExpiresIn    int    `json:"expiresIn"`
+	RefreshToken string `json:"refreshToken"`
+}
+
+type loginRequest struct {
+	UserID   string `json:"userId"`
+	Password string `json:"password"`
+	MFACode  string `json:"mfaCode,omitempty"`
+}
+
+type refreshTokenRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+type setPasswordRequest struct {
+	CurrentPassword string `json:"currentPassword,omitempty"`
+	Password        string `json:"password"`
+}
+
+// Login checks the password of a user and starts a session. It fails with
+// ErrForbidden for users with MFA enabled, who sign in with LoginMFA.
+func (c *Client) Login(ctx context.Context, userID, password string) (*Tokens, error) {
+	return c.LoginMFA(ctx, userID, password, "")
+}
+
+// LoginMFA checks the password and the TOTP or recovery code of a user and
+// starts a session.
+func (c *Client) LoginMFA(ctx context.Context, userID, password, code string) (*Tokens, error) {
+	tokens := &Tokens{}
+	input := loginRequest{UserID: userID, Password: password, MFACode: code}
+	if _, err := c.do(ctx, http.MethodPost, authPath+"/login", nil, input, tokens); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// Refresh exchanges a refresh token for new tokens. Using a refresh token
+// twice ends its session.
+func (c *Client) Refresh(ctx context.Context, refreshToken string) (*Tokens, error) {
+	tokens := &Tokens{}
+	input := refreshTokenRequest{RefreshToken: refreshToken}
+	if _, err := c.do(ctx, http.MethodPost, authPath+"/refresh", nil, input, tokens); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// Logout ends the session of a refresh token.
+func (c *Client) Logout(ctx context.Context, refreshToken string) error {
+	input := refreshTokenRequest{RefreshToken: refreshToken}
+	_, err := c.do(ctx, http.MethodPost, authPath+"/logout", nil, input, &messageResponse{})
+	return err
+}
+
+// SetPassword sets the password of a user. current is required when users
+// change their own password and is ignored for admins.
+func (c *Client) SetPassword(ctx context.Context, id, current, password string) error {
+	path := usersPath + "/" + url.PathEscape(id) + "/password"
+	input := setPasswordRequest{CurrentPassword: current, Password: password}
+	_, err := c.do(ctx, http.MethodPut, path, nil, input, &messageResponse{})
+	return err
+}
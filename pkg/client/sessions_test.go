@@ -0,0 +1,133 @@
+package client_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	"github.com/Sergey-Polishchenko/simple-api/internal/domain"
+	"github.com/Sergey-Polishchenko/simple-api/internal/infrastructure/memory"
+	httpapi "github.com/Sergey-Polishchenko/simple-api/internal/interfaces/http"
+	"github.com/Sergey-Polishchenko/simple-api/internal/interfaces/http/middleware"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/jwks"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/logger"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/password"
+	"github.com/Sergey-Polishchenko/simple-api/pkg/client"
+)
+
+// newSessionServer serves the API with password logins for user "1",
+// whose password is "correct horse", and returns its URL.
+func newSessionServer(t *testing.T) string {
+	url, _ := newAccountServer(t)
+	return url
+}
+
+// mailbox receives the emails of the account flows.
+type mailbox chan app.Email
+
+func (m mailbox) Send(_ context.Context, email app.Email) error {
+	m <- email
+	return nil
+}
+
+// newAccountServer is newSessionServer with the account flows, which send
+// their emails to the returned mailbox.
+func newAccountServer(t *testing.T) (string, mailbox) {
+	gin.SetMode(gin.TestMode)
+
+	logger := logger.NewZapLogger()
+	hasher := password.NewHasher(password.Params{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 16})
+	users := memory.NewUserRepo()
+
+	hash, err := hasher.Hash("correct horse")
+	require.NoError(t, err)
+	require.NoError(t, users.Create(context.Background(), domain.NewUser("1", "John").WithPasswordHash(hash)))
+
+	signer, err := jwks.NewSigner([]byte("0123456789abcdef0123456789abcdef"), "")
+	require.NoError(t, err)
+	keys, err := jwks.NewSet([]jwks.Key{signer.VerificationKey()}, "", 0)
+	require.NoError(t, err)
+
+	mfa := app.NewMFAApp(memory.NewMFARepo(), logger, "simple-api")
+	sessions := memory.NewRefreshTokenRepo()
+	auth, err := app.NewAuthApp(users, sessions, hasher, signer, logger, app.AuthConfig{
+		AccessTokenTTL:  time.Minute,
+		RefreshTokenTTL: time.Hour,
+	}, app.WithSecondFactor(mfa))
+	require.NoError(t, err)
+
+	mail := make(mailbox, 10)
+	accounts, err := app.NewAccountApp(users, memory.NewUsedTokenRepo(), sessions, mail, hasher, logger, app.AccountConfig{
+		Secret:           []byte("fedcba9876543210fedcba9876543210"),
+		VerificationTTL:  time.Hour,
+		PasswordResetTTL: time.Hour,
+	})
+	require.NoError(t, err)
+
+	service := app.NewUserApp(users, logger, app.WithPasswordHasher(hasher))
+	router, err := httpapi.NewRouter(service, logger, httpapi.Config{
+		EnforceContract: true,
+		JWT:             &middleware.JWTConfig{Keys: keys},
+		Auth:            auth,
+		MFA:             mfa,
+		Accounts:        accounts,
+	})
+	require.NoError(t, err)
+
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return server.URL, mail
+}
+
+func TestClient_Sessions(t *testing.T) {
+	url := newSessionServer(t)
+	ctx := context.Background()
+
+	anonymous, err := client.New(url)
+	require.NoError(t, err)
+
+	_, err = anonymous.Login(ctx, "1", "battery staple")
+	assert.ErrorIs(t, err, client.ErrUnauthorized)
+
+	tokens, err := anonymous.Login(ctx, "1", "correct horse")
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer", tokens.TokenType)
+	assert.Equal(t, 60, tokens.ExpiresIn)
+
+	user, err := client.New(url, client.WithAuth(client.BearerToken(tokens.AccessToken)))
+	require.NoError(t, err)
+
+	me, err := user.GetUser(ctx, "1")
+	require.NoError(t, err)
+	assert.Equal(t, "John", me.Name)
+
+	err = user.SetPassword(ctx, "1", "wrong password", "battery staple")
+	assert.ErrorIs(t, err, client.ErrForbidden)
+	err = user.SetPassword(ctx, "1", "correct horse", "short")
+	assert.ErrorIs(t, err, client.ErrBadRequest)
+	require.NoError(t, user.SetPassword(ctx, "1", "correct horse", "battery staple"))
+
+	refreshed, err := anonymous.Refresh(ctx, tokens.RefreshToken)
+	require.NoError(t, err)
+
+	// Reusing a refresh token ends the session.
+	_, err = anonymous.Refresh(ctx, tokens.RefreshToken)
+	assert.ErrorIs(t, err, client.ErrUnauthorized)
+	_, err = anonymous.Refresh(ctx, refreshed.RefreshToken)
+	assert.ErrorIs(t, err, client.ErrUnauthorized)
+
+	tokens, err = anonymous.Login(ctx, "1", "battery staple")
+	require.NoError(t, err)
+	require.NoError(t, anonymous.Logout(ctx, tokens.RefreshToken))
+	_, err = anonymous.Refresh(ctx, tokens.RefreshToken)
+	assert.ErrorIs(t, err, client.ErrUnauthorized)
+
+	_, err = anonymous.GetUser(ctx, "1")
+	assert.ErrorIs(t, err, client.ErrUnauthorized, "only the auth endpoints are public")
+}
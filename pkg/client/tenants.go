@@ -0,0 +1,58 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const tenantsPath = "/api/v1/tenants"
+
+// Tenant describes a tenant, which owns a separate set of users.
+type Tenant struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// CreateTenantInput describes a new tenant. ID is a DNS label, as it can
+// name the tenant in a subdomain.
+type CreateTenantInput struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// CreateTenant creates a tenant. It fails with ErrConflict if the ID is taken.
+func (c *Client) CreateTenant(ctx context.Context, input CreateTenantInput) (*Tenant, error) {
+	tenant := &Tenant{}
+	if _, err := c.do(ctx, http.MethodPost, tenantsPath, nil, input, tenant); err != nil {
+		return nil, err
+	}
+	return tenant, nil
+}
+
+// GetTenant fetches a tenant by ID.
+func (c *Client) GetTenant(ctx context.Context, id string) (*Tenant, error) {
+	tenant := &Tenant{}
+	if _, err := c.do(ctx, http.MethodGet, tenantsPath+"/"+url.PathEscape(id), nil, nil, tenant); err != nil {
+		return nil, err
+	}
+	return tenant, nil
+}
+
+// ListTenants fetches all tenants.
+func (c *Client) ListTenants(ctx context.Context) ([]*Tenant, error) {
+	var tenants []*Tenant
+	if _, err := c.do(ctx, http.MethodGet, tenantsPath, nil, nil, &tenants); err != nil {
+		return nil, err
+	}
+	return tenants, nil
+}
+
+// RemoveTenant removes a tenant by ID. It fails with ErrConflict while
+// the tenant still has users.
+func (c *Client) RemoveTenant(ctx context.Context, id string) error {
+	_, err := c.do(ctx, http.MethodDelete, tenantsPath+"/"+url.PathEscape(id), nil, nil, &messageResponse{})
+	return err
+}
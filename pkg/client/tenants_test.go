@@ -0,0 +1,108 @@
+package client_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	app "github.com/Sergey-Polishchenko/simple-api/internal/application"
+	"github.com/Sergey-Polishchenko/simple-api/internal/infrastructure/memory"
+	httpapi "github.com/Sergey-Polishchenko/simple-api/internal/interfaces/http"
+	"github.com/Sergey-Polishchenko/simple-api/internal/pkg/logger"
+	"github.com/Sergey-Polishchenko/simple-api/pkg/client"
+)
+
+// newTenantServer serves the API with multi-tenancy and API key
+// authentication, and returns its URL along with an operator key.
+func newTenantServer(t *testing.T) (string, string) {
+	gin.SetMode(gin.TestMode)
+
+	logger := logger.NewZapLogger()
+	users := memory.NewUserRepo()
+	service := app.NewUserApp(users, logger)
+	apiKeys := app.NewAPIKeyApp(memory.NewAPIKeyRepo(), logger, []byte("0123456789abcdef0123456789abcdef"))
+
+	admin := app.ContextWithClaims(context.Background(), &app.Claims{Roles: []string{app.RoleAdmin}})
+	_, operatorKey, err := apiKeys.Mint(admin, app.MintAPIKeyParams{Name: "operator", Scopes: []string{app.ScopeUsersAdmin}})
+	require.NoError(t, err)
+
+	router, err := httpapi.NewRouter(service, logger, httpapi.Config{
+		EnforceContract: true,
+		APIKeys:         apiKeys,
+		Tenants:         &httpapi.TenantConfig{Service: app.NewTenantApp(memory.NewTenantRepo(), users, logger)},
+	})
+	require.NoError(t, err)
+
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return server.URL, operatorKey
+}
+
+func TestClient_Tenants(t *testing.T) {
+	url, operatorKey := newTenantServer(t)
+	ctx := context.Background()
+
+	operator, err := client.New(url, client.WithAuth(client.APIKey(operatorKey)))
+	require.NoError(t, err)
+
+	created, err := operator.CreateTenant(ctx, client.CreateTenantInput{ID: "acme", Name: "Acme Corp"})
+	require.NoError(t, err)
+	assert.Equal(t, "Acme Corp", created.Name)
+
+	_, err = operator.CreateTenant(ctx, client.CreateTenantInput{ID: "acme", Name: "Again"})
+	assert.ErrorIs(t, err, client.ErrConflict)
+	_, err = operator.CreateTenant(ctx, client.CreateTenantInput{ID: "Not A Label", Name: "Bad"})
+	assert.ErrorIs(t, err, client.ErrBadRequest)
+
+	tenants, err := operator.ListTenants(ctx)
+	require.NoError(t, err)
+	require.Len(t, tenants, 2)
+	assert.Equal(t, "acme", tenants[0].ID)
+	assert.Equal(t, app.DefaultTenantID, tenants[1].ID)
+
+	// Operators act in any tenant; the key minted in acme belongs to it.
+	inAcme, err := client.New(url, client.WithAuth(client.APIKey(operatorKey)), client.WithTenant("acme"))
+	require.NoError(t, err)
+	minted, err := inAcme.MintAPIKey(ctx, client.MintAPIKeyInput{Name: "acme", Scopes: []string{client.ScopeUsersAdmin}})
+	require.NoError(t, err)
+
+	acme, err := client.New(url, client.WithAuth(client.APIKey(minted.Key)))
+	require.NoError(t, err)
+	user, err := acme.CreateUser(ctx, client.CreateUserInput{Name: "Wile"})
+	require.NoError(t, err)
+
+	page, err := acme.ListUsers(ctx, client.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, page.Users, 1)
+	page, err = operator.ListUsers(ctx, client.ListOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, page.Users, "users of acme are not in the default tenant")
+
+	_, err = operator.GetUser(ctx, user.ID)
+	assert.ErrorIs(t, err, client.ErrNotFound)
+	_, err = inAcme.GetUser(ctx, user.ID)
+	assert.NoError(t, err)
+
+	// Tenant admins are confined to their tenant.
+	_, err = acme.ListTenants(ctx)
+	assert.ErrorIs(t, err, client.ErrForbidden)
+	escaping, err := client.New(url, client.WithAuth(client.APIKey(minted.Key)), client.WithTenant(app.DefaultTenantID))
+	require.NoError(t, err)
+	_, err = escaping.ListUsers(ctx, client.ListOptions{})
+	assert.ErrorIs(t, err, client.ErrForbidden)
+
+	unknown, err := client.New(url, client.WithAuth(client.APIKey(operatorKey)), client.WithTenant("missing"))
+	require.NoError(t, err)
+	_, err = unknown.ListUsers(ctx, client.ListOptions{})
+	assert.ErrorIs(t, err, client.ErrNotFound)
+
+	assert.ErrorIs(t, operator.RemoveTenant(ctx, "acme"), client.ErrConflict)
+	require.NoError(t, acme.DeleteUser(ctx, user.ID))
+	require.NoError(t, operator.RemoveTenant(ctx, "acme"))
+	_, err = operator.GetTenant(ctx, "acme")
+	assert.ErrorIs(t, err, client.ErrNotFound)
+}
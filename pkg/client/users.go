@@ -0,0 +1,171 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"iter"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+)
+
+// DefaultPageSize is the page size used by ListUsers and Users when none is set.
+const DefaultPageSize = 20
+
+const usersPath = "/api/v1/users"
+
+// User is a system user.
+type User struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email,omitempty"`
+	// EmailVerified reports whether the user confirmed Email.
+	EmailVerified bool `json:"emailVerified,omitempty"`
+	// Attributes holds the custom attributes of the user, with string,
+	// float64 or bool values.
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
+
+// CreateUserInput holds the fields of a new user.
+type CreateUserInput struct {
+	Name string `json:"name"`
+	// Email is unverified until the user confirms it with VerifyEmail.
+	Email string `json:"email,omitempty"`
+	// Attributes must match the attribute schema of the tenant.
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
+
+// UpdateUserInput holds the new fields of an existing user.
+type UpdateUserInput struct {
+	Name string `json:"name"`
+	// Attributes replaces the custom attributes of the user; with a nil
+	// map the user keeps them, an empty one removes them.
+	Attributes map[string]interface{} `json:"-"`
+}
+
+// MarshalJSON omits nil attributes only, as omitempty would drop empty ones.
+func (in UpdateUserInput) MarshalJSON() ([]byte, error) {
+	body := map[string]interface{}{"name": in.Name}
+	if in.Attributes != nil {
+		body["attributes"] = in.Attributes
+	}
+	return json.Marshal(body)
+}
+
+// ListOptions selects a page of users.
+type ListOptions struct {
+	// Limit is the page size; DefaultPageSize if zero.
+	Limit int
+	// After is the cursor returned as NextCursor by the previous page.
+	After string
+	// Name keeps users whose name contains the value, case-insensitively.
+	Name string
+	// Attributes keeps users whose custom attributes have the given values.
+	Attributes map[string]string
+	// Sort orders users by a custom attribute, descending when prefixed
+	// with a dash, instead of by ID.
+	Sort string
+}
+
+// UserPage is one page of users ordered by ID, or ListOptions.Sort.
+type UserPage struct {
+	Users []*User
+	// NextCursor is empty on the last page.
+	NextCursor string
+}
+
+type messageResponse struct {
+	Message string `json:"message"`
+}
+
+// CreateUser creates a user. It is retried on 429 only, see RetryPolicy.
+func (c *Client) CreateUser(ctx context.Context, input CreateUserInput) (*User, error) {
+	user := &User{}
+	if _, err := c.do(ctx, http.MethodPost, usersPath, nil, input, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// GetUser fetches a user by ID.
+func (c *Client) GetUser(ctx context.Context, id string) (*User, error) {
+	user := &User{}
+	if _, err := c.do(ctx, http.MethodGet, userPath(id), nil, nil, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// UpdateUser replaces the fields of an existing user.
+func (c *Client) UpdateUser(ctx context.Context, id string, input UpdateUserInput) error {
+	_, err := c.do(ctx, http.MethodPut, userPath(id), nil, input, &messageResponse{})
+	return err
+}
+
+// DeleteUser deletes a user by ID.
+func (c *Client) DeleteUser(ctx context.Context, id string) error {
+	_, err := c.do(ctx, http.MethodDelete, userPath(id), nil, nil, &messageResponse{})
+	return err
+}
+
+// ListUsers fetches a single page of users.
+func (c *Client) ListUsers(ctx context.Context, opts ListOptions) (*UserPage, error) {
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+
+	query := url.Values{}
+	query.Set("limit", strconv.Itoa(limit))
+	if opts.After != "" {
+		query.Set("after", opts.After)
+	}
+	if opts.Name != "" {
+		query.Set("name", opts.Name)
+	}
+	for _, name := range slices.Sorted(maps.Keys(opts.Attributes)) {
+		query.Add("attr", name+"="+opts.Attributes[name])
+	}
+	if opts.Sort != "" {
+		query.Set("sort", opts.Sort)
+	}
+
+	var users []*User
+	header, err := c.do(ctx, http.MethodGet, usersPath, query, nil, &users)
+	if err != nil {
+		return nil, err
+	}
+
+	return &UserPage{Users: users, NextCursor: header.Get(nextCursorHeader)}, nil
+}
+
+// Users iterates over all users matching opts, fetching pages on demand.
+// Iteration stops after the first error, which is yielded with a nil user.
+func (c *Client) Users(ctx context.Context, opts ListOptions) iter.Seq2[*User, error] {
+	return func(yield func(*User, error) bool) {
+		for {
+			page, err := c.ListUsers(ctx, opts)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			for _, user := range page.Users {
+				if !yield(user, nil) {
+					return
+				}
+			}
+
+			if page.NextCursor == "" {
+				return
+			}
+			opts.After = page.NextCursor
+		}
+	}
+}
+
+func userPath(id string) string {
+	return usersPath + "/" + url.PathEscape(id)
+}
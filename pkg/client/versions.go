@@ -0,0 +1,68 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// UserVersion is a user as it was from ValidFrom until ValidTo. A version
+// starts with every change of the name, email address or attributes.
+type UserVersion struct {
+	Version   int       `json:"version"`
+	User      User      `json:"user"`
+	ValidFrom time.Time `json:"validFrom"`
+	// ValidTo is nil for the current version.
+	ValidTo *time.Time `json:"validTo"`
+}
+
+// VersionOptions selects a page of the versions of a user.
+type VersionOptions struct {
+	// Limit is the page size; DefaultPageSize if zero.
+	Limit int
+	// After is the cursor returned as NextCursor by the previous page.
+	After string
+}
+
+// UserVersionPage is one page of the versions of a user, newest first.
+type UserVersionPage struct {
+	Versions []*UserVersion
+	// NextCursor is empty on the last page.
+	NextCursor string
+}
+
+// GetUserAsOf fetches a user as it was at the given time, even if removed
+// since. It fails with ErrNotFound if the user didn't exist then.
+func (c *Client) GetUserAsOf(ctx context.Context, id string, at time.Time) (*User, error) {
+	query := url.Values{"as_of": {at.UTC().Format(time.RFC3339Nano)}}
+	user := &User{}
+	if _, err := c.do(ctx, http.MethodGet, userPath(id), query, nil, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// ListUserVersions fetches a single page of the versions of a user,
+// including a removed one.
+func (c *Client) ListUserVersions(ctx context.Context, id string, opts VersionOptions) (*UserVersionPage, error) {
+	var versions []*UserVersion
+	header, err := c.do(ctx, http.MethodGet, userPath(id)+"/versions", pageQuery(opts.Limit, opts.After), nil, &versions)
+	if err != nil {
+		return nil, err
+	}
+	return &UserVersionPage{Versions: versions, NextCursor: header.Get(nextCursorHeader)}, nil
+}
+
+// RevertUser restores the name, email address and attributes a user had
+// in a version. A restored email address has to be verified again. It
+// fails with ErrConflict if another user took the email address since.
+func (c *Client) RevertUser(ctx context.Context, id string, version int) (*User, error) {
+	path := userPath(id) + "/versions/" + strconv.Itoa(version) + "/revert"
+	user := &User{}
+	if _, err := c.do(ctx, http.MethodPost, path, nil, nil, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
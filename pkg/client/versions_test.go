@@ -0,0 +1,53 @@
+package client_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Sergey-Polishchenko/simple-api/pkg/client"
+)
+
+func TestClient_UserVersions(t *testing.T) {
+	c := newTestClient(t)
+	ctx := context.Background()
+
+	john, err := c.CreateUser(ctx, client.CreateUserInput{Name: "John"})
+	require.NoError(t, err)
+	require.NoError(t, c.UpdateUser(ctx, john.ID, client.UpdateUserInput{Name: "Johnny"}))
+	require.NoError(t, c.UpdateUser(ctx, john.ID, client.UpdateUserInput{Name: "Jonathan"}))
+
+	page, err := c.ListUserVersions(ctx, john.ID, client.VersionOptions{Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, page.Versions, 2)
+	assert.NotEmpty(t, page.NextCursor)
+	assert.Equal(t, 3, page.Versions[0].Version)
+	assert.Nil(t, page.Versions[0].ValidTo)
+	require.NotNil(t, page.Versions[1].ValidTo)
+
+	page, err = c.ListUserVersions(ctx, john.ID, client.VersionOptions{Limit: 2, After: page.NextCursor})
+	require.NoError(t, err)
+	require.Len(t, page.Versions, 1)
+	assert.Empty(t, page.NextCursor)
+	first := page.Versions[0]
+	assert.Equal(t, client.User{ID: john.ID, Name: "John"}, first.User)
+
+	asOf, err := c.GetUserAsOf(ctx, john.ID, first.ValidFrom)
+	require.NoError(t, err)
+	assert.Equal(t, "John", asOf.Name)
+	_, err = c.GetUserAsOf(ctx, john.ID, first.ValidFrom.Add(-1))
+	assert.ErrorIs(t, err, client.ErrNotFound)
+
+	reverted, err := c.RevertUser(ctx, john.ID, 1)
+	require.NoError(t, err)
+	assert.Equal(t, &client.User{ID: john.ID, Name: "John"}, reverted)
+	_, err = c.RevertUser(ctx, john.ID, 9)
+	assert.ErrorIs(t, err, client.ErrNotFound)
+
+	require.NoError(t, c.DeleteUser(ctx, john.ID))
+	page, err = c.ListUserVersions(ctx, john.ID, client.VersionOptions{})
+	require.NoError(t, err)
+	assert.Len(t, page.Versions, 4)
+}